@@ -0,0 +1,408 @@
+package apibackend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/output"
+)
+
+// stubServer serves canned OpenAI and Anthropic responses and records the last request.
+type stubServer struct {
+	*httptest.Server
+	lastPath   string
+	lastHeader http.Header
+	lastBody   map[string]any
+}
+
+func newStubServer(t *testing.T) *stubServer {
+	t.Helper()
+	s := &stubServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lastPath = r.URL.Path
+		s.lastHeader = r.Header.Clone()
+		s.lastBody = nil
+		_ = json.NewDecoder(r.Body).Decode(&s.lastBody)
+
+		prompt := ""
+		if msgs, ok := s.lastBody["messages"].([]any); ok && len(msgs) > 0 {
+			prompt, _ = msgs[len(msgs)-1].(map[string]any)["content"].(string)
+		}
+		stream, _ := s.lastBody["stream"].(bool)
+
+		if prompt == "fail" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"rate limited","type":"rate_limit_error"}}`)
+			return
+		}
+
+		switch {
+		case r.URL.Path == "/chat/completions" && stream:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, `data: {"id":"chatcmpl-1","model":"gpt-test","choices":[{"delta":{"content":"Hel"}}]}`+"\n\n")
+			fmt.Fprint(w, `data: {"id":"chatcmpl-1","model":"gpt-test","choices":[{"delta":{"content":"lo"}}]}`+"\n\n")
+			fmt.Fprint(w, `data: {"id":"chatcmpl-1","model":"gpt-test","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`+"\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		case r.URL.Path == "/chat/completions":
+			fmt.Fprint(w, `{"id":"chatcmpl-1","model":"gpt-test","choices":[{"message":{"role":"assistant","content":"Hello"}}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`)
+		case r.URL.Path == "/messages" && stream:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: message_start\n"+`data: {"type":"message_start","message":{"id":"msg_1","model":"claude-test","usage":{"input_tokens":4,"output_tokens":1}}}`+"\n\n")
+			fmt.Fprint(w, "event: content_block_delta\n"+`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`+"\n\n")
+			fmt.Fprint(w, "event: message_delta\n"+`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":6}}`+"\n\n")
+			fmt.Fprint(w, "event: message_stop\n"+`data: {"type":"message_stop"}`+"\n\n")
+		case r.URL.Path == "/messages":
+			fmt.Fprint(w, `{"id":"msg_1","model":"claude-test","content":[{"type":"text","text":"Hi"},{"type":"text","text":" there"}],"usage":{"input_tokens":4,"output_tokens":6}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestClientComplete(t *testing.T) {
+	srv := newStubServer(t)
+
+	tests := []struct {
+		name        string
+		provider    string
+		prompt      string
+		wantPath    string
+		wantContent string
+		wantSession string
+		wantTokens  int
+		wantError   string
+	}{
+		{"openai", ProviderOpenAI, "hello", "/chat/completions", "Hello", "chatcmpl-1", 5, ""},
+		{"anthropic", ProviderAnthropic, "hello", "/messages", "Hi there", "msg_1", 10, ""},
+		{"http error", ProviderOpenAI, "fail", "/chat/completions", "", "", 0, "rate limited"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(Endpoint{Provider: tt.provider, BaseURL: srv.URL + "/", APIKey: "sk-test"})
+			if err != nil {
+				t.Fatalf("NewClient() error: %v", err)
+			}
+
+			resp, err := client.Complete(context.Background(), &Request{Model: "m", Prompt: tt.prompt, SystemPrompt: "be brief"})
+			if err != nil {
+				t.Fatalf("Complete() error: %v", err)
+			}
+			if srv.lastPath != tt.wantPath {
+				t.Errorf("path = %q, want %q", srv.lastPath, tt.wantPath)
+			}
+			if tt.wantError != "" {
+				if !strings.Contains(resp.Error, tt.wantError) {
+					t.Errorf("Error = %q, want it to contain %q", resp.Error, tt.wantError)
+				}
+				return
+			}
+			if resp.Content != tt.wantContent || resp.SessionID != tt.wantSession {
+				t.Errorf("unexpected response: %+v", resp)
+			}
+			if resp.Usage == nil || resp.Usage.TotalTokens != tt.wantTokens {
+				t.Errorf("unexpected usage: %+v", resp.Usage)
+			}
+		})
+	}
+
+	t.Run("auth and request shape", func(t *testing.T) {
+		client, _ := NewClient(Endpoint{Provider: ProviderAnthropic, BaseURL: srv.URL, APIKey: "sk-ant", Headers: map[string]string{"X-Extra": "1"}})
+		if _, err := client.Complete(context.Background(), &Request{Model: "m", Prompt: "hi", SystemPrompt: "sys"}); err != nil {
+			t.Fatal(err)
+		}
+		if srv.lastHeader.Get("x-api-key") != "sk-ant" || srv.lastHeader.Get("anthropic-version") == "" {
+			t.Errorf("missing anthropic auth headers: %v", srv.lastHeader)
+		}
+		if srv.lastHeader.Get("X-Extra") != "1" {
+			t.Error("expected extra header to be sent")
+		}
+		if srv.lastBody["system"] != "sys" || srv.lastBody["max_tokens"] != float64(defaultAnthropicMaxTokens) {
+			t.Errorf("unexpected anthropic body: %v", srv.lastBody)
+		}
+
+		client, _ = NewClient(Endpoint{Provider: ProviderOpenAI, BaseURL: srv.URL, APIKey: "sk-oa"})
+		if _, err := client.Complete(context.Background(), &Request{Model: "m", Prompt: "hi", SystemPrompt: "sys"}); err != nil {
+			t.Fatal(err)
+		}
+		if srv.lastHeader.Get("Authorization") != "Bearer sk-oa" {
+			t.Errorf("unexpected Authorization header: %q", srv.lastHeader.Get("Authorization"))
+		}
+		if msgs, _ := srv.lastBody["messages"].([]any); len(msgs) != 2 {
+			t.Errorf("expected system and user messages, got %v", srv.lastBody["messages"])
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		if _, err := NewClient(Endpoint{Provider: "other"}); err == nil {
+			t.Error("expected error for unknown provider")
+		}
+	})
+}
+
+func TestClientStream(t *testing.T) {
+	srv := newStubServer(t)
+
+	tests := []struct {
+		name       string
+		provider   string
+		prompt     string
+		wantTypes  []output.EventType
+		wantText   string
+		wantOutput int64
+	}{
+		{
+			name:       "openai",
+			provider:   ProviderOpenAI,
+			prompt:     "hello",
+			wantTypes:  []output.EventType{output.EventInit, output.EventMessage, output.EventMessage, output.EventDone},
+			wantText:   "Hello",
+			wantOutput: 2,
+		},
+		{
+			name:       "anthropic",
+			provider:   ProviderAnthropic,
+			prompt:     "hello",
+			wantTypes:  []output.EventType{output.EventInit, output.EventMessage, output.EventDone},
+			wantText:   "Hi",
+			wantOutput: 6,
+		},
+		{
+			name:      "http error",
+			provider:  ProviderAnthropic,
+			prompt:    "fail",
+			wantTypes: []output.EventType{output.EventError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := NewClient(Endpoint{Provider: tt.provider, BaseURL: srv.URL})
+
+			var events []*output.UnifiedEvent
+			err := client.Stream(context.Background(), &Request{Model: "m", Prompt: tt.prompt}, func(e *output.UnifiedEvent) error {
+				events = append(events, e)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Stream() error: %v", err)
+			}
+
+			if len(events) != len(tt.wantTypes) {
+				t.Fatalf("expected %d events, got %d", len(tt.wantTypes), len(events))
+			}
+			var text strings.Builder
+			for i, e := range events {
+				if e.Type != tt.wantTypes[i] {
+					t.Errorf("event %d type = %q, want %q", i, e.Type, tt.wantTypes[i])
+				}
+				if e.Type == output.EventMessage {
+					msg, _ := e.GetMessageContent()
+					text.WriteString(msg.Text)
+				}
+			}
+			if text.String() != tt.wantText {
+				t.Errorf("streamed text = %q, want %q", text.String(), tt.wantText)
+			}
+
+			last := events[len(events)-1]
+			if last.Type == output.EventDone {
+				var done output.DoneContent
+				if err := json.Unmarshal(last.Content, &done); err != nil || done.TokenUsage == nil || done.TokenUsage.OutputTokens != tt.wantOutput {
+					t.Errorf("unexpected done content: %s", last.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	srv := newStubServer(t)
+	t.Setenv(APIKeyEnvVar, "sk-test")
+	t.Setenv(HeadersEnvVar, `{"X-Team":"platform"}`)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		check    func(t *testing.T, stdout string)
+	}{
+		{
+			name:     "json",
+			args:     []string{"--provider", "openai", "--base-url", srv.URL, "--model", "m", "--output", "json", "--", "hello"},
+			wantCode: 0,
+			check: func(t *testing.T, stdout string) {
+				var resp backend.UnifiedResponse
+				if err := json.Unmarshal([]byte(stdout), &resp); err != nil || resp.Content != "Hello" {
+					t.Errorf("unexpected json output: %q", stdout)
+				}
+			},
+		},
+		{
+			name:     "text",
+			args:     []string{"--provider", "anthropic", "--base-url", srv.URL, "--model", "m", "--output", "text", "--", "hello"},
+			wantCode: 0,
+			check: func(t *testing.T, stdout string) {
+				if stdout != "Hi there\n" {
+					t.Errorf("unexpected text output: %q", stdout)
+				}
+			},
+		},
+		{
+			name:     "stream-json",
+			args:     []string{"--provider", "openai", "--base-url", srv.URL, "--model", "m", "--output", "stream-json", "--", "hello"},
+			wantCode: 0,
+			check: func(t *testing.T, stdout string) {
+				if lines := strings.Split(strings.TrimSpace(stdout), "\n"); len(lines) != 4 {
+					t.Errorf("expected 4 event lines, got %q", stdout)
+				}
+			},
+		},
+		{
+			name:     "api error",
+			args:     []string{"--provider", "openai", "--base-url", srv.URL, "--model", "m", "--", "fail"},
+			wantCode: 1,
+			check: func(t *testing.T, stdout string) {
+				if !strings.Contains(stdout, "rate limited") {
+					t.Errorf("expected error in output, got %q", stdout)
+				}
+			},
+		},
+		{
+			name:     "missing model",
+			args:     []string{"--provider", "openai", "--base-url", srv.URL, "--", "hello"},
+			wantCode: 1,
+			check: func(t *testing.T, stdout string) {
+				if !strings.Contains(stdout, "model is required") {
+					t.Errorf("expected model error, got %q", stdout)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := Main(context.Background(), tt.args, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d (stderr: %s)", code, tt.wantCode, stderr.String())
+			}
+			tt.check(t, stdout.String())
+		})
+	}
+
+	if srv.lastHeader.Get("Authorization") != "Bearer sk-test" {
+		t.Errorf("expected key from %s, got %q", APIKeyEnvVar, srv.lastHeader.Get("Authorization"))
+	}
+	if srv.lastHeader.Get("X-Team") != "platform" {
+		t.Errorf("expected headers from %s, got %v", HeadersEnvVar, srv.lastHeader)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	t.Setenv("TEST_API_KEY", "sk-env")
+
+	b, err := NewBackend(Spec{
+		Name:      "local-llm",
+		Provider:  ProviderOpenAI,
+		BaseURL:   "http://localhost:11434/v1",
+		APIKeyEnv: "TEST_API_KEY",
+		Headers:   map[string]string{"B": "2", "A": "1"},
+		Timeout:   30 * time.Second,
+		MaxTokens: 512,
+	})
+	if err != nil {
+		t.Fatalf("NewBackend() error: %v", err)
+	}
+	b.executable, b.execErr = "/usr/local/bin/clinvk", nil
+
+	t.Run("name and availability", func(t *testing.T) {
+		if b.Name() != "local-llm" {
+			t.Errorf("Name() = %q", b.Name())
+		}
+		if !b.IsAvailable() {
+			t.Error("expected backend to be available when key env is set")
+		}
+		t.Setenv("TEST_API_KEY", "")
+		if b.IsAvailable() {
+			t.Error("expected backend to be unavailable without key")
+		}
+	})
+
+	t.Run("BuildCommandUnified", func(t *testing.T) {
+		cmd := b.BuildCommandUnified("hello", &backend.UnifiedOptions{
+			WorkDir:      "/tmp",
+			Model:        "llama3",
+			SystemPrompt: "be brief",
+			OutputFormat: backend.OutputStreamJSON,
+		})
+		want := "/usr/local/bin/clinvk __api-call --provider openai --output stream-json --base-url http://localhost:11434/v1 " +
+			"--model llama3 --system-prompt be brief --max-tokens 512 --timeout 30s -- hello"
+		if got := strings.Join(cmd.Args, " "); got != want {
+			t.Errorf("args =\n  %q\nwant\n  %q", got, want)
+		}
+		if cmd.Dir != "/tmp" {
+			t.Errorf("Dir = %q, want /tmp", cmd.Dir)
+		}
+		env := cmd.Env[len(cmd.Env)-2:]
+		if env[0] != APIKeyEnvVar+"=sk-env" || env[1] != HeadersEnvVar+`={"A":"1","B":"2"}` {
+			t.Errorf("expected API key and headers in env, got %q", env)
+		}
+	})
+
+	t.Run("ResumeCommand ignores session", func(t *testing.T) {
+		cmd := b.ResumeCommand("sess-1", "again", nil)
+		if strings.Contains(strings.Join(cmd.Args, " "), "sess-1") {
+			t.Error("expected session ID to be ignored")
+		}
+		if cmd.Args[len(cmd.Args)-1] != "again" {
+			t.Errorf("expected prompt last, got %v", cmd.Args)
+		}
+	})
+
+	t.Run("ParseJSONResponse and ParseOutput", func(t *testing.T) {
+		resp, err := b.ParseJSONResponse(`{"content":"ok","session_id":"id-1"}` + "\n")
+		if err != nil || resp.Content != "ok" || resp.SessionID != "id-1" {
+			t.Errorf("unexpected response: %+v, %v", resp, err)
+		}
+		if got := b.ParseOutput(`{"error":"boom"}`); got != "boom" {
+			t.Errorf("ParseOutput() = %q, want 'boom'", got)
+		}
+		if got := b.ParseOutput("plain"); got != "plain" {
+			t.Errorf("ParseOutput() = %q, want 'plain'", got)
+		}
+	})
+
+	t.Run("DecodeStreamLine", func(t *testing.T) {
+		eventType, content, err := b.DecodeStreamLine(`{"type":"message","content":{"text":"hi","role":"assistant"}}`)
+		if err != nil || eventType != output.EventMessage || content == nil {
+			t.Errorf("unexpected decode: %v, %v, %v", eventType, content, err)
+		}
+	})
+
+	t.Run("Capabilities", func(t *testing.T) {
+		caps := b.Capabilities()
+		if caps.Resume || caps.MaxTurns || caps.SupportsSandboxMode(backend.SandboxReadOnly) {
+			t.Errorf("expected single-turn capabilities, got %+v", caps)
+		}
+		if !caps.SystemPrompt || !caps.MaxTokens || !caps.Streaming {
+			t.Errorf("expected system prompt, max tokens and streaming, got %+v", caps)
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		if _, err := NewBackend(Spec{Name: "x", Provider: "other"}); err == nil {
+			t.Error("expected error for unknown provider")
+		}
+	})
+}
@@ -0,0 +1,226 @@
+package apibackend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/output"
+)
+
+// CommandName is the hidden clinvk subcommand that performs API calls.
+// Backends re-invoke the clinvk executable with it so that API models run
+// through the same process-based execution path as CLI backends.
+const CommandName = "__api-call"
+
+// APIKeyEnvVar passes the API key to the subcommand without exposing it in argv.
+const APIKeyEnvVar = "CLINVK_API_KEY"
+
+// HeadersEnvVar passes the extra HTTP headers to the subcommand as a JSON
+// object, since they may carry credentials too.
+const HeadersEnvVar = "CLINVK_API_HEADERS"
+
+// Spec describes an API backend.
+type Spec struct {
+	// Name is the backend identifier.
+	Name string
+
+	// Provider is the API flavor: openai or anthropic.
+	Provider string
+
+	// BaseURL overrides the provider's default API base URL.
+	BaseURL string
+
+	// APIKey is the API key (prefer APIKeyEnv).
+	APIKey string
+
+	// APIKeyEnv names the environment variable holding the API key.
+	APIKeyEnv string
+
+	// Headers are extra HTTP headers sent with each request.
+	Headers map[string]string
+
+	// Timeout bounds each HTTP request (0 = no timeout).
+	Timeout time.Duration
+
+	// MaxTokens is the default response token limit.
+	MaxTokens int
+}
+
+// Backend implements backend.Backend for an HTTP API.
+type Backend struct {
+	spec       Spec
+	executable string
+	execErr    error
+}
+
+// NewBackend creates an API backend from a spec.
+func NewBackend(spec Spec) (*Backend, error) {
+	if spec.Name == "" {
+		return nil, errors.New("API backend name is required")
+	}
+	switch spec.Provider {
+	case ProviderOpenAI, ProviderAnthropic:
+	default:
+		return nil, fmt.Errorf("API backend %q: unknown provider %q (valid: %s, %s)", spec.Name, spec.Provider, ProviderOpenAI, ProviderAnthropic)
+	}
+
+	b := &Backend{spec: spec}
+	b.executable, b.execErr = os.Executable()
+	return b, nil
+}
+
+// Name returns the backend identifier.
+func (b *Backend) Name() string {
+	return b.spec.Name
+}
+
+// IsAvailable reports whether the backend is usable: the clinvk executable is
+// known and, if a key environment variable is configured, it is set.
+func (b *Backend) IsAvailable() bool {
+	if b.execErr != nil || b.executable == "" {
+		return false
+	}
+	return b.spec.APIKeyEnv == "" || b.apiKey() != ""
+}
+
+// apiKey resolves the API key from the spec or its environment variable.
+func (b *Backend) apiKey() string {
+	if b.spec.APIKey != "" {
+		return b.spec.APIKey
+	}
+	if b.spec.APIKeyEnv != "" {
+		return os.Getenv(b.spec.APIKeyEnv)
+	}
+	return ""
+}
+
+// BuildCommand creates an exec.Cmd for running a prompt.
+func (b *Backend) BuildCommand(prompt string, opts *backend.Options) *exec.Cmd {
+	var unified *backend.UnifiedOptions
+	if opts != nil {
+		unified = &backend.UnifiedOptions{WorkDir: opts.WorkDir, Model: opts.Model}
+	}
+	return b.BuildCommandUnified(prompt, unified)
+}
+
+// ResumeCommand creates an exec.Cmd for a follow-up prompt.
+// HTTP APIs are stateless, so the session ID is not used.
+func (b *Backend) ResumeCommand(_, prompt string, opts *backend.Options) *exec.Cmd {
+	return b.BuildCommand(prompt, opts)
+}
+
+// BuildCommandUnified creates an exec.Cmd using unified options.
+func (b *Backend) BuildCommandUnified(prompt string, opts *backend.UnifiedOptions) *exec.Cmd {
+	if b.execErr != nil {
+		return &exec.Cmd{Path: CommandName, Args: []string{CommandName}, Err: b.execErr}
+	}
+
+	if opts == nil {
+		opts = &backend.UnifiedOptions{}
+	}
+
+	format := "json"
+	switch opts.OutputFormat {
+	case backend.OutputText:
+		format = "text"
+	case backend.OutputStreamJSON:
+		format = "stream-json"
+	}
+
+	args := []string{CommandName,
+		"--provider", b.spec.Provider,
+		"--output", format,
+	}
+	if b.spec.BaseURL != "" {
+		args = append(args, "--base-url", b.spec.BaseURL)
+	}
+	if opts.Model != "" {
+		args = append(args, "--model", opts.Model)
+	}
+	if opts.SystemPrompt != "" {
+		args = append(args, "--system-prompt", opts.SystemPrompt)
+	}
+	maxTokens := opts.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = b.spec.MaxTokens
+	}
+	if maxTokens > 0 {
+		args = append(args, "--max-tokens", strconv.Itoa(maxTokens))
+	}
+	if b.spec.Timeout > 0 {
+		args = append(args, "--timeout", b.spec.Timeout.String())
+	}
+
+	args = append(args, "--", prompt)
+
+	cmd := exec.Command(b.executable, args...)
+	cmd.Dir = opts.WorkDir
+	cmd.Env = append(os.Environ(), APIKeyEnvVar+"="+b.apiKey())
+	if len(b.spec.Headers) > 0 {
+		// A map of strings always encodes
+		headers, _ := json.Marshal(b.spec.Headers)
+		cmd.Env = append(cmd.Env, HeadersEnvVar+"="+string(headers))
+	}
+	return cmd
+}
+
+// ResumeCommandUnified creates a follow-up exec.Cmd using unified options.
+// HTTP APIs are stateless, so the session ID is not used.
+func (b *Backend) ResumeCommandUnified(_, prompt string, opts *backend.UnifiedOptions) *exec.Cmd {
+	return b.BuildCommandUnified(prompt, opts)
+}
+
+// ParseOutput extracts the response text from raw output.
+func (b *Backend) ParseOutput(rawOutput string) string {
+	if resp, err := b.ParseJSONResponse(rawOutput); err == nil {
+		if resp.Error != "" {
+			return resp.Error
+		}
+		return resp.Content
+	}
+	return rawOutput
+}
+
+// ParseJSONResponse parses the unified response printed by the subcommand.
+func (b *Backend) ParseJSONResponse(rawOutput string) (*backend.UnifiedResponse, error) {
+	var resp backend.UnifiedResponse
+	if err := json.Unmarshal([]byte(strings.TrimSpace(rawOutput)), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DecodeStreamLine decodes a unified event line printed by the subcommand.
+func (b *Backend) DecodeStreamLine(line string) (output.EventType, any, error) {
+	var event output.UnifiedEvent
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		return output.EventMessage, &output.MessageContent{Text: line, Role: "assistant"}, nil
+	}
+	var content any
+	if len(event.Content) > 0 {
+		content = event.Content
+	}
+	return event.Type, content, nil
+}
+
+// SeparateStderr returns true so diagnostics never mix with the JSON result.
+func (b *Backend) SeparateStderr() bool {
+	return true
+}
+
+// Capabilities returns the options an API call honors. Calls are single-turn
+// and run no tools, so resume, approval and sandbox options do not apply.
+func (b *Backend) Capabilities() backend.Capabilities {
+	return backend.Capabilities{
+		Streaming:    true,
+		SystemPrompt: true,
+		MaxTokens:    true,
+	}
+}
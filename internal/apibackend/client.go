@@ -0,0 +1,508 @@
+// Package apibackend implements backends that call OpenAI- or Anthropic-compatible
+// HTTP APIs directly instead of driving a local CLI.
+package apibackend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/output"
+)
+
+// Supported API providers.
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+)
+
+// Default base URLs per provider.
+const (
+	DefaultOpenAIBaseURL    = "https://api.openai.com/v1"
+	DefaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+)
+
+// anthropicVersion is the API version header sent to Anthropic-compatible endpoints.
+const anthropicVersion = "2023-06-01"
+
+// defaultAnthropicMaxTokens is used when no limit is given, since the Messages API requires one.
+const defaultAnthropicMaxTokens = 4096
+
+// maxSSELine bounds a single server-sent event line.
+const maxSSELine = 10 * 1024 * 1024
+
+// Endpoint describes how to reach an API.
+type Endpoint struct {
+	Provider string
+	BaseURL  string
+	APIKey   string
+	Headers  map[string]string
+	Timeout  time.Duration
+}
+
+// Request is a single-turn completion request.
+type Request struct {
+	Model        string
+	Prompt       string
+	SystemPrompt string
+	MaxTokens    int
+}
+
+// Client calls an API endpoint.
+type Client struct {
+	endpoint   Endpoint
+	httpClient *http.Client
+}
+
+// NewClient creates a client for an endpoint.
+func NewClient(endpoint Endpoint) (*Client, error) {
+	switch endpoint.Provider {
+	case ProviderOpenAI:
+		if endpoint.BaseURL == "" {
+			endpoint.BaseURL = DefaultOpenAIBaseURL
+		}
+	case ProviderAnthropic:
+		if endpoint.BaseURL == "" {
+			endpoint.BaseURL = DefaultAnthropicBaseURL
+		}
+	default:
+		return nil, fmt.Errorf("unknown API provider %q (valid: %s, %s)", endpoint.Provider, ProviderOpenAI, ProviderAnthropic)
+	}
+	endpoint.BaseURL = strings.TrimRight(endpoint.BaseURL, "/")
+
+	return &Client{
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: endpoint.Timeout},
+	}, nil
+}
+
+// Complete sends a request and returns the full response.
+// API-level errors are reported in UnifiedResponse.Error; transport errors are returned.
+func (c *Client) Complete(ctx context.Context, req *Request) (*backend.UnifiedResponse, error) {
+	start := time.Now()
+	httpReq, err := c.newRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *backend.UnifiedResponse
+	if c.endpoint.Provider == ProviderAnthropic {
+		result, err = parseAnthropicResponse(body)
+	} else {
+		result, err = parseOpenAIResponse(body)
+	}
+	if err != nil {
+		if resp.StatusCode >= 400 {
+			return &backend.UnifiedResponse{Error: httpErrorMessage(resp.StatusCode, body)}, nil
+		}
+		return nil, err
+	}
+	if resp.StatusCode >= 400 && result.Error == "" {
+		result.Error = httpErrorMessage(resp.StatusCode, body)
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// Stream sends a streaming request and emits unified events as they arrive:
+// init when the response starts, message for each text delta, done with token
+// usage at the end, or error if the API reports one.
+func (c *Client) Stream(ctx context.Context, req *Request, emit func(*output.UnifiedEvent) error) error {
+	httpReq, err := c.newRequest(ctx, req, true)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		msg := httpErrorMessage(resp.StatusCode, body)
+		if parsed := apiErrorMessage(body); parsed != "" {
+			msg = parsed
+		}
+		return emitEvent(emit, output.EventError, &output.ErrorContent{Code: "api_error", Message: msg})
+	}
+
+	s := &streamState{emit: emit, model: req.Model}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELine)
+
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+
+		if c.endpoint.Provider == ProviderAnthropic {
+			err = s.handleAnthropic([]byte(data))
+		} else {
+			err = s.handleOpenAI([]byte(data))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if s.failed {
+		return nil
+	}
+	return s.done()
+}
+
+// newRequest builds the HTTP request for the provider.
+func (c *Client) newRequest(ctx context.Context, req *Request, stream bool) (*http.Request, error) {
+	var (
+		url  string
+		body any
+	)
+
+	switch c.endpoint.Provider {
+	case ProviderAnthropic:
+		maxTokens := req.MaxTokens
+		if maxTokens <= 0 {
+			maxTokens = defaultAnthropicMaxTokens
+		}
+		url = c.endpoint.BaseURL + "/messages"
+		body = &anthropicRequest{
+			Model:     req.Model,
+			MaxTokens: maxTokens,
+			System:    req.SystemPrompt,
+			Messages:  []chatMessage{{Role: "user", Content: req.Prompt}},
+			Stream:    stream,
+		}
+	default:
+		messages := make([]chatMessage, 0, 2)
+		if req.SystemPrompt != "" {
+			messages = append(messages, chatMessage{Role: "system", Content: req.SystemPrompt})
+		}
+		messages = append(messages, chatMessage{Role: "user", Content: req.Prompt})
+		oaReq := &openAIRequest{
+			Model:     req.Model,
+			Messages:  messages,
+			MaxTokens: req.MaxTokens,
+			Stream:    stream,
+		}
+		if stream {
+			oaReq.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+		}
+		url = c.endpoint.BaseURL + "/chat/completions"
+		body = oaReq
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	if c.endpoint.Provider == ProviderAnthropic {
+		httpReq.Header.Set("anthropic-version", anthropicVersion)
+		if c.endpoint.APIKey != "" {
+			httpReq.Header.Set("x-api-key", c.endpoint.APIKey)
+		}
+	} else if c.endpoint.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.endpoint.APIKey)
+	}
+
+	for k, v := range c.endpoint.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	return httpReq, nil
+}
+
+// chatMessage is a message shared by both request formats.
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIRequest struct {
+	Model         string               `json:"model"`
+	Messages      []chatMessage        `json:"messages"`
+	MaxTokens     int                  `json:"max_tokens,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type openAIResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+	Error *apiError    `json:"error"`
+}
+
+type anthropicRequest struct {
+	Model     string        `json:"model"`
+	MaxTokens int           `json:"max_tokens"`
+	System    string        `json:"system,omitempty"`
+	Messages  []chatMessage `json:"messages"`
+	Stream    bool          `json:"stream,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage *anthropicUsage `json:"usage"`
+	Error *apiError       `json:"error"`
+}
+
+type anthropicStreamEvent struct {
+	Type    string             `json:"type"`
+	Message *anthropicResponse `json:"message"`
+	Delta   struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"`
+	Error *apiError       `json:"error"`
+}
+
+type apiError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func parseOpenAIResponse(body []byte) (*backend.UnifiedResponse, error) {
+	var resp openAIResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	result := &backend.UnifiedResponse{SessionID: resp.ID, Model: resp.Model}
+	if resp.Error != nil {
+		result.Error = resp.Error.Message
+		return result, nil
+	}
+	if len(resp.Choices) > 0 {
+		result.Content = resp.Choices[0].Message.Content
+	}
+	if resp.Usage != nil {
+		result.Usage = &backend.TokenUsage{
+			InputTokens:  resp.Usage.PromptTokens,
+			OutputTokens: resp.Usage.CompletionTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+		}
+	}
+	return result, nil
+}
+
+func parseAnthropicResponse(body []byte) (*backend.UnifiedResponse, error) {
+	var resp anthropicResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	result := &backend.UnifiedResponse{SessionID: resp.ID, Model: resp.Model}
+	if resp.Error != nil {
+		result.Error = resp.Error.Message
+		return result, nil
+	}
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	result.Content = text.String()
+	if resp.Usage != nil {
+		result.Usage = &backend.TokenUsage{
+			InputTokens:  resp.Usage.InputTokens,
+			OutputTokens: resp.Usage.OutputTokens,
+			TotalTokens:  resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		}
+	}
+	return result, nil
+}
+
+// apiErrorMessage extracts {"error":{"message":...}} from an error body.
+func apiErrorMessage(body []byte) string {
+	var wrapper struct {
+		Error *apiError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &wrapper); err == nil && wrapper.Error != nil {
+		return wrapper.Error.Message
+	}
+	return ""
+}
+
+// httpErrorMessage formats an HTTP error status with its body.
+func httpErrorMessage(status int, body []byte) string {
+	if msg := apiErrorMessage(body); msg != "" {
+		return fmt.Sprintf("HTTP %d: %s", status, msg)
+	}
+	text := strings.TrimSpace(string(body))
+	if text == "" {
+		return fmt.Sprintf("HTTP %d", status)
+	}
+	return fmt.Sprintf("HTTP %d: %s", status, text)
+}
+
+// streamState tracks a streaming response.
+type streamState struct {
+	emit         func(*output.UnifiedEvent) error
+	model        string
+	started      bool
+	failed       bool
+	inputTokens  int64
+	outputTokens int64
+}
+
+func (s *streamState) start(id, model string) error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	if model != "" {
+		s.model = model
+	}
+	return emitEvent(s.emit, output.EventInit, &output.InitContent{Model: s.model, BackendSessionID: id})
+}
+
+func (s *streamState) text(delta string) error {
+	if delta == "" {
+		return nil
+	}
+	return emitEvent(s.emit, output.EventMessage, &output.MessageContent{Text: delta, Role: "assistant", IsPartial: true})
+}
+
+func (s *streamState) fail(msg string) error {
+	s.failed = true
+	return emitEvent(s.emit, output.EventError, &output.ErrorContent{Code: "api_error", Message: msg})
+}
+
+func (s *streamState) done() error {
+	return emitEvent(s.emit, output.EventDone, &output.DoneContent{
+		TokenUsage: &output.TokenUsageContent{InputTokens: s.inputTokens, OutputTokens: s.outputTokens},
+	})
+}
+
+func (s *streamState) handleOpenAI(data []byte) error {
+	var chunk openAIResponse
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil // Skip malformed chunks
+	}
+	if chunk.Error != nil {
+		return s.fail(chunk.Error.Message)
+	}
+	if err := s.start(chunk.ID, chunk.Model); err != nil {
+		return err
+	}
+	if chunk.Usage != nil {
+		s.inputTokens = int64(chunk.Usage.PromptTokens)
+		s.outputTokens = int64(chunk.Usage.CompletionTokens)
+	}
+	if len(chunk.Choices) > 0 {
+		return s.text(chunk.Choices[0].Delta.Content)
+	}
+	return nil
+}
+
+func (s *streamState) handleAnthropic(data []byte) error {
+	var event anthropicStreamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil // Skip malformed events
+	}
+
+	switch event.Type {
+	case "message_start":
+		id, model := "", ""
+		if event.Message != nil {
+			id, model = event.Message.ID, event.Message.Model
+			if event.Message.Usage != nil {
+				s.inputTokens = int64(event.Message.Usage.InputTokens)
+				s.outputTokens = int64(event.Message.Usage.OutputTokens)
+			}
+		}
+		return s.start(id, model)
+	case "content_block_delta":
+		if event.Delta.Type == "text_delta" {
+			return s.text(event.Delta.Text)
+		}
+	case "message_delta":
+		if event.Usage != nil {
+			s.outputTokens = int64(event.Usage.OutputTokens)
+		}
+	case "error":
+		msg := "stream error"
+		if event.Error != nil {
+			msg = event.Error.Message
+		}
+		return s.fail(msg)
+	}
+	return nil
+}
+
+// emitEvent creates an event with content and passes it to emit.
+func emitEvent(emit func(*output.UnifiedEvent) error, eventType output.EventType, content any) error {
+	event := output.NewUnifiedEvent(eventType, "", "")
+	if err := event.SetContent(content); err != nil {
+		return err
+	}
+	return emit(event)
+}
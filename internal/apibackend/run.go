@@ -0,0 +1,116 @@
+package apibackend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/output"
+)
+
+// Main runs the API call subcommand with args (excluding the command name)
+// and returns the process exit code. The API key is read from APIKeyEnvVar
+// and extra headers from HeadersEnvVar.
+//
+// Output depends on --output: "json" prints a backend.UnifiedResponse,
+// "stream-json" prints one output.UnifiedEvent per line, and "text" prints
+// the response text.
+func Main(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(CommandName, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	endpoint := Endpoint{}
+	req := &Request{}
+	format := "json"
+
+	fs.StringVar(&endpoint.Provider, "provider", ProviderOpenAI, "API provider (openai, anthropic)")
+	fs.StringVar(&endpoint.BaseURL, "base-url", "", "API base URL")
+	fs.DurationVar(&endpoint.Timeout, "timeout", 0, "request timeout")
+	fs.StringVar(&req.Model, "model", "", "model to use")
+	fs.StringVar(&req.SystemPrompt, "system-prompt", "", "system prompt")
+	fs.IntVar(&req.MaxTokens, "max-tokens", 0, "maximum response tokens")
+	fs.StringVar(&format, "output", format, "output format (text, json, stream-json)")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	req.Prompt = strings.Join(fs.Args(), " ")
+	endpoint.APIKey = os.Getenv(APIKeyEnvVar)
+
+	if headers := os.Getenv(HeadersEnvVar); headers != "" {
+		if err := json.Unmarshal([]byte(headers), &endpoint.Headers); err != nil {
+			return writeFailure(format, fmt.Sprintf("invalid %s: %v", HeadersEnvVar, err), stdout, stderr)
+		}
+	}
+
+	client, err := NewClient(endpoint)
+	if err == nil && req.Model == "" {
+		err = errors.New("model is required")
+	}
+	if err != nil {
+		return writeFailure(format, err.Error(), stdout, stderr)
+	}
+
+	if format == "stream-json" {
+		enc := json.NewEncoder(stdout)
+		failed := false
+		err := client.Stream(ctx, req, func(event *output.UnifiedEvent) error {
+			if event.Type == output.EventError {
+				failed = true
+			}
+			return enc.Encode(event)
+		})
+		if err != nil {
+			return writeFailure(format, err.Error(), stdout, stderr)
+		}
+		if failed {
+			return 1
+		}
+		return 0
+	}
+
+	resp, err := client.Complete(ctx, req)
+	if err != nil {
+		return writeFailure(format, err.Error(), stdout, stderr)
+	}
+
+	if format == "text" {
+		if resp.Error != "" {
+			fmt.Fprintln(stderr, resp.Error)
+			return 1
+		}
+		fmt.Fprintln(stdout, resp.Content)
+		return 0
+	}
+
+	if err := json.NewEncoder(stdout).Encode(resp); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if resp.Error != "" {
+		return 1
+	}
+	return 0
+}
+
+// writeFailure reports an error in the requested output format.
+func writeFailure(format, msg string, stdout, stderr io.Writer) int {
+	switch format {
+	case "json":
+		_ = json.NewEncoder(stdout).Encode(&backend.UnifiedResponse{Error: msg})
+	case "stream-json":
+		event := output.NewUnifiedEvent(output.EventError, "", "")
+		if err := event.SetContent(&output.ErrorContent{Code: "api_error", Message: msg}); err == nil {
+			_ = json.NewEncoder(stdout).Encode(event)
+		}
+	default:
+		fmt.Fprintln(stderr, msg)
+	}
+	return 1
+}
@@ -3,6 +3,7 @@ package app
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -13,6 +14,7 @@ import (
 	"github.com/signalridge/clinvoker/internal/backend"
 	"github.com/signalridge/clinvoker/internal/config"
 	"github.com/signalridge/clinvoker/internal/session"
+	"github.com/signalridge/clinvoker/internal/structured"
 	"github.com/signalridge/clinvoker/internal/util"
 )
 
@@ -31,6 +33,9 @@ var (
 	outputFormat        string // text, json, stream-json
 	continueLastSession bool   // continue last session
 	ephemeralMode       bool   // stateless mode, no session persisted
+	fallbackBackends    []string
+	jsonSchemaFile      string // JSON schema the reply must match
+	jsonRetries         int    // times to ask again for a reply not matching the schema
 )
 
 var rootCmd = &cobra.Command{
@@ -58,6 +63,9 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output-format", "o", "json", "output format: text, json, stream-json")
 	rootCmd.PersistentFlags().BoolVar(&ephemeralMode, "ephemeral", false, "stateless mode: don't persist session (like standard LLM APIs)")
 	rootCmd.Flags().BoolVarP(&continueLastSession, "continue", "c", false, "continue the last session")
+	rootCmd.Flags().StringSliceVar(&fallbackBackends, "fallback", nil, "backends to try in order if the backend fails (overrides config fallback)")
+	rootCmd.Flags().StringVar(&jsonSchemaFile, "json-schema", "", "JSON schema file the reply must match; the matching JSON is returned in structured")
+	rootCmd.Flags().IntVar(&jsonRetries, "json-retries", 0, "times to ask again for a reply that does not match --json-schema")
 
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(resumeCmd)
@@ -66,12 +74,14 @@ func init() {
 	rootCmd.AddCommand(parallelCmd)
 	rootCmd.AddCommand(compareCmd)
 	rootCmd.AddCommand(chainCmd)
+	rootCmd.AddCommand(apiCallCmd)
 }
 
 func initConfig() {
 	if err := config.Init(cfgFile); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to load config: %v\n", err)
 	}
+	registerConfiguredBackends(config.Get())
 }
 
 // Execute runs the root command.
@@ -97,6 +107,7 @@ type promptContext struct {
 	dryRun      bool
 	userFormat  backend.OutputFormat
 	ephemeral   bool
+	schema      *structured.Schema
 }
 
 // normalizedFlags holds normalized flag values after applying config defaults.
@@ -135,15 +146,34 @@ func preparePromptContext(cmd *cobra.Command, _ string) (*promptContext, error)
 	cfg := config.Get()
 	flags := normalizeFlags(cmd)
 
-	// Validate output format
-	switch backend.OutputFormat(flags.outputFormat) {
+	if err := validateOutputFormat(flags.outputFormat); err != nil {
+		return nil, err
+	}
+	schema, err := loadJSONSchema(flags.outputFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := newPromptContext(cfg, flags, resolveBackendName(cfg), modelName)
+	if err != nil {
+		return nil, err
+	}
+	ctx.schema = schema
+	return ctx, nil
+}
+
+// validateOutputFormat checks a user-supplied output format.
+func validateOutputFormat(format string) error {
+	switch backend.OutputFormat(format) {
 	case backend.OutputDefault, backend.OutputText, backend.OutputJSON, backend.OutputStreamJSON, "":
-		// Valid formats
+		return nil
 	default:
-		return nil, fmt.Errorf("invalid output format %q: must be one of: text, json, stream-json", flags.outputFormat)
+		return fmt.Errorf("invalid output format %q: must be one of: text, json, stream-json", format)
 	}
+}
 
-	// Determine backend
+// resolveBackendName returns the backend selected by flag or config.
+func resolveBackendName(cfg *config.Config) string {
 	bn := backendName
 	if bn == "" {
 		bn = cfg.DefaultBackend
@@ -151,7 +181,11 @@ func preparePromptContext(cmd *cobra.Command, _ string) (*promptContext, error)
 	if bn == "" {
 		bn = "claude"
 	}
+	return bn
+}
 
+// newPromptContext resolves backend bn and builds its execution options.
+func newPromptContext(cfg *config.Config, flags *normalizedFlags, bn, model string) (*promptContext, error) {
 	// Get backend
 	b, err := backend.Get(bn)
 	if err != nil {
@@ -169,12 +203,15 @@ func preparePromptContext(cmd *cobra.Command, _ string) (*promptContext, error)
 	// Build unified options
 	opts := &backend.UnifiedOptions{
 		WorkDir:      workDir,
-		Model:        modelName,
+		Model:        model,
 		OutputFormat: internalFormat,
 		Ephemeral:    ephemeralMode,
 	}
 	applyUnifiedDefaults(opts, cfg, flags.dryRun)
 	applyBackendDefaults(opts, bn, cfg)
+	if err := backend.ValidateMCPServers(opts.MCPServers); err != nil {
+		return nil, fmt.Errorf("config error: %w", err)
+	}
 
 	// Get backend-specific model if not already set
 	if opts.Model == "" {
@@ -228,15 +265,23 @@ func runPrompt(cmd *cobra.Command, args []string) error {
 		// If no sessions found, fall back to creating new session
 	}
 
+	chain := util.FallbackChain(resolveBackendName(cfg), fallbackBackends, cfg)
+	if (len(chain) > 1 || util.ResolveRetryPolicy(nil, cfg).Enabled()) && !normalizeFlags(cmd).dryRun {
+		return runPromptWithFallback(cmd, prompt, chain)
+	}
+
 	ctx, err := preparePromptContext(cmd, prompt)
 	if err != nil {
 		return err
 	}
 
 	// Build command
-	execCmd := ctx.backend.BuildCommandUnified(prompt, ctx.opts)
+	build := func(p string) *exec.Cmd {
+		return ctx.backend.BuildCommandUnified(p, ctx.opts)
+	}
 
 	if ctx.dryRun {
+		execCmd := build(structuredPrompt(prompt, ctx.schema))
 		fmt.Printf("Would execute: %s %v\n", execCmd.Path, execCmd.Args[1:])
 		return nil
 	}
@@ -262,8 +307,9 @@ func runPrompt(cmd *cobra.Command, args []string) error {
 		OutputMode: DetermineOutputMode(ctx.userFormat),
 		Stdin:      true,
 		Timeout:    GetCommandTimeout(),
+		Schema:     ctx.schema,
 	}
-	result, err := ExecuteCommand(execCfg, execCmd)
+	result, err := executePrompt(execCfg, prompt, build)
 
 	// Update session with backend session ID (skip if ephemeral mode)
 	if ctx.sess != nil && ctx.store != nil {
@@ -274,6 +320,9 @@ func runPrompt(cmd *cobra.Command, args []string) error {
 		if saveErr := ctx.store.Save(ctx.sess); saveErr != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to save session: %v\n", saveErr)
 		}
+		if err == nil {
+			recordTurn(ctx.store, ctx.sess, prompt, result)
+		}
 	}
 
 	// Clean up backend session if ephemeral mode
@@ -303,6 +352,11 @@ func runContinueLastSession(_ *cobra.Command, prompt string, flags *normalizedFl
 		return fmt.Errorf("invalid output format %q: must be one of: text, json, stream-json", flags.outputFormat)
 	}
 
+	schema, err := loadJSONSchema(flags.outputFormat)
+	if err != nil {
+		return err
+	}
+
 	store := session.NewStore()
 
 	// Build filter based on flags
@@ -339,6 +393,9 @@ func runContinueLastSession(_ *cobra.Command, prompt string, flags *normalizedFl
 	if !flags.dryRun && !b.IsAvailable() {
 		return fmt.Errorf("backend %q is not available", sess.Backend)
 	}
+	if err := checkResumeSupported(b, cfg); err != nil {
+		return err
+	}
 
 	// Determine output formats (use normalized format)
 	userFormat := backend.OutputFormat(flags.outputFormat)
@@ -352,6 +409,9 @@ func runContinueLastSession(_ *cobra.Command, prompt string, flags *normalizedFl
 	}
 	applyUnifiedDefaults(opts, cfg, flags.dryRun)
 	applyBackendDefaults(opts, sess.Backend, cfg)
+	if err := backend.ValidateMCPServers(opts.MCPServers); err != nil {
+		return fmt.Errorf("config error: %w", err)
+	}
 
 	// Get backend-specific model if not already set
 	if opts.Model == "" {
@@ -367,9 +427,12 @@ func runContinueLastSession(_ *cobra.Command, prompt string, flags *normalizedFl
 	}
 
 	// Build resume command
-	execCmd := b.ResumeCommandUnified(bSessionID, prompt, opts)
+	build := func(p string) *exec.Cmd {
+		return b.ResumeCommandUnified(bSessionID, p, opts)
+	}
 
 	if flags.dryRun {
+		execCmd := build(structuredPrompt(prompt, schema))
 		fmt.Printf("Would continue session %s (%s)\n", shortSessionID(sess.ID), sess.Backend)
 		fmt.Printf("Command: %s %v\n", execCmd.Path, execCmd.Args[1:])
 		return nil
@@ -388,8 +451,9 @@ func runContinueLastSession(_ *cobra.Command, prompt string, flags *normalizedFl
 		OutputMode: DetermineOutputMode(userFormat),
 		Stdin:      true,
 		Timeout:    GetCommandTimeout(),
+		Schema:     schema,
 	}
-	result, err := ExecuteCommand(execCfg, execCmd)
+	result, err := executePrompt(execCfg, prompt, build)
 
 	// Persist session updates (including backend session ID) after execution.
 	if result != nil && sess != nil {
@@ -400,6 +464,9 @@ func runContinueLastSession(_ *cobra.Command, prompt string, flags *normalizedFl
 		if saveErr := store.Save(sess); saveErr != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to save session: %v\n", saveErr)
 		}
+		if err == nil {
+			recordTurn(store, sess, prompt, result)
+		}
 	}
 
 	if err != nil {
@@ -539,4 +606,10 @@ type PromptResult struct {
 	Error     string              `json:"error,omitempty"`
 	Usage     *backend.TokenUsage `json:"usage,omitempty"`
 	Raw       map[string]any      `json:"raw,omitempty"`
+
+	// Structured is the reply JSON validated against --json-schema.
+	Structured json.RawMessage `json:"structured,omitempty"`
+
+	// Attempts lists every backend tried when a fallback chain is in effect.
+	Attempts []util.FallbackAttempt `json:"attempts,omitempty"`
 }
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/apibackend"
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/output"
+	"github.com/signalridge/clinvoker/internal/plugin"
+)
+
+// registerConfiguredBackends registers backends declared in config and
+// plugins found in the plugins directory alongside the built-in ones.
+// Invalid declarations are reported and skipped.
+func registerConfiguredBackends(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+
+	for _, name := range config.DeclaredBackends(cfg) {
+		bc := cfg.Backends[name]
+		if !bc.IsBackendEnabled() {
+			continue
+		}
+
+		switch bc.Type {
+		case config.BackendTypeGeneric:
+			g, err := backend.NewGeneric(genericSpecFromConfig(name, &bc.Generic))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: skipping backend %q: %v\n", name, err)
+				continue
+			}
+			registerGeneric(g, bc.Generic.AllowedFlags)
+		case config.BackendTypeHTTP:
+			b, err := apibackend.NewBackend(apiSpecFromConfig(name, &bc.HTTP))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: skipping backend %q: %v\n", name, err)
+				continue
+			}
+			backend.Register(b)
+			output.RegisterLineDecoder(name, b.DecodeStreamLine)
+		default:
+			fmt.Fprintf(os.Stderr, "Warning: skipping backend %q: unknown type %q\n", name, bc.Type)
+		}
+	}
+
+	registerPlugins(config.PluginsDir(), cfg)
+	registerModelCatalog(cfg)
+}
+
+// registerModelCatalog applies the models, aliases and prefixes declared
+// for each backend to the model catalog.
+func registerModelCatalog(cfg *config.Config) {
+	for name, bc := range cfg.Backends {
+		if bc.Models == nil && len(bc.ModelAliases) == 0 && bc.ModelPrefixes == nil {
+			continue
+		}
+		var models []backend.ModelInfo
+		if bc.Models != nil {
+			models = make([]backend.ModelInfo, len(bc.Models))
+			for i, m := range bc.Models {
+				models[i] = backend.ModelInfo{
+					ID:              m.ID,
+					DisplayName:     m.DisplayName,
+					ContextWindow:   m.ContextWindow,
+					MaxOutputTokens: m.MaxOutputTokens,
+					InputPrice:      m.InputPrice,
+					OutputPrice:     m.OutputPrice,
+				}
+			}
+		}
+		backend.ConfigureModels(name, models, bc.ModelAliases, bc.ModelPrefixes)
+	}
+}
+
+// registerPlugins registers the plugin executables found in dir.
+// Plugins never replace a backend that is already registered, and a plugin
+// can be turned off with backends.<name>.enabled: false.
+func registerPlugins(dir string, cfg *config.Config) {
+	plugins, err := plugin.Discover(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to scan plugins in %s: %v\n", dir, err)
+		return
+	}
+
+	for _, p := range plugins {
+		if bc, ok := cfg.Backends[p.Name]; ok && !bc.IsBackendEnabled() {
+			continue
+		}
+		if _, err := backend.Get(p.Name); err == nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping plugin %s: backend %q already exists\n", p.Path, p.Name)
+			continue
+		}
+
+		b := plugin.NewBackend(p.Name, p.Path)
+		backend.Register(b)
+		output.RegisterLineDecoder(p.Name, b.DecodeStreamLine)
+	}
+}
+
+// registerGeneric registers a generic backend with its flag allowlist and stream decoder.
+func registerGeneric(g *backend.Generic, allowedFlags []string) {
+	backend.Register(g)
+	backend.RegisterAllowedFlags(g.Name(), allowedFlags)
+	output.RegisterLineDecoder(g.Name(), g.DecodeStreamLine)
+}
+
+// genericSpecFromConfig converts a generic backend declaration to a backend spec.
+func genericSpecFromConfig(name string, gc *config.GenericBackendConfig) backend.GenericSpec {
+	return backend.GenericSpec{
+		Name:              name,
+		Command:           gc.Command,
+		Args:              gc.Args,
+		PromptArgs:        gc.PromptArgs,
+		ResumeArgs:        gc.ResumeArgs,
+		ModelArgs:         gc.ModelArgs,
+		SystemPromptArgs:  gc.SystemPromptArgs,
+		MaxTurnsArgs:      gc.MaxTurnsArgs,
+		ApprovalFlags:     gc.ApprovalFlags,
+		SandboxFlags:      gc.SandboxFlags,
+		OutputFormatFlags: gc.OutputFormatFlags,
+		VerboseFlags:      gc.VerboseFlags,
+		EphemeralFlags:    gc.EphemeralFlags,
+		Env:               gc.Env,
+		SeparateStderr:    gc.SeparateStderr,
+		Output: backend.GenericOutputSpec{
+			Format:           gc.Output.Format,
+			ContentPath:      gc.Output.ContentPath,
+			SessionIDPath:    gc.Output.SessionIDPath,
+			ErrorPath:        gc.Output.ErrorPath,
+			ModelPath:        gc.Output.ModelPath,
+			InputTokensPath:  gc.Output.InputTokensPath,
+			OutputTokensPath: gc.Output.OutputTokensPath,
+			ContentRegex:     gc.Output.ContentRegex,
+			SessionIDRegex:   gc.Output.SessionIDRegex,
+		},
+	}
+}
+
+// apiSpecFromConfig converts an HTTP backend declaration to an API backend spec.
+func apiSpecFromConfig(name string, hc *config.HTTPBackendConfig) apibackend.Spec {
+	return apibackend.Spec{
+		Name:      name,
+		Provider:  hc.Provider,
+		BaseURL:   hc.BaseURL,
+		APIKey:    hc.APIKey,
+		APIKeyEnv: hc.APIKeyEnv,
+		Headers:   hc.Headers,
+		Timeout:   time.Duration(hc.TimeoutSecs) * time.Second,
+		MaxTokens: hc.MaxTokens,
+	}
+}
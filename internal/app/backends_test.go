@@ -0,0 +1,143 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/output"
+)
+
+func TestRegisterConfiguredBackends(t *testing.T) {
+	disabled := false
+	cfg := &config.Config{
+		Backends: map[string]config.BackendConfig{
+			"test-generic": {
+				Type: config.BackendTypeGeneric,
+				Generic: config.GenericBackendConfig{
+					Command:      "test-generic-cli",
+					AllowedFlags: []string{"--cache"},
+				},
+			},
+			"test-disabled": {
+				Type:    config.BackendTypeGeneric,
+				Enabled: &disabled,
+				Generic: config.GenericBackendConfig{Command: "x"},
+			},
+			"test-invalid": {
+				Type: config.BackendTypeGeneric,
+			},
+			"test-http": {
+				Type: config.BackendTypeHTTP,
+				HTTP: config.HTTPBackendConfig{Provider: "openai", BaseURL: "http://localhost:11434/v1"},
+			},
+		},
+	}
+
+	registerConfiguredBackends(cfg)
+	t.Cleanup(func() {
+		for _, name := range []string{"test-generic", "test-disabled", "test-invalid", "test-http"} {
+			backend.Unregister(name)
+			backend.UnregisterAllowedFlags(name)
+			output.UnregisterLineDecoder(name)
+		}
+	})
+
+	b, err := backend.Get("test-generic")
+	if err != nil {
+		t.Fatalf("expected generic backend to be registered: %v", err)
+	}
+	if b.Name() != "test-generic" {
+		t.Errorf("expected name 'test-generic', got %q", b.Name())
+	}
+	if err := backend.ValidateExtraFlagsForBackend("test-generic", []string{"--cache"}); err != nil {
+		t.Errorf("expected configured flag to be allowed: %v", err)
+	}
+
+	event, err := output.NewParser("test-generic", "").ParseLine("hello")
+	if err != nil || event == nil || event.Type != output.EventMessage {
+		t.Errorf("expected message event from stream decoder, got %+v, %v", event, err)
+	}
+
+	if _, err := backend.Get("test-http"); err != nil {
+		t.Errorf("expected http backend to be registered: %v", err)
+	}
+
+	for _, name := range []string{"test-disabled", "test-invalid"} {
+		if _, err := backend.Get(name); err == nil {
+			t.Errorf("expected backend %q to be skipped", name)
+		}
+	}
+}
+
+func TestRegisterModelCatalog(t *testing.T) {
+	t.Cleanup(backend.ResetModelCatalog)
+
+	registerModelCatalog(&config.Config{
+		Backends: map[string]config.BackendConfig{
+			"claude": {
+				Models:       []config.ModelConfig{{ID: "claude-custom", ContextWindow: 200000, InputPrice: 3}},
+				ModelAliases: map[string]string{"fast": "claude-custom"},
+			},
+		},
+	})
+
+	models := backend.Models("claude")
+	if len(models) != 1 || models[0].ID != "claude-custom" || models[0].Backend != "claude" {
+		t.Fatalf("unexpected models: %+v", models)
+	}
+	if models[0].ContextWindow != 200000 || models[0].InputPrice != 3 {
+		t.Errorf("expected metadata to be kept, got %+v", models[0])
+	}
+	if got := backend.ResolveModelAlias("claude", "fast"); got != "claude-custom" {
+		t.Errorf("fast = %q, want claude-custom", got)
+	}
+	if got := backend.ResolveModelAlias("claude", "best"); got != "opus" {
+		t.Errorf("expected built-in alias to remain, got %q", got)
+	}
+	if got := backend.Models("codex"); len(got) == 0 {
+		t.Error("expected unconfigured backends to keep built-in models")
+	}
+}
+
+func TestGenericSpecFromConfig(t *testing.T) {
+	spec := genericSpecFromConfig("aider", &config.GenericBackendConfig{
+		Command:    "aider",
+		PromptArgs: []string{"--message", "{{prompt}}"},
+		Output: config.GenericOutputConfig{
+			Format:      "json",
+			ContentPath: "text",
+		},
+	})
+
+	if spec.Name != "aider" || spec.Command != "aider" {
+		t.Errorf("unexpected spec identity: %+v", spec)
+	}
+	if len(spec.PromptArgs) != 2 {
+		t.Errorf("expected prompt args to be copied, got %v", spec.PromptArgs)
+	}
+	if spec.Output.Format != "json" || spec.Output.ContentPath != "text" {
+		t.Errorf("unexpected output spec: %+v", spec.Output)
+	}
+}
+
+func TestAPISpecFromConfig(t *testing.T) {
+	spec := apiSpecFromConfig("local", &config.HTTPBackendConfig{
+		Provider:    "anthropic",
+		BaseURL:     "http://localhost:8080/v1",
+		APIKeyEnv:   "LOCAL_KEY",
+		TimeoutSecs: 90,
+		MaxTokens:   1024,
+	})
+
+	if spec.Name != "local" || spec.Provider != "anthropic" || spec.BaseURL != "http://localhost:8080/v1" {
+		t.Errorf("unexpected spec identity: %+v", spec)
+	}
+	if spec.APIKeyEnv != "LOCAL_KEY" || spec.MaxTokens != 1024 {
+		t.Errorf("unexpected spec settings: %+v", spec)
+	}
+	if spec.Timeout != 90*time.Second {
+		t.Errorf("expected 90s timeout, got %v", spec.Timeout)
+	}
+}
@@ -0,0 +1,25 @@
+package app
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/signalridge/clinvoker/internal/apibackend"
+)
+
+// apiCallCmd performs a single HTTP API call on behalf of an API backend.
+// It is an internal entry point and is not meant to be run by users.
+var apiCallCmd = &cobra.Command{
+	Use:                apibackend.CommandName,
+	Short:              "Call an HTTP API backend (internal)",
+	Hidden:             true,
+	DisableFlagParsing: true,
+	SilenceUsage:       true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if code := apibackend.Main(cmd.Context(), args, os.Stdout, os.Stderr); code != 0 {
+			os.Exit(code)
+		}
+		return nil
+	},
+}
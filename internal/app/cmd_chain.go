@@ -214,6 +214,16 @@ func executeChainStep(index int, step *ChainStep, chain *ChainDefinition, ctx *c
 		return result
 	}
 
+	requested := &backend.UnifiedOptions{
+		ApprovalMode: backend.ApprovalMode(step.ApprovalMode),
+		SandboxMode:  backend.SandboxMode(step.SandboxMode),
+		MaxTurns:     step.MaxTurns,
+	}
+	if err := checkUnsupported(b, backend.CheckOptions(b.Capabilities(), requested), ctx.cfg); err != nil {
+		failStepResult(&result, startTime, err.Error())
+		return result
+	}
+
 	// Prepare execution context
 	prompt := substitutePromptPlaceholders(step.Prompt, ctx.previousOutput, ctx.hasPreviousOutput)
 	stepWorkDir := resolveStepWorkDir(step.WorkDir, chain.PassWorkingDir, ctx.previousWorkDir)
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/signalridge/clinvoker/internal/mcp"
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+// mcpCmd serves clinvk as MCP tools over stdio.
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve clinvk as MCP tools over stdio",
+	Long: `Serve clinvk to other agents over the Model Context Protocol (MCP).
+
+The server reads JSON-RPC messages from stdin and writes them to stdout, as
+MCP clients expect of servers they start. It offers these tools:
+
+  prompt          - Run a prompt on a backend
+  parallel        - Run several prompts at once
+  chain           - Run prompts one after another
+  compare         - Run a prompt on several backends
+  list_sessions   - List sessions
+  resume_session  - Continue a session with a new prompt
+
+Tool calls that ask for progress are sent progress notifications while
+they run. Logs are written to stderr.
+
+Example client configuration:
+  {"mcpServers": {"clinvk": {"command": "clinvk", "args": ["mcp"]}}}`,
+	Args: cobra.NoArgs,
+	RunE: runMCP,
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+}
+
+func runMCP(cmd *cobra.Command, args []string) error {
+	// Stdout carries the protocol, so logs go to stderr
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	}))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := mcp.NewServer(service.NewExecutorWithLogger(logger), version, logger)
+	return srv.Serve(ctx, cmd.InOrStdin(), cmd.OutOrStdout())
+}
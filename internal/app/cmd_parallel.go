@@ -331,6 +331,18 @@ func executeParallelTask(idx int, t *ParallelTask, pCtx *parallelContext) TaskRe
 		return result
 	}
 
+	requested := &backend.UnifiedOptions{
+		ApprovalMode: backend.ApprovalMode(t.ApprovalMode),
+		SandboxMode:  backend.SandboxMode(t.SandboxMode),
+		MaxTokens:    t.MaxTokens,
+		MaxTurns:     t.MaxTurns,
+		SystemPrompt: t.SystemPrompt,
+	}
+	if err := checkUnsupported(b, backend.CheckOptions(b.Capabilities(), requested), pCtx.cfg); err != nil {
+		failTaskResult(&result, startTime, err.Error())
+		return result
+	}
+
 	// Build unified options
 	opts := buildParallelTaskOptions(t, pCtx.cfg)
 
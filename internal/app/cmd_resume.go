@@ -120,6 +120,9 @@ func runResume(cmd *cobra.Command, args []string) error {
 	if !dryRun && !b.IsAvailable() {
 		return fmt.Errorf("backend %q is not available", sess.Backend)
 	}
+	if err := checkResumeSupported(b, cfg); err != nil {
+		return err
+	}
 
 	// Determine output formats
 	userFormat := backend.OutputFormat(outputFormat)
@@ -176,6 +179,9 @@ func runResume(cmd *cobra.Command, args []string) error {
 		if saveErr := store.Save(sess); saveErr != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to save session: %v\n", saveErr)
 		}
+		if err == nil {
+			recordTurn(store, sess, prompt, result)
+		}
 	}
 
 	if err != nil {
@@ -13,8 +13,6 @@ import (
 
 	"github.com/signalridge/clinvoker/internal/config"
 	"github.com/signalridge/clinvoker/internal/server"
-	"github.com/signalridge/clinvoker/internal/server/handlers"
-	"github.com/signalridge/clinvoker/internal/server/service"
 )
 
 // serveCmd starts the HTTP server.
@@ -23,7 +21,7 @@ var serveCmd = &cobra.Command{
 	Short: "Start the HTTP API server",
 	Long: `Start an HTTP server that exposes AI backends as APIs.
 
-The server provides three distinct API styles:
+The server provides four distinct API styles:
 
   1. Custom RESTful API (/api/v1/*)
      Full-featured API with all clinvk capabilities:
@@ -38,31 +36,45 @@ The server provides three distinct API styles:
      Drop-in replacement for OpenAI API:
      - GET  /openai/v1/models           - List available models
      - POST /openai/v1/chat/completions - Create chat completion
+     - POST /openai/v1/responses        - Create response
 
   3. Anthropic Compatible API (/anthropic/v1/*)
      Drop-in replacement for Anthropic API:
+     - GET  /anthropic/v1/models        - List available models
      - POST /anthropic/v1/messages      - Create message
 
+  4. Gemini Compatible API (/gemini/v1beta/*)
+     Drop-in replacement for the Gemini API:
+     - GET  /gemini/v1beta/models                         - List available models
+     - POST /gemini/v1beta/models/{model}:generateContent - Generate content
+
+With --mcp, clinvk's tools are also served to MCP clients over the
+streamable HTTP transport at /mcp (see clinvk mcp).
+
 Configuration (in ~/.clinvk/config.yaml):
   server:
     host: "0.0.0.0"    # Bind to all interfaces
     port: 8080         # Listen port
+    mcp_enabled: true  # Serve /mcp
 
 Examples:
   clinvk serve
   clinvk serve --port 8080
-  clinvk serve --host 0.0.0.0 --port 3000`,
+  clinvk serve --host 0.0.0.0 --port 3000
+  clinvk serve --mcp`,
 	RunE: runServe,
 }
 
 var (
 	serveHost string
 	servePort int
+	serveMCP  bool
 )
 
 func init() {
 	serveCmd.Flags().StringVar(&serveHost, "host", "", "host to bind to (default from config or 127.0.0.1)")
 	serveCmd.Flags().IntVarP(&servePort, "port", "p", 0, "port to listen on (default from config or 8080)")
+	serveCmd.Flags().BoolVar(&serveMCP, "mcp", false, "serve the MCP streamable HTTP transport at /mcp (default from config)")
 
 	rootCmd.AddCommand(serveCmd)
 }
@@ -97,22 +109,14 @@ func runServe(cmd *cobra.Command, args []string) error {
 	cfg := server.Config{
 		Host: host,
 		Port: port,
+		MCP:  serveMCP || appCfg.Server.MCPEnabled,
 	}
 
 	// Create server
 	srv := server.New(cfg, logger)
 
 	// Register routes
-	customHandlers := handlers.NewCustomHandlers(srv.Executor())
-	customHandlers.Register(srv.API())
-
-	// Register OpenAI compatible handlers (stateless)
-	openaiHandlers := handlers.NewOpenAIHandlers(service.NewStatelessRunner(srv.Logger()), srv.Logger())
-	openaiHandlers.Register(srv.API())
-
-	// Register Anthropic compatible handlers (stateless)
-	anthropicHandlers := handlers.NewAnthropicHandlers(service.NewStatelessRunner(srv.Logger()), srv.Logger())
-	anthropicHandlers.Register(srv.API())
+	srv.RegisterRoutes()
 
 	// Set up graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -131,8 +135,12 @@ func runServe(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	fmt.Println("Available endpoints:")
 	fmt.Println("  Custom API:     /api/v1/prompt, /api/v1/parallel, /api/v1/chain, /api/v1/compare")
-	fmt.Println("  OpenAI:         /openai/v1/models, /openai/v1/chat/completions")
-	fmt.Println("  Anthropic:      /anthropic/v1/messages")
+	fmt.Println("  OpenAI:         /openai/v1/models, /openai/v1/chat/completions, /openai/v1/responses")
+	fmt.Println("  Anthropic:      /anthropic/v1/models, /anthropic/v1/messages")
+	fmt.Println("  Gemini:         /gemini/v1beta/models")
+	if cfg.MCP {
+		fmt.Println("  MCP:            /mcp")
+	}
 	fmt.Println("  Docs:           /openapi.json")
 	fmt.Println("  Health:         /health")
 	fmt.Println()
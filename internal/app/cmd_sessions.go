@@ -2,6 +2,8 @@ package app
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 
@@ -86,9 +88,12 @@ func init() {
 	sessionsListCmd.Flags().IntVarP(&listLimit, "limit", "n", 0, "limit number of sessions shown")
 }
 
+var showTranscript bool
+
 var sessionsShowCmd = &cobra.Command{
 	Use:   "show <session-id>",
 	Short: "Show session details",
+	Long:  "Show session details. With --transcript, also show the prompt and response of every recorded turn.",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		store := session.NewStore()
@@ -153,10 +158,55 @@ var sessionsShowCmd = &cobra.Command{
 			}
 		}
 
+		if showTranscript {
+			turns, err := store.Transcript(sess.ID)
+			if err != nil {
+				return err
+			}
+			printTranscript(os.Stdout, turns)
+		}
+
 		return nil
 	},
 }
 
+func init() {
+	sessionsShowCmd.Flags().BoolVar(&showTranscript, "transcript", false, "show the prompts and responses of the session")
+}
+
+// printTranscript writes the turns of a session transcript for reading.
+func printTranscript(w io.Writer, turns []session.Turn) {
+	fmt.Fprintln(w)
+	if len(turns) == 0 {
+		fmt.Fprintln(w, "No transcript recorded.")
+		return
+	}
+
+	for _, turn := range turns {
+		fmt.Fprintf(w, "=== Turn %d (%s) ===\n", turn.Turn, turn.StartedAt.Local().Format(time.RFC3339))
+		fmt.Fprintf(w, "> %s\n", strings.ReplaceAll(strings.TrimRight(turn.Prompt, "\n"), "\n", "\n> "))
+		fmt.Fprintln(w)
+		for _, call := range turn.ToolCalls {
+			status := ""
+			if call.IsError {
+				status = " (failed)"
+			}
+			fmt.Fprintf(w, "[tool] %s%s\n", call.Name, status)
+		}
+		if turn.Response != "" {
+			fmt.Fprintln(w, strings.TrimRight(turn.Response, "\n"))
+		}
+		if turn.Error != "" {
+			fmt.Fprintf(w, "Error (exit %d): %s\n", turn.ExitCode, turn.Error)
+		}
+		if turn.TokenUsage != nil {
+			fmt.Fprintf(w, "Tokens: %d (input: %d, output: %d)\n",
+				turn.TokenUsage.Total(), turn.TokenUsage.InputTokens, turn.TokenUsage.OutputTokens)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
 var sessionsDeleteCmd = &cobra.Command{
 	Use:   "delete <session-id>",
 	Short: "Delete a session",
@@ -1,9 +1,14 @@
 package app
 
 import (
+	"bytes"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/signalridge/clinvoker/internal/session"
 )
 
 func TestSessionsCmd_Structure(t *testing.T) {
@@ -425,3 +430,60 @@ func TestSessionsCleanCmd_IsInitialized(t *testing.T) {
 		t.Fatal("sessionsCleanCmd should not be nil")
 	}
 }
+
+func TestSessionsShowCmd_TranscriptFlag(t *testing.T) {
+	flag := sessionsShowCmd.Flags().Lookup("transcript")
+	if flag == nil {
+		t.Fatal("flag \"transcript\" not found")
+	}
+	if flag.DefValue != "false" {
+		t.Errorf("flag \"transcript\" default value = %q, want %q", flag.DefValue, "false")
+	}
+}
+
+func TestPrintTranscript(t *testing.T) {
+	tests := []struct {
+		name  string
+		turns []session.Turn
+		want  []string
+	}{
+		{
+			name: "no turns",
+			want: []string{"No transcript recorded."},
+		},
+		{
+			name: "turns",
+			turns: []session.Turn{
+				{
+					Turn:       1,
+					StartedAt:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+					Prompt:     "fix the\nparser",
+					Response:   "Fixed.",
+					ToolCalls:  []session.ToolCall{{Name: "Edit"}, {Name: "Bash", IsError: true}},
+					TokenUsage: &session.TokenUsage{InputTokens: 10, OutputTokens: 5},
+				},
+				{Turn: 2, Prompt: "again", ExitCode: 1, Error: "rate limited"},
+			},
+			want: []string{
+				"=== Turn 1 (",
+				"> fix the\n> parser",
+				"[tool] Edit\n[tool] Bash (failed)\nFixed.",
+				"Tokens: 15 (input: 10, output: 5)",
+				"=== Turn 2 (",
+				"Error (exit 1): rate limited",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printTranscript(&buf, tt.turns)
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
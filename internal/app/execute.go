@@ -10,12 +10,15 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/signalridge/clinvoker/internal/backend"
 	"github.com/signalridge/clinvoker/internal/config"
 	"github.com/signalridge/clinvoker/internal/output"
 	"github.com/signalridge/clinvoker/internal/session"
+	"github.com/signalridge/clinvoker/internal/structured"
+	"github.com/signalridge/clinvoker/internal/util"
 )
 
 // OutputMode controls how execution output is handled.
@@ -38,6 +41,14 @@ type ExecutionResult struct {
 	Error           string
 	Response        *backend.UnifiedResponse
 	DurationSeconds float64
+
+	// ToolCalls lists the tools the backend used, in stream mode.
+	ToolCalls []session.ToolCall
+
+	// Structured is the reply JSON, when it matches the JSON schema.
+	Structured json.RawMessage
+	// SchemaError is why the reply does not match the JSON schema.
+	SchemaError error
 }
 
 // ExecutionConfig holds configuration for command execution.
@@ -47,6 +58,19 @@ type ExecutionConfig struct {
 	OutputMode OutputMode
 	Stdin      bool          // Whether to connect stdin
 	Timeout    time.Duration // Command timeout (0 = no timeout)
+
+	// Discard, if set, is called with the finished result before its output
+	// is written. Returning true drops the output so the caller can retry on
+	// another backend. In stream mode output is held until the first content
+	// event, after which the result can no longer be discarded.
+	Discard func(*ExecutionResult) bool
+
+	// Attempts lists earlier fallback attempts to include in JSON output.
+	Attempts []util.FallbackAttempt
+
+	// Schema, if set, is the JSON schema the reply must match. A reply that
+	// does not fails the run. Stream mode does not validate replies.
+	Schema *structured.Schema
 }
 
 // ErrCommandTimeout is returned when a command exceeds its timeout.
@@ -63,23 +87,42 @@ func ExecuteCommand(cfg *ExecutionConfig, cmd *exec.Cmd) (*ExecutionResult, erro
 		defer cancel()
 	}
 
+	// Fail fast while the backend's circuit is open
+	call, err := util.StartBackendCall(cfg.Backend.Name())
+	if err != nil {
+		result := &ExecutionResult{ExitCode: 1, Error: err.Error()}
+		if cfg.Discard != nil {
+			cfg.Discard(result)
+		}
+		return result, err
+	}
+
+	var result *ExecutionResult
 	switch cfg.OutputMode {
 	case OutputModeStream:
-		return executeStream(ctx, cfg.Backend, cmd, cfg.Session, cfg.Stdin)
+		result, err = executeStream(ctx, cfg, cmd)
 	case OutputModeJSON:
-		return executeWithCapture(ctx, cfg.Backend, cmd, cfg.Session, true, cfg.Stdin)
+		result, err = executeWithCapture(ctx, cfg, cmd, true)
 	case OutputModeText:
-		return executeWithCapture(ctx, cfg.Backend, cmd, cfg.Session, false, cfg.Stdin)
+		result, err = executeWithCapture(ctx, cfg, cmd, false)
 	default:
-		return executeWithCapture(ctx, cfg.Backend, cmd, cfg.Session, false, cfg.Stdin)
+		result, err = executeWithCapture(ctx, cfg, cmd, false)
+	}
+
+	if err != nil && !errors.Is(err, ErrCommandTimeout) {
+		call.Done(1, err.Error())
+	} else {
+		call.Done(result.ExitCode, result.Error)
 	}
+	return result, err
 }
 
 // executeStream executes a command with direct stream output.
-func executeStream(ctx context.Context, b backend.Backend, cmd *exec.Cmd, sess *session.Session, useStdin bool) (*ExecutionResult, error) {
+func executeStream(ctx context.Context, cfg *ExecutionConfig, cmd *exec.Cmd) (*ExecutionResult, error) {
 	startTime := time.Now()
+	b, sess := cfg.Backend, cfg.Session
 
-	if useStdin {
+	if cfg.Stdin {
 		cmd.Stdin = os.Stdin
 	}
 
@@ -99,11 +142,12 @@ func executeStream(ctx context.Context, b backend.Backend, cmd *exec.Cmd, sess *
 		return &ExecutionResult{ExitCode: 1}, err
 	}
 
-	// Monitor context for timeout
-	waitDone := make(chan error, 1)
-	go func() {
-		waitDone <- cmd.Wait()
-	}()
+	// Kill the command on timeout. Its output is read to the end before
+	// waiting for it, as waiting closes the pipe.
+	stopKill := context.AfterFunc(ctx, func() {
+		_ = cmd.Process.Kill()
+	})
+	defer stopKill()
 
 	parser := output.NewParser(b.Name(), "")
 	if sess != nil {
@@ -118,7 +162,27 @@ func executeStream(ctx context.Context, b backend.Backend, cmd *exec.Cmd, sess *
 	var backendSessionID string
 	var tokenUsage *backend.TokenUsage
 	var streamErr error
+	var turn util.TurnRecorder
 	timedOut := false
+	abandoned := false
+
+	// Hold lines back until the first content event while the result may
+	// still be discarded.
+	var held []string
+	holding := cfg.Discard != nil
+	release := func(result *ExecutionResult) bool {
+		if !holding {
+			return false
+		}
+		holding = false
+		if cfg.Discard(result) {
+			return true
+		}
+		for _, line := range held {
+			fmt.Fprintln(os.Stdout, line)
+		}
+		return false
+	}
 
 scanLoop:
 	for scanner.Scan() {
@@ -132,15 +196,30 @@ scanLoop:
 		}
 
 		line := scanner.Text()
-		if _, err := fmt.Fprintln(os.Stdout, line); err != nil {
+		event, parseErr := parser.ParseLine(line)
+		if parseErr != nil {
+			event = nil
+		}
+
+		if holding && event != nil && event.Type.IsContent() {
+			holding = false
+			for _, h := range held {
+				fmt.Fprintln(os.Stdout, h)
+			}
+			held = nil
+		}
+		if holding {
+			held = append(held, line)
+		} else if _, err := fmt.Fprintln(os.Stdout, line); err != nil {
 			streamErr = err
+			abandoned = true
 			break scanLoop
 		}
 
-		event, parseErr := parser.ParseLine(line)
-		if parseErr != nil || event == nil {
+		if event == nil {
 			continue
 		}
+		turn.Add(event)
 
 		switch event.Type {
 		case output.EventInit:
@@ -162,33 +241,31 @@ scanLoop:
 	}
 
 	if scanErr := scanner.Err(); scanErr != nil && streamErr == nil && !timedOut {
-		// Ignore "file already closed" error which can occur due to race
-		// between scanner reading and cmd.Wait() closing the pipe
-		if !errors.Is(scanErr, os.ErrClosed) && scanErr.Error() != "read |0: file already closed" {
-			streamErr = scanErr
-		}
+		streamErr = scanErr
+		abandoned = true
+	}
+	if abandoned {
+		// Unblock a command still writing to the pipe no longer read
+		_ = cmd.Process.Kill()
 	}
 
 	// Wait for command to finish or timeout
-	var waitErr error
-	select {
-	case waitErr = <-waitDone:
-		// Command finished
-	case <-ctx.Done():
-		// Context canceled (timeout)
-		_ = cmd.Process.Kill()
-		<-waitDone // Wait for process to exit after kill
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
 		timedOut = true
 	}
 
 	result := &ExecutionResult{
 		DurationSeconds: time.Since(startTime).Seconds(),
 		SessionID:       backendSessionID,
+		Content:         turn.Response(),
+		ToolCalls:       turn.ToolCalls(),
 	}
 
 	if timedOut {
 		result.ExitCode = 124 // Standard timeout exit code
 		result.Error = ErrCommandTimeout.Error()
+		release(result)
 		return result, ErrCommandTimeout
 	}
 
@@ -219,6 +296,10 @@ scanLoop:
 	}
 	result.Response = resp
 
+	if release(result) {
+		return result, nil
+	}
+
 	if sess != nil {
 		updateSessionFromResponse(sess, result.ExitCode, result.Error, resp)
 	}
@@ -231,8 +312,9 @@ scanLoop:
 }
 
 // executeWithCapture executes a command and captures output.
-func executeWithCapture(ctx context.Context, b backend.Backend, cmd *exec.Cmd, sess *session.Session, outputJSON, useStdin bool) (*ExecutionResult, error) {
+func executeWithCapture(ctx context.Context, cfg *ExecutionConfig, cmd *exec.Cmd, outputJSON bool) (*ExecutionResult, error) {
 	startTime := time.Now()
+	b, sess := cfg.Backend, cfg.Session
 
 	var stdoutBuf, stderrBuf bytes.Buffer
 	cmd.Stdout = &stdoutBuf
@@ -242,7 +324,7 @@ func executeWithCapture(ctx context.Context, b backend.Backend, cmd *exec.Cmd, s
 		cmd.Stderr = &stdoutBuf
 	}
 
-	if useStdin {
+	if cfg.Stdin {
 		cmd.Stdin = os.Stdin
 	}
 
@@ -276,6 +358,9 @@ func executeWithCapture(ctx context.Context, b backend.Backend, cmd *exec.Cmd, s
 	if timedOut {
 		result.ExitCode = 124 // Standard timeout exit code
 		result.Error = ErrCommandTimeout.Error()
+		if cfg.Discard != nil {
+			cfg.Discard(result)
+		}
 		return result, ErrCommandTimeout
 	}
 
@@ -310,11 +395,26 @@ func executeWithCapture(ctx context.Context, b backend.Backend, cmd *exec.Cmd, s
 		result.Content = b.ParseOutput(rawOutput)
 	}
 
-	// Capture process-level error if no response error was set
+	// Capture stderr, then the process-level error, if no response error was set
+	if result.Error == "" && result.ExitCode != 0 {
+		result.Error = strings.TrimSpace(stderrBuf.String())
+	}
 	if result.Error == "" && errMsg != "" {
 		result.Error = errMsg
 	}
 
+	if cfg.Schema != nil && result.ExitCode == 0 {
+		result.Structured, result.SchemaError = cfg.Schema.Extract(result.Content)
+		if result.SchemaError != nil {
+			result.ExitCode = 1
+			result.Error = result.SchemaError.Error()
+		}
+	}
+
+	if cfg.Discard != nil && cfg.Discard(result) {
+		return result, nil
+	}
+
 	// Update session if available
 	if sess != nil {
 		updateSessionFromResponse(sess, result.ExitCode, errMsg, resp)
@@ -322,7 +422,7 @@ func executeWithCapture(ctx context.Context, b backend.Backend, cmd *exec.Cmd, s
 
 	// Output based on mode
 	if outputJSON {
-		if err := outputJSONResult(b, result, sess); err != nil {
+		if err := outputJSONResult(b, result, sess, cfg.Attempts); err != nil {
 			// Set exit code to indicate encoding failure if not already set
 			if result.ExitCode == 0 {
 				result.ExitCode = 1
@@ -335,13 +435,19 @@ func executeWithCapture(ctx context.Context, b backend.Backend, cmd *exec.Cmd, s
 	return result, nil
 }
 
-// outputTextResult outputs the result as plain text.
+// outputTextResult outputs the result as plain text. A reply matching the
+// JSON schema is output as the JSON alone.
 func outputTextResult(b backend.Backend, result *ExecutionResult) {
 	if result.Response != nil && result.Response.Error != "" {
 		fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", b.Name(), result.Response.Error)
 	}
+	if result.SchemaError != nil {
+		fmt.Fprintf(os.Stderr, "Error [%s]: %v\n", b.Name(), result.SchemaError)
+	}
 
-	if result.Content != "" {
+	if result.Structured != nil {
+		fmt.Println(string(result.Structured))
+	} else if result.Content != "" {
 		fmt.Print(result.Content)
 		if result.Content != "" && result.Content[len(result.Content)-1] != '\n' {
 			fmt.Println()
@@ -366,14 +472,19 @@ func outputTextResult(b backend.Backend, result *ExecutionResult) {
 }
 
 // outputJSONResult outputs the result as unified JSON.
+// Earlier fallback attempts, if any, are listed together with this one.
 // Returns an error if JSON encoding fails.
-func outputJSONResult(b backend.Backend, result *ExecutionResult, sess *session.Session) error {
+func outputJSONResult(b backend.Backend, result *ExecutionResult, sess *session.Session, attempts []util.FallbackAttempt) error {
 	pr := PromptResult{
-		Backend:  b.Name(),
-		Duration: result.DurationSeconds,
-		ExitCode: result.ExitCode,
-		Content:  result.Content,
-		Error:    result.Error,
+		Backend:    b.Name(),
+		Duration:   result.DurationSeconds,
+		ExitCode:   result.ExitCode,
+		Content:    result.Content,
+		Error:      result.Error,
+		Structured: result.Structured,
+	}
+	if len(attempts) > 0 {
+		pr.Attempts = append(append([]util.FallbackAttempt(nil), attempts...), newFallbackAttempt(b.Name(), result))
 	}
 
 	if result.Response != nil {
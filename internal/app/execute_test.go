@@ -1,6 +1,9 @@
 package app
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -10,6 +13,8 @@ import (
 
 	"github.com/signalridge/clinvoker/internal/backend"
 	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+	"github.com/signalridge/clinvoker/internal/util"
 )
 
 // mockBackend implements backend.Backend for testing
@@ -51,6 +56,9 @@ func (m *mockBackend) ParseJSONResponse(rawOutput string) (*backend.UnifiedRespo
 	return &backend.UnifiedResponse{Content: rawOutput}, nil
 }
 func (m *mockBackend) SeparateStderr() bool { return m.separateStderr }
+func (m *mockBackend) Capabilities() backend.Capabilities {
+	return backend.Capabilities{}
+}
 
 // ==================== ExecuteAndCapture Tests ====================
 
@@ -661,3 +669,138 @@ func TestExecuteCommand_WithTimeout(t *testing.T) {
 		}
 	})
 }
+
+func TestExecuteCommand_StderrAsError(t *testing.T) {
+	cfg := &ExecutionConfig{
+		Backend:    &mockBackend{name: "gemini", jsonError: errors.New("no json"), separateStderr: true},
+		OutputMode: OutputModeText,
+	}
+	cmd := exec.Command("sh", "-c", "echo 'Error: read ECONNRESET' >&2; exit 1")
+
+	var result *ExecutionResult
+	captureStdout(t, func() {
+		result, _ = ExecuteCommand(cfg, cmd)
+	})
+
+	if result.Error != "Error: read ECONNRESET" {
+		t.Errorf("expected stderr as error, got %q", result.Error)
+	}
+}
+
+func TestExecuteCommand_Discard(t *testing.T) {
+	failing := func() *exec.Cmd {
+		return exec.Command("sh", "-c", `echo '{"type":"result","is_error":true,"result":"rate limit exceeded"}'; exit 1`)
+	}
+
+	for _, mode := range []OutputMode{OutputModeText, OutputModeJSON, OutputModeStream} {
+		t.Run(fmt.Sprintf("mode %d discards failed output", mode), func(t *testing.T) {
+			var seen *ExecutionResult
+			cfg := &ExecutionConfig{
+				Backend:    &mockBackend{name: "claude", jsonError: errors.New("no json")},
+				OutputMode: mode,
+				Discard: func(r *ExecutionResult) bool {
+					seen = r
+					return true
+				},
+			}
+
+			var result *ExecutionResult
+			out := captureStdout(t, func() {
+				result, _ = ExecuteCommand(cfg, failing())
+			})
+
+			if out != "" {
+				t.Errorf("expected no output, got %q", out)
+			}
+			if seen == nil || seen != result || result.ExitCode != 1 {
+				t.Errorf("expected Discard to see the failed result, got %+v", seen)
+			}
+		})
+	}
+
+	t.Run("stream output is kept once content arrives", func(t *testing.T) {
+		discardCalled := false
+		cfg := &ExecutionConfig{
+			Backend:    &mockBackend{name: "claude"},
+			OutputMode: OutputModeStream,
+			Discard: func(*ExecutionResult) bool {
+				discardCalled = true
+				return true
+			},
+		}
+		cmd := exec.Command("sh", "-c", `echo '{"type":"assistant","message":{"content":[{"type":"text","text":"hi"}]}}'; exit 1`)
+
+		out := captureStdout(t, func() {
+			_, _ = ExecuteCommand(cfg, cmd)
+		})
+
+		if discardCalled {
+			t.Error("expected Discard not to be called after content was streamed")
+		}
+		if !strings.Contains(out, `"text":"hi"`) {
+			t.Errorf("expected streamed content, got %q", out)
+		}
+	})
+
+	t.Run("kept result is output", func(t *testing.T) {
+		cfg := &ExecutionConfig{
+			Backend:    &mockBackend{name: "test"},
+			OutputMode: OutputModeJSON,
+			Discard:    func(*ExecutionResult) bool { return false },
+			Attempts:   []util.FallbackAttempt{{Backend: "claude", ExitCode: 1, ErrorCode: "rate_limited"}},
+		}
+
+		out := captureStdout(t, func() {
+			_, _ = ExecuteCommand(cfg, exec.Command("echo", "answer"))
+		})
+
+		var pr PromptResult
+		if err := json.Unmarshal([]byte(out), &pr); err != nil {
+			t.Fatalf("invalid JSON output %q: %v", out, err)
+		}
+		if len(pr.Attempts) != 2 || pr.Attempts[1].Backend != "test" || pr.Attempts[1].ErrorCode != "" {
+			t.Errorf("unexpected attempts: %+v", pr.Attempts)
+		}
+	})
+}
+
+func TestNewFallbackAttempt(t *testing.T) {
+	attempt := newFallbackAttempt("claude", &ExecutionResult{ExitCode: 1, Error: "429 Too Many Requests", DurationSeconds: 1.5})
+	if attempt.ErrorCode != "rate_limited" || attempt.DurationMS != 1500 {
+		t.Errorf("unexpected attempt: %+v", attempt)
+	}
+
+	ok := newFallbackAttempt("gemini", &ExecutionResult{})
+	if ok.ErrorCode != "" || ok.ExitCode != 0 {
+		t.Errorf("unexpected attempt for success: %+v", ok)
+	}
+}
+
+func TestExecuteCommand_CircuitBreaker(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+	config.Get().CircuitBreaker.FailureThreshold = 1
+	t.Cleanup(func() { util.ResetBackendBreakers() })
+
+	cfg := &ExecutionConfig{
+		Backend:    &mockBackend{name: "cb-cli"},
+		OutputMode: OutputModeText,
+	}
+	captureStdout(t, func() {
+		result, err := ExecuteCommand(cfg, exec.Command("sh", "-c", "exit 1"))
+		if err != nil || result.ExitCode != 1 {
+			t.Errorf("expected failed run, got %+v, %v", result, err)
+		}
+	})
+
+	result, err := ExecuteCommand(cfg, exec.Command("echo", "not run"))
+	if !apperrors.IsCode(err, apperrors.ErrCodeBackendUnavailable) {
+		t.Fatalf("expected backend_unavailable from open circuit, got %v", err)
+	}
+	if result.ExitCode != 1 || result.Error == "" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
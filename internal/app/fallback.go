@@ -0,0 +1,199 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+	"github.com/signalridge/clinvoker/internal/session"
+	"github.com/signalridge/clinvoker/internal/util"
+)
+
+// runPromptWithFallback runs the prompt on each backend of chain in turn until
+// one answers or fails with an error that another backend cannot fix.
+// Transient failures are retried on the same backend per the configured retry
+// policy first. Output of abandoned attempts is discarded.
+func runPromptWithFallback(cmd *cobra.Command, prompt string, chain []string) error {
+	cfg := config.Get()
+	flags := normalizeFlags(cmd)
+
+	if err := validateOutputFormat(flags.outputFormat); err != nil {
+		return err
+	}
+	schema, err := loadJSONSchema(flags.outputFormat)
+	if err != nil {
+		return err
+	}
+
+	var store *session.Store
+	if !ephemeralMode {
+		store = session.NewStore()
+	}
+
+	policy := util.ResolveRetryPolicy(nil, cfg)
+	var attempts []util.FallbackAttempt
+	for i, name := range chain {
+		last := i == len(chain)-1
+		start := time.Now()
+
+		model := modelName
+		if i > 0 {
+			model = util.FallbackModel(name, modelName)
+		}
+
+		ctx, err := newPromptContext(cfg, flags, name, model)
+		if err != nil {
+			code := apperrors.ErrCodeBackendUnavailable
+			if _, getErr := backend.Get(name); getErr != nil {
+				code = apperrors.ErrCodeBackendNotFound
+			}
+			if last {
+				return err
+			}
+			attempts = append(attempts, util.FallbackAttempt{
+				Backend:    name,
+				ExitCode:   1,
+				ErrorCode:  string(code),
+				Error:      err.Error(),
+				DurationMS: time.Since(start).Milliseconds(),
+			})
+			warnFallback(name, code, chain[i+1])
+			continue
+		}
+		ctx.schema = schema
+
+		for try := 1; ; try++ {
+			canRetry := try < policy.MaxAttempts
+			var discard func(apperrors.ErrorCode) bool
+			if canRetry || !last {
+				discard = func(code apperrors.ErrorCode) bool {
+					return (canRetry && apperrors.IsRetryable(code)) || (!last && apperrors.IsBackendFailure(code))
+				}
+			}
+
+			result, attempt, err := runPromptAttempt(ctx, store, prompt, attempts, discard)
+			if attempt == nil {
+				if err != nil {
+					return err
+				}
+				if result != nil && result.ExitCode != 0 {
+					os.Exit(result.ExitCode)
+				}
+				return nil
+			}
+
+			attempts = append(attempts, *attempt)
+			code := apperrors.ErrorCode(attempt.ErrorCode)
+			if canRetry && apperrors.IsRetryable(code) {
+				fmt.Fprintf(os.Stderr, "Warning: backend %s failed (%s); retrying (attempt %d of %d)\n", name, code, try+1, policy.MaxAttempts)
+				time.Sleep(policy.Backoff(try))
+				continue
+			}
+			warnFallback(name, code, chain[i+1])
+			break
+		}
+	}
+
+	return nil
+}
+
+// runPromptAttempt runs the prompt once on the prepared backend. A failed run
+// whose error code discard accepts is abandoned: its output and session are
+// dropped and it is returned as an attempt. Otherwise, or when discard is nil,
+// the session is saved and the returned attempt is nil.
+func runPromptAttempt(ctx *promptContext, store *session.Store, prompt string, attempts []util.FallbackAttempt, discard func(apperrors.ErrorCode) bool) (*ExecutionResult, *util.FallbackAttempt, error) {
+	cfg := ctx.cfg
+	name := ctx.backendName
+
+	// Create session (skip if ephemeral mode)
+	var sess *session.Session
+	if store != nil {
+		sessOpts := &session.SessionOptions{
+			Model:         ctx.opts.Model,
+			InitialPrompt: prompt,
+			Tags:          append([]string{}, cfg.Session.DefaultTags...),
+		}
+		var err error
+		sess, err = store.CreateWithOptions(name, workDir, sessOpts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to create session: %v\n", err)
+		}
+	}
+
+	discarded := false
+	execCfg := &ExecutionConfig{
+		Backend:    ctx.backend,
+		Session:    sess,
+		OutputMode: DetermineOutputMode(ctx.userFormat),
+		Stdin:      true,
+		Timeout:    GetCommandTimeout(),
+		Attempts:   attempts,
+		Schema:     ctx.schema,
+	}
+	if discard != nil {
+		execCfg.Discard = func(r *ExecutionResult) bool {
+			discarded = r.ExitCode != 0 && discard(apperrors.ClassifyFailure(r.ExitCode, r.Error))
+			return discarded
+		}
+	}
+
+	result, err := executePrompt(execCfg, prompt, func(p string) *exec.Cmd {
+		return ctx.backend.BuildCommandUnified(p, ctx.opts)
+	})
+
+	// Clean up backend session if ephemeral mode
+	if ctx.ephemeral && result != nil {
+		cleanupBackendSession(name, result.SessionID)
+	}
+
+	if discarded {
+		if sess != nil {
+			_ = store.Delete(sess.ID)
+		}
+		attempt := newFallbackAttempt(name, result)
+		if apperrors.IsCode(err, apperrors.ErrCodeBackendUnavailable) {
+			attempt.ErrorCode = string(apperrors.ErrCodeBackendUnavailable)
+		}
+		return result, &attempt, nil
+	}
+
+	// Update session with backend session ID
+	if sess != nil {
+		sess.MarkUsed()
+		if result != nil && result.SessionID != "" {
+			sess.BackendSessionID = result.SessionID
+		}
+		if saveErr := store.Save(sess); saveErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save session: %v\n", saveErr)
+		}
+		if err == nil {
+			recordTurn(store, sess, prompt, result)
+		}
+	}
+
+	return result, nil, err
+}
+
+// newFallbackAttempt records a finished execution as a fallback attempt.
+func newFallbackAttempt(backendName string, result *ExecutionResult) util.FallbackAttempt {
+	attempt := util.FallbackAttempt{
+		Backend:    backendName,
+		ExitCode:   result.ExitCode,
+		Error:      result.Error,
+		DurationMS: int64(result.DurationSeconds * 1000),
+	}
+	if result.ExitCode != 0 {
+		attempt.ErrorCode = string(apperrors.ClassifyFailure(result.ExitCode, result.Error))
+	}
+	return attempt
+}
+
+func warnFallback(backendName string, code apperrors.ErrorCode, next string) {
+	fmt.Fprintf(os.Stderr, "Warning: backend %s failed (%s); falling back to %s\n", backendName, code, next)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/signalridge/clinvoker/internal/backend"
 	"github.com/signalridge/clinvoker/internal/config"
@@ -67,6 +68,32 @@ func resolveModel(explicit, backendName, globalModel string) string {
 	return ""
 }
 
+// checkUnsupported applies the configured policy to options backend b cannot
+// honor, printing warnings to stderr. It returns an error under the error policy.
+func checkUnsupported(b backend.Backend, unsupported []backend.UnsupportedOption, cfg *config.Config) error {
+	policy := ""
+	if cfg != nil {
+		policy = cfg.UnifiedFlags.UnsupportedOptions
+	}
+	warnings, err := backend.ApplyUnsupportedPolicy(b.Name(), unsupported, policy)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+	return nil
+}
+
+// checkResumeSupported applies the unsupported-options policy when b cannot
+// continue sessions; such backends start a fresh conversation instead.
+func checkResumeSupported(b backend.Backend, cfg *config.Config) error {
+	if b.Capabilities().Resume {
+		return nil
+	}
+	return checkUnsupported(b, []backend.UnsupportedOption{backend.ResumeUnsupported}, cfg)
+}
+
 // applyUnifiedDefaults applies unified flag defaults from config to options.
 // This is a thin wrapper around util.ApplyUnifiedDefaults for package convenience.
 func applyUnifiedDefaults(opts *backend.UnifiedOptions, cfg *config.Config, effectiveDryRun bool) {
@@ -146,6 +173,32 @@ func updateSessionAfterExecutionWithBackendID(store *session.Store, sess *sessio
 	}
 }
 
+// recordTurn appends a finished run to the transcript of sess. Call it only
+// for runs the session was updated from.
+func recordTurn(store *session.Store, sess *session.Session, prompt string, result *ExecutionResult) {
+	if result == nil {
+		return
+	}
+
+	completedAt := time.Now()
+	turn := &session.Turn{
+		StartedAt:   completedAt.Add(-time.Duration(result.DurationSeconds * float64(time.Second))),
+		CompletedAt: completedAt,
+		Prompt:      prompt,
+		Response:    result.Content,
+		ToolCalls:   result.ToolCalls,
+		ExitCode:    result.ExitCode,
+		Error:       result.Error,
+	}
+	if result.Response != nil {
+		turn.TokenUsage = util.TokenUsageFromBackend(result.Response.Usage)
+	}
+
+	if err := util.RecordTurn(store, sess, turn); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record transcript: %v\n", err)
+	}
+}
+
 // truncateString truncates a string to maxLen, adding "..." if truncated.
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/structured"
+)
+
+// loadJSONSchema compiles the --json-schema file, if any, for output in
+// format. Replies are validated once complete, so streamed output is not
+// supported.
+func loadJSONSchema(format string) (*structured.Schema, error) {
+	if jsonSchemaFile == "" {
+		return nil, nil
+	}
+	if backend.OutputFormat(format) == backend.OutputStreamJSON {
+		return nil, errors.New("--json-schema is not supported with stream-json output")
+	}
+	if jsonRetries < 0 {
+		return nil, errors.New("--json-retries must not be negative")
+	}
+
+	data, err := os.ReadFile(jsonSchemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON schema: %w", err)
+	}
+	schema, err := structured.Compile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", jsonSchemaFile, err)
+	}
+	return schema, nil
+}
+
+// structuredPrompt adds the instructions of schema, if any, to prompt.
+func structuredPrompt(prompt string, schema *structured.Schema) string {
+	if schema == nil {
+		return prompt
+	}
+	return prompt + "\n\n" + schema.Instructions()
+}
+
+// executePrompt runs prompt with the command build returns for it. With a
+// JSON schema in cfg, the reply must match it: replies that do not are
+// dropped and asked for again, with the rejected reply, up to --json-retries
+// times. The last reply is kept whether it matches or not.
+func executePrompt(cfg *ExecutionConfig, prompt string, build func(prompt string) *exec.Cmd) (*ExecutionResult, error) {
+	if cfg.Schema == nil {
+		return ExecuteCommand(cfg, build(prompt))
+	}
+
+	attemptPrompt := structuredPrompt(prompt, cfg.Schema)
+	for try := 0; ; try++ {
+		last := try >= jsonRetries
+		attemptCfg := *cfg
+		attemptCfg.Discard = func(r *ExecutionResult) bool {
+			// Another backend is not tried for a reply that does not match
+			if r.SchemaError != nil {
+				return !last
+			}
+			return cfg.Discard != nil && cfg.Discard(r)
+		}
+
+		result, err := ExecuteCommand(&attemptCfg, build(attemptPrompt))
+		if err != nil || result.SchemaError == nil || last {
+			return result, err
+		}
+
+		if ephemeralMode {
+			cleanupBackendSession(cfg.Backend.Name(), result.SessionID)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v; asking again (attempt %d of %d)\n", result.SchemaError, try+2, jsonRetries+1)
+		attemptPrompt = cfg.Schema.RepairPrompt(prompt, result.Content, result.SchemaError)
+	}
+}
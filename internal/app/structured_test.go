@@ -0,0 +1,134 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadJSONSchema(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	if err := os.WriteFile(valid, []byte(`{"type":"object"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"type":`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		format  string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "no schema", wantNil: true},
+		{name: "valid", file: valid, format: "json"},
+		{name: "invalid", file: invalid, format: "json", wantErr: true},
+		{name: "missing", file: filepath.Join(dir, "missing.json"), format: "json", wantErr: true},
+		{name: "stream-json", file: valid, format: "stream-json", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldFile := jsonSchemaFile
+			jsonSchemaFile = tt.file
+			t.Cleanup(func() { jsonSchemaFile = oldFile })
+
+			schema, err := loadJSONSchema(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadJSONSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (schema == nil) != tt.wantNil {
+				t.Errorf("loadJSONSchema() = %v, wantNil %v", schema, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestExecutePrompt_JSONSchema(t *testing.T) {
+	dir := t.TempDir()
+	schemaFile := filepath.Join(dir, "schema.json")
+	if err := os.WriteFile(schemaFile, []byte(`{"type":"object","required":["answer"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	oldFile, oldRetries := jsonSchemaFile, jsonRetries
+	jsonSchemaFile = schemaFile
+	t.Cleanup(func() { jsonSchemaFile, jsonRetries = oldFile, oldRetries })
+
+	schema, err := loadJSONSchema("json")
+	if err != nil {
+		t.Fatalf("loadJSONSchema() error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		replies        []string
+		retries        int
+		wantExitCode   int
+		wantStructured string
+		wantPrompts    int
+	}{
+		{name: "valid reply", replies: []string{`{"answer":42}`}, wantStructured: `{"answer":42}`, wantPrompts: 1},
+		{name: "retried", replies: []string{"forty-two", `{"answer":42}`}, retries: 1, wantStructured: `{"answer":42}`, wantPrompts: 2},
+		{name: "retries exhausted", replies: []string{"forty-two", "still forty-two"}, retries: 1, wantExitCode: 1, wantPrompts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonRetries = tt.retries
+			replies := tt.replies
+			var prompts []string
+			build := func(p string) *exec.Cmd {
+				prompts = append(prompts, p)
+				reply := replies[0]
+				replies = replies[1:]
+				return exec.Command("printf", "%s", reply)
+			}
+			cfg := &ExecutionConfig{
+				Backend:    &mockBackend{name: "test"},
+				OutputMode: OutputModeJSON,
+				Schema:     schema,
+			}
+
+			var result *ExecutionResult
+			out := captureStdout(t, func() {
+				result, _ = executePrompt(cfg, "What is the answer?", build)
+			})
+
+			if result.ExitCode != tt.wantExitCode {
+				t.Errorf("exit code = %d, want %d", result.ExitCode, tt.wantExitCode)
+			}
+			if len(prompts) != tt.wantPrompts {
+				t.Fatalf("prompts = %d, want %d", len(prompts), tt.wantPrompts)
+			}
+			if !strings.Contains(prompts[0], `"required":["answer"]`) {
+				t.Errorf("prompt = %q, want it to contain the schema", prompts[0])
+			}
+			if len(prompts) > 1 && !strings.Contains(prompts[1], "[Previous response: forty-two]") {
+				t.Errorf("retry prompt = %q, want it to contain the rejected reply", prompts[1])
+			}
+
+			// Only the kept reply is output
+			var pr PromptResult
+			if err := json.Unmarshal([]byte(out), &pr); err != nil {
+				t.Fatalf("invalid JSON output %q: %v", out, err)
+			}
+			var structured bytes.Buffer
+			if pr.Structured != nil {
+				if err := json.Compact(&structured, pr.Structured); err != nil {
+					t.Fatalf("invalid structured %s: %v", pr.Structured, err)
+				}
+			}
+			if structured.String() != tt.wantStructured {
+				t.Errorf("structured = %s, want %s", structured.String(), tt.wantStructured)
+			}
+		})
+	}
+}
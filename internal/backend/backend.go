@@ -43,6 +43,9 @@ type Backend interface {
 	// SeparateStderr returns true if this backend's stderr should be
 	// captured separately (to filter out noise like credential messages).
 	SeparateStderr() bool
+
+	// Capabilities describes which unified options the backend honors.
+	Capabilities() Capabilities
 }
 
 // UnifiedResponse represents a normalized response from any backend.
@@ -92,4 +95,7 @@ type Options struct {
 
 	// ExtraFlags contains additional flags to pass to the backend.
 	ExtraFlags []string
+
+	// Env sets additional environment variables for the command, as KEY=value.
+	Env []string
 }
@@ -0,0 +1,173 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Capabilities describes which unified options a backend honors.
+// Options a backend does not support are dropped when mapping to CLI flags;
+// CheckOptions reports them so callers can warn or reject the request.
+type Capabilities struct {
+	// Resume reports whether sessions can be continued.
+	Resume bool `json:"resume"`
+
+	// Streaming reports whether output is emitted incrementally.
+	Streaming bool `json:"streaming"`
+
+	// SystemPrompt reports whether a custom system prompt is applied.
+	SystemPrompt bool `json:"system_prompt"`
+
+	// MaxTokens reports whether the response token limit is applied.
+	MaxTokens bool `json:"max_tokens"`
+
+	// MaxTurns reports whether the agentic turn limit is applied.
+	MaxTurns bool `json:"max_turns"`
+
+	// ApprovalModes lists the non-default approval modes the backend maps.
+	ApprovalModes []ApprovalMode `json:"approval_modes"`
+
+	// SandboxModes lists the non-default sandbox modes the backend maps.
+	SandboxModes []SandboxMode `json:"sandbox_modes"`
+
+	// ToolAllowlist reports whether allowed_tools restricts tool use.
+	ToolAllowlist bool `json:"tool_allowlist"`
+
+	// ThinkingEvents reports whether streams include thinking events.
+	ThinkingEvents bool `json:"thinking_events"`
+
+	// MCPServers reports whether mcp_servers are attached to runs.
+	MCPServers bool `json:"mcp_servers"`
+}
+
+// AllApprovalModes are the non-default approval modes.
+var AllApprovalModes = []ApprovalMode{ApprovalAuto, ApprovalNone, ApprovalAlways}
+
+// AllSandboxModes are the non-default sandbox modes.
+var AllSandboxModes = []SandboxMode{SandboxReadOnly, SandboxWorkspace, SandboxFull}
+
+// SupportsApprovalMode reports whether mode is honored. The default mode always is.
+func (c Capabilities) SupportsApprovalMode(mode ApprovalMode) bool {
+	if mode == "" || mode == ApprovalDefault {
+		return true
+	}
+	for _, m := range c.ApprovalModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsSandboxMode reports whether mode is honored. The default mode always is.
+func (c Capabilities) SupportsSandboxMode(mode SandboxMode) bool {
+	if mode == "" || mode == SandboxDefault {
+		return true
+	}
+	for _, m := range c.SandboxModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// UnsupportedOption is a unified option the backend would ignore.
+type UnsupportedOption struct {
+	// Option is the unified option name (e.g. "system_prompt").
+	Option string `json:"option"`
+
+	// Value is the requested value, when it is an enumerated mode.
+	Value string `json:"value,omitempty"`
+}
+
+// String returns a human-readable description.
+func (u UnsupportedOption) String() string {
+	if u.Value != "" {
+		return fmt.Sprintf("%s=%s", u.Option, u.Value)
+	}
+	return u.Option
+}
+
+// CheckOptions returns the options in opts that caps does not support.
+func CheckOptions(caps Capabilities, opts *UnifiedOptions) []UnsupportedOption {
+	if opts == nil {
+		return nil
+	}
+
+	var unsupported []UnsupportedOption
+	if !caps.SupportsApprovalMode(opts.ApprovalMode) {
+		unsupported = append(unsupported, UnsupportedOption{Option: "approval_mode", Value: string(opts.ApprovalMode)})
+	}
+	if !caps.SupportsSandboxMode(opts.SandboxMode) {
+		unsupported = append(unsupported, UnsupportedOption{Option: "sandbox_mode", Value: string(opts.SandboxMode)})
+	}
+	if opts.SystemPrompt != "" && !caps.SystemPrompt {
+		unsupported = append(unsupported, UnsupportedOption{Option: "system_prompt"})
+	}
+	if opts.MaxTokens > 0 && !caps.MaxTokens {
+		unsupported = append(unsupported, UnsupportedOption{Option: "max_tokens"})
+	}
+	if opts.MaxTurns > 0 && !caps.MaxTurns {
+		unsupported = append(unsupported, UnsupportedOption{Option: "max_turns"})
+	}
+	if opts.AllowedTools != "" && opts.AllowedTools != "all" && !caps.ToolAllowlist {
+		unsupported = append(unsupported, UnsupportedOption{Option: "allowed_tools"})
+	}
+	if len(opts.MCPServers) > 0 && !caps.MCPServers {
+		unsupported = append(unsupported, UnsupportedOption{Option: "mcp_servers"})
+	}
+	return unsupported
+}
+
+// Policies for handling unsupported options.
+const (
+	// UnsupportedWarn reports unsupported options and runs the request anyway (default).
+	UnsupportedWarn = "warn"
+
+	// UnsupportedError rejects requests that use unsupported options.
+	UnsupportedError = "error"
+
+	// UnsupportedIgnore drops unsupported options silently.
+	UnsupportedIgnore = "ignore"
+)
+
+// ResumeUnsupported is reported when continuing a session on a backend without resume support.
+var ResumeUnsupported = UnsupportedOption{Option: "resume"}
+
+// UnsupportedOptionsError is returned when a request uses options the backend cannot honor.
+type UnsupportedOptionsError struct {
+	Backend string
+	Options []UnsupportedOption
+}
+
+func (e *UnsupportedOptionsError) Error() string {
+	return fmt.Sprintf("backend %q does not support: %s", e.Backend, joinOptions(e.Options))
+}
+
+// ApplyUnsupportedPolicy handles options a backend cannot honor according to
+// policy. Under the error policy it returns an *UnsupportedOptionsError; under
+// the warn policy (or an empty policy) it returns one warning per option;
+// under the ignore policy it returns nothing.
+func ApplyUnsupportedPolicy(backendName string, unsupported []UnsupportedOption, policy string) ([]string, error) {
+	if policy == UnsupportedIgnore || len(unsupported) == 0 {
+		return nil, nil
+	}
+	if policy == UnsupportedError {
+		return nil, &UnsupportedOptionsError{Backend: backendName, Options: unsupported}
+	}
+
+	warnings := make([]string, len(unsupported))
+	for i, u := range unsupported {
+		warnings[i] = fmt.Sprintf("backend %q does not support %s; option ignored", backendName, u)
+	}
+	return warnings, nil
+}
+
+func joinOptions(opts []UnsupportedOption) string {
+	parts := make([]string, len(opts))
+	for i, o := range opts {
+		parts[i] = o.String()
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,166 @@
+package backend
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinCapabilities(t *testing.T) {
+	tests := []struct {
+		backend      Backend
+		systemPrompt bool
+		maxTurns     bool
+		sandbox      bool
+		tools        bool
+		thinking     bool
+	}{
+		{backend: &Claude{}, systemPrompt: true, maxTurns: true, tools: true, thinking: true},
+		{backend: &Codex{}, sandbox: true, thinking: true},
+		{backend: &Gemini{}, sandbox: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend.Name(), func(t *testing.T) {
+			caps := tt.backend.Capabilities()
+			if !caps.Resume || !caps.Streaming {
+				t.Error("expected resume and streaming support")
+			}
+			if caps.SystemPrompt != tt.systemPrompt {
+				t.Errorf("SystemPrompt = %v, want %v", caps.SystemPrompt, tt.systemPrompt)
+			}
+			if caps.MaxTurns != tt.maxTurns {
+				t.Errorf("MaxTurns = %v, want %v", caps.MaxTurns, tt.maxTurns)
+			}
+			if caps.MaxTokens {
+				t.Error("expected no max tokens support from CLI backends")
+			}
+			if caps.SupportsSandboxMode(SandboxReadOnly) != tt.sandbox {
+				t.Errorf("read-only sandbox support = %v, want %v", !tt.sandbox, tt.sandbox)
+			}
+			if !caps.SupportsApprovalMode(ApprovalNone) {
+				t.Error("expected approval modes to be supported")
+			}
+			if caps.ToolAllowlist != tt.tools {
+				t.Errorf("ToolAllowlist = %v, want %v", caps.ToolAllowlist, tt.tools)
+			}
+			if caps.ThinkingEvents != tt.thinking {
+				t.Errorf("ThinkingEvents = %v, want %v", caps.ThinkingEvents, tt.thinking)
+			}
+			if !caps.MCPServers {
+				t.Error("expected MCP server support")
+			}
+		})
+	}
+}
+
+// TestCapabilitiesMatchFlagMapper guards against capabilities drifting from
+// what the flag mapper actually emits.
+func TestCapabilitiesMatchFlagMapper(t *testing.T) {
+	for _, b := range []Backend{&Claude{}, &Codex{}, &Gemini{}} {
+		t.Run(b.Name(), func(t *testing.T) {
+			caps := b.Capabilities()
+			m := newFlagMapper(b.Name())
+
+			if got := len(m.mapSystemPrompt("x")) > 0; got != caps.SystemPrompt {
+				t.Errorf("system prompt: mapper=%v capabilities=%v", got, caps.SystemPrompt)
+			}
+			if got := len(m.mapMaxTurns(3)) > 0; got != caps.MaxTurns {
+				t.Errorf("max turns: mapper=%v capabilities=%v", got, caps.MaxTurns)
+			}
+			for _, mode := range AllApprovalModes {
+				if got := len(m.mapApprovalMode(mode)) > 0; got != caps.SupportsApprovalMode(mode) {
+					t.Errorf("approval %s: mapper=%v capabilities=%v", mode, got, caps.SupportsApprovalMode(mode))
+				}
+			}
+			for _, mode := range []SandboxMode{SandboxReadOnly, SandboxWorkspace} {
+				if got := len(m.mapSandboxMode(mode)) > 0; got != caps.SupportsSandboxMode(mode) {
+					t.Errorf("sandbox %s: mapper=%v capabilities=%v", mode, got, caps.SupportsSandboxMode(mode))
+				}
+			}
+		})
+	}
+}
+
+func TestCheckOptions(t *testing.T) {
+	caps := Capabilities{
+		SystemPrompt:  true,
+		ApprovalModes: []ApprovalMode{ApprovalAuto},
+	}
+
+	tests := []struct {
+		name string
+		opts *UnifiedOptions
+		want []string
+	}{
+		{name: "nil options", opts: nil},
+		{name: "defaults", opts: &UnifiedOptions{ApprovalMode: ApprovalDefault, SandboxMode: SandboxDefault}},
+		{name: "supported", opts: &UnifiedOptions{SystemPrompt: "x", ApprovalMode: ApprovalAuto}},
+		{name: "allowed tools all", opts: &UnifiedOptions{AllowedTools: "all"}},
+		{
+			name: "unsupported",
+			opts: &UnifiedOptions{
+				ApprovalMode: ApprovalNone,
+				SandboxMode:  SandboxReadOnly,
+				MaxTokens:    10,
+				MaxTurns:     2,
+				AllowedTools: "Read",
+				MCPServers:   map[string]MCPServer{"docs": {URL: "https://mcp.example.com/mcp"}},
+			},
+			want: []string{"approval_mode=none", "sandbox_mode=read-only", "max_tokens", "max_turns", "allowed_tools", "mcp_servers"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckOptions(caps, tt.opts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("CheckOptions() = %v, want %v", got, tt.want)
+			}
+			for i, u := range got {
+				if u.String() != tt.want[i] {
+					t.Errorf("option %d = %q, want %q", i, u.String(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyUnsupportedPolicy(t *testing.T) {
+	unsupported := []UnsupportedOption{{Option: "max_tokens"}, {Option: "sandbox_mode", Value: "full"}}
+
+	t.Run("warn", func(t *testing.T) {
+		warnings, err := ApplyUnsupportedPolicy("codex", unsupported, UnsupportedWarn)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(warnings) != 2 || !strings.Contains(warnings[1], "sandbox_mode=full") {
+			t.Errorf("unexpected warnings: %v", warnings)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := ApplyUnsupportedPolicy("codex", unsupported, UnsupportedError)
+		var target *UnsupportedOptionsError
+		if !errors.As(err, &target) {
+			t.Fatalf("expected UnsupportedOptionsError, got %v", err)
+		}
+		if !strings.Contains(err.Error(), `"codex"`) || !strings.Contains(err.Error(), "max_tokens, sandbox_mode=full") {
+			t.Errorf("unexpected error message: %v", err)
+		}
+	})
+
+	t.Run("ignore", func(t *testing.T) {
+		warnings, err := ApplyUnsupportedPolicy("codex", unsupported, UnsupportedIgnore)
+		if err != nil || warnings != nil {
+			t.Errorf("expected nothing, got %v, %v", warnings, err)
+		}
+	})
+
+	t.Run("nothing unsupported", func(t *testing.T) {
+		warnings, err := ApplyUnsupportedPolicy("codex", nil, UnsupportedError)
+		if err != nil || warnings != nil {
+			t.Errorf("expected nothing, got %v, %v", warnings, err)
+		}
+	})
+}
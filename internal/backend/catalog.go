@@ -0,0 +1,210 @@
+package backend
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModelInfo describes a model in the catalog.
+type ModelInfo struct {
+	// ID is the model identifier passed to the backend.
+	ID string `json:"id"`
+
+	// Backend is the backend that serves the model.
+	Backend string `json:"backend"`
+
+	// DisplayName is a human-readable name.
+	DisplayName string `json:"display_name,omitempty"`
+
+	// ContextWindow is the maximum context length in tokens.
+	ContextWindow int `json:"context_window,omitempty"`
+
+	// MaxOutputTokens is the maximum response length in tokens.
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+
+	// InputPrice is the price in USD per million input tokens.
+	InputPrice float64 `json:"input_price,omitempty"`
+
+	// OutputPrice is the price in USD per million output tokens.
+	OutputPrice float64 `json:"output_price,omitempty"`
+}
+
+// modelCatalog holds the models, aliases and ID prefixes of one backend.
+type modelCatalog struct {
+	models   []ModelInfo
+	aliases  map[string]string
+	prefixes []string
+}
+
+// defaultModelCatalogs returns the built-in catalogs, which config can extend or replace.
+func defaultModelCatalogs() map[string]*modelCatalog {
+	return map[string]*modelCatalog{
+		BackendClaude: {
+			models: []ModelInfo{
+				{ID: "claude-opus-4-5-20251101", Backend: BackendClaude, DisplayName: "Claude Opus 4.5"},
+				{ID: "claude-sonnet-4-5-20250929", Backend: BackendClaude, DisplayName: "Claude Sonnet 4.5"},
+				{ID: "claude-haiku-4-5-20251001", Backend: BackendClaude, DisplayName: "Claude Haiku 4.5"},
+			},
+			aliases: map[string]string{
+				"fast": "haiku", "quick": "haiku",
+				"balanced": "sonnet", "default": "sonnet",
+				"best": "opus", "powerful": "opus",
+			},
+			prefixes: []string{"claude"},
+		},
+		BackendCodex: {
+			models: []ModelInfo{
+				{ID: "gpt-5.2", Backend: BackendCodex},
+				{ID: "gpt-5-codex", Backend: BackendCodex},
+				{ID: "gpt-4.1-mini", Backend: BackendCodex},
+			},
+			aliases: map[string]string{
+				"fast": "gpt-4.1-mini", "quick": "gpt-4.1-mini",
+				"balanced": "gpt-5.2", "default": "gpt-5.2",
+				"best": "gpt-5-codex", "powerful": "gpt-5-codex",
+			},
+			prefixes: []string{"gpt"},
+		},
+		BackendGemini: {
+			models: []ModelInfo{
+				{ID: "gemini-2.5-pro", Backend: BackendGemini},
+				{ID: "gemini-2.5-flash", Backend: BackendGemini},
+			},
+			aliases: map[string]string{
+				"fast": "gemini-2.5-flash", "quick": "gemini-2.5-flash",
+				"balanced": "gemini-2.5-pro", "default": "gemini-2.5-pro",
+				"best": "gemini-2.5-pro", "powerful": "gemini-2.5-pro",
+			},
+			prefixes: []string{"gemini"},
+		},
+	}
+}
+
+var (
+	catalogMu sync.RWMutex
+	catalogs  = defaultModelCatalogs()
+)
+
+// ConfigureModels updates the catalog of a backend. A non-nil models or
+// prefixes slice replaces the current one; aliases are merged over the
+// existing aliases.
+func ConfigureModels(backendName string, models []ModelInfo, aliases map[string]string, prefixes []string) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	c, ok := catalogs[backendName]
+	if !ok {
+		c = &modelCatalog{}
+		catalogs[backendName] = c
+	}
+	if models != nil {
+		c.models = make([]ModelInfo, len(models))
+		for i, m := range models {
+			m.Backend = backendName
+			c.models[i] = m
+		}
+	}
+	if len(aliases) > 0 {
+		merged := make(map[string]string, len(c.aliases)+len(aliases))
+		for k, v := range c.aliases {
+			merged[k] = v
+		}
+		for k, v := range aliases {
+			merged[k] = v
+		}
+		c.aliases = merged
+	}
+	if prefixes != nil {
+		c.prefixes = append([]string(nil), prefixes...)
+	}
+}
+
+// ResetModelCatalog restores the built-in catalogs.
+func ResetModelCatalog() {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	catalogs = defaultModelCatalogs()
+}
+
+// Models returns the catalog models of a backend.
+func Models(backendName string) []ModelInfo {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	c, ok := catalogs[backendName]
+	if !ok {
+		return nil
+	}
+	return append([]ModelInfo(nil), c.models...)
+}
+
+// ListModels returns the catalog models of all registered backends,
+// ordered by backend name and then as configured.
+func ListModels() []ModelInfo {
+	names := List()
+	sort.Strings(names)
+
+	var models []ModelInfo
+	for _, name := range names {
+		models = append(models, Models(name)...)
+	}
+	return models
+}
+
+// ResolveModelAlias maps an alias such as "fast" to the backend's model name.
+// Anything that is not an alias is returned unchanged.
+func ResolveModelAlias(backendName, model string) string {
+	if model == "" {
+		return ""
+	}
+
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	if c, ok := catalogs[backendName]; ok {
+		if target, ok := c.aliases[strings.ToLower(model)]; ok {
+			return target
+		}
+	}
+	return model
+}
+
+// FindModelBackend returns the registered backend that owns a model ID.
+// Exact catalog IDs win; otherwise the backend with the longest matching
+// model ID prefix is used.
+func FindModelBackend(model string) (string, bool) {
+	if model == "" {
+		return "", false
+	}
+	registered := List()
+	sort.Strings(registered)
+
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	for _, name := range registered {
+		if c, ok := catalogs[name]; ok {
+			for _, m := range c.models {
+				if m.ID == model {
+					return name, true
+				}
+			}
+		}
+	}
+
+	lower := strings.ToLower(model)
+	best, bestLen := "", 0
+	for _, name := range registered {
+		c, ok := catalogs[name]
+		if !ok {
+			continue
+		}
+		for _, p := range c.prefixes {
+			if len(p) > bestLen && strings.HasPrefix(lower, strings.ToLower(p)) {
+				best, bestLen = name, len(p)
+			}
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,104 @@
+package backend
+
+import "testing"
+
+func TestResolveModelAlias(t *testing.T) {
+	t.Cleanup(ResetModelCatalog)
+
+	tests := []struct {
+		backend string
+		model   string
+		want    string
+	}{
+		{BackendClaude, "FAST", "haiku"},
+		{BackendCodex, "best", "gpt-5-codex"},
+		{BackendGemini, "balanced", "gemini-2.5-pro"},
+		{BackendClaude, "claude-custom", "claude-custom"},
+		{"unknown", "fast", "fast"},
+		{BackendClaude, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend+"/"+tt.model, func(t *testing.T) {
+			if got := ResolveModelAlias(tt.backend, tt.model); got != tt.want {
+				t.Errorf("ResolveModelAlias(%q, %q) = %q, want %q", tt.backend, tt.model, got, tt.want)
+			}
+		})
+	}
+
+	ConfigureModels(BackendClaude, nil, map[string]string{"fast": "claude-custom"}, nil)
+	if got := ResolveModelAlias(BackendClaude, "fast"); got != "claude-custom" {
+		t.Errorf("configured alias = %q, want claude-custom", got)
+	}
+	if got := ResolveModelAlias(BackendClaude, "best"); got != "opus" {
+		t.Errorf("expected built-in aliases to be kept, got %q", got)
+	}
+}
+
+func TestConfigureModels(t *testing.T) {
+	t.Cleanup(ResetModelCatalog)
+
+	ConfigureModels(BackendGemini, []ModelInfo{{ID: "gemini-3-pro", ContextWindow: 1000000}}, nil, nil)
+
+	models := Models(BackendGemini)
+	if len(models) != 1 || models[0].ID != "gemini-3-pro" {
+		t.Fatalf("expected configured models to replace built-ins, got %+v", models)
+	}
+	if models[0].Backend != BackendGemini {
+		t.Errorf("Backend = %q, want %q", models[0].Backend, BackendGemini)
+	}
+	if len(Models(BackendClaude)) == 0 {
+		t.Error("expected other backends to keep built-in models")
+	}
+
+	ResetModelCatalog()
+	if got := Models(BackendGemini); len(got) != 2 {
+		t.Errorf("expected reset to restore built-in models, got %+v", got)
+	}
+}
+
+func TestFindModelBackend(t *testing.T) {
+	t.Cleanup(ResetModelCatalog)
+	ConfigureModels(BackendCodex, []ModelInfo{{ID: "claude-proxy"}}, nil, []string{"gpt", "o3"})
+
+	tests := []struct {
+		model  string
+		want   string
+		wantOK bool
+	}{
+		{"claude-sonnet-4-5", BackendClaude, true},
+		{"claude-proxy", BackendCodex, true}, // exact IDs beat prefixes
+		{"GPT-4o", BackendCodex, true},
+		{"o3-mini", BackendCodex, true},
+		{"gemini-1.5-pro", BackendGemini, true},
+		{"llama3", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, ok := FindModelBackend(tt.model)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("FindModelBackend(%q) = %q, %v, want %q, %v", tt.model, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestListModels(t *testing.T) {
+	t.Cleanup(ResetModelCatalog)
+	ConfigureModels("not-registered", []ModelInfo{{ID: "ghost"}}, nil, nil)
+
+	models := ListModels()
+	if len(models) == 0 {
+		t.Fatal("expected built-in models")
+	}
+	for _, m := range models {
+		if m.ID == "ghost" {
+			t.Error("expected models of unregistered backends to be omitted")
+		}
+		if m.Backend == "" {
+			t.Errorf("model %q has no backend", m.ID)
+		}
+	}
+}
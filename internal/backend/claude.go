@@ -2,6 +2,7 @@ package backend
 
 import (
 	"encoding/json"
+	"os"
 	"os/exec"
 )
 
@@ -42,6 +43,9 @@ func (c *Claude) BuildCommand(prompt string, opts *Options) *exec.Cmd {
 	if opts != nil && opts.WorkDir != "" {
 		cmd.Dir = opts.WorkDir
 	}
+	if opts != nil && len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
 
 	return cmd
 }
@@ -71,6 +75,9 @@ func (c *Claude) ResumeCommand(sessionID, prompt string, opts *Options) *exec.Cm
 	if opts != nil && opts.WorkDir != "" {
 		cmd.Dir = opts.WorkDir
 	}
+	if opts != nil && len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
 
 	return cmd
 }
@@ -157,3 +164,18 @@ func (c *Claude) ParseJSONResponse(rawOutput string) (*UnifiedResponse, error) {
 func (c *Claude) SeparateStderr() bool {
 	return false
 }
+
+// Capabilities returns Claude's supported options.
+// Claude Code has no sandbox flag; restrictions go through permission modes.
+func (c *Claude) Capabilities() Capabilities {
+	return Capabilities{
+		Resume:         true,
+		Streaming:      true,
+		SystemPrompt:   true,
+		MaxTurns:       true,
+		ApprovalModes:  AllApprovalModes,
+		ToolAllowlist:  true,
+		ThinkingEvents: true,
+		MCPServers:     true,
+	}
+}
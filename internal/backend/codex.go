@@ -2,6 +2,7 @@ package backend
 
 import (
 	"encoding/json"
+	"os"
 	"os/exec"
 	"strings"
 )
@@ -56,6 +57,9 @@ func (c *Codex) BuildCommand(prompt string, opts *Options) *exec.Cmd {
 	if opts != nil && opts.WorkDir != "" {
 		cmd.Dir = opts.WorkDir
 	}
+	if opts != nil && len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
 
 	return cmd
 }
@@ -99,6 +103,9 @@ func (c *Codex) ResumeCommand(sessionID, prompt string, opts *Options) *exec.Cmd
 	if opts != nil && opts.WorkDir != "" {
 		cmd.Dir = opts.WorkDir
 	}
+	if opts != nil && len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
 
 	return cmd
 }
@@ -222,3 +229,15 @@ func (c *Codex) ParseJSONResponse(rawOutput string) (*UnifiedResponse, error) {
 func (c *Codex) SeparateStderr() bool {
 	return true
 }
+
+// Capabilities returns Codex's supported options.
+func (c *Codex) Capabilities() Capabilities {
+	return Capabilities{
+		Resume:         true,
+		Streaming:      true,
+		ApprovalModes:  AllApprovalModes,
+		SandboxModes:   AllSandboxModes,
+		ThinkingEvents: true,
+		MCPServers:     true,
+	}
+}
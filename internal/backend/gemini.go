@@ -2,6 +2,7 @@ package backend
 
 import (
 	"encoding/json"
+	"os"
 	"os/exec"
 	"strings"
 )
@@ -54,6 +55,9 @@ func (g *Gemini) BuildCommand(prompt string, opts *Options) *exec.Cmd {
 	if opts != nil && opts.WorkDir != "" {
 		cmd.Dir = opts.WorkDir
 	}
+	if opts != nil && len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
 
 	return cmd
 }
@@ -96,6 +100,9 @@ func (g *Gemini) ResumeCommand(sessionID, prompt string, opts *Options) *exec.Cm
 	if opts != nil && opts.WorkDir != "" {
 		cmd.Dir = opts.WorkDir
 	}
+	if opts != nil && len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
 
 	return cmd
 }
@@ -202,3 +209,14 @@ func (g *Gemini) ParseJSONResponse(rawOutput string) (*UnifiedResponse, error) {
 func (g *Gemini) SeparateStderr() bool {
 	return true
 }
+
+// Capabilities returns Gemini's supported options.
+func (g *Gemini) Capabilities() Capabilities {
+	return Capabilities{
+		Resume:        true,
+		Streaming:     true,
+		ApprovalModes: AllApprovalModes,
+		SandboxModes:  AllSandboxModes,
+		MCPServers:    true,
+	}
+}
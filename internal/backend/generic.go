@@ -0,0 +1,534 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/signalridge/clinvoker/internal/output"
+)
+
+// Output formats understood by generic backends.
+const (
+	GenericOutputText  = "text"
+	GenericOutputJSON  = "json"
+	GenericOutputJSONL = "jsonl"
+)
+
+// GenericSpec describes a CLI backend declared entirely in configuration.
+// Argument templates may reference {{prompt}}, {{session_id}}, {{model}},
+// {{system_prompt}} and {{max_turns}}.
+type GenericSpec struct {
+	// Name is the backend identifier.
+	Name string
+
+	// Command is the executable name or path.
+	Command string
+
+	// Args are passed on every invocation, before any other arguments.
+	Args []string
+
+	// PromptArgs places the prompt on the command line (default: {{prompt}}).
+	PromptArgs []string
+
+	// ResumeArgs are added when resuming a session.
+	ResumeArgs []string
+
+	// ModelArgs selects the model.
+	ModelArgs []string
+
+	// SystemPromptArgs passes a system prompt.
+	SystemPromptArgs []string
+
+	// MaxTurnsArgs limits agentic turns.
+	MaxTurnsArgs []string
+
+	// ApprovalFlags maps approval modes to flags.
+	ApprovalFlags map[string][]string
+
+	// SandboxFlags maps sandbox modes to flags.
+	SandboxFlags map[string][]string
+
+	// OutputFormatFlags maps output formats to flags.
+	OutputFormatFlags map[string][]string
+
+	// VerboseFlags are added when verbose output is requested.
+	VerboseFlags []string
+
+	// EphemeralFlags disable session persistence on the backend.
+	EphemeralFlags []string
+
+	// Env sets additional environment variables for the process.
+	Env map[string]string
+
+	// SeparateStderr captures stderr separately from the response.
+	SeparateStderr bool
+
+	// Output describes how to extract the response.
+	Output GenericOutputSpec
+}
+
+// GenericOutputSpec describes how to extract a response from CLI output.
+type GenericOutputSpec struct {
+	// Format is text (default), json or jsonl.
+	Format string
+
+	// Paths locate fields in JSON output using a jq-like syntax.
+	ContentPath      string
+	SessionIDPath    string
+	ErrorPath        string
+	ModelPath        string
+	InputTokensPath  string
+	OutputTokensPath string
+
+	// ContentRegex and SessionIDRegex extract fields from text output.
+	ContentRegex   string
+	SessionIDRegex string
+}
+
+// Generic implements the Backend interface for a config-declared CLI.
+type Generic struct {
+	spec      GenericSpec
+	contentRe *regexp.Regexp
+	sessionRe *regexp.Regexp
+}
+
+// NewGeneric creates a generic backend from a spec.
+func NewGeneric(spec GenericSpec) (*Generic, error) {
+	if spec.Name == "" {
+		return nil, fmt.Errorf("generic backend name is required")
+	}
+	if strings.TrimSpace(spec.Command) == "" {
+		return nil, fmt.Errorf("generic backend %q: command is required", spec.Name)
+	}
+
+	switch spec.Output.Format {
+	case "":
+		spec.Output.Format = GenericOutputText
+	case GenericOutputText:
+	case GenericOutputJSON, GenericOutputJSONL:
+		if spec.Output.ContentPath == "" {
+			return nil, fmt.Errorf("generic backend %q: content path is required for %s output", spec.Name, spec.Output.Format)
+		}
+	default:
+		return nil, fmt.Errorf("generic backend %q: unknown output format %q", spec.Name, spec.Output.Format)
+	}
+
+	if len(spec.PromptArgs) == 0 {
+		spec.PromptArgs = []string{"{{prompt}}"}
+	}
+
+	g := &Generic{spec: spec}
+
+	var err error
+	if spec.Output.ContentRegex != "" {
+		if g.contentRe, err = regexp.Compile(spec.Output.ContentRegex); err != nil {
+			return nil, fmt.Errorf("generic backend %q: invalid content regex: %w", spec.Name, err)
+		}
+	}
+	if spec.Output.SessionIDRegex != "" {
+		if g.sessionRe, err = regexp.Compile(spec.Output.SessionIDRegex); err != nil {
+			return nil, fmt.Errorf("generic backend %q: invalid session ID regex: %w", spec.Name, err)
+		}
+	}
+
+	return g, nil
+}
+
+// Name returns the backend identifier.
+func (g *Generic) Name() string {
+	return g.spec.Name
+}
+
+// IsAvailable checks if the configured command is installed.
+func (g *Generic) IsAvailable() bool {
+	_, err := exec.LookPath(g.spec.Command)
+	return err == nil
+}
+
+// BuildCommand creates an exec.Cmd for running a prompt.
+func (g *Generic) BuildCommand(prompt string, opts *Options) *exec.Cmd {
+	return g.command(g.args(prompt, "", opts), opts)
+}
+
+// ResumeCommand creates an exec.Cmd for resuming a session.
+// Backends without resume_args start a fresh conversation instead.
+func (g *Generic) ResumeCommand(sessionID, prompt string, opts *Options) *exec.Cmd {
+	return g.command(g.args(prompt, sessionID, opts), opts)
+}
+
+// BuildCommandUnified creates an exec.Cmd using unified options.
+func (g *Generic) BuildCommandUnified(prompt string, opts *UnifiedOptions) *exec.Cmd {
+	return g.BuildCommand(prompt, g.mapUnified(opts))
+}
+
+// ResumeCommandUnified creates a resume exec.Cmd using unified options.
+func (g *Generic) ResumeCommandUnified(sessionID, prompt string, opts *UnifiedOptions) *exec.Cmd {
+	return g.ResumeCommand(sessionID, prompt, g.mapUnified(opts))
+}
+
+// args assembles the argument list: base args, resume, model, extra flags, prompt.
+func (g *Generic) args(prompt, sessionID string, opts *Options) []string {
+	args := expandArgs(g.spec.Args, nil)
+
+	if sessionID != "" {
+		args = append(args, expandArgs(g.spec.ResumeArgs, map[string]string{"session_id": sessionID})...)
+	}
+
+	if opts != nil {
+		if opts.Model != "" {
+			args = append(args, expandArgs(g.spec.ModelArgs, map[string]string{"model": opts.Model})...)
+		}
+		args = append(args, opts.ExtraFlags...)
+	}
+
+	if prompt != "" {
+		args = append(args, expandArgs(g.spec.PromptArgs, map[string]string{"prompt": prompt})...)
+	}
+
+	return args
+}
+
+// command creates the exec.Cmd with the configured environment and working directory.
+func (g *Generic) command(args []string, opts *Options) *exec.Cmd {
+	cmd := exec.Command(g.spec.Command, args...)
+	if opts != nil && opts.WorkDir != "" {
+		cmd.Dir = opts.WorkDir
+	}
+	if len(g.spec.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range g.spec.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	return cmd
+}
+
+// mapUnified converts unified options using the spec's flag mappings.
+func (g *Generic) mapUnified(unified *UnifiedOptions) *Options {
+	if unified == nil {
+		return nil
+	}
+
+	opts := &Options{
+		WorkDir:      unified.WorkDir,
+		Model:        unified.Model,
+		AllowedDirs:  unified.AllowedDirs,
+		AllowedTools: unified.AllowedTools,
+		ExtraFlags:   make([]string, 0),
+	}
+
+	if unified.ApprovalMode != "" && unified.ApprovalMode != ApprovalDefault {
+		opts.ExtraFlags = append(opts.ExtraFlags, g.spec.ApprovalFlags[string(unified.ApprovalMode)]...)
+	}
+	if unified.SandboxMode != "" && unified.SandboxMode != SandboxDefault {
+		opts.ExtraFlags = append(opts.ExtraFlags, g.spec.SandboxFlags[string(unified.SandboxMode)]...)
+	}
+	if unified.OutputFormat != "" && unified.OutputFormat != OutputDefault {
+		opts.ExtraFlags = append(opts.ExtraFlags, g.spec.OutputFormatFlags[string(unified.OutputFormat)]...)
+	}
+	if unified.Verbose {
+		opts.ExtraFlags = append(opts.ExtraFlags, g.spec.VerboseFlags...)
+	}
+	if unified.MaxTurns > 0 {
+		opts.ExtraFlags = append(opts.ExtraFlags,
+			expandArgs(g.spec.MaxTurnsArgs, map[string]string{"max_turns": strconv.Itoa(unified.MaxTurns)})...)
+	}
+	if unified.SystemPrompt != "" {
+		opts.ExtraFlags = append(opts.ExtraFlags,
+			expandArgs(g.spec.SystemPromptArgs, map[string]string{"system_prompt": unified.SystemPrompt})...)
+	}
+	if unified.Ephemeral {
+		opts.ExtraFlags = append(opts.ExtraFlags, g.spec.EphemeralFlags...)
+	}
+
+	opts.ExtraFlags = append(opts.ExtraFlags, unified.ExtraFlags...)
+
+	return opts
+}
+
+// expandArgs substitutes {{name}} placeholders in each template argument.
+func expandArgs(templates []string, values map[string]string) []string {
+	if len(templates) == 0 {
+		return nil
+	}
+	args := make([]string, len(templates))
+	for i, tmpl := range templates {
+		arg := tmpl
+		for k, v := range values {
+			arg = strings.ReplaceAll(arg, "{{"+k+"}}", v)
+		}
+		args[i] = arg
+	}
+	return args
+}
+
+// ParseOutput extracts the response text from raw output.
+func (g *Generic) ParseOutput(rawOutput string) string {
+	if g.spec.Output.Format != GenericOutputText {
+		if resp, err := g.ParseJSONResponse(rawOutput); err == nil && resp.Content != "" {
+			return resp.Content
+		}
+		return rawOutput
+	}
+	if content, ok := matchRegex(g.contentRe, rawOutput, "content"); ok {
+		return content
+	}
+	return rawOutput
+}
+
+// ParseJSONResponse extracts a unified response using the spec's output rules.
+func (g *Generic) ParseJSONResponse(rawOutput string) (*UnifiedResponse, error) {
+	switch g.spec.Output.Format {
+	case GenericOutputJSON:
+		cleanOutput := strings.TrimSpace(rawOutput)
+		if idx := strings.Index(cleanOutput, "{"); idx > 0 {
+			cleanOutput = cleanOutput[idx:]
+		}
+		var doc any
+		if err := json.Unmarshal([]byte(cleanOutput), &doc); err != nil {
+			return nil, err
+		}
+		resp := &UnifiedResponse{}
+		g.applyDocument(resp, doc, false)
+		if raw, ok := doc.(map[string]any); ok {
+			resp.Raw = raw
+		}
+		return resp, nil
+
+	case GenericOutputJSONL:
+		resp := &UnifiedResponse{}
+		var parts []string
+		parsed := false
+		for _, line := range strings.Split(rawOutput, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			var doc any
+			if err := json.Unmarshal([]byte(line), &doc); err != nil {
+				continue
+			}
+			parsed = true
+			if text, ok := lookupString(doc, g.spec.Output.ContentPath); ok && text != "" {
+				parts = append(parts, text)
+			}
+			g.applyDocument(resp, doc, true)
+		}
+		if !parsed {
+			return nil, fmt.Errorf("no JSON lines found in %s output", g.spec.Name)
+		}
+		resp.Content = strings.Join(parts, "\n")
+		return resp, nil
+
+	default:
+		resp := &UnifiedResponse{Content: g.ParseOutput(rawOutput)}
+		if sessionID, ok := matchRegex(g.sessionRe, rawOutput, "session_id"); ok {
+			resp.SessionID = sessionID
+		}
+		return resp, nil
+	}
+}
+
+// applyDocument copies fields located by the spec's paths into resp.
+// When merging (JSONL), content is handled by the caller and the first
+// session ID wins while later values overwrite the rest.
+func (g *Generic) applyDocument(resp *UnifiedResponse, doc any, merge bool) {
+	out := g.spec.Output
+
+	if !merge {
+		if text, ok := lookupString(doc, out.ContentPath); ok {
+			resp.Content = text
+		}
+	}
+	if id, ok := lookupString(doc, out.SessionIDPath); ok && id != "" && (!merge || resp.SessionID == "") {
+		resp.SessionID = id
+	}
+	if msg, ok := lookupString(doc, out.ErrorPath); ok && msg != "" {
+		resp.Error = msg
+	}
+	if model, ok := lookupString(doc, out.ModelPath); ok && model != "" {
+		resp.Model = model
+	}
+
+	input, hasInput := lookupInt(doc, out.InputTokensPath)
+	outputTokens, hasOutput := lookupInt(doc, out.OutputTokensPath)
+	if hasInput || hasOutput {
+		resp.Usage = &TokenUsage{
+			InputTokens:  input,
+			OutputTokens: outputTokens,
+			TotalTokens:  input + outputTokens,
+		}
+	}
+}
+
+// DecodeStreamLine converts one line of streamed output into a unified event.
+// Text output becomes message events; JSON lines are mapped via the spec's paths.
+func (g *Generic) DecodeStreamLine(line string) (output.EventType, any, error) {
+	out := g.spec.Output
+
+	var doc any
+	if out.Format == GenericOutputText || json.Unmarshal([]byte(line), &doc) != nil {
+		return output.EventMessage, &output.MessageContent{Text: line, Role: "assistant"}, nil
+	}
+
+	if msg, ok := lookupString(doc, out.ErrorPath); ok && msg != "" {
+		return output.EventError, &output.ErrorContent{Message: msg}, nil
+	}
+	if text, ok := lookupString(doc, out.ContentPath); ok && text != "" {
+		return output.EventMessage, &output.MessageContent{Text: text, Role: "assistant"}, nil
+	}
+	if id, ok := lookupString(doc, out.SessionIDPath); ok && id != "" {
+		model, _ := lookupString(doc, out.ModelPath)
+		return output.EventInit, &output.InitContent{Model: model, BackendSessionID: id}, nil
+	}
+	input, hasInput := lookupInt(doc, out.InputTokensPath)
+	outputTokens, hasOutput := lookupInt(doc, out.OutputTokensPath)
+	if hasInput || hasOutput {
+		return output.EventDone, &output.DoneContent{
+			TokenUsage: &output.TokenUsageContent{
+				InputTokens:  int64(input),
+				OutputTokens: int64(outputTokens),
+			},
+		}, nil
+	}
+
+	return "", nil, nil
+}
+
+// SeparateStderr returns whether stderr is captured separately, as configured.
+func (g *Generic) SeparateStderr() bool {
+	return g.spec.SeparateStderr
+}
+
+// Capabilities derives the supported options from the configured templates.
+// A mode counts as supported only if it maps to flags.
+func (g *Generic) Capabilities() Capabilities {
+	caps := Capabilities{
+		Resume:       len(g.spec.ResumeArgs) > 0,
+		Streaming:    true,
+		SystemPrompt: len(g.spec.SystemPromptArgs) > 0,
+		MaxTurns:     len(g.spec.MaxTurnsArgs) > 0,
+	}
+	for _, mode := range AllApprovalModes {
+		if len(g.spec.ApprovalFlags[string(mode)]) > 0 {
+			caps.ApprovalModes = append(caps.ApprovalModes, mode)
+		}
+	}
+	for _, mode := range AllSandboxModes {
+		if len(g.spec.SandboxFlags[string(mode)]) > 0 {
+			caps.SandboxModes = append(caps.SandboxModes, mode)
+		}
+	}
+	return caps
+}
+
+// matchRegex returns the named group (or first group, or whole match) of re in s.
+func matchRegex(re *regexp.Regexp, s, group string) (string, bool) {
+	if re == nil {
+		return "", false
+	}
+	m := re.FindStringSubmatch(s)
+	if m == nil {
+		return "", false
+	}
+	if idx := re.SubexpIndex(group); idx > 0 {
+		return strings.TrimSpace(m[idx]), true
+	}
+	if len(m) > 1 {
+		return strings.TrimSpace(m[1]), true
+	}
+	return strings.TrimSpace(m[0]), true
+}
+
+// lookupPath resolves a jq-like path such as ".result.items[0].text" in doc.
+func lookupPath(doc any, path string) (any, bool) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), ".")
+	if path == "" {
+		return nil, false
+	}
+
+	cur := doc
+	for _, segment := range strings.Split(path, ".") {
+		key := segment
+		var indexes []string
+		if i := strings.Index(segment, "["); i >= 0 {
+			key = segment[:i]
+			for _, part := range strings.Split(segment[i+1:], "[") {
+				indexes = append(indexes, strings.TrimSuffix(part, "]"))
+			}
+		}
+
+		if key != "" {
+			obj, ok := cur.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if cur, ok = obj[key]; !ok {
+				return nil, false
+			}
+		}
+
+		for _, idx := range indexes {
+			arr, ok := cur.([]any)
+			if !ok {
+				return nil, false
+			}
+			n, err := strconv.Atoi(idx)
+			if err != nil {
+				return nil, false
+			}
+			if n < 0 {
+				n += len(arr)
+			}
+			if n < 0 || n >= len(arr) {
+				return nil, false
+			}
+			cur = arr[n]
+		}
+	}
+	return cur, true
+}
+
+// lookupString resolves path and converts the value to a string.
+func lookupString(doc any, path string) (string, bool) {
+	v, ok := lookupPath(doc, path)
+	if !ok || v == nil {
+		return "", false
+	}
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(val), true
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return "", false
+		}
+		return string(data), true
+	}
+}
+
+// lookupInt resolves path and converts the value to an int.
+func lookupInt(doc any, path string) (int, bool) {
+	v, ok := lookupPath(doc, path)
+	if !ok {
+		return 0, false
+	}
+	switch val := v.(type) {
+	case float64:
+		return int(val), true
+	case string:
+		n, err := strconv.Atoi(val)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,288 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/signalridge/clinvoker/internal/output"
+)
+
+func newTestGeneric(t *testing.T, spec GenericSpec) *Generic {
+	t.Helper()
+	if spec.Name == "" {
+		spec.Name = "aider"
+	}
+	if spec.Command == "" {
+		spec.Command = "aider"
+	}
+	g, err := NewGeneric(spec)
+	if err != nil {
+		t.Fatalf("NewGeneric() error = %v", err)
+	}
+	return g
+}
+
+func TestNewGeneric(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    GenericSpec
+		wantErr string
+	}{
+		{"missing name", GenericSpec{Command: "x"}, "name is required"},
+		{"missing command", GenericSpec{Name: "x"}, "command is required"},
+		{"json without content path", GenericSpec{Name: "x", Command: "x", Output: GenericOutputSpec{Format: "json"}}, "content path"},
+		{"unknown format", GenericSpec{Name: "x", Command: "x", Output: GenericOutputSpec{Format: "xml"}}, "unknown output format"},
+		{"bad regex", GenericSpec{Name: "x", Command: "x", Output: GenericOutputSpec{ContentRegex: "("}}, "invalid content regex"},
+		{"valid text", GenericSpec{Name: "x", Command: "x"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGeneric(tt.spec)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGenericBuildCommand(t *testing.T) {
+	g := newTestGeneric(t, GenericSpec{
+		Args:             []string{"--no-pretty"},
+		PromptArgs:       []string{"--message", "{{prompt}}"},
+		ResumeArgs:       []string{"--restore", "{{session_id}}"},
+		ModelArgs:        []string{"--model={{model}}"},
+		SystemPromptArgs: []string{"--system", "{{system_prompt}}"},
+		MaxTurnsArgs:     []string{"--max-turns", "{{max_turns}}"},
+		ApprovalFlags:    map[string][]string{"none": {"--yes-always"}},
+		SandboxFlags:     map[string][]string{"read-only": {"--dry-run"}},
+		OutputFormatFlags: map[string][]string{
+			"json": {"--json"},
+		},
+		Env: map[string]string{"AIDER_TEST": "1"},
+	})
+
+	t.Run("unified options map to configured flags", func(t *testing.T) {
+		cmd := g.BuildCommandUnified("fix it", &UnifiedOptions{
+			WorkDir:      "/work",
+			Model:        "sonnet",
+			ApprovalMode: ApprovalNone,
+			SandboxMode:  SandboxReadOnly,
+			OutputFormat: OutputJSON,
+			SystemPrompt: "be brief",
+			MaxTurns:     3,
+			ExtraFlags:   []string{"--cache"},
+		})
+
+		want := "aider --no-pretty --model=sonnet --yes-always --dry-run --json --max-turns 3 --system be brief --cache --message fix it"
+		if got := strings.Join(cmd.Args, " "); got != want {
+			t.Errorf("args = %q, want %q", got, want)
+		}
+		if cmd.Dir != "/work" {
+			t.Errorf("expected workdir '/work', got %q", cmd.Dir)
+		}
+		found := false
+		for _, env := range cmd.Env {
+			if env == "AIDER_TEST=1" {
+				found = true
+			}
+		}
+		if !found {
+			t.Error("expected configured env var to be set")
+		}
+	})
+
+	t.Run("unmapped modes add no flags", func(t *testing.T) {
+		cmd := g.BuildCommandUnified("hi", &UnifiedOptions{ApprovalMode: ApprovalAuto, SandboxMode: SandboxFull})
+		if got := strings.Join(cmd.Args, " "); got != "aider --no-pretty --message hi" {
+			t.Errorf("unexpected args: %q", got)
+		}
+	})
+
+	t.Run("resume includes session args", func(t *testing.T) {
+		cmd := g.ResumeCommandUnified("abc", "again", nil)
+		if got := strings.Join(cmd.Args, " "); got != "aider --no-pretty --restore abc --message again" {
+			t.Errorf("unexpected args: %q", got)
+		}
+	})
+
+	t.Run("default prompt args", func(t *testing.T) {
+		plain := newTestGeneric(t, GenericSpec{})
+		cmd := plain.BuildCommand("hello", nil)
+		if got := strings.Join(cmd.Args, " "); got != "aider hello" {
+			t.Errorf("unexpected args: %q", got)
+		}
+	})
+}
+
+func TestGenericParseJSONResponse(t *testing.T) {
+	t.Run("json paths", func(t *testing.T) {
+		g := newTestGeneric(t, GenericSpec{Output: GenericOutputSpec{
+			Format:           GenericOutputJSON,
+			ContentPath:      ".result.messages[-1].text",
+			SessionIDPath:    "session",
+			ModelPath:        "meta.model",
+			InputTokensPath:  "usage.in",
+			OutputTokensPath: "usage.out",
+		}})
+
+		raw := `noise {"session":"s-1","meta":{"model":"m"},"usage":{"in":10,"out":5},` +
+			`"result":{"messages":[{"text":"first"},{"text":"last"}]}}`
+		resp, err := g.ParseJSONResponse(raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Content != "last" || resp.SessionID != "s-1" || resp.Model != "m" {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+		if resp.Usage == nil || resp.Usage.InputTokens != 10 || resp.Usage.OutputTokens != 5 || resp.Usage.TotalTokens != 15 {
+			t.Errorf("unexpected usage: %+v", resp.Usage)
+		}
+		if g.ParseOutput(raw) != "last" {
+			t.Errorf("ParseOutput() = %q, want 'last'", g.ParseOutput(raw))
+		}
+	})
+
+	t.Run("json error path", func(t *testing.T) {
+		g := newTestGeneric(t, GenericSpec{Output: GenericOutputSpec{
+			Format: GenericOutputJSON, ContentPath: "text", ErrorPath: "error.message",
+		}})
+		resp, err := g.ParseJSONResponse(`{"error":{"message":"rate limited"}}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Error != "rate limited" {
+			t.Errorf("expected error message, got %+v", resp)
+		}
+	})
+
+	t.Run("invalid json returns error", func(t *testing.T) {
+		g := newTestGeneric(t, GenericSpec{Output: GenericOutputSpec{Format: GenericOutputJSON, ContentPath: "text"}})
+		if _, err := g.ParseJSONResponse("plain failure"); err == nil {
+			t.Error("expected error for non-JSON output")
+		}
+	})
+
+	t.Run("jsonl merges lines", func(t *testing.T) {
+		g := newTestGeneric(t, GenericSpec{Output: GenericOutputSpec{
+			Format:           GenericOutputJSONL,
+			ContentPath:      "text",
+			SessionIDPath:    "id",
+			OutputTokensPath: "tokens",
+		}})
+		raw := "{\"id\":\"a\"}\nnot json\n{\"text\":\"one\",\"id\":\"b\"}\n{\"text\":\"two\"}\n{\"tokens\":7}\n"
+		resp, err := g.ParseJSONResponse(raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Content != "one\ntwo" || resp.SessionID != "a" {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+		if resp.Usage == nil || resp.Usage.OutputTokens != 7 {
+			t.Errorf("unexpected usage: %+v", resp.Usage)
+		}
+	})
+
+	t.Run("text regex", func(t *testing.T) {
+		g := newTestGeneric(t, GenericSpec{Output: GenericOutputSpec{
+			ContentRegex:   `(?s)Answer:\s*(?P<content>.*)`,
+			SessionIDRegex: `session=(\S+)`,
+		}})
+		resp, err := g.ParseJSONResponse("session=xyz\nAnswer: 42\n")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Content != "42" || resp.SessionID != "xyz" {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("text without regex returns raw output", func(t *testing.T) {
+		g := newTestGeneric(t, GenericSpec{})
+		if got := g.ParseOutput("hello"); got != "hello" {
+			t.Errorf("ParseOutput() = %q, want 'hello'", got)
+		}
+	})
+}
+
+func TestGenericDecodeStreamLine(t *testing.T) {
+	g := newTestGeneric(t, GenericSpec{Output: GenericOutputSpec{
+		Format:          GenericOutputJSONL,
+		ContentPath:     "delta",
+		SessionIDPath:   "session",
+		ErrorPath:       "error",
+		InputTokensPath: "usage.input",
+	}})
+
+	tests := []struct {
+		line string
+		want output.EventType
+	}{
+		{`{"session":"s"}`, output.EventInit},
+		{`{"delta":"hi"}`, output.EventMessage},
+		{`{"error":"boom"}`, output.EventError},
+		{`{"usage":{"input":3}}`, output.EventDone},
+		{`{"other":true}`, ""},
+		{`plain text`, output.EventMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, _, err := g.DecodeStreamLine(tt.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("event type = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenericCapabilities(t *testing.T) {
+	g := newTestGeneric(t, GenericSpec{
+		Name:             "aider",
+		Command:          "aider",
+		ResumeArgs:       []string{"--restore-chat-history"},
+		SystemPromptArgs: []string{"--system", "{{system_prompt}}"},
+		ApprovalFlags:    map[string][]string{"none": {"--yes-always"}},
+		SandboxFlags:     map[string][]string{"read-only": {"--dry-run"}, "full": nil},
+	})
+
+	caps := g.Capabilities()
+	if !caps.Resume || !caps.SystemPrompt {
+		t.Errorf("expected resume and system prompt from templates: %+v", caps)
+	}
+	if caps.MaxTurns || caps.MaxTokens {
+		t.Errorf("expected no turn or token limits without templates: %+v", caps)
+	}
+	if !caps.SupportsApprovalMode(ApprovalNone) || caps.SupportsApprovalMode(ApprovalAuto) {
+		t.Errorf("unexpected approval modes: %v", caps.ApprovalModes)
+	}
+	if !caps.SupportsSandboxMode(SandboxReadOnly) || caps.SupportsSandboxMode(SandboxFull) {
+		t.Errorf("unexpected sandbox modes: %v", caps.SandboxModes)
+	}
+
+	bare := newTestGeneric(t, GenericSpec{Name: "bare", Command: "bare"})
+	if bare.Capabilities().Resume {
+		t.Error("expected no resume without resume_args")
+	}
+}
+
+func TestRegisterAllowedFlags(t *testing.T) {
+	RegisterAllowedFlags("aider", []string{"--cache"})
+	t.Cleanup(func() { UnregisterAllowedFlags("aider") })
+
+	if err := ValidateExtraFlagsForBackend("aider", []string{"--cache"}); err != nil {
+		t.Errorf("expected registered flag to be allowed: %v", err)
+	}
+	if err := ValidateExtraFlagsForBackend("aider", []string{"--model", "x"}); err == nil {
+		t.Error("expected flag of another backend to be rejected")
+	}
+}
@@ -0,0 +1,406 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// MCPServer declares an MCP server for the backend to connect to. A server
+// is either started as a command and spoken to over stdio, or reached at
+// the URL of its streamable HTTP endpoint.
+//
+// Secrets belong in the environment rather than the declaration: EnvVars
+// forwards variables from clinvk's environment to a command, and
+// BearerTokenEnv names the variable holding the token sent to a URL.
+type MCPServer struct {
+	// Command starts a stdio server.
+	Command string `json:"command,omitempty" doc:"Command that starts a stdio server"`
+
+	// Args are the arguments of Command.
+	Args []string `json:"args,omitempty" doc:"Arguments of command"`
+
+	// Env sets environment variables for Command, as KEY=value.
+	Env []string `json:"env,omitempty" doc:"Environment variables for command, as KEY=value"`
+
+	// EnvVars names variables forwarded to Command from clinvk's environment.
+	EnvVars []string `json:"env_vars,omitempty" doc:"Variables forwarded to command from the environment"`
+
+	// URL is the streamable HTTP endpoint of a remote server.
+	URL string `json:"url,omitempty" doc:"Streamable HTTP endpoint of a remote server"`
+
+	// Headers are sent with every request to URL.
+	Headers map[string]string `json:"headers,omitempty" doc:"Headers sent to url"`
+
+	// BearerTokenEnv names the variable holding a bearer token for URL.
+	BearerTokenEnv string `json:"bearer_token_env,omitempty" doc:"Variable holding a bearer token for url"`
+}
+
+var (
+	// mcpServerNamePattern keeps names usable as keys of every backend's
+	// config, including Codex's dotted -c overrides.
+	mcpServerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+	envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ValidateMCPServers reports the first invalid server declaration.
+func ValidateMCPServers(servers map[string]MCPServer) error {
+	for _, name := range sortedMCPServerNames(servers) {
+		if !mcpServerNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid MCP server name %q (use letters, digits, '-' and '_')", name)
+		}
+		if err := servers[name].validate(); err != nil {
+			return fmt.Errorf("MCP server %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks that the server is either a command or a URL, with only
+// the settings that apply to it.
+func (s MCPServer) validate() error {
+	switch {
+	case s.Command == "" && s.URL == "":
+		return errors.New("command or url is required")
+	case s.Command != "" && s.URL != "":
+		return errors.New("command and url are mutually exclusive")
+	}
+
+	if s.URL != "" {
+		if len(s.Args) > 0 || len(s.Env) > 0 || len(s.EnvVars) > 0 {
+			return errors.New("args, env and env_vars apply only to command servers")
+		}
+		if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid url %q (must be http or https)", s.URL)
+		}
+		if s.BearerTokenEnv != "" && !envVarNamePattern.MatchString(s.BearerTokenEnv) {
+			return fmt.Errorf("invalid bearer_token_env %q", s.BearerTokenEnv)
+		}
+		return nil
+	}
+
+	if len(s.Headers) > 0 || s.BearerTokenEnv != "" {
+		return errors.New("headers and bearer_token_env apply only to url servers")
+	}
+	for _, kv := range s.Env {
+		if key, _, ok := strings.Cut(kv, "="); !ok || !envVarNamePattern.MatchString(key) {
+			return fmt.Errorf("invalid env entry %q (want KEY=value)", kv)
+		}
+	}
+	for _, name := range s.EnvVars {
+		if !envVarNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid env_vars entry %q", name)
+		}
+	}
+	return nil
+}
+
+// env returns the environment of a command server: Env, then EnvVars as
+// ${NAME} references the backend expands from its own environment.
+func (s MCPServer) env() map[string]string {
+	if len(s.Env) == 0 && len(s.EnvVars) == 0 {
+		return nil
+	}
+	env := make(map[string]string, len(s.Env)+len(s.EnvVars))
+	for _, kv := range s.Env {
+		key, value, _ := strings.Cut(kv, "=")
+		env[key] = value
+	}
+	for _, name := range s.EnvVars {
+		env[name] = "${" + name + "}"
+	}
+	return env
+}
+
+// headers returns the headers of a URL server, with the bearer token as a
+// ${NAME} reference the backend expands from its own environment.
+func (s MCPServer) headers() map[string]string {
+	if len(s.Headers) == 0 && s.BearerTokenEnv == "" {
+		return nil
+	}
+	headers := make(map[string]string, len(s.Headers)+1)
+	for k, v := range s.Headers {
+		headers[k] = v
+	}
+	if s.BearerTokenEnv != "" {
+		headers["Authorization"] = "Bearer ${" + s.BearerTokenEnv + "}"
+	}
+	return headers
+}
+
+// sortedMCPServerNames returns the server names in a stable order, so the
+// same declaration always maps to the same flags and files.
+func sortedMCPServerNames(servers map[string]MCPServer) []string {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// geminiSettingsPathEnv points Gemini CLI at the settings file holding the
+// declared servers. Gemini gives these system settings precedence over the
+// user's own.
+const geminiSettingsPathEnv = "GEMINI_CLI_SYSTEM_SETTINGS_PATH"
+
+// geminiSystemSettingsPath returns the system settings file Gemini CLI
+// reads when it is not redirected, which the generated file must carry
+// over. It is a variable so tests can redirect it.
+var geminiSystemSettingsPath = func() string {
+	if path := os.Getenv(geminiSettingsPathEnv); path != "" {
+		return path
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return "/Library/Application Support/GeminiCli/settings.json"
+	case "windows":
+		return `C:\ProgramData\gemini-cli\settings.json`
+	default:
+		return "/etc/gemini-cli/settings.json"
+	}
+}
+
+// mcpConfigDir returns the directory generated MCP config files are
+// written to. It is a variable so tests can redirect it.
+var mcpConfigDir = func() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "clinvk-mcp")
+	}
+	return filepath.Join(home, ".clinvk", "mcp")
+}
+
+// mapMCPServers translates servers into the backend's native mechanism,
+// returning the flags to add and the environment to set:
+//
+//   - Claude reads a --mcp-config file.
+//   - Codex takes -c overrides of its mcp_servers table.
+//   - Gemini reads a settings file named by GEMINI_CLI_SYSTEM_SETTINGS_PATH.
+func (m *flagMapper) mapMCPServers(servers map[string]MCPServer) (flags, env []string) {
+	if len(servers) == 0 {
+		return nil, nil
+	}
+
+	switch m.backend {
+	case "claude":
+		data := claudeMCPConfig(servers)
+		path, err := writeMCPConfig("claude", data)
+		if err != nil {
+			// The flag also takes the JSON itself
+			return []string{"--mcp-config", string(data)}, nil
+		}
+		return []string{"--mcp-config", path}, nil
+
+	case "codex":
+		return codexMCPOverrides(servers), nil
+
+	case "gemini":
+		data, err := geminiMCPSettings(servers)
+		if err != nil {
+			// Replacing the system settings would drop the administrator's ones
+			slog.Warn("failed to read Gemini system settings; MCP servers ignored", "error", err)
+			return nil, nil
+		}
+		path, err := writeMCPConfig("gemini", data)
+		if err != nil {
+			slog.Warn("failed to write Gemini MCP settings; MCP servers ignored", "error", err)
+			return nil, nil
+		}
+		return nil, []string{geminiSettingsPathEnv + "=" + path}
+	}
+	return nil, nil
+}
+
+// claudeMCPConfig returns the --mcp-config JSON for servers.
+func claudeMCPConfig(servers map[string]MCPServer) []byte {
+	entries := make(map[string]any, len(servers))
+	for name, s := range servers {
+		if s.URL != "" {
+			entries[name] = map[string]any{"type": "http", "url": s.URL, "headers": s.headers()}
+		} else {
+			entries[name] = map[string]any{"type": "stdio", "command": s.Command, "args": s.Args, "env": s.env()}
+		}
+	}
+	return marshalMCPConfig(nil, entries)
+}
+
+// geminiMCPSettings returns a Gemini settings file declaring servers. The
+// file replaces the system settings, so it starts from those when they
+// exist; a declared server replaces a system one of the same name.
+func geminiMCPSettings(servers map[string]MCPServer) ([]byte, error) {
+	entries := make(map[string]any, len(servers))
+	for name, s := range servers {
+		if s.URL != "" {
+			entries[name] = map[string]any{"httpUrl": s.URL, "headers": s.headers()}
+		} else {
+			entries[name] = map[string]any{"command": s.Command, "args": s.Args, "env": s.env()}
+		}
+	}
+
+	path := geminiSystemSettingsPath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return marshalMCPConfig(nil, entries), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var settings map[string]any
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("invalid settings file %s: %w", path, err)
+	}
+	return marshalMCPConfig(settings, entries), nil
+}
+
+// marshalMCPConfig adds entries to the mcpServers object both Claude and
+// Gemini read, dropping unset fields. The object is kept in settings, which
+// may be nil, along with its other servers.
+func marshalMCPConfig(settings map[string]any, entries map[string]any) []byte {
+	for _, entry := range entries {
+		fields := entry.(map[string]any)
+		for k, v := range fields {
+			switch v := v.(type) {
+			case []string:
+				if len(v) == 0 {
+					delete(fields, k)
+				}
+			case map[string]string:
+				if len(v) == 0 {
+					delete(fields, k)
+				}
+			}
+		}
+	}
+	if settings == nil {
+		settings = map[string]any{}
+	}
+	mcpServers, _ := settings["mcpServers"].(map[string]any)
+	if mcpServers == nil {
+		mcpServers = make(map[string]any, len(entries))
+	}
+	for name, entry := range entries {
+		mcpServers[name] = entry
+	}
+	settings["mcpServers"] = mcpServers
+
+	// Maps marshal with sorted keys, so equal declarations give equal bytes
+	data, _ := json.Marshal(settings)
+	return data
+}
+
+// codexMCPOverrides returns the -c flags declaring servers in Codex's
+// mcp_servers table. Codex forwards env_vars and reads the bearer token
+// from its variable itself.
+func codexMCPOverrides(servers map[string]MCPServer) []string {
+	var flags []string
+	set := func(name, key, value string) {
+		flags = append(flags, "-c", fmt.Sprintf("mcp_servers.%s.%s=%s", name, key, value))
+	}
+
+	for _, name := range sortedMCPServerNames(servers) {
+		s := servers[name]
+		if s.URL != "" {
+			set(name, "url", tomlString(s.URL))
+			if len(s.Headers) > 0 {
+				set(name, "http_headers", tomlTable(s.Headers))
+			}
+			if s.BearerTokenEnv != "" {
+				set(name, "bearer_token_env_var", tomlString(s.BearerTokenEnv))
+			}
+			continue
+		}
+
+		set(name, "command", tomlString(s.Command))
+		if len(s.Args) > 0 {
+			set(name, "args", tomlArray(s.Args))
+		}
+		if len(s.Env) > 0 {
+			env := make(map[string]string, len(s.Env))
+			for _, kv := range s.Env {
+				key, value, _ := strings.Cut(kv, "=")
+				env[key] = value
+			}
+			set(name, "env", tomlTable(env))
+		}
+		if len(s.EnvVars) > 0 {
+			set(name, "env_vars", tomlArray(s.EnvVars))
+		}
+	}
+	return flags
+}
+
+// tomlString quotes s as a TOML basic string, whose escapes JSON shares.
+func tomlString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// tomlArray returns values as a TOML array of strings.
+func tomlArray(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = tomlString(v)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// tomlTable returns values as a TOML inline table with quoted keys.
+func tomlTable(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = tomlString(k) + " = " + tomlString(values[k])
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// writeMCPConfig writes data to a file under mcpConfigDir named by its
+// hash and returns the path. Runs declaring the same servers share the
+// file, so there is nothing to clean up after each run.
+func writeMCPConfig(prefix string, data []byte) (string, error) {
+	dir := mcpConfigDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	path := filepath.Join(dir, fmt.Sprintf("%s-%x.json", prefix, sum[:12]))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	// Write and rename, so concurrent runs never read a partial file
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
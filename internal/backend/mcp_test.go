@@ -0,0 +1,246 @@
+package backend
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// useMCPConfigDir redirects generated MCP config files to a temporary
+// directory, where Gemini's system settings are looked for too.
+func useMCPConfigDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	origDir, origSettings := mcpConfigDir, geminiSystemSettingsPath
+	mcpConfigDir = func() string { return dir }
+	geminiSystemSettingsPath = func() string { return filepath.Join(dir, "system-settings.json") }
+	t.Cleanup(func() { mcpConfigDir, geminiSystemSettingsPath = origDir, origSettings })
+	return dir
+}
+
+var testMCPServers = map[string]MCPServer{
+	"github": {
+		Command: "npx",
+		Args:    []string{"-y", "github-mcp"},
+		Env:     []string{"LOG_LEVEL=debug"},
+		EnvVars: []string{"GITHUB_TOKEN"},
+	},
+	"docs": {
+		URL:            "https://docs.example.com/mcp",
+		Headers:        map[string]string{"X-Team": "core"},
+		BearerTokenEnv: "DOCS_TOKEN",
+	},
+}
+
+func TestValidateMCPServers(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers map[string]MCPServer
+		wantErr string
+	}{
+		{name: "none"},
+		{name: "valid", servers: testMCPServers},
+		{name: "bad name", servers: map[string]MCPServer{"a.b": {Command: "x"}}, wantErr: "invalid MCP server name"},
+		{name: "neither", servers: map[string]MCPServer{"s": {}}, wantErr: "command or url is required"},
+		{name: "both", servers: map[string]MCPServer{"s": {Command: "x", URL: "https://x"}}, wantErr: "mutually exclusive"},
+		{name: "bad url", servers: map[string]MCPServer{"s": {URL: "ftp://x"}}, wantErr: "invalid url"},
+		{name: "args on url", servers: map[string]MCPServer{"s": {URL: "https://x", Args: []string{"a"}}}, wantErr: "only to command servers"},
+		{name: "headers on command", servers: map[string]MCPServer{"s": {Command: "x", Headers: map[string]string{"A": "b"}}}, wantErr: "only to url servers"},
+		{name: "bad env", servers: map[string]MCPServer{"s": {Command: "x", Env: []string{"NOVALUE"}}}, wantErr: "invalid env entry"},
+		{name: "bad env var", servers: map[string]MCPServer{"s": {Command: "x", EnvVars: []string{"A-B"}}}, wantErr: "invalid env_vars entry"},
+		{name: "bad token env", servers: map[string]MCPServer{"s": {URL: "https://x", BearerTokenEnv: "$TOKEN"}}, wantErr: "invalid bearer_token_env"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMCPServers(tt.servers)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMapMCPServers_Claude(t *testing.T) {
+	useMCPConfigDir(t)
+
+	flags, env := newFlagMapper("claude").mapMCPServers(testMCPServers)
+	if len(flags) != 2 || flags[0] != "--mcp-config" || len(env) != 0 {
+		t.Fatalf("flags = %v, env = %v; want --mcp-config <file>", flags, env)
+	}
+
+	var config struct {
+		MCPServers map[string]map[string]any `json:"mcpServers"`
+	}
+	data, err := os.ReadFile(flags[1])
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+
+	github := config.MCPServers["github"]
+	if github["type"] != "stdio" || github["command"] != "npx" {
+		t.Errorf("github = %v, want a stdio server running npx", github)
+	}
+	if env, _ := github["env"].(map[string]any); env["LOG_LEVEL"] != "debug" || env["GITHUB_TOKEN"] != "${GITHUB_TOKEN}" {
+		t.Errorf("github env = %v", github["env"])
+	}
+	docs := config.MCPServers["docs"]
+	if docs["type"] != "http" || docs["url"] != "https://docs.example.com/mcp" {
+		t.Errorf("docs = %v, want an http server", docs)
+	}
+	if headers, _ := docs["headers"].(map[string]any); headers["Authorization"] != "Bearer ${DOCS_TOKEN}" || headers["X-Team"] != "core" {
+		t.Errorf("docs headers = %v", docs["headers"])
+	}
+
+	// The same servers map to the same file
+	again, _ := newFlagMapper("claude").mapMCPServers(testMCPServers)
+	if again[1] != flags[1] {
+		t.Errorf("second run wrote %s, want %s", again[1], flags[1])
+	}
+}
+
+func TestMapMCPServers_Claude_InlineFallback(t *testing.T) {
+	dir := useMCPConfigDir(t)
+	// A file where the directory should be makes the write fail
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.WriteFile(blocked, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mcpConfigDir = func() string { return blocked }
+
+	flags, _ := newFlagMapper("claude").mapMCPServers(testMCPServers)
+	if len(flags) != 2 || !json.Valid([]byte(flags[1])) {
+		t.Errorf("flags = %v, want the config passed inline", flags)
+	}
+}
+
+func TestMapMCPServers_Codex(t *testing.T) {
+	flags, env := newFlagMapper("codex").mapMCPServers(testMCPServers)
+	if len(env) != 0 {
+		t.Errorf("env = %v, want none", env)
+	}
+
+	want := []string{
+		`mcp_servers.docs.url="https://docs.example.com/mcp"`,
+		`mcp_servers.docs.http_headers={"X-Team" = "core"}`,
+		`mcp_servers.docs.bearer_token_env_var="DOCS_TOKEN"`,
+		`mcp_servers.github.command="npx"`,
+		`mcp_servers.github.args=["-y", "github-mcp"]`,
+		`mcp_servers.github.env={"LOG_LEVEL" = "debug"}`,
+		`mcp_servers.github.env_vars=["GITHUB_TOKEN"]`,
+	}
+	if len(flags) != 2*len(want) {
+		t.Fatalf("flags = %v, want %d overrides", flags, len(want))
+	}
+	for i, override := range want {
+		if flags[2*i] != "-c" || flags[2*i+1] != override {
+			t.Errorf("override %d = %s %s, want -c %s", i, flags[2*i], flags[2*i+1], override)
+		}
+	}
+}
+
+func TestMapMCPServers_Gemini(t *testing.T) {
+	useMCPConfigDir(t)
+
+	flags, env := newFlagMapper("gemini").mapMCPServers(testMCPServers)
+	if len(flags) != 0 || len(env) != 1 {
+		t.Fatalf("flags = %v, env = %v; want only the settings path", flags, env)
+	}
+	path, ok := strings.CutPrefix(env[0], geminiSettingsPathEnv+"=")
+	if !ok {
+		t.Fatalf("env = %v, want %s", env, geminiSettingsPathEnv)
+	}
+
+	var settings struct {
+		MCPServers map[string]map[string]any `json:"mcpServers"`
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read settings: %v", err)
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		t.Fatalf("invalid settings: %v", err)
+	}
+	if settings.MCPServers["github"]["command"] != "npx" {
+		t.Errorf("github = %v", settings.MCPServers["github"])
+	}
+	if settings.MCPServers["docs"]["httpUrl"] != "https://docs.example.com/mcp" {
+		t.Errorf("docs = %v", settings.MCPServers["docs"])
+	}
+}
+
+func TestMapMCPServers_GeminiSystemSettings(t *testing.T) {
+	dir := useMCPConfigDir(t)
+	system := `{"security":{"auth":{"enforcedType":"oauth-personal"}},` +
+		`"mcpServers":{"audit":{"command":"audit-mcp"},"docs":{"httpUrl":"https://old.example.com/mcp"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "system-settings.json"), []byte(system), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, env := newFlagMapper("gemini").mapMCPServers(testMCPServers)
+	if len(env) != 1 {
+		t.Fatalf("env = %v, want the settings path", env)
+	}
+	data, err := os.ReadFile(strings.TrimPrefix(env[0], geminiSettingsPathEnv+"="))
+	if err != nil {
+		t.Fatalf("failed to read settings: %v", err)
+	}
+	var settings struct {
+		Security struct {
+			Auth struct {
+				EnforcedType string `json:"enforcedType"`
+			} `json:"auth"`
+		} `json:"security"`
+		MCPServers map[string]map[string]any `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		t.Fatalf("invalid settings: %v", err)
+	}
+	if settings.Security.Auth.EnforcedType != "oauth-personal" {
+		t.Errorf("expected the system settings to survive, got %s", data)
+	}
+	if settings.MCPServers["audit"]["command"] != "audit-mcp" || settings.MCPServers["github"]["command"] != "npx" {
+		t.Errorf("expected system and declared servers, got %v", settings.MCPServers)
+	}
+	if settings.MCPServers["docs"]["httpUrl"] != "https://docs.example.com/mcp" {
+		t.Errorf("expected the declared docs server to win, got %v", settings.MCPServers["docs"])
+	}
+
+	// Settings that cannot be carried over are not replaced.
+	if err := os.WriteFile(filepath.Join(dir, "system-settings.json"), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, env := newFlagMapper("gemini").mapMCPServers(testMCPServers); env != nil {
+		t.Errorf("env = %v, want none with unreadable system settings", env)
+	}
+}
+
+func TestMapMCPServers_UnknownBackend(t *testing.T) {
+	flags, env := newFlagMapper("unknown").mapMCPServers(testMCPServers)
+	if flags != nil || env != nil {
+		t.Errorf("flags = %v, env = %v; want none", flags, env)
+	}
+}
+
+func TestGemini_BuildCommandUnified_MCPServers(t *testing.T) {
+	useMCPConfigDir(t)
+
+	cmd := (&Gemini{}).BuildCommandUnified("hello", &UnifiedOptions{MCPServers: testMCPServers})
+	if !slices.ContainsFunc(cmd.Env, func(kv string) bool {
+		return strings.HasPrefix(kv, geminiSettingsPathEnv+"=")
+	}) {
+		t.Errorf("expected %s in the command environment", geminiSettingsPathEnv)
+	}
+}
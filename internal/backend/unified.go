@@ -3,6 +3,7 @@ package backend
 import (
 	"fmt"
 	"strings"
+	"sync"
 )
 
 // allowedFlagPatterns defines the allowlist of flags permitted for each backend.
@@ -21,11 +22,29 @@ var allowedFlagPatterns = map[string][]string{
 	"gemini": {
 		"--model", "--output-format", "--sandbox", "--approval-mode",
 		"--yolo", "--debug", "--color", "--disable-color",
+		"--allowed-mcp-server-names",
 	},
 	// Common short flags allowed across all backends
 	"common": {"-v", "-m", "-o", "-q", "-h", "--help", "--version"},
 }
 
+// allowedFlagsMu guards allowedFlagPatterns, which config-declared backends extend at startup.
+var allowedFlagsMu sync.RWMutex
+
+// RegisterAllowedFlags sets the extra-flag allowlist for a backend that is not built in.
+func RegisterAllowedFlags(backendName string, flags []string) {
+	allowedFlagsMu.Lock()
+	defer allowedFlagsMu.Unlock()
+	allowedFlagPatterns[backendName] = append([]string(nil), flags...)
+}
+
+// UnregisterAllowedFlags removes the extra-flag allowlist for a backend.
+func UnregisterAllowedFlags(backendName string) {
+	allowedFlagsMu.Lock()
+	defer allowedFlagsMu.Unlock()
+	delete(allowedFlagPatterns, backendName)
+}
+
 // booleanFlags defines flags that do NOT take a value (boolean flags).
 // This prevents the flag validator from incorrectly consuming the next argument.
 // Note: flags not in this set are assumed to potentially take a value.
@@ -54,6 +73,9 @@ var booleanFlags = map[string]bool{
 
 // buildAllowedSet builds a case-insensitive set of allowed flags for a backend.
 func buildAllowedSet(backend string) map[string]bool {
+	allowedFlagsMu.RLock()
+	defer allowedFlagsMu.RUnlock()
+
 	allowed := make(map[string]bool)
 
 	// Add common flags
@@ -92,11 +114,13 @@ func extractFlagName(flag string) string {
 func ValidateExtraFlags(flags []string) error {
 	// Build a combined allowlist for all backends (for backward compatibility)
 	allowed := make(map[string]bool)
+	allowedFlagsMu.RLock()
 	for _, patterns := range allowedFlagPatterns {
 		for _, f := range patterns {
 			allowed[strings.ToLower(f)] = true
 		}
 	}
+	allowedFlagsMu.RUnlock()
 
 	// Track whether the previous token was a non-boolean flag without "=" (could accept a value)
 	prevFlagMayHaveValue := false
@@ -216,6 +240,9 @@ type UnifiedOptions struct {
 
 	// Ephemeral disables session persistence on the backend (stateless mode).
 	Ephemeral bool
+
+	// MCPServers declares MCP servers the backend connects to, by name.
+	MCPServers map[string]MCPServer
 }
 
 // ApprovalMode controls how the backend asks for user approval.
@@ -327,66 +354,21 @@ func (m *flagMapper) MapToOptions(unified *UnifiedOptions) *Options {
 		opts.ExtraFlags = append(opts.ExtraFlags, m.mapEphemeral()...)
 	}
 
+	if len(unified.MCPServers) > 0 {
+		flags, env := m.mapMCPServers(unified.MCPServers)
+		opts.ExtraFlags = append(opts.ExtraFlags, flags...)
+		opts.Env = append(opts.Env, env...)
+	}
+
 	// Add any extra flags from user
 	opts.ExtraFlags = append(opts.ExtraFlags, unified.ExtraFlags...)
 
 	return opts
 }
 
-// mapModel maps unified model names to backend-specific names.
+// mapModel maps unified model aliases to backend-specific names using the model catalog.
 func (m *flagMapper) mapModel(model string) string {
-	if model == "" {
-		return ""
-	}
-
-	// Map unified model aliases to backend-specific names
-	switch m.backend {
-	case "claude":
-		return m.mapClaudeModel(model)
-	case "gemini":
-		return m.mapGeminiModel(model)
-	case "codex":
-		return m.mapCodexModel(model)
-	default:
-		return model
-	}
-}
-
-func (m *flagMapper) mapClaudeModel(model string) string {
-	switch model {
-	case "fast", "quick":
-		return "haiku"
-	case "balanced", "default":
-		return "sonnet"
-	case "best", "powerful":
-		return "opus"
-	default:
-		return model
-	}
-}
-
-func (m *flagMapper) mapGeminiModel(model string) string {
-	switch model {
-	case "fast", "quick":
-		return "gemini-2.5-flash"
-	case "balanced", "default", "best", "powerful":
-		return "gemini-2.5-pro"
-	default:
-		return model
-	}
-}
-
-func (m *flagMapper) mapCodexModel(model string) string {
-	switch model {
-	case "fast", "quick":
-		return "gpt-4.1-mini"
-	case "balanced", "default":
-		return "gpt-5.2"
-	case "best", "powerful":
-		return "gpt-5-codex"
-	default:
-		return model
-	}
+	return ResolveModelAlias(m.backend, model)
 }
 
 // mapApprovalMode maps approval mode to backend-specific flags.
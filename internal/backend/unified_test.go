@@ -867,6 +867,12 @@ func TestValidateExtraFlagsForBackend(t *testing.T) {
 			flags:   []string{"--json"}, // json is codex-only
 			wantErr: true,
 		},
+		{
+			name:    "claude mcp config",
+			backend: "claude",
+			flags:   []string{"--mcp-config", `{"mcpServers":{"x":{"command":"sh"}}}`}, // runs commands; use mcp_servers
+			wantErr: true,
+		},
 		{
 			name:    "codex valid flags",
 			backend: "codex",
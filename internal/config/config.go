@@ -4,6 +4,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/fsnotify/fsnotify"
@@ -12,13 +13,18 @@ import (
 
 // Config represents the application configuration.
 type Config struct {
-	DefaultBackend string                   `mapstructure:"default_backend"`
-	UnifiedFlags   UnifiedFlagsConfig       `mapstructure:"unified_flags"`
-	Backends       map[string]BackendConfig `mapstructure:"backends"`
-	Session        SessionConfig            `mapstructure:"session"`
-	Output         OutputConfig             `mapstructure:"output"`
-	Parallel       ParallelConfig           `mapstructure:"parallel"`
-	Server         ServerConfig             `mapstructure:"server"`
+	DefaultBackend string                     `mapstructure:"default_backend"`
+	Fallback       []string                   `mapstructure:"fallback"`
+	UnifiedFlags   UnifiedFlagsConfig         `mapstructure:"unified_flags"`
+	Backends       map[string]BackendConfig   `mapstructure:"backends"`
+	MCPServers     map[string]MCPServerConfig `mapstructure:"mcp_servers"`
+	Pools          map[string]PoolConfig      `mapstructure:"pools"`
+	Session        SessionConfig              `mapstructure:"session"`
+	Output         OutputConfig               `mapstructure:"output"`
+	Parallel       ParallelConfig             `mapstructure:"parallel"`
+	CircuitBreaker CircuitBreakerConfig       `mapstructure:"circuit_breaker"`
+	Retry          RetryConfig                `mapstructure:"retry"`
+	Server         ServerConfig               `mapstructure:"server"`
 }
 
 // ServerConfig contains HTTP server settings.
@@ -100,6 +106,71 @@ type ServerConfig struct {
 	// MetricsEnabled enables the /metrics endpoint for Prometheus scraping.
 	// Default: false
 	MetricsEnabled bool `mapstructure:"metrics_enabled"`
+
+	// MCPEnabled serves the MCP streamable HTTP transport at /mcp, so
+	// agents can call clinvk's tools over the network.
+	// Default: false
+	MCPEnabled bool `mapstructure:"mcp_enabled"`
+
+	// AllowRequestMCPServers lets API requests declare their own
+	// mcp_servers. Command servers run on the host, so requests may not
+	// declare them unless this is set; the configured mcp_servers apply
+	// either way.
+	// Default: false
+	AllowRequestMCPServers bool `mapstructure:"allow_request_mcp_servers"`
+
+	// Concurrency Limits
+	// MaxConcurrentProcesses caps the backend processes the server runs at
+	// once across all backends (0 = unlimited).
+	MaxConcurrentProcesses int `mapstructure:"max_concurrent_processes"`
+
+	// MaxConcurrentPerBackend caps the processes each backend runs at once
+	// (0 = unlimited).
+	MaxConcurrentPerBackend int `mapstructure:"max_concurrent_per_backend"`
+
+	// BackendConcurrency overrides MaxConcurrentPerBackend for individual
+	// backends, keyed by backend name.
+	BackendConcurrency map[string]int `mapstructure:"backend_concurrency"`
+
+	// QueueSize caps the requests waiting for a process slot when a limit is
+	// reached; requests beyond it are rejected with 429.
+	// Default: 100. Set to 0 to reject instead of waiting.
+	QueueSize int `mapstructure:"queue_size"`
+
+	// QueueTimeoutSecs bounds the wait for a process slot; requests still
+	// waiting are rejected with 503.
+	// Default: 60. Set to 0 to wait until the request ends.
+	QueueTimeoutSecs int `mapstructure:"queue_timeout_secs"`
+
+	// Webhooks
+	// WebhookSecretEnv names the environment variable holding the secret that
+	// signs callback_url deliveries, so the secret stays out of the config
+	// file. Deliveries are unsigned when the variable is empty.
+	// Default: "CLINVK_WEBHOOK_SECRET"
+	WebhookSecretEnv string `mapstructure:"webhook_secret_env"`
+
+	// WebhookMaxAttempts caps the attempts per delivery, including the first.
+	// Default: 5. Set to 0 to attempt once.
+	WebhookMaxAttempts int `mapstructure:"webhook_max_attempts"`
+
+	// WebhookTimeoutSecs bounds each delivery attempt.
+	// Default: 10. Set to 0 to use the default.
+	WebhookTimeoutSecs int `mapstructure:"webhook_timeout_secs"`
+
+	// WebhookAllowedHosts restricts callback URLs to these hosts, which
+	// may also be local or private addresses.
+	// If empty, any host with a public address is allowed.
+	WebhookAllowedHosts []string `mapstructure:"webhook_allowed_hosts"`
+
+	// Jobs
+	// JobRetentionHours deletes finished jobs and their events this long
+	// after they finish.
+	// Default: 168 (7 days). Set to 0 to keep jobs regardless of age.
+	JobRetentionHours int `mapstructure:"job_retention_hours"`
+
+	// JobMaxFinished caps the finished jobs kept; the oldest are deleted.
+	// Default: 1000. Set to 0 for no cap.
+	JobMaxFinished int `mapstructure:"job_max_finished"`
 }
 
 // UnifiedFlagsConfig contains unified flag settings that apply across backends.
@@ -125,6 +196,37 @@ type UnifiedFlagsConfig struct {
 	// CommandTimeoutSecs is the maximum time in seconds to wait for a command to complete.
 	// Set to 0 for no timeout (default). Common values: 300 (5 min), 600 (10 min), 1800 (30 min).
 	CommandTimeoutSecs int `mapstructure:"command_timeout_secs"`
+
+	// UnsupportedOptions controls what happens when a request sets an option
+	// the backend cannot honor: warn (default), error, or ignore.
+	UnsupportedOptions string `mapstructure:"unsupported_options"`
+}
+
+// MCPServerConfig declares an MCP server attached to every backend run.
+// A server either runs Command over stdio or is reached at URL.
+// Keep secrets out of the file: EnvVars forwards variables from clinvk's
+// environment, and BearerTokenEnv names the variable holding a token.
+type MCPServerConfig struct {
+	// Command starts a stdio server.
+	Command string `mapstructure:"command"`
+
+	// Args are the arguments of Command.
+	Args []string `mapstructure:"args"`
+
+	// Env sets environment variables for Command, as KEY=value.
+	Env []string `mapstructure:"env"`
+
+	// EnvVars names variables forwarded to Command from clinvk's environment.
+	EnvVars []string `mapstructure:"env_vars"`
+
+	// URL is the streamable HTTP endpoint of a remote server.
+	URL string `mapstructure:"url"`
+
+	// Headers are sent with every request to URL.
+	Headers map[string]string `mapstructure:"headers"`
+
+	// BearerTokenEnv names the variable holding a bearer token for URL.
+	BearerTokenEnv string `mapstructure:"bearer_token_env"`
 }
 
 // BackendConfig contains backend-specific configuration.
@@ -149,6 +251,172 @@ type BackendConfig struct {
 
 	// SystemPrompt provides a default system prompt for this backend.
 	SystemPrompt string `mapstructure:"system_prompt"`
+
+	// Type selects how the backend is provided.
+	// Empty for the built-in backends; "generic" declares a CLI backend
+	// described entirely by the Generic section; "http" calls an
+	// OpenAI- or Anthropic-compatible API described by the HTTP section.
+	Type string `mapstructure:"type"`
+
+	// Generic describes a config-declared CLI backend (type: generic).
+	Generic GenericBackendConfig `mapstructure:"generic"`
+
+	// HTTP describes a direct API backend (type: http).
+	HTTP HTTPBackendConfig `mapstructure:"http"`
+
+	// Models lists the concrete models this backend serves.
+	// When set, it replaces the built-in model list for the backend.
+	Models []ModelConfig `mapstructure:"models"`
+
+	// ModelAliases maps short names (e.g. "fast") to model names.
+	// Entries are merged over the built-in aliases.
+	ModelAliases map[string]string `mapstructure:"model_aliases"`
+
+	// ModelPrefixes lists model ID prefixes routed to this backend by the
+	// OpenAI-compatible endpoints when the model is not in any catalog.
+	ModelPrefixes []string `mapstructure:"model_prefixes"`
+}
+
+// ModelConfig describes a model in the catalog.
+type ModelConfig struct {
+	// ID is the model identifier passed to the backend.
+	ID string `mapstructure:"id"`
+
+	// DisplayName is a human-readable name.
+	DisplayName string `mapstructure:"display_name"`
+
+	// ContextWindow is the maximum context length in tokens.
+	ContextWindow int `mapstructure:"context_window"`
+
+	// MaxOutputTokens is the maximum response length in tokens.
+	MaxOutputTokens int `mapstructure:"max_output_tokens"`
+
+	// InputPrice is the price in USD per million input tokens.
+	InputPrice float64 `mapstructure:"input_price"`
+
+	// OutputPrice is the price in USD per million output tokens.
+	OutputPrice float64 `mapstructure:"output_price"`
+}
+
+// Backend types that can be declared in configuration.
+const (
+	// BackendTypeGeneric is an arbitrary CLI driven by argument templates.
+	BackendTypeGeneric = "generic"
+
+	// BackendTypeHTTP is an OpenAI- or Anthropic-compatible HTTP API.
+	BackendTypeHTTP = "http"
+)
+
+// HTTPBackendConfig describes a backend that calls an HTTP API directly.
+type HTTPBackendConfig struct {
+	// Provider is the API flavor: "openai" (chat completions) or "anthropic" (messages).
+	Provider string `mapstructure:"provider"`
+
+	// BaseURL overrides the provider's default base URL
+	// (e.g. "http://localhost:11434/v1" for a local OpenAI-compatible server).
+	BaseURL string `mapstructure:"base_url"`
+
+	// APIKeyEnv names the environment variable holding the API key.
+	APIKeyEnv string `mapstructure:"api_key_env"`
+
+	// APIKey is the API key itself. Prefer APIKeyEnv to keep keys out of config files.
+	APIKey string `mapstructure:"api_key"`
+
+	// Headers are extra HTTP headers sent with each request.
+	Headers map[string]string `mapstructure:"headers"`
+
+	// TimeoutSecs bounds each API request (0 = no timeout).
+	TimeoutSecs int `mapstructure:"timeout_secs"`
+
+	// MaxTokens is the default response token limit.
+	MaxTokens int `mapstructure:"max_tokens"`
+}
+
+// GenericBackendConfig describes how to invoke and parse an arbitrary AI CLI.
+// Argument templates may reference {{prompt}}, {{session_id}}, {{model}},
+// {{system_prompt}} and {{max_turns}}; a template group is only emitted when
+// the values it references are set.
+type GenericBackendConfig struct {
+	// Command is the executable name or path.
+	Command string `mapstructure:"command"`
+
+	// Args are passed on every invocation, before any other arguments.
+	Args []string `mapstructure:"args"`
+
+	// PromptArgs places the prompt on the command line (default: ["{{prompt}}"]).
+	PromptArgs []string `mapstructure:"prompt_args"`
+
+	// ResumeArgs are added when resuming a session (e.g. ["--resume", "{{session_id}}"]).
+	// If empty, the backend cannot resume sessions.
+	ResumeArgs []string `mapstructure:"resume_args"`
+
+	// ModelArgs selects the model (e.g. ["--model", "{{model}}"]).
+	ModelArgs []string `mapstructure:"model_args"`
+
+	// SystemPromptArgs passes a system prompt.
+	SystemPromptArgs []string `mapstructure:"system_prompt_args"`
+
+	// MaxTurnsArgs limits agentic turns.
+	MaxTurnsArgs []string `mapstructure:"max_turns_args"`
+
+	// ApprovalFlags maps unified approval modes (auto, none, always) to flags.
+	ApprovalFlags map[string][]string `mapstructure:"approval_flags"`
+
+	// SandboxFlags maps unified sandbox modes (read-only, workspace, full) to flags.
+	SandboxFlags map[string][]string `mapstructure:"sandbox_flags"`
+
+	// OutputFormatFlags maps unified output formats (text, json, stream-json) to flags.
+	OutputFormatFlags map[string][]string `mapstructure:"output_format_flags"`
+
+	// VerboseFlags are added when verbose output is requested.
+	VerboseFlags []string `mapstructure:"verbose_flags"`
+
+	// EphemeralFlags disable session persistence on the backend.
+	EphemeralFlags []string `mapstructure:"ephemeral_flags"`
+
+	// AllowedFlags is the allowlist for extra flags passed through the API.
+	AllowedFlags []string `mapstructure:"allowed_flags"`
+
+	// Env sets additional environment variables for the process.
+	Env map[string]string `mapstructure:"env"`
+
+	// SeparateStderr captures stderr separately from the response.
+	SeparateStderr bool `mapstructure:"separate_stderr"`
+
+	// Output describes how to extract the response from the CLI output.
+	Output GenericOutputConfig `mapstructure:"output"`
+}
+
+// GenericOutputConfig describes how to extract a response from CLI output.
+// Paths use a jq-like syntax such as "result.text" or ".items[0].content".
+type GenericOutputConfig struct {
+	// Format is the output format: text (default), json or jsonl.
+	Format string `mapstructure:"format"`
+
+	// ContentPath locates the response text (required for json/jsonl).
+	ContentPath string `mapstructure:"content_path"`
+
+	// SessionIDPath locates the backend session ID.
+	SessionIDPath string `mapstructure:"session_id_path"`
+
+	// ErrorPath locates an error message.
+	ErrorPath string `mapstructure:"error_path"`
+
+	// ModelPath locates the model that produced the response.
+	ModelPath string `mapstructure:"model_path"`
+
+	// InputTokensPath locates the input token count.
+	InputTokensPath string `mapstructure:"input_tokens_path"`
+
+	// OutputTokensPath locates the output token count.
+	OutputTokensPath string `mapstructure:"output_tokens_path"`
+
+	// ContentRegex extracts the response from text output.
+	// The "content" named group is used if present, otherwise the first group.
+	ContentRegex string `mapstructure:"content_regex"`
+
+	// SessionIDRegex extracts the session ID from text output.
+	SessionIDRegex string `mapstructure:"session_id_regex"`
 }
 
 // SessionConfig contains session management configuration.
@@ -164,6 +432,17 @@ type SessionConfig struct {
 
 	// StoreTokenUsage enables token usage tracking.
 	StoreTokenUsage bool `mapstructure:"store_token_usage"`
+
+	// Transcripts records the prompt and response of every turn.
+	Transcripts bool `mapstructure:"transcripts"`
+
+	// TranscriptMaxBytes is the size at which a transcript file is rotated
+	// (0 = 10 MiB).
+	TranscriptMaxBytes int64 `mapstructure:"transcript_max_bytes"`
+
+	// TranscriptMaxFiles is the number of rotated transcript files kept
+	// (0 = 3).
+	TranscriptMaxFiles int `mapstructure:"transcript_max_files"`
 }
 
 // OutputConfig contains output settings.
@@ -193,6 +472,70 @@ type ParallelConfig struct {
 	AggregateOutput bool `mapstructure:"aggregate_output"`
 }
 
+// CircuitBreakerConfig contains per-backend circuit breaker settings.
+type CircuitBreakerConfig struct {
+	// Enabled guards every backend with a circuit breaker.
+	// Default: true
+	Enabled bool `mapstructure:"enabled"`
+
+	// FailureThreshold is the number of consecutive failures that open a
+	// backend's circuit. Default: 5
+	FailureThreshold int `mapstructure:"failure_threshold"`
+
+	// SuccessThreshold is the number of successful trial calls that close
+	// the circuit again. Default: 2
+	SuccessThreshold int `mapstructure:"success_threshold"`
+
+	// OpenTimeoutSecs is how long an open circuit rejects calls before a
+	// trial call is let through. Default: 30
+	OpenTimeoutSecs int `mapstructure:"open_timeout_secs"`
+}
+
+// PoolConfig declares a virtual backend that spreads requests across member
+// backends, such as several accounts of the same CLI.
+type PoolConfig struct {
+	// Strategy picks a member for each request: "round_robin" (default),
+	// "least_busy" or "weighted".
+	Strategy string `mapstructure:"strategy"`
+
+	// Members lists the backends of the pool.
+	Members []PoolMemberConfig `mapstructure:"members"`
+}
+
+// PoolMemberConfig declares a member backend of a pool.
+type PoolMemberConfig struct {
+	// Backend is the name of a registered backend.
+	Backend string `mapstructure:"backend"`
+
+	// Weight is the member's share of requests with the weighted strategy.
+	// Default: 1
+	Weight int `mapstructure:"weight"`
+
+	// MaxConcurrency caps the member's requests in flight (0 = unlimited).
+	MaxConcurrency int `mapstructure:"max_concurrency"`
+}
+
+// RetryConfig contains the default retry policy for backend runs. Only
+// transient failures (rate limits, timeouts, network and auth errors) are
+// retried.
+type RetryConfig struct {
+	// MaxAttempts is the total number of runs per backend, including the
+	// first. 0 and 1 disable retries. Default: 1
+	MaxAttempts int `mapstructure:"max_attempts"`
+
+	// InitialBackoffMS is the wait before the first retry. Default: 1000
+	InitialBackoffMS int `mapstructure:"initial_backoff_ms"`
+
+	// MaxBackoffMS caps the wait between retries. Default: 30000
+	MaxBackoffMS int `mapstructure:"max_backoff_ms"`
+
+	// Multiplier grows the wait after each retry. Default: 2
+	Multiplier float64 `mapstructure:"multiplier"`
+
+	// Jitter randomizes each wait by up to this fraction. Default: 0.2
+	Jitter float64 `mapstructure:"jitter"`
+}
+
 // IsBackendEnabled checks if a backend is enabled (defaults to true).
 func (c *BackendConfig) IsBackendEnabled() bool {
 	if c.Enabled == nil {
@@ -201,6 +544,11 @@ func (c *BackendConfig) IsBackendEnabled() bool {
 	return *c.Enabled
 }
 
+// IsGeneric reports whether the backend is declared as a generic CLI backend.
+func (c *BackendConfig) IsGeneric() bool {
+	return c.Type == BackendTypeGeneric
+}
+
 var (
 	cfg        *Config
 	cfgMu      sync.RWMutex
@@ -225,6 +573,7 @@ func Init(cfgFile string) error {
 				AutoResume:      true,
 				RetentionDays:   30,
 				StoreTokenUsage: true,
+				Transcripts:     true,
 			},
 			Output: OutputConfig{
 				Format:     "json",
@@ -237,6 +586,19 @@ func Init(cfgFile string) error {
 				FailFast:        false,
 				AggregateOutput: true,
 			},
+			CircuitBreaker: CircuitBreakerConfig{
+				Enabled:          true,
+				FailureThreshold: 5,
+				SuccessThreshold: 2,
+				OpenTimeoutSecs:  30,
+			},
+			Retry: RetryConfig{
+				MaxAttempts:      1,
+				InitialBackoffMS: 1000,
+				MaxBackoffMS:     30000,
+				Multiplier:       2,
+				Jitter:           0.2,
+			},
 			Server: ServerConfig{
 				Host:                 "127.0.0.1",
 				Port:                 8080,
@@ -249,6 +611,13 @@ func Init(cfgFile string) error {
 				RateLimitBurst:       20,
 				RateLimitCleanupSecs: 180,
 				MaxRequestBodyBytes:  10 * 1024 * 1024, // 10MB
+				QueueSize:            100,
+				QueueTimeoutSecs:     60,
+				WebhookSecretEnv:     "CLINVK_WEBHOOK_SECRET",
+				WebhookMaxAttempts:   5,
+				WebhookTimeoutSecs:   10,
+				JobRetentionHours:    168,
+				JobMaxFinished:       1000,
 			},
 		}
 
@@ -377,6 +746,16 @@ func SessionsDir() string {
 	return filepath.Join(ConfigDir(), "sessions")
 }
 
+// JobsDir returns the directory where server jobs are persisted.
+func JobsDir() string {
+	return filepath.Join(ConfigDir(), "jobs")
+}
+
+// PluginsDir returns the directory scanned for backend plugins.
+func PluginsDir() string {
+	return filepath.Join(ConfigDir(), "plugins")
+}
+
 // EnsureConfigDir creates the configuration directory if it doesn't exist.
 func EnsureConfigDir() error {
 	dir := ConfigDir()
@@ -440,10 +819,12 @@ func GetEffectiveModel(backend string) string {
 }
 
 // EnabledBackends returns a list of enabled backend names.
+// Built-in backends come first, followed by config-declared backends in name order.
 func EnabledBackends() []string {
 	c := Get()
 	// Default all backends enabled
 	backends := []string{"claude", "codex", "gemini"}
+	backends = append(backends, DeclaredBackends(c)...)
 
 	var enabled []string
 	for _, name := range backends {
@@ -457,6 +838,22 @@ func EnabledBackends() []string {
 	return enabled
 }
 
+// DeclaredBackends returns the sorted names of backends declared in config
+// (as opposed to the built-in ones), regardless of whether they are enabled.
+func DeclaredBackends(c *Config) []string {
+	if c == nil {
+		return nil
+	}
+	var names []string
+	for name, bc := range c.Backends {
+		if bc.Type != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Reset resets the configuration (mainly for testing).
 func Reset() {
 	cfgMu.Lock()
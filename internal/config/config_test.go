@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -457,6 +458,34 @@ func TestEnabledBackends(t *testing.T) {
 	})
 }
 
+func TestDeclaredBackends(t *testing.T) {
+	Reset()
+	Init("")
+	cfg := Get()
+	cfg.Backends = map[string]BackendConfig{
+		"claude": {Model: "opus"},
+		"zeta":   {Type: BackendTypeGeneric, Generic: GenericBackendConfig{Command: "zeta"}},
+		"aider":  {Type: BackendTypeGeneric, Generic: GenericBackendConfig{Command: "aider"}},
+		"off":    {Type: BackendTypeGeneric, Enabled: boolPtr(false)},
+	}
+
+	declared := DeclaredBackends(cfg)
+	want := []string{"aider", "off", "zeta"}
+	if strings.Join(declared, ",") != strings.Join(want, ",") {
+		t.Errorf("DeclaredBackends() = %v, want %v", declared, want)
+	}
+
+	enabled := EnabledBackends()
+	want = []string{"claude", "codex", "gemini", "aider", "zeta"}
+	if strings.Join(enabled, ",") != strings.Join(want, ",") {
+		t.Errorf("EnabledBackends() = %v, want %v", enabled, want)
+	}
+
+	if DeclaredBackends(nil) != nil {
+		t.Error("expected nil for nil config")
+	}
+}
+
 // ============================================================================
 // SessionConfig Tests
 // ============================================================================
@@ -927,6 +956,12 @@ func TestServerConfigDefaults(t *testing.T) {
 		{"RateLimitBurst", cfg.Server.RateLimitBurst, 20},
 		{"RateLimitCleanupSecs", cfg.Server.RateLimitCleanupSecs, 180},
 		{"MaxRequestBodyBytes", cfg.Server.MaxRequestBodyBytes, int64(10 * 1024 * 1024)},
+		{"MaxConcurrentProcesses", cfg.Server.MaxConcurrentProcesses, 0},
+		{"QueueSize", cfg.Server.QueueSize, 100},
+		{"QueueTimeoutSecs", cfg.Server.QueueTimeoutSecs, 60},
+		{"WebhookSecretEnv", cfg.Server.WebhookSecretEnv, "CLINVK_WEBHOOK_SECRET"},
+		{"WebhookMaxAttempts", cfg.Server.WebhookMaxAttempts, 5},
+		{"WebhookTimeoutSecs", cfg.Server.WebhookTimeoutSecs, 10},
 	}
 
 	for _, tt := range tests {
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"net"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 
 	apperrors "github.com/signalridge/clinvoker/internal/errors"
@@ -24,10 +27,13 @@ func Validate(cfg *Config) []error {
 	var errs []error
 
 	// Validate default backend
-	if err := validateDefaultBackend(cfg.DefaultBackend); err != nil {
+	if err := validateDefaultBackend(cfg.DefaultBackend, DeclaredBackends(cfg)); err != nil {
 		errs = append(errs, err)
 	}
 
+	// Validate fallback chain
+	errs = append(errs, validateFallback(cfg.Fallback)...)
+
 	// Validate unified flags
 	errs = append(errs, validateUnifiedFlags(&cfg.UnifiedFlags)...)
 
@@ -35,6 +41,13 @@ func Validate(cfg *Config) []error {
 	for name, bc := range cfg.Backends {
 		errs = append(errs, validateBackendConfig(name, &bc)...)
 	}
+	errs = append(errs, validateModelOwnership(cfg.Backends)...)
+
+	// Validate backend pools
+	errs = append(errs, validatePools(cfg.Pools, cfg.Backends)...)
+
+	// Validate MCP servers
+	errs = append(errs, validateMCPServers(cfg.MCPServers)...)
 
 	// Validate session config
 	errs = append(errs, validateSessionConfig(&cfg.Session)...)
@@ -48,6 +61,10 @@ func Validate(cfg *Config) []error {
 	// Validate parallel config
 	errs = append(errs, validateParallelConfig(&cfg.Parallel)...)
 
+	// Validate circuit breaker config
+	errs = append(errs, validateCircuitBreakerConfig(&cfg.CircuitBreaker)...)
+	errs = append(errs, validateRetryConfig(&cfg.Retry)...)
+
 	return errs
 }
 
@@ -71,12 +88,16 @@ func ValidateConfig() error {
 }
 
 // validateDefaultBackend validates the default backend setting.
-func validateDefaultBackend(backend string) error {
+// Backends declared in config are accepted alongside the built-in ones.
+func validateDefaultBackend(backend string, declared []string) error {
 	validBackends := map[string]bool{
 		"claude": true,
 		"codex":  true,
 		"gemini": true,
 	}
+	for _, name := range declared {
+		validBackends[name] = true
+	}
 
 	if backend == "" {
 		return &ValidationError{
@@ -88,7 +109,7 @@ func validateDefaultBackend(backend string) error {
 	if !validBackends[backend] {
 		return &ValidationError{
 			Field:   "default_backend",
-			Message: fmt.Sprintf("invalid backend %q (valid: claude, codex, gemini)", backend),
+			Message: fmt.Sprintf("invalid backend %q (valid: %s)", backend, strings.Join(append([]string{"claude", "codex", "gemini"}, declared...), ", ")),
 		}
 	}
 
@@ -147,6 +168,16 @@ func validateUnifiedFlags(flags *UnifiedFlagsConfig) []error {
 		})
 	}
 
+	// Validate unsupported_options policy
+	switch flags.UnsupportedOptions {
+	case "", "warn", "error", "ignore":
+	default:
+		errs = append(errs, &ValidationError{
+			Field:   "unified_flags.unsupported_options",
+			Message: fmt.Sprintf("invalid policy %q (valid: warn, error, ignore)", flags.UnsupportedOptions),
+		})
+	}
+
 	return errs
 }
 
@@ -176,6 +207,137 @@ func validateOutputConfig(output *OutputConfig) []error {
 	return errs
 }
 
+// validateFallback validates the fallback backend chain.
+// Names are not checked against the registry since plugins are discovered at startup.
+func validateFallback(fallback []string) []error {
+	var errs []error
+	seen := make(map[string]bool, len(fallback))
+	for i, name := range fallback {
+		field := fmt.Sprintf("fallback[%d]", i)
+		switch {
+		case name == "":
+			errs = append(errs, &ValidationError{Field: field, Message: "backend name cannot be empty"})
+		case seen[name]:
+			errs = append(errs, &ValidationError{Field: field, Message: fmt.Sprintf("duplicate backend %q", name)})
+		}
+		seen[name] = true
+	}
+	return errs
+}
+
+// validPoolStrategies lists the accepted pool strategies.
+var validPoolStrategies = map[string]bool{
+	"":            true,
+	"round_robin": true,
+	"least_busy":  true,
+	"weighted":    true,
+}
+
+// validatePools validates backend pool declarations. Member names are not
+// checked against the registry since plugins are discovered at startup, but
+// pools may neither shadow a backend nor contain other pools.
+func validatePools(pools map[string]PoolConfig, backends map[string]BackendConfig) []error {
+	var errs []error
+
+	names := make([]string, 0, len(pools))
+	for name := range pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pc := pools[name]
+		prefix := "pools." + name
+
+		if _, ok := backends[name]; ok || name == "claude" || name == "codex" || name == "gemini" {
+			errs = append(errs, &ValidationError{Field: prefix, Message: "name is already used by a backend"})
+		}
+		if !validPoolStrategies[pc.Strategy] {
+			errs = append(errs, &ValidationError{
+				Field:   prefix + ".strategy",
+				Message: fmt.Sprintf("invalid strategy %q (valid: round_robin, least_busy, weighted)", pc.Strategy),
+			})
+		}
+		if len(pc.Members) == 0 {
+			errs = append(errs, &ValidationError{Field: prefix + ".members", Message: "must not be empty"})
+		}
+
+		seen := make(map[string]bool, len(pc.Members))
+		for i, m := range pc.Members {
+			field := fmt.Sprintf("%s.members[%d]", prefix, i)
+			switch {
+			case m.Backend == "":
+				errs = append(errs, &ValidationError{Field: field + ".backend", Message: "backend name cannot be empty"})
+			case seen[m.Backend]:
+				errs = append(errs, &ValidationError{Field: field + ".backend", Message: fmt.Sprintf("duplicate backend %q", m.Backend)})
+			default:
+				if _, ok := pools[m.Backend]; ok {
+					errs = append(errs, &ValidationError{Field: field + ".backend", Message: fmt.Sprintf("%q is a pool; pools cannot be nested", m.Backend)})
+				}
+			}
+			seen[m.Backend] = true
+
+			if m.Weight < 0 {
+				errs = append(errs, &ValidationError{Field: field + ".weight", Message: "must not be negative"})
+			}
+			if m.MaxConcurrency < 0 {
+				errs = append(errs, &ValidationError{Field: field + ".max_concurrency", Message: "must not be negative"})
+			}
+		}
+	}
+
+	return errs
+}
+
+// mcpServerNamePattern keeps server names usable as keys of every
+// backend's own MCP configuration.
+var mcpServerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// validateMCPServers validates the MCP servers attached to backend runs.
+func validateMCPServers(servers map[string]MCPServerConfig) []error {
+	var errs []error
+
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sc := servers[name]
+		prefix := "mcp_servers." + name
+
+		if !mcpServerNamePattern.MatchString(name) {
+			errs = append(errs, &ValidationError{Field: prefix, Message: "name may only contain letters, digits, '-' and '_'"})
+		}
+
+		switch {
+		case sc.Command == "" && sc.URL == "":
+			errs = append(errs, &ValidationError{Field: prefix, Message: "command or url is required"})
+		case sc.Command != "" && sc.URL != "":
+			errs = append(errs, &ValidationError{Field: prefix, Message: "command and url are mutually exclusive"})
+		case sc.URL != "":
+			if u, err := url.Parse(sc.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, &ValidationError{
+					Field:   prefix + ".url",
+					Message: fmt.Sprintf("invalid URL %q (must be http or https)", sc.URL),
+				})
+			}
+		}
+
+		for i, kv := range sc.Env {
+			if key, _, ok := strings.Cut(kv, "="); !ok || key == "" {
+				errs = append(errs, &ValidationError{
+					Field:   fmt.Sprintf("%s.env[%d]", prefix, i),
+					Message: fmt.Sprintf("invalid entry %q (want KEY=value)", kv),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
 // validateBackendConfig validates a backend-specific configuration.
 func validateBackendConfig(name string, bc *BackendConfig) []error {
 	var errs []error
@@ -212,9 +374,199 @@ func validateBackendConfig(name string, bc *BackendConfig) []error {
 		}
 	}
 
+	switch bc.Type {
+	case "":
+	case BackendTypeGeneric:
+		errs = append(errs, validateGenericBackendConfig(name, &bc.Generic)...)
+	case BackendTypeHTTP:
+		errs = append(errs, validateHTTPBackendConfig(name, &bc.HTTP)...)
+	default:
+		errs = append(errs, &ValidationError{
+			Field:   fmt.Sprintf("backends.%s.type", name),
+			Message: fmt.Sprintf("invalid type %q (valid: %s, %s)", bc.Type, BackendTypeGeneric, BackendTypeHTTP),
+		})
+	}
+
+	if bc.Type != "" && isBuiltinBackend(name) {
+		errs = append(errs, &ValidationError{
+			Field:   fmt.Sprintf("backends.%s.type", name),
+			Message: "built-in backends cannot be redeclared",
+		})
+	}
+
+	errs = append(errs, validateModelCatalog(name, bc)...)
+
+	return errs
+}
+
+// validateModelCatalog validates the models and aliases of a backend.
+func validateModelCatalog(name string, bc *BackendConfig) []error {
+	var errs []error
+
+	seen := make(map[string]bool, len(bc.Models))
+	for i, m := range bc.Models {
+		field := fmt.Sprintf("backends.%s.models[%d]", name, i)
+		if m.ID == "" {
+			errs = append(errs, &ValidationError{Field: field + ".id", Message: "id is required"})
+		} else if seen[m.ID] {
+			errs = append(errs, &ValidationError{Field: field + ".id", Message: fmt.Sprintf("duplicate model %q", m.ID)})
+		}
+		seen[m.ID] = true
+		if m.ContextWindow < 0 || m.MaxOutputTokens < 0 || m.InputPrice < 0 || m.OutputPrice < 0 {
+			errs = append(errs, &ValidationError{Field: field, Message: "token limits and prices must be non-negative"})
+		}
+	}
+
+	for alias, target := range bc.ModelAliases {
+		if target == "" {
+			errs = append(errs, &ValidationError{
+				Field:   fmt.Sprintf("backends.%s.model_aliases.%s", name, alias),
+				Message: "alias target is required",
+			})
+		}
+	}
+
+	for i, p := range bc.ModelPrefixes {
+		if p == "" {
+			errs = append(errs, &ValidationError{
+				Field:   fmt.Sprintf("backends.%s.model_prefixes[%d]", name, i),
+				Message: "prefix cannot be empty",
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateModelOwnership rejects model IDs declared by more than one backend,
+// since a model ID must resolve to a single backend.
+func validateModelOwnership(backends map[string]BackendConfig) []error {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	owner := make(map[string]string)
+	for _, name := range names {
+		for i, m := range backends[name].Models {
+			if m.ID == "" {
+				continue
+			}
+			if prev, ok := owner[m.ID]; ok && prev != name {
+				errs = append(errs, &ValidationError{
+					Field:   fmt.Sprintf("backends.%s.models[%d].id", name, i),
+					Message: fmt.Sprintf("model %q is already declared by backend %q", m.ID, prev),
+				})
+				continue
+			}
+			owner[m.ID] = name
+		}
+	}
 	return errs
 }
 
+// validateGenericBackendConfig validates a config-declared CLI backend.
+func validateGenericBackendConfig(name string, gc *GenericBackendConfig) []error {
+	var errs []error
+	field := func(key string) string {
+		return fmt.Sprintf("backends.%s.generic.%s", name, key)
+	}
+
+	if strings.TrimSpace(gc.Command) == "" {
+		errs = append(errs, &ValidationError{
+			Field:   field("command"),
+			Message: "must not be empty",
+		})
+	}
+
+	switch gc.Output.Format {
+	case "", "text":
+	case "json", "jsonl":
+		if gc.Output.ContentPath == "" {
+			errs = append(errs, &ValidationError{
+				Field:   field("output.content_path"),
+				Message: fmt.Sprintf("required for %s output", gc.Output.Format),
+			})
+		}
+	default:
+		errs = append(errs, &ValidationError{
+			Field:   field("output.format"),
+			Message: fmt.Sprintf("invalid format %q (valid: text, json, jsonl)", gc.Output.Format),
+		})
+	}
+
+	patterns := []struct{ key, pattern string }{
+		{"output.content_regex", gc.Output.ContentRegex},
+		{"output.session_id_regex", gc.Output.SessionIDRegex},
+	}
+	for _, p := range patterns {
+		if p.pattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(p.pattern); err != nil {
+			errs = append(errs, &ValidationError{
+				Field:   field(p.key),
+				Message: fmt.Sprintf("invalid regex: %v", err),
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateHTTPBackendConfig validates a direct API backend.
+func validateHTTPBackendConfig(name string, hc *HTTPBackendConfig) []error {
+	var errs []error
+	field := func(key string) string {
+		return fmt.Sprintf("backends.%s.http.%s", name, key)
+	}
+
+	switch hc.Provider {
+	case "openai", "anthropic":
+	default:
+		errs = append(errs, &ValidationError{
+			Field:   field("provider"),
+			Message: fmt.Sprintf("invalid provider %q (valid: openai, anthropic)", hc.Provider),
+		})
+	}
+
+	if hc.BaseURL != "" {
+		if u, err := url.Parse(hc.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, &ValidationError{
+				Field:   field("base_url"),
+				Message: fmt.Sprintf("invalid URL %q (must be http or https)", hc.BaseURL),
+			})
+		}
+	}
+
+	if hc.TimeoutSecs < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   field("timeout_secs"),
+			Message: "must be non-negative",
+		})
+	}
+
+	if hc.MaxTokens < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   field("max_tokens"),
+			Message: "must be non-negative",
+		})
+	}
+
+	return errs
+}
+
+// isBuiltinBackend reports whether name is one of the compiled-in backends.
+func isBuiltinBackend(name string) bool {
+	switch name {
+	case "claude", "codex", "gemini":
+		return true
+	}
+	return false
+}
+
 // validateSessionConfig validates session configuration.
 func validateSessionConfig(session *SessionConfig) []error {
 	var errs []error
@@ -226,6 +578,20 @@ func validateSessionConfig(session *SessionConfig) []error {
 		})
 	}
 
+	if session.TranscriptMaxBytes < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "session.transcript_max_bytes",
+			Message: "must be non-negative",
+		})
+	}
+
+	if session.TranscriptMaxFiles < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "session.transcript_max_files",
+			Message: "must be non-negative",
+		})
+	}
+
 	return errs
 }
 
@@ -319,6 +685,80 @@ func validateServerConfig(server *ServerConfig) []error {
 		})
 	}
 
+	if server.MaxConcurrentProcesses < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "server.max_concurrent_processes",
+			Message: "must be non-negative",
+		})
+	}
+
+	if server.MaxConcurrentPerBackend < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "server.max_concurrent_per_backend",
+			Message: "must be non-negative",
+		})
+	}
+
+	for name, limit := range server.BackendConcurrency {
+		if limit < 0 {
+			errs = append(errs, &ValidationError{
+				Field:   fmt.Sprintf("server.backend_concurrency.%s", name),
+				Message: "must be non-negative",
+			})
+		}
+	}
+
+	if server.QueueSize < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "server.queue_size",
+			Message: "must be non-negative",
+		})
+	}
+
+	if server.QueueTimeoutSecs < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "server.queue_timeout_secs",
+			Message: "must be non-negative",
+		})
+	}
+
+	if server.WebhookMaxAttempts < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "server.webhook_max_attempts",
+			Message: "must be non-negative",
+		})
+	}
+
+	if server.WebhookTimeoutSecs < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "server.webhook_timeout_secs",
+			Message: "must be non-negative",
+		})
+	}
+
+	if server.JobRetentionHours < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "server.job_retention_hours",
+			Message: "must be non-negative",
+		})
+	}
+
+	if server.JobMaxFinished < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "server.job_max_finished",
+			Message: "must be non-negative",
+		})
+	}
+
+	for i, host := range server.WebhookAllowedHosts {
+		if strings.TrimSpace(host) == "" {
+			errs = append(errs, &ValidationError{
+				Field:   fmt.Sprintf("server.webhook_allowed_hosts[%d]", i),
+				Message: "must not be empty",
+			})
+		}
+	}
+
 	return errs
 }
 
@@ -336,6 +776,72 @@ func validateParallelConfig(parallel *ParallelConfig) []error {
 	return errs
 }
 
+// validateCircuitBreakerConfig validates circuit breaker configuration.
+func validateCircuitBreakerConfig(cb *CircuitBreakerConfig) []error {
+	if !cb.Enabled {
+		return nil
+	}
+
+	var errs []error
+	fields := []struct {
+		name  string
+		value int
+	}{
+		{"circuit_breaker.failure_threshold", cb.FailureThreshold},
+		{"circuit_breaker.success_threshold", cb.SuccessThreshold},
+		{"circuit_breaker.open_timeout_secs", cb.OpenTimeoutSecs},
+	}
+	for _, f := range fields {
+		if f.value < 1 {
+			errs = append(errs, &ValidationError{
+				Field:   f.name,
+				Message: "must be at least 1",
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateRetryConfig validates retry configuration. Zero values select the
+// defaults.
+func validateRetryConfig(r *RetryConfig) []error {
+	var errs []error
+
+	if r.MaxAttempts < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "retry.max_attempts",
+			Message: "must not be negative",
+		})
+	}
+	if r.InitialBackoffMS < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "retry.initial_backoff_ms",
+			Message: "must not be negative",
+		})
+	}
+	if r.MaxBackoffMS != 0 && r.MaxBackoffMS < r.InitialBackoffMS {
+		errs = append(errs, &ValidationError{
+			Field:   "retry.max_backoff_ms",
+			Message: "must not be less than retry.initial_backoff_ms",
+		})
+	}
+	if r.Multiplier != 0 && r.Multiplier < 1 {
+		errs = append(errs, &ValidationError{
+			Field:   "retry.multiplier",
+			Message: "must be at least 1",
+		})
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		errs = append(errs, &ValidationError{
+			Field:   "retry.jitter",
+			Message: "must be between 0 and 1",
+		})
+	}
+
+	return errs
+}
+
 // isValidHostname checks if a string is a valid hostname.
 func isValidHostname(host string) bool {
 	if host == "" || len(host) > 253 {
@@ -0,0 +1,475 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDeclaredBackends(t *testing.T) {
+	tests := []struct {
+		name      string
+		backends  map[string]BackendConfig
+		defaultBk string
+		wantField string
+	}{
+		{
+			name: "valid generic backend",
+			backends: map[string]BackendConfig{
+				"aider": {Type: BackendTypeGeneric, Generic: GenericBackendConfig{Command: "aider"}},
+			},
+			defaultBk: "aider",
+		},
+		{
+			name:      "unknown default backend",
+			defaultBk: "aider",
+			wantField: "default_backend",
+		},
+		{
+			name: "missing command",
+			backends: map[string]BackendConfig{
+				"aider": {Type: BackendTypeGeneric},
+			},
+			wantField: "backends.aider.generic.command",
+		},
+		{
+			name: "unknown type",
+			backends: map[string]BackendConfig{
+				"aider": {Type: "magic"},
+			},
+			wantField: "backends.aider.type",
+		},
+		{
+			name: "built-in redeclared",
+			backends: map[string]BackendConfig{
+				"claude": {Type: BackendTypeGeneric, Generic: GenericBackendConfig{Command: "claude"}},
+			},
+			wantField: "backends.claude.type",
+		},
+		{
+			name: "json output without content path",
+			backends: map[string]BackendConfig{
+				"aider": {Type: BackendTypeGeneric, Generic: GenericBackendConfig{
+					Command: "aider",
+					Output:  GenericOutputConfig{Format: "json"},
+				}},
+			},
+			wantField: "backends.aider.generic.output.content_path",
+		},
+		{
+			name: "invalid output format",
+			backends: map[string]BackendConfig{
+				"aider": {Type: BackendTypeGeneric, Generic: GenericBackendConfig{
+					Command: "aider",
+					Output:  GenericOutputConfig{Format: "xml"},
+				}},
+			},
+			wantField: "backends.aider.generic.output.format",
+		},
+		{
+			name: "invalid regex",
+			backends: map[string]BackendConfig{
+				"aider": {Type: BackendTypeGeneric, Generic: GenericBackendConfig{
+					Command: "aider",
+					Output:  GenericOutputConfig{SessionIDRegex: "("},
+				}},
+			},
+			wantField: "backends.aider.generic.output.session_id_regex",
+		},
+		{
+			name: "valid http backend",
+			backends: map[string]BackendConfig{
+				"local": {Type: BackendTypeHTTP, HTTP: HTTPBackendConfig{
+					Provider: "openai",
+					BaseURL:  "http://localhost:11434/v1",
+				}},
+			},
+			defaultBk: "local",
+		},
+		{
+			name: "http backend with unknown provider",
+			backends: map[string]BackendConfig{
+				"local": {Type: BackendTypeHTTP, HTTP: HTTPBackendConfig{Provider: "cohere"}},
+			},
+			wantField: "backends.local.http.provider",
+		},
+		{
+			name: "http backend with invalid base URL",
+			backends: map[string]BackendConfig{
+				"local": {Type: BackendTypeHTTP, HTTP: HTTPBackendConfig{Provider: "anthropic", BaseURL: "localhost:8080"}},
+			},
+			wantField: "backends.local.http.base_url",
+		},
+		{
+			name: "http backend with negative timeout",
+			backends: map[string]BackendConfig{
+				"local": {Type: BackendTypeHTTP, HTTP: HTTPBackendConfig{Provider: "openai", TimeoutSecs: -1}},
+			},
+			wantField: "backends.local.http.timeout_secs",
+		},
+		{
+			name: "http backend redeclaring built-in",
+			backends: map[string]BackendConfig{
+				"codex": {Type: BackendTypeHTTP, HTTP: HTTPBackendConfig{Provider: "openai"}},
+			},
+			wantField: "backends.codex.type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{DefaultBackend: "claude", Backends: tt.backends, Parallel: ParallelConfig{MaxWorkers: 1}}
+			if tt.defaultBk != "" {
+				cfg.DefaultBackend = tt.defaultBk
+			}
+
+			errs := Validate(cfg)
+			if tt.wantField == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+
+			found := false
+			for _, err := range errs {
+				if strings.Contains(err.Error(), tt.wantField+":") {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected error for %s, got %v", tt.wantField, errs)
+			}
+		})
+	}
+}
+
+func TestValidateModelCatalog(t *testing.T) {
+	tests := []struct {
+		name      string
+		backends  map[string]BackendConfig
+		wantField string
+	}{
+		{
+			name: "valid catalog",
+			backends: map[string]BackendConfig{
+				"claude": {
+					Models:        []ModelConfig{{ID: "claude-sonnet-4-5", ContextWindow: 200000, InputPrice: 3}},
+					ModelAliases:  map[string]string{"fast": "claude-haiku-4-5"},
+					ModelPrefixes: []string{"claude"},
+				},
+			},
+		},
+		{
+			name: "missing model id",
+			backends: map[string]BackendConfig{
+				"claude": {Models: []ModelConfig{{DisplayName: "Sonnet"}}},
+			},
+			wantField: "backends.claude.models[0].id",
+		},
+		{
+			name: "duplicate model id",
+			backends: map[string]BackendConfig{
+				"claude": {Models: []ModelConfig{{ID: "a"}, {ID: "a"}}},
+			},
+			wantField: "backends.claude.models[1].id",
+		},
+		{
+			name: "model declared by two backends",
+			backends: map[string]BackendConfig{
+				"claude": {Models: []ModelConfig{{ID: "shared"}}},
+				"codex":  {Models: []ModelConfig{{ID: "shared"}}},
+			},
+			wantField: "backends.codex.models[0].id",
+		},
+		{
+			name: "negative price",
+			backends: map[string]BackendConfig{
+				"claude": {Models: []ModelConfig{{ID: "a", OutputPrice: -1}}},
+			},
+			wantField: "backends.claude.models[0]",
+		},
+		{
+			name: "empty alias target",
+			backends: map[string]BackendConfig{
+				"claude": {ModelAliases: map[string]string{"fast": ""}},
+			},
+			wantField: "backends.claude.model_aliases.fast",
+		},
+		{
+			name: "empty prefix",
+			backends: map[string]BackendConfig{
+				"codex": {ModelPrefixes: []string{""}},
+			},
+			wantField: "backends.codex.model_prefixes[0]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{DefaultBackend: "claude", Backends: tt.backends, Parallel: ParallelConfig{MaxWorkers: 1}}
+
+			errs := Validate(cfg)
+			if tt.wantField == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+
+			found := false
+			for _, err := range errs {
+				if strings.Contains(err.Error(), tt.wantField+":") {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected error for %s, got %v", tt.wantField, errs)
+			}
+		})
+	}
+}
+
+func TestValidateCircuitBreakerConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		cb        CircuitBreakerConfig
+		wantField string
+	}{
+		{name: "disabled", cb: CircuitBreakerConfig{}},
+		{name: "valid", cb: CircuitBreakerConfig{Enabled: true, FailureThreshold: 5, SuccessThreshold: 2, OpenTimeoutSecs: 30}},
+		{name: "zero threshold", cb: CircuitBreakerConfig{Enabled: true, SuccessThreshold: 1, OpenTimeoutSecs: 1}, wantField: "circuit_breaker.failure_threshold"},
+		{name: "zero timeout", cb: CircuitBreakerConfig{Enabled: true, FailureThreshold: 1, SuccessThreshold: 1}, wantField: "circuit_breaker.open_timeout_secs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateCircuitBreakerConfig(&tt.cb)
+			if tt.wantField == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantField+":") {
+				t.Errorf("expected error for %s, got %v", tt.wantField, errs)
+			}
+		})
+	}
+}
+
+func TestValidateServerConcurrency(t *testing.T) {
+	tests := []struct {
+		name      string
+		mutate    func(*ServerConfig)
+		wantField string
+	}{
+		{name: "unlimited", mutate: func(*ServerConfig) {}},
+		{name: "limits", mutate: func(s *ServerConfig) {
+			s.MaxConcurrentProcesses = 8
+			s.MaxConcurrentPerBackend = 2
+			s.BackendConcurrency = map[string]int{"claude": 4}
+			s.QueueSize = 100
+			s.QueueTimeoutSecs = 60
+		}},
+		{name: "negative process limit", mutate: func(s *ServerConfig) { s.MaxConcurrentProcesses = -1 }, wantField: "server.max_concurrent_processes"},
+		{name: "negative backend limit", mutate: func(s *ServerConfig) { s.BackendConcurrency = map[string]int{"codex": -1} }, wantField: "server.backend_concurrency.codex"},
+		{name: "negative queue size", mutate: func(s *ServerConfig) { s.QueueSize = -1 }, wantField: "server.queue_size"},
+		{name: "negative queue timeout", mutate: func(s *ServerConfig) { s.QueueTimeoutSecs = -1 }, wantField: "server.queue_timeout_secs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s ServerConfig
+			tt.mutate(&s)
+			errs := validateServerConfig(&s)
+			if tt.wantField == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantField+":") {
+				t.Errorf("expected error for %s, got %v", tt.wantField, errs)
+			}
+		})
+	}
+}
+
+func TestValidateServerWebhooks(t *testing.T) {
+	tests := []struct {
+		name      string
+		mutate    func(*ServerConfig)
+		wantField string
+	}{
+		{name: "defaults", mutate: func(*ServerConfig) {}},
+		{name: "configured", mutate: func(s *ServerConfig) {
+			s.WebhookSecretEnv = "CI_WEBHOOK_SECRET"
+			s.WebhookMaxAttempts = 5
+			s.WebhookTimeoutSecs = 10
+			s.WebhookAllowedHosts = []string{"ci.example.com"}
+		}},
+		{name: "negative attempts", mutate: func(s *ServerConfig) { s.WebhookMaxAttempts = -1 }, wantField: "server.webhook_max_attempts"},
+		{name: "negative timeout", mutate: func(s *ServerConfig) { s.WebhookTimeoutSecs = -1 }, wantField: "server.webhook_timeout_secs"},
+		{name: "empty host", mutate: func(s *ServerConfig) { s.WebhookAllowedHosts = []string{"ci.example.com", " "} }, wantField: "server.webhook_allowed_hosts[1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s ServerConfig
+			tt.mutate(&s)
+			errs := validateServerConfig(&s)
+			if tt.wantField == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantField+":") {
+				t.Errorf("expected error for %s, got %v", tt.wantField, errs)
+			}
+		})
+	}
+}
+
+func TestValidateRetryConfig(t *testing.T) {
+	valid := RetryConfig{MaxAttempts: 3, InitialBackoffMS: 1000, MaxBackoffMS: 30000, Multiplier: 2, Jitter: 0.2}
+
+	tests := []struct {
+		name      string
+		mutate    func(*RetryConfig)
+		wantField string
+	}{
+		{name: "valid", mutate: func(*RetryConfig) {}},
+		{name: "zero values", mutate: func(r *RetryConfig) { *r = RetryConfig{} }},
+		{name: "negative attempts", mutate: func(r *RetryConfig) { r.MaxAttempts = -1 }, wantField: "retry.max_attempts"},
+		{name: "max below initial", mutate: func(r *RetryConfig) { r.MaxBackoffMS = 500 }, wantField: "retry.max_backoff_ms"},
+		{name: "shrinking multiplier", mutate: func(r *RetryConfig) { r.Multiplier = 0.5 }, wantField: "retry.multiplier"},
+		{name: "jitter out of range", mutate: func(r *RetryConfig) { r.Jitter = 1.5 }, wantField: "retry.jitter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid
+			tt.mutate(&r)
+			errs := validateRetryConfig(&r)
+			if tt.wantField == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantField+":") {
+				t.Errorf("expected error for %s, got %v", tt.wantField, errs)
+			}
+		})
+	}
+}
+
+func TestValidatePools(t *testing.T) {
+	backends := map[string]BackendConfig{"codex-work": {Type: BackendTypeGeneric}}
+
+	tests := []struct {
+		name      string
+		pools     map[string]PoolConfig
+		wantField string
+	}{
+		{name: "valid", pools: map[string]PoolConfig{
+			"codex-pool": {Strategy: "weighted", Members: []PoolMemberConfig{{Backend: "codex", Weight: 2}, {Backend: "codex-work", MaxConcurrency: 1}}},
+		}},
+		{name: "shadows backend", pools: map[string]PoolConfig{
+			"codex": {Members: []PoolMemberConfig{{Backend: "codex-work"}}},
+		}, wantField: "pools.codex"},
+		{name: "invalid strategy", pools: map[string]PoolConfig{
+			"p": {Strategy: "random", Members: []PoolMemberConfig{{Backend: "codex"}}},
+		}, wantField: "pools.p.strategy"},
+		{name: "no members", pools: map[string]PoolConfig{"p": {}}, wantField: "pools.p.members"},
+		{name: "duplicate member", pools: map[string]PoolConfig{
+			"p": {Members: []PoolMemberConfig{{Backend: "codex"}, {Backend: "codex"}}},
+		}, wantField: "pools.p.members[1].backend"},
+		{name: "nested pool", pools: map[string]PoolConfig{
+			"p": {Members: []PoolMemberConfig{{Backend: "q"}}},
+			"q": {Members: []PoolMemberConfig{{Backend: "codex"}}},
+		}, wantField: "pools.p.members[0].backend"},
+		{name: "negative weight", pools: map[string]PoolConfig{
+			"p": {Members: []PoolMemberConfig{{Backend: "codex", Weight: -1}}},
+		}, wantField: "pools.p.members[0].weight"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validatePools(tt.pools, backends)
+			if tt.wantField == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantField+":") {
+				t.Errorf("expected error for %s, got %v", tt.wantField, errs)
+			}
+		})
+	}
+}
+
+func TestValidateFallback(t *testing.T) {
+	tests := []struct {
+		name      string
+		fallback  []string
+		wantField string
+	}{
+		{name: "valid", fallback: []string{"claude", "gemini", "codex"}},
+		{name: "empty name", fallback: []string{"claude", ""}, wantField: "fallback[1]"},
+		{name: "duplicate", fallback: []string{"gemini", "gemini"}, wantField: "fallback[1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateFallback(tt.fallback)
+			if tt.wantField == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantField+":") {
+				t.Errorf("expected error for %s, got %v", tt.wantField, errs)
+			}
+		})
+	}
+}
+
+func TestValidateMCPServers(t *testing.T) {
+	tests := []struct {
+		name      string
+		server    MCPServerConfig
+		key       string
+		wantField string
+	}{
+		{name: "command", server: MCPServerConfig{Command: "github-mcp", Env: []string{"LOG_LEVEL=debug"}, EnvVars: []string{"GITHUB_TOKEN"}}},
+		{name: "url", server: MCPServerConfig{URL: "https://mcp.example.com/mcp", BearerTokenEnv: "DOCS_TOKEN"}},
+		{name: "bad name", key: "my.server", server: MCPServerConfig{Command: "x"}, wantField: "mcp_servers.my.server"},
+		{name: "neither", server: MCPServerConfig{}, wantField: "mcp_servers.s"},
+		{name: "both", server: MCPServerConfig{Command: "x", URL: "https://mcp.example.com"}, wantField: "mcp_servers.s"},
+		{name: "bad url", server: MCPServerConfig{URL: "file:///tmp/mcp"}, wantField: "mcp_servers.s.url"},
+		{name: "bad env", server: MCPServerConfig{Command: "x", Env: []string{"LOG_LEVEL"}}, wantField: "mcp_servers.s.env[0]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := tt.key
+			if key == "" {
+				key = "s"
+			}
+			errs := validateMCPServers(map[string]MCPServerConfig{key: tt.server})
+			if tt.wantField == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantField+":") {
+				t.Errorf("expected error for %s, got %v", tt.wantField, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package errors
+
+import "strings"
+
+// timeoutExitCode is the conventional exit code of a timed-out command.
+const timeoutExitCode = 124
+
+// contextOverflowMarkers are substrings backends print when the prompt does
+// not fit the model's context window.
+var contextOverflowMarkers = []string{
+	"context length",
+	"context_length_exceeded",
+	"context window",
+	"maximum context",
+	"prompt is too long",
+	"input is too long",
+	"too many tokens",
+	"http 413",
+}
+
+// authMarkers are substrings backends print when credentials are missing,
+// invalid or expired.
+var authMarkers = []string{
+	"unauthorized",
+	"unauthenticated",
+	"authentication",
+	"invalid api key",
+	"invalid_api_key",
+	"invalid x-api-key",
+	"api key not valid",
+	"not logged in",
+	"please log in",
+	"token expired",
+	"expired token",
+	"token has expired",
+	"http 401",
+	"http 403",
+}
+
+// rateLimitMarkers are substrings backends print when throttled.
+var rateLimitMarkers = []string{
+	"rate limit",
+	"rate_limit",
+	"ratelimit",
+	"too many requests",
+	"429",
+	"quota exceeded",
+	"resource_exhausted",
+	"overloaded",
+	"usage limit",
+}
+
+// networkMarkers are substrings backends print when the connection to the
+// provider failed.
+var networkMarkers = []string{
+	"econnreset",
+	"econnrefused",
+	"enotfound",
+	"etimedout",
+	"connection reset",
+	"connection refused",
+	"network error",
+	"socket hang up",
+	"fetch failed",
+	"bad gateway",
+	"service unavailable",
+	"temporarily unavailable",
+	"http 502",
+	"http 503",
+	"http 504",
+}
+
+// timeoutMarkers are substrings backends print when a request timed out.
+var timeoutMarkers = []string{
+	"timed out",
+	"timeout",
+	"deadline exceeded",
+}
+
+// ClassifyFailure maps a failed backend run to an error code from its exit
+// code and error output, which may be stderr or the error of a JSON response.
+func ClassifyFailure(exitCode int, message string) ErrorCode {
+	msg := strings.ToLower(message)
+	switch {
+	case containsAny(msg, contextOverflowMarkers):
+		return ErrCodeContextOverflow
+	case containsAny(msg, authMarkers):
+		return ErrCodeAuthFailed
+	case containsAny(msg, rateLimitMarkers):
+		return ErrCodeRateLimited
+	case containsAny(msg, networkMarkers):
+		return ErrCodeNetwork
+	case exitCode == timeoutExitCode || containsAny(msg, timeoutMarkers):
+		return ErrCodeBackendTimeout
+	default:
+		return ErrCodeBackendExecution
+	}
+}
+
+// IsBackendFailure reports whether code describes a failure of the backend
+// itself rather than of the request, so another backend may succeed.
+func IsBackendFailure(code ErrorCode) bool {
+	switch code {
+	case ErrCodeBackendUnavailable, ErrCodeBackendNotFound, ErrCodeBackendTimeout,
+		ErrCodeBackendExecution, ErrCodeRateLimited, ErrCodeAuthFailed, ErrCodeNetwork:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryable reports whether code describes a transient failure, so running
+// the same request on the same backend again may succeed. Auth failures are
+// retryable because CLIs refresh expired tokens on the next run.
+func IsRetryable(code ErrorCode) bool {
+	switch code {
+	case ErrCodeRateLimited, ErrCodeBackendTimeout, ErrCodeNetwork, ErrCodeAuthFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package errors
+
+import "testing"
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		exitCode int
+		message  string
+		want     ErrorCode
+	}{
+		{"rate limit message", 1, "Error: Rate limit reached for requests", ErrCodeRateLimited},
+		{"http 429", 1, "API Error: 429 Too Many Requests", ErrCodeRateLimited},
+		{"quota", 1, "RESOURCE_EXHAUSTED: quota exceeded", ErrCodeRateLimited},
+		{"timeout exit code", 124, "", ErrCodeBackendTimeout},
+		{"timeout message", 1, "request timed out", ErrCodeBackendTimeout},
+		{"context overflow", 1, `{"error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`, ErrCodeContextOverflow},
+		{"openai context overflow", 1, "HTTP 400: context_length_exceeded", ErrCodeContextOverflow},
+		{"invalid api key", 1, "Invalid API key · Please run /login", ErrCodeAuthFailed},
+		{"http 401", 1, "HTTP 401: unauthorized", ErrCodeAuthFailed},
+		{"connection reset", 1, "Error: read ECONNRESET", ErrCodeNetwork},
+		{"http 503", 1, "HTTP 503: upstream unavailable", ErrCodeNetwork},
+		{"other failure", 2, "unexpected token", ErrCodeBackendExecution},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyFailure(tt.exitCode, tt.message); got != tt.want {
+				t.Errorf("ClassifyFailure(%d, %q) = %q, want %q", tt.exitCode, tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBackendFailure(t *testing.T) {
+	for _, code := range []ErrorCode{ErrCodeRateLimited, ErrCodeBackendUnavailable, ErrCodeBackendExecution} {
+		if !IsBackendFailure(code) {
+			t.Errorf("expected %q to be a backend failure", code)
+		}
+	}
+	for _, code := range []ErrorCode{ErrCodeInvalidRequest, ErrCodeValidation, ErrCodeUnknown, ErrCodeContextOverflow} {
+		if IsBackendFailure(code) {
+			t.Errorf("expected %q not to be a backend failure", code)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	for _, code := range []ErrorCode{ErrCodeRateLimited, ErrCodeBackendTimeout, ErrCodeNetwork, ErrCodeAuthFailed} {
+		if !IsRetryable(code) {
+			t.Errorf("expected %q to be retryable", code)
+		}
+	}
+	for _, code := range []ErrorCode{ErrCodeContextOverflow, ErrCodeBackendExecution, ErrCodeBackendNotFound, ErrCodeInvalidRequest} {
+		if IsRetryable(code) {
+			t.Errorf("expected %q not to be retryable", code)
+		}
+	}
+}
@@ -16,6 +16,14 @@ const (
 	ErrCodeBackendNotFound    ErrorCode = "backend_not_found"
 	ErrCodeBackendTimeout     ErrorCode = "backend_timeout"
 	ErrCodeBackendExecution   ErrorCode = "backend_execution_error"
+	ErrCodeRateLimited        ErrorCode = "rate_limited"
+	ErrCodeAuthFailed         ErrorCode = "auth_failed"
+	ErrCodeContextOverflow    ErrorCode = "context_overflow"
+	ErrCodeNetwork            ErrorCode = "network_error"
+
+	// Capacity errors
+	ErrCodeQueueFull    ErrorCode = "queue_full"
+	ErrCodeQueueTimeout ErrorCode = "queue_timeout"
 
 	// Request errors
 	ErrCodeInvalidRequest  ErrorCode = "invalid_request"
@@ -0,0 +1,93 @@
+// Package job provides persistence for asynchronous server jobs.
+package job
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// IDBytes is the number of random bytes for job ID generation (128-bit entropy).
+const IDBytes = 16
+
+// Kind identifies the kind of work a job runs.
+type Kind string
+
+const (
+	KindPrompt   Kind = "prompt"
+	KindParallel Kind = "parallel"
+	KindChain    Kind = "chain"
+)
+
+// Status represents the current status of a job.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Done reports whether the status is final.
+func (s Status) Done() bool {
+	return s == StatusCompleted || s == StatusFailed || s == StatusCanceled
+}
+
+// Job is an asynchronous run of a prompt, parallel or chain request.
+type Job struct {
+	// ID is the unique job identifier.
+	ID string `json:"id"`
+
+	// Kind is the kind of request the job runs.
+	Kind Kind `json:"kind"`
+
+	// Status is the job's current status.
+	Status Status `json:"status"`
+
+	// Request is the request body the job was submitted with.
+	Request json.RawMessage `json:"request,omitempty"`
+
+	// Result is the response body of the finished request.
+	Result json.RawMessage `json:"result,omitempty"`
+
+	// Error explains why the job failed or was canceled.
+	Error string `json:"error,omitempty"`
+
+	// CreatedAt is when the job was submitted.
+	CreatedAt time.Time `json:"created_at"`
+
+	// StartedAt is when the job started running.
+	StartedAt *time.Time `json:"started_at,omitempty"`
+
+	// FinishedAt is when the job reached a final status.
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// CallbackURL receives the job when it reaches a final status.
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// Event is a progress event recorded for a job. Seq numbers a job's
+// events from 1, so clients can resume a stream after the last one seen.
+type Event struct {
+	Seq  int             `json:"seq"`
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Event types recorded by the job runner. Runs may record others.
+const (
+	// EventStatus reports a change of the job's status.
+	EventStatus = "status"
+)
+
+func generateID() (string, error) {
+	bytes := make([]byte, IDBytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,330 @@
+package job
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/config"
+)
+
+// ErrNotFound is returned when a job does not exist.
+var ErrNotFound = errors.New("job not found")
+
+// idPattern validates job IDs (hex string, 32 characters).
+var idPattern = regexp.MustCompile(`^[a-f0-9]{32}$`)
+
+const (
+	jobFileSuffix    = ".json"
+	eventsFileSuffix = ".events.jsonl"
+)
+
+// Store persists jobs and their events as files in a directory: a JSON
+// document per job and a JSON Lines log of its events.
+type Store struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewStore creates a job store in the default jobs directory.
+func NewStore() *Store {
+	return NewStoreWithDir(config.JobsDir())
+}
+
+// NewStoreWithDir creates a job store with a custom directory.
+func NewStoreWithDir(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// validateID checks if the job ID is valid and safe to use in a path.
+func validateID(id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("invalid job ID %q", id)
+	}
+	return nil
+}
+
+func (s *Store) jobPath(id string) string {
+	return filepath.Join(s.dir, id+jobFileSuffix)
+}
+
+func (s *Store) eventsPath(id string) string {
+	return filepath.Join(s.dir, id+eventsFileSuffix)
+}
+
+// Create persists a new pending job for the request.
+func (s *Store) Create(kind Kind, request json.RawMessage) (*Job, error) {
+	id, err := generateID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate job ID: %w", err)
+	}
+
+	j := &Job{
+		ID:        id,
+		Kind:      kind,
+		Status:    StatusPending,
+		Request:   request,
+		CreatedAt: time.Now(),
+	}
+	if err := s.Save(j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Save persists a job, replacing any earlier version.
+func (s *Store) Save(j *Job) error {
+	if err := validateID(j.ID); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+	if err := writeFileAtomic(s.jobPath(j.ID), data, 0600); err != nil {
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	return nil
+}
+
+// Get loads a job by ID.
+func (s *Store) Get(id string) (*Job, error) {
+	if err := validateID(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(s.jobPath(id))
+}
+
+func (s *Store) load(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read job: %w", err)
+	}
+
+	var j Job
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("failed to parse job: %w", err)
+	}
+	return &j, nil
+}
+
+// List returns all jobs, newest first. Unreadable job files are skipped.
+func (s *Store) List() ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read jobs directory: %w", err)
+	}
+
+	var jobs []*Job
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, jobFileSuffix) {
+			continue
+		}
+		if validateID(strings.TrimSuffix(name, jobFileSuffix)) != nil {
+			continue
+		}
+		j, err := s.load(filepath.Join(s.dir, name))
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].CreatedAt.After(jobs[k].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// AppendEvent adds an event to a job's event log.
+func (s *Store) AppendEvent(id string, ev Event) error {
+	if err := validateID(id); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.eventsPath(id), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open job events: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write job event: %w", err)
+	}
+	return f.Close()
+}
+
+// Events returns a job's events with a sequence number above afterSeq,
+// oldest first.
+func (s *Store) Events(id string, afterSeq int) ([]Event, error) {
+	if err := validateID(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.eventsPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open job events: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			// Skip a line torn by a crash.
+			continue
+		}
+		if ev.Seq > afterSeq {
+			events = append(events, ev)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read job events: %w", err)
+	}
+	return events, nil
+}
+
+// LastSeq returns the sequence number of a job's last event, or 0.
+func (s *Store) LastSeq(id string) (int, error) {
+	events, err := s.Events(id, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+	return events[len(events)-1].Seq, nil
+}
+
+// Delete removes a job and its event log.
+func (s *Store) Delete(id string) error {
+	if err := validateID(id); err != nil {
+		return ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.jobPath(id)); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	if err := os.Remove(s.eventsPath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete job events: %w", err)
+	}
+	return nil
+}
+
+// Prune deletes finished jobs that finished more than maxAge ago, and the
+// oldest finished jobs beyond the newest maxFinished. A zero maxAge or
+// maxFinished disables that limit. Unfinished jobs are kept. It returns
+// the number of jobs deleted.
+func (s *Store) Prune(maxAge time.Duration, maxFinished int) (int, error) {
+	if maxAge <= 0 && maxFinished <= 0 {
+		return 0, nil
+	}
+
+	jobs, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	kept, deleted := 0, 0
+	for _, j := range jobs {
+		if !j.Status.Done() {
+			continue
+		}
+		expired := maxAge > 0 && j.FinishedAt != nil && j.FinishedAt.Before(cutoff)
+		if !expired && (maxFinished <= 0 || kept < maxFinished) {
+			kept++
+			continue
+		}
+		if err := s.Delete(j.ID); err != nil && !errors.Is(err, ErrNotFound) {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place,
+// so readers never see a partially written job.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_CreateGetSave(t *testing.T) {
+	store := NewStoreWithDir(t.TempDir())
+
+	j, err := store.Create(KindPrompt, json.RawMessage(`{"backend":"claude","prompt":"hi"}`))
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if len(j.ID) != IDBytes*2 || j.Status != StatusPending {
+		t.Fatalf("unexpected job: %+v", j)
+	}
+
+	now := time.Now()
+	j.Status = StatusCompleted
+	j.Result = json.RawMessage(`{"exit_code":0}`)
+	j.FinishedAt = &now
+	if err := store.Save(j); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	got, err := store.Get(j.ID)
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if got.Status != StatusCompleted || got.Kind != KindPrompt || got.FinishedAt == nil {
+		t.Errorf("unexpected job: %+v", got)
+	}
+	var result map[string]int
+	if err := json.Unmarshal(got.Result, &result); err != nil || result["exit_code"] != 0 {
+		t.Errorf("unexpected result %s: %v", got.Result, err)
+	}
+}
+
+func TestStore_GetNotFound(t *testing.T) {
+	store := NewStoreWithDir(t.TempDir())
+
+	tests := []string{
+		"0123456789abcdef0123456789abcdef",
+		"../../etc/passwd",
+		"short",
+	}
+	for _, id := range tests {
+		t.Run(id, func(t *testing.T) {
+			if _, err := store.Get(id); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get(%q) = %v, want ErrNotFound", id, err)
+			}
+		})
+	}
+}
+
+func TestStore_List(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStoreWithDir(dir)
+
+	if jobs, err := store.List(); err != nil || len(jobs) != 0 {
+		t.Fatalf("expected no jobs, got %v, %v", jobs, err)
+	}
+
+	first, _ := store.Create(KindPrompt, nil)
+	second, _ := store.Create(KindChain, nil)
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	if err := store.Save(second); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.json"), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != second.ID || jobs[1].ID != first.ID {
+		t.Errorf("expected the two jobs newest first, got %+v", jobs)
+	}
+}
+
+func TestStore_Events(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStoreWithDir(dir)
+	j, _ := store.Create(KindParallel, nil)
+
+	if seq, err := store.LastSeq(j.ID); err != nil || seq != 0 {
+		t.Fatalf("LastSeq() = %d, %v; want 0", seq, err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		ev := Event{Seq: i, Type: EventStatus, Time: time.Now(), Data: json.RawMessage(`{"status":"running"}`)}
+		if err := store.AppendEvent(j.ID, ev); err != nil {
+			t.Fatalf("AppendEvent() error: %v", err)
+		}
+	}
+
+	events, err := store.Events(j.ID, 1)
+	if err != nil {
+		t.Fatalf("Events() error: %v", err)
+	}
+	if len(events) != 2 || events[0].Seq != 2 || events[1].Seq != 3 {
+		t.Errorf("expected events 2 and 3, got %+v", events)
+	}
+
+	// A line torn by a crash is skipped.
+	f, err := os.OpenFile(filepath.Join(dir, j.ID+eventsFileSuffix), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"seq":4,"ty`)
+	_ = f.Close()
+
+	if seq, err := store.LastSeq(j.ID); err != nil || seq != 3 {
+		t.Errorf("LastSeq() = %d, %v; want 3", seq, err)
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStoreWithDir(dir)
+
+	// finished creates a job that finished age ago, with an event
+	finished := func(age time.Duration) *Job {
+		t.Helper()
+		j, err := store.Create(KindPrompt, nil)
+		if err != nil {
+			t.Fatalf("Create() error: %v", err)
+		}
+		at := time.Now().Add(-age)
+		j.Status = StatusCompleted
+		j.CreatedAt = at
+		j.FinishedAt = &at
+		if err := store.Save(j); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+		if err := store.AppendEvent(j.ID, Event{Seq: 1, Type: EventStatus, Time: at}); err != nil {
+			t.Fatalf("AppendEvent() error: %v", err)
+		}
+		return j
+	}
+
+	expired := finished(48 * time.Hour)
+	oldest := finished(3 * time.Hour)
+	older := finished(2 * time.Hour)
+	newest := finished(time.Hour)
+	running, _ := store.Create(KindPrompt, nil)
+	running.CreatedAt = time.Now().Add(-72 * time.Hour)
+	running.Status = StatusRunning
+	_ = store.Save(running)
+
+	n, err := store.Prune(24*time.Hour, 2)
+	if err != nil {
+		t.Fatalf("Prune() error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Prune() = %d, want 2", n)
+	}
+
+	for _, j := range []*Job{expired, oldest} {
+		if _, err := store.Get(j.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected job %s to be deleted, got %v", j.ID, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, j.ID+eventsFileSuffix)); !os.IsNotExist(err) {
+			t.Errorf("expected the events of job %s to be deleted, got %v", j.ID, err)
+		}
+	}
+	for _, j := range []*Job{older, newest, running} {
+		if _, err := store.Get(j.ID); err != nil {
+			t.Errorf("expected job %s to be kept, got %v", j.ID, err)
+		}
+	}
+
+	if n, err := store.Prune(0, 0); err != nil || n != 0 {
+		t.Errorf("Prune(0, 0) = %d, %v; want nothing deleted", n, err)
+	}
+}
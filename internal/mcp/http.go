@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/signalridge/clinvoker/internal/server/middleware"
+)
+
+// protocolVersionHeader carries the negotiated protocol version on every
+// request after initialize.
+const protocolVersionHeader = "MCP-Protocol-Version"
+
+// Handler returns the streamable HTTP transport. Each POST carries one
+// message. The response to a request is sent as JSON, or as Server-Sent
+// Events when the client accepts them and the request is sent
+// notifications before its response, as a tool call asking for progress
+// is. The server keeps no session and opens no stream of its own, so other
+// methods are not allowed.
+//
+// Browsers may only call the handler from localhost and the origins in
+// allowedOrigins, which may hold * wildcards. The Host header is not
+// trusted: a page on a name rebound to a local server sends its own.
+func (s *Server) Handler(allowedOrigins []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !middleware.OriginAllowed(origin, allowedOrigins) {
+			writeHTTPError(w, http.StatusForbidden, CodeInvalidRequest, "origin not allowed: "+origin)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeHTTPError(w, http.StatusMethodNotAllowed, CodeInvalidRequest, "only POST is supported")
+			return
+		}
+		if version := r.Header.Get(protocolVersionHeader); version != "" && !slices.Contains(supportedVersions, version) {
+			writeHTTPError(w, http.StatusBadRequest, CodeInvalidRequest, "unsupported protocol version: "+version)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+		if err != nil {
+			writeHTTPError(w, http.StatusRequestEntityTooLarge, CodeInvalidRequest, err.Error())
+			return
+		}
+		var req Request
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeHTTPError(w, http.StatusBadRequest, CodeParseError, err.Error())
+			return
+		}
+
+		// Responses and notifications are acknowledged without a body.
+		// Calls end with their connection, so cancellations are not needed.
+		if req.Method == "" || req.isNotification() {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		conn := &httpConn{w: w, sse: acceptsEventStream(r)}
+		conn.respond(s.handle(r.Context(), &req, conn.notify))
+	})
+}
+
+// httpConn writes the answer to one HTTP request, switching to Server-Sent
+// Events on the first notification.
+type httpConn struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	sse     bool
+	started bool
+}
+
+// notify sends a notification as an event, or drops it when the client
+// does not accept events.
+func (c *httpConn) notify(method string, params any) error {
+	if !c.sse {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writeEvent(&Notification{JSONRPC: jsonrpcVersion, Method: method, Params: params})
+}
+
+// respond sends the response, as the last event when events were sent.
+func (c *httpConn) respond(resp *Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started {
+		_ = c.writeEvent(resp)
+		return
+	}
+	c.w.Header().Set("Content-Type", "application/json")
+	c.w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(c.w).Encode(resp)
+}
+
+// writeEvent writes msg as a message event.
+func (c *httpConn) writeEvent(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if !c.started {
+		c.w.Header().Set("Content-Type", "text/event-stream")
+		c.w.Header().Set("Cache-Control", "no-cache")
+		c.w.WriteHeader(http.StatusOK)
+		c.started = true
+	}
+	if _, err := fmt.Fprintf(c.w, "event: message\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+	if flusher, ok := c.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// writeHTTPError writes a JSON-RPC error that answers no request.
+func writeHTTPError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(&Response{JSONRPC: jsonrpcVersion, Error: &RPCError{Code: code, Message: message}})
+}
+
+// acceptsEventStream reports whether the client accepts Server-Sent Events.
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, _ := strings.Cut(part, ";")
+			if strings.TrimSpace(mediaType) == "text/event-stream" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,501 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+// message is a message written by the server.
+type message struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// newTestServer returns a server whose claude backend streams "Hello" and
+// " world", or sleeps for the prompt "slow".
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	lines := []string{
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Hello"}]}}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":" world"}]}}`,
+		`{"type":"result","usage":{"input_tokens":12,"output_tokens":5}}`,
+	}
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend(backend.BackendClaude,
+		mock.WithAvailable(true),
+		mock.WithCapabilities(backend.Capabilities{Resume: true}),
+		mock.WithCommandFunc(func(prompt string, _ *backend.UnifiedOptions) *exec.Cmd {
+			if prompt == "slow" {
+				return exec.Command("sleep", "10")
+			}
+			return exec.Command("printf", "%s\n", lines[0], lines[1], lines[2])
+		}),
+	)))
+
+	return NewServer(service.NewExecutor(), "test", nil)
+}
+
+// serve runs the stdio transport on requests and returns what it wrote.
+func serve(t *testing.T, srv *Server, requests ...string) []message {
+	t.Helper()
+	var out strings.Builder
+	if err := srv.Serve(context.Background(), strings.NewReader(strings.Join(requests, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve() error: %v", err)
+	}
+	return decodeMessages(t, strings.Split(strings.TrimSpace(out.String()), "\n"))
+}
+
+func decodeMessages(t *testing.T, lines []string) []message {
+	t.Helper()
+	var msgs []message
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		var msg message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("Unmarshal(%s) error: %v", line, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// toolCall returns a tools/call request.
+func toolCall(t *testing.T, id int, name string, args map[string]any, progressToken any) string {
+	t.Helper()
+	params := map[string]any{"name": name, "arguments": args}
+	if progressToken != nil {
+		params["_meta"] = map[string]any{"progressToken": progressToken}
+	}
+	data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": MethodToolsCall, "params": params})
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	return string(data)
+}
+
+// toolResult decodes the result of a tools/call response, with its
+// structured content decoded into structured.
+func toolResult(t *testing.T, msg message, structured any) CallToolResult {
+	t.Helper()
+	if msg.Error != nil {
+		t.Fatalf("tools/call error: %v", msg.Error)
+	}
+	var result struct {
+		CallToolResult
+		StructuredContent json.RawMessage `json:"structuredContent"`
+	}
+	if err := json.Unmarshal(msg.Result, &result); err != nil {
+		t.Fatalf("Unmarshal(%s) error: %v", msg.Result, err)
+	}
+	if structured != nil {
+		if err := json.Unmarshal(result.StructuredContent, structured); err != nil {
+			t.Fatalf("Unmarshal(%s) error: %v", result.StructuredContent, err)
+		}
+	}
+	return result.CallToolResult
+}
+
+// progressOf decodes the progress notifications among msgs.
+func progressOf(t *testing.T, msgs []message) []ProgressParams {
+	t.Helper()
+	var progress []ProgressParams
+	for _, msg := range msgs {
+		if msg.Method != MethodProgress {
+			continue
+		}
+		var params ProgressParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			t.Fatalf("Unmarshal(%s) error: %v", msg.Params, err)
+		}
+		progress = append(progress, params)
+	}
+	return progress
+}
+
+func TestServe_Protocol(t *testing.T) {
+	srv := newTestServer(t)
+
+	msgs := serve(t, srv,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":"two","method":"ping"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":5,"result":{}}`,
+		`not json`,
+		`{"id":6,"method":"ping"}`,
+	)
+	if len(msgs) != 6 {
+		t.Fatalf("got %d messages, want 6: %+v", len(msgs), msgs)
+	}
+
+	var initResult InitializeResult
+	if err := json.Unmarshal(msgs[0].Result, &initResult); err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+	if initResult.ProtocolVersion != "2025-03-26" {
+		t.Errorf("protocolVersion = %q, want the requested 2025-03-26", initResult.ProtocolVersion)
+	}
+	if initResult.ServerInfo != (Implementation{Name: serverName, Version: "test"}) {
+		t.Errorf("serverInfo = %+v", initResult.ServerInfo)
+	}
+	if initResult.Capabilities.Tools == nil {
+		t.Error("expected the tools capability")
+	}
+
+	if string(msgs[1].ID) != `"two"` || string(msgs[1].Result) != "{}" {
+		t.Errorf("ping response = id %s, result %s", msgs[1].ID, msgs[1].Result)
+	}
+
+	var list ListToolsResult
+	if err := json.Unmarshal(msgs[2].Result, &list); err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+		if tool.InputSchema["type"] != "object" {
+			t.Errorf("tool %s input schema type = %v, want object", tool.Name, tool.InputSchema["type"])
+		}
+	}
+	want := []string{ToolPrompt, ToolParallel, ToolChain, ToolCompare, ToolListSessions, ToolResumeSession}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("tools = %v, want %v", names, want)
+	}
+
+	wantErrors := []struct {
+		id   string
+		code int
+	}{
+		{"4", CodeMethodNotFound},
+		{"null", CodeParseError},
+		{"6", CodeInvalidRequest},
+	}
+	for i, want := range wantErrors {
+		msg := msgs[3+i]
+		if string(msg.ID) != want.id || msg.Error == nil || msg.Error.Code != want.code {
+			t.Errorf("message %d = id %s, error %+v, want id %s, code %d", 3+i, msg.ID, msg.Error, want.id, want.code)
+		}
+	}
+}
+
+func TestServe_ToolCalls(t *testing.T) {
+	srv := newTestServer(t)
+
+	t.Run("prompt", func(t *testing.T) {
+		msgs := serve(t, srv, toolCall(t, 1, ToolPrompt, map[string]any{"prompt": "hi", "ephemeral": true}, nil))
+		if len(msgs) != 1 {
+			t.Fatalf("got %d messages, want 1", len(msgs))
+		}
+		var result service.PromptResult
+		tr := toolResult(t, msgs[0], &result)
+		if tr.IsError {
+			t.Errorf("isError = true, content = %+v", tr.Content)
+		}
+		if result.Backend != backend.BackendClaude || result.SessionID != "" {
+			t.Errorf("result = %+v, want an ephemeral claude run", result)
+		}
+		if len(tr.Content) != 1 || !strings.Contains(tr.Content[0].Text, `"backend": "claude"`) {
+			t.Errorf("content = %+v, want the result as JSON text", tr.Content)
+		}
+	})
+
+	var sessionID string
+	t.Run("prompt with progress", func(t *testing.T) {
+		msgs := serve(t, srv, toolCall(t, 1, ToolPrompt, map[string]any{"prompt": "hi"}, "tok"))
+
+		var messages []string
+		for _, p := range progressOf(t, msgs) {
+			if string(p.ProgressToken) != `"tok"` {
+				t.Errorf("progressToken = %s, want \"tok\"", p.ProgressToken)
+			}
+			messages = append(messages, p.Message)
+		}
+		if want := []string{"Hello", "world"}; !reflect.DeepEqual(messages, want) {
+			t.Errorf("progress messages = %q, want %q", messages, want)
+		}
+
+		var result service.PromptResult
+		if tr := toolResult(t, msgs[len(msgs)-1], &result); tr.IsError {
+			t.Errorf("isError = true, content = %+v", tr.Content)
+		}
+		if result.Output != "Hello world" {
+			t.Errorf("output = %q, want %q", result.Output, "Hello world")
+		}
+		if result.TokenUsage == nil || result.TokenUsage.InputTokens != 12 {
+			t.Errorf("token usage = %+v, want the backend's", result.TokenUsage)
+		}
+		if result.SessionID == "" {
+			t.Fatal("expected the run to be recorded as a session")
+		}
+		sessionID = result.SessionID
+	})
+
+	t.Run("list and resume sessions", func(t *testing.T) {
+		if sessionID == "" {
+			t.Skip("no session was recorded")
+		}
+		msgs := serve(t, srv,
+			toolCall(t, 1, ToolListSessions, map[string]any{"backend": backend.BackendClaude}, nil),
+			toolCall(t, 2, ToolResumeSession, map[string]any{"session_id": sessionID[:8], "prompt": "again"}, nil),
+		)
+		if len(msgs) != 2 {
+			t.Fatalf("got %d messages, want 2", len(msgs))
+		}
+		byID := map[string]message{}
+		for _, msg := range msgs {
+			byID[string(msg.ID)] = msg
+		}
+
+		var list service.SessionListResult
+		toolResult(t, byID["1"], &list)
+		if list.Total != 1 || list.Sessions[0].ID != sessionID {
+			t.Errorf("sessions = %+v, want only %s", list, sessionID)
+		}
+
+		var result service.PromptResult
+		if tr := toolResult(t, byID["2"], &result); tr.IsError {
+			t.Errorf("isError = true, content = %+v", tr.Content)
+		}
+		if result.SessionID != sessionID {
+			t.Errorf("session = %q, want %q", result.SessionID, sessionID)
+		}
+	})
+
+	t.Run("parallel, chain and compare report progress", func(t *testing.T) {
+		tests := []struct {
+			name string
+			args map[string]any
+		}{
+			{ToolParallel, map[string]any{"tasks": []map[string]any{{"prompt": "a", "ephemeral": true}, {"prompt": "b", "ephemeral": true}}}},
+			{ToolChain, map[string]any{"steps": []map[string]any{{"name": "first", "prompt": "a"}, {"prompt": "{{previous}}"}}}},
+			{ToolCompare, map[string]any{"backends": []string{backend.BackendClaude, backend.BackendClaude}, "prompt": "a"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				msgs := serve(t, srv, toolCall(t, 1, tt.name, tt.args, 7))
+				progress := progressOf(t, msgs)
+				if len(progress) != 2 {
+					t.Fatalf("got %d progress notifications, want 2", len(progress))
+				}
+				for i, p := range progress {
+					if p.Progress != float64(i+1) || p.Total != 2 || string(p.ProgressToken) != "7" {
+						t.Errorf("progress %d = %+v", i, p)
+					}
+				}
+				if tr := toolResult(t, msgs[len(msgs)-1], nil); tr.IsError {
+					t.Errorf("isError = true, content = %+v", tr.Content)
+				}
+			})
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		msgs := serve(t, srv,
+			toolCall(t, 1, "missing", nil, nil),
+			toolCall(t, 2, ToolPrompt, map[string]any{"prompt": "hi", "unknown": true}, nil),
+			toolCall(t, 3, ToolPrompt, map[string]any{}, nil),
+			toolCall(t, 4, ToolResumeSession, map[string]any{"session_id": "nope", "prompt": "hi"}, nil),
+		)
+		byID := map[string]message{}
+		for _, msg := range msgs {
+			byID[string(msg.ID)] = msg
+		}
+
+		if msg := byID["1"]; msg.Error == nil || msg.Error.Code != CodeInvalidParams {
+			t.Errorf("unknown tool error = %+v, want code %d", msg.Error, CodeInvalidParams)
+		}
+		for _, id := range []string{"2", "3", "4"} {
+			if tr := toolResult(t, byID[id], nil); !tr.IsError {
+				t.Errorf("call %s: isError = false, want a tool error", id)
+			}
+		}
+	})
+}
+
+func TestServe_Cancel(t *testing.T) {
+	srv := newTestServer(t)
+
+	r, w := io.Pipe()
+	var out strings.Builder
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(context.Background(), r, &out) }()
+
+	start := time.Now()
+	_, _ = io.WriteString(w, toolCall(t, 1, ToolPrompt, map[string]any{"prompt": "slow", "ephemeral": true}, nil)+"\n")
+	_, _ = io.WriteString(w, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}`+"\n")
+	_ = w.Close()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Serve() error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not return after the call was cancelled")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled call ran for %v", elapsed)
+	}
+	if out.Len() != 0 {
+		t.Errorf("cancelled call was answered: %s", out.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	srv := newTestServer(t)
+	server := httptest.NewServer(srv.Handler([]string{"https://*.example.com"}))
+	t.Cleanup(server.Close)
+
+	post := func(t *testing.T, body string, headers map[string]string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("NewRequest() error: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+	const ping = `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+
+	t.Run("request", func(t *testing.T) {
+		resp := post(t, ping, map[string]string{"Accept": "application/json, text/event-stream", protocolVersionHeader: ProtocolVersion})
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+			t.Fatalf("status = %d, Content-Type = %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		var msg message
+		if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+			t.Fatalf("Decode() error: %v", err)
+		}
+		if string(msg.ID) != "1" || string(msg.Result) != "{}" {
+			t.Errorf("response = %+v", msg)
+		}
+	})
+
+	t.Run("notification", func(t *testing.T) {
+		if resp := post(t, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, nil); resp.StatusCode != http.StatusAccepted {
+			t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+		}
+	})
+
+	t.Run("tool call with progress", func(t *testing.T) {
+		call := toolCall(t, 2, ToolPrompt, map[string]any{"prompt": "hi", "ephemeral": true}, "tok")
+		resp := post(t, call, map[string]string{"Accept": "application/json, text/event-stream"})
+		if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+			t.Fatalf("Content-Type = %q, want text/event-stream", got)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("ReadAll() error: %v", err)
+		}
+
+		var lines []string
+		for _, line := range strings.Split(string(body), "\n") {
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				lines = append(lines, data)
+			}
+		}
+		msgs := decodeMessages(t, lines)
+		if len(progressOf(t, msgs)) != 2 {
+			t.Errorf("events = %s, want two progress notifications", body)
+		}
+		var result service.PromptResult
+		toolResult(t, msgs[len(msgs)-1], &result)
+		if result.Output != "Hello world" {
+			t.Errorf("output = %q, want %q", result.Output, "Hello world")
+		}
+	})
+
+	t.Run("tool call without event stream", func(t *testing.T) {
+		call := toolCall(t, 3, ToolPrompt, map[string]any{"prompt": "hi", "ephemeral": true}, "tok")
+		resp := post(t, call, map[string]string{"Accept": "application/json"})
+		if got := resp.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+	})
+
+	t.Run("rejections", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			method  string
+			body    string
+			headers map[string]string
+			want    int
+		}{
+			{"GET", http.MethodGet, "", nil, http.StatusMethodNotAllowed},
+			{"parse error", http.MethodPost, "{", nil, http.StatusBadRequest},
+			{"protocol version", http.MethodPost, ping, map[string]string{protocolVersionHeader: "1999-01-01"}, http.StatusBadRequest},
+			{"origin", http.MethodPost, ping, map[string]string{"Origin": "https://evil.test"}, http.StatusForbidden},
+			{"allowed origin", http.MethodPost, ping, map[string]string{"Origin": "https://app.example.com"}, http.StatusOK},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req, err := http.NewRequest(tt.method, server.URL, strings.NewReader(tt.body))
+				if err != nil {
+					t.Fatalf("NewRequest() error: %v", err)
+				}
+				for k, v := range tt.headers {
+					req.Header.Set(k, v)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatalf("Do() error: %v", err)
+				}
+				_ = resp.Body.Close()
+				if resp.StatusCode != tt.want {
+					t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("rebound host", func(t *testing.T) {
+		// A page on a name rebound to the server sends its own name as Host
+		req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(ping))
+		if err != nil {
+			t.Fatalf("NewRequest() error: %v", err)
+		}
+		req.Host = "evil.example:8080"
+		req.Header.Set("Origin", "http://evil.example:8080")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+// Package mcp serves clinvk to other agents over the Model Context Protocol.
+//
+// The server exposes the executor as MCP tools: prompt, parallel, chain,
+// compare, list_sessions and resume_session. It speaks JSON-RPC 2.0 over
+// stdio (one message per line, as started by `clinvk mcp`) or over the
+// streamable HTTP transport (mounted by `clinvk serve`). Methods:
+//
+//	initialize                InitializeParams -> InitializeResult
+//	ping                      -> {}
+//	tools/list                -> ListToolsResult
+//	tools/call                CallToolParams -> CallToolResult
+//	notifications/initialized (ignored)
+//	notifications/cancelled   CancelledParams (stdio only)
+//
+// A tools/call whose _meta carries a progressToken is sent
+// notifications/progress while it runs.
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// ProtocolVersion is the latest MCP revision the server implements.
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the MCP revisions the server accepts, newest first.
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// Method names.
+const (
+	MethodInitialize  = "initialize"
+	MethodPing        = "ping"
+	MethodToolsList   = "tools/list"
+	MethodToolsCall   = "tools/call"
+	MethodInitialized = "notifications/initialized"
+	MethodCancelled   = "notifications/cancelled"
+	MethodProgress    = "notifications/progress"
+)
+
+const (
+	jsonrpcVersion = "2.0"
+	serverName     = "clinvk"
+
+	// maxMessageSize caps a message read from stdio or HTTP.
+	maxMessageSize = 10 * 1024 * 1024
+	// progressMessageSize caps the text of a progress message.
+	progressMessageSize = 200
+)
+
+// serverInstructions tell the client what the tools are for.
+const serverInstructions = "Run prompts on the AI CLI backends configured in clinvk (such as claude, codex and gemini), alone, in parallel, as a chain or compared across backends. Sessions created by prompt can be continued with resume_session."
+
+// Standard JSON-RPC error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Request is a JSON-RPC request, or a notification when ID is empty.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// isNotification reports whether no response is expected for r.
+func (r *Request) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+// Response is a JSON-RPC response.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// Notification is a JSON-RPC notification sent by the server.
+type Notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// RPCError is a JSON-RPC error object.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements error.
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// Implementation names an MCP client or server.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// InitializeParams are the params of initialize.
+type InitializeParams struct {
+	ProtocolVersion string          `json:"protocolVersion"`
+	Capabilities    json.RawMessage `json:"capabilities,omitempty"`
+	ClientInfo      Implementation  `json:"clientInfo"`
+}
+
+// InitializeResult is the result of initialize.
+type InitializeResult struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+	ServerInfo      Implementation     `json:"serverInfo"`
+	Instructions    string             `json:"instructions,omitempty"`
+}
+
+// ServerCapabilities are the features the server offers.
+type ServerCapabilities struct {
+	Tools *ToolsCapability `json:"tools,omitempty"`
+}
+
+// ToolsCapability describes the tools feature.
+type ToolsCapability struct {
+	ListChanged bool `json:"listChanged"`
+}
+
+// Tool describes a tool the client may call.
+type Tool struct {
+	Name        string           `json:"name"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description"`
+	InputSchema map[string]any   `json:"inputSchema"`
+	Annotations *ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are hints about a tool's behavior.
+type ToolAnnotations struct {
+	ReadOnlyHint  bool `json:"readOnlyHint"`
+	OpenWorldHint bool `json:"openWorldHint"`
+}
+
+// ListToolsResult is the result of tools/list.
+type ListToolsResult struct {
+	Tools []Tool `json:"tools"`
+}
+
+// CallToolParams are the params of tools/call.
+type CallToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Meta      *RequestMeta    `json:"_meta,omitempty"`
+}
+
+// RequestMeta is the _meta of a request.
+type RequestMeta struct {
+	// ProgressToken is a string or number the client matches progress
+	// notifications with.
+	ProgressToken json.RawMessage `json:"progressToken,omitempty"`
+}
+
+// CallToolResult is the result of tools/call. A tool that ran but failed
+// sets IsError, so the calling model can see the failure.
+type CallToolResult struct {
+	Content           []Content `json:"content"`
+	StructuredContent any       `json:"structuredContent,omitempty"`
+	IsError           bool      `json:"isError,omitempty"`
+}
+
+// Content is a content block of a tool result.
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// ProgressParams are the params of notifications/progress.
+type ProgressParams struct {
+	ProgressToken json.RawMessage `json:"progressToken"`
+	Progress      float64         `json:"progress"`
+	Total         float64         `json:"total,omitempty"`
+	Message       string          `json:"message,omitempty"`
+}
+
+// CancelledParams are the params of notifications/cancelled.
+type CancelledParams struct {
+	RequestID json.RawMessage `json:"requestId"`
+	Reason    string          `json:"reason,omitempty"`
+}
+
+// negotiateVersion returns the protocol version to use with a client that
+// requested version: the same one when supported, the latest otherwise.
+func negotiateVersion(version string) string {
+	if slices.Contains(supportedVersions, version) {
+		return version
+	}
+	return ProtocolVersion
+}
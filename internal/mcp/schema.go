@@ -0,0 +1,87 @@
+package mcp
+
+// promptSchema is the input schema of a prompt, as taken by the prompt tool
+// and by parallel tasks.
+func promptSchema() map[string]any {
+	properties := runOptionProperties()
+	properties["backend"] = stringSchema("Backend or pool to run on (default from config)")
+	properties["prompt"] = stringSchema("Prompt to run")
+	properties["model"] = stringSchema("Model to use (default from config)")
+	properties["workdir"] = stringSchema("Working directory of the backend")
+	properties["system_prompt"] = stringSchema("System prompt")
+	properties["ephemeral"] = booleanSchema("Do not record the run as a session")
+	properties["json_schema"] = map[string]any{
+		"type":        "object",
+		"description": "JSON Schema the reply must match; the matching JSON is returned as structured",
+	}
+	return objectSchema(properties, "prompt")
+}
+
+// chainStepSchema is the input schema of a chain step.
+func chainStepSchema() map[string]any {
+	properties := runOptionProperties()
+	properties["name"] = stringSchema("Step name, reported in progress and results")
+	properties["backend"] = stringSchema("Backend or pool to run on (default from config)")
+	properties["prompt"] = stringSchema("Prompt to run; {{previous}} is replaced by the output of the previous step")
+	properties["model"] = stringSchema("Model to use (default from config)")
+	properties["workdir"] = stringSchema("Working directory of the backend")
+	properties["system_prompt"] = stringSchema("System prompt")
+	return objectSchema(properties, "prompt")
+}
+
+// resumeSchema is the input schema of the resume_session tool.
+func resumeSchema() map[string]any {
+	properties := runOptionProperties()
+	properties["session_id"] = stringSchema("ID, or unique ID prefix, of the session to continue")
+	properties["prompt"] = stringSchema("Prompt to continue the session with")
+	return objectSchema(properties, "session_id", "prompt")
+}
+
+// runOptionProperties are the options of a backend run.
+func runOptionProperties() map[string]any {
+	return map[string]any{
+		"approval_mode": enumSchema("How the backend asks for approval of its actions", "default", "auto", "none", "always"),
+		"sandbox_mode":  enumSchema("What the backend may access", "default", "read-only", "workspace", "full"),
+		"max_tokens":    integerSchema("Maximum response tokens, for backends that support a limit"),
+		"max_turns":     integerSchema("Maximum agentic turns"),
+	}
+}
+
+// objectSchema is the schema of an object with properties, of which
+// required must be set. Other properties are not allowed.
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// arraySchema is the schema of a non-empty array of items.
+func arraySchema(description string, items map[string]any) map[string]any {
+	return map[string]any{"type": "array", "description": description, "items": items, "minItems": 1}
+}
+
+// stringSchema is the schema of a string.
+func stringSchema(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+// enumSchema is the schema of a string among values.
+func enumSchema(description string, values ...string) map[string]any {
+	return map[string]any{"type": "string", "description": description, "enum": values}
+}
+
+// integerSchema is the schema of a non-negative integer.
+func integerSchema(description string) map[string]any {
+	return map[string]any{"type": "integer", "description": description, "minimum": 0}
+}
+
+// booleanSchema is the schema of a boolean.
+func booleanSchema(description string) map[string]any {
+	return map[string]any{"type": "boolean", "description": description}
+}
@@ -0,0 +1,239 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+// Server answers MCP requests by running tools on an executor.
+type Server struct {
+	executor *service.Executor
+	version  string
+	logger   *slog.Logger
+}
+
+// NewServer creates a server that runs tools on executor and reports
+// version as its own.
+func NewServer(executor *service.Executor, version string, logger *slog.Logger) *Server {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Server{executor: executor, version: version, logger: logger}
+}
+
+// notifyFunc sends a notification to the client that made a request.
+type notifyFunc func(method string, params any) error
+
+// handle answers a request. Notifications are left to the transport.
+func (s *Server) handle(ctx context.Context, req *Request, notify notifyFunc) *Response {
+	resp := &Response{JSONRPC: jsonrpcVersion, ID: req.ID}
+	if req.JSONRPC != jsonrpcVersion || req.Method == "" {
+		resp.Error = &RPCError{Code: CodeInvalidRequest, Message: "invalid JSON-RPC 2.0 request"}
+		return resp
+	}
+
+	result, err := s.dispatch(ctx, req, notify)
+	if err != nil {
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &RPCError{Code: CodeInternalError, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+// dispatch runs the method of req and returns its result.
+func (s *Server) dispatch(ctx context.Context, req *Request, notify notifyFunc) (any, error) {
+	switch req.Method {
+	case MethodInitialize:
+		var params InitializeParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		s.logger.Debug("MCP client connected", "client", params.ClientInfo.Name, "version", params.ClientInfo.Version, "protocol", params.ProtocolVersion)
+		return &InitializeResult{
+			ProtocolVersion: negotiateVersion(params.ProtocolVersion),
+			Capabilities:    ServerCapabilities{Tools: &ToolsCapability{}},
+			ServerInfo:      Implementation{Name: serverName, Version: s.version},
+			Instructions:    serverInstructions,
+		}, nil
+
+	case MethodPing:
+		return struct{}{}, nil
+
+	case MethodToolsList:
+		return &ListToolsResult{Tools: toolList()}, nil
+
+	case MethodToolsCall:
+		var params CallToolParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.callTool(ctx, &params, notify)
+
+	default:
+		return nil, &RPCError{Code: CodeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+}
+
+// decodeParams decodes the params of a request into v.
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return &RPCError{Code: CodeInvalidParams, Message: "params are required"}
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &RPCError{Code: CodeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+// Serve runs the stdio transport: it reads messages from r, one per line,
+// and writes responses and notifications to w, until r is exhausted or ctx
+// is done. Tool calls run concurrently, so the client can ping or cancel
+// them meanwhile; Serve returns once the calls in flight have answered.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	conn := &stdioConn{enc: json.NewEncoder(w), inflight: make(map[string]context.CancelFunc), logger: s.logger}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Read on a goroutine, so ctx ends Serve even while r blocks
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				select {
+				case err := <-readErr:
+					return err
+				default:
+					return nil
+				}
+			}
+			if len(line) > 0 {
+				s.serveLine(ctx, conn, line, &wg)
+			}
+		}
+	}
+}
+
+// serveLine answers one message read from stdio.
+func (s *Server) serveLine(ctx context.Context, conn *stdioConn, line []byte, wg *sync.WaitGroup) {
+	var req Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		conn.send(&Response{JSONRPC: jsonrpcVersion, Error: &RPCError{Code: CodeParseError, Message: err.Error()}})
+		return
+	}
+
+	switch {
+	case req.Method == "":
+		// A response; the server sends no requests
+		return
+	case req.isNotification():
+		if req.Method == MethodCancelled {
+			var params CancelledParams
+			if err := json.Unmarshal(req.Params, &params); err == nil {
+				conn.cancel(params.RequestID)
+			}
+		}
+		return
+	case req.Method != MethodToolsCall:
+		conn.send(s.handle(ctx, &req, conn.notify))
+		return
+	}
+
+	callCtx, cancel := context.WithCancel(ctx)
+	conn.track(req.ID, cancel)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		resp := s.handle(callCtx, &req, conn.notify)
+		// A call the client cancelled is not answered
+		if conn.untrack(req.ID) {
+			conn.send(resp)
+		}
+	}()
+}
+
+// stdioConn writes the messages of a stdio session and tracks its tool
+// calls in flight.
+type stdioConn struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	inflight map[string]context.CancelFunc
+	logger   *slog.Logger
+}
+
+// send writes a message.
+func (c *stdioConn) send(msg any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.enc.Encode(msg); err != nil {
+		c.logger.Warn("failed to write MCP message", "error", err)
+	}
+}
+
+// notify writes a notification.
+func (c *stdioConn) notify(method string, params any) error {
+	c.send(&Notification{JSONRPC: jsonrpcVersion, Method: method, Params: params})
+	return nil
+}
+
+// track records a tool call in flight.
+func (c *stdioConn) track(id json.RawMessage, cancel context.CancelFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inflight[string(id)] = cancel
+}
+
+// untrack forgets a finished tool call, and reports whether it was still
+// in flight rather than cancelled.
+func (c *stdioConn) untrack(id json.RawMessage) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.inflight[string(id)]
+	delete(c.inflight, string(id))
+	return ok
+}
+
+// cancel stops a tool call in flight.
+func (c *stdioConn) cancel(id json.RawMessage) {
+	c.mu.Lock()
+	cancel, ok := c.inflight[string(id)]
+	delete(c.inflight, string(id))
+	c.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
@@ -0,0 +1,581 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/output"
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+// Tool names.
+const (
+	ToolPrompt        = "prompt"
+	ToolParallel      = "parallel"
+	ToolChain         = "chain"
+	ToolCompare       = "compare"
+	ToolListSessions  = "list_sessions"
+	ToolResumeSession = "resume_session"
+)
+
+// defaultSessionLimit is how many sessions list_sessions returns by default.
+const defaultSessionLimit = 20
+
+// toolFunc runs a tool with its arguments.
+type toolFunc func(s *Server, ctx context.Context, args json.RawMessage, p *progress) (*CallToolResult, error)
+
+// tool is a tool and the function that runs it.
+type tool struct {
+	Tool
+	run toolFunc
+}
+
+// tools returns the tools the server offers.
+func tools() []tool {
+	runs := &ToolAnnotations{OpenWorldHint: true}
+	return []tool{
+		{Tool{
+			Name:        ToolPrompt,
+			Title:       "Run a prompt",
+			Description: "Run a prompt on a clinvk backend and return its output. Unless ephemeral, the run is recorded as a session that resume_session can continue.",
+			InputSchema: promptSchema(),
+			Annotations: runs,
+		}, (*Server).callPrompt},
+		{Tool{
+			Name:        ToolParallel,
+			Title:       "Run prompts in parallel",
+			Description: "Run several prompts at once, each on its own backend, and return every result.",
+			InputSchema: objectSchema(map[string]any{
+				"tasks":        arraySchema("Prompts to run", promptSchema()),
+				"max_parallel": integerSchema("Maximum prompts running at once (default from config)"),
+				"fail_fast":    booleanSchema("Stop the remaining tasks once one fails"),
+			}, "tasks"),
+			Annotations: runs,
+		}, (*Server).callParallel},
+		{Tool{
+			Name:        ToolChain,
+			Title:       "Run a chain of prompts",
+			Description: "Run prompts one after another. A step's prompt may include {{previous}}, which is replaced by the output of the step before it.",
+			InputSchema: objectSchema(map[string]any{
+				"steps":            arraySchema("Steps to run in order", chainStepSchema()),
+				"stop_on_failure":  booleanSchema("Stop the chain at the first failed step"),
+				"pass_working_dir": booleanSchema("Run a step without a workdir in the workdir of the step before it"),
+			}, "steps"),
+			Annotations: runs,
+		}, (*Server).callChain},
+		{Tool{
+			Name:        ToolCompare,
+			Title:       "Compare backends",
+			Description: "Run the same prompt on several backends and return each backend's output side by side.",
+			InputSchema: objectSchema(map[string]any{
+				"backends":   arraySchema("Backends to compare", map[string]any{"type": "string"}),
+				"prompt":     stringSchema("Prompt to run on every backend"),
+				"model":      stringSchema("Model to use on every backend"),
+				"workdir":    stringSchema("Working directory of the backends"),
+				"sequential": booleanSchema("Run the backends one at a time instead of at once"),
+			}, "backends", "prompt"),
+			Annotations: runs,
+		}, (*Server).callCompare},
+		{Tool{
+			Name:        ToolListSessions,
+			Title:       "List sessions",
+			Description: "List the clinvk sessions, most recently used first.",
+			InputSchema: objectSchema(map[string]any{
+				"backend": stringSchema("Only sessions of this backend"),
+				"status":  enumSchema("Only sessions with this status", "active", "paused", "completed", "error"),
+				"limit":   integerSchema(fmt.Sprintf("Maximum sessions to return (default %d)", defaultSessionLimit)),
+				"offset":  integerSchema("Sessions to skip"),
+			}),
+			Annotations: &ToolAnnotations{ReadOnlyHint: true},
+		}, (*Server).callListSessions},
+		{Tool{
+			Name:        ToolResumeSession,
+			Title:       "Resume a session",
+			Description: "Continue a clinvk session with a new prompt, on the backend, model and working directory the session was started with.",
+			InputSchema: resumeSchema(),
+			Annotations: runs,
+		}, (*Server).callResumeSession},
+	}
+}
+
+// toolList returns the descriptions of the tools.
+func toolList() []Tool {
+	all := tools()
+	list := make([]Tool, len(all))
+	for i, t := range all {
+		list[i] = t.Tool
+	}
+	return list
+}
+
+// callTool runs the tool a tools/call names. A tool that fails is reported
+// in its result rather than as a protocol error, so the calling model sees
+// why.
+func (s *Server) callTool(ctx context.Context, params *CallToolParams, notify notifyFunc) (*CallToolResult, error) {
+	for _, t := range tools() {
+		if t.Name != params.Name {
+			continue
+		}
+		result, err := t.run(s, ctx, params.Arguments, newProgress(params.Meta, notify))
+		if err != nil {
+			return &CallToolResult{Content: []Content{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+		}
+		return result, nil
+	}
+	return nil, &RPCError{Code: CodeInvalidParams, Message: "unknown tool: " + params.Name}
+}
+
+// promptArgs are the arguments of the prompt tool and of parallel tasks.
+type promptArgs struct {
+	Backend      string         `json:"backend"`
+	Prompt       string         `json:"prompt"`
+	Model        string         `json:"model"`
+	WorkDir      string         `json:"workdir"`
+	ApprovalMode string         `json:"approval_mode"`
+	SandboxMode  string         `json:"sandbox_mode"`
+	MaxTokens    int            `json:"max_tokens"`
+	MaxTurns     int            `json:"max_turns"`
+	SystemPrompt string         `json:"system_prompt"`
+	Ephemeral    bool           `json:"ephemeral"`
+	JSONSchema   map[string]any `json:"json_schema"`
+}
+
+// request returns the executor request of the arguments.
+func (a *promptArgs) request() (*service.PromptRequest, error) {
+	if a.Prompt == "" {
+		return nil, errors.New("prompt is required")
+	}
+	return &service.PromptRequest{
+		Backend:      backendOrDefault(a.Backend),
+		Prompt:       a.Prompt,
+		Model:        a.Model,
+		WorkDir:      a.WorkDir,
+		ApprovalMode: a.ApprovalMode,
+		SandboxMode:  a.SandboxMode,
+		MaxTokens:    a.MaxTokens,
+		MaxTurns:     a.MaxTurns,
+		SystemPrompt: a.SystemPrompt,
+		Ephemeral:    a.Ephemeral,
+		JSONSchema:   a.JSONSchema,
+	}, nil
+}
+
+// callPrompt runs the prompt tool.
+func (s *Server) callPrompt(ctx context.Context, raw json.RawMessage, p *progress) (*CallToolResult, error) {
+	var args promptArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	req, err := args.request()
+	if err != nil {
+		return nil, err
+	}
+	return s.runPrompt(ctx, req, p)
+}
+
+// resumeArgs are the arguments of the resume_session tool.
+type resumeArgs struct {
+	SessionID    string `json:"session_id"`
+	Prompt       string `json:"prompt"`
+	ApprovalMode string `json:"approval_mode"`
+	SandboxMode  string `json:"sandbox_mode"`
+	MaxTokens    int    `json:"max_tokens"`
+	MaxTurns     int    `json:"max_turns"`
+}
+
+// callResumeSession runs the resume_session tool.
+func (s *Server) callResumeSession(ctx context.Context, raw json.RawMessage, p *progress) (*CallToolResult, error) {
+	var args resumeArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.SessionID == "" {
+		return nil, errors.New("session_id is required")
+	}
+	if args.Prompt == "" {
+		return nil, errors.New("prompt is required")
+	}
+
+	sess, err := s.executor.GetSession(ctx, args.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("session %s: %w", args.SessionID, err)
+	}
+	return s.runPrompt(ctx, &service.PromptRequest{
+		Backend:      sess.Backend,
+		Prompt:       args.Prompt,
+		Model:        sess.Model,
+		WorkDir:      sess.WorkingDir,
+		ApprovalMode: args.ApprovalMode,
+		SandboxMode:  args.SandboxMode,
+		MaxTokens:    args.MaxTokens,
+		MaxTurns:     args.MaxTurns,
+		SessionID:    sess.ID,
+	}, p)
+}
+
+// runPrompt runs a prompt. When the client asked for progress, the prompt
+// is streamed and its events are reported as progress.
+func (s *Server) runPrompt(ctx context.Context, req *service.PromptRequest, p *progress) (*CallToolResult, error) {
+	if !p.enabled() || req.JSONSchema != nil {
+		result, err := s.executor.ExecutePrompt(ctx, req)
+		if result == nil {
+			return nil, err
+		}
+		return jsonResult(result, result.ExitCode != 0 || result.Error != "")
+	}
+
+	start := time.Now()
+	var out strings.Builder
+	streamResult, err := s.executor.StreamPrompt(ctx, req, func(event *output.UnifiedEvent) error {
+		if event.Type == output.EventMessage {
+			if content, err := event.GetMessageContent(); err == nil {
+				out.WriteString(content.Text)
+			}
+		}
+		p.event(event)
+		return nil
+	})
+	if streamResult == nil {
+		return nil, err
+	}
+
+	result := &service.PromptResult{
+		SessionID:  streamResult.SessionID,
+		Backend:    streamResult.Backend,
+		ExitCode:   streamResult.ExitCode,
+		DurationMS: time.Since(start).Milliseconds(),
+		Output:     out.String(),
+		Error:      streamResult.Error,
+		TokenUsage: streamResult.TokenUsage,
+		Attempts:   streamResult.Attempts,
+	}
+	if err != nil && result.Error == "" {
+		result.Error = err.Error()
+	}
+	return jsonResult(result, result.ExitCode != 0 || result.Error != "")
+}
+
+// parallelArgs are the arguments of the parallel tool.
+type parallelArgs struct {
+	Tasks       []promptArgs `json:"tasks"`
+	MaxParallel int          `json:"max_parallel"`
+	FailFast    bool         `json:"fail_fast"`
+}
+
+// callParallel runs the parallel tool, reporting progress as tasks finish.
+func (s *Server) callParallel(ctx context.Context, raw json.RawMessage, p *progress) (*CallToolResult, error) {
+	var args parallelArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	if len(args.Tasks) == 0 {
+		return nil, errors.New("tasks are required")
+	}
+
+	req := &service.ParallelRequest{
+		Tasks:       make([]service.PromptRequest, len(args.Tasks)),
+		MaxParallel: args.MaxParallel,
+		FailFast:    args.FailFast,
+	}
+	for i := range args.Tasks {
+		task, err := args.Tasks[i].request()
+		if err != nil {
+			return nil, fmt.Errorf("task %d: %w", i+1, err)
+		}
+		req.Tasks[i] = *task
+	}
+	total := len(req.Tasks)
+	req.OnResult = func(index int, result *service.PromptResult) {
+		p.advance(total, fmt.Sprintf("Task %d %s on %s", index+1, outcome(result.ExitCode, result.Error), result.Backend))
+	}
+
+	result, err := s.executor.ExecuteParallel(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResult(result, result.Failed > 0)
+}
+
+// chainStepArgs are the arguments of a chain step.
+type chainStepArgs struct {
+	Name         string `json:"name"`
+	Backend      string `json:"backend"`
+	Prompt       string `json:"prompt"`
+	Model        string `json:"model"`
+	WorkDir      string `json:"workdir"`
+	ApprovalMode string `json:"approval_mode"`
+	SandboxMode  string `json:"sandbox_mode"`
+	MaxTokens    int    `json:"max_tokens"`
+	MaxTurns     int    `json:"max_turns"`
+	SystemPrompt string `json:"system_prompt"`
+}
+
+// chainArgs are the arguments of the chain tool.
+type chainArgs struct {
+	Steps          []chainStepArgs `json:"steps"`
+	StopOnFailure  bool            `json:"stop_on_failure"`
+	PassWorkingDir bool            `json:"pass_working_dir"`
+}
+
+// callChain runs the chain tool, reporting progress as steps finish.
+func (s *Server) callChain(ctx context.Context, raw json.RawMessage, p *progress) (*CallToolResult, error) {
+	var args chainArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	if len(args.Steps) == 0 {
+		return nil, errors.New("steps are required")
+	}
+
+	req := &service.ChainRequest{
+		Steps:          make([]service.ChainStep, len(args.Steps)),
+		StopOnFailure:  args.StopOnFailure,
+		PassWorkingDir: args.PassWorkingDir,
+	}
+	for i, step := range args.Steps {
+		if step.Prompt == "" {
+			return nil, fmt.Errorf("step %d: prompt is required", i+1)
+		}
+		req.Steps[i] = service.ChainStep{
+			Name:         step.Name,
+			Backend:      backendOrDefault(step.Backend),
+			Prompt:       step.Prompt,
+			Model:        step.Model,
+			WorkDir:      step.WorkDir,
+			ApprovalMode: step.ApprovalMode,
+			SandboxMode:  step.SandboxMode,
+			MaxTokens:    step.MaxTokens,
+			MaxTurns:     step.MaxTurns,
+			SystemPrompt: step.SystemPrompt,
+		}
+	}
+	total := len(req.Steps)
+	req.OnStep = func(result *service.ChainStepResult) {
+		name := fmt.Sprintf("Step %d", result.Step)
+		if result.Name != "" {
+			name += " (" + result.Name + ")"
+		}
+		p.advance(total, fmt.Sprintf("%s %s on %s", name, outcome(result.ExitCode, result.Error), result.Backend))
+	}
+
+	result, err := s.executor.ExecuteChain(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResult(result, result.FailedStep != 0)
+}
+
+// compareArgs are the arguments of the compare tool.
+type compareArgs struct {
+	Backends   []string `json:"backends"`
+	Prompt     string   `json:"prompt"`
+	Model      string   `json:"model"`
+	WorkDir    string   `json:"workdir"`
+	Sequential bool     `json:"sequential"`
+}
+
+// callCompare runs the compare tool, reporting progress as backends finish.
+func (s *Server) callCompare(ctx context.Context, raw json.RawMessage, p *progress) (*CallToolResult, error) {
+	var args compareArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	if len(args.Backends) == 0 {
+		return nil, errors.New("backends are required")
+	}
+	if args.Prompt == "" {
+		return nil, errors.New("prompt is required")
+	}
+
+	total := len(args.Backends)
+	result, err := s.executor.ExecuteCompare(ctx, &service.CompareRequest{
+		Backends:   args.Backends,
+		Prompt:     args.Prompt,
+		Model:      args.Model,
+		WorkDir:    args.WorkDir,
+		Sequential: args.Sequential,
+		OnResult: func(_ int, result *service.CompareBackendResult) {
+			p.advance(total, fmt.Sprintf("%s %s", result.Backend, outcome(result.ExitCode, result.Error)))
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	failed := false
+	for _, r := range result.Results {
+		if r.ExitCode != 0 || r.Error != "" {
+			failed = true
+		}
+	}
+	return jsonResult(result, failed)
+}
+
+// listSessionsArgs are the arguments of the list_sessions tool.
+type listSessionsArgs struct {
+	Backend string `json:"backend"`
+	Status  string `json:"status"`
+	Limit   int    `json:"limit"`
+	Offset  int    `json:"offset"`
+}
+
+// callListSessions runs the list_sessions tool.
+func (s *Server) callListSessions(ctx context.Context, raw json.RawMessage, _ *progress) (*CallToolResult, error) {
+	var args listSessionsArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.Limit <= 0 {
+		args.Limit = defaultSessionLimit
+	}
+
+	result, err := s.executor.ListSessionsPaginated(ctx, &service.SessionListOptions{
+		Backend: args.Backend,
+		Status:  args.Status,
+		Limit:   args.Limit,
+		Offset:  args.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jsonResult(result, false)
+}
+
+// decodeArgs decodes the arguments of a tool call into v, rejecting
+// arguments the tool does not take.
+func decodeArgs(raw json.RawMessage, v any) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		raw = json.RawMessage("{}")
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// jsonResult returns a tool result holding v, both as structured content
+// and as its JSON text for clients that read only text.
+func jsonResult(v any, isError bool) (*CallToolResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &CallToolResult{
+		Content:           []Content{{Type: "text", Text: string(data)}},
+		StructuredContent: v,
+		IsError:           isError,
+	}, nil
+}
+
+// backendOrDefault returns name, or the configured default backend.
+func backendOrDefault(name string) string {
+	if name != "" {
+		return name
+	}
+	return config.Get().DefaultBackend
+}
+
+// outcome describes how a run ended.
+func outcome(exitCode int, errMsg string) string {
+	if exitCode != 0 || errMsg != "" {
+		return "failed"
+	}
+	return "finished"
+}
+
+// progress sends the progress notifications of a tool call. It sends
+// nothing when the call carries no progress token.
+type progress struct {
+	token  json.RawMessage
+	notify notifyFunc
+
+	mu   sync.Mutex
+	sent int
+}
+
+// newProgress returns the progress of a call with meta.
+func newProgress(meta *RequestMeta, notify notifyFunc) *progress {
+	p := &progress{notify: notify}
+	if meta != nil {
+		p.token = meta.ProgressToken
+	}
+	return p
+}
+
+// enabled reports whether the client asked for progress.
+func (p *progress) enabled() bool {
+	return len(p.token) > 0 && p.notify != nil
+}
+
+// advance reports one more step of total, or of an unknown total when
+// total is zero.
+func (p *progress) advance(total int, message string) {
+	if !p.enabled() {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent++
+	_ = p.notify(MethodProgress, &ProgressParams{
+		ProgressToken: p.token,
+		Progress:      float64(p.sent),
+		Total:         float64(total),
+		Message:       truncate(message, progressMessageSize),
+	})
+}
+
+// event reports a backend event, if it says something about the run.
+func (p *progress) event(event *output.UnifiedEvent) {
+	if message := eventMessage(event); message != "" {
+		p.advance(0, message)
+	}
+}
+
+// eventMessage describes an event for a progress notification, or returns
+// "" for events that are not reported.
+func eventMessage(event *output.UnifiedEvent) string {
+	switch event.Type {
+	case output.EventInit:
+		return "Started " + event.Backend
+	case output.EventMessage:
+		if content, err := event.GetMessageContent(); err == nil {
+			return strings.TrimSpace(content.Text)
+		}
+	case output.EventThinking:
+		return "Thinking"
+	case output.EventToolUse:
+		if content, err := event.GetToolUseContent(); err == nil {
+			return "Using tool " + content.ToolName
+		}
+	case output.EventToolResult:
+		if content, err := event.GetToolResultContent(); err == nil {
+			if content.IsError {
+				return "Tool " + content.ToolName + " failed"
+			}
+			return "Tool " + content.ToolName + " finished"
+		}
+	case output.EventError:
+		if content, err := event.GetErrorContent(); err == nil {
+			return "Error: " + content.Message
+		}
+	}
+	return ""
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -80,6 +80,81 @@ var (
 	)
 )
 
+// Circuit breaker metrics
+var (
+	// CircuitBreakerState tracks each backend's circuit state
+	// (0 = closed, 1 = open, 2 = half-open).
+	CircuitBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "circuit_breaker_state",
+			Help:      "Backend circuit breaker state (0=closed, 1=open, 2=half-open)",
+		},
+		[]string{"backend"},
+	)
+
+	// CircuitBreakerRejections counts calls rejected by an open circuit.
+	CircuitBreakerRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "circuit_breaker_rejections_total",
+			Help:      "Total number of backend calls rejected by an open circuit breaker",
+		},
+		[]string{"backend"},
+	)
+)
+
+// Concurrency limit metrics
+var (
+	// ConcurrencyQueueDepth tracks the requests waiting for a process slot
+	// per backend.
+	ConcurrencyQueueDepth = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "concurrency_queue_depth",
+			Help:      "Number of requests waiting for a backend process slot",
+		},
+		[]string{"backend"},
+	)
+
+	// ConcurrencyQueueWait tracks the time requests waited for a process
+	// slot in seconds.
+	ConcurrencyQueueWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "concurrency_queue_wait_seconds",
+			Help:      "Time spent waiting for a backend process slot in seconds",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 17), // 1ms to ~65s
+		},
+		[]string{"backend"},
+	)
+
+	// ConcurrencyRejections counts requests turned away by the concurrency
+	// limits, by reason (queue_full or queue_timeout).
+	ConcurrencyRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "concurrency_rejections_total",
+			Help:      "Total number of requests rejected by the backend concurrency limits",
+		},
+		[]string{"backend", "reason"},
+	)
+)
+
+// Webhook metrics
+var (
+	// WebhookDeliveries counts finished webhook deliveries by event and
+	// status (delivered or failed).
+	WebhookDeliveries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_deliveries_total",
+			Help:      "Total number of finished webhook deliveries",
+		},
+		[]string{"event", "status"},
+	)
+)
+
 // RecordRequest records an HTTP request metric.
 func RecordRequest(method, path, status string) {
 	RequestsTotal.WithLabelValues(method, path, status).Inc()
@@ -109,3 +184,33 @@ func SetActiveSessions(count float64) {
 func IncrementSessionsCreated() {
 	SessionsCreated.Inc()
 }
+
+// SetCircuitBreakerState sets the circuit state of a backend.
+func SetCircuitBreakerState(backend string, state float64) {
+	CircuitBreakerState.WithLabelValues(backend).Set(state)
+}
+
+// RecordCircuitBreakerRejection records a call rejected by an open circuit.
+func RecordCircuitBreakerRejection(backend string) {
+	CircuitBreakerRejections.WithLabelValues(backend).Inc()
+}
+
+// SetConcurrencyQueueDepth sets the number of requests waiting for a backend.
+func SetConcurrencyQueueDepth(backend string, depth float64) {
+	ConcurrencyQueueDepth.WithLabelValues(backend).Set(depth)
+}
+
+// RecordConcurrencyQueueWait records the time a request waited for a slot.
+func RecordConcurrencyQueueWait(backend string, durationSeconds float64) {
+	ConcurrencyQueueWait.WithLabelValues(backend).Observe(durationSeconds)
+}
+
+// RecordConcurrencyRejection records a request rejected by the concurrency limits.
+func RecordConcurrencyRejection(backend, reason string) {
+	ConcurrencyRejections.WithLabelValues(backend, reason).Inc()
+}
+
+// RecordWebhookDelivery records a finished webhook delivery.
+func RecordWebhookDelivery(event, status string) {
+	WebhookDeliveries.WithLabelValues(event, status).Inc()
+}
@@ -43,3 +43,43 @@ func TestSessionMetrics(t *testing.T) {
 		t.Fatalf("SessionsCreated did not increment: before=%v after=%v", before, after)
 	}
 }
+
+func TestCircuitBreakerMetrics(t *testing.T) {
+	SetCircuitBreakerState("claude", 1)
+	if got := testutil.ToFloat64(CircuitBreakerState.WithLabelValues("claude")); got != 1 {
+		t.Fatalf("CircuitBreakerState = %v, want 1", got)
+	}
+
+	before := testutil.ToFloat64(CircuitBreakerRejections.WithLabelValues("claude"))
+	RecordCircuitBreakerRejection("claude")
+	after := testutil.ToFloat64(CircuitBreakerRejections.WithLabelValues("claude"))
+	if after != before+1 {
+		t.Fatalf("CircuitBreakerRejections did not increment: before=%v after=%v", before, after)
+	}
+}
+
+func TestConcurrencyMetrics(t *testing.T) {
+	SetConcurrencyQueueDepth("claude", 2)
+	if got := testutil.ToFloat64(ConcurrencyQueueDepth.WithLabelValues("claude")); got != 2 {
+		t.Fatalf("ConcurrencyQueueDepth = %v, want 2", got)
+	}
+
+	before := testutil.ToFloat64(ConcurrencyRejections.WithLabelValues("claude", "queue_full"))
+	RecordConcurrencyRejection("claude", "queue_full")
+	after := testutil.ToFloat64(ConcurrencyRejections.WithLabelValues("claude", "queue_full"))
+	if after != before+1 {
+		t.Fatalf("ConcurrencyRejections did not increment: before=%v after=%v", before, after)
+	}
+
+	// Histogram observation should not panic
+	RecordConcurrencyQueueWait("claude", 0.25)
+}
+
+func TestWebhookMetrics(t *testing.T) {
+	before := testutil.ToFloat64(WebhookDeliveries.WithLabelValues("job.completed", "delivered"))
+	RecordWebhookDelivery("job.completed", "delivered")
+	after := testutil.ToFloat64(WebhookDeliveries.WithLabelValues("job.completed", "delivered"))
+	if after != before+1 {
+		t.Fatalf("WebhookDeliveries did not increment: before=%v after=%v", before, after)
+	}
+}
@@ -22,6 +22,7 @@ type MockBackend struct {
 	jsonResponse   *backend.UnifiedResponse
 	jsonError      error
 	separateStderr bool
+	capabilities   backend.Capabilities
 	commandFunc    func(prompt string, opts *backend.UnifiedOptions) *exec.Cmd
 }
 
@@ -75,6 +76,13 @@ func WithSeparateStderr(separate bool) MockBackendOption {
 	}
 }
 
+// WithCapabilities sets the capabilities returned by Capabilities.
+func WithCapabilities(caps backend.Capabilities) MockBackendOption {
+	return func(m *MockBackend) {
+		m.capabilities = caps
+	}
+}
+
 // WithCommandFunc sets a custom function for building commands.
 func WithCommandFunc(f func(prompt string, opts *backend.UnifiedOptions) *exec.Cmd) MockBackendOption {
 	return func(m *MockBackend) {
@@ -133,6 +141,9 @@ func (m *MockBackend) ParseJSONResponse(rawOutput string) (*backend.UnifiedRespo
 // SeparateStderr returns whether stderr should be captured separately.
 func (m *MockBackend) SeparateStderr() bool { return m.separateStderr }
 
+// Capabilities returns the configured capabilities.
+func (m *MockBackend) Capabilities() backend.Capabilities { return m.capabilities }
+
 // TempDir creates a temporary directory for testing.
 // It returns the directory path and a cleanup function.
 func TempDir(t *testing.T) (string, func()) {
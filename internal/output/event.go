@@ -38,6 +38,17 @@ const (
 	EventTokenUsage EventType = "token_usage"
 )
 
+// IsContent reports whether events of this type carry response content,
+// as opposed to lifecycle, error and accounting events.
+func (t EventType) IsContent() bool {
+	switch t {
+	case EventMessage, EventToolUse, EventToolResult, EventThinking:
+		return true
+	default:
+		return false
+	}
+}
+
 // UnifiedEvent represents a normalized event from any backend.
 type UnifiedEvent struct {
 	// Type is the event type.
@@ -192,6 +203,18 @@ func (e *UnifiedEvent) GetToolResultContent() (*ToolResultContent, error) {
 	return &content, nil
 }
 
+// GetThinkingContent parses the content as ThinkingContent.
+func (e *UnifiedEvent) GetThinkingContent() (*ThinkingContent, error) {
+	if e.Type != EventThinking {
+		return nil, ErrInvalidEventType
+	}
+	var content ThinkingContent
+	if err := json.Unmarshal(e.Content, &content); err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
 // GetErrorContent parses the content as ErrorContent.
 func (e *UnifiedEvent) GetErrorContent() (*ErrorContent, error) {
 	if e.Type != EventError {
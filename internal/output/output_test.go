@@ -44,6 +44,18 @@ func TestNewUnifiedEvent(t *testing.T) {
 	})
 }
 
+func TestEventType_IsContent(t *testing.T) {
+	content := map[EventType]bool{
+		EventInit: false, EventMessage: true, EventToolUse: true, EventToolResult: true,
+		EventThinking: true, EventError: false, EventDone: false, EventProgress: false, EventTokenUsage: false,
+	}
+	for et, want := range content {
+		if got := et.IsContent(); got != want {
+			t.Errorf("%s.IsContent() = %v, want %v", et, got, want)
+		}
+	}
+}
+
 func TestUnifiedEvent_SetContent(t *testing.T) {
 	t.Run("sets content successfully", func(t *testing.T) {
 		event := NewUnifiedEvent(EventMessage, "claude", "session-123")
@@ -214,6 +226,32 @@ func TestUnifiedEvent_GetToolResultContent(t *testing.T) {
 	})
 }
 
+func TestUnifiedEvent_GetThinkingContent(t *testing.T) {
+	t.Run("returns content for thinking event", func(t *testing.T) {
+		event := NewUnifiedEvent(EventThinking, "claude", "session-123")
+		event.SetContent(&ThinkingContent{Text: "Let me think...", IsPartial: true})
+
+		content, err := event.GetThinkingContent()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if content.Text != "Let me think..." {
+			t.Errorf("expected text %q, got %q", "Let me think...", content.Text)
+		}
+		if !content.IsPartial {
+			t.Error("expected IsPartial to be true")
+		}
+	})
+
+	t.Run("returns error for wrong event type", func(t *testing.T) {
+		event := NewUnifiedEvent(EventMessage, "claude", "session-123")
+		_, err := event.GetThinkingContent()
+		if err != ErrInvalidEventType {
+			t.Errorf("expected ErrInvalidEventType, got %v", err)
+		}
+	})
+}
+
 func TestUnifiedEvent_GetErrorContent(t *testing.T) {
 	t.Run("returns content for error event", func(t *testing.T) {
 		event := NewUnifiedEvent(EventError, "claude", "session-123")
@@ -340,6 +378,34 @@ func TestParser_ParseLine(t *testing.T) {
 		}
 	})
 
+	t.Run("registered decoder handles unknown backend", func(t *testing.T) {
+		RegisterLineDecoder("custom", func(line string) (EventType, any, error) {
+			if line == "skip" {
+				return "", nil, nil
+			}
+			return EventMessage, &MessageContent{Text: line, Role: "assistant"}, nil
+		})
+		t.Cleanup(func() { UnregisterLineDecoder("custom") })
+
+		p := NewParser("custom", "session-123")
+		event, err := p.ParseLine("hello")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if event == nil || event.Type != EventMessage || event.Backend != "custom" {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+		content, err := event.GetMessageContent()
+		if err != nil || content.Text != "hello" {
+			t.Errorf("unexpected content: %+v, %v", content, err)
+		}
+
+		event, err = p.ParseLine("skip")
+		if err != nil || event != nil {
+			t.Errorf("expected skipped line, got %+v, %v", event, err)
+		}
+	})
+
 	t.Run("increments sequence", func(t *testing.T) {
 		p := NewParser("claude", "session-123")
 		p.ParseLine("test1")
@@ -406,6 +472,22 @@ func TestParser_ParseClaudeLine(t *testing.T) {
 		}
 	})
 
+	t.Run("parses assistant thinking", func(t *testing.T) {
+		line := `{"type": "assistant", "message": {"content": [{"type": "thinking", "thinking": "Let me think...", "signature": "sig"}]}}`
+		event, err := p.ParseLine(line)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if event.Type != EventThinking {
+			t.Errorf("expected type %q, got %q", EventThinking, event.Type)
+		}
+
+		content, _ := event.GetThinkingContent()
+		if content.Text != "Let me think..." {
+			t.Errorf("expected text %q, got %q", "Let me think...", content.Text)
+		}
+	})
+
 	t.Run("parses content_block_delta text", func(t *testing.T) {
 		line := `{"type": "content_block_delta", "delta": {"type": "text_delta", "text": "Hello"}}`
 		event, err := p.ParseLine(line)
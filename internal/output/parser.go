@@ -6,9 +6,41 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 )
 
+// LineDecoder decodes a single line of output for a backend that is not
+// built in. It returns the event type and its content; an empty event type
+// means the line carries no event.
+type LineDecoder func(line string) (EventType, any, error)
+
+var (
+	lineDecoders   = make(map[string]LineDecoder)
+	lineDecodersMu sync.RWMutex
+)
+
+// RegisterLineDecoder registers the stream decoder for a backend.
+func RegisterLineDecoder(backend string, decoder LineDecoder) {
+	lineDecodersMu.Lock()
+	defer lineDecodersMu.Unlock()
+	lineDecoders[backend] = decoder
+}
+
+// UnregisterLineDecoder removes the stream decoder for a backend.
+func UnregisterLineDecoder(backend string) {
+	lineDecodersMu.Lock()
+	defer lineDecodersMu.Unlock()
+	delete(lineDecoders, backend)
+}
+
+func lookupLineDecoder(backend string) (LineDecoder, bool) {
+	lineDecodersMu.RLock()
+	defer lineDecodersMu.RUnlock()
+	decoder, ok := lineDecoders[backend]
+	return decoder, ok
+}
+
 // Parser converts backend-specific output to unified events.
 type Parser struct {
 	backend   string
@@ -42,10 +74,25 @@ func (p *Parser) ParseLine(line string) (*UnifiedEvent, error) {
 	case "codex":
 		return p.parseCodexLine(line)
 	default:
+		if decoder, ok := lookupLineDecoder(p.backend); ok {
+			return p.parseDecodedLine(decoder, line)
+		}
 		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, p.backend)
 	}
 }
 
+// parseDecodedLine parses a line using a registered LineDecoder.
+func (p *Parser) parseDecodedLine(decoder LineDecoder, line string) (*UnifiedEvent, error) {
+	eventType, content, err := decoder(line)
+	if err != nil {
+		return nil, err
+	}
+	if eventType == "" {
+		return nil, nil
+	}
+	return p.createEvent(eventType, content)
+}
+
 // ParseStream reads and parses a stream of output.
 func (p *Parser) ParseStream(r io.Reader, eventCh chan<- *UnifiedEvent, errCh chan<- error) {
 	scanner := bufio.NewScanner(r)
@@ -105,6 +152,9 @@ func (p *Parser) parseClaudeLine(line string) (*UnifiedEvent, error) {
 					case "text":
 						text, _ := cm["text"].(string)
 						return p.createMessageEvent(text, false)
+					case "thinking":
+						text, _ := cm["thinking"].(string)
+						return p.createThinkingEvent(text, false)
 					case "tool_use":
 						return p.createToolUseEvent(
 							getString(cm, "id"),
@@ -0,0 +1,167 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"sync"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/output"
+)
+
+// Backend adapts a plugin process to the backend.Backend interface.
+type Backend struct {
+	name   string
+	path   string
+	client *Client
+
+	describeOnce sync.Once
+	describe     DescribeResult
+}
+
+// NewBackend creates a backend named name served by the plugin at path.
+func NewBackend(name, path string) *Backend {
+	return &Backend{
+		name:   name,
+		path:   path,
+		client: NewClient(path),
+	}
+}
+
+// Client returns the underlying RPC client.
+func (b *Backend) Client() *Client {
+	return b.client
+}
+
+// Name returns the backend identifier.
+func (b *Backend) Name() string {
+	return b.name
+}
+
+// Describe returns the plugin's static description, fetched once.
+// Plugins that do not implement Describe get the zero value.
+func (b *Backend) Describe() DescribeResult {
+	b.describeOnce.Do(func() {
+		params := &DescribeParams{ProtocolVersion: ProtocolVersion, Name: b.name}
+		if err := b.client.Call(context.Background(), MethodDescribe, params, &b.describe); err != nil && !isMethodNotFound(err) {
+			slog.Warn("plugin describe failed", "backend", b.name, "error", err)
+		}
+	})
+	return b.describe
+}
+
+// IsAvailable asks the plugin whether its backend can run.
+// Plugins that do not implement IsAvailable are considered available if they respond.
+func (b *Backend) IsAvailable() bool {
+	var available bool
+	err := b.client.Call(context.Background(), MethodIsAvailable, nil, &available)
+	if err != nil {
+		return isMethodNotFound(err)
+	}
+	return available
+}
+
+// BuildCommand creates an exec.Cmd for running a prompt.
+func (b *Backend) BuildCommand(prompt string, opts *backend.Options) *exec.Cmd {
+	return b.buildCommand(&BuildCommandParams{Prompt: prompt, Options: optionsFromBackend(opts)})
+}
+
+// ResumeCommand creates an exec.Cmd for resuming a session.
+func (b *Backend) ResumeCommand(sessionID, prompt string, opts *backend.Options) *exec.Cmd {
+	return b.buildCommand(&BuildCommandParams{Prompt: prompt, SessionID: sessionID, Options: optionsFromBackend(opts)})
+}
+
+// BuildCommandUnified creates an exec.Cmd using unified options.
+func (b *Backend) BuildCommandUnified(prompt string, opts *backend.UnifiedOptions) *exec.Cmd {
+	return b.buildCommand(&BuildCommandParams{Prompt: prompt, Options: optionsFromUnified(opts)})
+}
+
+// ResumeCommandUnified creates a resume exec.Cmd using unified options.
+func (b *Backend) ResumeCommandUnified(sessionID, prompt string, opts *backend.UnifiedOptions) *exec.Cmd {
+	return b.buildCommand(&BuildCommandParams{Prompt: prompt, SessionID: sessionID, Options: optionsFromUnified(opts)})
+}
+
+// buildCommand asks the plugin for a command. On failure the returned command
+// carries the error so that starting it fails instead of running something else.
+func (b *Backend) buildCommand(params *BuildCommandParams) *exec.Cmd {
+	var spec CommandSpec
+	err := b.client.Call(context.Background(), MethodBuildCommand, params, &spec)
+	if err == nil && spec.Path == "" {
+		err = errors.New("plugin returned an empty command path")
+	}
+	if err != nil {
+		return &exec.Cmd{Path: b.path, Args: []string{b.path}, Err: err}
+	}
+
+	cmd := exec.Command(spec.Path, spec.Args...)
+	cmd.Dir = spec.Dir
+	if len(spec.Env) > 0 {
+		cmd.Env = append(cmd.Environ(), spec.Env...)
+	}
+	return cmd
+}
+
+// ParseOutput extracts the response text from raw output.
+func (b *Backend) ParseOutput(rawOutput string) string {
+	var text string
+	if err := b.client.Call(context.Background(), MethodParseOutput, &RawParams{Raw: rawOutput}, &text); err != nil {
+		return rawOutput
+	}
+	return text
+}
+
+// ParseJSONResponse asks the plugin to extract a unified response.
+func (b *Backend) ParseJSONResponse(rawOutput string) (*backend.UnifiedResponse, error) {
+	var resp backend.UnifiedResponse
+	if err := b.client.Call(context.Background(), MethodParseJSONResponse, &RawParams{Raw: rawOutput}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DecodeStreamLine asks the plugin to convert one line of streamed output into a unified event.
+func (b *Backend) DecodeStreamLine(line string) (output.EventType, any, error) {
+	var result *LineResult
+	if err := b.client.Call(context.Background(), MethodParseLine, &LineParams{Line: line}, &result); err != nil {
+		return "", nil, err
+	}
+	if result == nil || result.Type == "" {
+		return "", nil, nil
+	}
+	var content any
+	if len(result.Content) > 0 {
+		content = result.Content
+	}
+	return output.EventType(result.Type), content, nil
+}
+
+// SeparateStderr returns whether stderr is captured separately, as described by the plugin.
+func (b *Backend) SeparateStderr() bool {
+	return b.Describe().SeparateStderr
+}
+
+// Capabilities returns the capabilities reported by Describe. Plugins receive
+// every unified option, so those that report none are assumed to handle all.
+func (b *Backend) Capabilities() backend.Capabilities {
+	if caps := b.Describe().Capabilities; caps != nil {
+		return *caps
+	}
+	return backend.Capabilities{
+		Resume:        true,
+		Streaming:     true,
+		SystemPrompt:  true,
+		MaxTokens:     true,
+		MaxTurns:      true,
+		ApprovalModes: backend.AllApprovalModes,
+		SandboxModes:  backend.AllSandboxModes,
+		ToolAllowlist: true,
+	}
+}
+
+// isMethodNotFound reports whether err means the plugin does not implement a method.
+func isMethodNotFound(err error) bool {
+	var rpcErr *RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == CodeMethodNotFound
+}
@@ -0,0 +1,224 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCallTimeout bounds a single RPC call.
+const DefaultCallTimeout = 30 * time.Second
+
+// maxMessageSize bounds a single JSON-RPC message.
+const maxMessageSize = 10 * 1024 * 1024
+
+// ErrPluginExited is returned for calls pending when the plugin process exits.
+var ErrPluginExited = errors.New("plugin process exited")
+
+// Client is a JSON-RPC client for a plugin process.
+// The process is started on the first call and restarted if it exits.
+// Client is safe for concurrent use.
+type Client struct {
+	path    string
+	args    []string
+	timeout time.Duration
+	nextID  atomic.Int64
+
+	mu   sync.Mutex
+	proc *process
+}
+
+// process is a running plugin instance.
+type process struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[int64]chan *Response
+	done    chan struct{}
+	err     error
+}
+
+// NewClient creates a client for the plugin executable at path.
+func NewClient(path string, args ...string) *Client {
+	return &Client{
+		path:    path,
+		args:    args,
+		timeout: DefaultCallTimeout,
+	}
+}
+
+// SetTimeout sets the per-call timeout.
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// Call invokes method with params and decodes the result into result (if non-nil).
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	p, err := c.process()
+	if err != nil {
+		return err
+	}
+
+	var rawParams json.RawMessage
+	if params != nil {
+		if rawParams, err = json.Marshal(params); err != nil {
+			return fmt.Errorf("failed to encode %s params: %w", method, err)
+		}
+	}
+
+	id := c.nextID.Add(1)
+	respCh := make(chan *Response, 1)
+
+	p.mu.Lock()
+	if p.pending == nil {
+		p.mu.Unlock()
+		return p.err
+	}
+	p.pending[id] = respCh
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		if p.pending != nil {
+			delete(p.pending, id)
+		}
+		p.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(&Request{JSONRPC: "2.0", ID: id, Method: method, Params: rawParams})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+
+	p.writeMu.Lock()
+	_, err = p.stdin.Write(append(data, '\n'))
+	p.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	select {
+	case resp := <-respCh:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("failed to decode %s result: %w", method, err)
+			}
+		}
+		return nil
+	case <-p.done:
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return fmt.Errorf("plugin call %s timed out after %s", method, c.timeout)
+	}
+}
+
+// Close stops the plugin process if it is running.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	p := c.proc
+	c.proc = nil
+	c.mu.Unlock()
+
+	if p == nil {
+		return nil
+	}
+	_ = p.stdin.Close()
+
+	select {
+	case <-p.done:
+	case <-time.After(2 * time.Second):
+		if p.cmd.Process != nil {
+			_ = p.cmd.Process.Kill()
+		}
+		<-p.done
+	}
+	return nil
+}
+
+// process returns the running plugin process, starting it if needed.
+func (c *Client) process() (*process, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.proc != nil {
+		select {
+		case <-c.proc.done:
+			// Exited; start a fresh instance below.
+		default:
+			return c.proc, nil
+		}
+	}
+
+	cmd := exec.Command(c.path, c.args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", c.path, err)
+	}
+
+	p := &process{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan *Response),
+		done:    make(chan struct{}),
+	}
+	go p.readLoop(stdout)
+
+	c.proc = p
+	return p, nil
+}
+
+// readLoop dispatches responses until stdout closes, then fails pending calls.
+func (p *process) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+
+	for scanner.Scan() {
+		var resp Response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			continue // Ignore non-protocol output
+		}
+
+		p.mu.Lock()
+		ch, ok := p.pending[resp.ID]
+		p.mu.Unlock()
+		if ok {
+			ch <- &resp
+		}
+	}
+
+	err := ErrPluginExited
+	if scanErr := scanner.Err(); scanErr != nil {
+		err = fmt.Errorf("%w: %v", ErrPluginExited, scanErr)
+	}
+	_ = p.cmd.Wait()
+
+	p.mu.Lock()
+	p.err = err
+	p.pending = nil
+	p.mu.Unlock()
+	close(p.done)
+}
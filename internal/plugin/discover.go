@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Plugin is a plugin executable found on disk.
+type Plugin struct {
+	// Name is the backend name, derived from the file name without extension.
+	Name string
+
+	// Path is the absolute path to the executable.
+	Path string
+}
+
+// Discover returns the plugin executables in dir, sorted by name.
+// A missing directory yields no plugins. Hidden files, directories and
+// non-executable files are skipped.
+func Discover(dir string) ([]Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var plugins []Plugin
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path) // Follow symlinks
+		if err != nil || !info.Mode().IsRegular() || !isExecutable(name, info) {
+			continue
+		}
+
+		backendName := strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+		if backendName == "" {
+			continue
+		}
+
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		plugins = append(plugins, Plugin{Name: backendName, Path: abs})
+	}
+
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Name < plugins[j].Name
+	})
+	return plugins, nil
+}
+
+// isExecutable reports whether a file can be run as a plugin.
+func isExecutable(name string, info fs.FileInfo) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(name), ".exe")
+	}
+	return info.Mode().Perm()&0o111 != 0
+}
@@ -0,0 +1,309 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/output"
+)
+
+const testPluginEnv = "CLINVK_TEST_PLUGIN"
+
+// TestMain lets the test binary act as a plugin when re-executed.
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) == "1" {
+		if err := Serve(os.Stdin, os.Stdout, testHandlers()); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func testHandlers() map[string]HandlerFunc {
+	return map[string]HandlerFunc{
+		MethodDescribe: func(json.RawMessage) (any, error) {
+			return &DescribeResult{SeparateStderr: true}, nil
+		},
+		MethodBuildCommand: func(params json.RawMessage) (any, error) {
+			var p BuildCommandParams
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, &RPCError{Code: CodeInvalidParams, Message: err.Error()}
+			}
+			if p.Prompt == "fail" {
+				return nil, errors.New("cannot build")
+			}
+			args := []string{p.Prompt}
+			if p.SessionID != "" {
+				args = append(args, "resume="+p.SessionID)
+			}
+			dir := ""
+			if p.Options != nil {
+				dir = p.Options.WorkDir
+				if p.Options.Model != "" {
+					args = append(args, "model="+p.Options.Model)
+				}
+			}
+			return &CommandSpec{Path: "echo", Args: args, Dir: dir, Env: []string{"PLUGIN_TEST=1"}}, nil
+		},
+		MethodParseJSONResponse: func(params json.RawMessage) (any, error) {
+			var p RawParams
+			_ = json.Unmarshal(params, &p)
+			content, session, _ := strings.Cut(strings.TrimSpace(p.Raw), "|")
+			return &backend.UnifiedResponse{Content: content, SessionID: session}, nil
+		},
+		MethodParseLine: func(params json.RawMessage) (any, error) {
+			var p LineParams
+			_ = json.Unmarshal(params, &p)
+			if p.Line == "skip" {
+				return nil, nil
+			}
+			return &LineResult{Type: "message", Content: json.RawMessage(`{"text":"` + p.Line + `","role":"assistant"}`)}, nil
+		},
+		"Crash": func(json.RawMessage) (any, error) {
+			os.Exit(3)
+			return nil, nil
+		},
+		"Sleep": func(json.RawMessage) (any, error) {
+			time.Sleep(time.Second)
+			return true, nil
+		},
+	}
+}
+
+func newTestBackend(t *testing.T) *Backend {
+	t.Helper()
+	t.Setenv(testPluginEnv, "1")
+	b := NewBackend("test-plugin", os.Args[0])
+	t.Cleanup(func() { _ = b.Client().Close() })
+	return b
+}
+
+func TestBackend(t *testing.T) {
+	b := newTestBackend(t)
+
+	t.Run("name", func(t *testing.T) {
+		if b.Name() != "test-plugin" {
+			t.Errorf("expected 'test-plugin', got %q", b.Name())
+		}
+	})
+
+	t.Run("IsAvailable defaults to true when not implemented", func(t *testing.T) {
+		if !b.IsAvailable() {
+			t.Error("expected plugin to be available")
+		}
+	})
+
+	t.Run("SeparateStderr comes from Describe", func(t *testing.T) {
+		if !b.SeparateStderr() {
+			t.Error("expected SeparateStderr to be true")
+		}
+	})
+
+	t.Run("Capabilities default to plugin-handled", func(t *testing.T) {
+		caps := b.Capabilities()
+		if !caps.Resume || !caps.SystemPrompt || !caps.SupportsSandboxMode(backend.SandboxFull) {
+			t.Errorf("expected permissive capabilities, got %+v", caps)
+		}
+	})
+
+	t.Run("BuildCommandUnified", func(t *testing.T) {
+		cmd := b.BuildCommandUnified("hello", &backend.UnifiedOptions{WorkDir: "/tmp", Model: "m1"})
+		if cmd.Err != nil {
+			t.Fatalf("unexpected command error: %v", cmd.Err)
+		}
+		if got := strings.Join(cmd.Args, " "); got != "echo hello model=m1" {
+			t.Errorf("unexpected args: %q", got)
+		}
+		if cmd.Dir != "/tmp" {
+			t.Errorf("expected dir '/tmp', got %q", cmd.Dir)
+		}
+		if len(cmd.Env) == 0 || cmd.Env[len(cmd.Env)-1] != "PLUGIN_TEST=1" {
+			t.Error("expected plugin env to be appended")
+		}
+	})
+
+	t.Run("ResumeCommand passes session ID", func(t *testing.T) {
+		cmd := b.ResumeCommand("s-1", "again", nil)
+		if got := strings.Join(cmd.Args, " "); got != "echo again resume=s-1" {
+			t.Errorf("unexpected args: %q", got)
+		}
+	})
+
+	t.Run("BuildCommand failure yields failing command", func(t *testing.T) {
+		cmd := b.BuildCommand("fail", nil)
+		if cmd.Err == nil {
+			t.Fatal("expected command error")
+		}
+		if err := cmd.Start(); err == nil || !strings.Contains(err.Error(), "cannot build") {
+			t.Errorf("expected start to fail with plugin error, got %v", err)
+		}
+	})
+
+	t.Run("ParseJSONResponse", func(t *testing.T) {
+		resp, err := b.ParseJSONResponse("answer|sess-9\n")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Content != "answer" || resp.SessionID != "sess-9" {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("ParseOutput falls back to raw output", func(t *testing.T) {
+		if got := b.ParseOutput("raw"); got != "raw" {
+			t.Errorf("ParseOutput() = %q, want 'raw'", got)
+		}
+	})
+
+	t.Run("DecodeStreamLine via output parser", func(t *testing.T) {
+		output.RegisterLineDecoder(b.Name(), b.DecodeStreamLine)
+		t.Cleanup(func() { output.UnregisterLineDecoder(b.Name()) })
+
+		p := output.NewParser(b.Name(), "sess")
+		event, err := p.ParseLine("hi there")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		content, err := event.GetMessageContent()
+		if err != nil || content.Text != "hi there" {
+			t.Errorf("unexpected content: %+v, %v", content, err)
+		}
+
+		event, err = p.ParseLine("skip")
+		if err != nil || event != nil {
+			t.Errorf("expected skipped line, got %+v, %v", event, err)
+		}
+	})
+}
+
+func TestClient(t *testing.T) {
+	t.Setenv(testPluginEnv, "1")
+
+	t.Run("unknown method returns method not found", func(t *testing.T) {
+		c := NewClient(os.Args[0])
+		defer c.Close()
+
+		err := c.Call(context.Background(), "Nope", nil, nil)
+		if !isMethodNotFound(err) {
+			t.Errorf("expected method not found, got %v", err)
+		}
+	})
+
+	t.Run("restarts after plugin exits", func(t *testing.T) {
+		c := NewClient(os.Args[0])
+		defer c.Close()
+
+		if err := c.Call(context.Background(), "Crash", nil, nil); !errors.Is(err, ErrPluginExited) {
+			t.Fatalf("expected ErrPluginExited, got %v", err)
+		}
+
+		var result DescribeResult
+		if err := c.Call(context.Background(), MethodDescribe, nil, &result); err != nil {
+			t.Fatalf("expected call to succeed after restart: %v", err)
+		}
+		if !result.SeparateStderr {
+			t.Error("expected describe result after restart")
+		}
+	})
+
+	t.Run("call timeout", func(t *testing.T) {
+		c := NewClient(os.Args[0])
+		c.SetTimeout(50 * time.Millisecond)
+		defer c.Close()
+
+		err := c.Call(context.Background(), "Sleep", nil, nil)
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("expected timeout error, got %v", err)
+		}
+	})
+
+	t.Run("missing executable", func(t *testing.T) {
+		c := NewClient(filepath.Join(t.TempDir(), "missing"))
+		if err := c.Call(context.Background(), MethodDescribe, nil, nil); err == nil {
+			t.Error("expected error for missing executable")
+		}
+	})
+}
+
+func TestDiscover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executable bits are not used on windows")
+	}
+
+	t.Run("missing directory", func(t *testing.T) {
+		plugins, err := Discover(filepath.Join(t.TempDir(), "none"))
+		if err != nil || plugins != nil {
+			t.Errorf("expected no plugins and no error, got %v, %v", plugins, err)
+		}
+	})
+
+	t.Run("finds executables", func(t *testing.T) {
+		dir := t.TempDir()
+		files := map[string]os.FileMode{
+			"Zeta.sh":   0o755,
+			"alpha":     0o755,
+			"notes.txt": 0o644,
+			".hidden":   0o755,
+		}
+		for name, mode := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), mode); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.Mkdir(filepath.Join(dir, "subdir"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		plugins, err := Discover(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(plugins) != 2 {
+			t.Fatalf("expected 2 plugins, got %+v", plugins)
+		}
+		if plugins[0].Name != "alpha" || plugins[1].Name != "zeta" {
+			t.Errorf("unexpected plugin names: %+v", plugins)
+		}
+		if !filepath.IsAbs(plugins[0].Path) {
+			t.Errorf("expected absolute path, got %q", plugins[0].Path)
+		}
+	})
+}
+
+func TestServe(t *testing.T) {
+	in := strings.NewReader("not json\n" + `{"jsonrpc":"2.0","id":7,"method":"Echo","params":"x"}` + "\n")
+	var out strings.Builder
+
+	err := Serve(in, &out, map[string]HandlerFunc{
+		"Echo": func(params json.RawMessage) (any, error) {
+			return params, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 responses, got %q", out.String())
+	}
+
+	var parseErr Response
+	if err := json.Unmarshal([]byte(lines[0]), &parseErr); err != nil || parseErr.Error == nil || parseErr.Error.Code != CodeParseError {
+		t.Errorf("expected parse error response, got %s", lines[0])
+	}
+
+	var echo Response
+	if err := json.Unmarshal([]byte(lines[1]), &echo); err != nil || echo.ID != 7 || string(echo.Result) != `"x"` {
+		t.Errorf("unexpected echo response: %s", lines[1])
+	}
+}
@@ -0,0 +1,173 @@
+// Package plugin implements external backends that talk JSON-RPC 2.0 over stdio.
+//
+// A plugin is an executable placed in ~/.clinvk/plugins/. clinvk starts it on
+// first use and exchanges newline-delimited JSON-RPC messages on its stdin and
+// stdout. The plugin exits when its stdin is closed. Methods mirror the
+// backend.Backend interface:
+//
+//	Describe           -> DescribeResult                (optional)
+//	IsAvailable        -> bool                          (optional, default true)
+//	BuildCommand       BuildCommandParams -> CommandSpec
+//	ParseOutput        RawParams -> string              (optional, default raw)
+//	ParseJSONResponse  RawParams -> backend.UnifiedResponse
+//	ParseLine          LineParams -> LineResult | null
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+)
+
+// ProtocolVersion is the plugin protocol version sent in Describe.
+const ProtocolVersion = 1
+
+// Method names.
+const (
+	MethodDescribe          = "Describe"
+	MethodIsAvailable       = "IsAvailable"
+	MethodBuildCommand      = "BuildCommand"
+	MethodParseOutput       = "ParseOutput"
+	MethodParseJSONResponse = "ParseJSONResponse"
+	MethodParseLine         = "ParseLine"
+)
+
+// Standard JSON-RPC error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Request is a JSON-RPC request.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is a JSON-RPC error object.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("plugin error %d: %s", e.Code, e.Message)
+}
+
+// DescribeParams is sent with Describe.
+type DescribeParams struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Name            string `json:"name"`
+}
+
+// DescribeResult describes plugin behavior that does not change per call.
+type DescribeResult struct {
+	// SeparateStderr keeps the backend's stderr out of the parsed response.
+	SeparateStderr bool `json:"separate_stderr,omitempty"`
+
+	// Capabilities lists the unified options the plugin honors.
+	// When omitted, every option is assumed to be handled by the plugin.
+	Capabilities *backend.Capabilities `json:"capabilities,omitempty"`
+}
+
+// CommandOptions is the wire form of backend.UnifiedOptions.
+type CommandOptions struct {
+	WorkDir      string   `json:"work_dir,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	ApprovalMode string   `json:"approval_mode,omitempty"`
+	SandboxMode  string   `json:"sandbox_mode,omitempty"`
+	OutputFormat string   `json:"output_format,omitempty"`
+	AllowedTools string   `json:"allowed_tools,omitempty"`
+	AllowedDirs  []string `json:"allowed_dirs,omitempty"`
+	Verbose      bool     `json:"verbose,omitempty"`
+	DryRun       bool     `json:"dry_run,omitempty"`
+	MaxTokens    int      `json:"max_tokens,omitempty"`
+	MaxTurns     int      `json:"max_turns,omitempty"`
+	SystemPrompt string   `json:"system_prompt,omitempty"`
+	ExtraFlags   []string `json:"extra_flags,omitempty"`
+	Ephemeral    bool     `json:"ephemeral,omitempty"`
+}
+
+// BuildCommandParams is sent with BuildCommand.
+// SessionID is set when resuming a session.
+type BuildCommandParams struct {
+	Prompt    string          `json:"prompt"`
+	SessionID string          `json:"session_id,omitempty"`
+	Options   *CommandOptions `json:"options,omitempty"`
+}
+
+// CommandSpec describes the process clinvk should run.
+type CommandSpec struct {
+	Path string   `json:"path"`
+	Args []string `json:"args,omitempty"`
+	Dir  string   `json:"dir,omitempty"`
+	Env  []string `json:"env,omitempty"`
+}
+
+// RawParams carries raw backend output.
+type RawParams struct {
+	Raw string `json:"raw"`
+}
+
+// LineParams carries one line of streamed backend output.
+type LineParams struct {
+	Line string `json:"line"`
+}
+
+// LineResult is a unified event decoded from one line of output.
+type LineResult struct {
+	Type    string          `json:"type"`
+	Content json.RawMessage `json:"content,omitempty"`
+}
+
+// optionsFromUnified converts unified options to their wire form.
+func optionsFromUnified(opts *backend.UnifiedOptions) *CommandOptions {
+	if opts == nil {
+		return nil
+	}
+	return &CommandOptions{
+		WorkDir:      opts.WorkDir,
+		Model:        opts.Model,
+		ApprovalMode: string(opts.ApprovalMode),
+		SandboxMode:  string(opts.SandboxMode),
+		OutputFormat: string(opts.OutputFormat),
+		AllowedTools: opts.AllowedTools,
+		AllowedDirs:  opts.AllowedDirs,
+		Verbose:      opts.Verbose,
+		DryRun:       opts.DryRun,
+		MaxTokens:    opts.MaxTokens,
+		MaxTurns:     opts.MaxTurns,
+		SystemPrompt: opts.SystemPrompt,
+		ExtraFlags:   opts.ExtraFlags,
+		Ephemeral:    opts.Ephemeral,
+	}
+}
+
+// optionsFromBackend converts backend-specific options to their wire form.
+func optionsFromBackend(opts *backend.Options) *CommandOptions {
+	if opts == nil {
+		return nil
+	}
+	return &CommandOptions{
+		WorkDir:      opts.WorkDir,
+		Model:        opts.Model,
+		AllowedTools: opts.AllowedTools,
+		AllowedDirs:  opts.AllowedDirs,
+		ExtraFlags:   opts.ExtraFlags,
+	}
+}
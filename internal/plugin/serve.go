@@ -0,0 +1,61 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// HandlerFunc handles one method call and returns its result.
+// Returning an *RPCError controls the error code sent to clinvk.
+type HandlerFunc func(params json.RawMessage) (any, error)
+
+// Serve runs the plugin side of the protocol, reading requests from r and
+// writing responses to w until r is exhausted. It lets Go plugins be written
+// as a map of method handlers.
+func Serve(r io.Reader, w io.Writer, handlers map[string]HandlerFunc) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	enc := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		var req Request
+		resp := Response{JSONRPC: "2.0"}
+
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = &RPCError{Code: CodeParseError, Message: err.Error()}
+		} else {
+			resp.ID = req.ID
+			resp.Result, resp.Error = dispatch(handlers, &req)
+		}
+
+		if err := enc.Encode(&resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// dispatch invokes the handler for req and encodes its result.
+func dispatch(handlers map[string]HandlerFunc, req *Request) (json.RawMessage, *RPCError) {
+	handler, ok := handlers[req.Method]
+	if !ok {
+		return nil, &RPCError{Code: CodeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+
+	result, err := handler(req.Params)
+	if err != nil {
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) {
+			return nil, rpcErr
+		}
+		return nil, &RPCError{Code: CodeInternalError, Message: err.Error()}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, &RPCError{Code: CodeInternalError, Message: err.Error()}
+	}
+	return data, nil
+}
@@ -0,0 +1,243 @@
+// Package pool balances requests across the member backends of a virtual
+// backend pool.
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Strategy selects how a pool picks a member for each request.
+type Strategy string
+
+const (
+	// StrategyRoundRobin cycles through the members in order.
+	StrategyRoundRobin Strategy = "round_robin"
+	// StrategyLeastBusy picks the member with the fewest requests in flight.
+	StrategyLeastBusy Strategy = "least_busy"
+	// StrategyWeighted spreads requests in proportion to member weights.
+	StrategyWeighted Strategy = "weighted"
+)
+
+// Strategies lists the valid strategies.
+var Strategies = []Strategy{StrategyRoundRobin, StrategyLeastBusy, StrategyWeighted}
+
+// ErrNoMember is returned when no member of a pool can take requests.
+var ErrNoMember = errors.New("no pool member is available")
+
+// Member declares a backend of a pool.
+type Member struct {
+	// Backend is the name of the member backend.
+	Backend string
+	// Weight is the member's share of requests with StrategyWeighted.
+	// Default: 1
+	Weight int
+	// MaxConcurrency caps the member's requests in flight (0 = unlimited).
+	MaxConcurrency int
+}
+
+type member struct {
+	Member
+	inFlight      int
+	currentWeight int
+}
+
+func (m *member) full() bool {
+	return m.MaxConcurrency > 0 && m.inFlight >= m.MaxConcurrency
+}
+
+// Pool spreads requests across its members. It is safe for concurrent use.
+type Pool struct {
+	name     string
+	strategy Strategy
+
+	mu      sync.Mutex
+	members []*member
+	next    int
+	// freed is closed and replaced whenever a lease is released.
+	freed chan struct{}
+}
+
+// New creates a pool. An empty strategy selects StrategyRoundRobin.
+func New(name string, strategy Strategy, members []Member) *Pool {
+	if strategy == "" {
+		strategy = StrategyRoundRobin
+	}
+	p := &Pool{
+		name:     name,
+		strategy: strategy,
+		members:  make([]*member, len(members)),
+		freed:    make(chan struct{}),
+	}
+	for i, m := range members {
+		if m.Weight <= 0 {
+			m.Weight = 1
+		}
+		p.members[i] = &member{Member: m}
+	}
+	return p
+}
+
+// Name returns the pool name.
+func (p *Pool) Name() string { return p.name }
+
+// Strategy returns the pool's balancing strategy.
+func (p *Pool) Strategy() Strategy { return p.strategy }
+
+// Acquire picks a member for one request and counts the request in flight
+// until the lease is released. Members for which eligible returns false are
+// skipped; a nil eligible accepts all members. When every eligible member is
+// at its concurrency limit, Acquire waits for a release or for ctx to end.
+// It returns ErrNoMember when no member is eligible.
+func (p *Pool) Acquire(ctx context.Context, eligible func(backend string) bool) (*Lease, error) {
+	for {
+		p.mu.Lock()
+		m, anyEligible := p.pick(eligible)
+		if m != nil {
+			m.inFlight++
+			p.mu.Unlock()
+			return &Lease{pool: p, member: m}, nil
+		}
+		freed := p.freed
+		p.mu.Unlock()
+
+		if !anyEligible {
+			return nil, fmt.Errorf("pool %q: %w", p.name, ErrNoMember)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-freed:
+		}
+	}
+}
+
+// pick selects a member with spare capacity. It also reports whether any
+// member is eligible at all. The caller must hold p.mu.
+func (p *Pool) pick(eligible func(string) bool) (*member, bool) {
+	var candidates []int
+	anyEligible := false
+	for i, m := range p.members {
+		if eligible != nil && !eligible(m.Backend) {
+			continue
+		}
+		anyEligible = true
+		if !m.full() {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, anyEligible
+	}
+
+	switch p.strategy {
+	case StrategyLeastBusy:
+		// Ties go to the member after the last one picked.
+		best := -1
+		for _, i := range p.rotate(candidates) {
+			if best < 0 || p.members[i].inFlight < p.members[best].inFlight {
+				best = i
+			}
+		}
+		p.next = best + 1
+		return p.members[best], true
+	case StrategyWeighted:
+		// Smooth weighted round-robin: every candidate gains its weight,
+		// the richest is picked and pays the total.
+		total := 0
+		best := -1
+		for _, i := range candidates {
+			m := p.members[i]
+			m.currentWeight += m.Weight
+			total += m.Weight
+			if best < 0 || m.currentWeight > p.members[best].currentWeight {
+				best = i
+			}
+		}
+		p.members[best].currentWeight -= total
+		return p.members[best], true
+	default:
+		i := p.rotate(candidates)[0]
+		p.next = i + 1
+		return p.members[i], true
+	}
+}
+
+// rotate orders candidates starting at the first index at or after p.next.
+func (p *Pool) rotate(candidates []int) []int {
+	for k, i := range candidates {
+		if i >= p.next {
+			return append(candidates[k:len(candidates):len(candidates)], candidates[:k]...)
+		}
+	}
+	return candidates
+}
+
+func (p *Pool) release(m *member) {
+	p.mu.Lock()
+	m.inFlight--
+	close(p.freed)
+	p.freed = make(chan struct{})
+	p.mu.Unlock()
+}
+
+// Lease is a request's claim on a pool member.
+type Lease struct {
+	pool   *Pool
+	member *member
+	once   sync.Once
+}
+
+// Backend returns the name of the leased member backend.
+func (l *Lease) Backend() string {
+	return l.member.Backend
+}
+
+// Release ends the request. It is safe to call more than once and on a nil
+// lease.
+func (l *Lease) Release() {
+	if l == nil {
+		return
+	}
+	l.once.Do(func() { l.pool.release(l.member) })
+}
+
+// MemberStats describes a pool member.
+type MemberStats struct {
+	Backend        string
+	Weight         int
+	MaxConcurrency int
+	InFlight       int
+}
+
+// Stats describes a pool and its members.
+type Stats struct {
+	Name     string
+	Strategy Strategy
+	InFlight int
+	Members  []MemberStats
+}
+
+// Stats returns the pool's current state.
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := Stats{
+		Name:     p.name,
+		Strategy: p.strategy,
+		Members:  make([]MemberStats, len(p.members)),
+	}
+	for i, m := range p.members {
+		stats.Members[i] = MemberStats{
+			Backend:        m.Backend,
+			Weight:         m.Weight,
+			MaxConcurrency: m.MaxConcurrency,
+			InFlight:       m.inFlight,
+		}
+		stats.InFlight += m.inFlight
+	}
+	return stats
+}
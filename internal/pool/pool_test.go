@@ -0,0 +1,118 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func acquireAll(t *testing.T, p *Pool, n int) []string {
+	t.Helper()
+	var picked []string
+	for i := 0; i < n; i++ {
+		lease, err := p.Acquire(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Acquire() error: %v", err)
+		}
+		picked = append(picked, lease.Backend())
+		lease.Release()
+	}
+	return picked
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPool_RoundRobin(t *testing.T) {
+	p := New("rr", "", []Member{{Backend: "a"}, {Backend: "b"}, {Backend: "c"}})
+
+	want := []string{"a", "b", "c", "a", "b"}
+	if got := acquireAll(t, p, 5); !equal(got, want) {
+		t.Errorf("picked %v, want %v", got, want)
+	}
+}
+
+func TestPool_Weighted(t *testing.T) {
+	p := New("w", StrategyWeighted, []Member{{Backend: "a", Weight: 3}, {Backend: "b", Weight: 1}})
+
+	counts := map[string]int{}
+	for _, name := range acquireAll(t, p, 8) {
+		counts[name]++
+	}
+	if counts["a"] != 6 || counts["b"] != 2 {
+		t.Errorf("expected a 3:1 split, got %v", counts)
+	}
+}
+
+func TestPool_LeastBusy(t *testing.T) {
+	p := New("lb", StrategyLeastBusy, []Member{{Backend: "a"}, {Backend: "b"}})
+
+	first, _ := p.Acquire(context.Background(), nil)
+	second, _ := p.Acquire(context.Background(), nil)
+	if first.Backend() == second.Backend() {
+		t.Fatalf("expected the idle member, got %s twice", first.Backend())
+	}
+
+	second.Release()
+	third, _ := p.Acquire(context.Background(), nil)
+	if third.Backend() != second.Backend() {
+		t.Errorf("expected the member freed by the release (%s), got %s", second.Backend(), third.Backend())
+	}
+}
+
+func TestPool_MaxConcurrency(t *testing.T) {
+	p := New("mc", "", []Member{{Backend: "a", MaxConcurrency: 1}})
+
+	held, err := p.Acquire(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.Acquire(ctx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected to wait for a full member, got %v", err)
+	}
+
+	done := make(chan *Lease)
+	go func() {
+		lease, _ := p.Acquire(context.Background(), nil)
+		done <- lease
+	}()
+	held.Release()
+	held.Release() // releasing twice is harmless
+
+	select {
+	case lease := <-done:
+		if stats := p.Stats(); stats.InFlight != 1 || stats.Members[0].InFlight != 1 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+		lease.Release()
+	case <-time.After(time.Second):
+		t.Fatal("expected a waiting Acquire to get the released member")
+	}
+}
+
+func TestPool_Eligible(t *testing.T) {
+	p := New("el", "", []Member{{Backend: "a"}, {Backend: "b"}})
+
+	lease, err := p.Acquire(context.Background(), func(name string) bool { return name == "b" })
+	if err != nil || lease.Backend() != "b" {
+		t.Fatalf("expected member b, got %v, %v", lease, err)
+	}
+	lease.Release()
+
+	if _, err := p.Acquire(context.Background(), func(string) bool { return false }); !errors.Is(err, ErrNoMember) {
+		t.Errorf("expected ErrNoMember, got %v", err)
+	}
+}
@@ -1,4 +1,5 @@
-// Package resilience provides fault tolerance patterns like circuit breakers.
+// Package resilience provides fault tolerance patterns like circuit breakers,
+// retry policies and concurrency limits.
 package resilience
 
 import (
@@ -212,6 +213,18 @@ func (cb *CircuitBreaker) RecordFailure() {
 	}
 }
 
+// RecordCanceled records a call that ended without an outcome, such as one
+// canceled by its caller. It frees the call's half-open slot without counting
+// a success or a failure.
+func (cb *CircuitBreaker) RecordCanceled() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == StateHalfOpen && cb.halfOpenCallCount > 0 {
+		cb.halfOpenCallCount--
+	}
+}
+
 // transitionTo changes the circuit breaker state.
 // Must be called with lock held.
 func (cb *CircuitBreaker) transitionTo(newState CircuitState) {
@@ -267,6 +280,9 @@ func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
+	if cb.onStateChange != nil && cb.state != StateClosed {
+		go cb.onStateChange(cb.name, cb.state, StateClosed)
+	}
 	cb.state = StateClosed
 	cb.failureCount = 0
 	cb.successCount = 0
@@ -345,6 +361,20 @@ func (r *CircuitBreakerRegistry) AllStats() map[string]Stats {
 	return result
 }
 
+// Reset resets the circuit breaker for the given name.
+// Returns false if no circuit breaker exists for it.
+func (r *CircuitBreakerRegistry) Reset(name string) bool {
+	r.mu.RLock()
+	cb, ok := r.breakers[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return false
+	}
+	cb.Reset()
+	return true
+}
+
 // ResetAll resets all circuit breakers.
 func (r *CircuitBreakerRegistry) ResetAll() {
 	r.mu.RLock()
@@ -419,3 +419,68 @@ func TestCircuitBreaker_OnStateChange(t *testing.T) {
 	}
 	mu.Unlock()
 }
+
+func TestCircuitBreaker_RecordCanceled(t *testing.T) {
+	cfg := DefaultConfig("test")
+	cfg.FailureThreshold = 1
+	cfg.Timeout = 10 * time.Millisecond
+	cb := NewCircuitBreaker(cfg)
+
+	cb.Allow()
+	cb.RecordFailure()
+	time.Sleep(20 * time.Millisecond)
+
+	// Trial call is canceled; its slot must be freed for the next one.
+	if !cb.Allow() {
+		t.Fatal("expected trial call in half-open state")
+	}
+	cb.RecordCanceled()
+
+	if cb.State() != StateHalfOpen {
+		t.Errorf("expected state %v, got %v", StateHalfOpen, cb.State())
+	}
+	if !cb.Allow() {
+		t.Error("expected canceled call to free its half-open slot")
+	}
+	stats := cb.Stats()
+	if stats.TotalFailures != 1 || stats.TotalSuccesses != 0 {
+		t.Errorf("expected canceled call not to be counted, got %+v", stats)
+	}
+}
+
+func TestCircuitBreakerRegistry_Reset(t *testing.T) {
+	var mu sync.Mutex
+	var changes []CircuitState
+
+	cfg := DefaultConfig("")
+	cfg.FailureThreshold = 1
+	cfg.OnStateChange = func(_ string, _, to CircuitState) {
+		mu.Lock()
+		changes = append(changes, to)
+		mu.Unlock()
+	}
+	registry := NewCircuitBreakerRegistry(cfg)
+
+	if registry.Reset("missing") {
+		t.Error("expected Reset to report unknown names")
+	}
+
+	cb := registry.Get("backend1")
+	cb.Allow()
+	cb.RecordFailure()
+
+	if !registry.Reset("backend1") {
+		t.Fatal("expected Reset to find backend1")
+	}
+	if cb.State() != StateClosed {
+		t.Errorf("expected state %v after reset, got %v", StateClosed, cb.State())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	// Callbacks run in their own goroutines, so their order is not fixed.
+	if len(changes) != 2 || (changes[0] != StateClosed && changes[1] != StateClosed) {
+		t.Errorf("expected reset to report a change to closed, got %v", changes)
+	}
+}
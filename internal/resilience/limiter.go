@@ -0,0 +1,222 @@
+package resilience
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Errors returned by the concurrency limiter.
+var (
+	ErrQueueFull    = errors.New("concurrency queue is full")
+	ErrQueueTimeout = errors.New("timed out waiting for a concurrency slot")
+)
+
+// LimiterConfig contains configuration for a concurrency limiter.
+type LimiterConfig struct {
+	// MaxInFlight caps the calls in flight across all keys (0 = unlimited).
+	MaxInFlight int
+
+	// MaxPerKey returns the cap on calls in flight for a key (0 = unlimited).
+	// A nil MaxPerKey leaves every key unlimited.
+	MaxPerKey func(key string) int
+
+	// QueueSize caps the calls waiting for a slot. Calls beyond it fail
+	// with ErrQueueFull; 0 fails them instead of waiting.
+	QueueSize int
+
+	// QueueTimeout bounds the wait for a slot; calls still waiting fail with
+	// ErrQueueTimeout. 0 waits until the call's context ends.
+	QueueTimeout time.Duration
+
+	// OnQueueChange is called, without the limiter's lock held, with a key
+	// and the number of calls now waiting for it.
+	OnQueueChange func(key string, waiting int)
+}
+
+// Limiter caps concurrent calls overall and per key. Calls over a cap wait in
+// a bounded FIFO queue; a freed slot goes to the longest waiting call it can
+// serve. It is safe for concurrent use.
+type Limiter struct {
+	cfg LimiterConfig
+
+	mu       sync.Mutex
+	inFlight int
+	perKey   map[string]int
+	queue    *list.List // of *limiterWaiter, oldest first
+	waiting  map[string]int
+}
+
+type limiterWaiter struct {
+	key     string
+	ready   chan struct{}
+	granted bool
+}
+
+// NewLimiter creates a concurrency limiter.
+func NewLimiter(cfg LimiterConfig) *Limiter {
+	return &Limiter{
+		cfg:     cfg,
+		perKey:  make(map[string]int),
+		queue:   list.New(),
+		waiting: make(map[string]int),
+	}
+}
+
+// Acquire takes a slot for key, waiting in the queue while a cap is reached.
+// The returned release function frees the slot; it is safe to call more
+// than once. Acquire fails with ErrQueueFull, ErrQueueTimeout or the
+// context's error.
+func (l *Limiter) Acquire(ctx context.Context, key string) (func(), error) {
+	l.mu.Lock()
+	if l.canRun(key) {
+		l.take(key)
+		l.mu.Unlock()
+		return l.releaser(key), nil
+	}
+	if l.queue.Len() >= l.cfg.QueueSize {
+		l.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	w := &limiterWaiter{key: key, ready: make(chan struct{})}
+	elem := l.queue.PushBack(w)
+	l.waiting[key]++
+	depth := l.waiting[key]
+	l.mu.Unlock()
+	l.notify(key, depth)
+
+	var timeout <-chan time.Time
+	if l.cfg.QueueTimeout > 0 {
+		timer := time.NewTimer(l.cfg.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		return l.releaser(key), nil
+	case <-timeout:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	if w.granted {
+		// The slot arrived as the wait ended; hand it on.
+		l.mu.Unlock()
+		l.release(key)
+		return nil, err
+	}
+	l.queue.Remove(elem)
+	depth = l.leave(key)
+	l.mu.Unlock()
+	l.notify(key, depth)
+	return nil, err
+}
+
+// canRun reports whether a call for key fits under the caps. The caller
+// must hold l.mu.
+func (l *Limiter) canRun(key string) bool {
+	if l.cfg.MaxInFlight > 0 && l.inFlight >= l.cfg.MaxInFlight {
+		return false
+	}
+	if l.cfg.MaxPerKey != nil {
+		if limit := l.cfg.MaxPerKey(key); limit > 0 && l.perKey[key] >= limit {
+			return false
+		}
+	}
+	return true
+}
+
+// take counts a call for key in flight. The caller must hold l.mu.
+func (l *Limiter) take(key string) {
+	l.inFlight++
+	l.perKey[key]++
+}
+
+// leave drops a waiter for key and returns the key's remaining waiters.
+// The caller must hold l.mu.
+func (l *Limiter) leave(key string) int {
+	l.waiting[key]--
+	depth := l.waiting[key]
+	if depth == 0 {
+		delete(l.waiting, key)
+	}
+	return depth
+}
+
+func (l *Limiter) releaser(key string) func() {
+	var once sync.Once
+	return func() { once.Do(func() { l.release(key) }) }
+}
+
+// release frees a slot for key and hands freed capacity to waiting calls,
+// oldest first.
+func (l *Limiter) release(key string) {
+	l.mu.Lock()
+	l.inFlight--
+	l.perKey[key]--
+	if l.perKey[key] == 0 {
+		delete(l.perKey, key)
+	}
+
+	depths := make(map[string]int)
+	for elem := l.queue.Front(); elem != nil; {
+		next := elem.Next()
+		w := elem.Value.(*limiterWaiter)
+		if l.canRun(w.key) {
+			l.take(w.key)
+			l.queue.Remove(elem)
+			depths[w.key] = l.leave(w.key)
+			w.granted = true
+			close(w.ready)
+		}
+		if l.cfg.MaxInFlight > 0 && l.inFlight >= l.cfg.MaxInFlight {
+			break
+		}
+		elem = next
+	}
+	l.mu.Unlock()
+
+	for k, depth := range depths {
+		l.notify(k, depth)
+	}
+}
+
+func (l *Limiter) notify(key string, depth int) {
+	if l.cfg.OnQueueChange != nil {
+		l.cfg.OnQueueChange(key, depth)
+	}
+}
+
+// LimiterStats describes a limiter's load.
+type LimiterStats struct {
+	InFlight       int
+	Waiting        int
+	InFlightPerKey map[string]int
+	WaitingPerKey  map[string]int
+}
+
+// Stats returns the limiter's current load.
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := LimiterStats{
+		InFlight:       l.inFlight,
+		Waiting:        l.queue.Len(),
+		InFlightPerKey: make(map[string]int, len(l.perKey)),
+		WaitingPerKey:  make(map[string]int, len(l.waiting)),
+	}
+	for k, n := range l.perKey {
+		stats.InFlightPerKey[k] = n
+	}
+	for k, n := range l.waiting {
+		stats.WaitingPerKey[k] = n
+	}
+	return stats
+}
@@ -0,0 +1,171 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func mustAcquire(t *testing.T, l *Limiter, key string) func() {
+	t.Helper()
+	release, err := l.Acquire(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Acquire(%q) error: %v", key, err)
+	}
+	return release
+}
+
+// acquireAsync starts an Acquire and returns a channel that receives its
+// outcome once the call is queued or done.
+func acquireAsync(t *testing.T, l *Limiter, key string) <-chan error {
+	t.Helper()
+	before := l.Stats().Waiting
+	done := make(chan error, 1)
+	go func() {
+		release, err := l.Acquire(context.Background(), key)
+		if err == nil {
+			defer release()
+		}
+		done <- err
+	}()
+	deadline := time.Now().Add(time.Second)
+	for l.Stats().Waiting == before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return done
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	l := NewLimiter(LimiterConfig{})
+	for i := 0; i < 10; i++ {
+		mustAcquire(t, l, "a")
+	}
+	if stats := l.Stats(); stats.InFlight != 10 {
+		t.Errorf("expected 10 in flight, got %+v", stats)
+	}
+}
+
+func TestLimiter_PerKey(t *testing.T) {
+	l := NewLimiter(LimiterConfig{
+		MaxPerKey: func(key string) int {
+			if key == "a" {
+				return 1
+			}
+			return 0
+		},
+	})
+
+	release := mustAcquire(t, l, "a")
+	if _, err := l.Acquire(context.Background(), "a"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull without a queue, got %v", err)
+	}
+	mustAcquire(t, l, "b")
+
+	release()
+	release() // releasing twice is harmless
+	mustAcquire(t, l, "a")
+	if stats := l.Stats(); stats.InFlight != 2 || stats.InFlightPerKey["a"] != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestLimiter_QueueFIFO(t *testing.T) {
+	var mu sync.Mutex
+	var depths []int
+	l := NewLimiter(LimiterConfig{
+		MaxInFlight: 1,
+		QueueSize:   2,
+		OnQueueChange: func(_ string, waiting int) {
+			mu.Lock()
+			depths = append(depths, waiting)
+			mu.Unlock()
+		},
+	})
+
+	release := mustAcquire(t, l, "a")
+	first := acquireAsync(t, l, "a")
+	second := acquireAsync(t, l, "a")
+
+	if _, err := l.Acquire(context.Background(), "a"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+
+	release()
+	select {
+	case err := <-first:
+		if err != nil {
+			t.Fatalf("first waiter error: %v", err)
+		}
+	case <-second:
+		t.Fatal("expected the oldest waiter to get the slot first")
+	case <-time.After(time.Second):
+		t.Fatal("expected a waiter to get the released slot")
+	}
+	if err := <-second; err != nil {
+		t.Fatalf("second waiter error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(depths) != 4 || depths[1] != 2 {
+		t.Errorf("expected the queue to grow to 2 and drain, got depths %v", depths)
+	}
+}
+
+func TestLimiter_QueueTimeout(t *testing.T) {
+	l := NewLimiter(LimiterConfig{MaxInFlight: 1, QueueSize: 1, QueueTimeout: 10 * time.Millisecond})
+	release := mustAcquire(t, l, "a")
+	defer release()
+
+	if _, err := l.Acquire(context.Background(), "a"); !errors.Is(err, ErrQueueTimeout) {
+		t.Fatalf("expected ErrQueueTimeout, got %v", err)
+	}
+	if stats := l.Stats(); stats.Waiting != 0 || stats.InFlight != 1 {
+		t.Errorf("expected the timed out call to leave the queue, got %+v", stats)
+	}
+}
+
+func TestLimiter_ContextCanceled(t *testing.T) {
+	l := NewLimiter(LimiterConfig{MaxInFlight: 1, QueueSize: 1})
+	release := mustAcquire(t, l, "a")
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context error, got %v", err)
+	}
+	if stats := l.Stats(); stats.Waiting != 0 {
+		t.Errorf("expected an empty queue, got %+v", stats)
+	}
+}
+
+func TestLimiter_SkipsBlockedKeys(t *testing.T) {
+	l := NewLimiter(LimiterConfig{
+		MaxInFlight: 2,
+		MaxPerKey:   func(string) int { return 1 },
+		QueueSize:   2,
+	})
+
+	releaseA := mustAcquire(t, l, "a")
+	releaseB := mustAcquire(t, l, "b")
+	waitA := acquireAsync(t, l, "a")
+	waitC := acquireAsync(t, l, "c")
+
+	// Freeing b's slot cannot serve the older waiter for a.
+	releaseB()
+	select {
+	case err := <-waitC:
+		if err != nil {
+			t.Fatalf("waiter for c error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the waiter for c to get the freed slot")
+	}
+
+	releaseA()
+	if err := <-waitA; err != nil {
+		t.Fatalf("waiter for a error: %v", err)
+	}
+}
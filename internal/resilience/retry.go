@@ -0,0 +1,87 @@
+package resilience
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy describes how often and how patiently a failed call is retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry.
+	// Default: 1 second
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts.
+	// Default: 30 seconds
+	MaxBackoff time.Duration
+
+	// Multiplier grows the wait after each retry.
+	// Default: 2
+	Multiplier float64
+
+	// Jitter randomizes each wait by up to this fraction in either direction,
+	// so clients throttled together do not retry together. Range [0, 1].
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns a policy that makes a single attempt.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    1,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// Enabled reports whether the policy retries at all.
+func (p RetryPolicy) Enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+// Backoff returns the wait before the given retry, counting from 1.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = time.Second
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	wait := float64(initial)
+	for i := 1; i < retry && wait < float64(maxBackoff); i++ {
+		wait *= multiplier
+	}
+	wait = min(wait, float64(maxBackoff))
+
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		wait *= 1 + jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(wait)
+}
+
+// Wait sleeps for the backoff of the given retry. It returns early with the
+// context's error when ctx is done.
+func (p RetryPolicy) Wait(ctx context.Context, retry int) error {
+	timer := time.NewTimer(p.Backoff(retry))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
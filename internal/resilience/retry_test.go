@@ -0,0 +1,72 @@
+package resilience
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     3,
+	}
+
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 300 * time.Millisecond},
+		{3, 900 * time.Millisecond},
+		{4, time.Second},
+		{50, time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.Backoff(tt.retry); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.retry, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicy_BackoffJitter(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+
+	for i := 0; i < 100; i++ {
+		got := policy.Backoff(2)
+		if got < time.Second || got > 3*time.Second {
+			t.Fatalf("Backoff(2) = %v, want within [1s, 3s]", got)
+		}
+	}
+}
+
+func TestRetryPolicy_Enabled(t *testing.T) {
+	if DefaultRetryPolicy().Enabled() {
+		t.Error("expected default policy not to retry")
+	}
+	if !(RetryPolicy{MaxAttempts: 2}).Enabled() {
+		t.Error("expected policy with two attempts to retry")
+	}
+}
+
+func TestRetryPolicy_WaitCanceled(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	if err := policy.Wait(ctx, 1); err != context.Canceled {
+		t.Errorf("Wait() = %v, want context.Canceled", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected Wait to return as soon as the context is done")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os/exec"
+	"strings"
 
 	"github.com/signalridge/clinvoker/internal/backend"
 	"github.com/signalridge/clinvoker/internal/executor"
@@ -17,6 +19,8 @@ type Request struct {
 	Prompt          string
 	Options         *backend.UnifiedOptions
 	RequestedFormat backend.OutputFormat
+	// ResumeSessionID, when set, resumes this backend session.
+	ResumeSessionID string
 }
 
 // Result is the execution output from the core executor.
@@ -46,7 +50,12 @@ func Execute(ctx context.Context, req *Request) (*Result, error) {
 	effectiveOpts.OutputFormat = util.InternalOutputFormat(req.RequestedFormat)
 
 	// Build command with context using shared util
-	execCmd := req.Backend.BuildCommandUnified(req.Prompt, &effectiveOpts)
+	var execCmd *exec.Cmd
+	if req.ResumeSessionID != "" {
+		execCmd = req.Backend.ResumeCommandUnified(req.ResumeSessionID, req.Prompt, &effectiveOpts)
+	} else {
+		execCmd = req.Backend.BuildCommandUnified(req.Prompt, &effectiveOpts)
+	}
 	execCmd = util.CommandWithContext(ctx, execCmd)
 
 	if effectiveOpts.DryRun {
@@ -102,5 +111,10 @@ func Execute(ctx context.Context, req *Request) (*Result, error) {
 		result.Output = req.Backend.ParseOutput(rawOutput)
 	}
 
+	// Use stderr as the error message when the backend reported none
+	if result.ExitCode != 0 && result.Error == "" {
+		result.Error = strings.TrimSpace(stderrBuf.String())
+	}
+
 	return result, nil
 }
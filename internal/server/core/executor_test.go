@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"os/exec"
 	"testing"
 
 	"github.com/signalridge/clinvoker/internal/backend"
@@ -173,6 +174,29 @@ func TestExecute_FallbackToParseOutputWhenJSONContentEmpty(t *testing.T) {
 	}
 }
 
+func TestExecute_StderrAsError(t *testing.T) {
+	mockBackend := mock.NewMockBackend("mock",
+		mock.WithAvailable(true),
+		mock.WithSeparateStderr(true),
+		mock.WithCommandFunc(func(string, *backend.UnifiedOptions) *exec.Cmd {
+			return exec.Command("sh", "-c", "echo 'HTTP 429: slow down' >&2; exit 1")
+		}),
+	)
+
+	result, err := Execute(context.Background(), &Request{
+		Backend: mockBackend,
+		Prompt:  "test prompt",
+		Options: &backend.UnifiedOptions{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.ExitCode != 1 || result.Error != "HTTP 429: slow down" {
+		t.Errorf("expected stderr as error, got exit=%d error=%q", result.ExitCode, result.Error)
+	}
+}
+
 func TestRequest_Structure(t *testing.T) {
 	mockBackend := mock.NewMockBackend("mock", mock.WithAvailable(true))
 
@@ -36,6 +36,12 @@ func HTTPStatusCode(code apperrors.ErrorCode) int {
 	case apperrors.ErrCodeBackendTimeout:
 		return http.StatusGatewayTimeout
 
+	case apperrors.ErrCodeQueueFull:
+		return http.StatusTooManyRequests
+
+	case apperrors.ErrCodeQueueTimeout:
+		return http.StatusServiceUnavailable
+
 	case apperrors.ErrCodeInvalidRequest,
 		apperrors.ErrCodeMissingRequired,
 		apperrors.ErrCodeValidation:
@@ -10,24 +10,44 @@ import (
 	"time"
 
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
 
 	"github.com/signalridge/clinvoker/internal/backend"
 	"github.com/signalridge/clinvoker/internal/output"
 	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/session"
+	"github.com/signalridge/clinvoker/internal/util"
 )
 
 // Message role constants.
 const (
 	roleUser      = "user"
 	roleAssistant = "assistant"
-	stopReasonEnd = "end_turn"
-	stopReasonErr = "error"
+)
+
+// Anthropic stop reasons.
+const (
+	stopReasonEnd          = "end_turn"
+	stopReasonMaxTokens    = "max_tokens"
+	stopReasonStopSequence = "stop_sequence"
+	stopReasonToolUse      = "tool_use"
+	stopReasonErr          = "error"
+)
+
+// Content block types.
+const (
+	blockTypeText     = "text"
+	blockTypeThinking = "thinking"
+	blockTypeToolUse  = "tool_use"
+
+	anthropicThinkingEnabled = "enabled"
 )
 
 // AnthropicHandlers provides handlers for Anthropic-compatible API.
 type AnthropicHandlers struct {
-	runner service.PromptRunner
-	logger *slog.Logger
+	runner        service.PromptRunner
+	conversations *service.Conversations
+	logger        *slog.Logger
 }
 
 // NewAnthropicHandlers creates a new Anthropic handlers instance.
@@ -38,6 +58,12 @@ func NewAnthropicHandlers(runner service.PromptRunner, logger *slog.Logger) *Ant
 	return &AnthropicHandlers{runner: runner, logger: logger}
 }
 
+// SetConversations enables stateful conversations, continued on the
+// sessions of conversations.
+func (h *AnthropicHandlers) SetConversations(conversations *service.Conversations) {
+	h.conversations = conversations
+}
+
 // Register registers all Anthropic-compatible API routes.
 // Endpoints follow Anthropic API spec: https://docs.anthropic.com/en/api/messages
 func (h *AnthropicHandlers) Register(api huma.API) {
@@ -64,32 +90,212 @@ func (h *AnthropicHandlers) Register(api huma.API) {
 		},
 		Tags: []string{"Anthropic Compatible"},
 	}, h.HandleMessages)
+
+	// Token counting endpoint - POST /anthropic/v1/messages/count_tokens
+	huma.Register(api, huma.Operation{
+		OperationID: "anthropicCountTokens",
+		Method:      http.MethodPost,
+		Path:        "/anthropic/v1/messages/count_tokens",
+		Summary:     "Count tokens in a Message",
+		Description: "Count the number of tokens in a Message, including tools and system prompt, without creating it. Compatible with Anthropic POST /v1/messages/count_tokens.",
+		Tags:        []string{"Anthropic Compatible"},
+	}, h.HandleCountTokens)
+
+	// Models endpoint - GET /anthropic/v1/models
+	huma.Register(api, huma.Operation{
+		OperationID: "anthropicListModels",
+		Method:      http.MethodGet,
+		Path:        "/anthropic/v1/models",
+		Summary:     "List Models",
+		Description: "List available models from the model catalog. Compatible with Anthropic GET /v1/models.",
+		Tags:        []string{"Anthropic Compatible"},
+	}, h.HandleModels)
+}
+
+// AnthropicModel represents an Anthropic model object.
+type AnthropicModel struct {
+	Type        string `json:"type"`
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// AnthropicModelsInput is the input for the models handler.
+type AnthropicModelsInput struct{}
+
+// AnthropicModelsResponse is the response for listing models.
+type AnthropicModelsResponse struct {
+	Body AnthropicModelsResponseBody
+}
+
+// AnthropicModelsResponseBody is the body of the models response.
+type AnthropicModelsResponseBody struct {
+	Data    []AnthropicModel `json:"data"`
+	HasMore bool             `json:"has_more"`
+	FirstID string           `json:"first_id,omitempty"`
+	LastID  string           `json:"last_id,omitempty"`
+}
+
+// HandleModels handles the GET /v1/models endpoint.
+// The catalog is returned in a single page.
+func (h *AnthropicHandlers) HandleModels(ctx context.Context, _ *AnthropicModelsInput) (*AnthropicModelsResponse, error) {
+	catalog := backend.ListModels()
+	createdAt := time.Now().UTC().Format(time.RFC3339)
+
+	models := make([]AnthropicModel, len(catalog))
+	for i, m := range catalog {
+		displayName := m.DisplayName
+		if displayName == "" {
+			displayName = m.ID
+		}
+		models[i] = AnthropicModel{
+			Type:        "model",
+			ID:          m.ID,
+			DisplayName: displayName,
+			CreatedAt:   createdAt,
+		}
+	}
+
+	body := AnthropicModelsResponseBody{Data: models}
+	if len(models) > 0 {
+		body.FirstID = models[0].ID
+		body.LastID = models[len(models)-1].ID
+	}
+	return &AnthropicModelsResponse{Body: body}, nil
 }
 
 // AnthropicMessage represents an Anthropic message.
 type AnthropicMessage struct {
 	Role    string `json:"role" doc:"Message role (user, assistant)"`
 	Content string `json:"content" doc:"Message content"`
+	// Blocks holds the content of a message sent as a list of content
+	// blocks rather than a string.
+	Blocks []AnthropicInputBlock `json:"-"`
+}
+
+// AnthropicInputBlock is a content block of a request message.
+type AnthropicInputBlock struct {
+	Type   string           `json:"type"`
+	Text   string           `json:"text,omitempty"`
+	Source *AnthropicSource `json:"source,omitempty"`
+	Title  string           `json:"title,omitempty"`
+	// ID, Name and Input are the call of a tool_use block.
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// ToolUseID, Content and IsError are the result of a tool_result
+	// block; Content is a string or a list of content blocks.
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// AnthropicSource is the data of an image or document block.
+type AnthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// UnmarshalJSON decodes a message whose content is a string or a list of
+// content blocks.
+func (m *AnthropicMessage) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = AnthropicMessage{Role: raw.Role}
+	if len(raw.Content) == 0 || string(raw.Content) == "null" {
+		return nil
+	}
+	if raw.Content[0] == '[' {
+		return json.Unmarshal(raw.Content, &m.Blocks)
+	}
+	return json.Unmarshal(raw.Content, &m.Content)
+}
+
+// TransformSchema documents that content may be a list of content blocks.
+func (m AnthropicMessage) TransformSchema(_ huma.Registry, s *huma.Schema) *huma.Schema {
+	if _, ok := s.Properties["content"]; ok {
+		s.Properties["content"] = contentSchema("Message content: a string, or a list of text, image, document, tool_use and tool_result blocks")
+	}
+	return s
+}
+
+// AnthropicSystem is a system prompt, given as a string or a list of text
+// blocks.
+type AnthropicSystem string
+
+// UnmarshalJSON accepts a string or a list of text blocks, whose texts are
+// joined.
+func (s *AnthropicSystem) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*s = AnthropicSystem(text)
+		return nil
+	}
+	var blocks []AnthropicInputBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return err
+	}
+	texts := make([]string, len(blocks))
+	for i, b := range blocks {
+		if b.Type != blockTypeText {
+			return fmt.Errorf("system[%d]: unsupported content block type %q", i, b.Type)
+		}
+		texts[i] = b.Text
+	}
+	*s = AnthropicSystem(strings.Join(texts, "\n"))
+	return nil
+}
+
+// Schema describes system as a string or a list of text blocks.
+func (AnthropicSystem) Schema(huma.Registry) *huma.Schema {
+	return &huma.Schema{
+		Description: "System prompt: a string, or a list of text blocks",
+		OneOf: []*huma.Schema{
+			{Type: huma.TypeString},
+			{Type: huma.TypeArray, Items: &huma.Schema{Type: huma.TypeObject}},
+		},
+	}
 }
 
 // AnthropicMessagesRequest is the request for creating messages.
 type AnthropicMessagesRequest struct {
-	Model         string             `json:"model" doc:"Model to use"`
-	MaxTokens     int                `json:"max_tokens" doc:"Maximum tokens to generate"`
-	Messages      []AnthropicMessage `json:"messages" doc:"Conversation messages"`
-	System        string             `json:"system,omitempty" doc:"System prompt"`
-	StopSequences []string           `json:"stop_sequences,omitempty" doc:"Stop sequences"`
-	Stream        bool               `json:"stream,omitempty" doc:"Stream responses"`
-	Temperature   float64            `json:"temperature,omitempty" doc:"Sampling temperature"`
-	TopP          float64            `json:"top_p,omitempty" doc:"Nucleus sampling parameter"`
-	TopK          int                `json:"top_k,omitempty" doc:"Top-k sampling parameter"`
-	Metadata      map[string]string  `json:"metadata,omitempty" doc:"Request metadata"`
+	Model         string               `json:"model" doc:"Model to use"`
+	MaxTokens     int                  `json:"max_tokens" doc:"Maximum tokens to generate"`
+	Messages      []AnthropicMessage   `json:"messages" doc:"Conversation messages"`
+	System        AnthropicSystem      `json:"system,omitempty" doc:"System prompt"`
+	StopSequences []string             `json:"stop_sequences,omitempty" doc:"Stop sequences; the reply is cut before the first one"`
+	Stream        bool                 `json:"stream,omitempty" doc:"Stream responses"`
+	Temperature   float64              `json:"temperature,omitempty" doc:"Sampling temperature"`
+	TopP          float64              `json:"top_p,omitempty" doc:"Nucleus sampling parameter"`
+	TopK          int                  `json:"top_k,omitempty" doc:"Top-k sampling parameter"`
+	Metadata      map[string]string    `json:"metadata,omitempty" doc:"Request metadata"`
+	Thinking      *AnthropicThinking   `json:"thinking,omitempty" doc:"Extended thinking; when enabled, streamed thinking is sent as thinking blocks"`
+	Tools         []AnthropicTool      `json:"tools,omitempty" doc:"Tools the model may call; calls are returned as tool_use blocks"`
+	ToolChoice    *AnthropicToolChoice `json:"tool_choice,omitempty" doc:"How the model may call tools"`
+}
+
+// AnthropicThinking configures extended thinking.
+type AnthropicThinking struct {
+	Type string `json:"type" enum:"enabled,disabled" doc:"Whether thinking is enabled"`
+	// BudgetTokens is accepted for compatibility; backends decide how much
+	// to think.
+	BudgetTokens int `json:"budget_tokens,omitempty" doc:"Accepted for compatibility"`
 }
 
 // AnthropicContentBlock represents a content block in the response.
 type AnthropicContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
 }
 
 // AnthropicUsage represents token usage.
@@ -132,9 +338,9 @@ type anthropicStreamMessage struct {
 }
 
 type anthropicStreamContentBlockStart struct {
-	Type         string                     `json:"type"`
-	Index        int                        `json:"index"`
-	ContentBlock anthropicStreamContentText `json:"content_block"`
+	Type         string `json:"type"`
+	Index        int    `json:"index"`
+	ContentBlock any    `json:"content_block"`
 }
 
 type anthropicStreamContentText struct {
@@ -142,10 +348,22 @@ type anthropicStreamContentText struct {
 	Text string `json:"text"`
 }
 
+type anthropicStreamContentThinking struct {
+	Type     string `json:"type"`
+	Thinking string `json:"thinking"`
+}
+
+type anthropicStreamContentToolUse struct {
+	Type  string         `json:"type"`
+	ID    string         `json:"id"`
+	Name  string         `json:"name"`
+	Input map[string]any `json:"input"`
+}
+
 type anthropicStreamContentBlockDelta struct {
-	Type  string                   `json:"type"`
-	Index int                      `json:"index"`
-	Delta anthropicStreamTextDelta `json:"delta"`
+	Type  string `json:"type"`
+	Index int    `json:"index"`
+	Delta any    `json:"delta"`
 }
 
 type anthropicStreamTextDelta struct {
@@ -153,6 +371,16 @@ type anthropicStreamTextDelta struct {
 	Text string `json:"text"`
 }
 
+type anthropicStreamThinkingDelta struct {
+	Type     string `json:"type"`
+	Thinking string `json:"thinking"`
+}
+
+type anthropicStreamInputJSONDelta struct {
+	Type        string `json:"type"`
+	PartialJSON string `json:"partial_json"`
+}
+
 type anthropicStreamContentBlockStop struct {
 	Type  string `json:"type"`
 	Index int    `json:"index"`
@@ -185,7 +413,8 @@ type anthropicStreamErrorDetails struct {
 
 // AnthropicMessagesInput is the input for the messages handler.
 type AnthropicMessagesInput struct {
-	Body AnthropicMessagesRequest
+	Stateful bool `header:"X-Clinvk-Stateful" doc:"Continue the conversation on a clinvk session, sending only the newest user message"`
+	Body     AnthropicMessagesRequest
 }
 
 // HandleMessages handles the POST /v1/messages endpoint.
@@ -200,25 +429,11 @@ func (h *AnthropicHandlers) HandleMessages(ctx context.Context, input *Anthropic
 		return nil, huma.Error400BadRequest("max_tokens must be greater than 0")
 	}
 
-	// Extract prompt from messages
-	var prompt string
-	for _, msg := range input.Body.Messages {
-		if msg.Role == roleUser {
-			if prompt != "" {
-				prompt += "\n"
-			}
-			prompt += msg.Content
-		} else if msg.Role == roleAssistant {
-			// Include assistant context for continuations
-			if prompt != "" {
-				prompt += "\n[Previous response: " + msg.Content + "]\n"
-			}
-		}
-	}
-
-	if prompt == "" {
-		return nil, huma.Error400BadRequest("no user messages found")
+	conv, err := newAnthropicConversation(input.Body.Messages, input.Body.System, input.Body.Tools, input.Body.ToolChoice)
+	if err != nil {
+		return nil, err
 	}
+	tools := conv.tools
 
 	// Map model to backend
 	backendName := mapAnthropicModelToBackend(input.Body.Model)
@@ -226,17 +441,55 @@ func (h *AnthropicHandlers) HandleMessages(ctx context.Context, input *Anthropic
 	// Execute prompt (non-streaming)
 	req := &service.PromptRequest{
 		Backend:      backendName,
-		Prompt:       prompt,
+		Prompt:       conv.prompt,
 		Model:        input.Body.Model,
 		MaxTokens:    input.Body.MaxTokens,
-		SystemPrompt: input.Body.System,
+		SystemPrompt: conv.system,
 		Metadata:     input.Body.Metadata,
 	}
 
+	var turn *service.ConversationTurn
+	if user := input.Body.Metadata["user_id"]; statefulRequested(input.Stateful, user) {
+		scope := conversationScope("anthropic", user, backendName, input.Body.Model, conv.system)
+		turn = nextConversationTurn(h.conversations, scope, conv.messages, req)
+	}
+
+	// Tools are declared on every turn, as they may change between turns
+	if tools != nil {
+		req.Prompt += "\n\n" + tools.prompt()
+	}
+
+	cleanup, err := conv.files.attachTo(req)
+	if err != nil {
+		return nil, err
+	}
+
 	if !input.Body.Stream {
-		result, err := h.runner.ExecutePrompt(ctx, req)
+		defer cleanup()
+
+		var result *service.PromptResult
+		if turn != nil {
+			result, err = h.conversations.ExecutePrompt(ctx, req)
+		} else {
+			result, err = h.runner.ExecutePrompt(ctx, req)
+		}
 		if err != nil {
-			return nil, huma.Error500InternalServerError("execution failed", err)
+			return nil, executionError("execution failed", err)
+		}
+
+		text, stopSequence := cutAtStop(result.Output, input.Body.StopSequences)
+		content := []AnthropicContentBlock{{Type: blockTypeText, Text: text}}
+		reply := text
+		toolUse := false
+		if tools != nil && result.ExitCode == 0 {
+			if calls, ok := tools.parse(text); ok {
+				content = anthropicToolUseBlocks(calls)
+				reply = renderAnthropicToolUses(content)
+				toolUse = true
+			}
+		}
+		if turn != nil && result.ExitCode == 0 {
+			h.conversations.Finish(turn, result.SessionID, reply)
 		}
 
 		// Build response
@@ -246,31 +499,27 @@ func (h *AnthropicHandlers) HandleMessages(ctx context.Context, input *Anthropic
 			responseID = fmt.Sprintf("msg_%d", now)
 		}
 
-		stopReason := stopReasonEnd
-		if result.ExitCode != 0 {
-			stopReason = stopReasonErr
+		stopReason := anthropicStopReason(result.ExitCode != 0, toolUse, stopSequence, result.Backend, input.Body.MaxTokens, result.TokenUsage)
+		if stopReason != stopReasonStopSequence {
+			stopSequence = ""
 		}
 
 		// Token counts (use backend usage if available, fallback to rough estimate)
-		inputTokens := len(prompt) / 4
-		outputTokens := len(result.Output) / 4
+		inputTokens := conv.inputTokens()
+		outputTokens := len(reply) / 4
 		if result.TokenUsage != nil {
 			inputTokens = int(result.TokenUsage.InputTokens)
 			outputTokens = int(result.TokenUsage.OutputTokens)
 		}
 
 		body := AnthropicMessagesResponseBody{
-			ID:   responseID,
-			Type: "message",
-			Role: roleAssistant,
-			Content: []AnthropicContentBlock{
-				{
-					Type: "text",
-					Text: result.Output,
-				},
-			},
-			Model:      input.Body.Model,
-			StopReason: stopReason,
+			ID:           responseID,
+			Type:         "message",
+			Role:         roleAssistant,
+			Content:      content,
+			Model:        input.Body.Model,
+			StopReason:   stopReason,
+			StopSequence: stopSequence,
 			Usage: AnthropicUsage{
 				InputTokens:  inputTokens,
 				OutputTokens: outputTokens,
@@ -279,8 +528,11 @@ func (h *AnthropicHandlers) HandleMessages(ctx context.Context, input *Anthropic
 
 		return &huma.StreamResponse{
 			Body: func(hctx huma.Context) {
-				hctx.SetStatus(http.StatusOK)
+				if turn != nil && result.SessionID != "" {
+					hctx.SetHeader(sessionIDHeader, result.SessionID)
+				}
 				hctx.SetHeader("Content-Type", "application/json")
+				hctx.SetStatus(http.StatusOK)
 				if err := json.NewEncoder(hctx.BodyWriter()).Encode(body); err != nil {
 					h.logger.Debug("JSON encode error", "error", err)
 				}
@@ -290,10 +542,11 @@ func (h *AnthropicHandlers) HandleMessages(ctx context.Context, input *Anthropic
 
 	created := time.Now().Unix()
 	responseID := fmt.Sprintf("msg_%d", created)
+	thinking := input.Body.Thinking != nil && input.Body.Thinking.Type == anthropicThinkingEnabled
 
 	return &huma.StreamResponse{
 		Body: func(hctx huma.Context) {
-			setEventStreamHeaders(hctx)
+			defer cleanup()
 
 			// Helper to log SSE write errors at debug level
 			logSSEErr := func(event string, err error) {
@@ -302,75 +555,149 @@ func (h *AnthropicHandlers) HandleMessages(ctx context.Context, input *Anthropic
 				}
 			}
 
-			logSSEErr("message_start", writeSSEEvent(hctx, "message_start", anthropicStreamMessageStart{
-				Type: "message_start",
-				Message: anthropicStreamMessage{
-					ID:      responseID,
-					Type:    "message",
-					Role:    roleAssistant,
-					Content: []AnthropicContentBlock{},
-					Model:   input.Body.Model,
-					Usage: AnthropicUsage{
-						InputTokens:  0,
-						OutputTokens: 0,
+			// The message opens with the first content, so a request turned
+			// away before the backend runs can still get an error status.
+			var sessionID string
+			stream := &streamStart{start: func() {
+				if sessionID != "" {
+					hctx.SetHeader(sessionIDHeader, sessionID)
+				}
+				setEventStreamHeaders(hctx)
+
+				logSSEErr("message_start", writeSSEEvent(hctx, "message_start", anthropicStreamMessageStart{
+					Type: "message_start",
+					Message: anthropicStreamMessage{
+						ID:      responseID,
+						Type:    "message",
+						Role:    roleAssistant,
+						Content: []AnthropicContentBlock{},
+						Model:   input.Body.Model,
+						Usage: AnthropicUsage{
+							InputTokens:  0,
+							OutputTokens: 0,
+						},
 					},
-				},
-			}))
-
-			logSSEErr("content_block_start", writeSSEEvent(hctx, "content_block_start", anthropicStreamContentBlockStart{
-				Type:  "content_block_start",
-				Index: 0,
-				ContentBlock: anthropicStreamContentText{
-					Type: "text",
-					Text: "",
-				},
-			}))
+				}))
+			}}
+			blocks := &anthropicStreamBlocks{hctx: hctx, logSSEErr: logSSEErr, index: -1}
 
 			streamReq := *req
 			streamCtx := hctx.Context()
 
-			streamResult, streamErr := service.StreamPrompt(streamCtx, &streamReq, nil, nil, true, func(event *output.UnifiedEvent) error {
-				if event.Type != output.EventMessage {
+			var reply strings.Builder
+			var held toolStream
+			stops := &stopStream{stops: input.Body.StopSequences}
+			writeText := func(text string) error {
+				reply.WriteString(text)
+				if tools != nil {
+					text = held.write(text)
+				}
+				if text == "" {
 					return nil
 				}
-				content, err := event.GetMessageContent()
-				if err != nil {
-					return err
+				stream.begin()
+				return blocks.delta(blockTypeText, anthropicStreamTextDelta{Type: "text_delta", Text: text})
+			}
+			onEvent := func(event *output.UnifiedEvent) error {
+				if turn != nil {
+					sessionID = event.SessionID
 				}
-				if content.Text == "" {
-					return nil
+
+				switch event.Type {
+				case output.EventMessage:
+					content, err := event.GetMessageContent()
+					if err != nil {
+						return err
+					}
+					return writeText(stops.write(content.Text))
+
+				case output.EventThinking:
+					if !thinking {
+						return nil
+					}
+					content, err := event.GetThinkingContent()
+					if err != nil {
+						return err
+					}
+					if content.Text == "" {
+						return nil
+					}
+					stream.begin()
+					return blocks.delta(blockTypeThinking, anthropicStreamThinkingDelta{Type: "thinking_delta", Thinking: content.Text})
 				}
-				return writeSSEEvent(hctx, "content_block_delta", anthropicStreamContentBlockDelta{
-					Type:  "content_block_delta",
-					Index: 0,
-					Delta: anthropicStreamTextDelta{
-						Type: "text_delta",
-						Text: content.Text,
-					},
-				})
-			})
+				// Tools the backend ran itself are not tool_use blocks, which
+				// ask the client to run a tool, so they are not sent, as in a
+				// reply that is not streamed
+				return nil
+			}
 
-			logSSEErr("content_block_stop", writeSSEEvent(hctx, "content_block_stop", anthropicStreamContentBlockStop{
-				Type:  "content_block_stop",
-				Index: 0,
-			}))
+			var streamResult *service.StreamResult
+			var streamErr error
+			if turn != nil {
+				streamResult, streamErr = h.conversations.StreamPrompt(streamCtx, &streamReq, onEvent)
+			} else {
+				streamResult, streamErr = service.StreamPrompt(streamCtx, &streamReq, nil, nil, true, onEvent)
+			}
+
+			if !stream.started && writeConcurrencyRejection(hctx, streamErr) {
+				return
+			}
+			stream.begin()
+
+			// Text held back as a possible stop sequence was not one
+			if rest := stops.flush(); rest != "" {
+				logSSEErr("content_block_delta", writeText(rest))
+			}
+
+			failed := streamErr != nil || streamResult == nil || streamResult.ExitCode != 0 || streamResult.Error != ""
+
+			// Text held back as a possible tool call is sent now, as
+			// tool_use blocks when it is one
+			replyText := reply.String()
+			toolUse := false
+			if pending := held.pending(); pending != "" {
+				if calls, ok := tools.parse(pending); ok && !failed {
+					uses := anthropicToolUseBlocks(calls)
+					for _, use := range uses {
+						logSSEErr("content_block_delta", blocks.toolUse(&output.ToolUseContent{ToolID: use.ID, ToolName: use.Name, Input: use.Input}))
+					}
+					replyText = renderAnthropicToolUses(uses)
+					toolUse = true
+				} else {
+					logSSEErr("content_block_delta", blocks.delta(blockTypeText, anthropicStreamTextDelta{Type: "text_delta", Text: pending}))
+				}
+			}
 
-			stopReason := stopReasonEnd
-			if streamErr != nil || streamResult == nil || streamResult.ExitCode != 0 || streamResult.Error != "" {
-				stopReason = stopReasonErr
+			// A message always has content, if only an empty text block
+			if blocks.index < 0 {
+				blocks.open(blockTypeText, anthropicStreamContentText{Type: blockTypeText})
 			}
+			blocks.close()
 
+			var backendName string
 			usage := AnthropicUsage{}
-			if streamResult != nil && streamResult.TokenUsage != nil {
-				usage.InputTokens = int(streamResult.TokenUsage.InputTokens)
-				usage.OutputTokens = int(streamResult.TokenUsage.OutputTokens)
+			if streamResult != nil {
+				backendName = streamResult.Backend
+				if streamResult.TokenUsage != nil {
+					usage.InputTokens = int(streamResult.TokenUsage.InputTokens)
+					usage.OutputTokens = int(streamResult.TokenUsage.OutputTokens)
+				}
+			}
+			if !failed && turn != nil {
+				h.conversations.Finish(turn, streamResult.SessionID, replyText)
+			}
+
+			delta := anthropicStreamMessageDeltaData{StopReason: stopReasonErr}
+			if !failed {
+				delta.StopReason = anthropicStopReason(false, toolUse, stops.stop, backendName, input.Body.MaxTokens, streamResult.TokenUsage)
+			}
+			if delta.StopReason == stopReasonStopSequence {
+				delta.StopSequence = stops.stop
 			}
 
 			logSSEErr("message_delta", writeSSEEvent(hctx, "message_delta", anthropicStreamMessageDelta{
-				Type: "message_delta",
-				Delta: anthropicStreamMessageDeltaData{
-					StopReason: stopReason,
-				},
+				Type:  "message_delta",
+				Delta: delta,
 				Usage: usage,
 			}))
 
@@ -391,17 +718,224 @@ func (h *AnthropicHandlers) HandleMessages(ctx context.Context, input *Anthropic
 	}, nil
 }
 
+// AnthropicCountTokensRequest is the request for counting tokens.
+type AnthropicCountTokensRequest struct {
+	Model      string               `json:"model" doc:"Model to use"`
+	Messages   []AnthropicMessage   `json:"messages" doc:"Conversation messages"`
+	System     AnthropicSystem      `json:"system,omitempty" doc:"System prompt"`
+	Tools      []AnthropicTool      `json:"tools,omitempty" doc:"Tools the model may call"`
+	ToolChoice *AnthropicToolChoice `json:"tool_choice,omitempty" doc:"How the model may call tools"`
+	Thinking   *AnthropicThinking   `json:"thinking,omitempty" doc:"Accepted for compatibility"`
+}
+
+// AnthropicCountTokensInput is the input for the token counting handler.
+type AnthropicCountTokensInput struct {
+	Body AnthropicCountTokensRequest
+}
+
+// AnthropicCountTokensResponse is the response for counting tokens.
+type AnthropicCountTokensResponse struct {
+	Body AnthropicCountTokensResponseBody
+}
+
+// AnthropicCountTokensResponseBody is the body of the token count response.
+type AnthropicCountTokensResponseBody struct {
+	InputTokens int `json:"input_tokens" doc:"Estimated number of input tokens"`
+}
+
+// HandleCountTokens handles the POST /v1/messages/count_tokens endpoint.
+// CLI backends cannot count tokens without running the prompt, so the count
+// is estimated from the prompt the request would send.
+func (h *AnthropicHandlers) HandleCountTokens(ctx context.Context, input *AnthropicCountTokensInput) (*AnthropicCountTokensResponse, error) {
+	if input.Body.Model == "" {
+		return nil, huma.Error400BadRequest("model is required")
+	}
+	if len(input.Body.Messages) == 0 {
+		return nil, huma.Error400BadRequest("messages are required")
+	}
+
+	conv, err := newAnthropicConversation(input.Body.Messages, input.Body.System, input.Body.Tools, input.Body.ToolChoice)
+	if err != nil {
+		return nil, err
+	}
+	return &AnthropicCountTokensResponse{
+		Body: AnthropicCountTokensResponseBody{InputTokens: conv.inputTokens()},
+	}, nil
+}
+
+// anthropicConversation is the messages of a request rendered for a
+// backend.
+type anthropicConversation struct {
+	prompt   string
+	system   string
+	messages []service.ConversationMessage
+	tools    *toolSet
+	files    attachments
+}
+
+// newAnthropicConversation renders the messages of a request as a prompt.
+// Content blocks are rendered as text, with images and documents attached.
+func newAnthropicConversation(messages []AnthropicMessage, system AnthropicSystem, tools []AnthropicTool, toolChoice *AnthropicToolChoice) (*anthropicConversation, error) {
+	set, err := newAnthropicToolSet(tools, toolChoice)
+	if err != nil {
+		return nil, err
+	}
+	c := &anthropicConversation{system: string(system), tools: set}
+
+	for _, msg := range messages {
+		content := msg.Content
+		if msg.Blocks != nil {
+			if content, err = c.files.renderAnthropicBlocks(msg.Blocks); err != nil {
+				return nil, err
+			}
+		}
+
+		switch msg.Role {
+		case roleUser:
+			if c.prompt != "" {
+				c.prompt += "\n"
+			}
+			c.prompt += content
+		case roleAssistant:
+			// Include assistant context for continuations
+			if c.prompt != "" {
+				c.prompt += "\n[Previous response: " + content + "]\n"
+			}
+		default:
+			continue
+		}
+		c.messages = append(c.messages, service.ConversationMessage{Role: msg.Role, Content: content})
+	}
+
+	if c.prompt == "" {
+		return nil, huma.Error400BadRequest("no user messages found")
+	}
+	return c, nil
+}
+
+// inputTokens estimates the tokens of the prompt, system prompt and tools
+// of the conversation, at about four characters per token.
+func (c *anthropicConversation) inputTokens() int {
+	n := len(c.prompt) + len(c.system)
+	if c.tools != nil {
+		n += len(c.tools.prompt())
+	}
+	return n / 4
+}
+
+// anthropicStopReason derives the stop reason of a reply from its run: the
+// run failed, the reply called tools or was cut at a stop sequence, or its
+// output reached max_tokens on a backend that enforces the limit.
+func anthropicStopReason(failed, toolUse bool, stopSequence, backendName string, maxTokens int, usage *session.TokenUsage) string {
+	switch {
+	case failed:
+		return stopReasonErr
+	case toolUse:
+		return stopReasonToolUse
+	case stopSequence != "":
+		return stopReasonStopSequence
+	case usage != nil && maxTokens > 0 && usage.OutputTokens >= int64(maxTokens):
+		if b, err := backend.Get(backendName); err == nil && b.Capabilities().MaxTokens {
+			return stopReasonMaxTokens
+		}
+	}
+	return stopReasonEnd
+}
+
+// anthropicStreamBlocks tracks the content blocks of a streamed message.
+// Text and thinking deltas extend the open block of their type; any other
+// content closes it and opens a new block.
+type anthropicStreamBlocks struct {
+	hctx      huma.Context
+	logSSEErr func(event string, err error)
+	// index is the index of the open block, or of the last block once it
+	// is closed; -1 before the first block.
+	index int
+	// openType is the type of the open block, or "" when none is open.
+	openType string
+}
+
+// open closes the open block and starts a new one.
+func (b *anthropicStreamBlocks) open(blockType string, block any) {
+	b.close()
+	b.index++
+	b.openType = blockType
+	b.logSSEErr("content_block_start", writeSSEEvent(b.hctx, "content_block_start", anthropicStreamContentBlockStart{
+		Type:         "content_block_start",
+		Index:        b.index,
+		ContentBlock: block,
+	}))
+}
+
+// close stops the open block, if any.
+func (b *anthropicStreamBlocks) close() {
+	if b.openType == "" {
+		return
+	}
+	b.openType = ""
+	b.logSSEErr("content_block_stop", writeSSEEvent(b.hctx, "content_block_stop", anthropicStreamContentBlockStop{
+		Type:  "content_block_stop",
+		Index: b.index,
+	}))
+}
+
+// delta writes a delta to the open block of blockType, opening one first
+// when another kind of block is open.
+func (b *anthropicStreamBlocks) delta(blockType string, delta any) error {
+	if b.openType != blockType {
+		switch blockType {
+		case blockTypeThinking:
+			b.open(blockType, anthropicStreamContentThinking{Type: blockTypeThinking})
+		default:
+			b.open(blockType, anthropicStreamContentText{Type: blockTypeText})
+		}
+	}
+	return writeSSEEvent(b.hctx, "content_block_delta", anthropicStreamContentBlockDelta{
+		Type:  "content_block_delta",
+		Index: b.index,
+		Delta: delta,
+	})
+}
+
+// toolUse writes a complete tool_use block for a call of a declared tool.
+func (b *anthropicStreamBlocks) toolUse(content *output.ToolUseContent) error {
+	id := content.ToolID
+	if id == "" {
+		id = "toolu_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:24]
+	}
+	input := string(content.Input)
+	if input == "" || input == "null" {
+		input = "{}"
+	}
+
+	b.open(blockTypeToolUse, anthropicStreamContentToolUse{
+		Type:  blockTypeToolUse,
+		ID:    id,
+		Name:  content.ToolName,
+		Input: map[string]any{},
+	})
+	err := writeSSEEvent(b.hctx, "content_block_delta", anthropicStreamContentBlockDelta{
+		Type:  "content_block_delta",
+		Index: b.index,
+		Delta: anthropicStreamInputJSONDelta{Type: "input_json_delta", PartialJSON: input},
+	})
+	b.close()
+	return err
+}
+
 // mapAnthropicModelToBackend maps Anthropic model names to backend names.
 func mapAnthropicModelToBackend(model string) string {
-	// If the model is already a backend name, use it
-	switch model {
-	case backend.BackendClaude, backend.BackendCodex, backend.BackendGemini:
+	// If the model is already a registered backend or pool name, use it
+	if _, err := backend.Get(model); err == nil || util.IsPool(model) {
 		return model
 	}
 
-	// Map Anthropic-style model names to backends
-	if strings.Contains(model, "claude") {
-		return backend.BackendClaude
+	// Route exact catalog model IDs to their owning backend. Prefixes are
+	// not consulted: unknown models go to claude, as the Anthropic API implies.
+	for _, m := range backend.ListModels() {
+		if m.ID == model {
+			return m.Backend
+		}
 	}
 
 	// Default to claude for Anthropic API
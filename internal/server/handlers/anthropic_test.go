@@ -2,10 +2,22 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
 	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
 	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/session"
 )
 
 func TestAnthropicHandlers_HandleMessages_Validation(t *testing.T) {
@@ -161,6 +173,38 @@ func TestAnthropicHandlers_HandleMessages_MessageParsing(t *testing.T) {
 	}
 }
 
+func TestAnthropicHandlers_HandleModels(t *testing.T) {
+	handlers := NewAnthropicHandlers(service.NewExecutor(), nil)
+
+	resp, err := handlers.HandleModels(context.Background(), &AnthropicModelsInput{})
+	if err != nil {
+		t.Fatalf("HandleModels failed: %v", err)
+	}
+
+	if len(resp.Body.Data) == 0 {
+		t.Fatal("expected at least one model")
+	}
+	if resp.Body.HasMore {
+		t.Error("expected a single page")
+	}
+	if resp.Body.FirstID != resp.Body.Data[0].ID || resp.Body.LastID != resp.Body.Data[len(resp.Body.Data)-1].ID {
+		t.Errorf("unexpected first_id/last_id: %q/%q", resp.Body.FirstID, resp.Body.LastID)
+	}
+
+	found := false
+	for _, m := range resp.Body.Data {
+		if m.Type != "model" || m.DisplayName == "" || m.CreatedAt == "" {
+			t.Errorf("incomplete model entry: %+v", m)
+		}
+		if m.ID == "claude-opus-4-5-20251101" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected built-in catalog model in listing")
+	}
+}
+
 func TestMapAnthropicModelToBackend_Comprehensive(t *testing.T) {
 	tests := []struct {
 		model    string
@@ -182,6 +226,10 @@ func TestMapAnthropicModelToBackend_Comprehensive(t *testing.T) {
 		{"unknown-model", backend.BackendClaude},
 		{"", backend.BackendClaude},
 		{"gpt-4", backend.BackendClaude}, // Different from OpenAI handler
+
+		// Exact catalog IDs route to their backend
+		{"gemini-2.5-flash", backend.BackendGemini},
+		{"gpt-5.2", backend.BackendCodex},
 	}
 
 	for _, tt := range tests {
@@ -303,6 +351,7 @@ func TestAnthropicValidStopReasons(t *testing.T) {
 		"end_turn",
 		"max_tokens",
 		"stop_sequence",
+		"tool_use",
 		"error",
 	}
 
@@ -317,3 +366,357 @@ func TestAnthropicValidStopReasons(t *testing.T) {
 		})
 	}
 }
+
+func TestAnthropicSystem_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    AnthropicSystem
+		wantErr bool
+	}{
+		{name: "string", data: `"Be brief."`, want: "Be brief."},
+		{
+			name: "text blocks",
+			data: `[{"type":"text","text":"Be brief."},{"type":"text","text":"Answer in French.","cache_control":{"type":"ephemeral"}}]`,
+			want: "Be brief.\nAnswer in French.",
+		},
+		{name: "image block", data: `[{"type":"image"}]`, wantErr: true},
+		{name: "number", data: `1`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got AnthropicSystem
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnthropicStopReason(t *testing.T) {
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-limited",
+		mock.WithCapabilities(backend.Capabilities{MaxTokens: true}),
+	)))
+	usage := &session.TokenUsage{InputTokens: 10, OutputTokens: 100}
+
+	tests := []struct {
+		name         string
+		failed       bool
+		toolUse      bool
+		stopSequence string
+		backend      string
+		usage        *session.TokenUsage
+		want         string
+	}{
+		{name: "end of turn", backend: "mock-limited", want: stopReasonEnd},
+		{name: "failed", failed: true, toolUse: true, want: stopReasonErr},
+		{name: "tool use", toolUse: true, stopSequence: "END", want: stopReasonToolUse},
+		{name: "stop sequence", stopSequence: "END", backend: "mock-limited", usage: usage, want: stopReasonStopSequence},
+		{name: "max tokens reached", backend: "mock-limited", usage: usage, want: stopReasonMaxTokens},
+		{name: "max tokens not enforced", backend: "unknown-backend", usage: usage, want: stopReasonEnd},
+		{name: "below max tokens", backend: "mock-limited", usage: &session.TokenUsage{OutputTokens: 99}, want: stopReasonEnd},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := anthropicStopReason(tt.failed, tt.toolUse, tt.stopSequence, tt.backend, 100, tt.usage)
+			if got != tt.want {
+				t.Errorf("anthropicStopReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnthropicHandlers_HandleCountTokens(t *testing.T) {
+	handlers := NewAnthropicHandlers(service.NewExecutor(), nil)
+	count := func(t *testing.T, body AnthropicCountTokensRequest) int {
+		t.Helper()
+		resp, err := handlers.HandleCountTokens(context.Background(), &AnthropicCountTokensInput{Body: body})
+		if err != nil {
+			t.Fatalf("HandleCountTokens() error: %v", err)
+		}
+		return resp.Body.InputTokens
+	}
+
+	messages := []AnthropicMessage{{Role: "user", Content: strings.Repeat("word ", 40)}}
+	base := count(t, AnthropicCountTokensRequest{Model: "claude", Messages: messages})
+	if base != 50 {
+		t.Errorf("input_tokens = %d, want 50", base)
+	}
+	withSystem := count(t, AnthropicCountTokensRequest{Model: "claude", Messages: messages, System: AnthropicSystem(strings.Repeat("rule ", 40))})
+	if withSystem <= base {
+		t.Errorf("input_tokens with system = %d, want more than %d", withSystem, base)
+	}
+	withTools := count(t, AnthropicCountTokensRequest{
+		Model:    "claude",
+		Messages: messages,
+		Tools:    []AnthropicTool{{Name: "get_weather", InputSchema: map[string]any{"type": "object"}}},
+	})
+	if withTools <= base {
+		t.Errorf("input_tokens with tools = %d, want more than %d", withTools, base)
+	}
+
+	for _, tt := range []struct {
+		name string
+		body AnthropicCountTokensRequest
+	}{
+		{name: "missing model", body: AnthropicCountTokensRequest{Messages: messages}},
+		{name: "missing messages", body: AnthropicCountTokensRequest{Model: "claude"}},
+		{name: "no user messages", body: AnthropicCountTokensRequest{Model: "claude", Messages: []AnthropicMessage{{Role: "assistant", Content: "Hi"}}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := handlers.HandleCountTokens(context.Background(), &AnthropicCountTokensInput{Body: tt.body}); err == nil {
+				t.Error("HandleCountTokens() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestAnthropicHandlers_HandleMessages_StopsAndTools(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	// The backend answers each prompt with the next of replies, if any
+	var mu sync.Mutex
+	var replies []string
+	var prompts []string
+	var opts []*backend.UnifiedOptions
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-messages",
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(func(p string, o *backend.UnifiedOptions) *exec.Cmd {
+			mu.Lock()
+			defer mu.Unlock()
+			prompts = append(prompts, p)
+			opts = append(opts, o)
+			reply := "Hello END world"
+			if len(replies) > 0 {
+				reply, replies = replies[0], replies[1:]
+			}
+			return exec.Command("printf", "%s", reply)
+		}),
+	)))
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	NewAnthropicHandlers(service.NewStatelessRunner(nil), nil).Register(api)
+
+	send := func(t *testing.T, body string) AnthropicMessagesResponseBody {
+		t.Helper()
+		rec := serveJobRequest(router, http.MethodPost, "/anthropic/v1/messages", body, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		var resp AnthropicMessagesResponseBody
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Unmarshal() error: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("stop sequences", func(t *testing.T) {
+		resp := send(t, `{"model":"mock-messages","max_tokens":100,"stop_sequences":["END"],"messages":[{"role":"user","content":"hi"}]}`)
+		if len(resp.Content) != 1 || resp.Content[0].Text != "Hello " {
+			t.Errorf("content = %+v, want the text before the stop sequence", resp.Content)
+		}
+		if resp.StopReason != stopReasonStopSequence || resp.StopSequence != "END" {
+			t.Errorf("stop_reason = %q, stop_sequence = %q", resp.StopReason, resp.StopSequence)
+		}
+	})
+
+	t.Run("end of turn", func(t *testing.T) {
+		resp := send(t, `{"model":"mock-messages","max_tokens":100,"stop_sequences":["STOP"],"messages":[{"role":"user","content":"hi"}]}`)
+		if resp.StopReason != stopReasonEnd || resp.StopSequence != "" {
+			t.Errorf("stop_reason = %q, stop_sequence = %q", resp.StopReason, resp.StopSequence)
+		}
+	})
+
+	t.Run("system blocks", func(t *testing.T) {
+		mu.Lock()
+		opts = nil
+		mu.Unlock()
+		send(t, `{"model":"mock-messages","max_tokens":100,"system":[{"type":"text","text":"Be brief."},{"type":"text","text":"Be kind.","cache_control":{"type":"ephemeral"}}],"messages":[{"role":"user","content":"hi"}]}`)
+		if got := opts[0].SystemPrompt; got != "Be brief.\nBe kind." {
+			t.Errorf("system prompt = %q", got)
+		}
+	})
+
+	t.Run("tool use", func(t *testing.T) {
+		mu.Lock()
+		replies = []string{`{"tool_calls": [{"name": "get_weather", "arguments": {"city": "Paris"}}]}`}
+		prompts = nil
+		mu.Unlock()
+		resp := send(t, `{"model":"mock-messages","max_tokens":100,
+			"tools":[{"name":"get_weather","description":"Get the weather","input_schema":{"type":"object"}}],
+			"messages":[{"role":"user","content":"Weather in Paris?"}]}`)
+		if resp.StopReason != stopReasonToolUse {
+			t.Errorf("stop_reason = %q, want %q", resp.StopReason, stopReasonToolUse)
+		}
+		if len(resp.Content) != 1 {
+			t.Fatalf("content = %+v, want one tool_use block", resp.Content)
+		}
+		use := resp.Content[0]
+		if use.Type != blockTypeToolUse || !strings.HasPrefix(use.ID, "toolu_") || use.Name != "get_weather" || string(use.Input) != `{"city":"Paris"}` {
+			t.Errorf("tool_use = %+v", use)
+		}
+		if !strings.Contains(prompts[0], "get_weather: Get the weather") {
+			t.Errorf("prompt = %q, want the tools declared", prompts[0])
+		}
+	})
+
+	t.Run("tool result", func(t *testing.T) {
+		mu.Lock()
+		prompts = nil
+		mu.Unlock()
+		send(t, `{"model":"mock-messages","max_tokens":100,
+			"tools":[{"name":"get_weather","input_schema":{"type":"object"}}],
+			"messages":[
+				{"role":"user","content":"Weather in Paris?"},
+				{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris"}}]},
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"Sunny"}]}
+			]}`)
+		if !strings.Contains(prompts[0], "[Previous response: [Tool call toolu_1: get_weather({\"city\":\"Paris\"})]]") ||
+			!strings.Contains(prompts[0], "[Tool result toolu_1: Sunny]") {
+			t.Errorf("prompt = %q", prompts[0])
+		}
+	})
+
+	t.Run("count tokens", func(t *testing.T) {
+		rec := serveJobRequest(router, http.MethodPost, "/anthropic/v1/messages/count_tokens",
+			`{"model":"mock-messages","system":[{"type":"text","text":"Be brief."}],"messages":[{"role":"user","content":"How are you today?"}]}`, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		var resp AnthropicCountTokensResponseBody
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Unmarshal() error: %v", err)
+		}
+		if resp.InputTokens != 6 {
+			t.Errorf("input_tokens = %d, want 6", resp.InputTokens)
+		}
+	})
+}
+
+func TestAnthropicHandlers_HandleMessages_StreamStopsAndTools(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	// Each reply streams in pieces, one claude line each
+	var mu sync.Mutex
+	var reply []string
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend(backend.BackendClaude,
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(func(p string, opts *backend.UnifiedOptions) *exec.Cmd {
+			mu.Lock()
+			defer mu.Unlock()
+			args := []string{"%s\n"}
+			for _, text := range reply {
+				line, _ := json.Marshal(map[string]any{
+					"type":    "assistant",
+					"message": map[string]any{"content": []map[string]any{{"type": "text", "text": text}}},
+				})
+				args = append(args, string(line))
+			}
+			return exec.Command("printf", args...)
+		}),
+	)))
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	NewAnthropicHandlers(service.NewStatelessRunner(nil), nil).Register(api)
+
+	// stream returns the text, the tool_use blocks with their input and the
+	// final delta
+	stream := func(t *testing.T, pieces []string, body string) (string, []string, anthropicStreamMessageDeltaData) {
+		t.Helper()
+		mu.Lock()
+		reply = pieces
+		mu.Unlock()
+		rec := serveJobRequest(router, http.MethodPost, "/anthropic/v1/messages", body, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+
+		var text string
+		var uses []string
+		var delta anthropicStreamMessageDeltaData
+		for _, data := range sseData(rec.Body.String()) {
+			var event struct {
+				Type         string          `json:"type"`
+				ContentBlock json.RawMessage `json:"content_block"`
+				Delta        json.RawMessage `json:"delta"`
+			}
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatalf("Unmarshal(%s) error: %v", data, err)
+			}
+			switch event.Type {
+			case "content_block_start":
+				var block AnthropicContentBlock
+				_ = json.Unmarshal(event.ContentBlock, &block)
+				if block.Type == blockTypeToolUse {
+					uses = append(uses, block.Name)
+				}
+			case "content_block_delta":
+				var d struct {
+					Text        string `json:"text"`
+					PartialJSON string `json:"partial_json"`
+				}
+				_ = json.Unmarshal(event.Delta, &d)
+				text += d.Text
+				if d.PartialJSON != "" {
+					uses[len(uses)-1] += " " + d.PartialJSON
+				}
+			case "message_delta":
+				_ = json.Unmarshal(event.Delta, &delta)
+			}
+		}
+		return text, uses, delta
+	}
+
+	t.Run("stop sequence split across pieces", func(t *testing.T) {
+		text, _, delta := stream(t, []string{"Hello E", "ND", " world"},
+			`{"model":"claude","max_tokens":100,"stream":true,"stop_sequences":["END"],"messages":[{"role":"user","content":"hi"}]}`)
+		if text != "Hello " {
+			t.Errorf("text = %q, want %q", text, "Hello ")
+		}
+		if delta.StopReason != stopReasonStopSequence || delta.StopSequence != "END" {
+			t.Errorf("delta = %+v", delta)
+		}
+	})
+
+	t.Run("tool use", func(t *testing.T) {
+		text, uses, delta := stream(t, []string{`{"tool_calls": [{"name": "get_weather",`, ` "arguments": {"city": "Paris"}}]}`},
+			`{"model":"claude","max_tokens":100,"stream":true,
+				"tools":[{"name":"get_weather","input_schema":{"type":"object"}}],
+				"messages":[{"role":"user","content":"Weather in Paris?"}]}`)
+		if text != "" {
+			t.Errorf("text = %q, want none", text)
+		}
+		if len(uses) != 1 || uses[0] != `get_weather {"city": "Paris"}` {
+			t.Errorf("tool_use blocks = %q", uses)
+		}
+		if delta.StopReason != stopReasonToolUse {
+			t.Errorf("stop_reason = %q, want %q", delta.StopReason, stopReasonToolUse)
+		}
+	})
+
+	t.Run("text with tools", func(t *testing.T) {
+		text, uses, delta := stream(t, []string{"It is ", "sunny."},
+			`{"model":"claude","max_tokens":100,"stream":true,
+				"tools":[{"name":"get_weather","input_schema":{"type":"object"}}],
+				"messages":[{"role":"user","content":"Weather in Paris?"}]}`)
+		if text != "It is sunny." || len(uses) != 0 || delta.StopReason != stopReasonEnd {
+			t.Errorf("text = %q, tool_use blocks = %q, delta = %+v", text, uses, delta)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const blockTypeToolResult = "tool_result"
+
+// Anthropic tool choices.
+const (
+	anthropicToolChoiceAuto = "auto"
+	anthropicToolChoiceAny  = "any"
+	anthropicToolChoiceTool = "tool"
+	anthropicToolChoiceNone = "none"
+)
+
+// AnthropicTool is a tool the model may call.
+type AnthropicTool struct {
+	Type        string         `json:"type,omitempty" enum:"custom" doc:"Tool type; only custom tools are supported"`
+	Name        string         `json:"name" minLength:"1" doc:"Tool name"`
+	Description string         `json:"description,omitempty" doc:"What the tool does"`
+	InputSchema map[string]any `json:"input_schema" doc:"JSON Schema of the tool input"`
+	// CacheControl is accepted for compatibility; prompts are not cached.
+	CacheControl map[string]any `json:"cache_control,omitempty" doc:"Accepted for compatibility"`
+}
+
+// AnthropicToolChoice is how the model may call tools.
+type AnthropicToolChoice struct {
+	Type string `json:"type" enum:"auto,any,tool,none" doc:"Whether the model may (auto), must (any, tool) or must not (none) call tools"`
+	Name string `json:"name,omitempty" doc:"Tool the model must call, for the tool type"`
+	// DisableParallelToolUse is accepted for compatibility; several tools
+	// may always be called at once.
+	DisableParallelToolUse bool `json:"disable_parallel_tool_use,omitempty" doc:"Accepted for compatibility"`
+}
+
+// newAnthropicToolSet validates the tools and tool choice of a request. The
+// tools are called by the same contract as on the OpenAI endpoint. It
+// returns nil when no tool may be called.
+func newAnthropicToolSet(tools []AnthropicTool, choice *AnthropicToolChoice) (*toolSet, error) {
+	functions := make([]OpenAITool, len(tools))
+	for i, t := range tools {
+		functions[i] = OpenAITool{
+			Type: toolTypeFunction,
+			Function: OpenAIFunction{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.InputSchema,
+			},
+		}
+	}
+
+	var toolChoice any
+	if choice != nil {
+		switch choice.Type {
+		case anthropicToolChoiceAuto:
+			toolChoice = toolChoiceAuto
+		case anthropicToolChoiceAny:
+			toolChoice = toolChoiceRequired
+		case anthropicToolChoiceNone:
+			toolChoice = toolChoiceNone
+		case anthropicToolChoiceTool:
+			if choice.Name == "" {
+				return nil, huma.Error400BadRequest("tool_choice of type tool requires a name")
+			}
+			toolChoice = map[string]any{"type": toolTypeFunction, "function": map[string]any{"name": choice.Name}}
+		}
+	}
+	return newToolSet(functions, toolChoice)
+}
+
+// anthropicToolUseBlocks returns the tool_use blocks of tool calls.
+func anthropicToolUseBlocks(calls []OpenAIToolCall) []AnthropicContentBlock {
+	blocks := make([]AnthropicContentBlock, len(calls))
+	for i, c := range calls {
+		input := json.RawMessage(c.Function.Arguments)
+		if !json.Valid(input) {
+			input = json.RawMessage("{}")
+		}
+		blocks[i] = AnthropicContentBlock{
+			Type:  blockTypeToolUse,
+			ID:    "toolu_" + strings.TrimPrefix(c.ID, "call_"),
+			Name:  c.Function.Name,
+			Input: input,
+		}
+	}
+	return blocks
+}
+
+// renderAnthropicToolUses renders tool_use blocks as text, as they are
+// rendered when sent back in a request, to match conversations.
+func renderAnthropicToolUses(blocks []AnthropicContentBlock) string {
+	lines := make([]string, len(blocks))
+	for i, b := range blocks {
+		lines[i] = renderAnthropicToolUse(b.ID, b.Name, b.Input)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderAnthropicToolUse renders a tool call as text, as on the OpenAI
+// endpoint.
+func renderAnthropicToolUse(id, name string, input json.RawMessage) string {
+	args := "{}"
+	if len(input) > 0 && string(input) != "null" {
+		args = string(input)
+	}
+	return fmt.Sprintf("[Tool call %s: %s(%s)]", id, name, args)
+}
+
+// renderAnthropicToolResult renders a tool_result block as text. Its
+// content is a string or a list of content blocks.
+func (a *attachments) renderAnthropicToolResult(b AnthropicInputBlock) (string, error) {
+	var content string
+	switch {
+	case len(b.Content) == 0 || string(b.Content) == "null":
+	case b.Content[0] == '[':
+		var blocks []AnthropicInputBlock
+		if err := json.Unmarshal(b.Content, &blocks); err != nil {
+			return "", fmt.Errorf("invalid tool_result content: %w", err)
+		}
+		rendered, err := a.renderAnthropicBlocks(blocks)
+		if err != nil {
+			return "", err
+		}
+		content = rendered
+	default:
+		if err := json.Unmarshal(b.Content, &content); err != nil {
+			return "", fmt.Errorf("invalid tool_result content: %w", err)
+		}
+	}
+
+	if b.IsError {
+		return fmt.Sprintf("[Tool error %s: %s]", b.ToolUseID, content), nil
+	}
+	return fmt.Sprintf("[Tool result %s: %s]", b.ToolUseID, content), nil
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+// Kinds of attachments, as named in prompts.
+const (
+	attachmentImage    = "Image"
+	attachmentDocument = "Document"
+)
+
+// attachmentExtensions are the file extensions of common attachment types.
+var attachmentExtensions = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+	"text/markdown":   ".md",
+	"text/csv":        ".csv",
+}
+
+// attachments collects the images and documents sent in the content of a
+// request. CLI backends only take text prompts, so the files are written to
+// a directory the backend can read and referenced in the prompt by name.
+type attachments struct {
+	names []string
+	files map[string][]byte
+}
+
+// add records a file and returns its reference for the prompt. Files are
+// named by a hash of their content, so a file keeps its name across the
+// requests of a conversation.
+func (a *attachments) add(kind, mediaType string, data []byte) string {
+	sum := sha256.Sum256(data)
+	name := strings.ToLower(kind) + "-" + hex.EncodeToString(sum[:8]) + attachmentExtension(mediaType)
+	if a.files == nil {
+		a.files = make(map[string][]byte)
+	}
+	if _, ok := a.files[name]; !ok {
+		a.files[name] = data
+		a.names = append(a.names, name)
+	}
+	return fmt.Sprintf("[%s: %s]", kind, name)
+}
+
+// addBase64 records a base64-encoded file.
+func (a *attachments) addBase64(kind, mediaType, data string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 data for %s", strings.ToLower(kind))
+	}
+	return a.add(kind, mediaType, decoded), nil
+}
+
+// addURL records the file of a data URL. Other URLs are not fetched by the
+// server; they are passed on in the prompt for the backend to open.
+func (a *attachments) addURL(kind, url string) (string, error) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return fmt.Sprintf("[%s: %s]", kind, url), nil
+	}
+	meta, data, ok := strings.Cut(rest, ",")
+	mediaType, isBase64 := strings.CutSuffix(meta, ";base64")
+	if !ok || !isBase64 {
+		return "", fmt.Errorf("%s data URL must be base64-encoded", strings.ToLower(kind))
+	}
+	return a.addBase64(kind, mediaType, data)
+}
+
+// attachTo writes the attachments to a new directory under the working
+// directory of req, lets the backend read it and tells the model where the
+// files are. The returned function removes the directory once the request
+// has run.
+func (a *attachments) attachTo(req *service.PromptRequest) (func(), error) {
+	if len(a.names) == 0 {
+		return func() {}, nil
+	}
+
+	workDir := req.WorkDir
+	if workDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to store attachments", err)
+		}
+		workDir = wd
+	}
+	dir, err := os.MkdirTemp(workDir, ".clinvk-attachments-")
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to store attachments", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+
+	for _, name := range a.names {
+		if err := os.WriteFile(filepath.Join(dir, name), a.files[name], 0o600); err != nil {
+			cleanup()
+			return nil, huma.Error500InternalServerError("failed to store attachments", err)
+		}
+	}
+
+	req.AllowedDirs = append(req.AllowedDirs, dir)
+	req.Prompt += fmt.Sprintf("\n\nThe attached files referenced above are in %s: %s. Read them from there.", dir, strings.Join(a.names, ", "))
+	return cleanup, nil
+}
+
+// attachmentExtension returns the file extension for a media type.
+func attachmentExtension(mediaType string) string {
+	if ext, ok := attachmentExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// renderOpenAIParts renders the content parts of an OpenAI message as text,
+// recording its images and files.
+func (a *attachments) renderOpenAIParts(parts []OpenAIContentPart) (string, error) {
+	texts := make([]string, 0, len(parts))
+	for i, p := range parts {
+		var text string
+		var err error
+		switch p.Type {
+		case "text":
+			text = p.Text
+		case "image_url":
+			if p.ImageURL == nil || p.ImageURL.URL == "" {
+				return "", huma.Error400BadRequest(fmt.Sprintf("content[%d]: image_url requires a url", i))
+			}
+			text, err = a.addURL(attachmentImage, p.ImageURL.URL)
+		case "file":
+			switch {
+			case p.File == nil || p.File.FileData == "":
+				return "", huma.Error400BadRequest(fmt.Sprintf("content[%d]: file requires file_data; file IDs are not supported", i))
+			case strings.HasPrefix(p.File.FileData, "data:"):
+				text, err = a.addURL(attachmentDocument, p.File.FileData)
+			default:
+				text, err = a.addBase64(attachmentDocument, mime.TypeByExtension(filepath.Ext(p.File.Filename)), p.File.FileData)
+			}
+		default:
+			return "", huma.Error400BadRequest(fmt.Sprintf("content[%d]: unsupported content part type %q", i, p.Type))
+		}
+		if err != nil {
+			return "", huma.Error400BadRequest(fmt.Sprintf("content[%d]: %v", i, err))
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// renderAnthropicBlocks renders the content blocks of an Anthropic message
+// as text, recording its images and documents. Tool calls and results are
+// rendered as on the OpenAI endpoint.
+func (a *attachments) renderAnthropicBlocks(blocks []AnthropicInputBlock) (string, error) {
+	texts := make([]string, 0, len(blocks))
+	for i, b := range blocks {
+		var text string
+		var err error
+		switch b.Type {
+		case "text":
+			text = b.Text
+		case blockTypeThinking, "redacted_thinking":
+			// Thinking sent back with a reply is not part of the prompt
+			continue
+		case blockTypeToolUse:
+			text = renderAnthropicToolUse(b.ID, b.Name, b.Input)
+		case blockTypeToolResult:
+			text, err = a.renderAnthropicToolResult(b)
+		case "image", "document":
+			kind := attachmentImage
+			if b.Type == "document" {
+				kind = attachmentDocument
+			}
+			if b.Source == nil {
+				return "", huma.Error400BadRequest(fmt.Sprintf("content[%d]: %s requires a source", i, b.Type))
+			}
+			switch b.Source.Type {
+			case "base64":
+				text, err = a.addBase64(kind, b.Source.MediaType, b.Source.Data)
+			case "text":
+				text = a.add(kind, "text/plain", []byte(b.Source.Data))
+			case "url":
+				text, err = a.addURL(kind, b.Source.URL)
+			default:
+				return "", huma.Error400BadRequest(fmt.Sprintf("content[%d]: unsupported source type %q", i, b.Source.Type))
+			}
+			if b.Title != "" && err == nil {
+				text = fmt.Sprintf("%s (%s)", text, b.Title)
+			}
+		default:
+			return "", huma.Error400BadRequest(fmt.Sprintf("content[%d]: unsupported content block type %q", i, b.Type))
+		}
+		if err != nil {
+			return "", huma.Error400BadRequest(fmt.Sprintf("content[%d]: %v", i, err))
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// renderGeminiParts renders the parts of a Gemini message as text,
+// recording its inline data and files. Thoughts are not sent back.
+func (a *attachments) renderGeminiParts(parts []GeminiPart) (string, error) {
+	texts := make([]string, 0, len(parts))
+	for i, p := range parts {
+		var text string
+		var err error
+		switch {
+		case p.Thought:
+			continue
+		case p.InlineData != nil:
+			text, err = a.addBase64(geminiAttachmentKind(p.InlineData.MimeType), p.InlineData.MimeType, p.InlineData.Data)
+		case p.FileData != nil:
+			if p.FileData.FileURI == "" {
+				return "", huma.Error400BadRequest(fmt.Sprintf("parts[%d]: fileData requires a fileUri", i))
+			}
+			text, err = a.addURL(geminiAttachmentKind(p.FileData.MimeType), p.FileData.FileURI)
+		default:
+			text = p.Text
+		}
+		if err != nil {
+			return "", huma.Error400BadRequest(fmt.Sprintf("parts[%d]: %v", i, err))
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// geminiAttachmentKind returns the kind of a Gemini file of mediaType.
+func geminiAttachmentKind(mediaType string) string {
+	if strings.HasPrefix(mediaType, "image/") {
+		return attachmentImage
+	}
+	return attachmentDocument
+}
+
+// contentSchema is the schema of message content that is a string or a
+// list of content parts.
+func contentSchema(description string) *huma.Schema {
+	return &huma.Schema{
+		Description: description,
+		OneOf: []*huma.Schema{
+			{Type: huma.TypeString, Nullable: true},
+			{Type: huma.TypeArray, Items: &huma.Schema{Type: huma.TypeObject}},
+		},
+	}
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+var (
+	pngData    = base64.StdEncoding.EncodeToString([]byte("\x89PNG fake image"))
+	pdfData    = base64.StdEncoding.EncodeToString([]byte("%PDF-1.7 fake document"))
+	pngDataURL = "data:image/png;base64," + pngData
+)
+
+func TestAttachments_RenderOpenAIParts(t *testing.T) {
+	tests := []struct {
+		name    string
+		parts   []OpenAIContentPart
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "text and image",
+			parts: []OpenAIContentPart{
+				{Type: "text", Text: "What is this?"},
+				{Type: "image_url", ImageURL: &OpenAIImageURL{URL: pngDataURL}},
+			},
+			want: `^What is this\?\n\[Image: image-[0-9a-f]{16}\.png\]$`,
+		},
+		{
+			name:  "linked image",
+			parts: []OpenAIContentPart{{Type: "image_url", ImageURL: &OpenAIImageURL{URL: "https://example.com/cat.png"}}},
+			want:  `^\[Image: https://example\.com/cat\.png\]$`,
+		},
+		{
+			name:  "file data URL",
+			parts: []OpenAIContentPart{{Type: "file", File: &OpenAIFile{FileData: "data:application/pdf;base64," + pdfData}}},
+			want:  `^\[Document: document-[0-9a-f]{16}\.pdf\]$`,
+		},
+		{
+			name:  "file named by filename",
+			parts: []OpenAIContentPart{{Type: "file", File: &OpenAIFile{FileData: pdfData, Filename: "report.pdf"}}},
+			want:  `^\[Document: document-[0-9a-f]{16}\.pdf\]$`,
+		},
+		{name: "file ID", parts: []OpenAIContentPart{{Type: "file", File: &OpenAIFile{FileID: "file-1"}}}, wantErr: true},
+		{name: "invalid base64", parts: []OpenAIContentPart{{Type: "image_url", ImageURL: &OpenAIImageURL{URL: "data:image/png;base64,%%%"}}}, wantErr: true},
+		{name: "data URL without base64", parts: []OpenAIContentPart{{Type: "image_url", ImageURL: &OpenAIImageURL{URL: "data:text/plain,hello"}}}, wantErr: true},
+		{name: "unsupported part", parts: []OpenAIContentPart{{Type: "input_audio"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var files attachments
+			got, err := files.renderOpenAIParts(tt.parts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderOpenAIParts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("renderOpenAIParts() = %q, want match %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachments_RenderAnthropicBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		blocks  []AnthropicInputBlock
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "text and image",
+			blocks: []AnthropicInputBlock{
+				{Type: "text", Text: "Describe"},
+				{Type: "image", Source: &AnthropicSource{Type: "base64", MediaType: "image/png", Data: pngData}},
+			},
+			want: `^Describe\n\[Image: image-[0-9a-f]{16}\.png\]$`,
+		},
+		{
+			name:   "titled PDF",
+			blocks: []AnthropicInputBlock{{Type: "document", Title: "Q3", Source: &AnthropicSource{Type: "base64", MediaType: "application/pdf", Data: pdfData}}},
+			want:   `^\[Document: document-[0-9a-f]{16}\.pdf\] \(Q3\)$`,
+		},
+		{
+			name:   "plain text document",
+			blocks: []AnthropicInputBlock{{Type: "document", Source: &AnthropicSource{Type: "text", MediaType: "text/plain", Data: "notes"}}},
+			want:   `^\[Document: document-[0-9a-f]{16}\.txt\]$`,
+		},
+		{
+			name:   "linked image",
+			blocks: []AnthropicInputBlock{{Type: "image", Source: &AnthropicSource{Type: "url", URL: "https://example.com/cat.png"}}},
+			want:   `^\[Image: https://example\.com/cat\.png\]$`,
+		},
+		{name: "missing source", blocks: []AnthropicInputBlock{{Type: "image"}}, wantErr: true},
+		{name: "unsupported source", blocks: []AnthropicInputBlock{{Type: "document", Source: &AnthropicSource{Type: "file"}}}, wantErr: true},
+		{
+			name: "tool use and result",
+			blocks: []AnthropicInputBlock{
+				{Type: "thinking", Text: "Let me look."},
+				{Type: "tool_use", ID: "toolu_1", Name: "get_weather", Input: json.RawMessage(`{"city":"Paris"}`)},
+				{Type: "tool_result", ToolUseID: "toolu_1", Content: json.RawMessage(`"Sunny"`)},
+				{Type: "tool_result", ToolUseID: "toolu_2", IsError: true, Content: json.RawMessage(`[{"type":"text","text":"Not found"}]`)},
+			},
+			want: `^\[Tool call toolu_1: get_weather\(\{"city":"Paris"\}\)\]\n\[Tool result toolu_1: Sunny\]\n\[Tool error toolu_2: Not found\]$`,
+		},
+		{name: "unsupported block", blocks: []AnthropicInputBlock{{Type: "server_tool_use"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var files attachments
+			got, err := files.renderAnthropicBlocks(tt.blocks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderAnthropicBlocks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("renderAnthropicBlocks() = %q, want match %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachments_RenderGeminiParts(t *testing.T) {
+	tests := []struct {
+		name    string
+		parts   []GeminiPart
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "text and inline image",
+			parts: []GeminiPart{
+				{Text: "Describe"},
+				{InlineData: &GeminiBlob{MimeType: "image/png", Data: pngData}},
+			},
+			want: `^Describe\n\[Image: image-[0-9a-f]{16}\.png\]$`,
+		},
+		{
+			name:  "inline PDF",
+			parts: []GeminiPart{{InlineData: &GeminiBlob{MimeType: "application/pdf", Data: pdfData}}},
+			want:  `^\[Document: document-[0-9a-f]{16}\.pdf\]$`,
+		},
+		{
+			name:  "linked file",
+			parts: []GeminiPart{{FileData: &GeminiFileData{MimeType: "image/png", FileURI: "https://example.com/cat.png"}}},
+			want:  `^\[Image: https://example\.com/cat\.png\]$`,
+		},
+		{
+			name:  "thoughts are skipped",
+			parts: []GeminiPart{{Text: "Hmm.", Thought: true}, {Text: "Answer"}},
+			want:  `^Answer$`,
+		},
+		{name: "invalid base64", parts: []GeminiPart{{InlineData: &GeminiBlob{MimeType: "image/png", Data: "%%%"}}}, wantErr: true},
+		{name: "missing file URI", parts: []GeminiPart{{FileData: &GeminiFileData{MimeType: "image/png"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var files attachments
+			got, err := files.renderGeminiParts(tt.parts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderGeminiParts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("renderGeminiParts() = %q, want match %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachments_AttachTo(t *testing.T) {
+	t.Run("no attachments", func(t *testing.T) {
+		var files attachments
+		req := &service.PromptRequest{Prompt: "hi"}
+		cleanup, err := files.attachTo(req)
+		if err != nil {
+			t.Fatalf("attachTo() error: %v", err)
+		}
+		cleanup()
+		if req.Prompt != "hi" || req.AllowedDirs != nil {
+			t.Errorf("request changed: %+v", req)
+		}
+	})
+
+	t.Run("writes files under the working directory", func(t *testing.T) {
+		var files attachments
+		ref := files.add(attachmentImage, "image/png", []byte("image"))
+		files.add(attachmentImage, "image/png", []byte("image"))
+
+		workDir := t.TempDir()
+		req := &service.PromptRequest{Prompt: ref, WorkDir: workDir}
+		cleanup, err := files.attachTo(req)
+		if err != nil {
+			t.Fatalf("attachTo() error: %v", err)
+		}
+
+		if len(req.AllowedDirs) != 1 || filepath.Dir(req.AllowedDirs[0]) != workDir {
+			t.Fatalf("AllowedDirs = %v, want a directory under %s", req.AllowedDirs, workDir)
+		}
+		dir := req.AllowedDirs[0]
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("ReadDir() = %v, %v, want one file", entries, err)
+		}
+		if !strings.Contains(req.Prompt, dir) || !strings.Contains(req.Prompt, entries[0].Name()) {
+			t.Errorf("prompt does not locate the file:\n%s", req.Prompt)
+		}
+
+		cleanup()
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed, got %v", dir, err)
+		}
+	})
+}
+
+func TestContentParts_EndToEnd(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+	t.Chdir(t.TempDir())
+
+	// The backend records the prompt and the attachments it can read
+	var prompt string
+	var attached []string
+	var dirs []string
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-vision",
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(func(p string, opts *backend.UnifiedOptions) *exec.Cmd {
+			prompt = p
+			dirs = opts.AllowedDirs
+			attached = nil
+			for _, dir := range opts.AllowedDirs {
+				entries, _ := os.ReadDir(dir)
+				for _, e := range entries {
+					attached = append(attached, e.Name())
+				}
+			}
+			return exec.Command("echo", "a cat")
+		}),
+	)))
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	NewOpenAIHandlers(service.NewStatelessRunner(nil), nil).Register(api)
+	NewAnthropicHandlers(service.NewStatelessRunner(nil), nil).Register(api)
+
+	tests := []struct {
+		name string
+		path string
+		body map[string]any
+	}{
+		{
+			name: "openai",
+			path: "/openai/v1/chat/completions",
+			body: map[string]any{
+				"model": "mock-vision",
+				"messages": []map[string]any{{"role": "user", "content": []map[string]any{
+					{"type": "text", "text": "What is this?"},
+					{"type": "image_url", "image_url": map[string]any{"url": pngDataURL}},
+				}}},
+			},
+		},
+		{
+			name: "anthropic",
+			path: "/anthropic/v1/messages",
+			body: map[string]any{
+				"model":      "mock-vision",
+				"max_tokens": 100,
+				"messages": []map[string]any{{"role": "user", "content": []map[string]any{
+					{"type": "text", "text": "What is this?"},
+					{"type": "image", "source": map[string]any{"type": "base64", "media_type": "image/png", "data": pngData}},
+				}}},
+			},
+		},
+		{
+			name: "responses",
+			path: "/openai/v1/responses",
+			body: map[string]any{
+				"model": "mock-vision",
+				"input": []map[string]any{{"role": "user", "content": []map[string]any{
+					{"type": "input_text", "text": "What is this?"},
+					{"type": "input_image", "image_url": pngDataURL},
+				}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatalf("Marshal() error: %v", err)
+			}
+			rec := serveJobRequest(router, http.MethodPost, tt.path, string(body), nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+
+			if len(attached) != 1 || !strings.Contains(prompt, "[Image: "+attached[0]+"]") {
+				t.Errorf("attached %v, prompt:\n%s", attached, prompt)
+			}
+			if !strings.HasPrefix(prompt, "What is this?") {
+				t.Errorf("prompt = %q", prompt)
+			}
+			for _, dir := range dirs {
+				if _, err := os.Stat(dir); !os.IsNotExist(err) {
+					t.Errorf("expected %s to be removed after the request, got %v", dir, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+const (
+	// sessionIDHeader carries the session of a stateful conversation in
+	// responses of the OpenAI- and Anthropic-compatible endpoints.
+	sessionIDHeader = "X-Clinvk-Session-Id"
+
+	// statefulUserPrefix opts a conversation into stateful mode through the
+	// user identifier of the request, for clients that cannot set headers.
+	statefulUserPrefix = "stateful:"
+)
+
+// statefulRequested reports whether a request asked for a stateful
+// conversation, by header or by its user identifier.
+func statefulRequested(header bool, user string) bool {
+	return header || strings.HasPrefix(user, statefulUserPrefix)
+}
+
+// conversationScope separates the conversations of different APIs, users,
+// backends, models and system prompts that may share a message history.
+func conversationScope(api, user, backendName, model, systemPrompt string) string {
+	return strings.Join([]string{api, user, backendName, model, systemPrompt}, "\x00")
+}
+
+// nextConversationTurn resolves the next turn of a stateful conversation.
+// When a session continues the conversation, req is pointed at it and only
+// the newest user message is sent. It returns nil when the conversation
+// cannot be stateful, e.g. because it does not end with a user message.
+func nextConversationTurn(conversations *service.Conversations, scope string, messages []service.ConversationMessage, req *service.PromptRequest) *service.ConversationTurn {
+	if conversations == nil {
+		return nil
+	}
+	turn, ok := conversations.Next(scope, messages)
+	if !ok {
+		return nil
+	}
+	if turn.SessionID != "" {
+		req.SessionID = turn.SessionID
+		req.Backend = turn.Backend
+		req.Prompt = turn.Prompt
+	}
+	return turn
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/session"
+)
+
+func TestStatefulConversations(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-chat",
+		mock.WithAvailable(true),
+		mock.WithCapabilities(backend.Capabilities{Resume: true}),
+		mock.WithJSONResponse(&backend.UnifiedResponse{Content: "hi there", SessionID: "backend-1"}),
+	)))
+
+	store := session.NewStoreWithDir(t.TempDir())
+	conversations := service.NewConversations(store, nil, 0)
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	openai := NewOpenAIHandlers(service.NewStatelessRunner(nil), nil)
+	openai.SetConversations(conversations)
+	openai.Register(api)
+	anthropic := NewAnthropicHandlers(service.NewStatelessRunner(nil), nil)
+	anthropic.SetConversations(conversations)
+	anthropic.Register(api)
+
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	first := []message{{Role: "user", Content: "hello"}}
+	second := []message{{Role: "user", Content: "hello"}, {Role: "assistant", Content: "hi there"}, {Role: "user", Content: "and now?"}}
+
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		body   func(messages []message) map[string]any
+	}{
+		{
+			name:   "openai by header",
+			path:   "/openai/v1/chat/completions",
+			header: http.Header{"X-Clinvk-Stateful": {"true"}},
+			body: func(messages []message) map[string]any {
+				return map[string]any{"model": "mock-chat", "messages": messages}
+			},
+		},
+		{
+			name: "openai by user",
+			path: "/openai/v1/chat/completions",
+			body: func(messages []message) map[string]any {
+				return map[string]any{"model": "mock-chat", "messages": messages, "user": "stateful:alice"}
+			},
+		},
+		{
+			name: "anthropic by user",
+			path: "/anthropic/v1/messages",
+			body: func(messages []message) map[string]any {
+				return map[string]any{"model": "mock-chat", "max_tokens": 100, "messages": messages, "metadata": map[string]string{"user_id": "stateful:alice"}}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := func(messages []message) string {
+				t.Helper()
+				body, err := json.Marshal(tt.body(messages))
+				if err != nil {
+					t.Fatalf("Marshal() error: %v", err)
+				}
+				rec := serveJobRequest(router, http.MethodPost, tt.path, string(body), tt.header)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+				}
+				return rec.Header().Get(sessionIDHeader)
+			}
+
+			sessionID := post(first)
+			if sessionID == "" {
+				t.Fatal("expected a session for the conversation")
+			}
+			if next := post(second); next != sessionID {
+				t.Fatalf("expected the conversation to continue session %s, got %q", sessionID, next)
+			}
+
+			sess, err := store.Get(sessionID)
+			if err != nil {
+				t.Fatalf("Get() error: %v", err)
+			}
+			if sess.TurnCount != 2 {
+				t.Errorf("TurnCount = %d, want 2", sess.TurnCount)
+			}
+
+			// The continued history is claimed: a retry starts over
+			if next := post(second); next == sessionID {
+				t.Error("expected a replayed history to start a new session")
+			}
+		})
+	}
+
+	t.Run("stateless by default", func(t *testing.T) {
+		body := `{"model":"mock-chat","messages":[{"role":"user","content":"hello"}]}`
+		rec := serveJobRequest(router, http.MethodPost, "/openai/v1/chat/completions", body, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		if id := rec.Header().Get(sessionIDHeader); id != "" {
+			t.Errorf("expected no session, got %q", id)
+		}
+	})
+}
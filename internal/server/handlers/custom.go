@@ -14,7 +14,9 @@ import (
 	"github.com/signalridge/clinvoker/internal/config"
 	"github.com/signalridge/clinvoker/internal/output"
 	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/structured"
 	"github.com/signalridge/clinvoker/internal/util"
+	"github.com/signalridge/clinvoker/internal/webhook"
 )
 
 // HealthInfo contains information for health check responses.
@@ -27,6 +29,7 @@ type HealthInfo struct {
 type CustomHandlers struct {
 	executor   *service.Executor
 	healthInfo HealthInfo
+	webhooks   *webhook.Dispatcher
 }
 
 // NewCustomHandlers creates a new custom handlers instance.
@@ -39,6 +42,27 @@ func NewCustomHandlersWithHealthInfo(executor *service.Executor, healthInfo Heal
 	return &CustomHandlers{executor: executor, healthInfo: healthInfo}
 }
 
+// SetWebhooks sets the dispatcher that delivers results to the requests'
+// callback URLs. Without one, callback URLs are ignored.
+func (h *CustomHandlers) SetWebhooks(d *webhook.Dispatcher) {
+	h.webhooks = d
+}
+
+// notify delivers the outcome of a request to its callback URL, as a
+// "<kind>.completed" event with the response body or, when execution
+// failed, a "<kind>.failed" event with the error.
+func (h *CustomHandlers) notify(callbackURL, kind, subject string, result any, err error) {
+	if h.webhooks == nil || callbackURL == "" {
+		return
+	}
+	event, data := kind+".completed", result
+	if err != nil {
+		event, data = kind+".failed", CallbackError{Error: err.Error()}
+	}
+	// Send fails only once the server is shutting down.
+	_, _ = h.webhooks.Send(callbackURL, event, subject, data)
+}
+
 // Register registers all custom API routes.
 func (h *CustomHandlers) Register(api huma.API) {
 	// Prompt endpoint
@@ -110,6 +134,15 @@ func (h *CustomHandlers) Register(api huma.API) {
 		Tags:        []string{"Custom API"},
 	}, h.HandleGetSession)
 
+	huma.Register(api, huma.Operation{
+		OperationID: "getSessionMessages",
+		Method:      http.MethodGet,
+		Path:        "/api/v1/sessions/{id}/messages",
+		Summary:     "Get session transcript",
+		Description: "Get the prompt, response, tool calls and token usage of every recorded turn of a session",
+		Tags:        []string{"Custom API"},
+	}, h.HandleGetSessionMessages)
+
 	huma.Register(api, huma.Operation{
 		OperationID: "deleteSession",
 		Method:      http.MethodDelete,
@@ -119,6 +152,16 @@ func (h *CustomHandlers) Register(api huma.API) {
 		Tags:        []string{"Custom API"},
 	}, h.HandleDeleteSession)
 
+	// Admin endpoints
+	huma.Register(api, huma.Operation{
+		OperationID: "resetCircuitBreakers",
+		Method:      http.MethodPost,
+		Path:        "/api/v1/admin/circuit-breakers/reset",
+		Summary:     "Reset circuit breakers",
+		Description: "Close the circuit of one backend, or of all backends",
+		Tags:        []string{"Admin"},
+	}, h.HandleResetCircuitBreakers)
+
 	// Health endpoint
 	huma.Register(api, huma.Operation{
 		OperationID: "healthCheck",
@@ -137,29 +180,35 @@ type PromptInput struct {
 
 // HandlePrompt handles prompt execution requests.
 func (h *CustomHandlers) HandlePrompt(ctx context.Context, input *PromptInput) (*huma.StreamResponse, error) {
-	if input.Body.Backend == "" {
-		return nil, huma.Error400BadRequest("backend is required")
-	}
-	if input.Body.Prompt == "" {
-		return nil, huma.Error400BadRequest("prompt is required")
+	if err := validatePromptRequest(&input.Body); err != nil {
+		return nil, err
 	}
 
 	cfg := config.Get()
 	requestedFormat := backend.OutputFormat(util.ApplyOutputFormatDefault(input.Body.OutputFormat, cfg))
 
+	if requestedFormat == backend.OutputStreamJSON && input.Body.JSONSchema != nil {
+		return nil, huma.Error400BadRequest("json_schema is not supported with stream-json output")
+	}
+
 	if requestedFormat == backend.OutputStreamJSON {
 		streamReq := input.Body.ToServiceRequest()
+		callbackURL := input.Body.CallbackURL
 		return &huma.StreamResponse{
 			Body: func(hctx huma.Context) {
-				hctx.SetStatus(http.StatusOK)
-				hctx.SetHeader("Content-Type", "application/x-ndjson")
-				hctx.SetHeader("Cache-Control", "no-cache")
+				start := time.Now()
+				stream := &streamStart{start: func() {
+					hctx.SetStatus(http.StatusOK)
+					hctx.SetHeader("Content-Type", "application/x-ndjson")
+					hctx.SetHeader("Cache-Control", "no-cache")
+				}}
 
 				writer := output.NewWriter(hctx.BodyWriter(), output.WithFormat(output.FormatJSON))
 				flusher, _ := hctx.BodyWriter().(http.Flusher)
 				sawErrorEvent := false
 
 				streamResult, streamErr := h.executor.StreamPrompt(hctx.Context(), streamReq, func(event *output.UnifiedEvent) error {
+					stream.begin()
 					if event.Type == output.EventError {
 						sawErrorEvent = true
 					}
@@ -172,6 +221,17 @@ func (h *CustomHandlers) HandlePrompt(ctx context.Context, input *PromptInput) (
 					return nil
 				})
 
+				if streamResult != nil {
+					h.notify(callbackURL, "prompt", "", FromStreamResult(streamResult, time.Since(start)), nil)
+				} else {
+					h.notify(callbackURL, "prompt", "", nil, streamErr)
+				}
+
+				if !stream.started && writeConcurrencyRejection(hctx, streamErr) {
+					return
+				}
+				stream.begin()
+
 				if (streamErr != nil || (streamResult != nil && streamResult.Error != "")) && !sawErrorEvent {
 					errMsg := "stream failed"
 					if streamErr != nil {
@@ -180,7 +240,11 @@ func (h *CustomHandlers) HandlePrompt(ctx context.Context, input *PromptInput) (
 						errMsg = streamResult.Error
 					}
 
-					errEvent := output.NewUnifiedEvent(output.EventError, streamReq.Backend, "")
+					backendName := streamReq.Backend
+					if streamResult != nil && streamResult.Backend != "" {
+						backendName = streamResult.Backend
+					}
+					errEvent := output.NewUnifiedEvent(output.EventError, backendName, "")
 					if err := errEvent.SetContent(&output.ErrorContent{Message: errMsg}); err == nil {
 						_ = writer.WriteEvent(errEvent)
 						if flusher != nil {
@@ -194,10 +258,12 @@ func (h *CustomHandlers) HandlePrompt(ctx context.Context, input *PromptInput) (
 
 	result, err := h.executor.ExecutePrompt(ctx, input.Body.ToServiceRequest())
 	if err != nil {
-		return nil, huma.Error500InternalServerError("execution failed", err)
+		h.notify(input.Body.CallbackURL, "prompt", "", nil, err)
+		return nil, executionError("execution failed", err)
 	}
 
 	payload := FromServiceResult(result)
+	h.notify(input.Body.CallbackURL, "prompt", payload.SessionID, payload, nil)
 	return &huma.StreamResponse{
 		Body: func(hctx huma.Context) {
 			hctx.SetStatus(http.StatusOK)
@@ -214,65 +280,19 @@ type ParallelInput struct {
 
 // HandleParallel handles parallel execution requests.
 func (h *CustomHandlers) HandleParallel(ctx context.Context, input *ParallelInput) (*ParallelResponse, error) {
-	if len(input.Body.Tasks) == 0 {
-		return nil, huma.Error400BadRequest("tasks are required")
-	}
-
-	// Convert to service request
-	serviceReq := &service.ParallelRequest{
-		MaxParallel: input.Body.MaxParallel,
-		FailFast:    input.Body.FailFast,
-		DryRun:      input.Body.DryRun,
-		Tasks:       make([]service.PromptRequest, len(input.Body.Tasks)),
-	}
-
-	for i, t := range input.Body.Tasks {
-		serviceReq.Tasks[i] = service.PromptRequest{
-			Backend:      t.Backend,
-			Prompt:       t.Prompt,
-			Model:        t.Model,
-			WorkDir:      t.WorkDir,
-			ApprovalMode: t.ApprovalMode,
-			SandboxMode:  t.SandboxMode,
-			OutputFormat: t.OutputFormat,
-			MaxTokens:    t.MaxTokens,
-			MaxTurns:     t.MaxTurns,
-			SystemPrompt: t.SystemPrompt,
-			Verbose:      t.Verbose,
-			Ephemeral:    t.Ephemeral,
-			Extra:        t.Extra,
-			Metadata:     t.Metadata,
-		}
+	if err := validateParallelRequest(&input.Body); err != nil {
+		return nil, err
 	}
 
-	result, err := h.executor.ExecuteParallel(ctx, serviceReq)
+	result, err := h.executor.ExecuteParallel(ctx, input.Body.ToServiceRequest())
 	if err != nil {
+		h.notify(input.Body.CallbackURL, "parallel", "", nil, err)
 		return nil, huma.Error500InternalServerError("parallel execution failed", err)
 	}
 
-	// Convert results
-	results := make([]PromptResponseBody, len(result.Results))
-	for i, r := range result.Results {
-		results[i] = PromptResponseBody{
-			SessionID:  r.SessionID,
-			Backend:    r.Backend,
-			ExitCode:   r.ExitCode,
-			DurationMS: r.DurationMS,
-			Output:     r.Output,
-			Error:      r.Error,
-			TokenUsage: r.TokenUsage,
-		}
-	}
-
-	return &ParallelResponse{
-		Body: ParallelResponseBody{
-			TotalTasks:    result.TotalTasks,
-			Completed:     result.Completed,
-			Failed:        result.Failed,
-			TotalDuration: result.TotalDuration,
-			Results:       results,
-		},
-	}, nil
+	body := FromParallelResult(result)
+	h.notify(input.Body.CallbackURL, "parallel", "", body, nil)
+	return &ParallelResponse{Body: body}, nil
 }
 
 // ChainInput is the input for the chain handler.
@@ -282,72 +302,89 @@ type ChainInput struct {
 
 // HandleChain handles chain execution requests.
 func (h *CustomHandlers) HandleChain(ctx context.Context, input *ChainInput) (*ChainResponse, error) {
-	if len(input.Body.Steps) == 0 {
-		return nil, huma.Error400BadRequest("steps are required")
+	if err := validateChainRequest(&input.Body); err != nil {
+		return nil, err
+	}
+
+	result, err := h.executor.ExecuteChain(ctx, input.Body.ToServiceRequest())
+	if err != nil {
+		h.notify(input.Body.CallbackURL, "chain", "", nil, err)
+		return nil, huma.Error500InternalServerError("chain execution failed", err)
 	}
-	if input.Body.PassSessionID || input.Body.PersistSessions {
-		return nil, huma.Error400BadRequest("chain is always ephemeral; pass_session_id and persist_sessions are not supported")
+
+	body := FromChainResult(result)
+	h.notify(input.Body.CallbackURL, "chain", "", body, nil)
+	return &ChainResponse{Body: body}, nil
+}
+
+// validatePromptRequest checks the fields a prompt request requires.
+func validatePromptRequest(req *PromptRequest) error {
+	if req.Backend == "" {
+		return huma.Error400BadRequest("backend is required")
 	}
-	for i, step := range input.Body.Steps {
-		if strings.Contains(step.Prompt, "{{session}}") {
-			return nil, huma.Error400BadRequest(fmt.Sprintf("chain step %d uses {{session}} but sessions are not persisted", i+1))
+	if req.Prompt == "" {
+		return huma.Error400BadRequest("prompt is required")
+	}
+	if req.JSONSchema != nil {
+		if _, err := structured.CompileValue(req.JSONSchema); err != nil {
+			return huma.Error400BadRequest(err.Error())
 		}
 	}
+	if err := validateRequestMCPServers(req.MCPServers); err != nil {
+		return err
+	}
+	return validateCallbackURL(req.CallbackURL)
+}
 
-	// Convert to service request
-	serviceReq := &service.ChainRequest{
-		StopOnFailure:  input.Body.StopOnFailure,
-		PassWorkingDir: input.Body.PassWorkingDir,
-		DryRun:         input.Body.DryRun,
-		Steps:          make([]service.ChainStep, len(input.Body.Steps)),
-	}
-
-	for i, s := range input.Body.Steps {
-		serviceReq.Steps[i] = service.ChainStep{
-			Backend:      s.Backend,
-			Prompt:       s.Prompt,
-			Model:        s.Model,
-			WorkDir:      s.WorkDir,
-			ApprovalMode: s.ApprovalMode,
-			SandboxMode:  s.SandboxMode,
-			MaxTokens:    s.MaxTokens,
-			MaxTurns:     s.MaxTurns,
-			SystemPrompt: s.SystemPrompt,
-			Verbose:      s.Verbose,
-			Extra:        s.Extra,
-			Name:         s.Name,
+// validateParallelRequest checks the fields a parallel request requires.
+func validateParallelRequest(req *ParallelRequest) error {
+	if len(req.Tasks) == 0 {
+		return huma.Error400BadRequest("tasks are required")
+	}
+	for i := range req.Tasks {
+		if err := validateRequestMCPServers(req.Tasks[i].MCPServers); err != nil {
+			return err
 		}
 	}
+	return validateCallbackURL(req.CallbackURL)
+}
 
-	result, err := h.executor.ExecuteChain(ctx, serviceReq)
-	if err != nil {
-		return nil, huma.Error500InternalServerError("chain execution failed", err)
+// validateRequestMCPServers rejects MCP servers declared by a request
+// unless the server allows requests to declare them.
+func validateRequestMCPServers(servers map[string]backend.MCPServer) error {
+	if len(servers) > 0 && !config.Get().Server.AllowRequestMCPServers {
+		return huma.Error400BadRequest(service.ErrRequestMCPServers.Error())
 	}
+	return nil
+}
 
-	// Convert results
-	results := make([]ChainStepResult, len(result.Results))
-	for i, r := range result.Results {
-		results[i] = ChainStepResult{
-			Step:       r.Step,
-			Name:       r.Name,
-			Backend:    r.Backend,
-			ExitCode:   r.ExitCode,
-			Error:      r.Error,
-			SessionID:  r.SessionID,
-			DurationMS: r.DurationMS,
-			Output:     r.Output,
+// validateChainRequest checks a chain request for steps and for the
+// session options chains do not support.
+func validateChainRequest(req *ChainRequest) error {
+	if len(req.Steps) == 0 {
+		return huma.Error400BadRequest("steps are required")
+	}
+	if req.PassSessionID || req.PersistSessions {
+		return huma.Error400BadRequest("chain is always ephemeral; pass_session_id and persist_sessions are not supported")
+	}
+	for i, step := range req.Steps {
+		if strings.Contains(step.Prompt, "{{session}}") {
+			return huma.Error400BadRequest(fmt.Sprintf("chain step %d uses {{session}} but sessions are not persisted", i+1))
 		}
 	}
+	return validateCallbackURL(req.CallbackURL)
+}
 
-	return &ChainResponse{
-		Body: ChainResponseBody{
-			TotalSteps:     result.TotalSteps,
-			CompletedSteps: result.CompletedSteps,
-			FailedStep:     result.FailedStep,
-			TotalDuration:  result.TotalDuration,
-			Results:        results,
-		},
-	}, nil
+// validateCallbackURL checks a request's callback URL, if any, against
+// the configured webhook hosts.
+func validateCallbackURL(callbackURL string) error {
+	if callbackURL == "" {
+		return nil
+	}
+	if err := webhook.ValidateURL(callbackURL, config.Get().Server.WebhookAllowedHosts); err != nil {
+		return huma.Error400BadRequest(err.Error())
+	}
+	return nil
 }
 
 // CompareInput is the input for the compare handler.
@@ -363,6 +400,9 @@ func (h *CustomHandlers) HandleCompare(ctx context.Context, input *CompareInput)
 	if input.Body.Prompt == "" {
 		return nil, huma.Error400BadRequest("prompt is required")
 	}
+	if err := validateCallbackURL(input.Body.CallbackURL); err != nil {
+		return nil, err
+	}
 
 	serviceReq := &service.CompareRequest{
 		Backends:   input.Body.Backends,
@@ -375,6 +415,7 @@ func (h *CustomHandlers) HandleCompare(ctx context.Context, input *CompareInput)
 
 	result, err := h.executor.ExecuteCompare(ctx, serviceReq)
 	if err != nil {
+		h.notify(input.Body.CallbackURL, "compare", "", nil, err)
 		return nil, huma.Error500InternalServerError("compare execution failed", err)
 	}
 
@@ -392,14 +433,14 @@ func (h *CustomHandlers) HandleCompare(ctx context.Context, input *CompareInput)
 		}
 	}
 
-	return &CompareResponse{
-		Body: CompareResponseBody{
-			Prompt:        result.Prompt,
-			Backends:      result.Backends,
-			Results:       results,
-			TotalDuration: result.TotalDuration,
-		},
-	}, nil
+	body := CompareResponseBody{
+		Prompt:        result.Prompt,
+		Backends:      result.Backends,
+		Results:       results,
+		TotalDuration: result.TotalDuration,
+	}
+	h.notify(input.Body.CallbackURL, "compare", "", body, nil)
+	return &CompareResponse{Body: body}, nil
 }
 
 // BackendsInput is the input for the backends handler.
@@ -412,8 +453,11 @@ func (h *CustomHandlers) HandleBackends(ctx context.Context, _ *BackendsInput) (
 	infos := make([]BackendInfo, len(backends))
 	for i, b := range backends {
 		infos[i] = BackendInfo{
-			Name:      b.Name,
-			Available: b.Available,
+			Name:         b.Name,
+			Available:    b.Available,
+			Capabilities: b.Capabilities,
+			Pools:        b.Pools,
+			Pool:         FromPoolInfo(b.Pool),
 		}
 	}
 
@@ -448,20 +492,7 @@ func (h *CustomHandlers) HandleSessions(ctx context.Context, input *SessionsInpu
 
 	infos := make([]SessionInfo, len(result.Sessions))
 	for i, s := range result.Sessions {
-		infos[i] = SessionInfo{
-			ID:            s.ID,
-			Backend:       s.Backend,
-			CreatedAt:     s.CreatedAt,
-			LastUsed:      s.LastUsed,
-			WorkingDir:    s.WorkingDir,
-			Model:         s.Model,
-			InitialPrompt: s.InitialPrompt,
-			Status:        s.Status,
-			TurnCount:     s.TurnCount,
-			TokenUsage:    s.TokenUsage,
-			Tags:          s.Tags,
-			Title:         s.Title,
-		}
+		infos[i] = FromSessionInfo(&s)
 	}
 
 	return &SessionsResponse{
@@ -487,19 +518,26 @@ func (h *CustomHandlers) HandleGetSession(ctx context.Context, input *GetSession
 	}
 
 	return &SessionResponse{
-		Body: SessionInfo{
-			ID:            sess.ID,
-			Backend:       sess.Backend,
-			CreatedAt:     sess.CreatedAt,
-			LastUsed:      sess.LastUsed,
-			WorkingDir:    sess.WorkingDir,
-			Model:         sess.Model,
-			InitialPrompt: sess.InitialPrompt,
-			Status:        sess.Status,
-			TurnCount:     sess.TurnCount,
-			TokenUsage:    sess.TokenUsage,
-			Tags:          sess.Tags,
-			Title:         sess.Title,
+		Body: FromSessionInfo(sess),
+	}, nil
+}
+
+// GetSessionMessagesInput is the input for getting a session transcript.
+type GetSessionMessagesInput struct {
+	ID string `path:"id" doc:"Session ID or prefix"`
+}
+
+// HandleGetSessionMessages handles session transcript requests.
+func (h *CustomHandlers) HandleGetSessionMessages(ctx context.Context, input *GetSessionMessagesInput) (*SessionMessagesResponse, error) {
+	transcript, err := h.executor.GetSessionTranscript(ctx, input.ID)
+	if err != nil {
+		return nil, huma.Error404NotFound("session not found", err)
+	}
+
+	return &SessionMessagesResponse{
+		Body: SessionMessagesResponseBody{
+			SessionID: transcript.SessionID,
+			Turns:     transcript.Turns,
 		},
 	}, nil
 }
@@ -545,6 +583,20 @@ func (h *CustomHandlers) HandleHealth(ctx context.Context, _ *HealthInput) (*Hea
 		}
 	}
 
+	// Get circuit breaker status; an open circuit degrades the server
+	breakers := h.executor.CircuitBreakers(ctx)
+	circuitStates := make(map[string]string, len(breakers))
+	anyCircuitOpen := false
+	for _, cb := range breakers {
+		circuitStates[cb.Backend] = cb.State
+		if cb.State != "closed" {
+			anyCircuitOpen = true
+		}
+	}
+	for i := range backendStatus {
+		backendStatus[i].CircuitState = circuitStates[backendStatus[i].Name]
+	}
+
 	// Get session store status
 	storeHealth := h.executor.GetSessionStoreHealth(ctx)
 	sessionStoreStatus := SessionStoreStatus{
@@ -562,7 +614,7 @@ func (h *CustomHandlers) HandleHealth(ctx context.Context, _ *HealthInput) (*Hea
 
 	// Determine overall status
 	status := "ok"
-	if !allBackendsAvailable {
+	if !allBackendsAvailable || anyCircuitOpen {
 		status = "degraded"
 	}
 	if !storeHealth.Available {
@@ -577,6 +629,37 @@ func (h *CustomHandlers) HandleHealth(ctx context.Context, _ *HealthInput) (*Hea
 			UptimeMillis: uptimeMillis,
 			Backends:     backendStatus,
 			SessionStore: sessionStoreStatus,
+
+			CircuitBreakers: FromCircuitBreakerInfo(breakers),
+		},
+	}, nil
+}
+
+// ResetCircuitBreakersInput is the input for the reset circuit breakers handler.
+type ResetCircuitBreakersInput struct {
+	Body *ResetCircuitBreakersRequest
+}
+
+// HandleResetCircuitBreakers closes the circuit of one backend, or of all
+// backends when none is named.
+func (h *CustomHandlers) HandleResetCircuitBreakers(ctx context.Context, input *ResetCircuitBreakersInput) (*ResetCircuitBreakersResponse, error) {
+	var backendName string
+	if input.Body != nil {
+		backendName = input.Body.Backend
+	}
+
+	reset, err := h.executor.ResetCircuitBreakers(ctx, backendName)
+	if err != nil {
+		return nil, huma.Error404NotFound("backend not found", err)
+	}
+	if reset == nil {
+		reset = []string{}
+	}
+
+	return &ResetCircuitBreakersResponse{
+		Body: ResetCircuitBreakersResponseBody{
+			Reset:           reset,
+			CircuitBreakers: FromCircuitBreakerInfo(h.executor.CircuitBreakers(ctx)),
 		},
 	}, nil
 }
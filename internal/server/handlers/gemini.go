@@ -0,0 +1,771 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/output"
+	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/session"
+	"github.com/signalridge/clinvoker/internal/structured"
+	"github.com/signalridge/clinvoker/internal/util"
+)
+
+// Gemini finish reasons.
+const (
+	geminiFinishStop  = "STOP"
+	geminiFinishOther = "OTHER"
+)
+
+const (
+	geminiRoleModel = "model"
+	geminiAltSSE    = "sse"
+
+	geminiMimeText = "text/plain"
+	geminiMimeJSON = "application/json"
+)
+
+// geminiGenerationMethods are the methods every model supports.
+var geminiGenerationMethods = []string{"generateContent", "streamGenerateContent"}
+
+// GeminiHandlers provides handlers for Gemini-compatible API.
+type GeminiHandlers struct {
+	runner        service.PromptRunner
+	conversations *service.Conversations
+	logger        *slog.Logger
+}
+
+// NewGeminiHandlers creates a new Gemini handlers instance.
+func NewGeminiHandlers(runner service.PromptRunner, logger *slog.Logger) *GeminiHandlers {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &GeminiHandlers{runner: runner, logger: logger}
+}
+
+// SetConversations enables stateful conversations, continued on the
+// sessions of conversations.
+func (h *GeminiHandlers) SetConversations(conversations *service.Conversations) {
+	h.conversations = conversations
+}
+
+// Register registers all Gemini-compatible API routes.
+// Endpoints follow Gemini API spec: https://ai.google.dev/api/generate-content
+func (h *GeminiHandlers) Register(api huma.API) {
+	// Models endpoint - GET /gemini/v1beta/models
+	huma.Register(api, huma.Operation{
+		OperationID: "geminiListModels",
+		Method:      http.MethodGet,
+		Path:        "/gemini/v1beta/models",
+		Summary:     "List models",
+		Description: "Lists the models available through the API. Compatible with Gemini GET /v1beta/models.",
+		Tags:        []string{"Gemini Compatible"},
+	}, h.HandleModels)
+
+	// Generate endpoint - POST /gemini/v1beta/models/{model}:generateContent
+	huma.Register(api, huma.Operation{
+		OperationID: "geminiGenerateContent",
+		Method:      http.MethodPost,
+		Path:        "/gemini/v1beta/models/{model}:generateContent",
+		Summary:     "Generate content",
+		Description: "Generates a model response given an input. Compatible with Gemini POST /v1beta/models/{model}:generateContent.",
+		Tags:        []string{"Gemini Compatible"},
+	}, h.HandleGenerateContent)
+
+	// Streaming endpoint - POST /gemini/v1beta/models/{model}:streamGenerateContent
+	huma.Register(api, huma.Operation{
+		OperationID: "geminiStreamGenerateContent",
+		Method:      http.MethodPost,
+		Path:        "/gemini/v1beta/models/{model}:streamGenerateContent",
+		Summary:     "Stream generated content",
+		Description: "Generates a streamed model response given an input. Compatible with Gemini POST /v1beta/models/{model}:streamGenerateContent.",
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "OK",
+				Content: map[string]*huma.MediaType{
+					"application/json": {
+						Schema: &huma.Schema{
+							Type:        huma.TypeArray,
+							Items:       &huma.Schema{Type: huma.TypeObject},
+							Description: "JSON array of response chunks, written as they are generated.",
+						},
+					},
+					"text/event-stream": {
+						Schema: &huma.Schema{
+							Type:        huma.TypeString,
+							Description: "Server-sent events stream of response chunks (when alt=sse).",
+						},
+					},
+				},
+			},
+		},
+		Tags: []string{"Gemini Compatible"},
+	}, h.HandleStreamGenerateContent)
+}
+
+// GeminiModel represents a Gemini model object.
+type GeminiModel struct {
+	Name                       string   `json:"name"`
+	BaseModelID                string   `json:"baseModelId"`
+	DisplayName                string   `json:"displayName"`
+	InputTokenLimit            int      `json:"inputTokenLimit,omitempty"`
+	OutputTokenLimit           int      `json:"outputTokenLimit,omitempty"`
+	SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+}
+
+// GeminiModelsInput is the input for the models handler.
+type GeminiModelsInput struct{}
+
+// GeminiModelsResponse is the response for listing models.
+type GeminiModelsResponse struct {
+	Body GeminiModelsResponseBody
+}
+
+// GeminiModelsResponseBody is the body of the models response.
+type GeminiModelsResponseBody struct {
+	Models []GeminiModel `json:"models"`
+}
+
+// HandleModels handles the GET /v1beta/models endpoint.
+// The catalog is returned in a single page, followed by the backend and
+// pool names, which are accepted as models too.
+func (h *GeminiHandlers) HandleModels(ctx context.Context, _ *GeminiModelsInput) (*GeminiModelsResponse, error) {
+	catalog := backend.ListModels()
+	backends := append(backend.List(), util.PoolNames()...)
+
+	models := make([]GeminiModel, 0, len(catalog)+len(backends))
+	for _, m := range catalog {
+		displayName := m.DisplayName
+		if displayName == "" {
+			displayName = m.ID
+		}
+		models = append(models, GeminiModel{
+			Name:                       "models/" + m.ID,
+			BaseModelID:                m.ID,
+			DisplayName:                displayName,
+			InputTokenLimit:            m.ContextWindow,
+			OutputTokenLimit:           m.MaxOutputTokens,
+			SupportedGenerationMethods: geminiGenerationMethods,
+		})
+	}
+	for _, name := range backends {
+		models = append(models, GeminiModel{
+			Name:                       "models/" + name,
+			BaseModelID:                name,
+			DisplayName:                name,
+			SupportedGenerationMethods: geminiGenerationMethods,
+		})
+	}
+	return &GeminiModelsResponse{Body: GeminiModelsResponseBody{Models: models}}, nil
+}
+
+// GeminiContent is a message of a conversation: its role and parts.
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty" enum:"user,model" doc:"Producer of the content (user, model)"`
+	Parts []GeminiPart `json:"parts" doc:"Ordered parts of the message"`
+}
+
+// GeminiPart is a part of a message: text, or an inline or linked file.
+type GeminiPart struct {
+	Text       string          `json:"text,omitempty" doc:"Text"`
+	Thought    bool            `json:"thought,omitempty" doc:"Whether the part is a thought of the model"`
+	InlineData *GeminiBlob     `json:"inlineData,omitempty" doc:"Inline file data"`
+	FileData   *GeminiFileData `json:"fileData,omitempty" doc:"File referenced by URI"`
+}
+
+// GeminiBlob is inline file data.
+type GeminiBlob struct {
+	MimeType string `json:"mimeType" doc:"Media type of the data"`
+	Data     string `json:"data" doc:"Base64-encoded data"`
+}
+
+// GeminiFileData is a file referenced by URI.
+type GeminiFileData struct {
+	MimeType string `json:"mimeType,omitempty" doc:"Media type of the file"`
+	FileURI  string `json:"fileUri" doc:"URI of the file"`
+}
+
+// GeminiGenerationConfig configures generation.
+type GeminiGenerationConfig struct {
+	StopSequences      []string              `json:"stopSequences,omitempty" doc:"Stop sequences; the reply is cut before the first one"`
+	MaxOutputTokens    int                   `json:"maxOutputTokens,omitempty" doc:"Maximum tokens to generate"`
+	CandidateCount     int                   `json:"candidateCount,omitempty" minimum:"0" maximum:"8" doc:"Number of candidates, run in parallel"`
+	ResponseMimeType   string                `json:"responseMimeType,omitempty" doc:"Reply format: text/plain or application/json"`
+	ResponseSchema     map[string]any        `json:"responseSchema,omitempty" doc:"Schema of JSON replies, in the Gemini schema subset"`
+	ResponseJSONSchema map[string]any        `json:"responseJsonSchema,omitempty" doc:"JSON Schema of JSON replies"`
+	ThinkingConfig     *GeminiThinkingConfig `json:"thinkingConfig,omitempty" doc:"Thinking; with includeThoughts, streamed thoughts are sent as thought parts"`
+	Temperature        float64               `json:"temperature,omitempty" doc:"Sampling temperature"`
+	TopP               float64               `json:"topP,omitempty" doc:"Nucleus sampling parameter"`
+	TopK               int                   `json:"topK,omitempty" doc:"Top-k sampling parameter"`
+	Seed               int                   `json:"seed,omitempty" doc:"Sampling seed"`
+	PresencePenalty    float64               `json:"presencePenalty,omitempty" doc:"Presence penalty"`
+	FrequencyPenalty   float64               `json:"frequencyPenalty,omitempty" doc:"Frequency penalty"`
+}
+
+// GeminiThinkingConfig configures thinking.
+type GeminiThinkingConfig struct {
+	IncludeThoughts bool `json:"includeThoughts,omitempty" doc:"Send thoughts as thought parts"`
+	// ThinkingBudget is accepted for compatibility; backends decide how
+	// much to think.
+	ThinkingBudget int `json:"thinkingBudget,omitempty" doc:"Accepted for compatibility"`
+}
+
+// GeminiSafetySetting is a safety setting, accepted for compatibility.
+type GeminiSafetySetting struct {
+	Category  string `json:"category" doc:"Harm category"`
+	Threshold string `json:"threshold" doc:"Blocking threshold"`
+}
+
+// GeminiGenerateContentRequest is the request for generating content.
+type GeminiGenerateContentRequest struct {
+	Contents          []GeminiContent         `json:"contents" doc:"Conversation messages"`
+	SystemInstruction *GeminiContent          `json:"systemInstruction,omitempty" doc:"System instruction"`
+	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty" doc:"Generation options"`
+	SafetySettings    []GeminiSafetySetting   `json:"safetySettings,omitempty" doc:"Accepted for compatibility; backends apply their own safety settings"`
+}
+
+// GeminiCandidate is a reply of a response.
+type GeminiCandidate struct {
+	Content      GeminiContent `json:"content"`
+	FinishReason string        `json:"finishReason,omitempty"`
+	Index        int           `json:"index"`
+}
+
+// GeminiUsageMetadata represents token usage.
+type GeminiUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+}
+
+// GeminiGenerateContentResponse is a response, or a chunk of a streamed one.
+type GeminiGenerateContentResponse struct {
+	Candidates    []GeminiCandidate    `json:"candidates"`
+	UsageMetadata *GeminiUsageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string               `json:"modelVersion"`
+	ResponseID    string               `json:"responseId"`
+}
+
+// GeminiGenerateContentOutput is the output of the generate handler.
+type GeminiGenerateContentOutput struct {
+	SessionID string `header:"X-Clinvk-Session-Id"`
+	Body      GeminiGenerateContentResponse
+}
+
+// GeminiGenerateContentInput is the input for the generate handler.
+type GeminiGenerateContentInput struct {
+	Model    string `path:"model" doc:"Model/backend to use"`
+	Stateful bool   `header:"X-Clinvk-Stateful" doc:"Continue the conversation on a clinvk session, sending only the newest user message"`
+	Body     GeminiGenerateContentRequest
+}
+
+// GeminiStreamGenerateContentInput is the input for the streaming handler.
+type GeminiStreamGenerateContentInput struct {
+	Model    string `path:"model" doc:"Model/backend to use"`
+	Alt      string `query:"alt" enum:"json,sse" doc:"Stream as server-sent events (sse) or as a JSON array (json, the default)"`
+	Stateful bool   `header:"X-Clinvk-Stateful" doc:"Continue the conversation on a clinvk session, sending only the newest user message"`
+	Body     GeminiGenerateContentRequest
+}
+
+// geminiGeneration is a generate request ready to run.
+type geminiGeneration struct {
+	model    string
+	req      *service.PromptRequest
+	prompt   string
+	stops    []string
+	thoughts bool
+	schema   map[string]any
+	turn     *service.ConversationTurn
+	n        int
+	cleanup  func()
+}
+
+// HandleGenerateContent handles the POST /v1beta/models/{model}:generateContent endpoint.
+func (h *GeminiHandlers) HandleGenerateContent(ctx context.Context, input *GeminiGenerateContentInput) (*GeminiGenerateContentOutput, error) {
+	g, err := h.prepare(input.Model, input.Stateful, &input.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer g.cleanup()
+
+	body, sessionID, err := h.complete(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	out := &GeminiGenerateContentOutput{Body: *body}
+	if g.turn != nil {
+		out.SessionID = sessionID
+	}
+	return out, nil
+}
+
+// HandleStreamGenerateContent handles the POST /v1beta/models/{model}:streamGenerateContent endpoint.
+func (h *GeminiHandlers) HandleStreamGenerateContent(ctx context.Context, input *GeminiStreamGenerateContentInput) (*huma.StreamResponse, error) {
+	g, err := h.prepare(input.Model, input.Stateful, &input.Body)
+	if err != nil {
+		return nil, err
+	}
+	sse := input.Alt == geminiAltSSE
+
+	// Several candidates, or replies that must be validated, are complete
+	// before they are sent, so the stream gets them in one chunk.
+	if g.n > 1 || g.schema != nil {
+		defer g.cleanup()
+
+		body, sessionID, err := h.complete(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+		return &huma.StreamResponse{
+			Body: func(hctx huma.Context) {
+				if g.turn != nil && sessionID != "" {
+					hctx.SetHeader(sessionIDHeader, sessionID)
+				}
+				out := &geminiStream{hctx: hctx, sse: sse}
+				out.start()
+				if err := out.write(body); err != nil {
+					h.logger.Debug("stream write error", "error", err)
+				}
+				if err := out.close(); err != nil {
+					h.logger.Debug("stream write error", "error", err)
+				}
+			},
+		}, nil
+	}
+
+	responseID := geminiResponseID("")
+
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			defer g.cleanup()
+
+			// Helper to log stream write errors at debug level
+			logWriteErr := func(err error) {
+				if err != nil {
+					h.logger.Debug("stream write error", "error", err)
+				}
+			}
+
+			var sessionID string
+			out := &geminiStream{hctx: hctx, sse: sse}
+			stream := &streamStart{start: func() {
+				if sessionID != "" {
+					hctx.SetHeader(sessionIDHeader, sessionID)
+				}
+				out.start()
+			}}
+			writeChunk := func(parts []GeminiPart, finishReason string, usage *GeminiUsageMetadata) error {
+				stream.begin()
+				return out.write(&GeminiGenerateContentResponse{
+					Candidates: []GeminiCandidate{{
+						Content:      GeminiContent{Role: geminiRoleModel, Parts: parts},
+						FinishReason: finishReason,
+					}},
+					UsageMetadata: usage,
+					ModelVersion:  g.model,
+					ResponseID:    responseID,
+				})
+			}
+
+			streamReq := *g.req
+			streamCtx := hctx.Context()
+
+			var reply strings.Builder
+			stops := &stopStream{stops: g.stops}
+			onEvent := func(event *output.UnifiedEvent) error {
+				switch event.Type {
+				case output.EventThinking:
+					if !g.thoughts {
+						return nil
+					}
+					content, err := event.GetThinkingContent()
+					if err != nil || content.Text == "" {
+						return err
+					}
+					return writeChunk([]GeminiPart{{Text: content.Text, Thought: true}}, "", nil)
+
+				case output.EventMessage:
+					content, err := event.GetMessageContent()
+					if err != nil {
+						return err
+					}
+					if g.turn != nil {
+						sessionID = event.SessionID
+					}
+					text := stops.write(content.Text)
+					if text == "" {
+						return nil
+					}
+					reply.WriteString(text)
+					return writeChunk([]GeminiPart{{Text: text}}, "", nil)
+				}
+				return nil
+			}
+
+			var streamResult *service.StreamResult
+			var streamErr error
+			if g.turn != nil {
+				streamResult, streamErr = h.conversations.StreamPrompt(streamCtx, &streamReq, onEvent)
+			} else {
+				streamResult, streamErr = service.StreamPrompt(streamCtx, &streamReq, nil, nil, true, onEvent)
+			}
+
+			if !stream.started && writeConcurrencyRejection(hctx, streamErr) {
+				return
+			}
+
+			// Text held back as a possible stop sequence was not one
+			parts := []GeminiPart{}
+			if rest := stops.flush(); rest != "" {
+				reply.WriteString(rest)
+				parts = append(parts, GeminiPart{Text: rest})
+			}
+
+			finishReason := geminiFinishStop
+			var tokenUsage *session.TokenUsage
+			if streamErr != nil || streamResult == nil || streamResult.ExitCode != 0 || streamResult.Error != "" {
+				finishReason = geminiFinishOther
+				if streamErr != nil {
+					h.logger.Debug("stream failed", "error", streamErr)
+				}
+			} else if g.turn != nil {
+				h.conversations.Finish(g.turn, streamResult.SessionID, reply.String())
+			}
+			if streamResult != nil {
+				tokenUsage = streamResult.TokenUsage
+			}
+
+			usage := &GeminiUsageMetadata{}
+			usage.add(g.prompt, reply.String(), tokenUsage)
+			logWriteErr(writeChunk(parts, finishReason, usage))
+			logWriteErr(out.close())
+		},
+	}, nil
+}
+
+// prepare checks a generate request and builds the prompt request for it.
+// The returned generation's cleanup must be called once it has run.
+func (h *GeminiHandlers) prepare(model string, statefulHeader bool, body *GeminiGenerateContentRequest) (*geminiGeneration, error) {
+	if len(body.Contents) == 0 {
+		return nil, huma.Error400BadRequest("contents are required")
+	}
+	config := body.GenerationConfig
+	if config == nil {
+		config = &GeminiGenerationConfig{}
+	}
+	schema, err := geminiResponseSchema(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parts are rendered as text, with inline data and files attached
+	var files attachments
+	var systemPrompt string
+	if body.SystemInstruction != nil {
+		if systemPrompt, err = files.renderGeminiParts(body.SystemInstruction.Parts); err != nil {
+			return nil, err
+		}
+	}
+
+	var prompt string
+	var conversation []service.ConversationMessage
+	for _, content := range body.Contents {
+		text, err := files.renderGeminiParts(content.Parts)
+		if err != nil {
+			return nil, err
+		}
+		switch content.Role {
+		case "", roleUser:
+			if prompt != "" {
+				prompt += "\n"
+			}
+			prompt += text
+			conversation = append(conversation, service.ConversationMessage{Role: roleUser, Content: text})
+		case geminiRoleModel:
+			// Include model context for continuations
+			if prompt != "" {
+				prompt += "\n[Previous response: " + text + "]\n"
+			}
+			conversation = append(conversation, service.ConversationMessage{Role: roleAssistant, Content: text})
+		}
+	}
+
+	if prompt == "" {
+		return nil, huma.Error400BadRequest("no user contents found")
+	}
+
+	// Map model to backend
+	backendName := mapGeminiModelToBackend(model)
+
+	req := &service.PromptRequest{
+		Backend:      backendName,
+		Prompt:       prompt,
+		Model:        model,
+		MaxTokens:    config.MaxOutputTokens,
+		SystemPrompt: systemPrompt,
+	}
+
+	// A stateful conversation continues with a single reply
+	stateful := h.conversations != nil && statefulHeader
+	n := max(config.CandidateCount, 1)
+	if stateful && n > 1 {
+		h.logger.Warn("candidateCount ignored for a stateful conversation", "candidateCount", n)
+		n = 1
+	}
+
+	var turn *service.ConversationTurn
+	if stateful {
+		scope := conversationScope("gemini", "", backendName, model, systemPrompt)
+		turn = nextConversationTurn(h.conversations, scope, conversation, req)
+	}
+
+	if schema != nil {
+		req.JSONSchema = schema
+		req.JSONSchemaRetries = responseFormatRetries
+	}
+
+	cleanup, err := files.attachTo(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &geminiGeneration{
+		model:    model,
+		req:      req,
+		prompt:   prompt,
+		stops:    config.StopSequences,
+		thoughts: config.ThinkingConfig != nil && config.ThinkingConfig.IncludeThoughts,
+		schema:   schema,
+		turn:     turn,
+		n:        n,
+		cleanup:  cleanup,
+	}, nil
+}
+
+// complete runs a generation to the end, running its candidates in
+// parallel. It returns the response and the session of a stateful turn.
+func (h *GeminiHandlers) complete(ctx context.Context, g *geminiGeneration) (*GeminiGenerateContentResponse, string, error) {
+	var runner service.PromptRunner = h.runner
+	if g.turn != nil {
+		runner = h.conversations
+	}
+
+	var results []service.PromptResult
+	if g.n > 1 {
+		tasks := make([]service.PromptRequest, g.n)
+		for i := range tasks {
+			tasks[i] = *g.req
+		}
+		results = service.RunParallel(ctx, runner, &service.ParallelRequest{Tasks: tasks}, h.logger).Results
+	} else {
+		result, err := runner.ExecutePrompt(ctx, g.req)
+		if err != nil {
+			return nil, "", executionError("execution failed", err)
+		}
+		results = []service.PromptResult{*result}
+	}
+
+	first := results[0]
+	body := &GeminiGenerateContentResponse{
+		Candidates:    make([]GeminiCandidate, len(results)),
+		UsageMetadata: &GeminiUsageMetadata{},
+		ModelVersion:  g.model,
+		ResponseID:    geminiResponseID(first.SessionID),
+	}
+	for i, result := range results {
+		output := truncateAtStop(result.Output, g.stops)
+		if result.Structured != nil {
+			// Validated JSON is returned whole; a stop could break it
+			output = string(result.Structured)
+		}
+
+		finishReason := geminiFinishStop
+		if result.ExitCode != 0 {
+			finishReason = geminiFinishOther
+		}
+		if i == 0 && g.turn != nil && result.ExitCode == 0 {
+			h.conversations.Finish(g.turn, result.SessionID, output)
+		}
+
+		body.Candidates[i] = GeminiCandidate{
+			Content:      GeminiContent{Role: geminiRoleModel, Parts: []GeminiPart{{Text: output}}},
+			FinishReason: finishReason,
+			Index:        i,
+		}
+		body.UsageMetadata.add(g.prompt, output, result.TokenUsage)
+	}
+
+	return body, first.SessionID, nil
+}
+
+// add counts the tokens of a reply to prompt, using backend usage if
+// available and a rough estimate otherwise.
+func (u *GeminiUsageMetadata) add(prompt, reply string, usage *session.TokenUsage) {
+	promptTokens := len(prompt) / 4
+	replyTokens := len(reply) / 4
+	if usage != nil {
+		promptTokens = int(usage.InputTokens)
+		replyTokens = int(usage.OutputTokens)
+		u.CachedContentTokenCount += int(usage.CachedTokens)
+		u.ThoughtsTokenCount += int(usage.ReasoningTokens)
+	}
+	u.PromptTokenCount += promptTokens
+	u.CandidatesTokenCount += replyTokens
+	u.TotalTokenCount = u.PromptTokenCount + u.CandidatesTokenCount + u.ThoughtsTokenCount
+}
+
+// geminiStream writes the chunks of a streamed response: as server-sent
+// events with alt=sse, and as the elements of a JSON array otherwise.
+type geminiStream struct {
+	hctx   huma.Context
+	sse    bool
+	chunks int
+}
+
+// start writes the headers of the stream.
+func (s *geminiStream) start() {
+	if s.sse {
+		setEventStreamHeaders(s.hctx)
+		return
+	}
+	s.hctx.SetHeader("Content-Type", "application/json")
+	s.hctx.SetHeader("Cache-Control", "no-cache")
+	s.hctx.SetStatus(http.StatusOK)
+}
+
+// write writes a chunk.
+func (s *geminiStream) write(chunk *GeminiGenerateContentResponse) error {
+	if s.sse {
+		return writeSSEJSON(s.hctx, chunk)
+	}
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+	sep := ",\r\n"
+	if s.chunks == 0 {
+		sep = "["
+	}
+	s.chunks++
+	return writeSSE(s.hctx, append([]byte(sep), data...))
+}
+
+// close ends the stream.
+func (s *geminiStream) close() error {
+	switch {
+	case s.sse:
+		return nil
+	case s.chunks == 0:
+		return writeSSE(s.hctx, []byte("[]"))
+	default:
+		return writeSSE(s.hctx, []byte("]"))
+	}
+}
+
+// geminiResponseID returns the ID of a response, from its session if any.
+func geminiResponseID(sessionID string) string {
+	if sessionID != "" {
+		return sessionID
+	}
+	return strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+// geminiResponseSchema returns the JSON Schema replies must match for c,
+// or nil for text replies. Like response_format on the OpenAI endpoint,
+// the schema is asked for in the prompt and replies are validated.
+func geminiResponseSchema(c *GeminiGenerationConfig) (map[string]any, error) {
+	switch c.ResponseMimeType {
+	case "", geminiMimeText:
+		if c.ResponseSchema != nil || c.ResponseJSONSchema != nil {
+			return nil, huma.Error400BadRequest("responseSchema requires responseMimeType application/json")
+		}
+		return nil, nil
+	case geminiMimeJSON:
+	default:
+		return nil, huma.Error400BadRequest(fmt.Sprintf("unsupported responseMimeType %q", c.ResponseMimeType))
+	}
+	if c.ResponseSchema != nil && c.ResponseJSONSchema != nil {
+		return nil, huma.Error400BadRequest("responseSchema and responseJsonSchema cannot both be set")
+	}
+
+	schema := map[string]any{}
+	switch {
+	case c.ResponseJSONSchema != nil:
+		schema = c.ResponseJSONSchema
+	case c.ResponseSchema != nil:
+		schema = geminiJSONSchema(c.ResponseSchema).(map[string]any)
+	}
+	if _, err := structured.CompileValue(schema); err != nil {
+		return nil, huma.Error400BadRequest("invalid response schema: " + err.Error())
+	}
+	return schema, nil
+}
+
+// geminiJSONSchema returns a copy of a Gemini schema value as JSON Schema:
+// its types, which Gemini spells in upper case, are in lower case, and the
+// example and propertyOrdering annotations JSON Schema lacks are dropped.
+func geminiJSONSchema(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, value := range v {
+			switch key {
+			case "type":
+				if t, ok := value.(string); ok {
+					value = strings.ToLower(t)
+				}
+				out[key] = value
+			case "example", "propertyOrdering":
+			case "properties":
+				// Property names are not keywords
+				props, ok := value.(map[string]any)
+				if !ok {
+					out[key] = value
+					continue
+				}
+				outProps := make(map[string]any, len(props))
+				for name, prop := range props {
+					outProps[name] = geminiJSONSchema(prop)
+				}
+				out[key] = outProps
+			default:
+				out[key] = geminiJSONSchema(value)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			out[i] = geminiJSONSchema(value)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// mapGeminiModelToBackend maps Gemini model names to backend names.
+func mapGeminiModelToBackend(model string) string {
+	// If the model is already a registered backend or pool name, use it
+	if _, err := backend.Get(model); err == nil || util.IsPool(model) {
+		return model
+	}
+
+	// Resolve catalog model IDs and prefixes to their owning backend
+	if name, ok := backend.FindModelBackend(model); ok {
+		return name
+	}
+
+	// Default to gemini for Gemini API
+	return backend.BackendGemini
+}
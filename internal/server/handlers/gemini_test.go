@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+func TestGeminiHandlers_GenerateContent(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	// The backend answers each prompt with the next of replies, if any
+	var mu sync.Mutex
+	var replies []string
+	var prompts []string
+	var opts []*backend.UnifiedOptions
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-gemini",
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(func(p string, o *backend.UnifiedOptions) *exec.Cmd {
+			mu.Lock()
+			defer mu.Unlock()
+			prompts = append(prompts, p)
+			opts = append(opts, o)
+			reply := "Hello END world"
+			if len(replies) > 0 {
+				reply, replies = replies[0], replies[1:]
+			}
+			return exec.Command("printf", "%s", reply)
+		}),
+	)))
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	NewGeminiHandlers(service.NewStatelessRunner(nil), nil).Register(api)
+
+	post := func(t *testing.T, body map[string]any, wantStatus int) string {
+		t.Helper()
+		if body["contents"] == nil {
+			body["contents"] = []map[string]any{{"role": "user", "parts": []map[string]any{{"text": "hi"}}}}
+		}
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Marshal() error: %v", err)
+		}
+		rec := serveJobRequest(router, http.MethodPost, "/gemini/v1beta/models/mock-gemini:generateContent", string(data), nil)
+		if rec.Code != wantStatus {
+			t.Fatalf("status = %d, want %d, body = %s", rec.Code, wantStatus, rec.Body.String())
+		}
+		return rec.Body.String()
+	}
+	generate := func(t *testing.T, body map[string]any) GeminiGenerateContentResponse {
+		t.Helper()
+		var resp GeminiGenerateContentResponse
+		if err := json.Unmarshal([]byte(post(t, body, http.StatusOK)), &resp); err != nil {
+			t.Fatalf("Unmarshal() error: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("reply", func(t *testing.T) {
+		resp := generate(t, map[string]any{})
+		if len(resp.Candidates) != 1 {
+			t.Fatalf("candidates = %+v, want 1", resp.Candidates)
+		}
+		c := resp.Candidates[0]
+		if c.Content.Role != geminiRoleModel || len(c.Content.Parts) != 1 || c.Content.Parts[0].Text != "Hello END world" {
+			t.Errorf("content = %+v", c.Content)
+		}
+		if c.FinishReason != geminiFinishStop {
+			t.Errorf("finishReason = %q, want %q", c.FinishReason, geminiFinishStop)
+		}
+		if resp.ModelVersion != "mock-gemini" || resp.ResponseID == "" {
+			t.Errorf("modelVersion = %q, responseId = %q", resp.ModelVersion, resp.ResponseID)
+		}
+		u := resp.UsageMetadata
+		if u == nil || u.CandidatesTokenCount == 0 || u.TotalTokenCount != u.PromptTokenCount+u.CandidatesTokenCount {
+			t.Errorf("usageMetadata = %+v", u)
+		}
+	})
+
+	t.Run("contents and system instruction", func(t *testing.T) {
+		mu.Lock()
+		prompts, opts = nil, nil
+		mu.Unlock()
+		generate(t, map[string]any{
+			"systemInstruction": map[string]any{"parts": []map[string]any{{"text": "Be brief."}}},
+			"contents": []map[string]any{
+				{"role": "user", "parts": []map[string]any{{"text": "Hi"}}},
+				{"role": "model", "parts": []map[string]any{{"text": "Hello!"}}},
+				{"role": "user", "parts": []map[string]any{{"text": "How are you?"}}},
+			},
+			"generationConfig": map[string]any{"maxOutputTokens": 64},
+		})
+		if len(prompts) != 1 || !strings.Contains(prompts[0], "[Previous response: Hello!]") || !strings.HasSuffix(prompts[0], "How are you?") {
+			t.Errorf("prompt = %q", prompts)
+		}
+		if opts[0].SystemPrompt != "Be brief." || opts[0].MaxTokens != 64 {
+			t.Errorf("options = %+v", opts[0])
+		}
+	})
+
+	t.Run("stop sequences truncate", func(t *testing.T) {
+		resp := generate(t, map[string]any{"generationConfig": map[string]any{"stopSequences": []string{"END"}}})
+		if got := resp.Candidates[0].Content.Parts[0].Text; got != "Hello " {
+			t.Errorf("text = %q, want %q", got, "Hello ")
+		}
+	})
+
+	t.Run("candidates run in parallel", func(t *testing.T) {
+		resp := generate(t, map[string]any{"generationConfig": map[string]any{"candidateCount": 3}})
+		if len(resp.Candidates) != 3 {
+			t.Fatalf("candidates = %+v, want 3", resp.Candidates)
+		}
+		for i, c := range resp.Candidates {
+			if c.Index != i || c.Content.Parts[0].Text != "Hello END world" {
+				t.Errorf("candidate %d = %+v", i, c)
+			}
+		}
+	})
+
+	t.Run("response schema", func(t *testing.T) {
+		mu.Lock()
+		replies = []string{"not JSON", `{"name":"Ada"}`}
+		mu.Unlock()
+		resp := generate(t, map[string]any{"generationConfig": map[string]any{
+			"responseMimeType": "application/json",
+			"responseSchema": map[string]any{
+				"type":       "OBJECT",
+				"properties": map[string]any{"name": map[string]any{"type": "STRING"}},
+				"required":   []string{"name"},
+			},
+		}})
+		if got := resp.Candidates[0].Content.Parts[0].Text; got != `{"name":"Ada"}` {
+			t.Errorf("text = %q, want the corrected reply", got)
+		}
+	})
+
+	t.Run("response schema ignores stop sequences", func(t *testing.T) {
+		mu.Lock()
+		replies = []string{`{"name":"Ada END"}`}
+		mu.Unlock()
+		resp := generate(t, map[string]any{"generationConfig": map[string]any{
+			"responseMimeType": "application/json",
+			"stopSequences":    []string{"END"},
+		}})
+		if got := resp.Candidates[0].Content.Parts[0].Text; got != `{"name":"Ada END"}` {
+			t.Errorf("text = %q, want the whole JSON reply", got)
+		}
+	})
+
+	for _, tt := range []struct {
+		name string
+		body map[string]any
+	}{
+		{name: "no contents", body: map[string]any{"contents": []map[string]any{}}},
+		{name: "no user contents", body: map[string]any{"contents": []map[string]any{{"role": "model", "parts": []map[string]any{{"text": "Hi"}}}}}},
+		{name: "schema without JSON", body: map[string]any{"generationConfig": map[string]any{"responseSchema": map[string]any{"type": "OBJECT"}}}},
+		{name: "unsupported mime type", body: map[string]any{"generationConfig": map[string]any{"responseMimeType": "text/x.enum"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			post(t, tt.body, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestGeminiHandlers_StreamGenerateContent(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	// The backend thinks and answers in two pieces, then reports its usage
+	lines := make([]string, 0, 4)
+	for _, content := range []map[string]any{
+		{"type": "thinking", "thinking": "Let me think."},
+		{"type": "text", "text": "Hello"},
+		{"type": "text", "text": " world"},
+	} {
+		line, _ := json.Marshal(map[string]any{
+			"type":    "assistant",
+			"message": map[string]any{"content": []map[string]any{content}},
+		})
+		lines = append(lines, string(line))
+	}
+	lines = append(lines, `{"type":"result","usage":{"input_tokens":12,"output_tokens":5}}`)
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend(backend.BackendClaude,
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(func(p string, opts *backend.UnifiedOptions) *exec.Cmd {
+			return exec.Command("printf", "%s\n", lines[0], lines[1], lines[2], lines[3])
+		}),
+	)))
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	NewGeminiHandlers(service.NewStatelessRunner(nil), nil).Register(api)
+
+	stream := func(t *testing.T, query string, config map[string]any) (*http.Response, []GeminiGenerateContentResponse) {
+		t.Helper()
+		data, err := json.Marshal(map[string]any{
+			"contents":         []map[string]any{{"role": "user", "parts": []map[string]any{{"text": "hi"}}}},
+			"generationConfig": config,
+		})
+		if err != nil {
+			t.Fatalf("Marshal() error: %v", err)
+		}
+		rec := serveJobRequest(router, http.MethodPost, "/gemini/v1beta/models/claude:streamGenerateContent"+query, string(data), nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+
+		var chunks []GeminiGenerateContentResponse
+		if strings.Contains(query, "alt=sse") {
+			for _, data := range sseData(rec.Body.String()) {
+				var chunk GeminiGenerateContentResponse
+				if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+					t.Fatalf("Unmarshal(%s) error: %v", data, err)
+				}
+				chunks = append(chunks, chunk)
+			}
+		} else if err := json.Unmarshal(rec.Body.Bytes(), &chunks); err != nil {
+			t.Fatalf("Unmarshal(%s) error: %v", rec.Body.String(), err)
+		}
+		return rec.Result(), chunks
+	}
+	texts := func(chunks []GeminiGenerateContentResponse) []string {
+		var texts []string
+		for _, chunk := range chunks {
+			for _, part := range chunk.Candidates[0].Content.Parts {
+				if part.Thought {
+					texts = append(texts, "thought: "+part.Text)
+				} else {
+					texts = append(texts, part.Text)
+				}
+			}
+		}
+		return texts
+	}
+
+	t.Run("JSON array", func(t *testing.T) {
+		resp, chunks := stream(t, "", nil)
+		if got := resp.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+		if got, want := texts(chunks), []string{"Hello", " world"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("texts = %q, want %q", got, want)
+		}
+
+		last := chunks[len(chunks)-1]
+		if last.Candidates[0].FinishReason != geminiFinishStop {
+			t.Errorf("finishReason = %q, want %q", last.Candidates[0].FinishReason, geminiFinishStop)
+		}
+		want := &GeminiUsageMetadata{PromptTokenCount: 12, CandidatesTokenCount: 5, TotalTokenCount: 17}
+		if !reflect.DeepEqual(last.UsageMetadata, want) {
+			t.Errorf("usageMetadata = %+v, want %+v", last.UsageMetadata, want)
+		}
+		for _, chunk := range chunks[:len(chunks)-1] {
+			if chunk.UsageMetadata != nil || chunk.Candidates[0].FinishReason != "" {
+				t.Errorf("chunk = %+v, want usage and finish reason on the last chunk only", chunk)
+			}
+		}
+	})
+
+	t.Run("SSE with thoughts", func(t *testing.T) {
+		resp, chunks := stream(t, "?alt=sse", map[string]any{"thinkingConfig": map[string]any{"includeThoughts": true}})
+		if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/event-stream") {
+			t.Errorf("Content-Type = %q, want text/event-stream", got)
+		}
+		if got, want := texts(chunks), []string{"thought: Let me think.", "Hello", " world"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("texts = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("stop sequences", func(t *testing.T) {
+		_, chunks := stream(t, "?alt=sse", map[string]any{"stopSequences": []string{" wor"}})
+		if got := strings.Join(texts(chunks), ""); got != "Hello" {
+			t.Errorf("text = %q, want %q", got, "Hello")
+		}
+	})
+
+	t.Run("candidates in one chunk", func(t *testing.T) {
+		_, chunks := stream(t, "", map[string]any{"candidateCount": 2})
+		if len(chunks) != 1 || len(chunks[0].Candidates) != 2 {
+			t.Fatalf("chunks = %+v, want one chunk with 2 candidates", chunks)
+		}
+		first, second := chunks[0].Candidates[0], chunks[0].Candidates[1]
+		if second.Index != 1 || second.Content.Parts[0].Text != first.Content.Parts[0].Text {
+			t.Errorf("candidates = %+v, want the same reply twice", chunks[0].Candidates)
+		}
+	})
+}
+
+func TestGeminiHandlers_HandleModels(t *testing.T) {
+	handlers := NewGeminiHandlers(service.NewExecutor(), nil)
+
+	resp, err := handlers.HandleModels(context.Background(), &GeminiModelsInput{})
+	if err != nil {
+		t.Fatalf("HandleModels() error: %v", err)
+	}
+
+	names := make(map[string]bool, len(resp.Body.Models))
+	for _, m := range resp.Body.Models {
+		if !strings.HasPrefix(m.Name, "models/") || m.BaseModelID == "" {
+			t.Errorf("model = %+v", m)
+		}
+		names[m.BaseModelID] = true
+	}
+	for _, name := range backend.List() {
+		if !names[name] {
+			t.Errorf("models do not include backend %q", name)
+		}
+	}
+}
+
+func TestMapGeminiModelToBackend(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"gemini", "gemini"},
+		{"claude", "claude"},
+		{"codex", "codex"},
+		{"gemini-2.5-pro", "gemini"},
+		{"unknown-model", "gemini"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := mapGeminiModelToBackend(tt.model); got != tt.want {
+				t.Errorf("mapGeminiModelToBackend(%q) = %q, want %q", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeminiResponseSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  GeminiGenerationConfig
+		want    map[string]any
+		wantErr bool
+	}{
+		{name: "text", config: GeminiGenerationConfig{}},
+		{name: "plain text", config: GeminiGenerationConfig{ResponseMimeType: geminiMimeText}},
+		{name: "any JSON", config: GeminiGenerationConfig{ResponseMimeType: geminiMimeJSON}, want: map[string]any{}},
+		{
+			name: "Gemini schema",
+			config: GeminiGenerationConfig{
+				ResponseMimeType: geminiMimeJSON,
+				ResponseSchema: map[string]any{
+					"type":  "ARRAY",
+					"items": map[string]any{"type": "INTEGER", "format": "int32"},
+				},
+			},
+			want: map[string]any{
+				"type":  "array",
+				"items": map[string]any{"type": "integer", "format": "int32"},
+			},
+		},
+		{
+			name: "Gemini annotations",
+			config: GeminiGenerationConfig{
+				ResponseMimeType: geminiMimeJSON,
+				ResponseSchema: map[string]any{
+					"type":             "OBJECT",
+					"properties":       map[string]any{"example": map[string]any{"type": "STRING", "example": "a"}},
+					"propertyOrdering": []any{"example"},
+				},
+			},
+			want: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"example": map[string]any{"type": "string"}},
+			},
+		},
+		{
+			name: "JSON schema",
+			config: GeminiGenerationConfig{
+				ResponseMimeType:   geminiMimeJSON,
+				ResponseJSONSchema: map[string]any{"type": "string"},
+			},
+			want: map[string]any{"type": "string"},
+		},
+		{
+			name: "both schemas",
+			config: GeminiGenerationConfig{
+				ResponseMimeType:   geminiMimeJSON,
+				ResponseSchema:     map[string]any{"type": "STRING"},
+				ResponseJSONSchema: map[string]any{"type": "string"},
+			},
+			wantErr: true,
+		},
+		{name: "schema without JSON", config: GeminiGenerationConfig{ResponseSchema: map[string]any{"type": "STRING"}}, wantErr: true},
+		{name: "invalid schema", config: GeminiGenerationConfig{ResponseMimeType: geminiMimeJSON, ResponseJSONSchema: map[string]any{"type": 1}}, wantErr: true},
+		{name: "unsupported keyword", config: GeminiGenerationConfig{ResponseMimeType: geminiMimeJSON, ResponseJSONSchema: map[string]any{"if": map[string]any{"type": "string"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := geminiResponseSchema(&tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("geminiResponseSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("geminiResponseSchema() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,11 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,7 +15,11 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
 	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/session"
+	"github.com/signalridge/clinvoker/internal/util"
 )
 
 func TestNewCustomHandlers(t *testing.T) {
@@ -178,6 +187,67 @@ func TestPromptRequestToServiceRequest(t *testing.T) {
 	}
 }
 
+func TestHandlePrompt_JSONSchema(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+	handlers := NewCustomHandlers(service.NewExecutor())
+
+	tests := []struct {
+		name   string
+		schema map[string]any
+		format string
+	}{
+		{name: "invalid schema", schema: map[string]any{"type": "object", "pattern": "("}},
+		{name: "unsupported keyword", schema: map[string]any{"type": "string", "if": map[string]any{"minLength": 1}}},
+		{name: "stream-json output", schema: map[string]any{"type": "object"}, format: "stream-json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := handlers.HandlePrompt(context.Background(), &PromptInput{Body: PromptRequest{
+				Backend:      "claude",
+				Prompt:       "test prompt",
+				OutputFormat: tt.format,
+				JSONSchema:   tt.schema,
+			}})
+			var statusErr huma.StatusError
+			if !errors.As(err, &statusErr) || statusErr.GetStatus() != http.StatusBadRequest {
+				t.Errorf("HandlePrompt() error = %v, want 400", err)
+			}
+		})
+	}
+}
+
+func TestHandlePrompt_MCPServers(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+	handlers := NewCustomHandlers(service.NewExecutor())
+	servers := map[string]backend.MCPServer{"shell": {Command: "sh", Args: []string{"-c", "id"}}}
+
+	_, err := handlers.HandlePrompt(context.Background(), &PromptInput{Body: PromptRequest{
+		Backend:    "claude",
+		Prompt:     "test prompt",
+		MCPServers: servers,
+	}})
+	var statusErr huma.StatusError
+	if !errors.As(err, &statusErr) || statusErr.GetStatus() != http.StatusBadRequest {
+		t.Errorf("HandlePrompt() error = %v, want 400", err)
+	}
+
+	_, err = handlers.HandleParallel(context.Background(), &ParallelInput{Body: ParallelRequest{
+		Tasks: []ParallelTask{{Backend: "claude", Prompt: "test prompt", MCPServers: servers}},
+	}})
+	if !errors.As(err, &statusErr) || statusErr.GetStatus() != http.StatusBadRequest {
+		t.Errorf("HandleParallel() error = %v, want 400", err)
+	}
+}
+
 func TestFromServiceResult(t *testing.T) {
 	svcResult := &service.PromptResult{
 		SessionID:  "test-session-123",
@@ -226,6 +296,12 @@ func TestHandleBackends(t *testing.T) {
 		if b.Name == "" {
 			t.Error("backend name should not be empty")
 		}
+		if b.Capabilities.ApprovalModes == nil || b.Capabilities.SandboxModes == nil {
+			t.Errorf("backend %s: expected mode lists to be non-nil", b.Name)
+		}
+		if b.Name == backend.BackendClaude && !b.Capabilities.SystemPrompt {
+			t.Error("expected claude to report system prompt support")
+		}
 	}
 }
 
@@ -331,6 +407,8 @@ func TestCustomHandlersRegister(t *testing.T) {
 		"/api/v1/backends",
 		"/api/v1/sessions",
 		"/api/v1/sessions/{id}",
+		"/api/v1/sessions/{id}/messages",
+		"/api/v1/admin/circuit-breakers/reset",
 		"/health",
 	}
 
@@ -378,6 +456,38 @@ func TestHandleGetSession_NotFound(t *testing.T) {
 	}
 }
 
+func TestHandleGetSessionMessages(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	store := session.NewStore()
+	sess, err := store.Create("claude", "/tmp")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	turn := &session.Turn{Turn: 1, Prompt: "hello", Response: "hi"}
+	if err := store.AppendTurn(sess.ID, turn, session.TranscriptLimits{}); err != nil {
+		t.Fatalf("failed to append turn: %v", err)
+	}
+
+	handlers := NewCustomHandlers(service.NewExecutor())
+
+	resp, err := handlers.HandleGetSessionMessages(context.Background(), &GetSessionMessagesInput{ID: sess.ID[:8]})
+	if err != nil {
+		t.Fatalf("HandleGetSessionMessages failed: %v", err)
+	}
+	if resp.Body.SessionID != sess.ID {
+		t.Errorf("session_id = %q, want %q", resp.Body.SessionID, sess.ID)
+	}
+	if len(resp.Body.Turns) != 1 || resp.Body.Turns[0].Prompt != "hello" || resp.Body.Turns[0].Response != "hi" {
+		t.Errorf("turns = %+v, want the recorded turn", resp.Body.Turns)
+	}
+
+	_, err = handlers.HandleGetSessionMessages(context.Background(), &GetSessionMessagesInput{ID: "nonexistent-session-id"})
+	if err == nil {
+		t.Error("expected error for nonexistent session")
+	}
+}
+
 func TestHandleDeleteSession_NotFound(t *testing.T) {
 	executor := service.NewExecutor()
 	handlers := NewCustomHandlers(executor)
@@ -441,3 +551,140 @@ func TestHealthResponseIncludesAllFields(t *testing.T) {
 		t.Log("Session store reported as unavailable (may be expected in test env)")
 	}
 }
+
+func TestCircuitBreakerEndpoints(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+	config.Get().CircuitBreaker.FailureThreshold = 1
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-cb-handler", mock.WithAvailable(true))))
+	t.Cleanup(func() { util.ResetBackendBreakers() })
+
+	call, err := util.StartBackendCall("mock-cb-handler")
+	if err != nil {
+		t.Fatalf("StartBackendCall failed: %v", err)
+	}
+	call.Done(1, "backend crashed")
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	handlers := NewCustomHandlers(service.NewExecutor())
+	handlers.Register(api)
+
+	health, err := handlers.HandleHealth(context.Background(), &HealthInput{})
+	if err != nil {
+		t.Fatalf("HandleHealth failed: %v", err)
+	}
+	if health.Body.Status != "degraded" {
+		t.Errorf("expected open circuit to degrade health, got %q", health.Body.Status)
+	}
+	found := false
+	for _, cb := range health.Body.CircuitBreakers {
+		if cb.Backend == "mock-cb-handler" {
+			found = cb.State == "open" && cb.TotalFailures == 1 && cb.LastFailure != nil
+		}
+	}
+	if !found {
+		t.Errorf("expected open breaker in health, got %+v", health.Body.CircuitBreakers)
+	}
+
+	t.Run("reset one backend", func(t *testing.T) {
+		body := strings.NewReader(`{"backend":"mock-cb-handler"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/circuit-breakers/reset", body)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		var resp ResetCircuitBreakersResponseBody
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		if len(resp.Reset) != 1 || resp.Reset[0] != "mock-cb-handler" {
+			t.Errorf("Reset = %v, want [mock-cb-handler]", resp.Reset)
+		}
+		if _, err := util.StartBackendCall("mock-cb-handler"); err != nil {
+			t.Errorf("expected circuit to be closed after reset: %v", err)
+		}
+	})
+
+	t.Run("reset all without body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/circuit-breakers/reset", http.NoBody)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("unknown backend", func(t *testing.T) {
+		body := strings.NewReader(`{"backend":"no-such-backend"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/circuit-breakers/reset", body)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", rec.Code)
+		}
+	})
+}
+
+func TestConcurrencyLimitResponses(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+	server := &config.Get().Server
+	server.BackendConcurrency = map[string]int{"mock-conc-handler": 1}
+	server.QueueSize = 0
+	server.QueueTimeoutSecs = 15
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-conc-handler", mock.WithAvailable(true))))
+
+	// Hold the backend's only process slot.
+	release, err := util.AcquireProcessSlot(context.Background(), "mock-conc-handler")
+	if err != nil {
+		t.Fatalf("AcquireProcessSlot failed: %v", err)
+	}
+	defer release()
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	executor := service.NewExecutor()
+	NewCustomHandlers(executor).Register(api)
+	NewOpenAIHandlers(executor, nil).Register(api)
+	NewAnthropicHandlers(executor, nil).Register(api)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"prompt", "/api/v1/prompt", `{"backend":"mock-conc-handler","prompt":"hi"}`},
+		{"prompt stream", "/api/v1/prompt", `{"backend":"mock-conc-handler","prompt":"hi","output_format":"stream-json"}`},
+		{"openai stream", "/openai/v1/chat/completions", `{"model":"mock-conc-handler","messages":[{"role":"user","content":"hi"}],"stream":true}`},
+		{"anthropic", "/anthropic/v1/messages", `{"model":"mock-conc-handler","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("status = %d, want 429, body = %s", rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Retry-After"); got != "15" {
+				t.Errorf("Retry-After = %q, want 15", got)
+			}
+		})
+	}
+}
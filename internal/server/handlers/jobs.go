@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/job"
+	"github.com/signalridge/clinvoker/internal/output"
+	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/util"
+)
+
+// Job event types recorded besides status events.
+const (
+	// jobEventOutput carries a backend event of a stream-json prompt job.
+	jobEventOutput = "output"
+	// jobEventTask carries the result of a finished parallel task.
+	jobEventTask = "task"
+	// jobEventStep carries the result of a finished chain step.
+	jobEventStep = "step"
+)
+
+// JobHandlers provides handlers for the asynchronous job API.
+type JobHandlers struct {
+	executor *service.Executor
+	jobs     *service.JobManager
+	registry huma.Registry
+	schemas  map[job.Kind]*huma.Schema
+}
+
+// NewJobHandlers creates a new job handlers instance.
+func NewJobHandlers(executor *service.Executor, jobs *service.JobManager) *JobHandlers {
+	return &JobHandlers{executor: executor, jobs: jobs}
+}
+
+// Register registers all job API routes.
+func (h *JobHandlers) Register(api huma.API) {
+	h.registry = api.OpenAPI().Components.Schemas
+	h.schemas = map[job.Kind]*huma.Schema{
+		job.KindPrompt:   h.registry.Schema(reflect.TypeOf(PromptRequest{}), true, ""),
+		job.KindParallel: h.registry.Schema(reflect.TypeOf(ParallelRequest{}), true, ""),
+		job.KindChain:    h.registry.Schema(reflect.TypeOf(ChainRequest{}), true, ""),
+	}
+
+	// The body is validated against the schema of its kind by the handler,
+	// which reports the same errors as the synchronous endpoints.
+	requestBody := &huma.RequestBody{
+		Description: "A prompt, parallel or chain request",
+		Required:    true,
+		Content: map[string]*huma.MediaType{
+			"application/json": {Schema: &huma.Schema{OneOf: []*huma.Schema{
+				h.schemas[job.KindPrompt],
+				h.schemas[job.KindParallel],
+				h.schemas[job.KindChain],
+			}}},
+		},
+	}
+	huma.Register(api, huma.Operation{
+		OperationID:      "createJob",
+		Method:           http.MethodPost,
+		Path:             "/api/v1/jobs",
+		Summary:          "Create job",
+		Description:      "Run a prompt, parallel or chain request in the background and return the job immediately",
+		Tags:             []string{"Jobs"},
+		DefaultStatus:    http.StatusAccepted,
+		RequestBody:      requestBody,
+		SkipValidateBody: true,
+	}, h.HandleCreateJob)
+	// The raw body is read as JSON only.
+	delete(requestBody.Content, "application/octet-stream")
+
+	huma.Register(api, huma.Operation{
+		OperationID: "listJobs",
+		Method:      http.MethodGet,
+		Path:        "/api/v1/jobs",
+		Summary:     "List jobs",
+		Description: "List all jobs, newest first",
+		Tags:        []string{"Jobs"},
+	}, h.HandleListJobs)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "getJob",
+		Method:      http.MethodGet,
+		Path:        "/api/v1/jobs/{id}",
+		Summary:     "Get job",
+		Description: "Get the status and result of a job",
+		Tags:        []string{"Jobs"},
+	}, h.HandleGetJob)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "streamJobEvents",
+		Method:      http.MethodGet,
+		Path:        "/api/v1/jobs/{id}/events",
+		Summary:     "Stream job events",
+		Description: "Stream the events of a job as Server-Sent Events, from the first or after Last-Event-ID",
+		Tags:        []string{"Jobs"},
+	}, h.HandleJobEvents)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "cancelJob",
+		Method:      http.MethodDelete,
+		Path:        "/api/v1/jobs/{id}",
+		Summary:     "Cancel job",
+		Description: "Cancel a pending or running job",
+		Tags:        []string{"Jobs"},
+	}, h.HandleCancelJob)
+}
+
+// CreateJobInput is the input for the create job handler.
+type CreateJobInput struct {
+	RawBody []byte
+}
+
+// HandleCreateJob handles job submissions.
+func (h *JobHandlers) HandleCreateJob(_ context.Context, input *CreateJobInput) (*JobResponse, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(input.RawBody, &fields); err != nil {
+		return nil, huma.Error400BadRequest("request body must be a JSON object", err)
+	}
+
+	kind := jobKind(fields)
+	if err := h.validateBody(kind, fields); err != nil {
+		return nil, err
+	}
+
+	var run service.JobFunc
+	var callbackURL string
+	switch kind {
+	case job.KindParallel:
+		var req ParallelRequest
+		if err := json.Unmarshal(input.RawBody, &req); err != nil {
+			return nil, huma.Error400BadRequest("invalid parallel request", err)
+		}
+		if err := validateParallelRequest(&req); err != nil {
+			return nil, err
+		}
+		run, callbackURL = h.parallelJob(&req), req.CallbackURL
+	case job.KindChain:
+		var req ChainRequest
+		if err := json.Unmarshal(input.RawBody, &req); err != nil {
+			return nil, huma.Error400BadRequest("invalid chain request", err)
+		}
+		if err := validateChainRequest(&req); err != nil {
+			return nil, err
+		}
+		run, callbackURL = h.chainJob(&req), req.CallbackURL
+	default:
+		var req PromptRequest
+		if err := json.Unmarshal(input.RawBody, &req); err != nil {
+			return nil, huma.Error400BadRequest("invalid prompt request", err)
+		}
+		if err := validatePromptRequest(&req); err != nil {
+			return nil, err
+		}
+		run, callbackURL = h.promptJob(&req), req.CallbackURL
+	}
+
+	// The raw body buffer is reused once the handler returns.
+	j, err := h.jobs.Submit(kind, json.RawMessage(bytes.Clone(input.RawBody)), callbackURL, run)
+	if errors.Is(err, service.ErrJobsShuttingDown) {
+		return nil, huma.Error503ServiceUnavailable("server is shutting down")
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to create job", err)
+	}
+
+	return &JobResponse{
+		Location: "/api/v1/jobs/" + j.ID,
+		Body:     FromJob(j),
+	}, nil
+}
+
+// jobKind infers the kind of a job from the fields of its request body.
+func jobKind(fields map[string]any) job.Kind {
+	if _, ok := fields["tasks"]; ok {
+		return job.KindParallel
+	}
+	if _, ok := fields["steps"]; ok {
+		return job.KindChain
+	}
+	return job.KindPrompt
+}
+
+// validateBody validates a job's request body against the schema of its kind.
+func (h *JobHandlers) validateBody(kind job.Kind, fields map[string]any) error {
+	pb := huma.NewPathBuffer([]byte{}, 0)
+	pb.Push("body")
+	res := &huma.ValidateResult{}
+	huma.Validate(h.registry, h.schemas[kind], pb, huma.ModeWriteToServer, fields, res)
+	if len(res.Errors) > 0 {
+		return huma.Error422UnprocessableEntity("validation failed", res.Errors...)
+	}
+	return nil
+}
+
+// promptJob returns the run of a prompt job. A stream-json prompt records
+// the backend's events as output events.
+func (h *JobHandlers) promptJob(req *PromptRequest) service.JobFunc {
+	serviceReq := req.ToServiceRequest()
+	format := backend.OutputFormat(util.ApplyOutputFormatDefault(req.OutputFormat, config.Get()))
+
+	if format != backend.OutputStreamJSON {
+		return func(ctx context.Context, _ func(string, any)) (any, error) {
+			result, err := h.executor.ExecutePrompt(ctx, serviceReq)
+			if err != nil {
+				return nil, err
+			}
+			return FromServiceResult(result), nil
+		}
+	}
+
+	return func(ctx context.Context, emit func(string, any)) (any, error) {
+		start := time.Now()
+		result, err := h.executor.StreamPrompt(ctx, serviceReq, func(event *output.UnifiedEvent) error {
+			emit(jobEventOutput, event)
+			return nil
+		})
+		if result == nil {
+			return nil, err
+		}
+		return FromStreamResult(result, time.Since(start)), err
+	}
+}
+
+// parallelJob returns the run of a parallel job, which records a task
+// event as each task finishes.
+func (h *JobHandlers) parallelJob(req *ParallelRequest) service.JobFunc {
+	return func(ctx context.Context, emit func(string, any)) (any, error) {
+		serviceReq := req.ToServiceRequest()
+		serviceReq.OnResult = func(index int, result *service.PromptResult) {
+			emit(jobEventTask, JobTaskEvent{Index: index, Result: FromServiceResult(result)})
+		}
+		result, err := h.executor.ExecuteParallel(ctx, serviceReq)
+		if err != nil {
+			return nil, err
+		}
+		return FromParallelResult(result), nil
+	}
+}
+
+// chainJob returns the run of a chain job, which records a step event as
+// each step finishes.
+func (h *JobHandlers) chainJob(req *ChainRequest) service.JobFunc {
+	return func(ctx context.Context, emit func(string, any)) (any, error) {
+		serviceReq := req.ToServiceRequest()
+		serviceReq.OnStep = func(result *service.ChainStepResult) {
+			emit(jobEventStep, FromChainStepResult(result))
+		}
+		result, err := h.executor.ExecuteChain(ctx, serviceReq)
+		if result == nil {
+			return nil, err
+		}
+		return FromChainResult(result), err
+	}
+}
+
+// ListJobsInput is the input for the list jobs handler.
+type ListJobsInput struct{}
+
+// HandleListJobs handles job listing requests.
+func (h *JobHandlers) HandleListJobs(_ context.Context, _ *ListJobsInput) (*JobsResponse, error) {
+	jobs, err := h.jobs.List()
+	if err != nil {
+		return nil, huma.Error500InternalServerError("failed to list jobs", err)
+	}
+
+	infos := make([]JobInfo, len(jobs))
+	for i, j := range jobs {
+		infos[i] = FromJob(j)
+		infos[i].Request = nil
+		infos[i].Result = nil
+	}
+
+	return &JobsResponse{
+		Body: JobsResponseBody{
+			Jobs:  infos,
+			Total: len(infos),
+		},
+	}, nil
+}
+
+// GetJobInput is the input for getting a single job.
+type GetJobInput struct {
+	ID string `path:"id" doc:"Job ID"`
+}
+
+// HandleGetJob handles get job requests.
+func (h *JobHandlers) HandleGetJob(_ context.Context, input *GetJobInput) (*JobResponse, error) {
+	j, err := h.jobs.Get(input.ID)
+	if err != nil {
+		return nil, jobError(err)
+	}
+	return &JobResponse{Body: FromJob(j)}, nil
+}
+
+// CancelJobInput is the input for canceling a job.
+type CancelJobInput struct {
+	ID string `path:"id" doc:"Job ID"`
+}
+
+// HandleCancelJob handles cancel job requests. It answers once the job has
+// stopped, with the canceled job.
+func (h *JobHandlers) HandleCancelJob(ctx context.Context, input *CancelJobInput) (*JobResponse, error) {
+	j, err := h.jobs.Cancel(ctx, input.ID)
+	if errors.Is(err, service.ErrJobFinished) {
+		return nil, huma.Error409Conflict("job has already finished")
+	}
+	if err != nil {
+		return nil, jobError(err)
+	}
+	return &JobResponse{Body: FromJob(j)}, nil
+}
+
+// JobEventsInput is the input for streaming job events.
+type JobEventsInput struct {
+	ID          string `path:"id" doc:"Job ID"`
+	LastEventID string `header:"Last-Event-ID" doc:"Resume after the event with this ID"`
+	After       int    `query:"after" minimum:"0" doc:"Resume after the event with this sequence number"`
+}
+
+// HandleJobEvents streams a job's events as Server-Sent Events. Each event
+// carries its sequence number as the SSE ID; the stream ends after the
+// job's final status event.
+func (h *JobHandlers) HandleJobEvents(_ context.Context, input *JobEventsInput) (*huma.StreamResponse, error) {
+	after := input.After
+	if input.LastEventID != "" {
+		seq, err := strconv.Atoi(input.LastEventID)
+		if err != nil || seq < 0 {
+			return nil, huma.Error400BadRequest("Last-Event-ID must be an event sequence number")
+		}
+		after = seq
+	}
+
+	past, live, stop, err := h.jobs.Subscribe(input.ID, after)
+	if err != nil {
+		return nil, jobError(err)
+	}
+
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			defer func() { stop() }()
+			setEventStreamHeaders(hctx)
+
+			last := after
+			write := func(ev job.Event) bool {
+				if ev.Seq <= last {
+					return true
+				}
+				last = ev.Seq
+				return writeSSEEventWithID(hctx, strconv.Itoa(ev.Seq), ev.Type, ev) == nil
+			}
+
+			for {
+				for _, ev := range past {
+					if !write(ev) {
+						return
+					}
+				}
+				if live == nil {
+					return
+				}
+				if !drainJobEvents(hctx.Context(), live, write) {
+					return
+				}
+				// The job finished or this subscriber fell behind; catch
+				// up from the stored events.
+				stop()
+				past, live, stop, err = h.jobs.Subscribe(input.ID, last)
+				if err != nil {
+					return
+				}
+			}
+		},
+	}, nil
+}
+
+// drainJobEvents writes live events until the channel closes. It returns
+// false when the client went away or a write failed.
+func drainJobEvents(ctx context.Context, live <-chan job.Event, write func(job.Event) bool) bool {
+	for {
+		select {
+		case ev, ok := <-live:
+			if !ok {
+				return true
+			}
+			if !write(ev) {
+				return false
+			}
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// jobError converts a job lookup error to an API error.
+func jobError(err error) error {
+	if errors.Is(err, job.ErrNotFound) {
+		return huma.Error404NotFound("job not found")
+	}
+	return huma.Error500InternalServerError("failed to load job", err)
+}
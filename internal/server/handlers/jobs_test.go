@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/job"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+func newJobsTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-jobs", mock.WithAvailable(true))))
+
+	jobs := service.NewJobManager(job.NewStoreWithDir(t.TempDir()), nil)
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	NewJobHandlers(service.NewExecutor(), jobs).Register(api)
+	return router
+}
+
+func serveJobRequest(router http.Handler, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, path, http.NoBody)
+	} else {
+		req = httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, values := range header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// submitJob creates a job and polls until it finishes.
+func submitJob(t *testing.T, router http.Handler, body string) JobInfo {
+	t.Helper()
+	rec := serveJobRequest(router, http.MethodPost, "/api/v1/jobs", body, nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202, body = %s", rec.Code, rec.Body.String())
+	}
+	var created JobInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if got := rec.Header().Get("Location"); got != "/api/v1/jobs/"+created.ID {
+		t.Errorf("Location = %q", got)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		rec := serveJobRequest(router, http.MethodGet, "/api/v1/jobs/"+created.ID, "", nil)
+		var info JobInfo
+		if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+			t.Fatalf("invalid job response: %v", err)
+		}
+		if job.Status(info.Status).Done() {
+			return info
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", created.ID)
+	return JobInfo{}
+}
+
+func TestJobEndpoints(t *testing.T) {
+	router := newJobsTestRouter(t)
+
+	t.Run("prompt job", func(t *testing.T) {
+		info := submitJob(t, router, `{"backend":"mock-jobs","prompt":"hello","ephemeral":true}`)
+		if info.Kind != "prompt" || info.Status != "completed" {
+			t.Fatalf("unexpected job: %+v", info)
+		}
+		var result PromptResponseBody
+		if err := json.Unmarshal(info.Result, &result); err != nil {
+			t.Fatalf("invalid result %s: %v", info.Result, err)
+		}
+		if result.ExitCode != 0 || !strings.Contains(result.Output, "hello") {
+			t.Errorf("unexpected result: %+v", result)
+		}
+
+		rec := serveJobRequest(router, http.MethodDelete, "/api/v1/jobs/"+info.ID, "", nil)
+		if rec.Code != http.StatusConflict {
+			t.Errorf("cancel of a finished job: status = %d, want 409", rec.Code)
+		}
+	})
+
+	t.Run("parallel job events", func(t *testing.T) {
+		info := submitJob(t, router, `{"tasks":[{"backend":"mock-jobs","prompt":"a"},{"backend":"mock-jobs","prompt":"b"}]}`)
+		if info.Kind != "parallel" || info.Status != "completed" {
+			t.Fatalf("unexpected job: %+v", info)
+		}
+
+		rec := serveJobRequest(router, http.MethodGet, "/api/v1/jobs/"+info.ID+"/events", "", nil)
+		if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Content-Type = %q", ct)
+		}
+		stream := rec.Body.String()
+		if n := strings.Count(stream, "event: task\n"); n != 2 {
+			t.Errorf("expected 2 task events, got %d in %s", n, stream)
+		}
+		if !strings.HasPrefix(stream, "id: 1\n") || !strings.Contains(stream, `"status":"completed"`) {
+			t.Errorf("unexpected stream: %s", stream)
+		}
+
+		rec = serveJobRequest(router, http.MethodGet, "/api/v1/jobs/"+info.ID+"/events", "", http.Header{"Last-Event-ID": {"4"}})
+		if stream := rec.Body.String(); !strings.HasPrefix(stream, "id: 5\n") || strings.Count(stream, "id: ") != 1 {
+			t.Errorf("expected only the final event after resuming, got %s", stream)
+		}
+	})
+
+	t.Run("chain job", func(t *testing.T) {
+		info := submitJob(t, router, `{"steps":[{"backend":"mock-jobs","prompt":"one"},{"backend":"mock-jobs","prompt":"{{previous}} two"}]}`)
+		var result ChainResponseBody
+		if err := json.Unmarshal(info.Result, &result); err != nil {
+			t.Fatalf("invalid result %s: %v", info.Result, err)
+		}
+		if info.Kind != "chain" || result.CompletedSteps != 2 {
+			t.Errorf("unexpected job: %+v", info)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		rec := serveJobRequest(router, http.MethodGet, "/api/v1/jobs", "", nil)
+		var resp JobsResponseBody
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		if resp.Total != 3 || resp.Jobs[0].Kind != "chain" || resp.Jobs[0].Result != nil {
+			t.Errorf("unexpected jobs: %+v", resp)
+		}
+	})
+}
+
+func TestJobEndpoints_Errors(t *testing.T) {
+	router := newJobsTestRouter(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"not an object", http.MethodPost, "/api/v1/jobs", `[1]`, http.StatusBadRequest},
+		{"missing prompt", http.MethodPost, "/api/v1/jobs", `{"backend":"mock-jobs"}`, http.StatusUnprocessableEntity},
+		{"unknown field", http.MethodPost, "/api/v1/jobs", `{"backend":"mock-jobs","prompt":"hi","bogus":1}`, http.StatusUnprocessableEntity},
+		{"no tasks", http.MethodPost, "/api/v1/jobs", `{"tasks":[]}`, http.StatusBadRequest},
+		{"chain session", http.MethodPost, "/api/v1/jobs", `{"steps":[{"backend":"mock-jobs","prompt":"hi"}],"persist_sessions":true}`, http.StatusBadRequest},
+		{"unknown job", http.MethodGet, "/api/v1/jobs/0123456789abcdef0123456789abcdef", "", http.StatusNotFound},
+		{"invalid job ID", http.MethodGet, "/api/v1/jobs/nope", "", http.StatusNotFound},
+		{"unknown job events", http.MethodGet, "/api/v1/jobs/0123456789abcdef0123456789abcdef/events", "", http.StatusNotFound},
+		{"cancel unknown job", http.MethodDelete, "/api/v1/jobs/0123456789abcdef0123456789abcdef", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveJobRequest(router, tt.method, tt.path, tt.body, nil)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d, body = %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestJobOpenAPI(t *testing.T) {
+	api := humachi.New(chi.NewRouter(), huma.DefaultConfig("test", "1.0"))
+	NewJobHandlers(service.NewExecutor(), service.NewJobManager(job.NewStoreWithDir(t.TempDir()), nil)).Register(api)
+
+	op := api.OpenAPI().Paths["/api/v1/jobs"].Post
+	if op == nil || op.RequestBody == nil {
+		t.Fatal("createJob request body missing")
+	}
+	if len(op.RequestBody.Content) != 1 || len(op.RequestBody.Content["application/json"].Schema.OneOf) != 3 {
+		t.Errorf("expected a JSON body of one of three schemas, got %+v", op.RequestBody.Content)
+	}
+}
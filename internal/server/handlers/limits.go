@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+	"github.com/signalridge/clinvoker/internal/util"
+)
+
+// concurrencyRejection converts a request turned away by the server's
+// concurrency limits into a 429 (queue full) or 503 (queue timeout) error
+// and the Retry-After value to send with it. ok is false for other errors.
+func concurrencyRejection(err error) (statusErr huma.StatusError, retryAfter string, ok bool) {
+	if !util.IsConcurrencyRejection(err) {
+		return nil, "", false
+	}
+
+	status := http.StatusServiceUnavailable
+	if apperrors.IsCode(err, apperrors.ErrCodeQueueFull) {
+		status = http.StatusTooManyRequests
+	}
+	msg := err.Error()
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		msg = appErr.Message
+	}
+	secs, isInt := apperrors.GetContext(err)["retry_after_secs"].(int)
+	if !isInt || secs < 1 {
+		secs = 1
+	}
+	return huma.NewError(status, msg), strconv.Itoa(secs), true
+}
+
+// executionError returns the error response for a failed prompt execution:
+// the concurrency rejection when the server was too busy to run it, and a
+// 500 otherwise.
+func executionError(msg string, err error) error {
+	if statusErr, retryAfter, ok := concurrencyRejection(err); ok {
+		return huma.ErrorWithHeaders(statusErr, http.Header{"Retry-After": {retryAfter}})
+	}
+	return huma.Error500InternalServerError(msg, err)
+}
+
+// writeConcurrencyRejection writes the rejection response for a streaming
+// request turned away by the server's concurrency limits. It must be called
+// before the stream has started and reports whether err was such a rejection.
+func writeConcurrencyRejection(ctx huma.Context, err error) bool {
+	statusErr, retryAfter, ok := concurrencyRejection(err)
+	if !ok {
+		return false
+	}
+	ctx.SetHeader("Content-Type", "application/problem+json")
+	ctx.SetHeader("Retry-After", retryAfter)
+	ctx.SetStatus(statusErr.GetStatus())
+	_ = json.NewEncoder(ctx.BodyWriter()).Encode(statusErr)
+	return true
+}
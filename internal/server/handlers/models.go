@@ -2,29 +2,41 @@
 package handlers
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/job"
 	"github.com/signalridge/clinvoker/internal/server/service"
 	"github.com/signalridge/clinvoker/internal/session"
+	"github.com/signalridge/clinvoker/internal/util"
+	"github.com/signalridge/clinvoker/internal/webhook"
 )
 
 // PromptRequest is the API request for prompt execution.
 type PromptRequest struct {
-	Backend      string            `json:"backend" doc:"Backend to use (claude, codex, gemini)"`
-	Prompt       string            `json:"prompt" doc:"The prompt to execute"`
-	Model        string            `json:"model,omitempty" doc:"Model to use"`
-	WorkDir      string            `json:"workdir,omitempty" doc:"Working directory"`
-	ApprovalMode string            `json:"approval_mode,omitempty" doc:"Approval mode (default, auto, none, always)"`
-	SandboxMode  string            `json:"sandbox_mode,omitempty" doc:"Sandbox mode (default, read-only, workspace, full)"`
-	OutputFormat string            `json:"output_format,omitempty" doc:"Output format (default, text, json, stream-json)"`
-	MaxTokens    int               `json:"max_tokens,omitempty" doc:"Maximum tokens for response"`
-	MaxTurns     int               `json:"max_turns,omitempty" doc:"Maximum agentic turns"`
-	SystemPrompt string            `json:"system_prompt,omitempty" doc:"Custom system prompt"`
-	Verbose      bool              `json:"verbose,omitempty" doc:"Enable verbose output"`
-	DryRun       bool              `json:"dry_run,omitempty" doc:"Simulate execution"`
-	Ephemeral    bool              `json:"ephemeral,omitempty" doc:"Stateless mode: don't persist session (like standard LLM APIs)"`
-	Extra        []string          `json:"extra,omitempty" doc:"Extra backend-specific flags"`
-	Metadata     map[string]string `json:"metadata,omitempty" doc:"Custom metadata"`
+	Backend           string                       `json:"backend" doc:"Backend to use (claude, codex, gemini)"`
+	Prompt            string                       `json:"prompt" doc:"The prompt to execute"`
+	Model             string                       `json:"model,omitempty" doc:"Model to use"`
+	WorkDir           string                       `json:"workdir,omitempty" doc:"Working directory"`
+	ApprovalMode      string                       `json:"approval_mode,omitempty" doc:"Approval mode (default, auto, none, always)"`
+	SandboxMode       string                       `json:"sandbox_mode,omitempty" doc:"Sandbox mode (default, read-only, workspace, full)"`
+	OutputFormat      string                       `json:"output_format,omitempty" doc:"Output format (default, text, json, stream-json)"`
+	MaxTokens         int                          `json:"max_tokens,omitempty" doc:"Maximum tokens for response"`
+	MaxTurns          int                          `json:"max_turns,omitempty" doc:"Maximum agentic turns"`
+	SystemPrompt      string                       `json:"system_prompt,omitempty" doc:"Custom system prompt"`
+	Verbose           bool                         `json:"verbose,omitempty" doc:"Enable verbose output"`
+	DryRun            bool                         `json:"dry_run,omitempty" doc:"Simulate execution"`
+	Ephemeral         bool                         `json:"ephemeral,omitempty" doc:"Stateless mode: don't persist session (like standard LLM APIs)"`
+	Extra             []string                     `json:"extra,omitempty" doc:"Extra backend-specific flags"`
+	Metadata          map[string]string            `json:"metadata,omitempty" doc:"Custom metadata"`
+	Fallback          []string                     `json:"fallback,omitempty" doc:"Backends to try in order if the backend fails (default: config fallback)"`
+	NoFallback        bool                         `json:"no_fallback,omitempty" doc:"Disable fallback for this request"`
+	Retry             *util.RetryOptions           `json:"retry,omitempty" doc:"Retry policy for transient failures (default: config retry)"`
+	CallbackURL       string                       `json:"callback_url,omitempty" doc:"URL to POST the result to when the request finishes"`
+	JSONSchema        map[string]any               `json:"json_schema,omitempty" doc:"JSON Schema the reply must match; the matching JSON is returned in structured"`
+	JSONSchemaRetries int                          `json:"json_schema_retries,omitempty" minimum:"0" maximum:"5" doc:"Times to ask again for a reply that does not match json_schema"`
+	MCPServers        map[string]backend.MCPServer `json:"mcp_servers,omitempty" doc:"MCP servers the backend connects to, by name, added to the configured mcp_servers"`
 }
 
 // PromptResponse is the API response for prompt execution.
@@ -34,31 +46,42 @@ type PromptResponse struct {
 
 // PromptResponseBody is the body of a prompt response.
 type PromptResponseBody struct {
-	SessionID  string              `json:"session_id,omitempty" doc:"Session ID"`
-	Backend    string              `json:"backend" doc:"Backend used"`
-	ExitCode   int                 `json:"exit_code" doc:"Exit code (0 = success)"`
-	DurationMS int64               `json:"duration_ms" doc:"Execution duration in milliseconds"`
-	Output     string              `json:"output,omitempty" doc:"Command output"`
-	Error      string              `json:"error,omitempty" doc:"Error message if failed"`
-	TokenUsage *session.TokenUsage `json:"token_usage,omitempty" doc:"Token usage statistics"`
+	SessionID  string                 `json:"session_id,omitempty" doc:"Session ID"`
+	Backend    string                 `json:"backend" doc:"Backend used"`
+	ExitCode   int                    `json:"exit_code" doc:"Exit code (0 = success)"`
+	DurationMS int64                  `json:"duration_ms" doc:"Execution duration in milliseconds"`
+	Output     string                 `json:"output,omitempty" doc:"Command output"`
+	Error      string                 `json:"error,omitempty" doc:"Error message if failed"`
+	TokenUsage *session.TokenUsage    `json:"token_usage,omitempty" doc:"Token usage statistics"`
+	Warnings   []string               `json:"warnings,omitempty" doc:"Requested options the backend ignored"`
+	Attempts   []util.FallbackAttempt `json:"attempts,omitempty" doc:"Backends tried when a fallback chain is in effect"`
+	Structured json.RawMessage        `json:"structured,omitempty" doc:"Reply JSON validated against json_schema"`
+}
+
+// CallbackError is the data of a "<kind>.failed" webhook event.
+type CallbackError struct {
+	Error string `json:"error" doc:"Why execution failed"`
 }
 
 // ParallelTask is a single task in parallel execution.
 type ParallelTask struct {
-	Backend      string            `json:"backend" doc:"Backend to use"`
-	Prompt       string            `json:"prompt" doc:"The prompt to execute"`
-	Model        string            `json:"model,omitempty" doc:"Model to use"`
-	WorkDir      string            `json:"workdir,omitempty" doc:"Working directory"`
-	ApprovalMode string            `json:"approval_mode,omitempty" doc:"Approval mode"`
-	SandboxMode  string            `json:"sandbox_mode,omitempty" doc:"Sandbox mode"`
-	OutputFormat string            `json:"output_format,omitempty" doc:"Output format (text, json, stream-json)"`
-	MaxTokens    int               `json:"max_tokens,omitempty" doc:"Maximum tokens"`
-	MaxTurns     int               `json:"max_turns,omitempty" doc:"Maximum turns"`
-	SystemPrompt string            `json:"system_prompt,omitempty" doc:"System prompt override"`
-	Verbose      bool              `json:"verbose,omitempty" doc:"Enable verbose output"`
-	Ephemeral    bool              `json:"ephemeral,omitempty" doc:"Ephemeral mode (no session persistence)"`
-	Extra        []string          `json:"extra,omitempty" doc:"Extra flags"`
-	Metadata     map[string]string `json:"metadata,omitempty" doc:"Task metadata"`
+	Backend      string                       `json:"backend" doc:"Backend to use"`
+	Prompt       string                       `json:"prompt" doc:"The prompt to execute"`
+	Model        string                       `json:"model,omitempty" doc:"Model to use"`
+	WorkDir      string                       `json:"workdir,omitempty" doc:"Working directory"`
+	ApprovalMode string                       `json:"approval_mode,omitempty" doc:"Approval mode"`
+	SandboxMode  string                       `json:"sandbox_mode,omitempty" doc:"Sandbox mode"`
+	OutputFormat string                       `json:"output_format,omitempty" doc:"Output format (text, json, stream-json)"`
+	MaxTokens    int                          `json:"max_tokens,omitempty" doc:"Maximum tokens"`
+	MaxTurns     int                          `json:"max_turns,omitempty" doc:"Maximum turns"`
+	SystemPrompt string                       `json:"system_prompt,omitempty" doc:"System prompt override"`
+	Verbose      bool                         `json:"verbose,omitempty" doc:"Enable verbose output"`
+	Ephemeral    bool                         `json:"ephemeral,omitempty" doc:"Ephemeral mode (no session persistence)"`
+	Extra        []string                     `json:"extra,omitempty" doc:"Extra flags"`
+	Metadata     map[string]string            `json:"metadata,omitempty" doc:"Task metadata"`
+	Fallback     []string                     `json:"fallback,omitempty" doc:"Backends to try in order if the backend fails"`
+	Retry        *util.RetryOptions           `json:"retry,omitempty" doc:"Retry policy for transient failures"`
+	MCPServers   map[string]backend.MCPServer `json:"mcp_servers,omitempty" doc:"MCP servers the backend connects to, by name"`
 }
 
 // ParallelRequest is the API request for parallel execution.
@@ -67,6 +90,7 @@ type ParallelRequest struct {
 	MaxParallel int            `json:"max_parallel,omitempty" doc:"Maximum concurrent tasks"`
 	FailFast    bool           `json:"fail_fast,omitempty" doc:"Stop on first failure"`
 	DryRun      bool           `json:"dry_run,omitempty" doc:"Simulate execution without running commands"`
+	CallbackURL string         `json:"callback_url,omitempty" doc:"URL to POST the result to when the request finishes"`
 }
 
 // ParallelResponse is the API response for parallel execution.
@@ -85,18 +109,19 @@ type ParallelResponseBody struct {
 
 // ChainStep is a step in chain execution.
 type ChainStep struct {
-	Backend      string   `json:"backend" doc:"Backend to use"`
-	Prompt       string   `json:"prompt" doc:"The prompt (supports {{previous}} placeholder)"`
-	Model        string   `json:"model,omitempty" doc:"Model to use"`
-	WorkDir      string   `json:"workdir,omitempty" doc:"Working directory"`
-	ApprovalMode string   `json:"approval_mode,omitempty" doc:"Approval mode"`
-	SandboxMode  string   `json:"sandbox_mode,omitempty" doc:"Sandbox mode"`
-	MaxTokens    int      `json:"max_tokens,omitempty" doc:"Maximum tokens"`
-	MaxTurns     int      `json:"max_turns,omitempty" doc:"Maximum turns"`
-	SystemPrompt string   `json:"system_prompt,omitempty" doc:"System prompt override"`
-	Verbose      bool     `json:"verbose,omitempty" doc:"Enable verbose output"`
-	Extra        []string `json:"extra,omitempty" doc:"Extra flags"`
-	Name         string   `json:"name,omitempty" doc:"Step name for display"`
+	Backend      string             `json:"backend" doc:"Backend to use"`
+	Prompt       string             `json:"prompt" doc:"The prompt (supports {{previous}} placeholder)"`
+	Model        string             `json:"model,omitempty" doc:"Model to use"`
+	WorkDir      string             `json:"workdir,omitempty" doc:"Working directory"`
+	ApprovalMode string             `json:"approval_mode,omitempty" doc:"Approval mode"`
+	SandboxMode  string             `json:"sandbox_mode,omitempty" doc:"Sandbox mode"`
+	MaxTokens    int                `json:"max_tokens,omitempty" doc:"Maximum tokens"`
+	MaxTurns     int                `json:"max_turns,omitempty" doc:"Maximum turns"`
+	SystemPrompt string             `json:"system_prompt,omitempty" doc:"System prompt override"`
+	Verbose      bool               `json:"verbose,omitempty" doc:"Enable verbose output"`
+	Extra        []string           `json:"extra,omitempty" doc:"Extra flags"`
+	Name         string             `json:"name,omitempty" doc:"Step name for display"`
+	Retry        *util.RetryOptions `json:"retry,omitempty" doc:"Retry policy for transient failures"`
 }
 
 // ChainRequest is the API request for chain execution.
@@ -107,18 +132,20 @@ type ChainRequest struct {
 	PassWorkingDir  bool        `json:"pass_working_dir,omitempty" doc:"Pass working directory between steps"`
 	PersistSessions bool        `json:"persist_sessions,omitempty" doc:"Unsupported (chain is always ephemeral)"`
 	DryRun          bool        `json:"dry_run,omitempty" doc:"Simulate execution without running commands"`
+	CallbackURL     string      `json:"callback_url,omitempty" doc:"URL to POST the result to when the request finishes"`
 }
 
 // ChainStepResult is the result of a single chain step.
 type ChainStepResult struct {
-	Step       int    `json:"step" doc:"Step number (1-indexed)"`
-	Name       string `json:"name,omitempty" doc:"Step name"`
-	Backend    string `json:"backend" doc:"Backend used"`
-	ExitCode   int    `json:"exit_code" doc:"Exit code"`
-	Error      string `json:"error,omitempty" doc:"Error message"`
-	SessionID  string `json:"session_id,omitempty" doc:"Session ID"`
-	DurationMS int64  `json:"duration_ms" doc:"Duration in milliseconds"`
-	Output     string `json:"output,omitempty" doc:"Command output"`
+	Step       int                    `json:"step" doc:"Step number (1-indexed)"`
+	Name       string                 `json:"name,omitempty" doc:"Step name"`
+	Backend    string                 `json:"backend" doc:"Backend used"`
+	ExitCode   int                    `json:"exit_code" doc:"Exit code"`
+	Error      string                 `json:"error,omitempty" doc:"Error message"`
+	SessionID  string                 `json:"session_id,omitempty" doc:"Session ID"`
+	DurationMS int64                  `json:"duration_ms" doc:"Duration in milliseconds"`
+	Output     string                 `json:"output,omitempty" doc:"Command output"`
+	Attempts   []util.FallbackAttempt `json:"attempts,omitempty" doc:"Runs of the step when it was retried or fell back"`
 }
 
 // ChainResponse is the API response for chain execution.
@@ -137,12 +164,13 @@ type ChainResponseBody struct {
 
 // CompareRequest is the API request for compare execution.
 type CompareRequest struct {
-	Backends   []string `json:"backends" doc:"Backends to compare"`
-	Prompt     string   `json:"prompt" doc:"The prompt to run on all backends"`
-	Model      string   `json:"model,omitempty" doc:"Model to use (if applicable)"`
-	WorkDir    string   `json:"workdir,omitempty" doc:"Working directory"`
-	Sequential bool     `json:"sequential,omitempty" doc:"Run sequentially instead of parallel"`
-	DryRun     bool     `json:"dry_run,omitempty" doc:"Simulate execution without running commands"`
+	Backends    []string `json:"backends" doc:"Backends to compare"`
+	Prompt      string   `json:"prompt" doc:"The prompt to run on all backends"`
+	Model       string   `json:"model,omitempty" doc:"Model to use (if applicable)"`
+	WorkDir     string   `json:"workdir,omitempty" doc:"Working directory"`
+	Sequential  bool     `json:"sequential,omitempty" doc:"Run sequentially instead of parallel"`
+	DryRun      bool     `json:"dry_run,omitempty" doc:"Simulate execution without running commands"`
+	CallbackURL string   `json:"callback_url,omitempty" doc:"URL to POST the result to when the request finishes"`
 }
 
 // CompareBackendResult is the result from one backend.
@@ -171,8 +199,27 @@ type CompareResponseBody struct {
 
 // BackendInfo represents information about a backend.
 type BackendInfo struct {
-	Name      string `json:"name" doc:"Backend name"`
-	Available bool   `json:"available" doc:"Whether the backend is available"`
+	Name         string               `json:"name" doc:"Backend name"`
+	Available    bool                 `json:"available" doc:"Whether the backend is available"`
+	Capabilities backend.Capabilities `json:"capabilities" doc:"Unified options the backend supports"`
+	Pools        []string             `json:"pools,omitempty" doc:"Pools the backend is a member of"`
+	Pool         *PoolStatus          `json:"pool,omitempty" doc:"Members of a backend pool and their requests in flight"`
+}
+
+// PoolStatus describes a backend pool.
+type PoolStatus struct {
+	Strategy string             `json:"strategy" doc:"Balancing strategy (round_robin, least_busy, weighted)"`
+	InFlight int                `json:"in_flight" doc:"Requests in flight across all members"`
+	Members  []PoolMemberStatus `json:"members" doc:"Member backends"`
+}
+
+// PoolMemberStatus describes a member of a backend pool.
+type PoolMemberStatus struct {
+	Backend        string `json:"backend" doc:"Member backend name"`
+	Available      bool   `json:"available" doc:"Whether the member backend is available"`
+	Weight         int    `json:"weight" doc:"Share of requests with the weighted strategy"`
+	MaxConcurrency int    `json:"max_concurrency,omitempty" doc:"Maximum requests in flight (0 = unlimited)"`
+	InFlight       int    `json:"in_flight" doc:"Requests in flight"`
 }
 
 // BackendsResponse is the API response for listing backends.
@@ -219,6 +266,17 @@ type SessionResponse struct {
 	Body SessionInfo
 }
 
+// SessionMessagesResponse is the API response for a session transcript.
+type SessionMessagesResponse struct {
+	Body SessionMessagesResponseBody
+}
+
+// SessionMessagesResponseBody is the body of a session transcript response.
+type SessionMessagesResponseBody struct {
+	SessionID string         `json:"session_id" doc:"Session ID"`
+	Turns     []session.Turn `json:"turns" doc:"Recorded turns, oldest first; turns dropped by rotation are missing"`
+}
+
 // DeleteSessionResponse is the API response for deleting a session.
 type DeleteSessionResponse struct {
 	Body DeleteSessionResponseBody
@@ -243,12 +301,43 @@ type HealthResponseBody struct {
 	UptimeMillis int64                 `json:"uptime_ms" doc:"Server uptime in milliseconds"`
 	Backends     []BackendHealthStatus `json:"backends,omitempty" doc:"Backend availability status"`
 	SessionStore SessionStoreStatus    `json:"session_store" doc:"Session store status"`
+
+	CircuitBreakers []CircuitBreakerStatus `json:"circuit_breakers,omitempty" doc:"Circuit breakers of backends called so far"`
 }
 
 // BackendHealthStatus represents the health status of a backend.
 type BackendHealthStatus struct {
-	Name      string `json:"name" doc:"Backend name"`
-	Available bool   `json:"available" doc:"Whether the backend is available"`
+	Name         string `json:"name" doc:"Backend name"`
+	Available    bool   `json:"available" doc:"Whether the backend is available"`
+	CircuitState string `json:"circuit_state,omitempty" doc:"Circuit breaker state (closed, open, or half-open)"`
+}
+
+// CircuitBreakerStatus represents the circuit breaker of a backend.
+type CircuitBreakerStatus struct {
+	Backend        string     `json:"backend" doc:"Backend name"`
+	State          string     `json:"state" doc:"Circuit state (closed, open, or half-open)"`
+	FailureCount   int        `json:"failure_count" doc:"Consecutive failures in the current state"`
+	TotalFailures  int64      `json:"total_failures" doc:"Total failed calls"`
+	TotalSuccesses int64      `json:"total_successes" doc:"Total successful calls"`
+	TotalRejected  int64      `json:"total_rejected" doc:"Total calls rejected while open"`
+	LastFailure    *time.Time `json:"last_failure,omitempty" doc:"Time of the last failure"`
+	LastChange     time.Time  `json:"last_change" doc:"Time of the last state change"`
+}
+
+// ResetCircuitBreakersRequest is the request to reset circuit breakers.
+type ResetCircuitBreakersRequest struct {
+	Backend string `json:"backend,omitempty" doc:"Backend to reset; all backends when empty"`
+}
+
+// ResetCircuitBreakersResponse is the API response for resetting circuit breakers.
+type ResetCircuitBreakersResponse struct {
+	Body ResetCircuitBreakersResponseBody
+}
+
+// ResetCircuitBreakersResponseBody is the body of a reset circuit breakers response.
+type ResetCircuitBreakersResponseBody struct {
+	Reset           []string               `json:"reset" doc:"Backends whose circuit was reset"`
+	CircuitBreakers []CircuitBreakerStatus `json:"circuit_breakers" doc:"Circuit breakers after the reset"`
 }
 
 // SessionStoreStatus represents the health status of the session store.
@@ -258,24 +347,99 @@ type SessionStoreStatus struct {
 	Error        string `json:"error,omitempty" doc:"Error message if unavailable"`
 }
 
+// FromCircuitBreakerInfo converts service circuit breaker info to API status.
+func FromCircuitBreakerInfo(infos []service.CircuitBreakerInfo) []CircuitBreakerStatus {
+	statuses := make([]CircuitBreakerStatus, len(infos))
+	for i, info := range infos {
+		statuses[i] = CircuitBreakerStatus{
+			Backend:        info.Backend,
+			State:          info.State,
+			FailureCount:   info.FailureCount,
+			TotalFailures:  info.TotalFailures,
+			TotalSuccesses: info.TotalSuccesses,
+			TotalRejected:  info.TotalRejected,
+			LastChange:     info.LastChange,
+		}
+		if !info.LastFailure.IsZero() {
+			lastFailure := info.LastFailure
+			statuses[i].LastFailure = &lastFailure
+		}
+	}
+	return statuses
+}
+
+// FromPoolInfo converts service pool info to its API form.
+func FromPoolInfo(info *service.PoolInfo) *PoolStatus {
+	if info == nil {
+		return nil
+	}
+	status := &PoolStatus{
+		Strategy: info.Strategy,
+		InFlight: info.InFlight,
+		Members:  make([]PoolMemberStatus, len(info.Members)),
+	}
+	for i, m := range info.Members {
+		status.Members[i] = PoolMemberStatus(m)
+	}
+	return status
+}
+
 // ToServiceRequest converts API request to service request.
 func (r *PromptRequest) ToServiceRequest() *service.PromptRequest {
 	return &service.PromptRequest{
-		Backend:      r.Backend,
-		Prompt:       r.Prompt,
-		Model:        r.Model,
-		WorkDir:      r.WorkDir,
-		ApprovalMode: r.ApprovalMode,
-		SandboxMode:  r.SandboxMode,
-		OutputFormat: r.OutputFormat,
-		MaxTokens:    r.MaxTokens,
-		MaxTurns:     r.MaxTurns,
-		SystemPrompt: r.SystemPrompt,
-		Verbose:      r.Verbose,
-		DryRun:       r.DryRun,
-		Ephemeral:    r.Ephemeral,
-		Extra:        r.Extra,
-		Metadata:     r.Metadata,
+		Backend:           r.Backend,
+		Prompt:            r.Prompt,
+		Model:             r.Model,
+		WorkDir:           r.WorkDir,
+		ApprovalMode:      r.ApprovalMode,
+		SandboxMode:       r.SandboxMode,
+		OutputFormat:      r.OutputFormat,
+		MaxTokens:         r.MaxTokens,
+		MaxTurns:          r.MaxTurns,
+		SystemPrompt:      r.SystemPrompt,
+		Verbose:           r.Verbose,
+		DryRun:            r.DryRun,
+		Ephemeral:         r.Ephemeral,
+		Extra:             r.Extra,
+		Metadata:          r.Metadata,
+		Fallback:          r.Fallback,
+		NoFallback:        r.NoFallback,
+		Retry:             r.Retry,
+		JSONSchema:        r.JSONSchema,
+		JSONSchemaRetries: r.JSONSchemaRetries,
+		MCPServers:        r.MCPServers,
+	}
+}
+
+// FromSessionInfo converts service session info to API session info.
+func FromSessionInfo(s *service.SessionInfo) SessionInfo {
+	return SessionInfo{
+		ID:            s.ID,
+		Backend:       s.Backend,
+		CreatedAt:     s.CreatedAt,
+		LastUsed:      s.LastUsed,
+		WorkingDir:    s.WorkingDir,
+		Model:         s.Model,
+		InitialPrompt: s.InitialPrompt,
+		Status:        s.Status,
+		TurnCount:     s.TurnCount,
+		TokenUsage:    s.TokenUsage,
+		Tags:          s.Tags,
+		Title:         s.Title,
+	}
+}
+
+// FromStreamResult converts the result of a streamed prompt, which took
+// duration, to API response body. The output was streamed, so it is left out.
+func FromStreamResult(r *service.StreamResult, duration time.Duration) PromptResponseBody {
+	return PromptResponseBody{
+		SessionID:  r.SessionID,
+		Backend:    r.Backend,
+		ExitCode:   r.ExitCode,
+		DurationMS: duration.Milliseconds(),
+		Error:      r.Error,
+		TokenUsage: r.TokenUsage,
+		Attempts:   r.Attempts,
 	}
 }
 
@@ -289,5 +453,225 @@ func FromServiceResult(r *service.PromptResult) PromptResponseBody {
 		Output:     r.Output,
 		Error:      r.Error,
 		TokenUsage: r.TokenUsage,
+		Warnings:   r.Warnings,
+		Attempts:   r.Attempts,
+		Structured: r.Structured,
+	}
+}
+
+// ToServiceRequest converts API request to service request.
+func (r *ParallelRequest) ToServiceRequest() *service.ParallelRequest {
+	req := &service.ParallelRequest{
+		MaxParallel: r.MaxParallel,
+		FailFast:    r.FailFast,
+		DryRun:      r.DryRun,
+		Tasks:       make([]service.PromptRequest, len(r.Tasks)),
+	}
+	for i, t := range r.Tasks {
+		req.Tasks[i] = service.PromptRequest{
+			Backend:      t.Backend,
+			Prompt:       t.Prompt,
+			Model:        t.Model,
+			WorkDir:      t.WorkDir,
+			ApprovalMode: t.ApprovalMode,
+			SandboxMode:  t.SandboxMode,
+			OutputFormat: t.OutputFormat,
+			MaxTokens:    t.MaxTokens,
+			MaxTurns:     t.MaxTurns,
+			SystemPrompt: t.SystemPrompt,
+			Verbose:      t.Verbose,
+			Ephemeral:    t.Ephemeral,
+			Extra:        t.Extra,
+			Metadata:     t.Metadata,
+			Fallback:     t.Fallback,
+			Retry:        t.Retry,
+			MCPServers:   t.MCPServers,
+		}
+	}
+	return req
+}
+
+// FromParallelResult converts service parallel result to API response body.
+func FromParallelResult(r *service.ParallelResult) ParallelResponseBody {
+	results := make([]PromptResponseBody, len(r.Results))
+	for i := range r.Results {
+		results[i] = FromServiceResult(&r.Results[i])
+	}
+	return ParallelResponseBody{
+		TotalTasks:    r.TotalTasks,
+		Completed:     r.Completed,
+		Failed:        r.Failed,
+		TotalDuration: r.TotalDuration,
+		Results:       results,
+	}
+}
+
+// ToServiceRequest converts API request to service request.
+func (r *ChainRequest) ToServiceRequest() *service.ChainRequest {
+	req := &service.ChainRequest{
+		StopOnFailure:  r.StopOnFailure,
+		PassWorkingDir: r.PassWorkingDir,
+		DryRun:         r.DryRun,
+		Steps:          make([]service.ChainStep, len(r.Steps)),
+	}
+	for i, s := range r.Steps {
+		req.Steps[i] = service.ChainStep{
+			Backend:      s.Backend,
+			Prompt:       s.Prompt,
+			Model:        s.Model,
+			WorkDir:      s.WorkDir,
+			ApprovalMode: s.ApprovalMode,
+			SandboxMode:  s.SandboxMode,
+			MaxTokens:    s.MaxTokens,
+			MaxTurns:     s.MaxTurns,
+			SystemPrompt: s.SystemPrompt,
+			Verbose:      s.Verbose,
+			Extra:        s.Extra,
+			Name:         s.Name,
+			Retry:        s.Retry,
+		}
+	}
+	return req
+}
+
+// FromChainStepResult converts a service chain step result to its API form.
+func FromChainStepResult(r *service.ChainStepResult) ChainStepResult {
+	return ChainStepResult{
+		Step:       r.Step,
+		Name:       r.Name,
+		Backend:    r.Backend,
+		ExitCode:   r.ExitCode,
+		Error:      r.Error,
+		SessionID:  r.SessionID,
+		DurationMS: r.DurationMS,
+		Output:     r.Output,
+		Attempts:   r.Attempts,
+	}
+}
+
+// FromChainResult converts service chain result to API response body.
+func FromChainResult(r *service.ChainResult) ChainResponseBody {
+	results := make([]ChainStepResult, len(r.Results))
+	for i := range r.Results {
+		results[i] = FromChainStepResult(&r.Results[i])
+	}
+	return ChainResponseBody{
+		TotalSteps:     r.TotalSteps,
+		CompletedSteps: r.CompletedSteps,
+		FailedStep:     r.FailedStep,
+		TotalDuration:  r.TotalDuration,
+		Results:        results,
+	}
+}
+
+// JobInfo represents an asynchronous job.
+type JobInfo struct {
+	ID         string          `json:"id" doc:"Job ID"`
+	Kind       string          `json:"kind" doc:"Request kind (prompt, parallel, chain)"`
+	Status     string          `json:"status" doc:"Job status (pending, running, completed, failed, canceled)"`
+	Error      string          `json:"error,omitempty" doc:"Why the job failed or was canceled"`
+	CreatedAt  time.Time       `json:"created_at" doc:"When the job was submitted"`
+	StartedAt  *time.Time      `json:"started_at,omitempty" doc:"When the job started running"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty" doc:"When the job finished"`
+	Request    json.RawMessage `json:"request,omitempty" doc:"Submitted request body"`
+	Result     json.RawMessage `json:"result,omitempty" doc:"Response body of the request, as its synchronous endpoint returns it"`
+}
+
+// JobResponse is the API response for a single job.
+type JobResponse struct {
+	Location string `header:"Location" doc:"URL of the job"`
+	Body     JobInfo
+}
+
+// JobsResponse is the API response for listing jobs.
+type JobsResponse struct {
+	Body JobsResponseBody
+}
+
+// JobsResponseBody is the body of a jobs response.
+type JobsResponseBody struct {
+	Jobs  []JobInfo `json:"jobs" doc:"Jobs, newest first, without requests and results"`
+	Total int       `json:"total" doc:"Total number of jobs"`
+}
+
+// JobTaskEvent is the data of a parallel job's task event.
+type JobTaskEvent struct {
+	Index  int                `json:"index" doc:"Task index (0-based)"`
+	Result PromptResponseBody `json:"result" doc:"Task result"`
+}
+
+// FromJob converts a job to its API form.
+func FromJob(j *job.Job) JobInfo {
+	return JobInfo{
+		ID:         j.ID,
+		Kind:       string(j.Kind),
+		Status:     string(j.Status),
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		StartedAt:  j.StartedAt,
+		FinishedAt: j.FinishedAt,
+		Request:    j.Request,
+		Result:     j.Result,
+	}
+}
+
+// WebhookDelivery represents the delivery of a result to a callback URL.
+type WebhookDelivery struct {
+	ID            string           `json:"id" doc:"Delivery ID, also sent in the X-Clinvk-Delivery header"`
+	Event         string           `json:"event" doc:"Event delivered, such as prompt.completed or job.failed"`
+	URL           string           `json:"url" doc:"Callback URL, without credentials or query string"`
+	Subject       string           `json:"subject,omitempty" doc:"ID of the job or session the delivery reports on"`
+	Status        string           `json:"status" doc:"Delivery status (pending, delivered, failed)"`
+	Attempts      []WebhookAttempt `json:"attempts" doc:"Attempts made so far, oldest first"`
+	CreatedAt     time.Time        `json:"created_at" doc:"When the event occurred"`
+	NextAttemptAt *time.Time       `json:"next_attempt_at,omitempty" doc:"When the next attempt is due"`
+	FinishedAt    *time.Time       `json:"finished_at,omitempty" doc:"When the delivery succeeded or was given up"`
+}
+
+// WebhookAttempt is a single attempt of a webhook delivery.
+type WebhookAttempt struct {
+	Time       time.Time `json:"time" doc:"When the attempt was made"`
+	StatusCode int       `json:"status_code,omitempty" doc:"HTTP status of the receiver's response"`
+	Error      string    `json:"error,omitempty" doc:"Why the attempt failed"`
+	DurationMS int64     `json:"duration_ms" doc:"Duration in milliseconds"`
+}
+
+// WebhookDeliveryResponse is the API response for a single webhook delivery.
+type WebhookDeliveryResponse struct {
+	Body WebhookDelivery
+}
+
+// WebhookDeliveriesResponse is the API response for listing webhook deliveries.
+type WebhookDeliveriesResponse struct {
+	Body WebhookDeliveriesResponseBody
+}
+
+// WebhookDeliveriesResponseBody is the body of a webhook deliveries response.
+type WebhookDeliveriesResponseBody struct {
+	Deliveries []WebhookDelivery `json:"deliveries" doc:"Deliveries, newest first"`
+	Total      int               `json:"total" doc:"Number of deliveries returned"`
+}
+
+// FromDelivery converts a webhook delivery to its API form.
+func FromDelivery(d webhook.Delivery) WebhookDelivery {
+	attempts := make([]WebhookAttempt, len(d.Attempts))
+	for i, a := range d.Attempts {
+		attempts[i] = WebhookAttempt{
+			Time:       a.Time,
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+			DurationMS: a.DurationMS,
+		}
+	}
+	return WebhookDelivery{
+		ID:            d.ID,
+		Event:         d.Event,
+		URL:           d.URL,
+		Subject:       d.Subject,
+		Status:        string(d.Status),
+		Attempts:      attempts,
+		CreatedAt:     d.CreatedAt,
+		NextAttemptAt: d.NextAttemptAt,
+		FinishedAt:    d.FinishedAt,
 	}
 }
@@ -14,12 +14,14 @@ import (
 	"github.com/signalridge/clinvoker/internal/backend"
 	"github.com/signalridge/clinvoker/internal/output"
 	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/util"
 )
 
 // OpenAIHandlers provides handlers for OpenAI-compatible API.
 type OpenAIHandlers struct {
-	runner service.PromptRunner
-	logger *slog.Logger
+	runner        service.PromptRunner
+	conversations *service.Conversations
+	logger        *slog.Logger
 }
 
 const (
@@ -35,6 +37,12 @@ func NewOpenAIHandlers(runner service.PromptRunner, logger *slog.Logger) *OpenAI
 	return &OpenAIHandlers{runner: runner, logger: logger}
 }
 
+// SetConversations enables stateful conversations, continued on the
+// sessions of conversations.
+func (h *OpenAIHandlers) SetConversations(conversations *service.Conversations) {
+	h.conversations = conversations
+}
+
 // Register registers all OpenAI-compatible API routes.
 // Endpoints follow OpenAI API spec: https://platform.openai.com/docs/api-reference
 func (h *OpenAIHandlers) Register(api huma.API) {
@@ -71,14 +79,45 @@ func (h *OpenAIHandlers) Register(api huma.API) {
 		},
 		Tags: []string{"OpenAI Compatible"},
 	}, h.HandleChatCompletions)
+
+	// Responses endpoint - POST /openai/v1/responses
+	huma.Register(api, huma.Operation{
+		OperationID: "openaiResponses",
+		Method:      http.MethodPost,
+		Path:        "/openai/v1/responses",
+		Summary:     "Create response",
+		Description: "Creates a model response, continuing previous_response_id on its clinvk session. Compatible with OpenAI POST /v1/responses.",
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "OK",
+				Content: map[string]*huma.MediaType{
+					"application/json": {},
+					"text/event-stream": {
+						Schema: &huma.Schema{
+							Type:        huma.TypeString,
+							Description: "Server-sent events stream of response events (when stream=true).",
+						},
+					},
+				},
+			},
+		},
+		Tags: []string{"OpenAI Compatible"},
+	}, h.HandleResponses)
 }
 
 // OpenAIModel represents an OpenAI model object.
+// Fields after OwnedBy are clinvoker extensions taken from the model catalog.
 type OpenAIModel struct {
-	ID      string `json:"id"`
-	Object  string `json:"object"`
-	Created int64  `json:"created"`
-	OwnedBy string `json:"owned_by"`
+	ID              string  `json:"id"`
+	Object          string  `json:"object"`
+	Created         int64   `json:"created"`
+	OwnedBy         string  `json:"owned_by"`
+	Backend         string  `json:"backend,omitempty"`
+	DisplayName     string  `json:"display_name,omitempty"`
+	ContextWindow   int     `json:"context_window,omitempty"`
+	MaxOutputTokens int     `json:"max_output_tokens,omitempty"`
+	InputPrice      float64 `json:"input_price,omitempty"`
+	OutputPrice     float64 `json:"output_price,omitempty"`
 }
 
 // OpenAIModelsResponse is the response for listing models.
@@ -96,18 +135,36 @@ type OpenAIModelsResponseBody struct {
 type OpenAIModelsInput struct{}
 
 // HandleModels handles the GET /v1/models endpoint.
+// It lists the catalog models of every registered backend, followed by the
+// backend and pool names themselves, which are accepted as model IDs too.
 func (h *OpenAIHandlers) HandleModels(ctx context.Context, _ *OpenAIModelsInput) (*OpenAIModelsResponse, error) {
-	backends := backend.List()
+	catalog := backend.ListModels()
+	backends := append(backend.List(), util.PoolNames()...)
 	created := time.Now().Unix()
 
-	models := make([]OpenAIModel, len(backends))
-	for i, name := range backends {
-		models[i] = OpenAIModel{
+	models := make([]OpenAIModel, 0, len(catalog)+len(backends))
+	for _, m := range catalog {
+		models = append(models, OpenAIModel{
+			ID:              m.ID,
+			Object:          "model",
+			Created:         created,
+			OwnedBy:         m.Backend,
+			Backend:         m.Backend,
+			DisplayName:     m.DisplayName,
+			ContextWindow:   m.ContextWindow,
+			MaxOutputTokens: m.MaxOutputTokens,
+			InputPrice:      m.InputPrice,
+			OutputPrice:     m.OutputPrice,
+		})
+	}
+	for _, name := range backends {
+		models = append(models, OpenAIModel{
 			ID:      name,
 			Object:  "model",
 			Created: created,
 			OwnedBy: "clinvoker",
-		}
+			Backend: name,
+		})
 	}
 
 	return &OpenAIModelsResponse{
@@ -120,8 +177,67 @@ func (h *OpenAIHandlers) HandleModels(ctx context.Context, _ *OpenAIModelsInput)
 
 // OpenAIMessage represents a chat message.
 type OpenAIMessage struct {
-	Role    string `json:"role" doc:"Message role (system, user, assistant)"`
-	Content string `json:"content" doc:"Message content"`
+	Role    string `json:"role" doc:"Message role (system, user, assistant, tool)"`
+	Content string `json:"content" required:"false" nullable:"true" doc:"Message content"`
+	Name    string `json:"name,omitempty" doc:"Name of the participant"`
+	// ToolCalls are the tools called by an assistant message.
+	ToolCalls []OpenAIToolCall `json:"tool_calls,omitempty" doc:"Tools called by the assistant"`
+	// ToolCallID is the call a tool message answers.
+	ToolCallID string `json:"tool_call_id,omitempty" doc:"Tool call answered by a tool message"`
+	// Parts holds the content of a request message sent as a list of
+	// content parts rather than a string.
+	Parts []OpenAIContentPart `json:"-"`
+}
+
+// OpenAIContentPart is a part of the content of a message.
+type OpenAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *OpenAIImageURL `json:"image_url,omitempty"`
+	File     *OpenAIFile     `json:"file,omitempty"`
+}
+
+// OpenAIImageURL is the image of an image_url content part: a data URL or
+// a link.
+type OpenAIImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// OpenAIFile is the file of a file content part.
+type OpenAIFile struct {
+	FileData string `json:"file_data,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// UnmarshalJSON decodes a message whose content is a string or a list of
+// content parts.
+func (m *OpenAIMessage) UnmarshalJSON(data []byte) error {
+	type message OpenAIMessage
+	var raw struct {
+		message
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = OpenAIMessage(raw.message)
+	if len(raw.Content) == 0 || string(raw.Content) == "null" {
+		return nil
+	}
+	if raw.Content[0] == '[' {
+		return json.Unmarshal(raw.Content, &m.Parts)
+	}
+	return json.Unmarshal(raw.Content, &m.Content)
+}
+
+// TransformSchema documents that content may be a list of content parts.
+func (m OpenAIMessage) TransformSchema(_ huma.Registry, s *huma.Schema) *huma.Schema {
+	if _, ok := s.Properties["content"]; ok {
+		s.Properties["content"] = contentSchema("Message content: a string, or a list of text, image_url and file parts")
+	}
+	return s
 }
 
 // OpenAIChatCompletionRequest is the request for chat completions.
@@ -131,12 +247,21 @@ type OpenAIChatCompletionRequest struct {
 	MaxTokens        int             `json:"max_tokens,omitempty" doc:"Maximum tokens to generate"`
 	Temperature      float64         `json:"temperature,omitempty" doc:"Sampling temperature"`
 	TopP             float64         `json:"top_p,omitempty" doc:"Nucleus sampling parameter"`
-	N                int             `json:"n,omitempty" doc:"Number of completions"`
+	N                int             `json:"n,omitempty" minimum:"0" maximum:"8" doc:"Number of completions, run in parallel"`
 	Stream           bool            `json:"stream,omitempty" doc:"Stream responses"`
-	Stop             []string        `json:"stop,omitempty" doc:"Stop sequences"`
+	Stop             OpenAIStop      `json:"stop,omitempty"`
 	PresencePenalty  float64         `json:"presence_penalty,omitempty" doc:"Presence penalty"`
 	FrequencyPenalty float64         `json:"frequency_penalty,omitempty" doc:"Frequency penalty"`
 	User             string          `json:"user,omitempty" doc:"User identifier"`
+	Tools            []OpenAITool    `json:"tools,omitempty" doc:"Tools the model may call"`
+	ToolChoice       any             `json:"tool_choice,omitempty" doc:"none, auto, required, or {\"type\": \"function\", \"function\": {\"name\": ...}}"`
+	// ParallelToolCalls is accepted for compatibility; the model may always
+	// call several tools at once.
+	ParallelToolCalls *bool                 `json:"parallel_tool_calls,omitempty" doc:"Accepted for compatibility"`
+	ResponseFormat    *OpenAIResponseFormat `json:"response_format,omitempty" doc:"Reply format; JSON replies are validated and asked for again when invalid"`
+	// IncludeReasoning is a clinvk extension for backends that stream their
+	// thinking.
+	IncludeReasoning bool `json:"include_reasoning,omitempty" doc:"Stream backend thinking as reasoning_content deltas (clinvk extension)"`
 }
 
 // OpenAIChatCompletionChoice represents a completion choice.
@@ -187,13 +312,17 @@ type OpenAIChatCompletionChunkChoice struct {
 
 // OpenAIChatCompletionDelta represents a streaming delta.
 type OpenAIChatCompletionDelta struct {
-	Role    string `json:"role,omitempty"`
-	Content string `json:"content,omitempty"`
+	Role             string                `json:"role,omitempty"`
+	Content          string                `json:"content,omitempty"`
+	ReasoningContent string                `json:"reasoning_content,omitempty"`
+	ToolCalls        []OpenAIToolCallChunk `json:"tool_calls,omitempty"`
 }
 
 // OpenAIChatCompletionInput is the input for the chat completions handler.
 type OpenAIChatCompletionInput struct {
-	Body OpenAIChatCompletionRequest
+	Stateful bool `header:"X-Clinvk-Stateful" doc:"Continue the conversation on a clinvk session, sending only the newest user message"`
+	Strict   bool `header:"X-Clinvk-Strict" doc:"Reject parameters that cannot be honored instead of ignoring them"`
+	Body     OpenAIChatCompletionRequest
 }
 
 // HandleChatCompletions handles the POST /v1/chat/completions endpoint.
@@ -205,24 +334,49 @@ func (h *OpenAIHandlers) HandleChatCompletions(ctx context.Context, input *OpenA
 		return nil, huma.Error400BadRequest("messages are required")
 	}
 
+	tools, err := newToolSet(input.Body.Tools, input.Body.ToolChoice)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := responseFormatSchema(input.Body.ResponseFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	// Content parts are rendered as text, with images and files attached
+	var files attachments
+	for i, msg := range input.Body.Messages {
+		if msg.Parts != nil {
+			content, err := files.renderOpenAIParts(msg.Parts)
+			if err != nil {
+				return nil, err
+			}
+			input.Body.Messages[i].Content = content
+		}
+	}
+
 	// Extract prompt from messages
-	// Combine all user messages as the prompt
+	// Combine all user messages and tool results as the prompt
 	var prompt string
 	var systemPrompt string
+	var conversation []service.ConversationMessage
 	for _, msg := range input.Body.Messages {
+		content := renderOpenAIMessage(msg)
 		switch msg.Role {
 		case "system":
 			systemPrompt = msg.Content
-		case roleUser:
+		case roleUser, roleTool:
 			if prompt != "" {
 				prompt += "\n"
 			}
-			prompt += msg.Content
+			prompt += content
+			conversation = append(conversation, service.ConversationMessage{Role: roleUser, Content: content})
 		case roleAssistant:
 			// Include assistant context in prompt for continuations
 			if prompt != "" {
-				prompt += "\n[Previous response: " + msg.Content + "]\n"
+				prompt += "\n[Previous response: " + content + "]\n"
 			}
+			conversation = append(conversation, service.ConversationMessage{Role: roleAssistant, Content: content})
 		}
 	}
 
@@ -242,56 +396,67 @@ func (h *OpenAIHandlers) HandleChatCompletions(ctx context.Context, input *OpenA
 		SystemPrompt: systemPrompt,
 	}
 
-	if !input.Body.Stream {
-		result, err := h.runner.ExecutePrompt(ctx, req)
-		if err != nil {
-			return nil, huma.Error500InternalServerError("execution failed", err)
+	stateful := h.conversations != nil && statefulRequested(input.Stateful, input.Body.User)
+	if input.Strict {
+		if params := unsupportedParams(&input.Body, backendName, stateful); len(params) > 0 {
+			return nil, huma.Error400BadRequest("parameters not supported: " + strings.Join(params, ", "))
 		}
+	}
 
-		// Build response
-		now := time.Now().Unix()
-		responseID := fmt.Sprintf("chatcmpl-%s", result.SessionID)
-		if result.SessionID == "" {
-			responseID = fmt.Sprintf("chatcmpl-%d", now)
-		}
+	// A stateful conversation continues with a single reply
+	n := max(input.Body.N, 1)
+	if stateful && n > 1 {
+		h.logger.Warn("n ignored for a stateful conversation", "n", n)
+		n = 1
+	}
 
-		finishReason := openAIFinishReasonStop
-		if result.ExitCode != 0 {
-			finishReason = openAIFinishReasonErr
-		}
+	var turn *service.ConversationTurn
+	if stateful {
+		scope := conversationScope("openai", input.Body.User, backendName, input.Body.Model, systemPrompt)
+		turn = nextConversationTurn(h.conversations, scope, conversation, req)
+	}
 
-		// Token counts (use backend usage if available, fallback to rough estimate)
-		promptTokens := len(prompt) / 4
-		completionTokens := len(result.Output) / 4
-		if result.TokenUsage != nil {
-			promptTokens = int(result.TokenUsage.InputTokens)
-			completionTokens = int(result.TokenUsage.OutputTokens)
-		}
+	// Tools are declared on every turn, as they may change between turns
+	if tools != nil {
+		req.Prompt += "\n\n" + tools.prompt()
+	}
+	if schema != nil {
+		req.JSONSchema = schema
+		req.JSONSchemaRetries = responseFormatRetries
+	}
 
-		body := OpenAIChatCompletionResponseBody{
-			ID:      responseID,
-			Object:  "chat.completion",
-			Created: now,
-			Model:   input.Body.Model,
-			Choices: []OpenAIChatCompletionChoice{
-				{
-					Index: 0,
-					Message: OpenAIMessage{
-						Role:    roleAssistant,
-						Content: result.Output,
-					},
-					FinishReason: finishReason,
-				},
-			},
-			Usage: OpenAIUsage{
-				PromptTokens:     promptTokens,
-				CompletionTokens: completionTokens,
-				TotalTokens:      promptTokens + completionTokens,
-			},
-		}
+	cleanup, err := files.attachTo(req)
+	if err != nil {
+		return nil, err
+	}
+
+	completion := &chatCompletion{
+		body:   &input.Body,
+		req:    req,
+		prompt: prompt,
+		tools:  tools,
+		turn:   turn,
+		n:      n,
+	}
+
+	// Several replies, or replies that must be validated, are complete
+	// before they are sent, so a stream gets them in one piece.
+	if !input.Body.Stream || n > 1 || schema != nil {
+		defer cleanup()
 
+		body, sessionID, err := h.complete(ctx, completion)
+		if err != nil {
+			return nil, err
+		}
 		return &huma.StreamResponse{
 			Body: func(hctx huma.Context) {
+				if turn != nil && sessionID != "" {
+					hctx.SetHeader(sessionIDHeader, sessionID)
+				}
+				if input.Body.Stream {
+					h.writeCompletionChunks(hctx, body)
+					return
+				}
 				hctx.SetStatus(http.StatusOK)
 				hctx.SetHeader("Content-Type", "application/json")
 				if err := json.NewEncoder(hctx.BodyWriter()).Encode(body); err != nil {
@@ -306,9 +471,18 @@ func (h *OpenAIHandlers) HandleChatCompletions(ctx context.Context, input *OpenA
 
 	return &huma.StreamResponse{
 		Body: func(hctx huma.Context) {
-			setEventStreamHeaders(hctx)
+			defer cleanup()
+
+			var sessionID string
+			stream := &streamStart{start: func() {
+				if sessionID != "" {
+					hctx.SetHeader(sessionIDHeader, sessionID)
+				}
+				setEventStreamHeaders(hctx)
+			}}
 
 			writeChunk := func(delta OpenAIChatCompletionDelta, finish *string) error {
+				stream.begin()
 				return writeSSEJSON(hctx, OpenAIChatCompletionChunk{
 					ID:      responseID,
 					Object:  "chat.completion.chunk",
@@ -328,31 +502,101 @@ func (h *OpenAIHandlers) HandleChatCompletions(ctx context.Context, input *OpenA
 			streamReq := *req
 			streamCtx := hctx.Context()
 
-			streamResult, streamErr := service.StreamPrompt(streamCtx, &streamReq, nil, nil, true, func(event *output.UnifiedEvent) error {
-				if event.Type != output.EventMessage {
-					return nil
-				}
-				content, err := event.GetMessageContent()
-				if err != nil {
-					return err
+			var reply strings.Builder
+			var held toolStream
+			stops := &stopStream{stops: input.Body.Stop}
+			writeText := func(text string) error {
+				reply.WriteString(text)
+				if tools != nil {
+					text = held.write(text)
 				}
-				if content.Text == "" && sentRole {
+				if text == "" && sentRole {
 					return nil
 				}
 				delta := OpenAIChatCompletionDelta{
-					Content: content.Text,
+					Content: text,
 				}
 				if !sentRole {
 					delta.Role = roleAssistant
 					sentRole = true
 				}
 				return writeChunk(delta, nil)
-			})
+			}
+			onEvent := func(event *output.UnifiedEvent) error {
+				if event.Type == output.EventThinking && input.Body.IncludeReasoning {
+					content, err := event.GetThinkingContent()
+					if err != nil || content.Text == "" {
+						return err
+					}
+					delta := OpenAIChatCompletionDelta{ReasoningContent: content.Text}
+					if !sentRole {
+						delta.Role = roleAssistant
+						sentRole = true
+					}
+					return writeChunk(delta, nil)
+				}
+				if event.Type != output.EventMessage {
+					return nil
+				}
+				content, err := event.GetMessageContent()
+				if err != nil {
+					return err
+				}
+				if turn != nil {
+					sessionID = event.SessionID
+				}
+				return writeText(stops.write(content.Text))
+			}
+
+			var streamResult *service.StreamResult
+			var streamErr error
+			if turn != nil {
+				streamResult, streamErr = h.conversations.StreamPrompt(streamCtx, &streamReq, onEvent)
+			} else {
+				streamResult, streamErr = service.StreamPrompt(streamCtx, &streamReq, nil, nil, true, onEvent)
+			}
+
+			if !stream.started && writeConcurrencyRejection(hctx, streamErr) {
+				return
+			}
 
+			// Text held back as a possible stop sequence was not one
+			if rest := stops.flush(); rest != "" {
+				if err := writeText(rest); err != nil {
+					h.logger.Debug("SSE write error on held text", "error", err)
+				}
+			}
+
+			failed := streamErr != nil || streamResult == nil || streamResult.ExitCode != 0 || streamResult.Error != ""
 			finishReason := openAIFinishReasonStop
-			if streamErr != nil || streamResult == nil || streamResult.ExitCode != 0 || streamResult.Error != "" {
+			if failed {
 				finishReason = openAIFinishReasonErr
 			}
+
+			// Text held back as a possible tool call is sent now, as
+			// tool calls when it is one
+			replyText := reply.String()
+			if pending := held.pending(); pending != "" {
+				delta := OpenAIChatCompletionDelta{Content: pending}
+				if calls, ok := tools.parse(pending); ok && !failed {
+					delta = OpenAIChatCompletionDelta{ToolCalls: make([]OpenAIToolCallChunk, len(calls))}
+					for i, c := range calls {
+						delta.ToolCalls[i] = OpenAIToolCallChunk{Index: i, ID: c.ID, Type: c.Type, Function: c.Function}
+					}
+					finishReason = openAIFinishReasonToolCalls
+					replyText = renderOpenAIMessage(OpenAIMessage{Role: roleAssistant, ToolCalls: calls})
+				}
+				if !sentRole {
+					delta.Role = roleAssistant
+					sentRole = true
+				}
+				if err := writeChunk(delta, nil); err != nil {
+					h.logger.Debug("SSE write error on held chunk", "error", err)
+				}
+			}
+			if turn != nil && !failed {
+				h.conversations.Finish(turn, streamResult.SessionID, replyText)
+			}
 			if err := writeChunk(OpenAIChatCompletionDelta{}, &finishReason); err != nil {
 				h.logger.Debug("SSE write error on final chunk", "error", err)
 			}
@@ -363,24 +607,151 @@ func (h *OpenAIHandlers) HandleChatCompletions(ctx context.Context, input *OpenA
 	}, nil
 }
 
+// chatCompletion is a chat completion request ready to run.
+type chatCompletion struct {
+	body   *OpenAIChatCompletionRequest
+	req    *service.PromptRequest
+	prompt string
+	tools  *toolSet
+	turn   *service.ConversationTurn
+	n      int
+}
+
+// complete runs a chat completion to the end, running its n replies in
+// parallel. It returns the response and the session of a stateful turn.
+func (h *OpenAIHandlers) complete(ctx context.Context, c *chatCompletion) (*OpenAIChatCompletionResponseBody, string, error) {
+	var runner service.PromptRunner = h.runner
+	if c.turn != nil {
+		runner = h.conversations
+	}
+
+	var results []service.PromptResult
+	if c.n > 1 {
+		tasks := make([]service.PromptRequest, c.n)
+		for i := range tasks {
+			tasks[i] = *c.req
+		}
+		results = service.RunParallel(ctx, runner, &service.ParallelRequest{Tasks: tasks}, h.logger).Results
+	} else {
+		result, err := runner.ExecutePrompt(ctx, c.req)
+		if err != nil {
+			return nil, "", executionError("execution failed", err)
+		}
+		results = []service.PromptResult{*result}
+	}
+
+	now := time.Now().Unix()
+	first := results[0]
+	responseID := fmt.Sprintf("chatcmpl-%s", first.SessionID)
+	if first.SessionID == "" {
+		responseID = fmt.Sprintf("chatcmpl-%d", now)
+	}
+
+	body := &OpenAIChatCompletionResponseBody{
+		ID:      responseID,
+		Object:  "chat.completion",
+		Created: now,
+		Model:   c.body.Model,
+		Choices: make([]OpenAIChatCompletionChoice, len(results)),
+	}
+	for i, result := range results {
+		output := truncateAtStop(result.Output, c.body.Stop)
+		if result.Structured != nil {
+			// Validated JSON is returned whole; a stop could break it
+			output = string(result.Structured)
+		}
+
+		finishReason := openAIFinishReasonStop
+		if result.ExitCode != 0 {
+			finishReason = openAIFinishReasonErr
+		}
+
+		message := OpenAIMessage{
+			Role:    roleAssistant,
+			Content: output,
+		}
+		if c.tools != nil && result.ExitCode == 0 {
+			if calls, ok := c.tools.parse(output); ok {
+				message = OpenAIMessage{Role: roleAssistant, ToolCalls: calls}
+				finishReason = openAIFinishReasonToolCalls
+			}
+		}
+		if i == 0 && c.turn != nil && result.ExitCode == 0 {
+			h.conversations.Finish(c.turn, result.SessionID, renderOpenAIMessage(message))
+		}
+		body.Choices[i] = OpenAIChatCompletionChoice{
+			Index:        i,
+			Message:      message,
+			FinishReason: finishReason,
+		}
+
+		// Token counts (use backend usage if available, fallback to rough estimate).
+		// Every choice answers the same prompt, so it is counted once.
+		promptTokens := len(c.prompt) / 4
+		completionTokens := len(output) / 4
+		if result.TokenUsage != nil {
+			promptTokens = int(result.TokenUsage.InputTokens)
+			completionTokens = int(result.TokenUsage.OutputTokens)
+		}
+		body.Usage.PromptTokens = max(body.Usage.PromptTokens, promptTokens)
+		body.Usage.CompletionTokens += completionTokens
+	}
+	body.Usage.TotalTokens = body.Usage.PromptTokens + body.Usage.CompletionTokens
+
+	return body, first.SessionID, nil
+}
+
+// writeCompletionChunks streams a complete response: one chunk with the
+// message of each choice, then one with its finish reason.
+func (h *OpenAIHandlers) writeCompletionChunks(hctx huma.Context, body *OpenAIChatCompletionResponseBody) {
+	setEventStreamHeaders(hctx)
+
+	writeChunk := func(index int, delta OpenAIChatCompletionDelta, finish *string) {
+		err := writeSSEJSON(hctx, OpenAIChatCompletionChunk{
+			ID:      body.ID,
+			Object:  "chat.completion.chunk",
+			Created: body.Created,
+			Model:   body.Model,
+			Choices: []OpenAIChatCompletionChunkChoice{
+				{
+					Index:        index,
+					Delta:        delta,
+					FinishReason: finish,
+				},
+			},
+		})
+		if err != nil {
+			h.logger.Debug("SSE write error", "error", err)
+		}
+	}
+
+	for _, choice := range body.Choices {
+		delta := OpenAIChatCompletionDelta{Role: roleAssistant, Content: choice.Message.Content}
+		for i, c := range choice.Message.ToolCalls {
+			delta.ToolCalls = append(delta.ToolCalls, OpenAIToolCallChunk{Index: i, ID: c.ID, Type: c.Type, Function: c.Function})
+		}
+		writeChunk(choice.Index, delta, nil)
+	}
+	for _, choice := range body.Choices {
+		writeChunk(choice.Index, OpenAIChatCompletionDelta{}, &choice.FinishReason)
+	}
+	if err := writeSSE(hctx, []byte("data: [DONE]\n\n")); err != nil {
+		h.logger.Debug("SSE write error on DONE", "error", err)
+	}
+}
+
 // mapModelToBackend maps model names to backend names.
 func mapModelToBackend(model string) string {
-	// If the model is already a backend name, use it
-	switch model {
-	case backend.BackendClaude, backend.BackendCodex, backend.BackendGemini:
+	// If the model is already a registered backend or pool name, use it
+	if _, err := backend.Get(model); err == nil || util.IsPool(model) {
 		return model
 	}
 
-	// Map OpenAI-style model names to backends
-	switch {
-	case strings.Contains(model, "claude"):
-		return backend.BackendClaude
-	case strings.Contains(model, "gpt"):
-		return backend.BackendCodex
-	case strings.Contains(model, "gemini"):
-		return backend.BackendGemini
-	default:
-		// Default to claude
-		return backend.BackendClaude
+	// Resolve catalog model IDs and prefixes to their owning backend
+	if name, ok := backend.FindModelBackend(model); ok {
+		return name
 	}
+
+	// Default to claude
+	return backend.BackendClaude
 }
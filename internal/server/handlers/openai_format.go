@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/structured"
+)
+
+// Response format types.
+const (
+	responseFormatText       = "text"
+	responseFormatJSONObject = "json_object"
+	responseFormatJSONSchema = "json_schema"
+)
+
+// responseFormatRetries is how many times a reply that does not match the
+// response format is asked for again.
+const responseFormatRetries = 2
+
+// maxStopSequences is the number of stop sequences a request may set.
+const maxStopSequences = 4
+
+// OpenAIStop is a stop sequence or a list of them.
+type OpenAIStop []string
+
+// UnmarshalJSON accepts a single stop sequence or a list of them.
+func (s *OpenAIStop) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*s = OpenAIStop{one}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// Schema describes stop as a string or a list of strings.
+func (OpenAIStop) Schema(huma.Registry) *huma.Schema {
+	maxItems := maxStopSequences
+	return &huma.Schema{
+		Description: "Stop sequences; the reply is cut before the first one",
+		OneOf: []*huma.Schema{
+			{Type: huma.TypeString},
+			{Type: huma.TypeArray, Items: &huma.Schema{Type: huma.TypeString}, MaxItems: &maxItems},
+		},
+	}
+}
+
+// OpenAIResponseFormat is the format the reply must have.
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type" enum:"text,json_object,json_schema" doc:"Reply format"`
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty" doc:"Schema the reply must match, for the json_schema type"`
+}
+
+// OpenAIJSONSchema is a named JSON Schema for structured replies.
+type OpenAIJSONSchema struct {
+	Name        string         `json:"name" doc:"Schema name"`
+	Description string         `json:"description,omitempty" doc:"What the reply is for"`
+	Schema      map[string]any `json:"schema,omitempty" doc:"JSON Schema of the reply"`
+	// Strict is accepted for compatibility; replies are always validated.
+	Strict *bool `json:"strict,omitempty" doc:"Accepted for compatibility; replies are always validated"`
+}
+
+// responseFormatSchema returns the JSON Schema replies must match for f,
+// or nil for plain text replies. CLI backends cannot constrain their output,
+// so the schema is asked for in the prompt and replies are validated.
+func responseFormatSchema(f *OpenAIResponseFormat) (map[string]any, error) {
+	if f == nil || f.Type == "" || f.Type == responseFormatText {
+		return nil, nil
+	}
+
+	schema := map[string]any{"type": "object"}
+	if f.Type == responseFormatJSONSchema {
+		if f.JSONSchema == nil || f.JSONSchema.Name == "" {
+			return nil, huma.Error400BadRequest("response_format json_schema requires json_schema.name")
+		}
+		schema = f.JSONSchema.Schema
+		if schema == nil {
+			schema = map[string]any{}
+		}
+	}
+	if _, err := structured.CompileValue(schema); err != nil {
+		return nil, huma.Error400BadRequest("invalid response_format: " + err.Error())
+	}
+	return schema, nil
+}
+
+// truncateAtStop cuts text before the first of stops it contains.
+func truncateAtStop(text string, stops []string) string {
+	text, _ = cutAtStop(text, stops)
+	return text
+}
+
+// cutAtStop cuts text before the first of stops it contains, and returns
+// the stop sequence found there, or "" when text contains none.
+func cutAtStop(text string, stops []string) (string, string) {
+	cut, found := len(text), ""
+	for _, stop := range stops {
+		if i := strings.Index(text, stop); stop != "" && i >= 0 && i < cut {
+			cut, found = i, stop
+		}
+	}
+	return text[:cut], found
+}
+
+// stopStream cuts streamed text before the first stop sequence. Text that
+// may be the start of a stop sequence is held back until it is decided.
+type stopStream struct {
+	stops []string
+	held  string
+	// stop is the stop sequence the text was cut before, once found.
+	stop string
+}
+
+// write returns the part of text to stream now.
+func (s *stopStream) write(text string) string {
+	if s.stop != "" {
+		return ""
+	}
+	if len(s.stops) == 0 {
+		return text
+	}
+
+	s.held += text
+	if cut, stop := cutAtStop(s.held, s.stops); stop != "" {
+		s.held = ""
+		s.stop = stop
+		return cut
+	}
+
+	// Hold back the longest tail that begins a stop sequence
+	keep := 0
+	for _, stop := range s.stops {
+		for n := min(len(stop)-1, len(s.held)); n > keep; n-- {
+			if strings.HasSuffix(s.held, stop[:n]) {
+				keep = n
+				break
+			}
+		}
+	}
+	out := s.held[:len(s.held)-keep]
+	s.held = s.held[len(s.held)-keep:]
+	return out
+}
+
+// flush returns the text held back once the reply is complete.
+func (s *stopStream) flush() string {
+	out := s.held
+	s.held = ""
+	return out
+}
+
+// unsupportedParams lists the parameters of a chat completion request that
+// would be ignored, for rejecting them in strict mode.
+func unsupportedParams(body *OpenAIChatCompletionRequest, backendName string, stateful bool) []string {
+	var params []string
+	if body.Temperature != 0 {
+		params = append(params, "temperature")
+	}
+	if body.TopP != 0 {
+		params = append(params, "top_p")
+	}
+	if body.PresencePenalty != 0 {
+		params = append(params, "presence_penalty")
+	}
+	if body.FrequencyPenalty != 0 {
+		params = append(params, "frequency_penalty")
+	}
+	if body.MaxTokens > 0 {
+		if b, err := backend.Get(backendName); err == nil && !b.Capabilities().MaxTokens {
+			params = append(params, "max_tokens")
+		}
+	}
+	if body.N > 1 && stateful {
+		params = append(params, "n")
+	}
+	if body.ParallelToolCalls != nil && !*body.ParallelToolCalls {
+		params = append(params, "parallel_tool_calls")
+	}
+	for _, tool := range body.Tools {
+		if tool.Function.Strict {
+			params = append(params, "tools.function.strict")
+			break
+		}
+	}
+	return params
+}
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+func TestOpenAIStop_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{input: `"END"`, want: []string{"END"}},
+		{input: `["a","b"]`, want: []string{"a", "b"}},
+		{input: `42`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var stop OpenAIStop
+			err := json.Unmarshal([]byte(tt.input), &stop)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(stop, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("stop = %q, want %q", stop, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseFormatSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  *OpenAIResponseFormat
+		want    string
+		wantErr bool
+	}{
+		{name: "none", want: "null"},
+		{name: "text", format: &OpenAIResponseFormat{Type: responseFormatText}, want: "null"},
+		{name: "json object", format: &OpenAIResponseFormat{Type: responseFormatJSONObject}, want: `{"type":"object"}`},
+		{name: "json schema", format: &OpenAIResponseFormat{Type: responseFormatJSONSchema, JSONSchema: &OpenAIJSONSchema{Name: "answer", Schema: map[string]any{"type": "string"}}}, want: `{"type":"string"}`},
+		{name: "json schema without schema", format: &OpenAIResponseFormat{Type: responseFormatJSONSchema, JSONSchema: &OpenAIJSONSchema{Name: "answer"}}, want: `{}`},
+		{name: "missing name", format: &OpenAIResponseFormat{Type: responseFormatJSONSchema, JSONSchema: &OpenAIJSONSchema{}}, wantErr: true},
+		{name: "invalid pattern", format: &OpenAIResponseFormat{Type: responseFormatJSONSchema, JSONSchema: &OpenAIJSONSchema{Name: "answer", Schema: map[string]any{"type": "string", "pattern": "("}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := responseFormatSchema(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("responseFormatSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got, _ := json.Marshal(schema); string(got) != tt.want {
+				t.Errorf("responseFormatSchema() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStopStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		stops  []string
+		chunks []string
+		want   string
+	}{
+		{name: "no stops", chunks: []string{"Hello", " world"}, want: "Hello world"},
+		{name: "within a chunk", stops: []string{"END"}, chunks: []string{"Hello END world"}, want: "Hello "},
+		{name: "across chunks", stops: []string{"END"}, chunks: []string{"Hello E", "N", "D world"}, want: "Hello "},
+		{name: "partial match", stops: []string{"END"}, chunks: []string{"Hello EN", "TRY"}, want: "Hello ENTRY"},
+		{name: "held at the end", stops: []string{"END"}, chunks: []string{"Hello E"}, want: "Hello E"},
+		{name: "first of several", stops: []string{"\n\n", "###"}, chunks: []string{"a ##", "# b\n\nc"}, want: "a "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &stopStream{stops: tt.stops}
+			var got strings.Builder
+			for _, chunk := range tt.chunks {
+				got.WriteString(s.write(chunk))
+			}
+			got.WriteString(s.flush())
+			if got.String() != tt.want {
+				t.Errorf("streamed %q, want %q", got.String(), tt.want)
+			}
+			if whole := truncateAtStop(strings.Join(tt.chunks, ""), tt.stops); whole != tt.want {
+				t.Errorf("truncateAtStop() = %q, want %q", whole, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsupportedParams(t *testing.T) {
+	disabled := false
+	body := &OpenAIChatCompletionRequest{
+		Temperature:       0.2,
+		TopP:              0.9,
+		N:                 2,
+		ParallelToolCalls: &disabled,
+		Tools:             []OpenAITool{{Type: "function", Function: OpenAIFunction{Name: "f", Strict: true}}},
+	}
+	got := unsupportedParams(body, "unknown-backend", true)
+	want := []string{"temperature", "top_p", "n", "parallel_tool_calls", "tools.function.strict"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("unsupportedParams() = %v, want %v", got, want)
+	}
+
+	if got := unsupportedParams(&OpenAIChatCompletionRequest{N: 2}, "unknown-backend", false); len(got) != 0 {
+		t.Errorf("unsupportedParams() = %v, want none", got)
+	}
+}
+
+func TestHandleChatCompletions_Params(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	// The backend answers each prompt with the next of replies, if any
+	var mu sync.Mutex
+	var replies []string
+	var prompts []string
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-params",
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(func(p string, opts *backend.UnifiedOptions) *exec.Cmd {
+			mu.Lock()
+			defer mu.Unlock()
+			prompts = append(prompts, p)
+			reply := "Hello END world"
+			if len(replies) > 0 {
+				reply, replies = replies[0], replies[1:]
+			}
+			return exec.Command("printf", "%s", reply)
+		}),
+	)))
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	NewOpenAIHandlers(service.NewStatelessRunner(nil), nil).Register(api)
+
+	post := func(t *testing.T, body map[string]any, header http.Header, wantStatus int) string {
+		t.Helper()
+		body["model"] = "mock-params"
+		if _, ok := body["messages"]; !ok {
+			body["messages"] = []map[string]any{{"role": "user", "content": "hi"}}
+		}
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Marshal() error: %v", err)
+		}
+		rec := serveJobRequest(router, http.MethodPost, "/openai/v1/chat/completions", string(data), header)
+		if rec.Code != wantStatus {
+			t.Fatalf("status = %d, want %d, body = %s", rec.Code, wantStatus, rec.Body.String())
+		}
+		return rec.Body.String()
+	}
+	complete := func(t *testing.T, body map[string]any) OpenAIChatCompletionResponseBody {
+		t.Helper()
+		var resp OpenAIChatCompletionResponseBody
+		if err := json.Unmarshal([]byte(post(t, body, nil, http.StatusOK)), &resp); err != nil {
+			t.Fatalf("Unmarshal() error: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("n runs in parallel", func(t *testing.T) {
+		resp := complete(t, map[string]any{"n": 3})
+		if len(resp.Choices) != 3 {
+			t.Fatalf("choices = %+v, want 3", resp.Choices)
+		}
+		for i, c := range resp.Choices {
+			if c.Index != i || c.Message.Content != "Hello END world" {
+				t.Errorf("choice %d = %+v", i, c)
+			}
+		}
+	})
+
+	t.Run("n counts the prompt once", func(t *testing.T) {
+		messages := []map[string]any{{"role": "user", "content": "Say hello to the whole world, please."}}
+		one := complete(t, map[string]any{"messages": messages}).Usage
+		usage := complete(t, map[string]any{"messages": messages, "n": 3}).Usage
+		if one.PromptTokens == 0 {
+			t.Fatalf("usage = %+v, want prompt tokens", one)
+		}
+		if usage.PromptTokens != one.PromptTokens || usage.CompletionTokens != 3*one.CompletionTokens {
+			t.Errorf("usage = %+v, want the prompt of %+v once and its completion three times", usage, one)
+		}
+		if usage.TotalTokens != usage.PromptTokens+usage.CompletionTokens {
+			t.Errorf("total_tokens = %d, want %d", usage.TotalTokens, usage.PromptTokens+usage.CompletionTokens)
+		}
+	})
+
+	t.Run("stop truncates", func(t *testing.T) {
+		resp := complete(t, map[string]any{"stop": "END"})
+		if got := resp.Choices[0].Message.Content; got != "Hello " {
+			t.Errorf("content = %q, want %q", got, "Hello ")
+		}
+	})
+
+	t.Run("response format retries", func(t *testing.T) {
+		mu.Lock()
+		replies = []string{"not json", `{"answer":42}`}
+		prompts = nil
+		mu.Unlock()
+
+		resp := complete(t, map[string]any{"response_format": map[string]any{
+			"type":        "json_schema",
+			"json_schema": map[string]any{"name": "answer", "schema": map[string]any{"type": "object", "required": []string{"answer"}}},
+		}})
+		if c := resp.Choices[0]; c.Message.Content != `{"answer":42}` || c.FinishReason != openAIFinishReasonStop {
+			t.Errorf("choice = %+v", c)
+		}
+		if len(prompts) != 2 || !strings.Contains(prompts[1], "Your reply was rejected") || !strings.Contains(prompts[1], "not json") {
+			t.Errorf("prompts = %q", prompts)
+		}
+	})
+
+	t.Run("response format ignores stop", func(t *testing.T) {
+		mu.Lock()
+		replies = []string{`{"answer":"see END"}`}
+		mu.Unlock()
+
+		resp := complete(t, map[string]any{"stop": "END", "response_format": map[string]any{"type": "json_object"}})
+		if got := resp.Choices[0].Message.Content; got != `{"answer":"see END"}` {
+			t.Errorf("content = %q, want the whole JSON reply", got)
+		}
+	})
+
+	t.Run("response format gives up", func(t *testing.T) {
+		mu.Lock()
+		replies = []string{"no", "still no", "never"}
+		mu.Unlock()
+
+		resp := complete(t, map[string]any{"response_format": map[string]any{"type": "json_object"}})
+		if c := resp.Choices[0]; c.FinishReason != openAIFinishReasonErr {
+			t.Errorf("choice = %+v, want a failed reply", c)
+		}
+	})
+
+	t.Run("buffered stream", func(t *testing.T) {
+		var contents []string
+		for _, data := range sseData(post(t, map[string]any{"n": 2, "stream": true}, nil, http.StatusOK)) {
+			if data == "[DONE]" {
+				continue
+			}
+			var chunk OpenAIChatCompletionChunk
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				t.Fatalf("Unmarshal(%s) error: %v", data, err)
+			}
+			if text := chunk.Choices[0].Delta.Content; text != "" {
+				contents = append(contents, text)
+			}
+		}
+		if len(contents) != 2 {
+			t.Errorf("contents = %q, want one per choice", contents)
+		}
+	})
+
+	t.Run("strict mode", func(t *testing.T) {
+		strict := http.Header{"X-Clinvk-Strict": {"true"}}
+		body := post(t, map[string]any{"temperature": 0.5}, strict, http.StatusBadRequest)
+		if !strings.Contains(body, "temperature") {
+			t.Errorf("body = %s, want the rejected parameter", body)
+		}
+		post(t, map[string]any{"temperature": 0.5}, nil, http.StatusOK)
+		post(t, map[string]any{"n": 2}, strict, http.StatusOK)
+	})
+}
+
+func TestHandleChatCompletions_StreamStop(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	// The reply streams in pieces splitting the stop sequence
+	lines := make([]string, 0, 3)
+	for _, text := range []string{"Hello E", "ND", " world"} {
+		line, _ := json.Marshal(map[string]any{
+			"type":    "assistant",
+			"message": map[string]any{"content": []map[string]any{{"type": "text", "text": text}}},
+		})
+		lines = append(lines, string(line))
+	}
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend(backend.BackendClaude,
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(func(p string, opts *backend.UnifiedOptions) *exec.Cmd {
+			return exec.Command("printf", "%s\n", lines[0], lines[1], lines[2])
+		}),
+	)))
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	NewOpenAIHandlers(service.NewStatelessRunner(nil), nil).Register(api)
+
+	rec := serveJobRequest(router, http.MethodPost, "/openai/v1/chat/completions",
+		`{"model":"claude","stream":true,"stop":["END"],"messages":[{"role":"user","content":"hi"}]}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var content string
+	for _, data := range sseData(rec.Body.String()) {
+		if data == "[DONE]" {
+			continue
+		}
+		var chunk OpenAIChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("Unmarshal(%s) error: %v", data, err)
+		}
+		content += chunk.Choices[0].Delta.Content
+	}
+	if content != "Hello " {
+		t.Errorf("content = %q, want %q", content, "Hello ")
+	}
+}
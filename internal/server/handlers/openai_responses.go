@@ -0,0 +1,577 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+
+	"github.com/signalridge/clinvoker/internal/output"
+	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/session"
+)
+
+// Statuses of a response.
+const (
+	responseStatusInProgress = "in_progress"
+	responseStatusCompleted  = "completed"
+	responseStatusFailed     = "failed"
+)
+
+const (
+	responseIDPrefix = "resp_"
+
+	responseItemMessage   = "message"
+	responseContentInput  = "input_text"
+	responseContentOutput = "output_text"
+	responseErrorCode     = "server_error"
+)
+
+// OpenAIResponseRequest is the request for the Responses API.
+type OpenAIResponseRequest struct {
+	Model string `json:"model" doc:"Model/backend to use"`
+	// Input is a string, or a list of message items as decoded from JSON.
+	Input              any               `json:"input" doc:"Text, or a list of input message items"`
+	Instructions       string            `json:"instructions,omitempty" doc:"System instructions"`
+	PreviousResponseID string            `json:"previous_response_id,omitempty" doc:"Response to continue, on the clinvk session that produced it"`
+	MaxOutputTokens    int               `json:"max_output_tokens,omitempty" doc:"Maximum tokens to generate"`
+	Temperature        float64           `json:"temperature,omitempty" doc:"Sampling temperature"`
+	TopP               float64           `json:"top_p,omitempty" doc:"Nucleus sampling parameter"`
+	Stream             bool              `json:"stream,omitempty" doc:"Stream response events"`
+	Store              *bool             `json:"store,omitempty" doc:"Record the response on a clinvk session so it can be continued (default true)"`
+	Metadata           map[string]string `json:"metadata,omitempty" doc:"Metadata stored on the session"`
+	User               string            `json:"user,omitempty" doc:"User identifier"`
+}
+
+// OpenAIResponse is a response object of the Responses API.
+type OpenAIResponse struct {
+	ID                 string                     `json:"id"`
+	Object             string                     `json:"object"`
+	CreatedAt          int64                      `json:"created_at"`
+	Status             string                     `json:"status"`
+	Model              string                     `json:"model"`
+	Instructions       string                     `json:"instructions,omitempty"`
+	PreviousResponseID string                     `json:"previous_response_id,omitempty"`
+	Output             []OpenAIResponseOutputItem `json:"output"`
+	Error              *OpenAIResponseError       `json:"error"`
+	Usage              *OpenAIResponseUsage       `json:"usage,omitempty"`
+	Metadata           map[string]string          `json:"metadata,omitempty"`
+}
+
+// OpenAIResponseOutputItem is an output message of a response.
+type OpenAIResponseOutputItem struct {
+	Type    string                  `json:"type"`
+	ID      string                  `json:"id"`
+	Status  string                  `json:"status"`
+	Role    string                  `json:"role"`
+	Content []OpenAIResponseContent `json:"content"`
+}
+
+// OpenAIResponseContent is a content part of an output message.
+type OpenAIResponseContent struct {
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	Annotations []any  `json:"annotations"`
+}
+
+// OpenAIResponseError describes why a response failed.
+type OpenAIResponseError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// OpenAIResponseUsage represents token usage of a response.
+type OpenAIResponseUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+type openAIResponseStreamResponse struct {
+	Type           string         `json:"type"`
+	SequenceNumber int            `json:"sequence_number"`
+	Response       OpenAIResponse `json:"response"`
+}
+
+type openAIResponseStreamItem struct {
+	Type           string                   `json:"type"`
+	SequenceNumber int                      `json:"sequence_number"`
+	OutputIndex    int                      `json:"output_index"`
+	Item           OpenAIResponseOutputItem `json:"item"`
+}
+
+type openAIResponseStreamPart struct {
+	Type           string                `json:"type"`
+	SequenceNumber int                   `json:"sequence_number"`
+	ItemID         string                `json:"item_id"`
+	OutputIndex    int                   `json:"output_index"`
+	ContentIndex   int                   `json:"content_index"`
+	Part           OpenAIResponseContent `json:"part"`
+}
+
+type openAIResponseStreamTextDelta struct {
+	Type           string `json:"type"`
+	SequenceNumber int    `json:"sequence_number"`
+	ItemID         string `json:"item_id"`
+	OutputIndex    int    `json:"output_index"`
+	ContentIndex   int    `json:"content_index"`
+	Delta          string `json:"delta"`
+}
+
+type openAIResponseStreamTextDone struct {
+	Type           string `json:"type"`
+	SequenceNumber int    `json:"sequence_number"`
+	ItemID         string `json:"item_id"`
+	OutputIndex    int    `json:"output_index"`
+	ContentIndex   int    `json:"content_index"`
+	Text           string `json:"text"`
+}
+
+// openAIResponseInputItem is a message item of the input of a request.
+// Content is a string or a list of text parts.
+type openAIResponseInputItem struct {
+	Type    string          `json:"type"`
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// OpenAIResponseInput is the input for the responses handler.
+type OpenAIResponseInput struct {
+	Body OpenAIResponseRequest
+}
+
+// HandleResponses handles the POST /v1/responses endpoint.
+// Responses are recorded on clinvk sessions unless store is false; a
+// response ID names the session and turn that produced it, so
+// previous_response_id continues that session.
+func (h *OpenAIHandlers) HandleResponses(ctx context.Context, input *OpenAIResponseInput) (*huma.StreamResponse, error) {
+	if input.Body.Model == "" {
+		return nil, huma.Error400BadRequest("model is required")
+	}
+
+	var files attachments
+	messages, instructions, err := parseResponseInput(input.Body.Input, &files)
+	if err != nil {
+		return nil, err
+	}
+	systemPrompt := strings.Join(append([]string{input.Body.Instructions}, instructions...), "\n\n")
+
+	// Combine the input messages as the prompt, as chat completions do
+	var prompt string
+	for _, msg := range messages {
+		switch msg.Role {
+		case roleUser:
+			if prompt != "" {
+				prompt += "\n"
+			}
+			prompt += msg.Content
+		case roleAssistant:
+			if prompt != "" {
+				prompt += "\n[Previous response: " + msg.Content + "]\n"
+			}
+		}
+	}
+	if prompt == "" {
+		return nil, huma.Error400BadRequest("no user input found")
+	}
+
+	req := &service.PromptRequest{
+		Backend:      mapModelToBackend(input.Body.Model),
+		Prompt:       strings.TrimSpace(prompt),
+		Model:        input.Body.Model,
+		MaxTokens:    input.Body.MaxOutputTokens,
+		SystemPrompt: strings.TrimSpace(systemPrompt),
+		Metadata:     input.Body.Metadata,
+	}
+
+	stored := h.conversations != nil && (input.Body.Store == nil || *input.Body.Store)
+	turn := 1
+	if input.Body.PreviousResponseID != "" {
+		if !stored {
+			return nil, huma.Error400BadRequest("previous_response_id cannot be used with store set to false")
+		}
+		sess, err := h.previousResponseSession(input.Body.PreviousResponseID)
+		if err != nil {
+			return nil, err
+		}
+		req.SessionID = sess.ID
+		req.Backend = sess.Backend
+		turn = sess.TurnCount + 1
+	}
+
+	cleanup, err := files.attachTo(req)
+	if err != nil {
+		return nil, err
+	}
+
+	newResponse := func(sessionID, status string) OpenAIResponse {
+		id := responseIDPrefix + strings.ReplaceAll(uuid.NewString(), "-", "")
+		if stored && sessionID != "" {
+			id = openAIResponseID(sessionID, turn)
+		}
+		return OpenAIResponse{
+			ID:                 id,
+			Object:             "response",
+			CreatedAt:          time.Now().Unix(),
+			Status:             status,
+			Model:              input.Body.Model,
+			Instructions:       input.Body.Instructions,
+			PreviousResponseID: input.Body.PreviousResponseID,
+			Output:             []OpenAIResponseOutputItem{},
+			Metadata:           input.Body.Metadata,
+		}
+	}
+
+	if !input.Body.Stream {
+		defer cleanup()
+
+		var result *service.PromptResult
+		if stored {
+			result, err = h.conversations.ExecutePrompt(ctx, req)
+		} else {
+			result, err = h.runner.ExecutePrompt(ctx, req)
+		}
+		if err != nil {
+			return nil, executionError("execution failed", err)
+		}
+
+		body := newResponse(result.SessionID, responseStatusCompleted)
+		if result.ExitCode != 0 {
+			body.Status = responseStatusFailed
+			body.Error = &OpenAIResponseError{Code: responseErrorCode, Message: result.Error}
+		} else {
+			body.Output = []OpenAIResponseOutputItem{newResponseMessage(body.ID, responseStatusCompleted, result.Output)}
+		}
+
+		// Token counts (use backend usage if available, fallback to rough estimate)
+		usage := &OpenAIResponseUsage{InputTokens: len(req.Prompt) / 4, OutputTokens: len(result.Output) / 4}
+		if result.TokenUsage != nil {
+			usage.InputTokens = int(result.TokenUsage.InputTokens)
+			usage.OutputTokens = int(result.TokenUsage.OutputTokens)
+		}
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+		body.Usage = usage
+
+		return &huma.StreamResponse{
+			Body: func(hctx huma.Context) {
+				if stored && result.SessionID != "" {
+					hctx.SetHeader(sessionIDHeader, result.SessionID)
+				}
+				hctx.SetStatus(http.StatusOK)
+				hctx.SetHeader("Content-Type", "application/json")
+				if err := json.NewEncoder(hctx.BodyWriter()).Encode(body); err != nil {
+					h.logger.Debug("JSON encode error", "error", err)
+				}
+			},
+		}, nil
+	}
+
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			defer cleanup()
+
+			logSSEErr := func(event string, err error) {
+				if err != nil {
+					h.logger.Debug("SSE write error", "event", event, "error", err)
+				}
+			}
+
+			// Events are numbered in the order they are sent
+			sequence := -1
+			next := func() int {
+				sequence++
+				return sequence
+			}
+
+			// The response is created with the first event, when the
+			// session it is recorded on is known. A request turned away
+			// before the backend runs can still get an error status.
+			var sessionID string
+			var response OpenAIResponse
+			stream := &streamStart{start: func() {
+				response = newResponse(sessionID, responseStatusInProgress)
+				if stored && sessionID != "" {
+					hctx.SetHeader(sessionIDHeader, sessionID)
+				}
+				setEventStreamHeaders(hctx)
+
+				for _, event := range []string{"response.created", "response.in_progress"} {
+					logSSEErr(event, writeSSEEvent(hctx, event, openAIResponseStreamResponse{
+						Type:           event,
+						SequenceNumber: next(),
+						Response:       response,
+					}))
+				}
+			}}
+
+			// The output message opens with the first text
+			var item *OpenAIResponseOutputItem
+			startItem := func() {
+				if item != nil {
+					return
+				}
+				message := newResponseMessage(response.ID, responseStatusInProgress, "")
+				message.Content = []OpenAIResponseContent{}
+				item = &message
+				logSSEErr("response.output_item.added", writeSSEEvent(hctx, "response.output_item.added", openAIResponseStreamItem{
+					Type:           "response.output_item.added",
+					SequenceNumber: next(),
+					Item:           *item,
+				}))
+				logSSEErr("response.content_part.added", writeSSEEvent(hctx, "response.content_part.added", openAIResponseStreamPart{
+					Type:           "response.content_part.added",
+					SequenceNumber: next(),
+					ItemID:         item.ID,
+					Part:           OpenAIResponseContent{Type: responseContentOutput, Annotations: []any{}},
+				}))
+			}
+
+			streamReq := *req
+			streamCtx := hctx.Context()
+
+			var reply strings.Builder
+			onEvent := func(event *output.UnifiedEvent) error {
+				if event.Type != output.EventMessage {
+					return nil
+				}
+				content, err := event.GetMessageContent()
+				if err != nil {
+					return err
+				}
+				if content.Text == "" {
+					return nil
+				}
+				sessionID = event.SessionID
+				reply.WriteString(content.Text)
+				stream.begin()
+				startItem()
+				return writeSSEEvent(hctx, "response.output_text.delta", openAIResponseStreamTextDelta{
+					Type:           "response.output_text.delta",
+					SequenceNumber: next(),
+					ItemID:         item.ID,
+					Delta:          content.Text,
+				})
+			}
+
+			var streamResult *service.StreamResult
+			var streamErr error
+			if stored {
+				streamResult, streamErr = h.conversations.StreamPrompt(streamCtx, &streamReq, onEvent)
+			} else {
+				streamResult, streamErr = service.StreamPrompt(streamCtx, &streamReq, nil, nil, true, onEvent)
+			}
+
+			if !stream.started && writeConcurrencyRejection(hctx, streamErr) {
+				return
+			}
+			if streamResult != nil {
+				sessionID = streamResult.SessionID
+			}
+			stream.begin()
+
+			if item != nil {
+				text := reply.String()
+				logSSEErr("response.output_text.done", writeSSEEvent(hctx, "response.output_text.done", openAIResponseStreamTextDone{
+					Type:           "response.output_text.done",
+					SequenceNumber: next(),
+					ItemID:         item.ID,
+					Text:           text,
+				}))
+				part := OpenAIResponseContent{Type: responseContentOutput, Text: text, Annotations: []any{}}
+				logSSEErr("response.content_part.done", writeSSEEvent(hctx, "response.content_part.done", openAIResponseStreamPart{
+					Type:           "response.content_part.done",
+					SequenceNumber: next(),
+					ItemID:         item.ID,
+					Part:           part,
+				}))
+				item.Status = responseStatusCompleted
+				item.Content = []OpenAIResponseContent{part}
+				logSSEErr("response.output_item.done", writeSSEEvent(hctx, "response.output_item.done", openAIResponseStreamItem{
+					Type:           "response.output_item.done",
+					SequenceNumber: next(),
+					Item:           *item,
+				}))
+				response.Output = []OpenAIResponseOutputItem{*item}
+			}
+
+			usage := &OpenAIResponseUsage{}
+			if streamResult != nil && streamResult.TokenUsage != nil {
+				usage.InputTokens = int(streamResult.TokenUsage.InputTokens)
+				usage.OutputTokens = int(streamResult.TokenUsage.OutputTokens)
+				usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+			}
+			response.Usage = usage
+
+			event := "response.completed"
+			response.Status = responseStatusCompleted
+			if streamErr != nil || streamResult == nil || streamResult.ExitCode != 0 || streamResult.Error != "" {
+				event = "response.failed"
+				response.Status = responseStatusFailed
+				message := "backend execution failed"
+				switch {
+				case streamErr != nil:
+					message = streamErr.Error()
+				case streamResult != nil && streamResult.Error != "":
+					message = streamResult.Error
+				}
+				response.Error = &OpenAIResponseError{Code: responseErrorCode, Message: message}
+			}
+			logSSEErr(event, writeSSEEvent(hctx, event, openAIResponseStreamResponse{
+				Type:           event,
+				SequenceNumber: next(),
+				Response:       response,
+			}))
+		},
+	}, nil
+}
+
+// previousResponseSession returns the session a response was recorded on.
+// Only the latest response of a session can be continued, since a session
+// cannot be resumed from an earlier turn.
+func (h *OpenAIHandlers) previousResponseSession(responseID string) (*session.Session, error) {
+	sessionID, turn, ok := parseOpenAIResponseID(responseID)
+	if !ok {
+		return nil, huma.Error404NotFound(fmt.Sprintf("response %s not found", responseID))
+	}
+	sess, err := h.conversations.Session(sessionID)
+	if err != nil {
+		return nil, huma.Error404NotFound(fmt.Sprintf("response %s not found", responseID))
+	}
+	if sess.TurnCount != turn {
+		return nil, huma.Error409Conflict(fmt.Sprintf("response %s is not the latest response of session %s", responseID, sess.ID))
+	}
+	return sess, nil
+}
+
+// openAIResponseID returns the ID of the response recorded as turn of a
+// session.
+func openAIResponseID(sessionID string, turn int) string {
+	return fmt.Sprintf("%s%s_%d", responseIDPrefix, sessionID, turn)
+}
+
+// parseOpenAIResponseID returns the session and turn of a response ID
+// created by openAIResponseID.
+func parseOpenAIResponseID(id string) (string, int, bool) {
+	rest, ok := strings.CutPrefix(id, responseIDPrefix)
+	if !ok {
+		return "", 0, false
+	}
+	sep := strings.LastIndex(rest, "_")
+	if sep <= 0 {
+		return "", 0, false
+	}
+	turn, err := strconv.Atoi(rest[sep+1:])
+	if err != nil || turn < 1 {
+		return "", 0, false
+	}
+	return rest[:sep], turn, true
+}
+
+// newResponseMessage returns the output message of a response.
+func newResponseMessage(responseID, status, text string) OpenAIResponseOutputItem {
+	return OpenAIResponseOutputItem{
+		Type:    responseItemMessage,
+		ID:      "msg_" + strings.TrimPrefix(responseID, responseIDPrefix),
+		Status:  status,
+		Role:    roleAssistant,
+		Content: []OpenAIResponseContent{{Type: responseContentOutput, Text: text, Annotations: []any{}}},
+	}
+}
+
+// parseResponseInput returns the user and assistant messages of the input
+// of a request, and the text of its system and developer messages. Images
+// and files are recorded in files.
+func parseResponseInput(input any, files *attachments) ([]service.ConversationMessage, []string, error) {
+	switch v := input.(type) {
+	case string:
+		return []service.ConversationMessage{{Role: roleUser, Content: v}}, nil, nil
+	case []any:
+	default:
+		return nil, nil, huma.Error400BadRequest("input must be a string or a list of items")
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, nil, huma.Error400BadRequest("invalid input", err)
+	}
+	var items []openAIResponseInputItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, nil, huma.Error400BadRequest("invalid input", err)
+	}
+
+	var messages []service.ConversationMessage
+	var instructions []string
+	for i, item := range items {
+		if item.Type != "" && item.Type != responseItemMessage {
+			return nil, nil, huma.Error400BadRequest(fmt.Sprintf("input[%d]: unsupported item type %q", i, item.Type))
+		}
+		text, err := files.renderResponseContent(item.Content)
+		if err != nil {
+			return nil, nil, huma.Error400BadRequest(fmt.Sprintf("input[%d]: %v", i, err))
+		}
+		switch item.Role {
+		case roleUser, roleAssistant:
+			messages = append(messages, service.ConversationMessage{Role: item.Role, Content: text})
+		case "system", "developer":
+			instructions = append(instructions, text)
+		default:
+			return nil, nil, huma.Error400BadRequest(fmt.Sprintf("input[%d]: invalid role %q", i, item.Role))
+		}
+	}
+	return messages, instructions, nil
+}
+
+// renderResponseContent renders the content of an input message as text,
+// recording its images and files.
+func (a *attachments) renderResponseContent(content json.RawMessage) (string, error) {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text, nil
+	}
+
+	var parts []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		ImageURL string `json:"image_url"`
+		FileData string `json:"file_data"`
+		FileURL  string `json:"file_url"`
+		Filename string `json:"filename"`
+	}
+	if err := json.Unmarshal(content, &parts); err != nil {
+		return "", fmt.Errorf("content must be a string or a list of parts")
+	}
+	texts := make([]string, 0, len(parts))
+	for _, p := range parts {
+		var text string
+		var err error
+		switch {
+		case p.Type == responseContentInput || p.Type == responseContentOutput:
+			text = p.Text
+		case p.Type == "input_image" && p.ImageURL != "":
+			text, err = a.addURL(attachmentImage, p.ImageURL)
+		case p.Type == "input_file" && strings.HasPrefix(p.FileData, "data:"):
+			text, err = a.addURL(attachmentDocument, p.FileData)
+		case p.Type == "input_file" && p.FileData != "":
+			text, err = a.addBase64(attachmentDocument, mime.TypeByExtension(filepath.Ext(p.Filename)), p.FileData)
+		case p.Type == "input_file" && p.FileURL != "":
+			text, err = a.addURL(attachmentDocument, p.FileURL)
+		case p.Type == "input_image" || p.Type == "input_file":
+			return "", fmt.Errorf("%s requires inline data or a URL; file IDs are not supported", p.Type)
+		default:
+			return "", fmt.Errorf("unsupported content type %q", p.Type)
+		}
+		if err != nil {
+			return "", err
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/session"
+)
+
+func TestParseOpenAIResponseID(t *testing.T) {
+	tests := []struct {
+		id          string
+		wantSession string
+		wantTurn    int
+		wantOK      bool
+	}{
+		{id: openAIResponseID("abc123", 3), wantSession: "abc123", wantTurn: 3, wantOK: true},
+		{id: "resp_abc123"},
+		{id: "resp__1"},
+		{id: "resp_abc123_0"},
+		{id: "resp_abc123_x"},
+		{id: "chatcmpl-abc123_1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			sessionID, turn, ok := parseOpenAIResponseID(tt.id)
+			if ok != tt.wantOK {
+				t.Fatalf("parseOpenAIResponseID() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if sessionID != tt.wantSession || turn != tt.wantTurn {
+				t.Errorf("parseOpenAIResponseID() = %q, %d, want %q, %d", sessionID, turn, tt.wantSession, tt.wantTurn)
+			}
+		})
+	}
+}
+
+func TestParseResponseInput(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            string
+		wantMessages     []service.ConversationMessage
+		wantInstructions []string
+		wantErr          bool
+	}{
+		{name: "text", input: `"hello"`, wantMessages: []service.ConversationMessage{{Role: "user", Content: "hello"}}},
+		{
+			name:  "message items",
+			input: `[{"role":"developer","content":"Be brief."},{"type":"message","role":"user","content":[{"type":"input_text","text":"hi"},{"type":"input_text","text":"there"}]},{"role":"assistant","content":[{"type":"output_text","text":"hello"}]}]`,
+			wantMessages: []service.ConversationMessage{
+				{Role: "user", Content: "hi\nthere"},
+				{Role: "assistant", Content: "hello"},
+			},
+			wantInstructions: []string{"Be brief."},
+		},
+		{name: "unsupported item", input: `[{"type":"function_call_output","call_id":"call_1","output":"sunny"}]`, wantErr: true},
+		{name: "unsupported content", input: `[{"role":"user","content":[{"type":"input_audio","input_audio":{}}]}]`, wantErr: true},
+		{name: "invalid role", input: `[{"role":"tool","content":"x"}]`, wantErr: true},
+		{name: "invalid input", input: `42`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input any
+			if err := json.Unmarshal([]byte(tt.input), &input); err != nil {
+				t.Fatalf("Unmarshal() error: %v", err)
+			}
+			var files attachments
+			messages, instructions, err := parseResponseInput(input, &files)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseResponseInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(messages) != len(tt.wantMessages) {
+				t.Fatalf("messages = %+v, want %+v", messages, tt.wantMessages)
+			}
+			for i := range messages {
+				if messages[i] != tt.wantMessages[i] {
+					t.Errorf("message %d = %+v, want %+v", i, messages[i], tt.wantMessages[i])
+				}
+			}
+			if strings.Join(instructions, "|") != strings.Join(tt.wantInstructions, "|") {
+				t.Errorf("instructions = %q, want %q", instructions, tt.wantInstructions)
+			}
+		})
+	}
+}
+
+func TestHandleResponses(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	// Streamed replies are read by the claude stream parser
+	var prompts []string
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend(backend.BackendClaude,
+		mock.WithAvailable(true),
+		mock.WithCapabilities(backend.Capabilities{Resume: true}),
+		mock.WithCommandFunc(func(p string, opts *backend.UnifiedOptions) *exec.Cmd {
+			prompts = append(prompts, p)
+			if opts.OutputFormat == backend.OutputStreamJSON {
+				line, _ := json.Marshal(map[string]any{
+					"type":    "assistant",
+					"message": map[string]any{"content": []map[string]any{{"type": "text", "text": "streamed reply"}}},
+				})
+				return exec.Command("echo", string(line))
+			}
+			return exec.Command("echo", "plain reply")
+		}),
+	)))
+
+	store := session.NewStoreWithDir(t.TempDir())
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	openai := NewOpenAIHandlers(service.NewStatelessRunner(nil), nil)
+	openai.SetConversations(service.NewConversations(store, nil, 0))
+	openai.Register(api)
+
+	post := func(t *testing.T, body map[string]any, wantStatus int) string {
+		t.Helper()
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Marshal() error: %v", err)
+		}
+		rec := serveJobRequest(router, http.MethodPost, "/openai/v1/responses", string(data), nil)
+		if rec.Code != wantStatus {
+			t.Fatalf("status = %d, want %d, body = %s", rec.Code, wantStatus, rec.Body.String())
+		}
+		return rec.Body.String()
+	}
+	create := func(t *testing.T, body map[string]any) OpenAIResponse {
+		t.Helper()
+		var resp OpenAIResponse
+		if err := json.Unmarshal([]byte(post(t, body, http.StatusOK)), &resp); err != nil {
+			t.Fatalf("Unmarshal() error: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("continues the previous response", func(t *testing.T) {
+		first := create(t, map[string]any{"model": "claude", "input": "hello", "instructions": "Be brief."})
+		if first.Status != responseStatusCompleted || strings.TrimSpace(first.Output[0].Content[0].Text) != "plain reply" {
+			t.Fatalf("response = %+v", first)
+		}
+		sessionID, turn, ok := parseOpenAIResponseID(first.ID)
+		if !ok || turn != 1 {
+			t.Fatalf("expected the first turn of a session, got ID %q", first.ID)
+		}
+
+		second := create(t, map[string]any{
+			"model":                "claude",
+			"input":                []map[string]any{{"role": "user", "content": "and now?"}},
+			"previous_response_id": first.ID,
+		})
+		if second.ID != openAIResponseID(sessionID, 2) || second.PreviousResponseID != first.ID {
+			t.Errorf("ID = %q, previous_response_id = %q", second.ID, second.PreviousResponseID)
+		}
+		if last := prompts[len(prompts)-1]; last != "and now?" {
+			t.Errorf("prompt = %q, want only the new input", last)
+		}
+
+		sess, err := store.Get(sessionID)
+		if err != nil {
+			t.Fatalf("Get() error: %v", err)
+		}
+		if sess.TurnCount != 2 {
+			t.Errorf("TurnCount = %d, want 2", sess.TurnCount)
+		}
+
+		// Only the latest response of a session can be continued
+		post(t, map[string]any{"model": "claude", "input": "again", "previous_response_id": first.ID}, http.StatusConflict)
+	})
+
+	t.Run("unknown previous response", func(t *testing.T) {
+		post(t, map[string]any{"model": "claude", "input": "hi", "previous_response_id": "resp_missing_1"}, http.StatusNotFound)
+	})
+
+	t.Run("not stored", func(t *testing.T) {
+		resp := create(t, map[string]any{"model": "claude", "input": "hi", "store": false})
+		if _, _, ok := parseOpenAIResponseID(resp.ID); ok {
+			t.Errorf("expected a response without a session, got ID %q", resp.ID)
+		}
+		post(t, map[string]any{"model": "claude", "input": "hi", "store": false, "previous_response_id": resp.ID}, http.StatusBadRequest)
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		body := post(t, map[string]any{"model": "claude", "input": "hello", "stream": true}, http.StatusOK)
+
+		var events []string
+		var completed OpenAIResponse
+		for _, line := range strings.Split(body, "\n") {
+			if event, ok := strings.CutPrefix(line, "event: "); ok {
+				events = append(events, event)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok && strings.Contains(data, `"type":"response.completed"`) {
+				var payload openAIResponseStreamResponse
+				if err := json.Unmarshal([]byte(data), &payload); err != nil {
+					t.Fatalf("Unmarshal() error: %v", err)
+				}
+				completed = payload.Response
+			}
+		}
+
+		want := []string{
+			"response.created",
+			"response.in_progress",
+			"response.output_item.added",
+			"response.content_part.added",
+			"response.output_text.delta",
+			"response.output_text.done",
+			"response.content_part.done",
+			"response.output_item.done",
+			"response.completed",
+		}
+		if strings.Join(events, ",") != strings.Join(want, ",") {
+			t.Fatalf("events = %v, want %v", events, want)
+		}
+		if completed.Status != responseStatusCompleted || completed.Output[0].Content[0].Text != "streamed reply" {
+			t.Errorf("completed response = %+v", completed)
+		}
+		if _, turn, ok := parseOpenAIResponseID(completed.ID); !ok || turn != 1 {
+			t.Errorf("expected the first turn of a session, got ID %q", completed.ID)
+		}
+	})
+}
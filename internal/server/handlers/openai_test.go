@@ -26,6 +26,7 @@ func TestOpenAIHandlers_HandleModels(t *testing.T) {
 	}
 
 	// Verify model structure
+	ids := make(map[string]OpenAIModel)
 	for _, model := range resp.Body.Data {
 		if model.ID == "" {
 			t.Error("model ID should not be empty")
@@ -33,12 +34,21 @@ func TestOpenAIHandlers_HandleModels(t *testing.T) {
 		if model.Object != "model" {
 			t.Errorf("model object should be 'model', got %q", model.Object)
 		}
-		if model.OwnedBy != "clinvoker" {
-			t.Errorf("model owned_by should be 'clinvoker', got %q", model.OwnedBy)
+		if model.Backend == "" {
+			t.Errorf("model %q should have a backend", model.ID)
 		}
 		if model.Created == 0 {
 			t.Error("model created timestamp should not be zero")
 		}
+		ids[model.ID] = model
+	}
+
+	// Catalog models are owned by their backend; backend names by clinvoker
+	if m, ok := ids["gpt-5.2"]; !ok || m.OwnedBy != backend.BackendCodex {
+		t.Errorf("expected catalog model gpt-5.2 owned by codex, got %+v", m)
+	}
+	if m, ok := ids["claude"]; !ok || m.OwnedBy != "clinvoker" {
+		t.Errorf("expected backend entry claude owned by clinvoker, got %+v", m)
 	}
 }
 
@@ -191,8 +201,9 @@ func TestMapModelToBackend_Comprehensive(t *testing.T) {
 		{"gpt-4", backend.BackendCodex},
 		{"gpt-4-turbo", backend.BackendCodex},
 		{"gpt-3.5-turbo", backend.BackendCodex},
-		// Note: o3 doesn't contain "gpt", so defaults to claude
+		// Note: o3 matches no built-in model prefix, so defaults to claude
 		{"o3", backend.BackendClaude},
+		{"gpt-5-codex", backend.BackendCodex},
 
 		// Gemini model variants
 		{"gemini-pro", backend.BackendGemini},
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	roleTool = "tool"
+
+	openAIFinishReasonToolCalls = "tool_calls"
+
+	toolTypeFunction = "function"
+)
+
+// Tool choices other than naming a function.
+const (
+	toolChoiceNone     = "none"
+	toolChoiceAuto     = "auto"
+	toolChoiceRequired = "required"
+)
+
+// OpenAITool is a tool the model may call.
+type OpenAITool struct {
+	Type     string         `json:"type" enum:"function" doc:"Tool type"`
+	Function OpenAIFunction `json:"function" doc:"Function definition"`
+}
+
+// OpenAIFunction describes a function the model may call.
+type OpenAIFunction struct {
+	Name        string         `json:"name" minLength:"1" doc:"Function name"`
+	Description string         `json:"description,omitempty" doc:"What the function does"`
+	Parameters  map[string]any `json:"parameters,omitempty" doc:"JSON Schema of the arguments"`
+	Strict      bool           `json:"strict,omitempty" doc:"Accepted for compatibility; arguments are not validated"`
+}
+
+// OpenAIToolCall is a call of a tool by the model.
+type OpenAIToolCall struct {
+	ID       string                 `json:"id" doc:"Tool call ID"`
+	Type     string                 `json:"type" doc:"Tool type"`
+	Function OpenAIToolCallFunction `json:"function" doc:"Called function"`
+}
+
+// OpenAIToolCallFunction is the function and arguments of a tool call.
+type OpenAIToolCallFunction struct {
+	Name      string `json:"name" doc:"Function name"`
+	Arguments string `json:"arguments" doc:"Arguments as a JSON string"`
+}
+
+// OpenAIToolCallChunk is a tool call in a streaming delta.
+type OpenAIToolCallChunk struct {
+	Index    int                    `json:"index"`
+	ID       string                 `json:"id,omitempty"`
+	Type     string                 `json:"type,omitempty"`
+	Function OpenAIToolCallFunction `json:"function"`
+}
+
+// toolSet is the tools a request lets the model call.
+type toolSet struct {
+	tools []OpenAITool
+	// required is "" when the model may answer without calling a tool,
+	// toolChoiceRequired when it must call one, or the name of the
+	// function it must call.
+	required string
+}
+
+// newToolSet validates the tools and tool choice of a request. It returns
+// nil when no tool may be called.
+func newToolSet(tools []OpenAITool, toolChoice any) (*toolSet, error) {
+	set := &toolSet{tools: tools}
+
+	switch choice := toolChoice.(type) {
+	case nil:
+	case string:
+		switch choice {
+		case toolChoiceNone:
+			return nil, nil
+		case toolChoiceAuto:
+		case toolChoiceRequired:
+			set.required = toolChoiceRequired
+		default:
+			return nil, huma.Error400BadRequest(fmt.Sprintf("invalid tool_choice %q", choice))
+		}
+	case map[string]any:
+		function, _ := choice["function"].(map[string]any)
+		name, _ := function["name"].(string)
+		if choice["type"] != toolTypeFunction || name == "" {
+			return nil, huma.Error400BadRequest("tool_choice must name a function")
+		}
+		if !set.has(name) {
+			return nil, huma.Error400BadRequest(fmt.Sprintf("tool_choice names unknown function %q", name))
+		}
+		set.required = name
+	default:
+		return nil, huma.Error400BadRequest("tool_choice must be a string or an object")
+	}
+
+	if len(tools) == 0 {
+		if set.required != "" {
+			return nil, huma.Error400BadRequest("tool_choice requires tools")
+		}
+		return nil, nil
+	}
+	return set, nil
+}
+
+func (s *toolSet) has(name string) bool {
+	for _, t := range s.tools {
+		if t.Function.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// prompt renders the tools and the contract for calling them, to be
+// appended to the prompt.
+func (s *toolSet) prompt() string {
+	var b strings.Builder
+	b.WriteString("You can call the tools listed below. The caller runs them and replies with their results; do not run them yourself.\n")
+	b.WriteString("To call tools, reply with only this JSON object and no other text:\n")
+	b.WriteString(`{"tool_calls": [{"name": "<tool name>", "arguments": {<arguments matching the tool's parameters>}}]}`)
+	b.WriteString("\n")
+	switch s.required {
+	case "":
+		b.WriteString("If no tool is needed, reply normally.\n")
+	case toolChoiceRequired:
+		b.WriteString("You must call at least one tool.\n")
+	default:
+		fmt.Fprintf(&b, "You must call the %s tool.\n", s.required)
+	}
+
+	b.WriteString("\nTools:\n")
+	for _, t := range s.tools {
+		fmt.Fprintf(&b, "- %s", t.Function.Name)
+		if t.Function.Description != "" {
+			fmt.Fprintf(&b, ": %s", t.Function.Description)
+		}
+		b.WriteString("\n")
+		if len(t.Function.Parameters) > 0 {
+			if params, err := json.Marshal(t.Function.Parameters); err == nil {
+				fmt.Fprintf(&b, "  Parameters: %s\n", params)
+			}
+		}
+	}
+	return b.String()
+}
+
+// parse returns the tool calls in a reply that follows the contract of
+// prompt. Replies with any other text, or calling undeclared tools, are no
+// tool calls.
+func (s *toolSet) parse(reply string) ([]OpenAIToolCall, bool) {
+	text := strings.TrimSpace(reply)
+	if fenced, ok := strings.CutPrefix(text, "```"); ok {
+		fenced = strings.TrimPrefix(fenced, "json")
+		fenced, ok = strings.CutSuffix(strings.TrimSpace(fenced), "```")
+		if !ok {
+			return nil, false
+		}
+		text = strings.TrimSpace(fenced)
+	}
+	if !strings.HasPrefix(text, "{") {
+		return nil, false
+	}
+
+	var payload struct {
+		ToolCalls []struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		} `json:"tool_calls"`
+	}
+	if err := json.Unmarshal([]byte(text), &payload); err != nil || len(payload.ToolCalls) == 0 {
+		return nil, false
+	}
+
+	calls := make([]OpenAIToolCall, len(payload.ToolCalls))
+	for i, c := range payload.ToolCalls {
+		if !s.has(c.Name) {
+			return nil, false
+		}
+		// Arguments are passed on as a JSON string, as OpenAI does
+		args := "{}"
+		if len(c.Arguments) > 0 && string(c.Arguments) != "null" {
+			args = string(c.Arguments)
+			var encoded string
+			if json.Unmarshal(c.Arguments, &encoded) == nil {
+				args = encoded
+			}
+		}
+		calls[i] = OpenAIToolCall{
+			ID:   "call_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:24],
+			Type: toolTypeFunction,
+			Function: OpenAIToolCallFunction{
+				Name:      c.Name,
+				Arguments: args,
+			},
+		}
+	}
+	return calls, true
+}
+
+// toolStream passes streamed text through unless it may be a tool call,
+// which is held back until the reply is complete.
+type toolStream struct {
+	held        strings.Builder
+	passThrough bool
+}
+
+// write returns the part of text to stream now.
+func (s *toolStream) write(text string) string {
+	if s.passThrough {
+		return text
+	}
+	s.held.WriteString(text)
+	start := strings.TrimLeftFunc(s.held.String(), unicode.IsSpace)
+	if start == "" || strings.HasPrefix(start, "{") || strings.HasPrefix(start, "`") {
+		return ""
+	}
+	s.passThrough = true
+	out := s.held.String()
+	s.held.Reset()
+	return out
+}
+
+// pending returns the text held back.
+func (s *toolStream) pending() string {
+	return s.held.String()
+}
+
+// renderToolCalls renders tool calls as text, for prompts and to match
+// conversations.
+func renderToolCalls(calls []OpenAIToolCall) string {
+	lines := make([]string, len(calls))
+	for i, c := range calls {
+		lines[i] = fmt.Sprintf("[Tool call %s: %s(%s)]", c.ID, c.Function.Name, c.Function.Arguments)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderOpenAIMessage renders the content of a message as text, including
+// its tool calls and, for tool results, the call answered.
+func renderOpenAIMessage(msg OpenAIMessage) string {
+	switch {
+	case msg.Role == roleTool:
+		return fmt.Sprintf("[Tool result %s: %s]", msg.ToolCallID, msg.Content)
+	case len(msg.ToolCalls) > 0:
+		parts := []string{}
+		if msg.Content != "" {
+			parts = append(parts, msg.Content)
+		}
+		return strings.Join(append(parts, renderToolCalls(msg.ToolCalls)), "\n")
+	default:
+		return msg.Content
+	}
+}
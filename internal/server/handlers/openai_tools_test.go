@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+var weatherTool = OpenAITool{
+	Type: toolTypeFunction,
+	Function: OpenAIFunction{
+		Name:        "get_weather",
+		Description: "Get the weather of a city",
+		Parameters:  map[string]any{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}},
+	},
+}
+
+func TestNewToolSet(t *testing.T) {
+	tools := []OpenAITool{weatherTool}
+
+	tests := []struct {
+		name         string
+		tools        []OpenAITool
+		choice       any
+		wantNil      bool
+		wantRequired string
+		wantErr      bool
+	}{
+		{name: "no tools", wantNil: true},
+		{name: "auto by default", tools: tools},
+		{name: "auto", tools: tools, choice: "auto"},
+		{name: "none", tools: tools, choice: "none", wantNil: true},
+		{name: "required", tools: tools, choice: "required", wantRequired: toolChoiceRequired},
+		{
+			name:         "named function",
+			tools:        tools,
+			choice:       map[string]any{"type": "function", "function": map[string]any{"name": "get_weather"}},
+			wantRequired: "get_weather",
+		},
+		{name: "unknown choice", tools: tools, choice: "sometimes", wantErr: true},
+		{
+			name:    "unknown function",
+			tools:   tools,
+			choice:  map[string]any{"type": "function", "function": map[string]any{"name": "get_time"}},
+			wantErr: true,
+		},
+		{name: "required without tools", choice: "required", wantErr: true},
+		{name: "invalid type", tools: tools, choice: 1.0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := newToolSet(tt.tools, tt.choice)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newToolSet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if (set == nil) != tt.wantNil {
+				t.Fatalf("newToolSet() = %+v, want nil %v", set, tt.wantNil)
+			}
+			if set != nil && set.required != tt.wantRequired {
+				t.Errorf("required = %q, want %q", set.required, tt.wantRequired)
+			}
+		})
+	}
+}
+
+func TestToolSet_Prompt(t *testing.T) {
+	set := &toolSet{tools: []OpenAITool{weatherTool}, required: "get_weather"}
+	prompt := set.prompt()
+
+	for _, want := range []string{
+		`{"tool_calls": [`,
+		"- get_weather: Get the weather of a city",
+		`Parameters: {"properties":{"city":{"type":"string"}},"type":"object"}`,
+		"You must call the get_weather tool.",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestToolSet_Parse(t *testing.T) {
+	set := &toolSet{tools: []OpenAITool{weatherTool}}
+
+	tests := []struct {
+		name     string
+		reply    string
+		wantArgs []string
+	}{
+		{name: "object arguments", reply: `{"tool_calls": [{"name": "get_weather", "arguments": {"city": "Paris"}}]}`, wantArgs: []string{`{"city": "Paris"}`}},
+		{name: "string arguments", reply: `{"tool_calls": [{"name": "get_weather", "arguments": "{\"city\":\"Oslo\"}"}]}`, wantArgs: []string{`{"city":"Oslo"}`}},
+		{name: "no arguments", reply: `{"tool_calls": [{"name": "get_weather"}]}`, wantArgs: []string{"{}"}},
+		{
+			name:     "fenced, several calls",
+			reply:    "```json\n{\"tool_calls\": [{\"name\": \"get_weather\", \"arguments\": {\"city\": \"Rome\"}}, {\"name\": \"get_weather\", \"arguments\": {\"city\": \"Bern\"}}]}\n```",
+			wantArgs: []string{`{"city": "Rome"}`, `{"city": "Bern"}`},
+		},
+		{name: "plain text", reply: "It is sunny in Paris."},
+		{name: "text around the object", reply: `Sure: {"tool_calls": [{"name": "get_weather"}]}`},
+		{name: "undeclared tool", reply: `{"tool_calls": [{"name": "rm_rf", "arguments": {}}]}`},
+		{name: "other JSON", reply: `{"city": "Paris"}`},
+		{name: "unterminated fence", reply: "```json\n{\"tool_calls\": [{\"name\": \"get_weather\"}]}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, ok := set.parse(tt.reply)
+			if ok != (tt.wantArgs != nil) {
+				t.Fatalf("parse() ok = %v, want %v", ok, tt.wantArgs != nil)
+			}
+			if len(calls) != len(tt.wantArgs) {
+				t.Fatalf("got %d calls, want %d", len(calls), len(tt.wantArgs))
+			}
+			for i, c := range calls {
+				if c.Function.Name != "get_weather" || c.Type != toolTypeFunction || !strings.HasPrefix(c.ID, "call_") {
+					t.Errorf("call %d = %+v", i, c)
+				}
+				if c.Function.Arguments != tt.wantArgs[i] {
+					t.Errorf("call %d arguments = %q, want %q", i, c.Function.Arguments, tt.wantArgs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestToolStream(t *testing.T) {
+	tests := []struct {
+		name        string
+		chunks      []string
+		wantStream  string
+		wantPending string
+	}{
+		{name: "text passes through", chunks: []string{"  It is", " sunny"}, wantStream: "  It is sunny"},
+		{name: "object is held", chunks: []string{"\n", `{"tool_calls"`, ": []}"}, wantPending: "\n{\"tool_calls\": []}"},
+		{name: "fence is held", chunks: []string{"``", "`json"}, wantPending: "```json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s toolStream
+			var streamed strings.Builder
+			for _, chunk := range tt.chunks {
+				streamed.WriteString(s.write(chunk))
+			}
+			if streamed.String() != tt.wantStream {
+				t.Errorf("streamed %q, want %q", streamed.String(), tt.wantStream)
+			}
+			if s.pending() != tt.wantPending {
+				t.Errorf("pending %q, want %q", s.pending(), tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestRenderOpenAIMessage(t *testing.T) {
+	call := OpenAIToolCall{ID: "call_1", Type: toolTypeFunction, Function: OpenAIToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`}}
+
+	tests := []struct {
+		name string
+		msg  OpenAIMessage
+		want string
+	}{
+		{name: "text", msg: OpenAIMessage{Role: roleUser, Content: "hello"}, want: "hello"},
+		{name: "tool calls", msg: OpenAIMessage{Role: roleAssistant, ToolCalls: []OpenAIToolCall{call}}, want: `[Tool call call_1: get_weather({"city":"Paris"})]`},
+		{name: "text and tool calls", msg: OpenAIMessage{Role: roleAssistant, Content: "Checking.", ToolCalls: []OpenAIToolCall{call}}, want: "Checking.\n[Tool call call_1: get_weather({\"city\":\"Paris\"})]"},
+		{name: "tool result", msg: OpenAIMessage{Role: roleTool, ToolCallID: "call_1", Content: "sunny"}, want: "[Tool result call_1: sunny]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderOpenAIMessage(tt.msg); got != tt.want {
+				t.Errorf("renderOpenAIMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleChatCompletions_ToolCalls(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	// Streamed replies are read by the claude stream parser
+	reply := `{"tool_calls": [{"name": "get_weather", "arguments": {"city": "Paris"}}]}`
+	var prompt string
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend(backend.BackendClaude,
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(func(p string, opts *backend.UnifiedOptions) *exec.Cmd {
+			prompt = p
+			if opts.OutputFormat == backend.OutputStreamJSON {
+				line, _ := json.Marshal(map[string]any{
+					"type":    "assistant",
+					"message": map[string]any{"content": []map[string]any{{"type": "text", "text": reply}}},
+				})
+				return exec.Command("echo", string(line))
+			}
+			return exec.Command("echo", reply)
+		}),
+	)))
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	NewOpenAIHandlers(service.NewStatelessRunner(nil), nil).Register(api)
+
+	request := func(stream bool) string {
+		body, err := json.Marshal(map[string]any{
+			"model": "claude",
+			"messages": []map[string]any{
+				{"role": "user", "content": "Weather in Paris?"},
+				{"role": "assistant", "content": nil, "tool_calls": []map[string]any{{"id": "call_0", "type": "function", "function": map[string]any{"name": "get_weather", "arguments": `{"city":"Oslo"}`}}}},
+				{"role": "tool", "tool_call_id": "call_0", "content": "rainy"},
+				{"role": "user", "content": "And in Paris?"},
+			},
+			"tools":       []OpenAITool{weatherTool},
+			"tool_choice": "auto",
+			"stream":      stream,
+		})
+		if err != nil {
+			t.Fatalf("Marshal() error: %v", err)
+		}
+		rec := serveJobRequest(router, http.MethodPost, "/openai/v1/chat/completions", string(body), nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		return rec.Body.String()
+	}
+
+	t.Run("non-streaming", func(t *testing.T) {
+		var resp OpenAIChatCompletionResponseBody
+		if err := json.Unmarshal([]byte(request(false)), &resp); err != nil {
+			t.Fatalf("Unmarshal() error: %v", err)
+		}
+
+		for _, want := range []string{"[Tool call call_0: get_weather({\"city\":\"Oslo\"})]", "[Tool result call_0: rainy]", "- get_weather"} {
+			if !strings.Contains(prompt, want) {
+				t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+			}
+		}
+
+		choice := resp.Choices[0]
+		if choice.FinishReason != openAIFinishReasonToolCalls {
+			t.Errorf("finish_reason = %q, want %q", choice.FinishReason, openAIFinishReasonToolCalls)
+		}
+		if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"city": "Paris"}` {
+			t.Errorf("tool_calls = %+v", choice.Message.ToolCalls)
+		}
+		if choice.Message.Content != "" {
+			t.Errorf("content = %q, want none", choice.Message.Content)
+		}
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		body := request(true)
+		if strings.Contains(body, `"content":"{`) {
+			t.Errorf("tool call was streamed as content:\n%s", body)
+		}
+		for _, want := range []string{`"tool_calls":[{"index":0,"id":"call_`, `"name":"get_weather"`, `"finish_reason":"tool_calls"`} {
+			if !strings.Contains(body, want) {
+				t.Errorf("stream does not contain %q:\n%s", want, body)
+			}
+		}
+	})
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/websocket"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/output"
+	"github.com/signalridge/clinvoker/internal/server/middleware"
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+// Message types of the session WebSocket protocol.
+const (
+	// Client to server.
+	wsTypePrompt = "prompt"
+	wsTypeCancel = "cancel"
+
+	// Server to client.
+	wsTypeReady   = "ready"
+	wsTypeEvent   = "event"
+	wsTypeTurnEnd = "turn_end"
+	wsTypeError   = "error"
+)
+
+// Turn statuses reported in turn_end messages.
+const (
+	wsTurnCompleted = "completed"
+	wsTurnFailed    = "failed"
+	wsTurnCanceled  = "canceled"
+)
+
+// SessionSocketRequest is a message sent by the client over a session
+// WebSocket.
+type SessionSocketRequest struct {
+	Type         string   `json:"type"`
+	Prompt       string   `json:"prompt,omitempty"`
+	ApprovalMode string   `json:"approval_mode,omitempty"`
+	SandboxMode  string   `json:"sandbox_mode,omitempty"`
+	MaxTokens    int      `json:"max_tokens,omitempty"`
+	MaxTurns     int      `json:"max_turns,omitempty"`
+	SystemPrompt string   `json:"system_prompt,omitempty"`
+	Extra        []string `json:"extra,omitempty"`
+}
+
+// SessionSocketMessage is a message sent by the server over a session
+// WebSocket.
+type SessionSocketMessage struct {
+	Type    string               `json:"type"`
+	Turn    int                  `json:"turn,omitempty"`
+	Session *SessionInfo         `json:"session,omitempty"`
+	Event   *output.UnifiedEvent `json:"event,omitempty"`
+	Status  string               `json:"status,omitempty"`
+	Result  *PromptResponseBody  `json:"result,omitempty"`
+	Message string               `json:"message,omitempty"`
+}
+
+// SessionSocketHandlers serves interactive sessions over WebSocket.
+type SessionSocketHandlers struct {
+	executor *service.Executor
+	logger   *slog.Logger
+
+	mu        sync.Mutex
+	connected map[string]bool
+}
+
+// NewSessionSocketHandlers creates a new session WebSocket handlers instance.
+func NewSessionSocketHandlers(executor *service.Executor, logger *slog.Logger) *SessionSocketHandlers {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SessionSocketHandlers{
+		executor:  executor,
+		logger:    logger,
+		connected: make(map[string]bool),
+	}
+}
+
+// Register registers the session WebSocket route. Upgrades cannot be
+// described as OpenAPI operations, so the route is mounted on the router
+// directly.
+func (h *SessionSocketHandlers) Register(r chi.Router) {
+	r.Get("/api/v1/sessions/{id}/ws", h.HandleSessionSocket)
+}
+
+// HandleSessionSocket upgrades the request to a WebSocket bound to the
+// session named by the id path parameter (ID or prefix). A session accepts
+// one connection at a time.
+func (h *SessionSocketHandlers) HandleSessionSocket(w http.ResponseWriter, r *http.Request) {
+	info, err := h.executor.GetSession(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, huma.Error404NotFound("session not found", err))
+		return
+	}
+	if !h.connect(info.ID) {
+		writeProblem(w, huma.Error409Conflict("session already has an open connection"))
+		return
+	}
+	defer h.disconnect(info.ID)
+
+	websocket.Server{
+		Handshake: checkSocketOrigin,
+		Handler: func(ws *websocket.Conn) {
+			h.serve(ws, info)
+		},
+	}.ServeHTTP(w, r)
+}
+
+// checkSocketOrigin refuses upgrades from browsers on origins the CORS
+// settings do not allow, so other sites cannot open sessions with the
+// user's credentials. Clients that are not browsers send no origin.
+func checkSocketOrigin(cfg *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	if !middleware.OriginAllowed(origin, middleware.CORSOrigins(config.Get().Server.CORSAllowedOrigins)) {
+		return fmt.Errorf("origin not allowed: %s", origin)
+	}
+	var err error
+	cfg.Origin, err = websocket.Origin(cfg, r)
+	return err
+}
+
+func (h *SessionSocketHandlers) connect(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.connected[id] {
+		return false
+	}
+	h.connected[id] = true
+	return true
+}
+
+func (h *SessionSocketHandlers) disconnect(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.connected, id)
+}
+
+// sessionConn is an open session WebSocket.
+type sessionConn struct {
+	h    *SessionSocketHandlers
+	ws   *websocket.Conn
+	info *service.SessionInfo
+
+	// writeMu serializes writes from the reader and the running turn.
+	writeMu sync.Mutex
+
+	mu     sync.Mutex
+	turn   int
+	cancel context.CancelFunc // cancels the running turn, nil when idle
+	wg     sync.WaitGroup
+}
+
+func (h *SessionSocketHandlers) serve(ws *websocket.Conn, info *service.SessionInfo) {
+	// The connection outlives the request timeout and the server's read
+	// and write deadlines; a turn only ends with the connection or on
+	// cancel.
+	_ = ws.SetDeadline(time.Time{})
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ws.Request().Context()))
+	defer cancel()
+
+	c := &sessionConn{h: h, ws: ws, info: info, turn: info.TurnCount}
+	defer c.wg.Wait()
+
+	session := FromSessionInfo(info)
+	if err := c.send(&SessionSocketMessage{Type: wsTypeReady, Session: &session}); err != nil {
+		return
+	}
+
+	for {
+		var msg SessionSocketRequest
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				c.sendError("invalid message: " + err.Error())
+				continue
+			}
+			// Connection closed: stop the running turn
+			c.cancelTurn()
+			return
+		}
+
+		switch msg.Type {
+		case wsTypePrompt:
+			c.startTurn(ctx, &msg)
+		case wsTypeCancel:
+			if !c.cancelTurn() {
+				c.sendError("no turn is running")
+			}
+		default:
+			c.sendError("unknown message type: " + msg.Type)
+		}
+	}
+}
+
+func (c *sessionConn) send(msg *SessionSocketMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return websocket.JSON.Send(c.ws, msg)
+}
+
+func (c *sessionConn) sendError(message string) {
+	_ = c.send(&SessionSocketMessage{Type: wsTypeError, Message: message})
+}
+
+// cancelTurn cancels the running turn. It reports whether a turn was
+// running.
+func (c *sessionConn) cancelTurn() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel == nil {
+		return false
+	}
+	c.cancel()
+	return true
+}
+
+// startTurn runs msg as the next turn of the session, streaming its events
+// to the client. Only one turn runs at a time.
+func (c *sessionConn) startTurn(ctx context.Context, msg *SessionSocketRequest) {
+	if msg.Prompt == "" {
+		c.sendError("prompt is required")
+		return
+	}
+
+	c.mu.Lock()
+	if c.cancel != nil {
+		c.mu.Unlock()
+		c.sendError("a turn is already running")
+		return
+	}
+	turnCtx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+	c.turn++
+	turn := c.turn
+	c.mu.Unlock()
+
+	req := &service.PromptRequest{
+		Backend:      c.info.Backend,
+		Prompt:       msg.Prompt,
+		Model:        c.info.Model,
+		WorkDir:      c.info.WorkingDir,
+		ApprovalMode: msg.ApprovalMode,
+		SandboxMode:  msg.SandboxMode,
+		MaxTokens:    msg.MaxTokens,
+		MaxTurns:     msg.MaxTurns,
+		SystemPrompt: msg.SystemPrompt,
+		Extra:        msg.Extra,
+		SessionID:    c.info.ID,
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer func() {
+			c.mu.Lock()
+			c.cancel = nil
+			c.mu.Unlock()
+			cancel()
+		}()
+
+		start := time.Now()
+		result, err := c.h.executor.StreamPrompt(turnCtx, req, func(event *output.UnifiedEvent) error {
+			return c.send(&SessionSocketMessage{Type: wsTypeEvent, Turn: turn, Event: event})
+		})
+
+		var body PromptResponseBody
+		if result != nil {
+			body = FromStreamResult(result, time.Since(start))
+		} else {
+			body = PromptResponseBody{SessionID: c.info.ID, Backend: c.info.Backend, ExitCode: 1}
+		}
+		if err != nil && body.Error == "" {
+			body.Error = err.Error()
+		}
+
+		status := wsTurnCompleted
+		switch {
+		case turnCtx.Err() != nil:
+			status = wsTurnCanceled
+		case err != nil || body.ExitCode != 0 || body.Error != "":
+			status = wsTurnFailed
+		}
+		if status != wsTurnCompleted {
+			c.h.logger.Debug("session turn ended", "session_id", c.info.ID, "turn", turn, "status", status, "error", body.Error)
+		}
+
+		_ = c.send(&SessionSocketMessage{Type: wsTypeTurnEnd, Turn: turn, Status: status, Result: &body})
+	}()
+}
+
+// writeProblem writes err as an RFC 9457 problem response, like the errors
+// of huma operations.
+func writeProblem(w http.ResponseWriter, err huma.StatusError) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(err.GetStatus())
+	_ = json.NewEncoder(w).Encode(err)
+}
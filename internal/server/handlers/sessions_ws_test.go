@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/websocket"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/session"
+)
+
+func newSessionSocketServer(t *testing.T) (*httptest.Server, *session.Store) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-ws",
+		mock.WithAvailable(true),
+		mock.WithCapabilities(backend.Capabilities{Resume: true}),
+		mock.WithCommandFunc(func(prompt string, _ *backend.UnifiedOptions) *exec.Cmd {
+			if prompt == "slow" {
+				return exec.Command("sleep", "10")
+			}
+			return exec.Command("echo", prompt)
+		}),
+	)))
+
+	router := chi.NewRouter()
+	NewSessionSocketHandlers(service.NewExecutor(), nil).Register(router)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, session.NewStore()
+}
+
+func createSocketSession(t *testing.T, store *session.Store, backendSessionID string) *session.Session {
+	t.Helper()
+	sess, err := store.Create("mock-ws", t.TempDir())
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	sess.BackendSessionID = backendSessionID
+	if err := store.Save(sess); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	return sess
+}
+
+func dialSession(t *testing.T, server *httptest.Server, id string) (*websocket.Conn, error) {
+	t.Helper()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/sessions/" + id + "/ws"
+	ws, err := websocket.Dial(wsURL, "", server.URL)
+	if err == nil {
+		t.Cleanup(func() { _ = ws.Close() })
+	}
+	return ws, err
+}
+
+// receiveUntil reads messages until one of type msgType arrives.
+func receiveUntil(t *testing.T, ws *websocket.Conn, msgType string) SessionSocketMessage {
+	t.Helper()
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg SessionSocketMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			t.Fatalf("waiting for %s message: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func TestSessionSocket(t *testing.T) {
+	server, store := newSessionSocketServer(t)
+	sess := createSocketSession(t, store, "backend-123")
+
+	ws, err := dialSession(t, server, sess.ID[:8])
+	if err != nil {
+		t.Fatalf("Dial() error: %v", err)
+	}
+
+	ready := receiveUntil(t, ws, wsTypeReady)
+	if ready.Session == nil || ready.Session.ID != sess.ID {
+		t.Fatalf("expected ready for session %s, got %+v", sess.ID, ready.Session)
+	}
+
+	t.Run("second connection is rejected", func(t *testing.T) {
+		if _, err := dialSession(t, server, sess.ID); err == nil {
+			t.Fatal("expected the second connection to fail")
+		}
+	})
+
+	t.Run("turns resume the session", func(t *testing.T) {
+		for turn := 1; turn <= 2; turn++ {
+			if err := websocket.JSON.Send(ws, SessionSocketRequest{Type: wsTypePrompt, Prompt: "hello"}); err != nil {
+				t.Fatalf("Send() error: %v", err)
+			}
+			end := receiveUntil(t, ws, wsTypeTurnEnd)
+			if end.Turn != turn || end.Status != wsTurnCompleted {
+				t.Fatalf("turn_end = turn %d status %q, want turn %d completed (result %+v)", end.Turn, end.Status, turn, end.Result)
+			}
+			if end.Result == nil || end.Result.SessionID != sess.ID {
+				t.Fatalf("expected result for session %s, got %+v", sess.ID, end.Result)
+			}
+		}
+
+		updated, err := store.Get(sess.ID)
+		if err != nil {
+			t.Fatalf("Get() error: %v", err)
+		}
+		if updated.TurnCount != 2 {
+			t.Errorf("TurnCount = %d, want 2", updated.TurnCount)
+		}
+	})
+
+	t.Run("invalid messages", func(t *testing.T) {
+		tests := []struct {
+			name string
+			msg  string
+			want string
+		}{
+			{name: "empty prompt", msg: `{"type":"prompt"}`, want: "prompt is required"},
+			{name: "unknown type", msg: `{"type":"resume"}`, want: "unknown message type"},
+			{name: "nothing to cancel", msg: `{"type":"cancel"}`, want: "no turn is running"},
+			{name: "malformed", msg: `{"type":`, want: "invalid message"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := websocket.Message.Send(ws, tt.msg); err != nil {
+					t.Fatalf("Send() error: %v", err)
+				}
+				msg := receiveUntil(t, ws, wsTypeError)
+				if !strings.Contains(msg.Message, tt.want) {
+					t.Errorf("error message = %q, want it to contain %q", msg.Message, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestSessionSocket_Cancel(t *testing.T) {
+	server, store := newSessionSocketServer(t)
+	sess := createSocketSession(t, store, "")
+
+	ws, err := dialSession(t, server, sess.ID)
+	if err != nil {
+		t.Fatalf("Dial() error: %v", err)
+	}
+	receiveUntil(t, ws, wsTypeReady)
+
+	if err := websocket.JSON.Send(ws, SessionSocketRequest{Type: wsTypePrompt, Prompt: "slow"}); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if err := websocket.JSON.Send(ws, SessionSocketRequest{Type: wsTypePrompt, Prompt: "hello"}); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if msg := receiveUntil(t, ws, wsTypeError); !strings.Contains(msg.Message, "already running") {
+		t.Errorf("error message = %q, want a busy error", msg.Message)
+	}
+
+	if err := websocket.JSON.Send(ws, SessionSocketRequest{Type: wsTypeCancel}); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if end := receiveUntil(t, ws, wsTypeTurnEnd); end.Status != wsTurnCanceled {
+		t.Errorf("turn_end status = %q, want %q", end.Status, wsTurnCanceled)
+	}
+}
+
+func TestSessionSocket_NotFound(t *testing.T) {
+	server, _ := newSessionSocketServer(t)
+
+	resp, err := http.Get(server.URL + "/api/v1/sessions/missing/ws")
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestSessionSocket_Origin(t *testing.T) {
+	server, store := newSessionSocketServer(t)
+	sess := createSocketSession(t, store, "")
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/sessions/" + sess.ID + "/ws"
+
+	t.Run("foreign origin is refused", func(t *testing.T) {
+		cfg, err := websocket.NewConfig(wsURL, "http://evil.example")
+		if err != nil {
+			t.Fatalf("NewConfig() error: %v", err)
+		}
+		_, err = websocket.DialConfig(cfg)
+		var dialErr *websocket.DialError
+		if !errors.As(err, &dialErr) || !errors.Is(dialErr.Err, websocket.ErrBadStatus) {
+			t.Fatalf("expected a bad status, got %v", err)
+		}
+	})
+
+	t.Run("configured origin is allowed", func(t *testing.T) {
+		config.Get().Server.CORSAllowedOrigins = []string{"https://*.example.com"}
+		ws, err := websocket.Dial(wsURL, "", "https://app.example.com")
+		if err != nil {
+			t.Fatalf("Dial() error: %v", err)
+		}
+		t.Cleanup(func() { _ = ws.Close() })
+		receiveUntil(t, ws, wsTypeReady)
+	})
+}
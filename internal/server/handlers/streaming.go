@@ -8,11 +8,11 @@ import (
 )
 
 func setEventStreamHeaders(ctx huma.Context) {
-	ctx.SetStatus(http.StatusOK)
 	ctx.SetHeader("Content-Type", "text/event-stream")
 	ctx.SetHeader("Cache-Control", "no-cache")
 	ctx.SetHeader("Connection", "keep-alive")
 	ctx.SetHeader("X-Accel-Buffering", "no")
+	ctx.SetStatus(http.StatusOK)
 }
 
 func writeSSE(ctx huma.Context, data []byte) error {
@@ -48,3 +48,37 @@ func writeSSEEvent(ctx huma.Context, event string, payload any) error {
 	message = append(message, '\n', '\n')
 	return writeSSE(ctx, message)
 }
+
+// streamStart delays the start of a streaming response until its first
+// write, so a request turned away before the backend runs can still be
+// answered with an error status.
+type streamStart struct {
+	start   func()
+	started bool
+}
+
+// begin starts the response unless it has already started.
+func (s *streamStart) begin() {
+	if !s.started {
+		s.started = true
+		s.start()
+	}
+}
+
+func writeSSEEventWithID(ctx huma.Context, id, event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	message := make([]byte, 0, len(id)+len(event)+len(data)+24)
+	message = append(message, "id: "...)
+	message = append(message, id...)
+	message = append(message, '\n')
+	message = append(message, "event: "...)
+	message = append(message, event...)
+	message = append(message, '\n')
+	message = append(message, "data: "...)
+	message = append(message, data...)
+	message = append(message, '\n', '\n')
+	return writeSSE(ctx, message)
+}
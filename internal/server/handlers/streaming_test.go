@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/server/service"
+)
+
+// sseData returns the data payloads of an SSE body.
+func sseData(body string) []string {
+	var data []string
+	for _, line := range strings.Split(body, "\n") {
+		if payload, ok := strings.CutPrefix(line, "data: "); ok {
+			data = append(data, payload)
+		}
+	}
+	return data
+}
+
+func TestStreaming_ThinkingAndToolUse(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	// The backend thinks, runs a tool and answers, one claude line each
+	lines := make([]string, 0, 3)
+	for _, content := range []map[string]any{
+		{"type": "thinking", "thinking": "Let me check."},
+		{"type": "tool_use", "id": "toolu_1", "name": "Read", "input": map[string]any{"file_path": "go.mod"}},
+		{"type": "text", "text": "It is a Go module."},
+	} {
+		line, _ := json.Marshal(map[string]any{
+			"type":    "assistant",
+			"message": map[string]any{"content": []map[string]any{content}},
+		})
+		lines = append(lines, string(line))
+	}
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend(backend.BackendClaude,
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(func(p string, opts *backend.UnifiedOptions) *exec.Cmd {
+			return exec.Command("printf", "%s\n", lines[0], lines[1], lines[2])
+		}),
+	)))
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	NewOpenAIHandlers(service.NewStatelessRunner(nil), nil).Register(api)
+	NewAnthropicHandlers(service.NewStatelessRunner(nil), nil).Register(api)
+
+	post := func(t *testing.T, path string, body map[string]any) []string {
+		t.Helper()
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Marshal() error: %v", err)
+		}
+		rec := serveJobRequest(router, http.MethodPost, path, string(data), nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		return sseData(rec.Body.String())
+	}
+
+	anthropicBlocks := func(t *testing.T, body map[string]any) []string {
+		t.Helper()
+		var blocks []string
+		started := 0
+		for _, data := range post(t, "/anthropic/v1/messages", body) {
+			var event struct {
+				Type         string          `json:"type"`
+				Index        int             `json:"index"`
+				ContentBlock json.RawMessage `json:"content_block"`
+				Delta        json.RawMessage `json:"delta"`
+			}
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatalf("Unmarshal(%s) error: %v", data, err)
+			}
+			switch event.Type {
+			case "content_block_start":
+				if event.Index != started {
+					t.Errorf("block index = %d, want %d", event.Index, started)
+				}
+				started++
+				blocks = append(blocks, "start "+string(event.ContentBlock))
+			case "content_block_delta":
+				blocks = append(blocks, "delta "+string(event.Delta))
+			case "content_block_stop":
+				blocks = append(blocks, "stop")
+			}
+		}
+		return blocks
+	}
+
+	t.Run("anthropic with thinking", func(t *testing.T) {
+		got := anthropicBlocks(t, map[string]any{
+			"model":      "claude",
+			"max_tokens": 100,
+			"stream":     true,
+			"thinking":   map[string]any{"type": "enabled", "budget_tokens": 1024},
+			"messages":   []map[string]any{{"role": "user", "content": "What is this?"}},
+		})
+		want := []string{
+			`start {"type":"thinking","thinking":""}`,
+			`delta {"type":"thinking_delta","thinking":"Let me check."}`,
+			"stop",
+			`start {"type":"text","text":""}`,
+			`delta {"type":"text_delta","text":"It is a Go module."}`,
+			"stop",
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("blocks:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	})
+
+	t.Run("anthropic without thinking", func(t *testing.T) {
+		got := anthropicBlocks(t, map[string]any{
+			"model":      "claude",
+			"max_tokens": 100,
+			"stream":     true,
+			"messages":   []map[string]any{{"role": "user", "content": "What is this?"}},
+		})
+		// The tool the backend ran is not a tool_use block for the client
+		if len(got) != 3 || !strings.Contains(got[0], `"text"`) {
+			t.Errorf("blocks = %v, want only a text block", got)
+		}
+	})
+
+	for _, include := range []bool{true, false} {
+		name := "openai without reasoning"
+		if include {
+			name = "openai with reasoning"
+		}
+		t.Run(name, func(t *testing.T) {
+			var reasoning, content string
+			for _, data := range post(t, "/openai/v1/chat/completions", map[string]any{
+				"model":             "claude",
+				"stream":            true,
+				"include_reasoning": include,
+				"messages":          []map[string]any{{"role": "user", "content": "What is this?"}},
+			}) {
+				var chunk OpenAIChatCompletionChunk
+				if data == "[DONE]" {
+					continue
+				}
+				if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+					t.Fatalf("Unmarshal(%s) error: %v", data, err)
+				}
+				reasoning += chunk.Choices[0].Delta.ReasoningContent
+				content += chunk.Choices[0].Delta.Content
+			}
+
+			wantReasoning := ""
+			if include {
+				wantReasoning = "Let me check."
+			}
+			if reasoning != wantReasoning || content != "It is a Go module." {
+				t.Errorf("reasoning = %q, content = %q", reasoning, content)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"github.com/signalridge/clinvoker/internal/webhook"
+)
+
+// WebhookHandlers provides handlers for inspecting webhook deliveries.
+type WebhookHandlers struct {
+	webhooks *webhook.Dispatcher
+}
+
+// NewWebhookHandlers creates a new webhook handlers instance.
+func NewWebhookHandlers(webhooks *webhook.Dispatcher) *WebhookHandlers {
+	return &WebhookHandlers{webhooks: webhooks}
+}
+
+// Register registers all webhook API routes.
+func (h *WebhookHandlers) Register(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "listWebhookDeliveries",
+		Method:      http.MethodGet,
+		Path:        "/api/v1/webhooks/deliveries",
+		Summary:     "List webhook deliveries",
+		Description: "List recent deliveries to callback URLs, newest first",
+		Tags:        []string{"Webhooks"},
+	}, h.HandleListDeliveries)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "getWebhookDelivery",
+		Method:      http.MethodGet,
+		Path:        "/api/v1/webhooks/deliveries/{id}",
+		Summary:     "Get webhook delivery",
+		Description: "Get a delivery to a callback URL and its attempts",
+		Tags:        []string{"Webhooks"},
+	}, h.HandleGetDelivery)
+}
+
+// ListDeliveriesInput is the input for the list deliveries handler.
+type ListDeliveriesInput struct {
+	Status string `query:"status" enum:"pending,delivered,failed" doc:"Filter by status"`
+	Limit  int    `query:"limit" minimum:"0" doc:"Maximum number of deliveries to return (default: all kept)"`
+}
+
+// HandleListDeliveries handles webhook delivery listing requests.
+func (h *WebhookHandlers) HandleListDeliveries(_ context.Context, input *ListDeliveriesInput) (*WebhookDeliveriesResponse, error) {
+	deliveries := h.webhooks.List(webhook.Status(input.Status), input.Limit)
+
+	infos := make([]WebhookDelivery, len(deliveries))
+	for i, d := range deliveries {
+		infos[i] = FromDelivery(d)
+	}
+
+	return &WebhookDeliveriesResponse{
+		Body: WebhookDeliveriesResponseBody{
+			Deliveries: infos,
+			Total:      len(infos),
+		},
+	}, nil
+}
+
+// GetDeliveryInput is the input for getting a single delivery.
+type GetDeliveryInput struct {
+	ID string `path:"id" doc:"Delivery ID"`
+}
+
+// HandleGetDelivery handles get webhook delivery requests.
+func (h *WebhookHandlers) HandleGetDelivery(_ context.Context, input *GetDeliveryInput) (*WebhookDeliveryResponse, error) {
+	d, ok := h.webhooks.Get(input.ID)
+	if !ok {
+		return nil, huma.Error404NotFound("delivery not found")
+	}
+	return &WebhookDeliveryResponse{Body: FromDelivery(d)}, nil
+}
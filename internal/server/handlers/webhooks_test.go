@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/webhook"
+)
+
+type receivedCallback struct {
+	header http.Header
+	body   []byte
+}
+
+// newCallbackReceiver starts a server that records the callbacks it gets.
+func newCallbackReceiver(t *testing.T) (*httptest.Server, <-chan receivedCallback) {
+	t.Helper()
+	received := make(chan receivedCallback, 8)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedCallback{header: r.Header.Clone(), body: body}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+func newWebhooksTestRouter(t *testing.T, dispatcher *webhook.Dispatcher) http.Handler {
+	t.Helper()
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-hooks", mock.WithAvailable(true))))
+
+	router := chi.NewRouter()
+	api := humachi.New(router, huma.DefaultConfig("test", "1.0"))
+	custom := NewCustomHandlers(service.NewExecutor())
+	custom.SetWebhooks(dispatcher)
+	custom.Register(api)
+	NewWebhookHandlers(dispatcher).Register(api)
+	return router
+}
+
+func TestCallbacks(t *testing.T) {
+	receiver, received := newCallbackReceiver(t)
+	dispatcher := webhook.NewDispatcher(webhook.Config{Secret: "s3cret", AllowedHosts: []string{"127.0.0.1"}})
+	t.Cleanup(func() { _ = dispatcher.Shutdown(context.Background()) })
+	router := newWebhooksTestRouter(t, dispatcher)
+	config.Get().Server.WebhookAllowedHosts = []string{"127.0.0.1"}
+
+	tests := []struct {
+		name  string
+		path  string
+		body  string
+		event string
+	}{
+		{"prompt", "/api/v1/prompt", `{"backend":"mock-hooks","prompt":"hi","ephemeral":true,"callback_url":"%s"}`, "prompt.completed"},
+		{"parallel", "/api/v1/parallel", `{"tasks":[{"backend":"mock-hooks","prompt":"hi"}],"callback_url":"%s"}`, "parallel.completed"},
+		{"chain", "/api/v1/chain", `{"steps":[{"backend":"mock-hooks","prompt":"hi"}],"callback_url":"%s"}`, "chain.completed"},
+		{"compare", "/api/v1/compare", `{"backends":["mock-hooks"],"prompt":"hi","callback_url":"%s"}`, "compare.completed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(tt.body, receiver.URL+"/hook")
+			rec := serveJobRequest(router, http.MethodPost, tt.path, body, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+
+			var cb receivedCallback
+			select {
+			case cb = <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatal("no callback received")
+			}
+			if got := cb.header.Get(webhook.HeaderEvent); got != tt.event {
+				t.Errorf("event = %q, want %q", got, tt.event)
+			}
+			timestamp, _ := strconv.ParseInt(cb.header.Get(webhook.HeaderTimestamp), 10, 64)
+			if !webhook.Verify("s3cret", timestamp, cb.body, cb.header.Get(webhook.HeaderSignature)) {
+				t.Error("expected a valid signature")
+			}
+
+			var payload webhook.Payload
+			if err := json.Unmarshal(cb.body, &payload); err != nil || payload.Data == nil {
+				t.Errorf("unexpected payload %s: %v", cb.body, err)
+			}
+		})
+	}
+}
+
+func TestCallbackURLValidation(t *testing.T) {
+	dispatcher := webhook.NewDispatcher(webhook.Config{})
+	t.Cleanup(func() { _ = dispatcher.Shutdown(context.Background()) })
+	router := newWebhooksTestRouter(t, dispatcher)
+	config.Get().Server.WebhookAllowedHosts = []string{"hooks.example.com"}
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"prompt scheme", "/api/v1/prompt", `{"backend":"mock-hooks","prompt":"hi","callback_url":"ftp://hooks.example.com/cb"}`},
+		{"parallel host", "/api/v1/parallel", `{"tasks":[{"backend":"mock-hooks","prompt":"hi"}],"callback_url":"https://evil.example.com/cb"}`},
+		{"chain host", "/api/v1/chain", `{"steps":[{"backend":"mock-hooks","prompt":"hi"}],"callback_url":"https://evil.example.com/cb"}`},
+		{"compare relative", "/api/v1/compare", `{"backends":["mock-hooks"],"prompt":"hi","callback_url":"/cb"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveJobRequest(router, http.MethodPost, tt.path, tt.body, nil)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400, body = %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+	if n := len(dispatcher.List("", 0)); n != 0 {
+		t.Errorf("expected no deliveries, got %d", n)
+	}
+}
+
+func TestWebhookDeliveryEndpoints(t *testing.T) {
+	receiver, received := newCallbackReceiver(t)
+	dispatcher := webhook.NewDispatcher(webhook.Config{AllowedHosts: []string{"127.0.0.1"}})
+	t.Cleanup(func() { _ = dispatcher.Shutdown(context.Background()) })
+	router := newWebhooksTestRouter(t, dispatcher)
+
+	sent, err := dispatcher.Send(receiver.URL+"/hook?token=abc", "job.completed", "job1", map[string]string{"id": "job1"})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	<-received
+
+	var delivery WebhookDelivery
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		rec := serveJobRequest(router, http.MethodGet, "/api/v1/webhooks/deliveries/"+sent.ID, "", nil)
+		if err := json.Unmarshal(rec.Body.Bytes(), &delivery); err != nil {
+			t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+		}
+		if delivery.Status != string(webhook.StatusPending) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if delivery.Status != "delivered" || delivery.URL != receiver.URL+"/hook" || delivery.Subject != "job1" || len(delivery.Attempts) != 1 {
+		t.Errorf("unexpected delivery: %+v", delivery)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"all", "", 1},
+		{"delivered", "?status=delivered", 1},
+		{"failed", "?status=failed", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveJobRequest(router, http.MethodGet, "/api/v1/webhooks/deliveries"+tt.query, "", nil)
+			var resp WebhookDeliveriesResponseBody
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+			}
+			if resp.Total != tt.want || len(resp.Deliveries) != tt.want {
+				t.Errorf("got %d deliveries, want %d", resp.Total, tt.want)
+			}
+		})
+	}
+
+	errTests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"unknown delivery", "/api/v1/webhooks/deliveries/nope", http.StatusNotFound},
+		{"invalid status", "/api/v1/webhooks/deliveries?status=bogus", http.StatusUnprocessableEntity},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serveJobRequest(router, http.MethodGet, tt.path, "", nil); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -46,13 +46,18 @@ func APIKeyAuth() func(http.Handler) http.Handler {
 }
 
 // extractAPIKey extracts the API key from the request.
-// Supports X-Api-Key header and Authorization: Bearer header.
+// Supports X-Api-Key, X-Goog-Api-Key and Authorization: Bearer headers.
 func extractAPIKey(r *http.Request) string {
 	// Try X-Api-Key header first
 	if key := r.Header.Get("X-Api-Key"); key != "" {
 		return key
 	}
 
+	// Try X-Goog-Api-Key header, sent by Gemini clients
+	if key := r.Header.Get("X-Goog-Api-Key"); key != "" {
+		return key
+	}
+
 	// Try Authorization: Bearer header
 	authHeader := r.Header.Get("Authorization")
 	if authHeader != "" {
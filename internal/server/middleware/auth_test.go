@@ -237,6 +237,11 @@ func TestExtractAPIKey(t *testing.T) {
 			headers:     map[string]string{"X-Api-Key": "my-key"},
 			expectedKey: "my-key",
 		},
+		{
+			name:        "X-Goog-Api-Key header",
+			headers:     map[string]string{"X-Goog-Api-Key": "my-key"},
+			expectedKey: "my-key",
+		},
 		{
 			name:        "Bearer token",
 			headers:     map[string]string{"Authorization": "Bearer my-key"},
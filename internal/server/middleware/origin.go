@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/url"
+	"strings"
+)
+
+// DefaultCORSOrigins are the origins browsers may call the server from when
+// no origins are configured.
+var DefaultCORSOrigins = []string{"http://localhost:*", "http://127.0.0.1:*"}
+
+// CORSOrigins returns the configured origins, or DefaultCORSOrigins when
+// none are configured.
+func CORSOrigins(configured []string) []string {
+	if len(configured) == 0 {
+		return DefaultCORSOrigins
+	}
+	return configured
+}
+
+// OriginAllowed reports whether a browser on origin may call the server.
+// Localhost origins are always allowed; any other origin must match one of
+// allowed, whose patterns may hold a * wildcard as in the CORS settings.
+func OriginAllowed(origin string, allowed []string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	for _, pattern := range allowed {
+		if prefix, suffix, ok := strings.Cut(pattern, "*"); ok {
+			if len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		} else if pattern == origin {
+			return true
+		}
+	}
+	return false
+}
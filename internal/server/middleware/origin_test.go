@@ -0,0 +1,36 @@
+package middleware
+
+import "testing"
+
+func TestCORSOrigins(t *testing.T) {
+	if got := CORSOrigins(nil); len(got) != len(DefaultCORSOrigins) {
+		t.Errorf("CORSOrigins(nil) = %v, want the defaults", got)
+	}
+	if got := CORSOrigins([]string{"https://app.example.com"}); len(got) != 1 || got[0] != "https://app.example.com" {
+		t.Errorf("CORSOrigins() = %v, want the configured origins", got)
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://app.example.com", "https://*.corp.test"}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"http://localhost:3000", true},
+		{"http://127.0.0.1:5173", true},
+		{"http://[::1]:8080", true},
+		{"https://app.example.com", true},
+		{"https://tools.corp.test", true},
+		{"https://corp.test.evil", false},
+		{"http://evil.example:8080", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if got := OriginAllowed(tt.origin, allowed); got != tt.want {
+				t.Errorf("OriginAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
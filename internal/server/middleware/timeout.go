@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"time"
+
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// Timeout bounds requests to timeout, like chi's Timeout middleware, except
+// for requests to paths matching one of streamPatterns (path.Match
+// patterns). Those stream for as long as the client stays connected, so
+// they are also freed from the server's write timeout.
+func Timeout(timeout time.Duration, streamPatterns ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		bounded := chiMiddleware.Timeout(timeout)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, pattern := range streamPatterns {
+				if ok, _ := path.Match(pattern, r.URL.Path); ok {
+					// Not every writer supports deadlines; the stream then
+					// ends at the write timeout as before
+					_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			bounded.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	// The handler reports whether its request was canceled before it ended
+	handler := Timeout(10*time.Millisecond, "/api/v1/jobs/*/events")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(100 * time.Millisecond):
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/v1/prompt", http.StatusGatewayTimeout},
+		{"/api/v1/jobs/abc/events", http.StatusOK},
+		{"/api/v1/jobs/abc/events/extra", http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package server
 
 import (
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mcp"
 	"github.com/signalridge/clinvoker/internal/server/handlers"
+	"github.com/signalridge/clinvoker/internal/server/middleware"
 	"github.com/signalridge/clinvoker/internal/server/service"
 )
 
@@ -13,13 +16,39 @@ func (s *Server) RegisterRoutes() {
 		StartTime: s.startTime,
 	}
 	customHandlers := handlers.NewCustomHandlersWithHealthInfo(s.executor, healthInfo)
+	customHandlers.SetWebhooks(s.webhooks)
 	customHandlers.Register(s.api)
 
+	// Register asynchronous job API handlers
+	jobHandlers := handlers.NewJobHandlers(s.executor, s.jobs)
+	jobHandlers.Register(s.api)
+
+	// Register webhook delivery history handlers
+	webhookHandlers := handlers.NewWebhookHandlers(s.webhooks)
+	webhookHandlers.Register(s.api)
+
+	// Register the interactive session WebSocket
+	sessionSocketHandlers := handlers.NewSessionSocketHandlers(s.executor, s.logger)
+	sessionSocketHandlers.Register(s.router)
+
 	// Register OpenAI-compatible API handlers
 	openaiHandlers := handlers.NewOpenAIHandlers(service.NewStatelessRunner(s.logger), s.logger)
+	openaiHandlers.SetConversations(s.conversations)
 	openaiHandlers.Register(s.api)
 
 	// Register Anthropic-compatible API handlers
 	anthropicHandlers := handlers.NewAnthropicHandlers(service.NewStatelessRunner(s.logger), s.logger)
+	anthropicHandlers.SetConversations(s.conversations)
 	anthropicHandlers.Register(s.api)
+
+	// Register Gemini-compatible API handlers
+	geminiHandlers := handlers.NewGeminiHandlers(service.NewStatelessRunner(s.logger), s.logger)
+	geminiHandlers.SetConversations(s.conversations)
+	geminiHandlers.Register(s.api)
+
+	// Register the MCP streamable HTTP transport
+	if s.config.MCP {
+		mcpServer := mcp.NewServer(s.executor, Version, s.logger)
+		s.router.Handle("/mcp", mcpServer.Handler(middleware.CORSOrigins(config.Get().Server.CORSAllowedOrigins)))
+	}
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -17,14 +18,20 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/job"
+	"github.com/signalridge/clinvoker/internal/metrics"
+	"github.com/signalridge/clinvoker/internal/resilience"
 	"github.com/signalridge/clinvoker/internal/server/middleware"
 	"github.com/signalridge/clinvoker/internal/server/service"
+	"github.com/signalridge/clinvoker/internal/webhook"
 )
 
 // Config holds server configuration.
 type Config struct {
 	Host string
 	Port int
+	// MCP serves the MCP streamable HTTP transport at /mcp.
+	MCP bool
 }
 
 // Version is the server version.
@@ -32,14 +39,17 @@ const Version = "1.0.0"
 
 // Server is the HTTP server for the AI backend APIs.
 type Server struct {
-	config    Config
-	router    chi.Router
-	api       huma.API
-	executor  *service.Executor
-	logger    *slog.Logger
-	server    *http.Server
-	limiter   *middleware.RateLimiter
-	startTime time.Time
+	config        Config
+	router        chi.Router
+	api           huma.API
+	executor      *service.Executor
+	jobs          *service.JobManager
+	webhooks      *webhook.Dispatcher
+	conversations *service.Conversations
+	logger        *slog.Logger
+	server        *http.Server
+	limiter       *middleware.RateLimiter
+	startTime     time.Time
 }
 
 // New creates a new server instance.
@@ -95,14 +105,11 @@ func New(cfg Config, logger *slog.Logger) *Server {
 	if requestTimeout <= 0 {
 		requestTimeout = 5 * time.Minute
 	}
-	router.Use(chiMiddleware.Timeout(requestTimeout))
+	// Job event streams last as long as their job, so they are not bounded
+	router.Use(middleware.Timeout(requestTimeout, "/api/v1/jobs/*/events"))
 
 	// Add CORS - configurable via config, defaults to localhost for security
-	corsOrigins := appCfg.Server.CORSAllowedOrigins
-	if len(corsOrigins) == 0 {
-		// Default to localhost only if no origins configured
-		corsOrigins = []string{"http://localhost:*", "http://127.0.0.1:*"}
-	}
+	corsOrigins := middleware.CORSOrigins(appCfg.Server.CORSAllowedOrigins)
 	corsMaxAge := appCfg.Server.CORSMaxAge
 	if corsMaxAge <= 0 {
 		corsMaxAge = 300 // Default 5 minutes
@@ -124,8 +131,8 @@ func New(cfg Config, logger *slog.Logger) *Server {
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   corsOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Api-Key", "anthropic-version"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Api-Key", "X-Goog-Api-Key", "anthropic-version", "MCP-Protocol-Version", "X-Clinvk-Stateful", "X-Clinvk-Strict", "Last-Event-ID"},
+		ExposedHeaders:   []string{"Link", "X-Clinvk-Session-Id"},
 		AllowCredentials: appCfg.Server.CORSAllowCredentials,
 		MaxAge:           corsMaxAge,
 	}))
@@ -140,18 +147,70 @@ func New(cfg Config, logger *slog.Logger) *Server {
 	humaConfig.Info.Description = "Unified AI CLI wrapper API for multiple backends"
 	api := humachi.New(router, humaConfig)
 
+	webhooks := newWebhookDispatcher(appCfg.Server, logger)
+
+	// Jobs left running by a previous process cannot be resumed
+	jobs := service.NewJobManager(job.NewStore(), logger)
+	jobs.SetWebhooks(webhooks)
+	jobs.SetRetention(time.Duration(appCfg.Server.JobRetentionHours)*time.Hour, appCfg.Server.JobMaxFinished)
+	if n, err := jobs.Recover(); err != nil {
+		logger.Warn("Failed to recover jobs", "error", err)
+	} else if n > 0 {
+		logger.Info("Marked interrupted jobs as failed", "count", n)
+	}
+	if n, err := jobs.Prune(); err != nil {
+		logger.Warn("Failed to prune jobs", "error", err)
+	} else if n > 0 {
+		logger.Info("Deleted expired jobs", "count", n)
+	}
+
+	executor := service.NewExecutor()
+
 	srv := &Server{
-		config:    cfg,
-		router:    router,
-		api:       api,
-		executor:  service.NewExecutor(),
-		logger:    logger,
-		limiter:   limiter,
-		startTime: time.Now(),
+		config:        cfg,
+		router:        router,
+		api:           api,
+		executor:      executor,
+		jobs:          jobs,
+		webhooks:      webhooks,
+		conversations: service.NewConversations(executor.SessionStore(), logger, 0),
+		logger:        logger,
+		limiter:       limiter,
+		startTime:     time.Now(),
 	}
 	return srv
 }
 
+// newWebhookDispatcher creates the dispatcher that delivers results to
+// callback URLs, signed with the secret from the configured environment
+// variable.
+func newWebhookDispatcher(cfg config.ServerConfig, logger *slog.Logger) *webhook.Dispatcher {
+	var secret string
+	if cfg.WebhookSecretEnv != "" {
+		secret = os.Getenv(cfg.WebhookSecretEnv)
+	}
+	if secret == "" {
+		logger.Debug("Webhook secret not set; callback deliveries are unsigned", "env", cfg.WebhookSecretEnv)
+	}
+
+	return webhook.NewDispatcher(webhook.Config{
+		Secret: secret,
+		Retry: resilience.RetryPolicy{
+			MaxAttempts:    cfg.WebhookMaxAttempts,
+			InitialBackoff: time.Second,
+			MaxBackoff:     time.Minute,
+			Multiplier:     2,
+			Jitter:         0.2,
+		},
+		Timeout:      time.Duration(cfg.WebhookTimeoutSecs) * time.Second,
+		AllowedHosts: cfg.WebhookAllowedHosts,
+		OnFinish: func(d webhook.Delivery) {
+			metrics.RecordWebhookDelivery(d.Event, string(d.Status))
+		},
+		Logger: logger,
+	})
+}
+
 // StartTime returns the server's start time.
 func (s *Server) StartTime() time.Time {
 	return s.startTime
@@ -177,6 +236,16 @@ func (s *Server) Executor() *service.Executor {
 	return s.executor
 }
 
+// Jobs returns the server's job manager.
+func (s *Server) Jobs() *service.JobManager {
+	return s.jobs
+}
+
+// Webhooks returns the server's webhook dispatcher.
+func (s *Server) Webhooks() *webhook.Dispatcher {
+	return s.webhooks
+}
+
 // Logger returns the server logger.
 func (s *Server) Logger() *slog.Logger {
 	return s.logger
@@ -214,8 +283,16 @@ func (s *Server) Start() error {
 	return s.server.ListenAndServe()
 }
 
-// Shutdown gracefully shuts down the server.
+// Shutdown gracefully shuts down the server. Running jobs are interrupted
+// and marked failed, and webhook deliveries still waiting to be retried
+// are given up.
 func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.jobs.Shutdown(ctx); err != nil {
+		s.logger.Warn("Jobs did not stop in time", "error", err)
+	}
+	if err := s.webhooks.Shutdown(ctx); err != nil {
+		s.logger.Warn("Webhook deliveries did not stop in time", "error", err)
+	}
 	if s.server == nil {
 		return nil
 	}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/signalridge/clinvoker/internal/server/handlers"
@@ -296,3 +297,67 @@ func TestGracefulShutdown(t *testing.T) {
 		t.Errorf("expected no error on shutdown before start, got %v", err)
 	}
 }
+
+func TestRegisterRoutes(t *testing.T) {
+	srv := New(Config{Host: "127.0.0.1", Port: 8080}, slog.Default())
+	srv.RegisterRoutes()
+
+	paths := srv.API().OpenAPI().Paths
+	for _, path := range []string{
+		"/api/v1/prompt",
+		"/api/v1/jobs",
+		"/openai/v1/responses",
+		"/anthropic/v1/messages/count_tokens",
+		"/gemini/v1beta/models",
+	} {
+		if _, ok := paths[path]; !ok {
+			t.Errorf("expected route %s to be registered", path)
+		}
+	}
+}
+
+func TestRegisterRoutes_MCP(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		srv := New(Config{Host: "127.0.0.1", Port: 8080, MCP: enabled}, slog.Default())
+		srv.RegisterRoutes()
+
+		body := bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+		req := httptest.NewRequest(http.MethodPost, "/mcp", body)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+
+		want := http.StatusNotFound
+		if enabled {
+			want = http.StatusOK
+		}
+		if w.Code != want {
+			t.Errorf("MCP %v: status = %d, want %d", enabled, w.Code, want)
+		}
+	}
+}
+
+func TestCORSHeaders(t *testing.T) {
+	srv := New(Config{Host: "127.0.0.1", Port: 8080}, slog.Default())
+	srv.RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/jobs/abc/events", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "Last-Event-ID, X-Clinvk-Stateful, X-Clinvk-Strict")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got == "" {
+		t.Errorf("expected the clinvk request headers to be allowed, got status %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(got, "X-Clinvk-Session-Id") {
+		t.Errorf("Access-Control-Expose-Headers = %q, want it to contain X-Clinvk-Session-Id", got)
+	}
+}
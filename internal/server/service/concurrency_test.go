@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/output"
+	"github.com/signalridge/clinvoker/internal/util"
+)
+
+func TestExecutePrompt_ConcurrencyLimit(t *testing.T) {
+	initFallbackConfig(t)
+	server := &config.Get().Server
+	server.BackendConcurrency = map[string]int{"mock-conc": 1}
+	server.QueueSize = 1
+	server.QueueTimeoutSecs = 1
+
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-conc", mock.WithAvailable(true))))
+
+	release, err := util.AcquireProcessSlot(context.Background(), "mock-conc")
+	if err != nil {
+		t.Fatalf("AcquireProcessSlot() error: %v", err)
+	}
+
+	t.Run("queue timeout", func(t *testing.T) {
+		result, err := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend: "mock-conc",
+			Prompt:  "hello",
+			Retry:   fastRetry(3),
+		})
+		if !apperrors.IsCode(err, apperrors.ErrCodeQueueTimeout) {
+			t.Fatalf("expected queue_timeout, got %v", err)
+		}
+		if result.ExitCode == 0 || len(result.Attempts) != 1 {
+			t.Errorf("expected one rejected attempt, got exit=%d attempts=%+v", result.ExitCode, result.Attempts)
+		}
+	})
+
+	t.Run("queued request runs after release", func(t *testing.T) {
+		time.AfterFunc(20*time.Millisecond, release)
+
+		result, err := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend: "mock-conc",
+			Prompt:  "hello",
+		})
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("expected the queued request to run, got exit=%d err=%v", result.ExitCode, err)
+		}
+	})
+}
+
+func TestStreamPrompt_QueueFull(t *testing.T) {
+	initFallbackConfig(t)
+	server := &config.Get().Server
+	server.BackendConcurrency = map[string]int{"mock-conc-stream": 1}
+	server.QueueSize = 0
+
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-conc-stream", mock.WithAvailable(true))))
+
+	release, err := util.AcquireProcessSlot(context.Background(), "mock-conc-stream")
+	if err != nil {
+		t.Fatalf("AcquireProcessSlot() error: %v", err)
+	}
+	defer release()
+
+	result, err := StreamPrompt(context.Background(), &PromptRequest{
+		Backend: "mock-conc-stream",
+		Prompt:  "hello",
+		Retry:   fastRetry(2),
+	}, nil, nil, true, func(*output.UnifiedEvent) error {
+		t.Error("expected no events from a rejected stream")
+		return nil
+	})
+	if result != nil || !apperrors.IsCode(err, apperrors.ErrCodeQueueFull) {
+		t.Fatalf("expected queue_full, got %+v, %v", result, err)
+	}
+}
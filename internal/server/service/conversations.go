@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/signalridge/clinvoker/internal/output"
+	"github.com/signalridge/clinvoker/internal/session"
+)
+
+// DefaultConversationLimit is the number of conversations a Conversations
+// index remembers by default.
+const DefaultConversationLimit = 10000
+
+// ConversationMessage is a message of a chat-style conversation, as sent to
+// the OpenAI- and Anthropic-compatible endpoints.
+type ConversationMessage struct {
+	Role    string
+	Content string
+}
+
+// ConversationTurn is the next turn of a stateful conversation.
+type ConversationTurn struct {
+	// SessionID is the session continuing the conversation, or empty when
+	// no session holds its history and a new one must be started.
+	SessionID string
+	// Backend is the backend of the session, when SessionID is set.
+	Backend string
+	// Prompt is the newest user input: the messages after the last reply.
+	Prompt string
+
+	scope   string
+	history []ConversationMessage
+}
+
+// Conversations maps chat-style conversations to the sessions continuing
+// them, so a client that resends the whole message history on every call
+// only has its newest message sent to the backend. A conversation is known
+// by a hash of its messages up to the last reply; each session is only
+// reachable through the history it last answered.
+type Conversations struct {
+	store  *session.Store
+	logger *slog.Logger
+	limit  int
+
+	mu       sync.Mutex
+	sessions map[string]string // history key -> session ID
+	keys     map[string]string // session ID -> history key
+	order    []string          // history keys, oldest first
+}
+
+// NewConversations creates a conversation index over the sessions in store,
+// remembering up to limit conversations. A non-positive limit uses
+// DefaultConversationLimit.
+func NewConversations(store *session.Store, logger *slog.Logger, limit int) *Conversations {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if limit <= 0 {
+		limit = DefaultConversationLimit
+	}
+	return &Conversations{
+		store:    store,
+		logger:   logger,
+		limit:    limit,
+		sessions: make(map[string]string),
+		keys:     make(map[string]string),
+	}
+}
+
+// Next returns the turn continuing messages, whose last message must be the
+// user's. The messages after the last reply make up the new turn. scope
+// separates conversations that may share a history, e.g. of different users
+// or backends. A session found for the history is claimed by the turn: the
+// same history is not continued twice.
+func (c *Conversations) Next(scope string, messages []ConversationMessage) (*ConversationTurn, bool) {
+	if len(messages) == 0 || messages[len(messages)-1].Role != "user" {
+		return nil, false
+	}
+
+	replied := len(messages) - 1
+	for replied >= 0 && messages[replied].Role != "assistant" {
+		replied--
+	}
+	prior := messages[:replied+1]
+
+	contents := make([]string, 0, len(messages)-len(prior))
+	for _, m := range messages[len(prior):] {
+		contents = append(contents, m.Content)
+	}
+	turn := &ConversationTurn{
+		Prompt:  strings.Join(contents, "\n"),
+		scope:   scope,
+		history: messages,
+	}
+
+	if len(prior) == 0 {
+		return turn, true
+	}
+
+	key := conversationKey(scope, prior)
+	c.mu.Lock()
+	id, ok := c.sessions[key]
+	if ok {
+		c.forget(id)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return turn, true
+	}
+
+	sess, err := c.store.Get(id)
+	if err != nil {
+		c.logger.Debug("conversation session is gone", "session_id", id, "error", err)
+		return turn, true
+	}
+	turn.SessionID = sess.ID
+	turn.Backend = sess.Backend
+	return turn, true
+}
+
+// Finish records that the session sessionID answered turn with reply, so
+// the history ending in reply continues that session.
+func (c *Conversations) Finish(turn *ConversationTurn, sessionID, reply string) {
+	if sessionID == "" {
+		return
+	}
+
+	history := append(append([]ConversationMessage{}, turn.history...), ConversationMessage{Role: "assistant", Content: reply})
+	key := conversationKey(turn.scope, history)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.forget(sessionID)
+	c.sessions[key] = sessionID
+	c.keys[sessionID] = key
+	c.order = append(c.order, key)
+
+	// Drop the oldest conversations beyond the limit; keys forgotten
+	// earlier are skipped
+	for len(c.sessions) > c.limit && len(c.order) > 0 {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		if id, ok := c.sessions[oldest]; ok {
+			c.forget(id)
+		}
+	}
+	if len(c.order) > 2*c.limit {
+		c.compact()
+	}
+}
+
+// ExecutePrompt runs a turn of a conversation, recording it on a session.
+func (c *Conversations) ExecutePrompt(ctx context.Context, req *PromptRequest) (*PromptResult, error) {
+	return NewStatefulRunner(c.store, c.logger).ExecutePrompt(ctx, req)
+}
+
+// StreamPrompt runs a turn of a conversation in streaming mode, recording
+// it on a session.
+func (c *Conversations) StreamPrompt(ctx context.Context, req *PromptRequest, onEvent func(*output.UnifiedEvent) error) (*StreamResult, error) {
+	return StreamPrompt(ctx, req, c.store, c.logger, false, onEvent)
+}
+
+// Session returns the session with the given ID from the store conversations
+// are recorded in.
+func (c *Conversations) Session(id string) (*session.Session, error) {
+	return c.store.Get(id)
+}
+
+// forget removes the history key of a session. The caller holds c.mu.
+func (c *Conversations) forget(sessionID string) {
+	if key, ok := c.keys[sessionID]; ok {
+		delete(c.sessions, key)
+		delete(c.keys, sessionID)
+	}
+}
+
+// compact drops forgotten keys from c.order. The caller holds c.mu.
+func (c *Conversations) compact() {
+	order := c.order[:0]
+	for _, key := range c.order {
+		if _, ok := c.sessions[key]; ok {
+			order = append(order, key)
+		}
+	}
+	c.order = order
+}
+
+// conversationKey hashes the messages of a conversation within scope.
+// Surrounding whitespace is ignored, as clients may trim replies.
+func conversationKey(scope string, messages []ConversationMessage) string {
+	h := sha256.New()
+	h.Write([]byte(scope))
+	for _, m := range messages {
+		h.Write([]byte{0})
+		h.Write([]byte(m.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(strings.TrimSpace(m.Content)))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
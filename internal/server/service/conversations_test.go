@@ -0,0 +1,128 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/signalridge/clinvoker/internal/session"
+)
+
+func newConversationSession(t *testing.T, store *session.Store) *session.Session {
+	t.Helper()
+	sess, err := store.Create("claude", t.TempDir())
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	return sess
+}
+
+func TestConversations_Next(t *testing.T) {
+	store := session.NewStoreWithDir(t.TempDir())
+	sess := newConversationSession(t, store)
+
+	first := []ConversationMessage{{Role: "user", Content: "hello"}}
+	second := append(append([]ConversationMessage{}, first...),
+		ConversationMessage{Role: "assistant", Content: " hi there\n"},
+		ConversationMessage{Role: "user", Content: "how are you?"},
+	)
+
+	tests := []struct {
+		name        string
+		scope       string
+		messages    []ConversationMessage
+		wantOK      bool
+		wantSession string
+	}{
+		{name: "no messages", scope: "a", messages: nil, wantOK: false},
+		{name: "last message is no user turn", scope: "a", messages: second[:2], wantOK: false},
+		{name: "first turn starts a session", scope: "a", messages: first, wantOK: true},
+		{name: "other scope", scope: "b", messages: second, wantOK: true},
+		{name: "continuation", scope: "a", messages: second, wantOK: true, wantSession: sess.ID},
+		{name: "history is claimed once", scope: "a", messages: second, wantOK: true},
+	}
+
+	c := NewConversations(store, nil, 0)
+	turn, _ := c.Next("a", first)
+	c.Finish(turn, sess.ID, "hi there")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			turn, ok := c.Next(tt.scope, tt.messages)
+			if ok != tt.wantOK {
+				t.Fatalf("Next() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if turn.SessionID != tt.wantSession {
+				t.Errorf("SessionID = %q, want %q", turn.SessionID, tt.wantSession)
+			}
+			if want := tt.messages[len(tt.messages)-1].Content; turn.Prompt != want {
+				t.Errorf("Prompt = %q, want %q", turn.Prompt, want)
+			}
+			if turn.SessionID != "" && turn.Backend != "claude" {
+				t.Errorf("Backend = %q, want claude", turn.Backend)
+			}
+		})
+	}
+}
+
+func TestConversations_Finish(t *testing.T) {
+	store := session.NewStoreWithDir(t.TempDir())
+
+	history := func(reply string) []ConversationMessage {
+		return []ConversationMessage{
+			{Role: "user", Content: "hello"},
+			{Role: "assistant", Content: reply},
+			{Role: "user", Content: "again"},
+		}
+	}
+
+	t.Run("only the latest reply continues a session", func(t *testing.T) {
+		c := NewConversations(store, nil, 0)
+		sess := newConversationSession(t, store)
+
+		turn, _ := c.Next("", history("one")[:1])
+		c.Finish(turn, sess.ID, "one")
+		turn, _ = c.Next("", history("one")[:1])
+		c.Finish(turn, sess.ID, "two")
+
+		if turn, _ := c.Next("", history("one")); turn.SessionID != "" {
+			t.Errorf("expected the earlier reply to be forgotten, got session %q", turn.SessionID)
+		}
+		if turn, _ := c.Next("", history("two")); turn.SessionID != sess.ID {
+			t.Errorf("SessionID = %q, want %q", turn.SessionID, sess.ID)
+		}
+	})
+
+	t.Run("oldest conversations are dropped beyond the limit", func(t *testing.T) {
+		c := NewConversations(store, nil, 2)
+		replies := []string{"a", "b", "c"}
+		ids := make([]string, len(replies))
+		for i, reply := range replies {
+			ids[i] = newConversationSession(t, store).ID
+			turn, _ := c.Next("", history(reply)[:1])
+			c.Finish(turn, ids[i], reply)
+		}
+
+		if turn, _ := c.Next("", history("a")); turn.SessionID != "" {
+			t.Errorf("expected the oldest conversation to be dropped, got session %q", turn.SessionID)
+		}
+		if turn, _ := c.Next("", history("c")); turn.SessionID != ids[2] {
+			t.Errorf("SessionID = %q, want %q", turn.SessionID, ids[2])
+		}
+	})
+
+	t.Run("deleted session starts over", func(t *testing.T) {
+		c := NewConversations(store, nil, 0)
+		sess := newConversationSession(t, store)
+		turn, _ := c.Next("", history("gone")[:1])
+		c.Finish(turn, sess.ID, "gone")
+		if err := store.Delete(sess.ID); err != nil {
+			t.Fatalf("Delete() error: %v", err)
+		}
+
+		if turn, _ := c.Next("", history("gone")); turn.SessionID != "" {
+			t.Errorf("expected no session, got %q", turn.SessionID)
+		}
+	})
+}
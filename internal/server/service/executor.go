@@ -3,10 +3,12 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +18,7 @@ import (
 	"github.com/signalridge/clinvoker/internal/metrics"
 	"github.com/signalridge/clinvoker/internal/output"
 	"github.com/signalridge/clinvoker/internal/session"
+	"github.com/signalridge/clinvoker/internal/util"
 )
 
 // Default values for executor configuration.
@@ -185,6 +188,11 @@ func NewExecutor() *Executor {
 	return e
 }
 
+// SessionStore returns the store the executor keeps sessions in.
+func (e *Executor) SessionStore() *session.Store {
+	return e.store
+}
+
 // NewExecutorWithLogger creates a new executor with a custom logger.
 func NewExecutorWithLogger(logger *slog.Logger) *Executor {
 	if logger == nil {
@@ -255,6 +263,31 @@ type PromptRequest struct {
 	Ephemeral    bool              `json:"ephemeral,omitempty"`
 	Extra        []string          `json:"extra,omitempty"`
 	Metadata     map[string]string `json:"metadata,omitempty"`
+	// Fallback lists backends to try in order when the backend fails.
+	// Empty uses the configured fallback chain.
+	Fallback []string `json:"fallback,omitempty"`
+	// NoFallback runs the request on Backend only.
+	NoFallback bool `json:"no_fallback,omitempty"`
+	// Retry overrides the configured retry policy for transient failures.
+	Retry *util.RetryOptions `json:"retry,omitempty"`
+	// SessionID continues an existing session of Backend: the prompt
+	// resumes its backend session and the turn is recorded on it. Fallback
+	// is disabled, since no other backend can resume the session.
+	SessionID string `json:"session_id,omitempty"`
+	// AllowedDirs are directories the backend may read besides WorkDir,
+	// such as the attachments of a request. They are set by the server, not
+	// by clients.
+	AllowedDirs []string `json:"-"`
+	// JSONSchema asks for a reply matching this JSON Schema. The JSON is
+	// extracted from the reply and validated; see PromptResult.Structured.
+	JSONSchema map[string]any `json:"json_schema,omitempty"`
+	// JSONSchemaRetries is how many times a reply that does not match
+	// JSONSchema is asked for again.
+	JSONSchemaRetries int `json:"json_schema_retries,omitempty"`
+	// MCPServers declares MCP servers the backend connects to, by name.
+	// They are added to the configured mcp_servers, replacing any of the
+	// same name.
+	MCPServers map[string]backend.MCPServer `json:"mcp_servers,omitempty"`
 }
 
 // PromptResult represents the result of a prompt execution.
@@ -266,6 +299,13 @@ type PromptResult struct {
 	Output     string              `json:"output,omitempty"`
 	Error      string              `json:"error,omitempty"`
 	TokenUsage *session.TokenUsage `json:"token_usage,omitempty"`
+	Warnings   []string            `json:"warnings,omitempty"`
+	// Attempts lists every backend run when retries or a fallback chain
+	// are in effect.
+	Attempts []util.FallbackAttempt `json:"attempts,omitempty"`
+	// Structured is the JSON extracted from Output when the request set a
+	// JSON schema and the reply matched it.
+	Structured json.RawMessage `json:"structured,omitempty"`
 }
 
 // ExecutePrompt executes a single prompt.
@@ -285,6 +325,10 @@ type ParallelRequest struct {
 	MaxParallel int             `json:"max_parallel,omitempty"`
 	FailFast    bool            `json:"fail_fast,omitempty"`
 	DryRun      bool            `json:"dry_run,omitempty"`
+
+	// OnResult, when set, is called with each task's result as the task
+	// finishes. Calls may be concurrent.
+	OnResult func(index int, result *PromptResult) `json:"-"`
 }
 
 // ParallelResult represents the result of parallel execution.
@@ -298,6 +342,14 @@ type ParallelResult struct {
 
 // ExecuteParallel executes multiple prompts in parallel.
 func (e *Executor) ExecuteParallel(ctx context.Context, req *ParallelRequest) (*ParallelResult, error) {
+	return RunParallel(ctx, e, req, e.logger), nil
+}
+
+// RunParallel executes the tasks of req in parallel on runner.
+func RunParallel(ctx context.Context, runner PromptRunner, req *ParallelRequest, logger *slog.Logger) *ParallelResult {
+	if logger == nil {
+		logger = slog.Default()
+	}
 	start := time.Now()
 
 	maxP := req.MaxParallel
@@ -350,9 +402,9 @@ func (e *Executor) ExecuteParallel(ctx context.Context, req *ParallelRequest) (*
 			// Parallel execution is always ephemeral (clean mode).
 			t.Ephemeral = true
 
-			res, err := e.ExecutePrompt(ctx, &t)
+			res, err := runner.ExecutePrompt(ctx, &t)
 			if err != nil {
-				e.logger.Warn("prompt execution returned error", "task_index", idx, "backend", t.Backend, "error", err)
+				logger.Warn("prompt execution returned error", "task_index", idx, "backend", t.Backend, "error", err)
 			}
 
 			mu.Lock()
@@ -364,6 +416,10 @@ func (e *Executor) ExecuteParallel(ctx context.Context, req *ParallelRequest) (*
 			}
 			mu.Unlock()
 
+			if req.OnResult != nil {
+				req.OnResult(idx, res)
+			}
+
 			if req.FailFast && res.ExitCode != 0 {
 				cancel()
 			}
@@ -373,7 +429,7 @@ func (e *Executor) ExecuteParallel(ctx context.Context, req *ParallelRequest) (*
 	wg.Wait()
 	result.TotalDuration = time.Since(start).Milliseconds()
 
-	return result, nil
+	return result
 }
 
 // ChainStep represents a step in a chain execution.
@@ -390,6 +446,8 @@ type ChainStep struct {
 	Verbose      bool     `json:"verbose,omitempty"`
 	Extra        []string `json:"extra,omitempty"`
 	Name         string   `json:"name,omitempty"`
+	// Retry overrides the configured retry policy for this step.
+	Retry *util.RetryOptions `json:"retry,omitempty"`
 }
 
 // ChainRequest represents a chain execution request.
@@ -398,6 +456,10 @@ type ChainRequest struct {
 	StopOnFailure  bool        `json:"stop_on_failure,omitempty"`
 	PassWorkingDir bool        `json:"pass_working_dir,omitempty"`
 	DryRun         bool        `json:"dry_run,omitempty"`
+
+	// OnStep, when set, is called with each step's result as the step
+	// finishes.
+	OnStep func(result *ChainStepResult) `json:"-"`
 }
 
 // ChainStepResult represents the result of a chain step.
@@ -410,6 +472,8 @@ type ChainStepResult struct {
 	SessionID  string `json:"session_id,omitempty"`
 	DurationMS int64  `json:"duration_ms"`
 	Output     string `json:"output,omitempty"`
+	// Attempts lists every run of the step when it was retried or fell back.
+	Attempts []util.FallbackAttempt `json:"attempts,omitempty"`
 }
 
 // ChainResult represents the result of chain execution.
@@ -475,6 +539,7 @@ func (e *Executor) ExecuteChain(ctx context.Context, req *ChainRequest) (*ChainR
 			DryRun:       req.DryRun,
 			Ephemeral:    true,
 			Extra:        step.Extra,
+			Retry:        step.Retry,
 		}
 
 		res, err := e.ExecutePrompt(ctx, promptReq)
@@ -486,9 +551,13 @@ func (e *Executor) ExecuteChain(ctx context.Context, req *ChainRequest) (*ChainR
 		stepResult.Error = res.Error
 		stepResult.SessionID = ""
 		stepResult.Output = res.Output
+		stepResult.Attempts = res.Attempts
 		stepResult.DurationMS = time.Since(stepStart).Milliseconds()
 
 		result.Results = append(result.Results, stepResult)
+		if req.OnStep != nil {
+			req.OnStep(&stepResult)
+		}
 
 		if res.ExitCode == 0 && res.Error == "" {
 			result.CompletedSteps++
@@ -516,6 +585,10 @@ type CompareRequest struct {
 	WorkDir    string   `json:"workdir,omitempty"`
 	Sequential bool     `json:"sequential,omitempty"`
 	DryRun     bool     `json:"dry_run,omitempty"`
+
+	// OnResult, when set, is called with each backend's result as the
+	// backend finishes. Calls may be concurrent.
+	OnResult func(index int, result *CompareBackendResult) `json:"-"`
 }
 
 // CompareBackendResult represents the result from one backend in comparison.
@@ -550,6 +623,9 @@ func (e *Executor) ExecuteCompare(ctx context.Context, req *CompareRequest) (*Co
 	if req.Sequential {
 		for i, backendName := range req.Backends {
 			result.Results[i] = e.runCompareBackend(ctx, backendName, req)
+			if req.OnResult != nil {
+				req.OnResult(i, &result.Results[i])
+			}
 		}
 	} else {
 		var wg sync.WaitGroup
@@ -563,6 +639,9 @@ func (e *Executor) ExecuteCompare(ctx context.Context, req *CompareRequest) (*Co
 				mu.Lock()
 				result.Results[idx] = res
 				mu.Unlock()
+				if req.OnResult != nil {
+					req.OnResult(idx, &res)
+				}
 			}(i, backendName)
 		}
 
@@ -588,6 +667,8 @@ func (e *Executor) runCompareBackend(ctx context.Context, backendName string, re
 		DryRun:  req.DryRun,
 		// Compare is always ephemeral (clean mode).
 		Ephemeral: true,
+		// Each result must come from the backend it is reported for.
+		NoFallback: true,
 	}
 
 	res, err := e.ExecutePrompt(ctx, promptReq)
@@ -693,6 +774,27 @@ func (e *Executor) GetSession(ctx context.Context, id string) (*SessionInfo, err
 	return &info, nil
 }
 
+// SessionTranscript holds the recorded turns of a session.
+type SessionTranscript struct {
+	SessionID string         `json:"session_id"`
+	Turns     []session.Turn `json:"turns"`
+}
+
+// GetSessionTranscript returns the transcript of a session by ID, oldest
+// turn first.
+func (e *Executor) GetSessionTranscript(ctx context.Context, id string) (*SessionTranscript, error) {
+	s, err := e.store.GetByPrefix(id)
+	if err != nil {
+		return nil, err
+	}
+
+	turns, err := e.store.Transcript(s.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionTranscript{SessionID: s.ID, Turns: turns}, nil
+}
+
 // DeleteSession deletes a session by ID.
 func (e *Executor) DeleteSession(ctx context.Context, id string) error {
 	s, err := e.store.GetByPrefix(id)
@@ -704,26 +806,139 @@ func (e *Executor) DeleteSession(ctx context.Context, id string) error {
 
 // BackendInfo represents backend information for API responses.
 type BackendInfo struct {
-	Name      string `json:"name"`
-	Available bool   `json:"available"`
+	Name         string               `json:"name"`
+	Available    bool                 `json:"available"`
+	Capabilities backend.Capabilities `json:"capabilities"`
+	// Pools lists the pools the backend is a member of.
+	Pools []string `json:"pools,omitempty"`
+	// Pool describes the members of a backend pool; nil for plain backends.
+	Pool *PoolInfo `json:"pool,omitempty"`
+}
+
+// PoolInfo describes a backend pool and its members.
+type PoolInfo struct {
+	Strategy string           `json:"strategy"`
+	InFlight int              `json:"in_flight"`
+	Members  []PoolMemberInfo `json:"members"`
+}
+
+// PoolMemberInfo describes a member of a backend pool.
+type PoolMemberInfo struct {
+	Backend        string `json:"backend"`
+	Available      bool   `json:"available"`
+	Weight         int    `json:"weight"`
+	MaxConcurrency int    `json:"max_concurrency,omitempty"`
+	InFlight       int    `json:"in_flight"`
 }
 
-// ListBackends returns all registered backends.
+// ListBackends returns all registered backends followed by the backend pools.
 // Uses cached availability checks for better performance during frequent health checks.
 func (e *Executor) ListBackends(ctx context.Context) []BackendInfo {
 	names := backend.List()
-	result := make([]BackendInfo, len(names))
+	pools := util.BackendPoolStats()
+	result := make([]BackendInfo, len(names), len(names)+len(pools))
+
+	memberOf := make(map[string][]string)
+	for _, p := range pools {
+		for _, m := range p.Members {
+			memberOf[m.Backend] = append(memberOf[m.Backend], p.Name)
+		}
+	}
 
 	for i, name := range names {
 		result[i] = BackendInfo{
 			Name:      name,
 			Available: backend.IsAvailableCached(name),
+			Pools:     memberOf[name],
+		}
+		if b, err := backend.Get(name); err == nil {
+			caps := b.Capabilities()
+			// Report empty mode lists as [] rather than null.
+			if caps.ApprovalModes == nil {
+				caps.ApprovalModes = []backend.ApprovalMode{}
+			}
+			if caps.SandboxModes == nil {
+				caps.SandboxModes = []backend.SandboxMode{}
+			}
+			result[i].Capabilities = caps
 		}
 	}
 
+	for _, p := range pools {
+		info := BackendInfo{
+			Name: p.Name,
+			Capabilities: backend.Capabilities{
+				ApprovalModes: []backend.ApprovalMode{},
+				SandboxModes:  []backend.SandboxMode{},
+			},
+			Pool: &PoolInfo{
+				Strategy: string(p.Strategy),
+				InFlight: p.InFlight,
+				Members:  make([]PoolMemberInfo, len(p.Members)),
+			},
+		}
+		for j, m := range p.Members {
+			available := backend.IsAvailableCached(m.Backend)
+			info.Pool.Members[j] = PoolMemberInfo{
+				Backend:        m.Backend,
+				Available:      available,
+				Weight:         m.Weight,
+				MaxConcurrency: m.MaxConcurrency,
+				InFlight:       m.InFlight,
+			}
+			info.Available = info.Available || available
+		}
+		result = append(result, info)
+	}
+
 	return result
 }
 
+// CircuitBreakerInfo describes the circuit breaker guarding a backend.
+type CircuitBreakerInfo struct {
+	Backend        string
+	State          string
+	FailureCount   int
+	TotalFailures  int64
+	TotalSuccesses int64
+	TotalRejected  int64
+	LastFailure    time.Time
+	LastChange     time.Time
+}
+
+// CircuitBreakers returns the circuit breakers of all backends called so far,
+// ordered by backend name.
+func (e *Executor) CircuitBreakers(ctx context.Context) []CircuitBreakerInfo {
+	stats := util.BackendBreakerStats()
+	result := make([]CircuitBreakerInfo, 0, len(stats))
+	for name, st := range stats {
+		result = append(result, CircuitBreakerInfo{
+			Backend:        name,
+			State:          st.State.String(),
+			FailureCount:   st.FailureCount,
+			TotalFailures:  st.TotalFailures,
+			TotalSuccesses: st.TotalSuccesses,
+			TotalRejected:  st.TotalRejected,
+			LastFailure:    st.LastFailure,
+			LastChange:     st.LastChange,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Backend < result[j].Backend })
+	return result
+}
+
+// ResetCircuitBreakers closes the circuit of the named backend, or of all
+// backends when backendName is empty, and returns the backends reset.
+func (e *Executor) ResetCircuitBreakers(ctx context.Context, backendName string) ([]string, error) {
+	if backendName == "" {
+		return util.ResetBackendBreakers(), nil
+	}
+	if _, err := backend.Get(backendName); err != nil {
+		return nil, err
+	}
+	return util.ResetBackendBreakers(backendName), nil
+}
+
 // SessionStoreHealth represents the health status of the session store.
 type SessionStoreHealth struct {
 	Available    bool
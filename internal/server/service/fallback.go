@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+	"github.com/signalridge/clinvoker/internal/output"
+	"github.com/signalridge/clinvoker/internal/util"
+)
+
+// fallbackChain returns the backends to try for req, in order.
+func fallbackChain(req *PromptRequest) []string {
+	if req.NoFallback || req.SessionID != "" {
+		return []string{req.Backend}
+	}
+	return util.FallbackChain(req.Backend, req.Fallback, config.Get())
+}
+
+// fallbackRequest returns the request to send to the i-th backend of a
+// fallback chain. Fallback backends get no backend-specific extra flags and
+// only keep the model when it is an alias they understand.
+func fallbackRequest(req *PromptRequest, backendName string, i int) *PromptRequest {
+	attempt := *req
+	attempt.Backend = backendName
+	if i > 0 {
+		attempt.Model = util.FallbackModel(backendName, req.Model)
+		attempt.Extra = nil
+	}
+	return &attempt
+}
+
+// classifyPrepareError classifies a failure to prepare a request for a backend
+// and reports whether another backend may succeed. Failures tied to the
+// backend (unknown, unavailable, open circuit, unsupported options) fall
+// back; invalid requests do not.
+func classifyPrepareError(backendName string, err error) (apperrors.ErrorCode, bool) {
+	if apperrors.IsCode(err, apperrors.ErrCodeBackendUnavailable) {
+		return apperrors.ErrCodeBackendUnavailable, true
+	}
+
+	// Waiting again on another backend would only lengthen the queue.
+	if util.IsConcurrencyRejection(err) {
+		return apperrors.GetCode(err), false
+	}
+
+	var unsupported *backend.UnsupportedOptionsError
+	if errors.As(err, &unsupported) {
+		return apperrors.ErrCodeValidation, true
+	}
+
+	b, getErr := backend.Get(backendName)
+	if getErr != nil {
+		return apperrors.ErrCodeBackendNotFound, true
+	}
+	if !b.IsAvailable() {
+		return apperrors.ErrCodeBackendUnavailable, true
+	}
+	return apperrors.ErrCodeInvalidRequest, false
+}
+
+// concurrencyRejection returns err when it is a rejection by the server's
+// concurrency limits, which is reported to the client rather than as a failed
+// run, and nil otherwise.
+func concurrencyRejection(err error) error {
+	if util.IsConcurrencyRejection(err) {
+		return err
+	}
+	return nil
+}
+
+// classifyRunFailure classifies a backend run that exited non-zero and
+// reports whether another backend may succeed.
+func classifyRunFailure(exitCode int, errMsg string) (apperrors.ErrorCode, bool) {
+	code := apperrors.ClassifyFailure(exitCode, errMsg)
+	return code, apperrors.IsBackendFailure(code)
+}
+
+// heldEmitter forwards stream events for one fallback attempt. Events before
+// the first content event are held back, so an attempt that fails before
+// answering can be discarded without the client seeing it.
+type heldEmitter struct {
+	onEvent   func(*output.UnifiedEvent) error
+	held      []*output.UnifiedEvent
+	committed bool
+}
+
+func (e *heldEmitter) emit(event *output.UnifiedEvent) error {
+	if !e.committed && !event.Type.IsContent() {
+		e.held = append(e.held, event)
+		return nil
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	if e.onEvent == nil {
+		return nil
+	}
+	return e.onEvent(event)
+}
+
+// flush delivers the held events and forwards all further events directly.
+func (e *heldEmitter) flush() error {
+	e.committed = true
+	held := e.held
+	e.held = nil
+	if e.onEvent == nil {
+		return nil
+	}
+	for _, event := range held {
+		if err := e.onEvent(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/output"
+)
+
+func initFallbackConfig(t *testing.T) {
+	t.Helper()
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+}
+
+func TestExecutePrompt_Fallback(t *testing.T) {
+	initFallbackConfig(t)
+
+	limited := mock.NewMockBackend("mock-fb-limited",
+		mock.WithAvailable(true),
+		mock.WithJSONResponse(&backend.UnifiedResponse{Error: "API Error: 429 Too Many Requests"}),
+	)
+	healthy := mock.NewMockBackend("mock-fb-healthy", mock.WithAvailable(true))
+	t.Cleanup(mock.WithMockBackend(t, limited))
+	t.Cleanup(mock.WithMockBackend(t, healthy))
+
+	t.Run("falls back on rate limit", func(t *testing.T) {
+		result, err := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend:  "mock-fb-limited",
+			Prompt:   "hello",
+			Fallback: []string{"mock-fb-missing", "mock-fb-healthy"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ExitCode != 0 || result.Backend != "mock-fb-healthy" {
+			t.Fatalf("expected success from mock-fb-healthy, got backend=%q exit=%d error=%q", result.Backend, result.ExitCode, result.Error)
+		}
+		if !strings.Contains(result.Output, "hello") {
+			t.Errorf("unexpected output %q", result.Output)
+		}
+
+		wantCodes := []apperrors.ErrorCode{apperrors.ErrCodeRateLimited, apperrors.ErrCodeBackendNotFound, ""}
+		if len(result.Attempts) != len(wantCodes) {
+			t.Fatalf("expected %d attempts, got %+v", len(wantCodes), result.Attempts)
+		}
+		for i, want := range wantCodes {
+			if got := apperrors.ErrorCode(result.Attempts[i].ErrorCode); got != want {
+				t.Errorf("attempt %d code = %q, want %q", i, got, want)
+			}
+		}
+	})
+
+	t.Run("config chain", func(t *testing.T) {
+		config.Get().Fallback = []string{"mock-fb-healthy"}
+		t.Cleanup(func() { config.Get().Fallback = nil })
+
+		result, _ := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend: "mock-fb-limited",
+			Prompt:  "hello",
+		})
+		if result.Backend != "mock-fb-healthy" || len(result.Attempts) != 2 {
+			t.Errorf("expected config fallback to mock-fb-healthy, got %+v", result)
+		}
+	})
+
+	t.Run("no fallback", func(t *testing.T) {
+		result, _ := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend:    "mock-fb-limited",
+			Prompt:     "hello",
+			Fallback:   []string{"mock-fb-healthy"},
+			NoFallback: true,
+		})
+		if result.Backend != "mock-fb-limited" || result.ExitCode == 0 || result.Attempts != nil {
+			t.Errorf("expected single failed attempt, got %+v", result)
+		}
+	})
+
+	t.Run("invalid request does not fall back", func(t *testing.T) {
+		result, _ := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend:      "mock-fb-limited",
+			Prompt:       "hello",
+			OutputFormat: "yaml",
+			Fallback:     []string{"mock-fb-healthy"},
+		})
+		if len(result.Attempts) != 1 || result.Attempts[0].ErrorCode != string(apperrors.ErrCodeInvalidRequest) {
+			t.Errorf("expected one invalid_request attempt, got %+v", result.Attempts)
+		}
+	})
+}
+
+func TestFallbackRequest(t *testing.T) {
+	req := &PromptRequest{Backend: "claude", Model: "claude-opus-4-5", Extra: []string{"--verbose"}}
+
+	primary := fallbackRequest(req, "claude", 0)
+	if primary.Model != req.Model || len(primary.Extra) != 1 {
+		t.Errorf("expected primary request unchanged, got %+v", primary)
+	}
+
+	fb := fallbackRequest(req, "gemini", 1)
+	if fb.Backend != "gemini" || fb.Model != "" || fb.Extra != nil {
+		t.Errorf("expected backend-specific fields dropped, got %+v", fb)
+	}
+	if req.Backend != "claude" {
+		t.Error("expected original request to be unchanged")
+	}
+}
+
+// decodeTestLine turns "error:<msg>" lines into error events and other lines into messages.
+func decodeTestLine(line string) (output.EventType, any, error) {
+	if msg, ok := strings.CutPrefix(line, "error:"); ok {
+		return output.EventError, &output.ErrorContent{Message: msg}, nil
+	}
+	return output.EventMessage, &output.MessageContent{Text: line, Role: "assistant"}, nil
+}
+
+func TestStreamPrompt_Fallback(t *testing.T) {
+	initFallbackConfig(t)
+
+	failing := mock.NewMockBackend("mock-fbs-failing",
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(func(string, *backend.UnifiedOptions) *exec.Cmd {
+			return exec.Command("sh", "-c", "echo 'error:rate limit exceeded'; exit 1")
+		}),
+	)
+	healthy := mock.NewMockBackend("mock-fbs-healthy", mock.WithAvailable(true))
+	t.Cleanup(mock.WithMockBackend(t, failing))
+	t.Cleanup(mock.WithMockBackend(t, healthy))
+	for _, name := range []string{"mock-fbs-failing", "mock-fbs-healthy"} {
+		output.RegisterLineDecoder(name, decodeTestLine)
+		t.Cleanup(func() { output.UnregisterLineDecoder(name) })
+	}
+
+	var events []*output.UnifiedEvent
+	result, err := StreamPrompt(context.Background(), &PromptRequest{
+		Backend:  "mock-fbs-failing",
+		Prompt:   "hello",
+		Fallback: []string{"mock-fbs-healthy"},
+	}, nil, nil, true, func(event *output.UnifiedEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Backend != "mock-fbs-healthy" || result.ExitCode != 0 {
+		t.Fatalf("expected success from mock-fbs-healthy, got %+v", result)
+	}
+	if len(result.Attempts) != 2 || result.Attempts[0].ErrorCode != string(apperrors.ErrCodeRateLimited) {
+		t.Errorf("unexpected attempts: %+v", result.Attempts)
+	}
+	for _, event := range events {
+		if event.Backend != "mock-fbs-healthy" {
+			t.Errorf("expected events of the failed attempt to be dropped, got %s event from %s", event.Type, event.Backend)
+		}
+	}
+	if len(events) == 0 {
+		t.Error("expected events from the fallback backend")
+	}
+}
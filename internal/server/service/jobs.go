@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/job"
+	"github.com/signalridge/clinvoker/internal/webhook"
+)
+
+// Errors returned by the job manager.
+var (
+	ErrJobFinished      = errors.New("job has already finished")
+	ErrJobsShuttingDown = errors.New("server is shutting down")
+)
+
+// Causes recorded when a running job's context is canceled.
+var (
+	errJobCanceled       = errors.New("canceled by client")
+	errJobServerShutdown = errors.New("interrupted by server shutdown")
+)
+
+// jobInterruptedMessage is the error of jobs found unfinished at startup.
+const jobInterruptedMessage = "interrupted by server restart"
+
+// subscriberBuffer is the number of events buffered for a job subscriber.
+// A subscriber that falls further behind is dropped and must resume from
+// the persisted events.
+const subscriberBuffer = 64
+
+// JobFunc runs the work of a job. It reports progress through emit and
+// returns the job's result, which is stored as JSON.
+type JobFunc func(ctx context.Context, emit func(eventType string, data any)) (any, error)
+
+// JobStatusEvent is the data of a job's status events.
+type JobStatusEvent struct {
+	Status job.Status `json:"status"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// JobManager runs jobs in the background, detached from the request that
+// submitted them, and persists their state and events in a job store.
+type JobManager struct {
+	store    *job.Store
+	logger   *slog.Logger
+	webhooks *webhook.Dispatcher
+
+	// maxAge and maxFinished limit the finished jobs kept in the store.
+	maxAge      time.Duration
+	maxFinished int
+
+	mu     sync.Mutex
+	active map[string]*runningJob
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// runningJob is the in-memory state of a job that has not finished.
+type runningJob struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+
+	mu          sync.Mutex
+	job         *job.Job
+	seq         int
+	finished    bool
+	subscribers map[chan job.Event]struct{}
+}
+
+// NewJobManager creates a job manager backed by store.
+func NewJobManager(store *job.Store, logger *slog.Logger) *JobManager {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &JobManager{
+		store:  store,
+		logger: logger,
+		active: make(map[string]*runningJob),
+	}
+}
+
+// SetWebhooks sets the dispatcher that delivers finished jobs to their
+// callback URLs. Without one, callback URLs are ignored.
+func (m *JobManager) SetWebhooks(d *webhook.Dispatcher) {
+	m.webhooks = d
+}
+
+// SetRetention limits the finished jobs kept in the store to those that
+// finished within maxAge, and to the newest maxFinished. A zero limit is
+// disabled. Jobs are pruned by Prune and whenever a job finishes.
+func (m *JobManager) SetRetention(maxAge time.Duration, maxFinished int) {
+	m.maxAge = maxAge
+	m.maxFinished = maxFinished
+}
+
+// Prune deletes the finished jobs, and their events, that the retention
+// limits no longer keep, and returns how many there were.
+func (m *JobManager) Prune() (int, error) {
+	return m.store.Prune(m.maxAge, m.maxFinished)
+}
+
+// notify delivers a finished job to its callback URL, as a "job.<status>"
+// event.
+func (m *JobManager) notify(j *job.Job) {
+	if m.webhooks == nil || j.CallbackURL == "" {
+		return
+	}
+	if _, err := m.webhooks.Send(j.CallbackURL, "job."+string(j.Status), j.ID, j); err != nil {
+		m.logger.Warn("failed to send job webhook", "job_id", j.ID, "error", err)
+	}
+}
+
+// Recover marks the jobs a previous server process left unfinished as
+// failed, notifying their callback URLs, and returns how many there were.
+func (m *JobManager) Recover() (int, error) {
+	jobs, err := m.store.List()
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for _, j := range jobs {
+		if j.Status.Done() {
+			continue
+		}
+		now := time.Now()
+		j.Status = job.StatusFailed
+		j.Error = jobInterruptedMessage
+		j.FinishedAt = &now
+		if err := m.store.Save(j); err != nil {
+			return recovered, err
+		}
+		recovered++
+
+		seq, err := m.store.LastSeq(j.ID)
+		if err != nil {
+			m.logger.Warn("failed to read job events", "job_id", j.ID, "error", err)
+		}
+		data, _ := json.Marshal(JobStatusEvent{Status: j.Status, Error: j.Error})
+		if err := m.store.AppendEvent(j.ID, job.Event{Seq: seq + 1, Type: job.EventStatus, Time: now, Data: data}); err != nil {
+			m.logger.Warn("failed to record job event", "job_id", j.ID, "error", err)
+		}
+		m.notify(j)
+	}
+	return recovered, nil
+}
+
+// Submit persists a new job for request and starts run in the background.
+// The finished job is POSTed to callbackURL, if set. It returns the job as
+// submitted.
+func (m *JobManager) Submit(kind job.Kind, request json.RawMessage, callbackURL string, run JobFunc) (*job.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrJobsShuttingDown
+	}
+
+	j, err := m.store.Create(kind, request)
+	if err != nil {
+		return nil, err
+	}
+	if callbackURL != "" {
+		j.CallbackURL = callbackURL
+		if err := m.store.Save(j); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	rj := &runningJob{
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		job:         j,
+		subscribers: make(map[chan job.Event]struct{}),
+	}
+	m.active[j.ID] = rj
+	submitted := *j
+	m.record(rj, job.EventStatus, JobStatusEvent{Status: j.Status})
+
+	m.wg.Add(1)
+	go m.run(ctx, rj, run)
+	return &submitted, nil
+}
+
+func (m *JobManager) run(ctx context.Context, rj *runningJob, run JobFunc) {
+	defer m.wg.Done()
+
+	rj.mu.Lock()
+	now := time.Now()
+	rj.job.Status = job.StatusRunning
+	rj.job.StartedAt = &now
+	m.save(rj.job)
+	rj.mu.Unlock()
+	m.record(rj, job.EventStatus, JobStatusEvent{Status: job.StatusRunning})
+
+	result, err := run(ctx, func(eventType string, data any) {
+		m.record(rj, eventType, data)
+	})
+	m.finish(ctx, rj, result, err)
+}
+
+// finish stores the outcome of a job's run and ends its subscriptions.
+func (m *JobManager) finish(ctx context.Context, rj *runningJob, result any, runErr error) {
+	status := job.StatusCompleted
+	var errMsg string
+	var resultData json.RawMessage
+
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil && runErr == nil {
+			runErr = fmt.Errorf("failed to encode job result: %w", err)
+		}
+		resultData = data
+	}
+
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, errJobCanceled):
+		status, errMsg = job.StatusCanceled, cause.Error()
+	case errors.Is(cause, errJobServerShutdown):
+		status, errMsg = job.StatusFailed, cause.Error()
+	case runErr != nil:
+		status, errMsg = job.StatusFailed, runErr.Error()
+	}
+
+	rj.mu.Lock()
+	now := time.Now()
+	rj.job.Status = status
+	rj.job.Error = errMsg
+	rj.job.Result = resultData
+	rj.job.FinishedAt = &now
+	m.save(rj.job)
+	m.recordLocked(rj, job.EventStatus, JobStatusEvent{Status: status, Error: errMsg})
+	rj.finished = true
+	for ch := range rj.subscribers {
+		close(ch)
+	}
+	rj.subscribers = nil
+	finished := *rj.job
+	rj.mu.Unlock()
+	m.notify(&finished)
+
+	m.mu.Lock()
+	delete(m.active, rj.job.ID)
+	m.mu.Unlock()
+	if _, err := m.Prune(); err != nil {
+		m.logger.Warn("failed to prune jobs", "error", err)
+	}
+	rj.cancel(nil)
+	close(rj.done)
+}
+
+func (m *JobManager) save(j *job.Job) {
+	if err := m.store.Save(j); err != nil {
+		m.logger.Warn("failed to save job", "job_id", j.ID, "error", err)
+	}
+}
+
+// record persists an event for a job and passes it to its subscribers.
+func (m *JobManager) record(rj *runningJob, eventType string, data any) {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+	m.recordLocked(rj, eventType, data)
+}
+
+// recordLocked is record with rj.mu held.
+func (m *JobManager) recordLocked(rj *runningJob, eventType string, data any) {
+	if rj.finished {
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		m.logger.Warn("failed to encode job event", "job_id", rj.job.ID, "type", eventType, "error", err)
+		return
+	}
+
+	rj.seq++
+	ev := job.Event{Seq: rj.seq, Type: eventType, Time: time.Now(), Data: raw}
+	if err := m.store.AppendEvent(rj.job.ID, ev); err != nil {
+		m.logger.Warn("failed to record job event", "job_id", rj.job.ID, "type", eventType, "error", err)
+	}
+	for ch := range rj.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// Too slow; the subscriber can resume from the stored events.
+			delete(rj.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (m *JobManager) running(id string) *runningJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active[id]
+}
+
+// Get returns a job by ID.
+func (m *JobManager) Get(id string) (*job.Job, error) {
+	if rj := m.running(id); rj != nil {
+		rj.mu.Lock()
+		defer rj.mu.Unlock()
+		j := *rj.job
+		return &j, nil
+	}
+	return m.store.Get(id)
+}
+
+// List returns all jobs, newest first.
+func (m *JobManager) List() ([]*job.Job, error) {
+	return m.store.List()
+}
+
+// Cancel stops a running job and waits, until ctx ends, for it to finish.
+// It returns the job and ErrJobFinished when the job had already finished.
+func (m *JobManager) Cancel(ctx context.Context, id string) (*job.Job, error) {
+	rj := m.running(id)
+	if rj == nil {
+		j, err := m.store.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		return j, ErrJobFinished
+	}
+
+	rj.cancel(errJobCanceled)
+	select {
+	case <-rj.done:
+	case <-ctx.Done():
+	}
+	return m.Get(id)
+}
+
+// Subscribe returns a job's events after afterSeq and, while the job runs,
+// a channel of its later events. The channel is closed when the job
+// finishes, when stop is called, or when the subscriber falls too far
+// behind; live is nil for a finished job.
+func (m *JobManager) Subscribe(id string, afterSeq int) (past []job.Event, live <-chan job.Event, stop func(), err error) {
+	stop = func() {}
+
+	rj := m.running(id)
+	if rj == nil {
+		if _, err := m.store.Get(id); err != nil {
+			return nil, nil, stop, err
+		}
+		past, err = m.store.Events(id, afterSeq)
+		return past, nil, stop, err
+	}
+
+	// Events are stored and sent under rj.mu, so none falls between the
+	// stored ones read here and the live ones sent to the channel.
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+
+	past, err = m.store.Events(id, afterSeq)
+	if err != nil || rj.finished {
+		return past, nil, stop, err
+	}
+
+	ch := make(chan job.Event, subscriberBuffer)
+	rj.subscribers[ch] = struct{}{}
+	stop = func() {
+		rj.mu.Lock()
+		defer rj.mu.Unlock()
+		if _, ok := rj.subscribers[ch]; ok {
+			delete(rj.subscribers, ch)
+			close(ch)
+		}
+	}
+	return past, ch, stop, nil
+}
+
+// Shutdown stops accepting jobs, interrupts the running ones and waits,
+// until ctx ends, for them to finish. Interrupted jobs are marked failed.
+func (m *JobManager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	for _, rj := range m.active {
+		rj.cancel(errJobServerShutdown)
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/job"
+	"github.com/signalridge/clinvoker/internal/webhook"
+)
+
+// waitForJob polls until the job reaches a final status.
+func waitForJob(t *testing.T, m *JobManager, id string) *job.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		j, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Get() error: %v", err)
+		}
+		if j.Status.Done() {
+			return j
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+// blockingJob runs until its context ends.
+func blockingJob(started chan<- struct{}) JobFunc {
+	return func(ctx context.Context, _ func(string, any)) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+}
+
+func eventTypes(events []job.Event) []string {
+	types := make([]string, len(events))
+	for i, ev := range events {
+		types[i] = ev.Type
+	}
+	return types
+}
+
+func TestJobManager_Submit(t *testing.T) {
+	store := job.NewStoreWithDir(t.TempDir())
+	m := NewJobManager(store, nil)
+
+	t.Run("completed", func(t *testing.T) {
+		j, err := m.Submit(job.KindPrompt, json.RawMessage(`{"prompt":"hi"}`), "", func(_ context.Context, emit func(string, any)) (any, error) {
+			emit("progress", map[string]int{"done": 1})
+			return map[string]string{"output": "hello"}, nil
+		})
+		if err != nil {
+			t.Fatalf("Submit() error: %v", err)
+		}
+		if j.Status != job.StatusPending {
+			t.Errorf("expected a pending job, got %s", j.Status)
+		}
+
+		done := waitForJob(t, m, j.ID)
+		if done.Status != job.StatusCompleted || string(done.Result) != `{"output":"hello"}` {
+			t.Errorf("unexpected job: %+v", done)
+		}
+		if done.StartedAt == nil || done.FinishedAt == nil {
+			t.Error("expected start and finish times")
+		}
+
+		events, live, _, err := m.Subscribe(j.ID, 0)
+		if err != nil || live != nil {
+			t.Fatalf("Subscribe() = %v, %v; want only stored events", live, err)
+		}
+		want := []string{job.EventStatus, job.EventStatus, "progress", job.EventStatus}
+		if got := eventTypes(events); len(got) != len(want) || got[2] != "progress" {
+			t.Errorf("event types = %v, want %v", got, want)
+		}
+		if last := events[len(events)-1]; last.Seq != 4 || string(last.Data) != `{"status":"completed"}` {
+			t.Errorf("unexpected final event: %+v", last)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		j, _ := m.Submit(job.KindChain, nil, "", func(context.Context, func(string, any)) (any, error) {
+			return nil, errors.New("boom")
+		})
+		done := waitForJob(t, m, j.ID)
+		if done.Status != job.StatusFailed || done.Error != "boom" {
+			t.Errorf("unexpected job: %+v", done)
+		}
+	})
+}
+
+func TestJobManager_Retention(t *testing.T) {
+	store := job.NewStoreWithDir(t.TempDir())
+	m := NewJobManager(store, nil)
+	m.SetRetention(time.Hour, 1)
+
+	run := func(context.Context, func(string, any)) (any, error) { return "ok", nil }
+	first, _ := m.Submit(job.KindPrompt, nil, "", run)
+	waitForJob(t, m, first.ID)
+	second, _ := m.Submit(job.KindPrompt, nil, "", run)
+	// Shutdown waits for the second job to finish, and so to prune
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error: %v", err)
+	}
+
+	jobs, err := m.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != second.ID {
+		t.Errorf("expected only the newest finished job to be kept, got %d jobs", len(jobs))
+	}
+}
+
+func TestJobManager_SubscribeLive(t *testing.T) {
+	m := NewJobManager(job.NewStoreWithDir(t.TempDir()), nil)
+
+	proceed := make(chan struct{})
+	j, _ := m.Submit(job.KindPrompt, nil, "", func(_ context.Context, emit func(string, any)) (any, error) {
+		<-proceed
+		emit("output", "a")
+		emit("output", "b")
+		return nil, nil
+	})
+
+	past, live, stop, err := m.Subscribe(j.ID, 1)
+	if err != nil {
+		t.Fatalf("Subscribe() error: %v", err)
+	}
+	defer stop()
+	close(proceed)
+
+	seqs := []int{}
+	for _, ev := range past {
+		seqs = append(seqs, ev.Seq)
+	}
+	for ev := range live {
+		if len(seqs) > 0 && ev.Seq <= seqs[len(seqs)-1] {
+			continue
+		}
+		seqs = append(seqs, ev.Seq)
+	}
+	// Events 2 (running) to 5 (completed), with nothing missed or repeated.
+	if len(seqs) != 4 || seqs[0] != 2 || seqs[3] != 5 {
+		t.Errorf("unexpected event sequence %v", seqs)
+	}
+}
+
+func TestJobManager_Cancel(t *testing.T) {
+	m := NewJobManager(job.NewStoreWithDir(t.TempDir()), nil)
+
+	started := make(chan struct{})
+	j, _ := m.Submit(job.KindParallel, nil, "", blockingJob(started))
+	<-started
+
+	canceled, err := m.Cancel(context.Background(), j.ID)
+	if err != nil {
+		t.Fatalf("Cancel() error: %v", err)
+	}
+	if canceled.Status != job.StatusCanceled || canceled.Error != "canceled by client" {
+		t.Errorf("unexpected job: %+v", canceled)
+	}
+
+	if _, err := m.Cancel(context.Background(), j.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("expected ErrJobFinished, got %v", err)
+	}
+	if _, err := m.Cancel(context.Background(), "0123456789abcdef0123456789abcdef"); !errors.Is(err, job.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestJobManager_ShutdownAndRecover(t *testing.T) {
+	store := job.NewStoreWithDir(t.TempDir())
+	m := NewJobManager(store, nil)
+
+	started := make(chan struct{})
+	running, _ := m.Submit(job.KindPrompt, nil, "", blockingJob(started))
+	<-started
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error: %v", err)
+	}
+	if j, _ := store.Get(running.ID); j.Status != job.StatusFailed || j.Error != "interrupted by server shutdown" {
+		t.Errorf("unexpected job after shutdown: %+v", j)
+	}
+	if _, err := m.Submit(job.KindPrompt, nil, "", nil); !errors.Is(err, ErrJobsShuttingDown) {
+		t.Errorf("expected ErrJobsShuttingDown, got %v", err)
+	}
+
+	// A job a crashed process left running.
+	orphan, _ := store.Create(job.KindChain, nil)
+	orphan.Status = job.StatusRunning
+	if err := store.Save(orphan); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := NewJobManager(store, nil).Recover()
+	if err != nil || n != 1 {
+		t.Fatalf("Recover() = %d, %v; want 1", n, err)
+	}
+	j, _ := store.Get(orphan.ID)
+	if j.Status != job.StatusFailed || j.Error != "interrupted by server restart" || j.FinishedAt == nil {
+		t.Errorf("unexpected recovered job: %+v", j)
+	}
+	if events, _ := store.Events(orphan.ID, 0); len(events) != 1 || events[0].Seq != 1 {
+		t.Errorf("expected a final status event, got %+v", events)
+	}
+}
+
+func TestJobManager_Webhooks(t *testing.T) {
+	type callback struct {
+		event string
+		body  []byte
+	}
+	callbacks := make(chan callback, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		callbacks <- callback{event: r.Header.Get(webhook.HeaderEvent), body: body}
+	}))
+	defer srv.Close()
+
+	store := job.NewStoreWithDir(t.TempDir())
+	dispatcher := webhook.NewDispatcher(webhook.Config{AllowedHosts: []string{"127.0.0.1"}})
+	defer func() { _ = dispatcher.Shutdown(context.Background()) }()
+	m := NewJobManager(store, nil)
+	m.SetWebhooks(dispatcher)
+
+	j, _ := m.Submit(job.KindPrompt, nil, srv.URL, func(context.Context, func(string, any)) (any, error) {
+		return map[string]string{"output": "hello"}, nil
+	})
+	cb := <-callbacks
+	var payload struct {
+		Data job.Job `json:"data"`
+	}
+	if err := json.Unmarshal(cb.body, &payload); err != nil {
+		t.Fatalf("invalid payload %s: %v", cb.body, err)
+	}
+	if cb.event != "job.completed" || payload.Data.ID != j.ID || string(payload.Data.Result) != `{"output":"hello"}` {
+		t.Errorf("unexpected callback %s: %s", cb.event, cb.body)
+	}
+
+	// A job without a callback URL sends nothing.
+	quiet, _ := m.Submit(job.KindPrompt, nil, "", func(context.Context, func(string, any)) (any, error) {
+		return nil, nil
+	})
+	waitForJob(t, m, quiet.ID)
+
+	// Recovered jobs are reported as failed.
+	orphan, _ := store.Create(job.KindChain, nil)
+	orphan.Status = job.StatusRunning
+	orphan.CallbackURL = srv.URL
+	if err := store.Save(orphan); err != nil {
+		t.Fatal(err)
+	}
+	recovering := NewJobManager(store, nil)
+	recovering.SetWebhooks(dispatcher)
+	if _, err := recovering.Recover(); err != nil {
+		t.Fatalf("Recover() error: %v", err)
+	}
+	if cb := <-callbacks; cb.event != "job.failed" {
+		t.Errorf("unexpected callback %s: %s", cb.event, cb.body)
+	}
+	if n := len(dispatcher.List("", 0)); n != 2 {
+		t.Errorf("expected 2 deliveries, got %d", n)
+	}
+}
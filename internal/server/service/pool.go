@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"github.com/signalridge/clinvoker/internal/pool"
+	"github.com/signalridge/clinvoker/internal/util"
+)
+
+// leasePoolMember resolves a backend pool named by req.Backend to one of its
+// members and returns the request to run on that member. The lease must be
+// released when the run ends. Requests for other backends are returned as is
+// with a nil lease.
+func leasePoolMember(ctx context.Context, req *PromptRequest) (*PromptRequest, *pool.Lease, error) {
+	lease, err := util.AcquirePoolMember(ctx, req.Backend)
+	if err != nil || lease == nil {
+		return req, nil, err
+	}
+	member := *req
+	member.Backend = lease.Backend()
+	return &member, lease, nil
+}
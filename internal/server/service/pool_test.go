@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/output"
+)
+
+func TestExecutePrompt_Pool(t *testing.T) {
+	initFallbackConfig(t)
+
+	for _, name := range []string{"mock-pool-a", "mock-pool-b"} {
+		t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend(name, mock.WithAvailable(true))))
+	}
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-pool-down", mock.WithAvailable(false))))
+	config.Get().Pools = map[string]config.PoolConfig{
+		"mock-pool": {Members: []config.PoolMemberConfig{
+			{Backend: "mock-pool-a"}, {Backend: "mock-pool-down"}, {Backend: "mock-pool-b"},
+		}},
+		"mock-pool-empty": {Members: []config.PoolMemberConfig{{Backend: "mock-pool-down"}}},
+	}
+
+	t.Run("round robin over available members", func(t *testing.T) {
+		var got []string
+		for i := 0; i < 3; i++ {
+			result, _ := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+				Backend: "mock-pool",
+				Prompt:  "hello",
+			})
+			if result.ExitCode != 0 {
+				t.Fatalf("unexpected failure: %+v", result)
+			}
+			got = append(got, result.Backend)
+		}
+		if got[0] == got[1] || got[0] != got[2] {
+			t.Errorf("expected requests to alternate between members, got %v", got)
+		}
+	})
+
+	t.Run("no available member falls back", func(t *testing.T) {
+		result, _ := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend:  "mock-pool-empty",
+			Prompt:   "hello",
+			Fallback: []string{"mock-pool-a"},
+		})
+		if result.Backend != "mock-pool-a" || len(result.Attempts) != 2 {
+			t.Fatalf("expected fallback to mock-pool-a, got %+v", result)
+		}
+		if result.Attempts[0].Backend != "mock-pool-empty" || result.Attempts[0].ErrorCode != string(apperrors.ErrCodeBackendUnavailable) {
+			t.Errorf("unexpected first attempt: %+v", result.Attempts[0])
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		output.RegisterLineDecoder("mock-pool-a", decodeTestLine)
+		output.RegisterLineDecoder("mock-pool-b", decodeTestLine)
+		t.Cleanup(func() {
+			output.UnregisterLineDecoder("mock-pool-a")
+			output.UnregisterLineDecoder("mock-pool-b")
+		})
+
+		result, err := StreamPrompt(context.Background(), &PromptRequest{
+			Backend: "mock-pool",
+			Prompt:  "hello",
+		}, nil, nil, true, nil)
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("unexpected failure: %+v, %v", result, err)
+		}
+		if result.Backend != "mock-pool-a" && result.Backend != "mock-pool-b" {
+			t.Errorf("expected a pool member to answer, got %s", result.Backend)
+		}
+	})
+
+	t.Run("listed with members", func(t *testing.T) {
+		var pool *BackendInfo
+		var member *BackendInfo
+		for _, info := range NewExecutor().ListBackends(context.Background()) {
+			switch info.Name {
+			case "mock-pool":
+				pool = &info
+			case "mock-pool-a":
+				member = &info
+			}
+		}
+		if pool == nil || pool.Pool == nil || !pool.Available || len(pool.Pool.Members) != 3 || pool.Pool.Strategy != "round_robin" {
+			t.Fatalf("unexpected pool info: %+v", pool)
+		}
+		if pool.Pool.Members[1].Available {
+			t.Error("expected mock-pool-down to be reported unavailable")
+		}
+		if member == nil || len(member.Pools) != 1 || member.Pools[0] != "mock-pool" {
+			t.Errorf("expected mock-pool-a to list its pool, got %+v", member)
+		}
+	})
+}
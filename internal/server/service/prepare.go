@@ -1,11 +1,13 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/signalridge/clinvoker/internal/backend"
 	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/session"
 	"github.com/signalridge/clinvoker/internal/util"
 )
 
@@ -26,12 +28,19 @@ func validateOutputFormat(format string) error {
 	return nil
 }
 
+// ErrRequestMCPServers rejects a request declaring MCP servers while
+// server.allow_request_mcp_servers is off. Command servers run on the host,
+// so only the configured mcp_servers are attached by default.
+var ErrRequestMCPServers = errors.New("mcp_servers in requests are disabled; declare them in the mcp_servers config or set server.allow_request_mcp_servers")
+
 type preparedPrompt struct {
 	backend backend.Backend
 	model   string
 	opts    *backend.UnifiedOptions
 	// requestedFormat captures the requested output format (after config defaults).
 	requestedFormat backend.OutputFormat
+	// warnings lists requested options the backend will ignore.
+	warnings []string
 }
 
 func preparePrompt(req *PromptRequest, forceStateless bool) (*preparedPrompt, error) {
@@ -66,6 +75,10 @@ func preparePrompt(req *PromptRequest, forceStateless bool) (*preparedPrompt, er
 	}
 
 	cfg := config.Get()
+	if len(req.MCPServers) > 0 && !cfg.Server.AllowRequestMCPServers {
+		return nil, ErrRequestMCPServers
+	}
+
 	model := req.Model
 	if model == "" {
 		if bcfg, ok := cfg.Backends[req.Backend]; ok {
@@ -87,13 +100,29 @@ func preparePrompt(req *PromptRequest, forceStateless bool) (*preparedPrompt, er
 		DryRun:       req.DryRun,
 		Ephemeral:    req.Ephemeral,
 		ExtraFlags:   req.Extra,
+		AllowedDirs:  req.AllowedDirs,
+		MCPServers:   req.MCPServers,
+	}
+
+	// Check only what the request asked for; config defaults are applied
+	// best-effort to every backend.
+	unsupported := backend.CheckOptions(b.Capabilities(), opts)
+	warnings, err := backend.ApplyUnsupportedPolicy(req.Backend, unsupported, cfg.UnifiedFlags.UnsupportedOptions)
+	if err != nil {
+		return nil, err
 	}
 
 	util.ApplyUnifiedDefaults(opts, cfg, cfg.UnifiedFlags.DryRun)
 	util.ApplyBackendDefaults(opts, req.Backend, cfg)
+	if err := backend.ValidateMCPServers(opts.MCPServers); err != nil {
+		return nil, err
+	}
 
 	if forceStateless {
 		opts.Ephemeral = true
+	} else if req.SessionID != "" {
+		// A continued session must outlive the turn.
+		opts.Ephemeral = false
 	}
 
 	return &preparedPrompt{
@@ -101,5 +130,29 @@ func preparePrompt(req *PromptRequest, forceStateless bool) (*preparedPrompt, er
 		model:           model,
 		opts:            opts,
 		requestedFormat: requestedFormat,
+		warnings:        warnings,
 	}, nil
 }
+
+// resumeSession loads the session a request continues. It returns nil when
+// the request starts a new conversation.
+func resumeSession(req *PromptRequest, b backend.Backend, store *session.Store) (*session.Session, error) {
+	if req.SessionID == "" {
+		return nil, nil
+	}
+	if store == nil {
+		return nil, fmt.Errorf("session %s cannot be continued without session persistence", req.SessionID)
+	}
+
+	sess, err := store.Get(req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if sess.Backend != req.Backend {
+		return nil, fmt.Errorf("session %s belongs to backend %q, not %q", sess.ID, sess.Backend, req.Backend)
+	}
+	if sess.BackendSessionID != "" && !b.Capabilities().Resume {
+		return nil, fmt.Errorf("backend %q cannot resume sessions", req.Backend)
+	}
+	return sess, nil
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/signalridge/clinvoker/internal/backend"
@@ -58,3 +59,102 @@ func TestPreparePrompt_ExplicitOutputFormatWins(t *testing.T) {
 		t.Errorf("requestedFormat = %q, want %q", prep.requestedFormat, backend.OutputText)
 	}
 }
+
+func TestPreparePrompt_UnsupportedOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		wantErr      bool
+		wantWarnings int
+	}{
+		{name: "default policy warns", policy: "", wantWarnings: 2},
+		{name: "warn", policy: "warn", wantWarnings: 2},
+		{name: "ignore", policy: "ignore"},
+		{name: "error", policy: "error", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Reset()
+			t.Cleanup(config.Reset)
+			if err := config.Init(""); err != nil {
+				t.Fatalf("config init failed: %v", err)
+			}
+			config.Get().UnifiedFlags.UnsupportedOptions = tt.policy
+
+			mockBackend := mock.NewMockBackend("mock-caps", mock.WithCapabilities(backend.Capabilities{
+				SystemPrompt: true,
+			}))
+			t.Cleanup(mock.WithMockBackend(t, mockBackend))
+
+			prep, err := preparePrompt(&PromptRequest{
+				Backend:      "mock-caps",
+				Prompt:       "test",
+				SystemPrompt: "be brief",
+				MaxTokens:    100,
+				SandboxMode:  "read-only",
+			}, false)
+			if tt.wantErr {
+				var unsupported *backend.UnsupportedOptionsError
+				if !errors.As(err, &unsupported) || len(unsupported.Options) != 2 {
+					t.Fatalf("expected UnsupportedOptionsError with 2 options, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("preparePrompt failed: %v", err)
+			}
+			if len(prep.warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %v, want %d", prep.warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestPreparePrompt_MCPServers(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+	config.Get().MCPServers = map[string]config.MCPServerConfig{
+		"github": {Command: "github-mcp"},
+	}
+
+	mockBackend := mock.NewMockBackend("mock-mcp", mock.WithAvailable(true), mock.WithCapabilities(backend.Capabilities{
+		MCPServers: true,
+	}))
+	t.Cleanup(mock.WithMockBackend(t, mockBackend))
+
+	// Requests may not declare servers, which could run any command, by default
+	_, err := preparePrompt(&PromptRequest{
+		Backend:    "mock-mcp",
+		Prompt:     "test",
+		MCPServers: map[string]backend.MCPServer{"shell": {Command: "sh", Args: []string{"-c", "id"}}},
+	}, false)
+	if !errors.Is(err, ErrRequestMCPServers) {
+		t.Fatalf("error = %v, want %v", err, ErrRequestMCPServers)
+	}
+
+	config.Get().Server.AllowRequestMCPServers = true
+	prep, err := preparePrompt(&PromptRequest{
+		Backend:    "mock-mcp",
+		Prompt:     "test",
+		MCPServers: map[string]backend.MCPServer{"docs": {URL: "https://docs.example.com/mcp"}},
+	}, false)
+	if err != nil {
+		t.Fatalf("preparePrompt failed: %v", err)
+	}
+	if len(prep.opts.MCPServers) != 2 || len(prep.warnings) != 0 {
+		t.Errorf("MCPServers = %v, warnings = %v; want both servers and no warnings", prep.opts.MCPServers, prep.warnings)
+	}
+
+	_, err = preparePrompt(&PromptRequest{
+		Backend:    "mock-mcp",
+		Prompt:     "test",
+		MCPServers: map[string]backend.MCPServer{"docs": {URL: "ftp://docs.example.com"}},
+	}, false)
+	if err == nil {
+		t.Error("expected an invalid MCP server to be rejected")
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/output"
+	"github.com/signalridge/clinvoker/internal/session"
+	"github.com/signalridge/clinvoker/internal/util"
+)
+
+// flakyCommand fails the first failures runs printing failLine to stdout and
+// stderr, then echoes the prompt.
+func flakyCommand(failures int32, failLine string) func(string, *backend.UnifiedOptions) *exec.Cmd {
+	var runs atomic.Int32
+	return func(prompt string, _ *backend.UnifiedOptions) *exec.Cmd {
+		if runs.Add(1) <= failures {
+			return exec.Command("sh", "-c", "echo '"+failLine+"'; echo '"+failLine+"' >&2; exit 1")
+		}
+		return exec.Command("echo", prompt)
+	}
+}
+
+func fastRetry(maxAttempts int) *util.RetryOptions {
+	return &util.RetryOptions{MaxAttempts: maxAttempts, InitialBackoffMS: 1, MaxBackoffMS: 1}
+}
+
+func TestExecutePrompt_Retry(t *testing.T) {
+	initFallbackConfig(t)
+
+	t.Run("retries transient failure", func(t *testing.T) {
+		b := mock.NewMockBackend("mock-retry-flaky",
+			mock.WithAvailable(true),
+			mock.WithSeparateStderr(true),
+			mock.WithCommandFunc(flakyCommand(2, "API Error: 429 Too Many Requests")),
+		)
+		t.Cleanup(mock.WithMockBackend(t, b))
+
+		result, _ := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend: "mock-retry-flaky",
+			Prompt:  "hello",
+			Retry:   fastRetry(3),
+		})
+		if result.ExitCode != 0 {
+			t.Fatalf("expected success after retries, got exit=%d error=%q", result.ExitCode, result.Error)
+		}
+		if len(result.Attempts) != 3 || result.Attempts[0].ErrorCode != string(apperrors.ErrCodeRateLimited) {
+			t.Errorf("unexpected attempts: %+v", result.Attempts)
+		}
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		b := mock.NewMockBackend("mock-retry-down",
+			mock.WithAvailable(true),
+			mock.WithSeparateStderr(true),
+			mock.WithCommandFunc(flakyCommand(5, "Error: read ECONNRESET")),
+		)
+		t.Cleanup(mock.WithMockBackend(t, b))
+
+		result, _ := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend: "mock-retry-down",
+			Prompt:  "hello",
+			Retry:   fastRetry(2),
+		})
+		if result.ExitCode == 0 || len(result.Attempts) != 2 {
+			t.Errorf("expected two failed attempts, got exit=%d attempts=%+v", result.ExitCode, result.Attempts)
+		}
+	})
+
+	t.Run("does not retry context overflow", func(t *testing.T) {
+		b := mock.NewMockBackend("mock-retry-overflow",
+			mock.WithAvailable(true),
+			mock.WithSeparateStderr(true),
+			mock.WithCommandFunc(flakyCommand(5, "prompt is too long")),
+		)
+		t.Cleanup(mock.WithMockBackend(t, b))
+
+		result, _ := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend: "mock-retry-overflow",
+			Prompt:  "hello",
+			Retry:   fastRetry(3),
+		})
+		if len(result.Attempts) != 1 || result.Attempts[0].ErrorCode != string(apperrors.ErrCodeContextOverflow) {
+			t.Errorf("expected one context_overflow attempt, got %+v", result.Attempts)
+		}
+	})
+
+	t.Run("config policy", func(t *testing.T) {
+		b := mock.NewMockBackend("mock-retry-config",
+			mock.WithAvailable(true),
+			mock.WithSeparateStderr(true),
+			mock.WithCommandFunc(flakyCommand(1, "request timed out")),
+		)
+		t.Cleanup(mock.WithMockBackend(t, b))
+		config.Get().Retry = config.RetryConfig{MaxAttempts: 2, InitialBackoffMS: 1, MaxBackoffMS: 1}
+		t.Cleanup(func() { config.Get().Retry = config.RetryConfig{} })
+
+		result, _ := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend: "mock-retry-config",
+			Prompt:  "hello",
+		})
+		if result.ExitCode != 0 || len(result.Attempts) != 2 {
+			t.Errorf("expected success on second attempt, got exit=%d attempts=%+v", result.ExitCode, result.Attempts)
+		}
+	})
+}
+
+func TestExecutePrompt_RetryCanceled(t *testing.T) {
+	initFallbackConfig(t)
+
+	b := mock.NewMockBackend("mock-retry-canceled",
+		mock.WithAvailable(true),
+		mock.WithSeparateStderr(true),
+		mock.WithCommandFunc(flakyCommand(5, "429 Too Many Requests")),
+	)
+	t.Cleanup(mock.WithMockBackend(t, b))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, _ := NewStatelessRunner(nil).ExecutePrompt(ctx, &PromptRequest{
+		Backend: "mock-retry-canceled",
+		Prompt:  "hello",
+		Retry:   &util.RetryOptions{MaxAttempts: 3, InitialBackoffMS: 60000, MaxBackoffMS: 60000},
+	})
+	if len(result.Attempts) != 1 {
+		t.Errorf("expected no retries after cancellation, got %+v", result.Attempts)
+	}
+}
+
+func TestStreamPrompt_Retry(t *testing.T) {
+	initFallbackConfig(t)
+
+	b := mock.NewMockBackend("mock-retry-stream",
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(flakyCommand(1, "error:rate limit exceeded")),
+	)
+	t.Cleanup(mock.WithMockBackend(t, b))
+	output.RegisterLineDecoder("mock-retry-stream", decodeTestLine)
+	t.Cleanup(func() { output.UnregisterLineDecoder("mock-retry-stream") })
+
+	var events []*output.UnifiedEvent
+	result, err := StreamPrompt(context.Background(), &PromptRequest{
+		Backend: "mock-retry-stream",
+		Prompt:  "hello",
+		Retry:   fastRetry(2),
+	}, nil, nil, true, func(event *output.UnifiedEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 || len(result.Attempts) != 2 {
+		t.Fatalf("expected success on second attempt, got %+v", result)
+	}
+	for _, event := range events {
+		if event.Type == output.EventError {
+			t.Error("expected the error event of the failed attempt to be dropped")
+		}
+	}
+}
+
+func TestExecutePrompt_RetryKeepsOneSession(t *testing.T) {
+	initFallbackConfig(t)
+
+	b := mock.NewMockBackend("mock-retry-session",
+		mock.WithAvailable(true),
+		mock.WithSeparateStderr(true),
+		mock.WithCommandFunc(flakyCommand(2, "API Error: 429 Too Many Requests")),
+	)
+	t.Cleanup(mock.WithMockBackend(t, b))
+
+	store := session.NewStoreWithDir(t.TempDir())
+	result, _ := NewStatefulRunner(store, nil).ExecutePrompt(context.Background(), &PromptRequest{
+		Backend: "mock-retry-session",
+		Prompt:  "hello",
+		Retry:   fastRetry(3),
+	})
+	if result.ExitCode != 0 || len(result.Attempts) != 3 {
+		t.Fatalf("expected success on third attempt, got exit=%d attempts=%+v", result.ExitCode, result.Attempts)
+	}
+
+	sessions, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != result.SessionID {
+		t.Errorf("expected only the session of the last attempt, got %d sessions", len(sessions))
+	}
+}
+
+func TestStreamPrompt_RetryKeepsOneSession(t *testing.T) {
+	initFallbackConfig(t)
+
+	b := mock.NewMockBackend("mock-retry-stream-session",
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(flakyCommand(1, "error:rate limit exceeded")),
+	)
+	t.Cleanup(mock.WithMockBackend(t, b))
+	output.RegisterLineDecoder("mock-retry-stream-session", decodeTestLine)
+	t.Cleanup(func() { output.UnregisterLineDecoder("mock-retry-stream-session") })
+
+	store := session.NewStoreWithDir(t.TempDir())
+	result, err := StreamPrompt(context.Background(), &PromptRequest{
+		Backend: "mock-retry-stream-session",
+		Prompt:  "hello",
+		Retry:   fastRetry(2),
+	}, store, nil, false, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sessions, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != result.SessionID {
+		t.Errorf("expected only the session of the last attempt, got %d sessions", len(sessions))
+	}
+}
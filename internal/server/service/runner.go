@@ -7,7 +7,9 @@ import (
 
 	"github.com/signalridge/clinvoker/internal/backend"
 	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
 	"github.com/signalridge/clinvoker/internal/metrics"
+	"github.com/signalridge/clinvoker/internal/resilience"
 	"github.com/signalridge/clinvoker/internal/server/core"
 	"github.com/signalridge/clinvoker/internal/session"
 	"github.com/signalridge/clinvoker/internal/util"
@@ -59,7 +61,102 @@ func (r *StatelessRunner) ExecutePrompt(ctx context.Context, req *PromptRequest)
 	return result, err
 }
 
+// executePrompt runs the request on its backend, retrying transient failures
+// per the retry policy and then moving down the fallback chain while attempts
+// fail with errors another backend may not share. The result reports the
+// backend that answered. The error is set only when the server's concurrency
+// limits turned the request away.
 func executePrompt(ctx context.Context, req *PromptRequest, store *session.Store, logger *slog.Logger, forceStateless bool) (*PromptResult, error) {
+	if req.JSONSchema != nil {
+		return executeStructured(ctx, req, store, logger, forceStateless)
+	}
+
+	chain := fallbackChain(req)
+	policy := util.ResolveRetryPolicy(req.Retry, config.Get())
+	if len(chain) == 1 && !policy.Enabled() && !util.IsPool(req.Backend) {
+		result, err := executeAttempt(ctx, req, store, logger, forceStateless)
+		return result, concurrencyRejection(err)
+	}
+
+	start := time.Now()
+	var result *PromptResult
+	var rejection error
+	var attempts []util.FallbackAttempt
+	for i, name := range chain {
+		var fallback bool
+		result, fallback, rejection = executeWithRetry(ctx, fallbackRequest(req, name, i), policy, &attempts, store, logger, forceStateless)
+
+		if !fallback || ctx.Err() != nil || i == len(chain)-1 {
+			break
+		}
+		discardAttemptSession(req, result.SessionID, store, logger)
+		logger.Warn("backend failed, falling back", "backend", name, "error_code", attempts[len(attempts)-1].ErrorCode, "next", chain[i+1])
+	}
+
+	result.Attempts = attempts
+	result.DurationMS = time.Since(start).Milliseconds()
+	return result, rejection
+}
+
+// executeWithRetry runs the request on req.Backend, running it again after a
+// backoff while it fails with a retryable error and attempts remain. For a
+// backend pool each run goes to a member picked for it. Every run is appended
+// to attempts. It reports whether the last failure may be fixed by another
+// backend, and returns the error when the server's concurrency limits turned
+// the request away.
+func executeWithRetry(ctx context.Context, req *PromptRequest, policy resilience.RetryPolicy, attempts *[]util.FallbackAttempt, store *session.Store, logger *slog.Logger, forceStateless bool) (*PromptResult, bool, error) {
+	for try := 1; ; try++ {
+		runReq, lease, prepErr := leasePoolMember(ctx, req)
+		var result *PromptResult
+		if prepErr != nil {
+			result = &PromptResult{Backend: req.Backend, ExitCode: 1, Error: prepErr.Error()}
+		} else {
+			result, prepErr = executeAttempt(ctx, runReq, store, logger, forceStateless)
+			lease.Release()
+		}
+
+		attempt := util.FallbackAttempt{
+			Backend:    runReq.Backend,
+			ExitCode:   result.ExitCode,
+			Error:      result.Error,
+			DurationMS: result.DurationMS,
+		}
+		var code apperrors.ErrorCode
+		fallback := false
+		if prepErr != nil {
+			code, fallback = classifyPrepareError(runReq.Backend, prepErr)
+		} else if result.ExitCode != 0 {
+			code, fallback = classifyRunFailure(result.ExitCode, result.Error)
+		}
+		attempt.ErrorCode = string(code)
+		*attempts = append(*attempts, attempt)
+
+		if prepErr != nil || !apperrors.IsRetryable(code) || try >= policy.MaxAttempts || ctx.Err() != nil {
+			return result, fallback, concurrencyRejection(prepErr)
+		}
+		logger.Warn("backend failed, retrying", "backend", runReq.Backend, "error_code", code, "attempt", try)
+		if policy.Wait(ctx, try) != nil {
+			return result, fallback, nil
+		}
+		discardAttemptSession(req, result.SessionID, store, logger)
+	}
+}
+
+// discardAttemptSession deletes the session a failed attempt created, so a
+// request run again on another attempt leaves only the session of the run
+// it ends with. A session the request continues is kept.
+func discardAttemptSession(req *PromptRequest, sessionID string, store *session.Store, logger *slog.Logger) {
+	if store == nil || sessionID == "" || req.SessionID != "" {
+		return
+	}
+	if err := store.Delete(sessionID); err != nil {
+		logger.Warn("failed to delete session of failed attempt", "session_id", sessionID, "error", err)
+	}
+}
+
+// executeAttempt runs the request on req.Backend. The returned error is the
+// preparation failure, if any; it is also reported in the result.
+func executeAttempt(ctx context.Context, req *PromptRequest, store *session.Store, logger *slog.Logger, forceStateless bool) (*PromptResult, error) {
 	start := time.Now()
 	result := &PromptResult{
 		Backend: req.Backend,
@@ -70,16 +167,55 @@ func executePrompt(ctx context.Context, req *PromptRequest, store *session.Store
 		result.Error = err.Error()
 		result.ExitCode = 1
 		result.DurationMS = time.Since(start).Milliseconds()
-		return result, nil
+		return result, err
 	}
 
 	b := prep.backend
 	model := prep.model
 	opts := prep.opts
+	result.Warnings = prep.warnings
+	for _, w := range prep.warnings {
+		logger.Warn("unsupported option", "backend", req.Backend, "warning", w)
+	}
 
-	// Create session (skip if ephemeral or no store)
-	var sess *session.Session
-	if store != nil && !opts.Ephemeral {
+	sess, err := resumeSession(req, b, store)
+	if err != nil {
+		result.Error = err.Error()
+		result.ExitCode = 1
+		result.DurationMS = time.Since(start).Milliseconds()
+		return result, err
+	}
+
+	// Wait for a process slot, then fail fast while the backend's circuit
+	// is open
+	var call *util.BackendCall
+	if !opts.DryRun {
+		release, slotErr := util.AcquireProcessSlot(ctx, req.Backend)
+		if slotErr != nil {
+			result.Error = slotErr.Error()
+			result.ExitCode = 1
+			result.DurationMS = time.Since(start).Milliseconds()
+			return result, slotErr
+		}
+		defer release()
+
+		call, err = util.StartBackendCall(req.Backend)
+		if err != nil {
+			result.Error = err.Error()
+			result.ExitCode = 1
+			result.DurationMS = time.Since(start).Milliseconds()
+			return result, err
+		}
+	}
+
+	// Continue the requested session, or create one (skip if ephemeral or
+	// no store)
+	var resumeID string
+	if sess != nil {
+		result.SessionID = sess.ID
+		resumeID = sess.BackendSessionID
+		sess.MarkUsed()
+	} else if store != nil && !opts.Ephemeral {
 		cfg := config.Get()
 		tags := append([]string{}, cfg.Session.DefaultTags...)
 		tags = append(tags, "api")
@@ -109,6 +245,7 @@ func executePrompt(ctx context.Context, req *PromptRequest, store *session.Store
 		Prompt:          req.Prompt,
 		Options:         opts,
 		RequestedFormat: prep.requestedFormat,
+		ResumeSessionID: resumeID,
 	})
 
 	// Record backend execution metrics if enabled
@@ -122,6 +259,15 @@ func executePrompt(ctx context.Context, req *PromptRequest, store *session.Store
 		metrics.RecordBackendExecutionDuration(req.Backend, execDuration)
 	}
 
+	switch {
+	case ctx.Err() != nil:
+		call.Cancel()
+	case execErr != nil:
+		call.Done(1, execErr.Error())
+	default:
+		call.Done(coreRes.ExitCode, coreRes.Error)
+	}
+
 	if execErr != nil {
 		result.Error = execErr.Error()
 		result.ExitCode = 1
@@ -152,6 +298,17 @@ func executePrompt(ctx context.Context, req *PromptRequest, store *session.Store
 		if err := store.Save(sess); err != nil && logger != nil {
 			logger.Warn("failed to save session", "session_id", sess.ID, "error", err)
 		}
+		if err := util.RecordTurn(store, sess, &session.Turn{
+			StartedAt:   start,
+			CompletedAt: time.Now(),
+			Prompt:      req.Prompt,
+			Response:    result.Output,
+			TokenUsage:  result.TokenUsage,
+			ExitCode:    result.ExitCode,
+			Error:       result.Error,
+		}); err != nil {
+			logger.Warn("failed to record transcript", "session_id", sess.ID, "error", err)
+		}
 	}
 
 	// Cleanup backend session for ephemeral requests
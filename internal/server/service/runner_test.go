@@ -3,11 +3,15 @@ package service
 import (
 	"context"
 	"os/exec"
+	"strings"
 	"testing"
 
 	"github.com/signalridge/clinvoker/internal/backend"
 	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
 	"github.com/signalridge/clinvoker/internal/mock"
+	"github.com/signalridge/clinvoker/internal/session"
+	"github.com/signalridge/clinvoker/internal/util"
 )
 
 func TestNewStatefulRunner(t *testing.T) {
@@ -234,3 +238,164 @@ func TestPromptRunner_Interface(t *testing.T) {
 	var _ PromptRunner = (*StatefulRunner)(nil)
 	var _ PromptRunner = (*StatelessRunner)(nil)
 }
+
+func TestExecutePrompt_CircuitBreaker(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+	config.Get().CircuitBreaker.FailureThreshold = 2
+
+	failing := mock.NewMockBackend("mock-cb-failing",
+		mock.WithAvailable(true),
+		mock.WithJSONResponse(&backend.UnifiedResponse{Error: "backend crashed"}),
+	)
+	healthy := mock.NewMockBackend("mock-cb-healthy", mock.WithAvailable(true))
+	t.Cleanup(mock.WithMockBackend(t, failing))
+	t.Cleanup(mock.WithMockBackend(t, healthy))
+	t.Cleanup(func() { util.ResetBackendBreakers() })
+
+	runner := NewStatelessRunner(nil)
+	req := &PromptRequest{Backend: "mock-cb-failing", Prompt: "hello"}
+	for i := 0; i < 2; i++ {
+		result, _ := runner.ExecutePrompt(context.Background(), req)
+		if result.ExitCode == 0 {
+			t.Fatalf("call %d: expected failure", i)
+		}
+	}
+
+	// The circuit is open: the backend is no longer called.
+	result, _ := runner.ExecutePrompt(context.Background(), req)
+	if result.ExitCode == 0 || !strings.Contains(result.Error, string(apperrors.ErrCodeBackendUnavailable)) {
+		t.Errorf("expected fail-fast backend_unavailable error, got %+v", result)
+	}
+
+	// Fallback skips the open circuit.
+	result, _ = runner.ExecutePrompt(context.Background(), &PromptRequest{
+		Backend:  "mock-cb-failing",
+		Prompt:   "hello",
+		Fallback: []string{"mock-cb-healthy"},
+	})
+	if result.Backend != "mock-cb-healthy" || len(result.Attempts) != 2 ||
+		result.Attempts[0].ErrorCode != string(apperrors.ErrCodeBackendUnavailable) {
+		t.Errorf("expected fallback past the open circuit, got %+v", result)
+	}
+
+	// Dry runs bypass the breaker.
+	result, _ = runner.ExecutePrompt(context.Background(), &PromptRequest{Backend: "mock-cb-failing", Prompt: "hello", DryRun: true})
+	if result.ExitCode != 0 {
+		t.Errorf("expected dry run to bypass the breaker, got %+v", result)
+	}
+}
+
+func TestStatefulRunner_ExecutePrompt_ContinuesSession(t *testing.T) {
+	initFallbackConfig(t)
+
+	resumable := mock.NewMockBackend("mock-resume",
+		mock.WithAvailable(true),
+		mock.WithCapabilities(backend.Capabilities{Resume: true}),
+	)
+	t.Cleanup(mock.WithMockBackend(t, resumable))
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-no-resume", mock.WithAvailable(true))))
+
+	store := session.NewStoreWithDir(t.TempDir())
+	newSession := func(backendName, backendSessionID string) *session.Session {
+		sess, err := store.Create(backendName, t.TempDir())
+		if err != nil {
+			t.Fatalf("Create() error: %v", err)
+		}
+		sess.BackendSessionID = backendSessionID
+		if err := store.Save(sess); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+		return sess
+	}
+
+	t.Run("resumes backend session", func(t *testing.T) {
+		sess := newSession("mock-resume", "backend-123")
+
+		result, err := NewStatefulRunner(store, nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend:   "mock-resume",
+			Prompt:    "hello",
+			SessionID: sess.ID,
+		})
+		if err != nil {
+			t.Fatalf("ExecutePrompt() error: %v", err)
+		}
+		if result.SessionID != sess.ID {
+			t.Errorf("SessionID = %q, want %q", result.SessionID, sess.ID)
+		}
+		if !strings.Contains(result.Output, "resume backend-123 hello") {
+			t.Errorf("expected a resume command, got output %q", result.Output)
+		}
+
+		updated, err := store.Get(sess.ID)
+		if err != nil {
+			t.Fatalf("Get() error: %v", err)
+		}
+		if updated.TurnCount != sess.TurnCount+1 {
+			t.Errorf("TurnCount = %d, want %d", updated.TurnCount, sess.TurnCount+1)
+		}
+
+		turns, err := store.Transcript(sess.ID)
+		if err != nil {
+			t.Fatalf("Transcript() error: %v", err)
+		}
+		if len(turns) != 1 || turns[0].Turn != updated.TurnCount || turns[0].Prompt != "hello" || turns[0].Response != result.Output {
+			t.Errorf("transcript = %+v, want the turn just run", turns)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		backend string
+		session func() string
+		store   *session.Store
+		wantErr string
+	}{
+		{
+			name:    "unknown session",
+			backend: "mock-resume",
+			session: func() string { return "missing" },
+			store:   store,
+			wantErr: "not found",
+		},
+		{
+			name:    "other backend",
+			backend: "mock-no-resume",
+			session: func() string { return newSession("mock-resume", "").ID },
+			store:   store,
+			wantErr: "belongs to backend",
+		},
+		{
+			name:    "backend cannot resume",
+			backend: "mock-no-resume",
+			session: func() string { return newSession("mock-no-resume", "backend-456").ID },
+			store:   store,
+			wantErr: "cannot resume",
+		},
+		{
+			name:    "no session store",
+			backend: "mock-resume",
+			session: func() string { return newSession("mock-resume", "").ID },
+			wantErr: "without session persistence",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewStatefulRunner(tt.store, nil).ExecutePrompt(context.Background(), &PromptRequest{
+				Backend:   tt.backend,
+				Prompt:    "hello",
+				SessionID: tt.session(),
+			})
+			if err != nil {
+				t.Fatalf("ExecutePrompt() error: %v", err)
+			}
+			if result.ExitCode == 0 || !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("exit=%d error=%q, want it to contain %q", result.ExitCode, result.Error, tt.wantErr)
+			}
+		})
+	}
+}
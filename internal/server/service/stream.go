@@ -13,6 +13,7 @@ import (
 
 	"github.com/signalridge/clinvoker/internal/backend"
 	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
 	"github.com/signalridge/clinvoker/internal/metrics"
 	"github.com/signalridge/clinvoker/internal/output"
 	"github.com/signalridge/clinvoker/internal/session"
@@ -23,10 +24,17 @@ const maxStreamLine = 10 * 1024 * 1024
 
 // StreamResult represents the result of a streaming prompt execution.
 type StreamResult struct {
+	// SessionID is the clinvk session the turn was recorded on, if any.
+	SessionID string
+	// Backend is the backend that answered.
+	Backend          string
 	ExitCode         int
 	Error            string
 	TokenUsage       *session.TokenUsage
 	BackendSessionID string
+	// Attempts lists every backend run when retries or a fallback chain
+	// are in effect.
+	Attempts []util.FallbackAttempt
 }
 
 type streamScanResult struct {
@@ -34,15 +42,113 @@ type streamScanResult struct {
 	tokenUsage       *session.TokenUsage
 	handlerErr       error
 	streamErr        error
+	turn             util.TurnRecorder
 }
 
 // StreamPrompt executes a prompt and emits unified events as they stream.
 // If store is provided and the request is not ephemeral, it persists a session.
+// With retries or a fallback chain, a backend that fails before streaming any
+// content is run again or abandoned for the next one, and the events of the
+// failed run are not emitted. A backend pool streams from a member picked
+// for each run. A request turned away by the server's concurrency limits
+// returns a nil result and the rejection, before any event is emitted.
 func StreamPrompt(ctx context.Context, req *PromptRequest, store *session.Store, logger *slog.Logger, forceStateless bool, onEvent func(*output.UnifiedEvent) error) (*StreamResult, error) {
 	if logger == nil {
 		logger = slog.Default()
 	}
 
+	chain := fallbackChain(req)
+	policy := util.ResolveRetryPolicy(req.Retry, config.Get())
+	if len(chain) == 1 && !policy.Enabled() && !util.IsPool(req.Backend) {
+		return streamAttempt(ctx, req, store, logger, forceStateless, onEvent)
+	}
+
+	var attempts []util.FallbackAttempt
+backends:
+	for i, name := range chain {
+		attemptReq := fallbackRequest(req, name, i)
+		for try := 1; ; try++ {
+			start := time.Now()
+			emitter := &heldEmitter{onEvent: onEvent}
+			runReq, lease, err := leasePoolMember(ctx, attemptReq)
+			var result *StreamResult
+			if err == nil {
+				result, err = streamAttempt(ctx, runReq, store, logger, forceStateless, emitter.emit)
+				lease.Release()
+			}
+			attempt, fallback := streamAttemptOutcome(runReq.Backend, result, err, time.Since(start))
+			attempts = append(attempts, attempt)
+
+			code := apperrors.ErrorCode(attempt.ErrorCode)
+			if !emitter.committed && ctx.Err() == nil && apperrors.IsRetryable(code) && try < policy.MaxAttempts {
+				logger.Warn("backend failed, retrying", "backend", runReq.Backend, "error_code", code, "attempt", try)
+				if policy.Wait(ctx, try) == nil {
+					discardStreamSession(attemptReq, result, store, logger)
+					continue
+				}
+			}
+			if fallback && !emitter.committed && ctx.Err() == nil && i < len(chain)-1 {
+				discardStreamSession(attemptReq, result, store, logger)
+				logger.Warn("backend failed, falling back", "backend", runReq.Backend, "error_code", code, "next", chain[i+1])
+				continue backends
+			}
+
+			if err == nil {
+				err = emitter.flush()
+			}
+			if result != nil {
+				result.Attempts = attempts
+				if err != nil && result.Error == "" {
+					result.Error = err.Error()
+				}
+			}
+			return result, err
+		}
+	}
+
+	// Unreachable: the last attempt always returns.
+	return nil, fmt.Errorf("no backend to run")
+}
+
+// discardStreamSession deletes the session a failed stream attempt created.
+func discardStreamSession(req *PromptRequest, result *StreamResult, store *session.Store, logger *slog.Logger) {
+	if result != nil {
+		discardAttemptSession(req, result.SessionID, store, logger)
+	}
+}
+
+// streamAttemptOutcome records a finished stream attempt and reports whether
+// another backend may succeed where it failed.
+func streamAttemptOutcome(backendName string, result *StreamResult, err error, duration time.Duration) (util.FallbackAttempt, bool) {
+	attempt := util.FallbackAttempt{
+		Backend:    backendName,
+		DurationMS: duration.Milliseconds(),
+	}
+	fallback := false
+	switch {
+	case result == nil:
+		// Preparation or process start failed.
+		var code apperrors.ErrorCode
+		code, fallback = classifyPrepareError(backendName, err)
+		attempt.ExitCode = 1
+		attempt.ErrorCode = string(code)
+		attempt.Error = err.Error()
+	case err != nil:
+		// The event handler failed; the client is gone.
+		attempt.ExitCode = result.ExitCode
+		attempt.Error = result.Error
+	case result.ExitCode != 0:
+		var code apperrors.ErrorCode
+		code, fallback = classifyRunFailure(result.ExitCode, result.Error)
+		attempt.ExitCode = result.ExitCode
+		attempt.ErrorCode = string(code)
+		attempt.Error = result.Error
+	}
+	return attempt, fallback
+}
+
+// streamAttempt streams the request from req.Backend.
+func streamAttempt(ctx context.Context, req *PromptRequest, store *session.Store, logger *slog.Logger, forceStateless bool, onEvent func(*output.UnifiedEvent) error) (*StreamResult, error) {
 	start := time.Now()
 
 	prep, err := preparePrompt(req, forceStateless)
@@ -50,14 +156,40 @@ func StreamPrompt(ctx context.Context, req *PromptRequest, store *session.Store,
 		return nil, err
 	}
 
+	for _, w := range prep.warnings {
+		logger.Warn("unsupported option", "backend", req.Backend, "warning", w)
+	}
+
+	sess, err := resumeSession(req, prep.backend, store)
+	if err != nil {
+		return nil, err
+	}
+
 	// Copy options to avoid mutating caller's struct
 	opts := *prep.opts
 	opts.OutputFormat = backend.OutputStreamJSON
 
-	var sess *session.Session
-	sessionID := ""
+	// Wait for a process slot, then fail fast while the backend's circuit
+	// is open
+	var call *util.BackendCall
+	if !opts.DryRun {
+		release, err := util.AcquireProcessSlot(ctx, req.Backend)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		call, err = util.StartBackendCall(req.Backend)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	if store != nil && !opts.Ephemeral {
+	sessionID := ""
+	if sess != nil {
+		sessionID = sess.ID
+		sess.MarkUsed()
+	} else if store != nil && !opts.Ephemeral {
 		cfg := config.Get()
 		tags := append([]string{}, cfg.Session.DefaultTags...)
 		tags = append(tags, "api")
@@ -83,16 +215,21 @@ func StreamPrompt(ctx context.Context, req *PromptRequest, store *session.Store,
 		}
 	}
 
-	cmd := prep.backend.BuildCommandUnified(req.Prompt, &opts)
+	var cmd *exec.Cmd
+	if sess != nil && sess.BackendSessionID != "" {
+		cmd = prep.backend.ResumeCommandUnified(sess.BackendSessionID, req.Prompt, &opts)
+	} else {
+		cmd = prep.backend.BuildCommandUnified(req.Prompt, &opts)
+	}
 	cmd = util.CommandWithContext(ctx, cmd)
 
 	if opts.DryRun {
 		msg := fmt.Sprintf("Would execute: %s %v", cmd.Path, cmd.Args[1:])
 		if err := emitDryRunEvents(prep.backend.Name(), sessionID, msg, onEvent); err != nil {
-			return &StreamResult{ExitCode: 1, Error: err.Error()}, err
+			return &StreamResult{SessionID: sessionID, Backend: req.Backend, ExitCode: 1, Error: err.Error()}, err
 		}
 
-		result := &StreamResult{ExitCode: 0}
+		result := &StreamResult{SessionID: sessionID, Backend: req.Backend, ExitCode: 0}
 
 		// Record backend execution metrics if enabled
 		execDuration := time.Since(start).Seconds()
@@ -113,6 +250,7 @@ func StreamPrompt(ctx context.Context, req *PromptRequest, store *session.Store,
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
+		call.Cancel()
 		return nil, err
 	}
 
@@ -125,6 +263,7 @@ func StreamPrompt(ctx context.Context, req *PromptRequest, store *session.Store,
 	}
 
 	if err := cmd.Start(); err != nil {
+		call.Done(1, err.Error())
 		return nil, err
 	}
 
@@ -168,12 +307,16 @@ func StreamPrompt(ctx context.Context, req *PromptRequest, store *session.Store,
 	}
 
 	result := &StreamResult{
+		SessionID:        sessionID,
+		Backend:          req.Backend,
 		ExitCode:         exitCode,
 		TokenUsage:       tokenUsage,
 		BackendSessionID: backendSessionID,
 	}
 
 	if handlerErr != nil {
+		// The client is gone; this says nothing about the backend.
+		call.Cancel()
 		result.Error = handlerErr.Error()
 		if result.ExitCode == 0 {
 			result.ExitCode = 1
@@ -191,6 +334,12 @@ func StreamPrompt(ctx context.Context, req *PromptRequest, store *session.Store,
 		result.Error = stderrBuf.String()
 	}
 
+	if ctx.Err() != nil {
+		call.Cancel()
+	} else {
+		call.Done(result.ExitCode, result.Error)
+	}
+
 	if sess != nil {
 		if backendSessionID != "" {
 			sess.BackendSessionID = backendSessionID
@@ -210,6 +359,18 @@ func StreamPrompt(ctx context.Context, req *PromptRequest, store *session.Store,
 		if err := store.Save(sess); err != nil && logger != nil {
 			logger.Warn("failed to save session", "session_id", sess.ID, "error", err)
 		}
+		if err := util.RecordTurn(store, sess, &session.Turn{
+			StartedAt:   start,
+			CompletedAt: time.Now(),
+			Prompt:      req.Prompt,
+			Response:    scanResult.turn.Response(),
+			ToolCalls:   scanResult.turn.ToolCalls(),
+			TokenUsage:  tokenUsage,
+			ExitCode:    result.ExitCode,
+			Error:       result.Error,
+		}); err != nil {
+			logger.Warn("failed to record transcript", "session_id", sess.ID, "error", err)
+		}
 	}
 
 	return result, nil
@@ -255,6 +416,7 @@ func scanStreamOutput(logger *slog.Logger, backendName, sessionID string, reader
 		if event == nil {
 			continue
 		}
+		result.turn.Add(event)
 
 		switch event.Type {
 		case output.EventInit:
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/mock"
 	"github.com/signalridge/clinvoker/internal/output"
 	"github.com/signalridge/clinvoker/internal/session"
 )
@@ -169,3 +170,49 @@ func TestStreamPrompt_Constants(t *testing.T) {
 	// Actual testing of the limit would require a more complex setup with a mock backend
 	t.Logf("maxStreamLine constant is expected to be %d bytes (%d MB)", expectedMaxStreamLine, expectedMaxStreamLine/(1024*1024))
 }
+
+func TestStreamPrompt_ContinuesSession(t *testing.T) {
+	initFallbackConfig(t)
+
+	resumable := mock.NewMockBackend("mock-stream-resume",
+		mock.WithAvailable(true),
+		mock.WithCapabilities(backend.Capabilities{Resume: true}),
+	)
+	t.Cleanup(mock.WithMockBackend(t, resumable))
+
+	store := session.NewStoreWithDir(t.TempDir())
+	sess, err := store.Create("mock-stream-resume", t.TempDir())
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	sess.BackendSessionID = "backend-123"
+	if err := store.Save(sess); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	result, err := StreamPrompt(context.Background(), &PromptRequest{
+		Backend:   "mock-stream-resume",
+		Prompt:    "hello",
+		SessionID: sess.ID,
+	}, store, nil, false, nil)
+	if err != nil {
+		t.Fatalf("StreamPrompt() error: %v", err)
+	}
+	if result.SessionID != sess.ID {
+		t.Errorf("SessionID = %q, want %q", result.SessionID, sess.ID)
+	}
+
+	updated, err := store.Get(sess.ID)
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if updated.TurnCount != sess.TurnCount+1 {
+		t.Errorf("TurnCount = %d, want %d", updated.TurnCount, sess.TurnCount+1)
+	}
+	if turns, err := store.Transcript(sess.ID); err != nil || len(turns) != 1 || turns[0].Prompt != "hello" {
+		t.Errorf("transcript = %+v (err=%v), want the turn just run", turns, err)
+	}
+	if sessions, err := store.List(); err != nil || len(sessions) != 1 {
+		t.Errorf("expected no new session, got %d sessions (err=%v)", len(sessions), err)
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/signalridge/clinvoker/internal/session"
+	"github.com/signalridge/clinvoker/internal/structured"
+)
+
+// executeStructured runs req until its reply matches req.JSONSchema, asking
+// again up to req.JSONSchemaRetries times while it does not. The matching
+// JSON is returned in Structured, next to the raw output; a reply that never
+// matches fails the run.
+func executeStructured(ctx context.Context, req *PromptRequest, store *session.Store, logger *slog.Logger, forceStateless bool) (*PromptResult, error) {
+	schema, err := structured.CompileValue(req.JSONSchema)
+	if err != nil {
+		return &PromptResult{Backend: req.Backend, ExitCode: 1, Error: err.Error()}, nil
+	}
+
+	attempt := *req
+	attempt.JSONSchema = nil
+	attempt.Prompt = req.Prompt + "\n\n" + schema.Instructions()
+	for try := 0; ; try++ {
+		result, err := executePrompt(ctx, &attempt, store, logger, forceStateless)
+		if err != nil || result.ExitCode != 0 || result.Error != "" || req.DryRun {
+			return result, err
+		}
+
+		value, invalid := schema.Extract(result.Output)
+		if invalid == nil {
+			result.Structured = value
+			return result, nil
+		}
+		if try >= req.JSONSchemaRetries {
+			result.ExitCode = 1
+			result.Error = fmt.Sprintf("no reply matched the JSON schema after %d attempts: %v", try+1, invalid)
+			return result, nil
+		}
+		logger.Warn("reply does not match the JSON schema, asking again", "backend", result.Backend, "attempt", try+2, "error", invalid)
+
+		// A persisted session is continued with the correction alone;
+		// otherwise the prompt is sent again with the rejected reply.
+		if !forceStateless && !req.Ephemeral && result.SessionID != "" {
+			attempt.SessionID = result.SessionID
+			attempt.Backend = result.Backend
+			attempt.Prompt = schema.Correction(invalid)
+		} else {
+			attempt.Prompt = schema.RepairPrompt(req.Prompt, result.Output, invalid)
+		}
+	}
+}
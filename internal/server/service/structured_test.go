@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/mock"
+)
+
+func TestExecutePrompt_JSONSchema(t *testing.T) {
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	// The backend answers each prompt with the next of replies
+	var replies, prompts []string
+	t.Cleanup(mock.WithMockBackend(t, mock.NewMockBackend("mock-structured",
+		mock.WithAvailable(true),
+		mock.WithCommandFunc(func(prompt string, opts *backend.UnifiedOptions) *exec.Cmd {
+			prompts = append(prompts, prompt)
+			reply := replies[0]
+			replies = replies[1:]
+			return exec.Command("printf", "%s", reply)
+		}),
+	)))
+
+	schema := map[string]any{
+		"type":     "object",
+		"required": []string{"answer"},
+	}
+
+	tests := []struct {
+		name           string
+		replies        []string
+		retries        int
+		wantExitCode   int
+		wantStructured string
+		wantPrompts    int
+	}{
+		{name: "valid reply", replies: []string{`{"answer":42}`}, wantStructured: `{"answer":42}`, wantPrompts: 1},
+		{name: "retried", replies: []string{"forty-two", "```json\n{\"answer\":42}\n```"}, retries: 1, wantStructured: `{"answer":42}`, wantPrompts: 2},
+		{name: "retries exhausted", replies: []string{"forty-two", `{"result":42}`}, retries: 1, wantExitCode: 1, wantPrompts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replies, prompts = tt.replies, nil
+			result, err := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+				Backend:           "mock-structured",
+				Prompt:            "What is the answer?",
+				JSONSchema:        schema,
+				JSONSchemaRetries: tt.retries,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.ExitCode != tt.wantExitCode {
+				t.Errorf("exit code = %d, want %d (error %q)", result.ExitCode, tt.wantExitCode, result.Error)
+			}
+			if string(result.Structured) != tt.wantStructured {
+				t.Errorf("structured = %s, want %s", result.Structured, tt.wantStructured)
+			}
+			if len(prompts) != tt.wantPrompts {
+				t.Fatalf("prompts = %d, want %d", len(prompts), tt.wantPrompts)
+			}
+			if !strings.Contains(prompts[0], `"required":["answer"]`) {
+				t.Errorf("prompt = %q, want it to contain the schema", prompts[0])
+			}
+			if len(prompts) > 1 && !strings.Contains(prompts[1], "[Previous response: forty-two]") {
+				t.Errorf("retry prompt = %q, want it to contain the rejected reply", prompts[1])
+			}
+		})
+	}
+
+	t.Run("invalid schema", func(t *testing.T) {
+		result, err := NewStatelessRunner(nil).ExecutePrompt(context.Background(), &PromptRequest{
+			Backend:    "mock-structured",
+			Prompt:     "What is the answer?",
+			JSONSchema: map[string]any{"type": []string{"string", "null"}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ExitCode != 1 || result.Error == "" {
+			t.Errorf("result = %+v, want an error", result)
+		}
+	})
+}
@@ -556,9 +556,8 @@ func (s *Store) deleteLocked(id string) error {
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
 
-	// Also remove session artifacts directory if it exists
-	artifactsDir := filepath.Join(s.dir, id)
-	_ = os.RemoveAll(artifactsDir)
+	// Also remove session artifacts directory (transcript included) if it exists
+	_ = os.RemoveAll(s.artifactsDir(id))
 
 	return nil
 }
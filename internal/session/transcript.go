@@ -0,0 +1,288 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transcript file names. A session's transcript lives in its artifacts
+// directory as transcript.jsonl, one turn per line; rotated files are
+// transcript.1.jsonl (the newest) to transcript.N.jsonl (the oldest).
+const (
+	transcriptFileName = "transcript.jsonl"
+	transcriptPrefix   = "transcript."
+	transcriptSuffix   = ".jsonl"
+)
+
+// Transcript size defaults.
+const (
+	// DefaultTranscriptMaxBytes is the size at which a transcript file is rotated.
+	DefaultTranscriptMaxBytes = 10 * 1024 * 1024
+
+	// DefaultTranscriptMaxFiles is the number of rotated transcript files kept.
+	DefaultTranscriptMaxFiles = 3
+)
+
+// Turn is one exchange recorded in a session transcript.
+type Turn struct {
+	// Turn is the turn number within the session, starting at 1.
+	Turn int `json:"turn"`
+
+	// StartedAt is when the prompt was sent to the backend.
+	StartedAt time.Time `json:"started_at"`
+
+	// CompletedAt is when the backend finished.
+	CompletedAt time.Time `json:"completed_at"`
+
+	// Prompt is the prompt of the turn.
+	Prompt string `json:"prompt"`
+
+	// Response is the text the backend replied with.
+	Response string `json:"response,omitempty"`
+
+	// ToolCalls lists the tools the backend used, when its output was streamed.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// TokenUsage is the tokens the turn consumed.
+	TokenUsage *TokenUsage `json:"token_usage,omitempty"`
+
+	// ExitCode is the backend's exit code.
+	ExitCode int `json:"exit_code"`
+
+	// Error is why the turn failed.
+	Error string `json:"error,omitempty"`
+}
+
+// ToolCall is a tool the backend used during a turn.
+type ToolCall struct {
+	// ID identifies the call, when the backend reports one.
+	ID string `json:"id,omitempty"`
+
+	// Name is the tool name.
+	Name string `json:"name"`
+
+	// Input is the tool input.
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// Output is the tool result, truncated to keep transcripts small.
+	Output string `json:"output,omitempty"`
+
+	// IsError reports whether the tool failed.
+	IsError bool `json:"is_error,omitempty"`
+}
+
+// TranscriptLimits bounds the disk space of a transcript.
+type TranscriptLimits struct {
+	// MaxBytes is the size at which the transcript file is rotated (0 = never).
+	MaxBytes int64
+
+	// MaxFiles is the number of rotated files kept; older turns are dropped.
+	MaxFiles int
+}
+
+// AppendTurn appends a turn to the transcript of session id, rotating the
+// transcript first when the turn would grow it past limits.MaxBytes.
+func (s *Store) AppendTurn(id string, turn *Turn, limits TranscriptLimits) error {
+	if err := validateSessionID(id); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(turn)
+	if err != nil {
+		return fmt.Errorf("failed to marshal turn: %w", err)
+	}
+	line = append(line, '\n')
+
+	// Acquire cross-process lock for write operation
+	if err := s.fileLock.Lock(); err != nil {
+		return fmt.Errorf("failed to acquire store lock: %w", err)
+	}
+	defer func() {
+		_ = s.fileLock.Unlock()
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.sessionPath(id)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("session not found: %s", id)
+		}
+		return fmt.Errorf("failed to read session: %w", err)
+	}
+
+	dir := s.artifactsDir(id)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create session dir: %w", err)
+	}
+
+	path := filepath.Join(dir, transcriptFileName)
+	if limits.MaxBytes > 0 {
+		if info, err := os.Stat(path); err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > limits.MaxBytes {
+			if err := rotateTranscript(dir, limits.MaxFiles); err != nil {
+				return fmt.Errorf("failed to rotate transcript: %w", err)
+			}
+		}
+	}
+
+	// Use 0600 to protect potentially sensitive prompt data
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open transcript: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return f.Close()
+}
+
+// Transcript returns the turns recorded for session id, oldest first.
+// Turns dropped by rotation are missing, so the first turn returned may
+// not be turn 1.
+func (s *Store) Transcript(id string) ([]Turn, error) {
+	if err := validateSessionID(id); err != nil {
+		return nil, err
+	}
+
+	// Shared lock, so a rotation never runs between reading two files
+	if err := s.fileLock.LockShared(); err != nil {
+		return nil, fmt.Errorf("failed to acquire store lock: %w", err)
+	}
+	defer func() {
+		_ = s.fileLock.Unlock()
+	}()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := os.Stat(s.sessionPath(id)); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("session not found: %s", id)
+		}
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	dir := s.artifactsDir(id)
+	turns := []Turn{}
+	for _, name := range transcriptFiles(dir) {
+		var err error
+		turns, err = readTranscriptFile(filepath.Join(dir, name), turns)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return turns, nil
+}
+
+// artifactsDir returns the directory holding the files of session id
+// besides its metadata, which is removed along with the session.
+func (s *Store) artifactsDir(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// rotateTranscript shifts the transcript files in dir by one, dropping
+// those beyond keep, and leaves no current file.
+func rotateTranscript(dir string, keep int) error {
+	for _, name := range transcriptFiles(dir) {
+		n := rotatedIndex(name)
+		if n == 0 {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if n >= keep {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if err := os.Rename(path, filepath.Join(dir, rotatedName(n+1))); err != nil {
+			return err
+		}
+	}
+
+	current := filepath.Join(dir, transcriptFileName)
+	if keep <= 0 {
+		return os.Remove(current)
+	}
+	return os.Rename(current, filepath.Join(dir, rotatedName(1)))
+}
+
+// transcriptFiles lists the transcript files in dir, oldest first.
+func transcriptFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == transcriptFileName || rotatedIndex(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	// Higher rotation indexes are older; the current file is index 0
+	sort.Slice(names, func(i, j int) bool {
+		return rotatedIndex(names[i]) > rotatedIndex(names[j])
+	})
+	return names
+}
+
+// rotatedName returns the name of the nth rotated transcript file.
+func rotatedName(n int) string {
+	return transcriptPrefix + strconv.Itoa(n) + transcriptSuffix
+}
+
+// rotatedIndex returns n for the nth rotated transcript file, and 0 for
+// any other name.
+func rotatedIndex(name string) int {
+	rest, ok := strings.CutPrefix(name, transcriptPrefix)
+	if !ok {
+		return 0
+	}
+	digits, ok := strings.CutSuffix(rest, transcriptSuffix)
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 1 {
+		return 0
+	}
+	return n
+}
+
+// readTranscriptFile appends the turns in path to turns. Lines that do not
+// parse, such as one cut short by a crash, are skipped.
+func readTranscriptFile(path string, turns []Turn) ([]Turn, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return turns, nil
+		}
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var turn Turn
+			if json.Unmarshal(line, &turn) == nil {
+				turns = append(turns, turn)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return turns, nil
+}
@@ -0,0 +1,177 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore_AppendTurnAndTranscript(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	sess, err := store.Create("claude", "/tmp")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	turns := []*Turn{
+		{Turn: 1, Prompt: "first", Response: "one", TokenUsage: &TokenUsage{InputTokens: 10, OutputTokens: 5}},
+		{Turn: 2, Prompt: "second", ToolCalls: []ToolCall{{ID: "t1", Name: "Read", Input: json.RawMessage(`{"path":"a.go"}`), Output: "package a"}}},
+	}
+	for _, turn := range turns {
+		if err := store.AppendTurn(sess.ID, turn, TranscriptLimits{}); err != nil {
+			t.Fatalf("AppendTurn failed: %v", err)
+		}
+	}
+
+	got, err := store.Transcript(sess.ID)
+	if err != nil {
+		t.Fatalf("Transcript failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d turns, want 2", len(got))
+	}
+	if got[0].Prompt != "first" || got[0].Response != "one" || got[0].TokenUsage.Total() != 15 {
+		t.Errorf("turn 1 = %+v", got[0])
+	}
+	if len(got[1].ToolCalls) != 1 || got[1].ToolCalls[0].Name != "Read" || got[1].ToolCalls[0].Output != "package a" {
+		t.Errorf("turn 2 tool calls = %+v", got[1].ToolCalls)
+	}
+
+	info, err := os.Stat(filepath.Join(store.dir, sess.ID, transcriptFileName))
+	if err != nil {
+		t.Fatalf("transcript file missing: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("transcript mode = %o, want 600", perm)
+	}
+}
+
+func TestStore_TranscriptEmpty(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	sess, _ := store.Create("claude", "/tmp")
+	got, err := store.Transcript(sess.ID)
+	if err != nil {
+		t.Fatalf("Transcript failed: %v", err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("got %v, want an empty transcript", got)
+	}
+}
+
+func TestStore_TranscriptUnknownSession(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	id := strings.Repeat("a", 32)
+	if err := store.AppendTurn(id, &Turn{Turn: 1}, TranscriptLimits{}); err == nil {
+		t.Error("AppendTurn should fail for a missing session")
+	}
+	if _, err := store.Transcript(id); err == nil {
+		t.Error("Transcript should fail for a missing session")
+	}
+	if err := store.AppendTurn("../escape", &Turn{Turn: 1}, TranscriptLimits{}); err == nil {
+		t.Error("AppendTurn should reject path characters")
+	}
+}
+
+func TestStore_TranscriptRotation(t *testing.T) {
+	// Each turn is well over half the limit, so every append rotates
+	prompt := strings.Repeat("x", 600)
+
+	tests := []struct {
+		name      string
+		maxFiles  int
+		wantTurns []int
+	}{
+		{name: "keeps rotated files", maxFiles: 2, wantTurns: []int{3, 4, 5}},
+		{name: "keeps one file", maxFiles: 1, wantTurns: []int{4, 5}},
+		{name: "keeps no rotated files", maxFiles: 0, wantTurns: []int{5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, cleanup := setupTestStore(t)
+			defer cleanup()
+
+			sess, _ := store.Create("claude", "/tmp")
+			limits := TranscriptLimits{MaxBytes: 1000, MaxFiles: tt.maxFiles}
+			for i := 1; i <= 5; i++ {
+				if err := store.AppendTurn(sess.ID, &Turn{Turn: i, Prompt: prompt}, limits); err != nil {
+					t.Fatalf("AppendTurn %d failed: %v", i, err)
+				}
+			}
+
+			got, err := store.Transcript(sess.ID)
+			if err != nil {
+				t.Fatalf("Transcript failed: %v", err)
+			}
+			var gotTurns []int
+			for _, turn := range got {
+				gotTurns = append(gotTurns, turn.Turn)
+			}
+			if len(gotTurns) != len(tt.wantTurns) {
+				t.Fatalf("turns = %v, want %v", gotTurns, tt.wantTurns)
+			}
+			for i := range gotTurns {
+				if gotTurns[i] != tt.wantTurns[i] {
+					t.Fatalf("turns = %v, want %v", gotTurns, tt.wantTurns)
+				}
+			}
+
+			files := transcriptFiles(filepath.Join(store.dir, sess.ID))
+			if len(files) != tt.maxFiles+1 {
+				t.Errorf("files = %v, want %d", files, tt.maxFiles+1)
+			}
+		})
+	}
+}
+
+func TestStore_TranscriptSkipsMalformedLines(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	sess, _ := store.Create("claude", "/tmp")
+	if err := store.AppendTurn(sess.ID, &Turn{Turn: 1, Prompt: "ok"}, TranscriptLimits{}); err != nil {
+		t.Fatalf("AppendTurn failed: %v", err)
+	}
+
+	// A line cut short, as by a crash mid-write
+	path := filepath.Join(store.dir, sess.ID, transcriptFileName)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"turn":2,"prompt":"cut`)
+	f.Close()
+
+	got, err := store.Transcript(sess.ID)
+	if err != nil {
+		t.Fatalf("Transcript failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Prompt != "ok" {
+		t.Errorf("got %+v, want only the complete turn", got)
+	}
+}
+
+func TestStore_DeleteRemovesTranscript(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	sess, _ := store.Create("claude", "/tmp")
+	if err := store.AppendTurn(sess.ID, &Turn{Turn: 1, Prompt: "hello"}, TranscriptLimits{}); err != nil {
+		t.Fatalf("AppendTurn failed: %v", err)
+	}
+	if err := store.Delete(sess.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(store.dir, sess.ID)); !os.IsNotExist(err) {
+		t.Errorf("session directory still exists: %v", err)
+	}
+}
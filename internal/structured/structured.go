@@ -0,0 +1,403 @@
+// Package structured asks backends for JSON replies matching a JSON Schema,
+// and extracts and validates the JSON from their output.
+package structured
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Schema is a compiled JSON Schema that replies must match.
+//
+// Schemas use the keywords the server validates requests with: types,
+// properties, required, items, enum, bounds, lengths, patterns and
+// oneOf/anyOf/allOf/not. References to definitions within the schema, such
+// as "#/$defs/Item", are inlined, and const and the null type are read as
+// one-value enums.
+// Other keywords, such as if/then and patternProperties, are rejected
+// rather than ignored, as are remote or recursive references, lists of
+// types and additionalProperties other than true or false.
+type Schema struct {
+	raw      []byte
+	schema   *huma.Schema
+	registry huma.Registry
+}
+
+// Compile compiles a JSON Schema document.
+func Compile(data []byte) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	root, ok := doc.(map[string]any)
+	if !ok {
+		return nil, errors.New("invalid JSON schema: must be an object")
+	}
+	resolved, err := (&inliner{root: root}).inline(root, "")
+	if err != nil {
+		return nil, fmt.Errorf("unsupported JSON schema: %w", err)
+	}
+	if err := checkKeywords(resolved, ""); err != nil {
+		return nil, fmt.Errorf("unsupported JSON schema: %w", err)
+	}
+
+	resolvedData, err := json.Marshal(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	var schema huma.Schema
+	if err := json.Unmarshal(resolvedData, &schema); err != nil {
+		return nil, fmt.Errorf("unsupported JSON schema: %w", err)
+	}
+	if err := prepare(&schema); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	schema.PrecomputeMessages()
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	return &Schema{
+		raw:      raw,
+		schema:   &schema,
+		registry: huma.NewMapRegistry("#/components/schemas/", huma.DefaultSchemaNamer),
+	}, nil
+}
+
+// CompileValue compiles a JSON Schema given as decoded JSON, e.g. a field
+// of a request.
+func CompileValue(v any) (*Schema, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	return Compile(data)
+}
+
+// maxInlinedRefs bounds the references inlined into a schema, which can
+// otherwise grow exponentially with definitions referring to each other.
+const maxInlinedRefs = 1000
+
+// inliner rewrites a schema into one using only the keywords replies are
+// validated with: local $ref references are replaced by the schemas they
+// point to, definitions are dropped, and const and the null type become
+// one-value enums.
+type inliner struct {
+	root  map[string]any
+	refs  []string // references being inlined, to reject recursion
+	count int
+}
+
+// inline returns a rewritten copy of the schema s at path.
+func (in *inliner) inline(s map[string]any, path string) (map[string]any, error) {
+	out := make(map[string]any, len(s))
+	var err error
+	for _, key := range slices.Sorted(maps.Keys(s)) {
+		value := s[key]
+		switch key {
+		case "$defs", "definitions":
+			// Inlined where they are referenced
+		case "$ref":
+			// Resolved below
+		case "const":
+			if _, ok := s["enum"]; ok {
+				return nil, fmt.Errorf("keywords \"const\" and \"enum\" at %s cannot be combined", schemaPath(path))
+			}
+			out["enum"] = []any{value}
+		case "type":
+			if value != "null" {
+				out[key] = value
+				continue
+			}
+			// Validation ignores the null type, but not a null enum
+			if _, ok := s["enum"]; ok {
+				return nil, fmt.Errorf("keywords \"type\": \"null\" and \"enum\" at %s cannot be combined", schemaPath(path))
+			}
+			out["enum"] = []any{nil}
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				out[key] = value
+				continue
+			}
+			inlined := make(map[string]any, len(props))
+			for _, name := range slices.Sorted(maps.Keys(props)) {
+				if inlined[name], err = in.inlineSubschema(props[name], path+"/properties/"+name); err != nil {
+					return nil, err
+				}
+			}
+			out[key] = inlined
+		case "items", "not":
+			if out[key], err = in.inlineSubschema(value, path+"/"+key); err != nil {
+				return nil, err
+			}
+		case "oneOf", "anyOf", "allOf":
+			subs, ok := value.([]any)
+			if !ok {
+				out[key] = value
+				continue
+			}
+			inlined := make([]any, len(subs))
+			for i, sub := range subs {
+				if inlined[i], err = in.inlineSubschema(sub, fmt.Sprintf("%s/%s/%d", path, key, i)); err != nil {
+					return nil, err
+				}
+			}
+			out[key] = inlined
+		default:
+			out[key] = value
+		}
+	}
+
+	ref, ok := s["$ref"]
+	if !ok {
+		return out, nil
+	}
+	target, err := in.resolve(ref, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return target, nil
+	}
+	// Keywords next to $ref apply as well
+	allOf, _ := out["allOf"].([]any)
+	out["allOf"] = append(allOf, target)
+	return out, nil
+}
+
+// inlineSubschema rewrites a subschema. Values that are not schemas are
+// left to decoding to reject.
+func (in *inliner) inlineSubschema(v any, path string) (any, error) {
+	if sub, ok := v.(map[string]any); ok {
+		return in.inline(sub, path)
+	}
+	return v, nil
+}
+
+// resolve returns the rewritten schema that the reference ref at path
+// points to.
+func (in *inliner) resolve(ref any, path string) (map[string]any, error) {
+	pointer, _ := ref.(string)
+	tokens, ok := strings.CutPrefix(pointer, "#")
+	if !ok || (tokens != "" && !strings.HasPrefix(tokens, "/")) {
+		return nil, fmt.Errorf("$ref %q at %s is not supported: only references within the schema are", pointer, schemaPath(path))
+	}
+	if slices.Contains(in.refs, pointer) {
+		return nil, fmt.Errorf("$ref %q at %s is recursive, which is not supported", pointer, schemaPath(path))
+	}
+	if in.count++; in.count > maxInlinedRefs {
+		return nil, fmt.Errorf("schema has more than %d references", maxInlinedRefs)
+	}
+
+	var target any = in.root
+	if tokens != "" {
+		for _, token := range strings.Split(tokens[1:], "/") {
+			token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+			switch t := target.(type) {
+			case map[string]any:
+				target = t[token]
+			case []any:
+				i, err := strconv.Atoi(token)
+				if err != nil || i < 0 || i >= len(t) {
+					target = nil
+				} else {
+					target = t[i]
+				}
+			default:
+				target = nil
+			}
+		}
+	}
+	schema, ok := target.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("$ref %q at %s does not point to a schema", pointer, schemaPath(path))
+	}
+
+	in.refs = append(in.refs, pointer)
+	defer func() { in.refs = in.refs[:len(in.refs)-1] }()
+	return in.inline(schema, path)
+}
+
+// keywords are the schema keywords replies are validated with, and the
+// annotations that do not affect validation.
+var keywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true,
+	"title": true, "description": true, "default": true, "examples": true,
+	"deprecated": true, "readOnly": true, "writeOnly": true,
+	"type": true, "nullable": true, "enum": true, "format": true, "contentEncoding": true,
+	"minimum": true, "exclusiveMinimum": true, "maximum": true, "exclusiveMaximum": true, "multipleOf": true,
+	"minLength": true, "maxLength": true, "pattern": true,
+	"items": true, "minItems": true, "maxItems": true, "uniqueItems": true,
+	"properties": true, "required": true, "additionalProperties": true,
+	"minProperties": true, "maxProperties": true, "dependentRequired": true,
+	"oneOf": true, "anyOf": true, "allOf": true, "not": true,
+}
+
+// checkKeywords reports the first keyword in the schema s at path that
+// replies would not be validated with. Decoding into huma.Schema drops
+// such keywords silently, so a reply could match a schema it breaks.
+func checkKeywords(s map[string]any, path string) error {
+	for _, key := range slices.Sorted(maps.Keys(s)) {
+		value := s[key]
+		if !keywords[key] {
+			return fmt.Errorf("keyword %q at %s is not supported", key, schemaPath(path))
+		}
+
+		switch key {
+		case "properties":
+			props, _ := value.(map[string]any)
+			for name, prop := range props {
+				if err := checkSubschema(prop, path+"/properties/"+name); err != nil {
+					return err
+				}
+			}
+		case "items", "not":
+			if err := checkSubschema(value, path+"/"+key); err != nil {
+				return err
+			}
+		case "oneOf", "anyOf", "allOf":
+			subs, _ := value.([]any)
+			for i, sub := range subs {
+				if err := checkSubschema(sub, fmt.Sprintf("%s/%s/%d", path, key, i)); err != nil {
+					return err
+				}
+			}
+		case "additionalProperties":
+			if _, ok := value.(bool); !ok {
+				return fmt.Errorf("keyword %q at %s must be true or false", key, schemaPath(path))
+			}
+		}
+	}
+	return nil
+}
+
+// checkSubschema checks the keywords of a subschema. Values that are not
+// schemas are left to decoding to reject.
+func checkSubschema(v any, path string) error {
+	if sub, ok := v.(map[string]any); ok {
+		return checkKeywords(sub, path)
+	}
+	return nil
+}
+
+// schemaPath returns path as a JSON pointer, "/" for the root.
+func schemaPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// prepare readies s for validation and reports the first pattern in it that
+// does not compile. Required properties are only checked when they are
+// declared, so undeclared ones are declared with an empty schema.
+func prepare(s *huma.Schema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return err
+		}
+	}
+	for _, name := range s.Required {
+		if s.Properties[name] == nil {
+			if s.Properties == nil {
+				s.Properties = map[string]*huma.Schema{}
+			}
+			s.Properties[name] = &huma.Schema{}
+		}
+	}
+	subs := []*huma.Schema{s.Items, s.Not}
+	subs = append(subs, s.OneOf...)
+	subs = append(subs, s.AnyOf...)
+	subs = append(subs, s.AllOf...)
+	for _, prop := range s.Properties {
+		subs = append(subs, prop)
+	}
+	for _, sub := range subs {
+		if err := prepare(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Instructions asks for a reply matching the schema, to be added to the
+// prompt.
+func (s *Schema) Instructions() string {
+	return fmt.Sprintf("Reply with a single JSON value matching the JSON Schema below and nothing else: no code fences and no commentary.\n\n%s", s.raw)
+}
+
+// Correction asks again for a reply after err rejected the last one.
+func (s *Schema) Correction(err error) string {
+	return fmt.Sprintf("Your reply was rejected: %v.\n%s", err, s.Instructions())
+}
+
+// RepairPrompt asks again for a reply to prompt, for backends without the
+// rejected reply in context.
+func (s *Schema) RepairPrompt(prompt, reply string, err error) string {
+	return fmt.Sprintf("%s\n\n[Previous response: %s]\n\n%s", prompt, reply, s.Correction(err))
+}
+
+// Extract returns the JSON value in output if it matches the schema.
+// Surrounding text and code fences are ignored.
+func (s *Schema) Extract(output string) (json.RawMessage, error) {
+	text := extractJSON(output)
+
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, errors.New("reply does not contain valid JSON")
+	}
+
+	res := &huma.ValidateResult{}
+	huma.Validate(s.registry, s.schema, huma.NewPathBuffer([]byte(""), 0), huma.ModeReadFromServer, value, res)
+	if len(res.Errors) > 0 {
+		msgs := make([]string, len(res.Errors))
+		for i, err := range res.Errors {
+			msgs[i] = err.Error()
+		}
+		return nil, fmt.Errorf("reply does not match the JSON schema: %s", strings.Join(msgs, "; "))
+	}
+	return json.RawMessage(text), nil
+}
+
+// extractJSON finds the JSON in output: all of it, the first code block,
+// or the text from the first opening to the last closing bracket.
+func extractJSON(output string) string {
+	text := strings.TrimSpace(output)
+	if json.Valid([]byte(text)) {
+		return text
+	}
+
+	if _, rest, ok := strings.Cut(text, "```"); ok {
+		if start := strings.TrimLeft(rest, " "); !strings.HasPrefix(start, "{") && !strings.HasPrefix(start, "[") {
+			// Skip the language of the block
+			_, rest, _ = strings.Cut(rest, "\n")
+		}
+		if block, _, ok := strings.Cut(rest, "```"); ok && json.Valid([]byte(strings.TrimSpace(block))) {
+			return strings.TrimSpace(block)
+		}
+	}
+
+	if start := strings.IndexAny(text, "{["); start >= 0 {
+		closer := "}"
+		if text[start] == '[' {
+			closer = "]"
+		}
+		if end := strings.LastIndex(text, closer); end > start && json.Valid([]byte(text[start:end+1])) {
+			return text[start : end+1]
+		}
+	}
+	return text
+}
@@ -0,0 +1,168 @@
+package structured
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const weatherSchema = `{
+	"type": "object",
+	"properties": {"city": {"type": "string"}, "temp": {"type": "number"}},
+	"required": ["city", "temp"],
+	"additionalProperties": false
+}`
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr bool
+	}{
+		{name: "object", schema: weatherSchema},
+		{name: "empty", schema: `{}`},
+		{name: "not JSON", schema: `{`, wantErr: true},
+		{name: "not an object", schema: `[]`, wantErr: true},
+		{name: "type list", schema: `{"type":["string","null"]}`, wantErr: true},
+		{name: "invalid pattern", schema: `{"properties":{"a":{"type":"string","pattern":"("}}}`, wantErr: true},
+		{name: "annotations", schema: `{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"T","type":"string","examples":["a"]}`},
+		{name: "const", schema: `{"properties":{"a":{"const":1}}}`},
+		{name: "const and enum", schema: `{"const":1,"enum":[1,2]}`, wantErr: true},
+		{name: "ref", schema: `{"$ref":"#/$defs/a","$defs":{"a":{"type":"string"}}}`},
+		{name: "definitions ref", schema: `{"items":{"$ref":"#/definitions/a"},"definitions":{"a":{"type":"string"}}}`},
+		{name: "remote ref", schema: `{"$ref":"https://example.com/schema.json"}`, wantErr: true},
+		{name: "missing ref", schema: `{"$ref":"#/$defs/missing"}`, wantErr: true},
+		{name: "recursive ref", schema: `{"$ref":"#/$defs/node","$defs":{"node":{"properties":{"next":{"$ref":"#/$defs/node"}}}}}`, wantErr: true},
+		{name: "unsupported keyword in definition", schema: `{"$ref":"#/$defs/a","$defs":{"a":{"if":{"type":"string"}}}}`, wantErr: true},
+		{name: "if then", schema: `{"if":{"type":"string"},"then":{"minLength":1}}`, wantErr: true},
+		{name: "patternProperties", schema: `{"type":"object","patternProperties":{"^a":{"type":"string"}}}`, wantErr: true},
+		{name: "nested in items", schema: `{"type":"array","items":{"anyOf":[{"type":"string"},{"not":{"if":{"type":"string"}}}]}}`, wantErr: true},
+		{name: "additionalProperties schema", schema: `{"type":"object","additionalProperties":{"type":"string"}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]byte(tt.schema))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompile_UnsupportedKeyword(t *testing.T) {
+	_, err := Compile([]byte(`{"type":"object","properties":{"kind":{"type":"string","if":{"const":"a"}}}}`))
+	if err == nil || !strings.Contains(err.Error(), `"if"`) || !strings.Contains(err.Error(), "/properties/kind") {
+		t.Errorf("Compile() error = %v, want it to name if and its path", err)
+	}
+}
+
+// orderSchema is a nested model as pydantic generates it, with definitions,
+// a Literal field and an optional reference.
+const orderSchema = `{
+	"$defs": {
+		"Address": {
+			"properties": {"city": {"title": "City", "type": "string"}},
+			"required": ["city"],
+			"title": "Address",
+			"type": "object"
+		},
+		"Item": {
+			"properties": {
+				"sku": {"title": "Sku", "type": "string"},
+				"quantity": {"minimum": 1, "title": "Quantity", "type": "integer"}
+			},
+			"required": ["sku", "quantity"],
+			"title": "Item",
+			"type": "object"
+		}
+	},
+	"properties": {
+		"kind": {"const": "order", "title": "Kind", "type": "string"},
+		"items": {"items": {"$ref": "#/$defs/Item"}, "title": "Items", "type": "array"},
+		"shipping": {"anyOf": [{"$ref": "#/$defs/Address"}, {"type": "null"}], "default": null},
+		"billing": {"$ref": "#/$defs/Address", "description": "Billing address"}
+	},
+	"required": ["kind", "items", "billing"],
+	"title": "Order",
+	"type": "object"
+}`
+
+func TestSchema_ExtractNestedModel(t *testing.T) {
+	schema, err := Compile([]byte(orderSchema))
+	if err != nil {
+		t.Fatalf("Compile() error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		output  string
+		wantErr bool
+	}{
+		{name: "valid", output: `{"kind":"order","items":[{"sku":"a","quantity":2}],"shipping":null,"billing":{"city":"Paris"}}`},
+		{name: "with shipping", output: `{"kind":"order","items":[],"shipping":{"city":"Lyon"},"billing":{"city":"Paris"}}`},
+		{name: "wrong const", output: `{"kind":"refund","items":[],"billing":{"city":"Paris"}}`, wantErr: true},
+		{name: "invalid item", output: `{"kind":"order","items":[{"sku":"a","quantity":0}],"billing":{"city":"Paris"}}`, wantErr: true},
+		{name: "invalid optional reference", output: `{"kind":"order","items":[],"shipping":{"town":"Lyon"},"billing":{"city":"Paris"}}`, wantErr: true},
+		{name: "invalid reference with description", output: `{"kind":"order","items":[],"billing":{}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := schema.Extract(tt.output); (err != nil) != tt.wantErr {
+				t.Errorf("Extract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if !strings.Contains(schema.Instructions(), `"$ref":"#/$defs/Item"`) {
+		t.Errorf("expected the schema to be asked for as given, got %s", schema.Instructions())
+	}
+}
+
+func TestSchema_Extract(t *testing.T) {
+	schema, err := Compile([]byte(weatherSchema))
+	if err != nil {
+		t.Fatalf("Compile() error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{name: "bare", output: ` {"city":"Paris","temp":21.5} `, want: `{"city":"Paris","temp":21.5}`},
+		{name: "code block", output: "Here you go:\n```json\n{\"city\":\"Paris\",\"temp\":21}\n```\nAnything else?", want: `{"city":"Paris","temp":21}`},
+		{name: "surrounding text", output: `The weather is {"city":"Paris","temp":21} today.`, want: `{"city":"Paris","temp":21}`},
+		{name: "no JSON", output: `It is sunny.`, wantErr: true},
+		{name: "missing property", output: `{"city":"Paris"}`, wantErr: true},
+		{name: "wrong type", output: `{"city":"Paris","temp":"warm"}`, wantErr: true},
+		{name: "extra property", output: `{"city":"Paris","temp":21,"wind":3}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.Extract(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Extract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Extract() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchema_RepairPrompt(t *testing.T) {
+	schema, err := Compile([]byte(`{"type":"object"}`))
+	if err != nil {
+		t.Fatalf("Compile() error: %v", err)
+	}
+
+	got := schema.RepairPrompt("Weather?", "sunny", errors.New("reply does not contain valid JSON"))
+	for _, want := range []string{"Weather?", "[Previous response: sunny]", "reply does not contain valid JSON", `{"type":"object"}`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RepairPrompt() = %q, want it to contain %q", got, want)
+		}
+	}
+}
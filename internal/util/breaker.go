@@ -0,0 +1,137 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+	"github.com/signalridge/clinvoker/internal/metrics"
+	"github.com/signalridge/clinvoker/internal/resilience"
+)
+
+var (
+	breakersMu  sync.Mutex
+	breakers    *resilience.CircuitBreakerRegistry
+	breakersCfg config.CircuitBreakerConfig
+)
+
+// backendBreakers returns the breaker registry for the given settings.
+// The registry is rebuilt, dropping all breaker state, when they change.
+func backendBreakers(cfg config.CircuitBreakerConfig) *resilience.CircuitBreakerRegistry {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	if breakers != nil && breakersCfg == cfg {
+		return breakers
+	}
+
+	var registry *resilience.CircuitBreakerRegistry
+	registry = resilience.NewCircuitBreakerRegistry(resilience.CircuitBreakerConfig{
+		FailureThreshold: cfg.FailureThreshold,
+		SuccessThreshold: cfg.SuccessThreshold,
+		Timeout:          time.Duration(cfg.OpenTimeoutSecs) * time.Second,
+		OnStateChange: func(name string, _, _ resilience.CircuitState) {
+			// Callbacks may run out of order; report the current state.
+			if config.Get().Server.MetricsEnabled {
+				metrics.SetCircuitBreakerState(name, float64(registry.Get(name).State()))
+			}
+		},
+	})
+	breakers = registry
+	breakersCfg = cfg
+	return breakers
+}
+
+// BackendBreaker returns the circuit breaker guarding a backend, or nil when
+// circuit breakers are disabled.
+func BackendBreaker(backendName string) *resilience.CircuitBreaker {
+	cfg := config.Get().CircuitBreaker
+	if !cfg.Enabled {
+		return nil
+	}
+	return backendBreakers(cfg).Get(backendName)
+}
+
+// BackendBreakerStats returns the stats of every backend breaker created so far.
+func BackendBreakerStats() map[string]resilience.Stats {
+	cfg := config.Get().CircuitBreaker
+	if !cfg.Enabled {
+		return nil
+	}
+	return backendBreakers(cfg).AllStats()
+}
+
+// ResetBackendBreakers closes the circuits of the named backends, or of all
+// backends when no name is given. It returns the names that were reset.
+func ResetBackendBreakers(names ...string) []string {
+	cfg := config.Get().CircuitBreaker
+	if !cfg.Enabled {
+		return nil
+	}
+	registry := backendBreakers(cfg)
+
+	if len(names) == 0 {
+		for name := range registry.AllStats() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var reset []string
+	for _, name := range names {
+		if registry.Reset(name) {
+			reset = append(reset, name)
+		}
+	}
+	return reset
+}
+
+// BackendCall tracks one backend invocation against its circuit breaker.
+// A nil BackendCall is valid and records nothing.
+type BackendCall struct {
+	breaker *resilience.CircuitBreaker
+}
+
+// StartBackendCall asks the backend's circuit breaker to admit a call.
+// When the circuit is open it returns an ErrCodeBackendUnavailable error and
+// the call must not be made.
+func StartBackendCall(backendName string) (*BackendCall, error) {
+	cb := BackendBreaker(backendName)
+	if cb == nil {
+		return nil, nil
+	}
+	if !cb.Allow() {
+		if config.Get().Server.MetricsEnabled {
+			metrics.RecordCircuitBreakerRejection(backendName)
+		}
+		return nil, apperrors.Wrap(apperrors.ErrCodeBackendUnavailable,
+			fmt.Sprintf("backend %q is disabled after repeated failures", backendName),
+			resilience.ErrCircuitOpen).WithContext("backend", backendName)
+	}
+	return &BackendCall{breaker: cb}, nil
+}
+
+// Done records the outcome of the call. Only backend failures, as classified
+// by errors.ClassifyFailure, count against the backend.
+func (c *BackendCall) Done(exitCode int, errMsg string) {
+	if c == nil {
+		return
+	}
+	if exitCode != 0 && apperrors.IsBackendFailure(apperrors.ClassifyFailure(exitCode, errMsg)) {
+		c.breaker.RecordFailure()
+		return
+	}
+	c.breaker.RecordSuccess()
+}
+
+// Cancel ends the call without recording an outcome, for calls abandoned by
+// the caller rather than failed by the backend.
+func (c *BackendCall) Cancel() {
+	if c == nil {
+		return
+	}
+	c.breaker.RecordCanceled()
+}
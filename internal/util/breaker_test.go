@@ -0,0 +1,77 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+	"github.com/signalridge/clinvoker/internal/resilience"
+)
+
+func initBreakerConfig(t *testing.T, failureThreshold int) {
+	t.Helper()
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+	config.Get().CircuitBreaker.FailureThreshold = failureThreshold
+}
+
+func TestStartBackendCall(t *testing.T) {
+	initBreakerConfig(t, 2)
+
+	for i := 0; i < 2; i++ {
+		call, err := StartBackendCall("cb-test")
+		if err != nil {
+			t.Fatalf("call %d rejected: %v", i, err)
+		}
+		call.Done(1, "API Error: 429 Too Many Requests")
+	}
+
+	if _, err := StartBackendCall("cb-test"); !apperrors.IsCode(err, apperrors.ErrCodeBackendUnavailable) {
+		t.Fatalf("expected backend_unavailable from open circuit, got %v", err)
+	}
+	if stats := BackendBreakerStats()["cb-test"]; stats.State != resilience.StateOpen || stats.TotalRejected != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	if reset := ResetBackendBreakers("cb-test", "cb-unknown"); len(reset) != 1 || reset[0] != "cb-test" {
+		t.Errorf("ResetBackendBreakers() = %v, want [cb-test]", reset)
+	}
+	call, err := StartBackendCall("cb-test")
+	if err != nil {
+		t.Fatalf("expected reset circuit to admit calls: %v", err)
+	}
+	call.Done(0, "")
+}
+
+func TestBackendCall_Outcomes(t *testing.T) {
+	initBreakerConfig(t, 1)
+
+	// Canceled calls and successes do not trip the circuit.
+	call, _ := StartBackendCall("cb-outcomes")
+	call.Cancel()
+	call, _ = StartBackendCall("cb-outcomes")
+	call.Done(0, "")
+
+	if stats := BackendBreakerStats()["cb-outcomes"]; stats.State != resilience.StateClosed || stats.TotalFailures != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestStartBackendCall_Disabled(t *testing.T) {
+	initBreakerConfig(t, 1)
+	config.Get().CircuitBreaker.Enabled = false
+
+	for i := 0; i < 3; i++ {
+		call, err := StartBackendCall("cb-disabled")
+		if err != nil || call != nil {
+			t.Fatalf("expected no breaker when disabled, got %v, %v", call, err)
+		}
+		call.Done(1, "failed")
+	}
+	if BackendBreakerStats() != nil {
+		t.Error("expected no stats when disabled")
+	}
+}
@@ -14,7 +14,11 @@ func CommandWithContext(ctx context.Context, cmd *exec.Cmd) *exec.Cmd {
 	}
 
 	if len(cmd.Args) == 0 {
-		return exec.CommandContext(ctx, cmd.Path)
+		newCmd := exec.CommandContext(ctx, cmd.Path)
+		if cmd.Err != nil {
+			newCmd.Err = cmd.Err
+		}
+		return newCmd
 	}
 
 	newCmd := exec.CommandContext(ctx, cmd.Path, cmd.Args[1:]...)
@@ -22,6 +26,10 @@ func CommandWithContext(ctx context.Context, cmd *exec.Cmd) *exec.Cmd {
 	newCmd.Env = cmd.Env
 	newCmd.SysProcAttr = cmd.SysProcAttr
 	newCmd.ExtraFiles = cmd.ExtraFiles
+	// Preserve construction errors so the wrapped command still fails to start
+	if cmd.Err != nil {
+		newCmd.Err = cmd.Err
+	}
 	return newCmd
 }
 
@@ -2,6 +2,7 @@ package util
 
 import (
 	"context"
+	"errors"
 	"os/exec"
 	"testing"
 )
@@ -56,6 +57,19 @@ func TestCommandWithContext(t *testing.T) {
 	})
 }
 
+func TestCommandWithContextPreservesErr(t *testing.T) {
+	buildErr := errors.New("build failed")
+	cmd := &exec.Cmd{Path: "/bin/true", Args: []string{"/bin/true"}, Err: buildErr}
+
+	result := CommandWithContext(context.Background(), cmd)
+	if !errors.Is(result.Err, buildErr) {
+		t.Fatalf("expected Err to be preserved, got %v", result.Err)
+	}
+	if err := result.Start(); !errors.Is(err, buildErr) {
+		t.Errorf("expected Start to fail with build error, got %v", err)
+	}
+}
+
 func TestCleanupContext(t *testing.T) {
 	t.Run("nil returns background context", func(t *testing.T) {
 		result := CleanupContext(nil) //nolint:staticcheck // testing nil handling
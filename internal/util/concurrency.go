@@ -0,0 +1,144 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+	"github.com/signalridge/clinvoker/internal/metrics"
+	"github.com/signalridge/clinvoker/internal/resilience"
+)
+
+// concurrencySettings are the server settings the process limiter is built from.
+type concurrencySettings struct {
+	maxProcesses     int
+	maxPerBackend    int
+	perBackend       map[string]int
+	queueSize        int
+	queueTimeoutSecs int
+}
+
+func currentConcurrencySettings() concurrencySettings {
+	server := config.Get().Server
+	return concurrencySettings{
+		maxProcesses:     server.MaxConcurrentProcesses,
+		maxPerBackend:    server.MaxConcurrentPerBackend,
+		perBackend:       server.BackendConcurrency,
+		queueSize:        server.QueueSize,
+		queueTimeoutSecs: server.QueueTimeoutSecs,
+	}
+}
+
+// limited reports whether any concurrency limit is set.
+func (s concurrencySettings) limited() bool {
+	if s.maxProcesses > 0 || s.maxPerBackend > 0 {
+		return true
+	}
+	for _, limit := range s.perBackend {
+		if limit > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfterSecs is the wait suggested to rejected clients: the queue
+// timeout, by when every request now waiting has run or given up.
+func (s concurrencySettings) retryAfterSecs() int {
+	if s.queueTimeoutSecs > 0 {
+		return s.queueTimeoutSecs
+	}
+	return 1
+}
+
+var (
+	limiterMu  sync.Mutex
+	limiter    *resilience.Limiter
+	limiterCfg concurrencySettings
+)
+
+// processLimiter returns the limiter for the given settings. The limiter is
+// rebuilt, forgetting the processes in flight, when they change.
+func processLimiter(s concurrencySettings) *resilience.Limiter {
+	limiterMu.Lock()
+	defer limiterMu.Unlock()
+
+	if limiter != nil && reflect.DeepEqual(limiterCfg, s) {
+		return limiter
+	}
+
+	var l *resilience.Limiter
+	l = resilience.NewLimiter(resilience.LimiterConfig{
+		MaxInFlight: s.maxProcesses,
+		MaxPerKey: func(backendName string) int {
+			if limit, ok := s.perBackend[backendName]; ok {
+				return limit
+			}
+			return s.maxPerBackend
+		},
+		QueueSize:    s.queueSize,
+		QueueTimeout: time.Duration(s.queueTimeoutSecs) * time.Second,
+		OnQueueChange: func(backendName string, _ int) {
+			// Callbacks may run out of order; report the current depth.
+			if config.Get().Server.MetricsEnabled {
+				metrics.SetConcurrencyQueueDepth(backendName, float64(l.Stats().WaitingPerKey[backendName]))
+			}
+		},
+	})
+	limiter = l
+	limiterCfg = s
+	return limiter
+}
+
+// AcquireProcessSlot waits until the server's concurrency limits admit one
+// more process of the backend and returns the function that frees the slot.
+// When too many requests are already waiting it returns an ErrCodeQueueFull
+// error, and when the wait outlasts the queue timeout an ErrCodeQueueTimeout
+// error; both carry the suggested retry delay as "retry_after_secs" context.
+func AcquireProcessSlot(ctx context.Context, backendName string) (func(), error) {
+	s := currentConcurrencySettings()
+	if !s.limited() {
+		return func() {}, nil
+	}
+
+	start := time.Now()
+	release, err := processLimiter(s).Acquire(ctx, backendName)
+	metricsEnabled := config.Get().Server.MetricsEnabled
+	if metricsEnabled && !errors.Is(err, resilience.ErrQueueFull) {
+		metrics.RecordConcurrencyQueueWait(backendName, time.Since(start).Seconds())
+	}
+
+	var code apperrors.ErrorCode
+	var msg string
+	switch {
+	case err == nil:
+		return release, nil
+	case errors.Is(err, resilience.ErrQueueFull):
+		code = apperrors.ErrCodeQueueFull
+		msg = fmt.Sprintf("too many requests are waiting for backend %q", backendName)
+	case errors.Is(err, resilience.ErrQueueTimeout):
+		code = apperrors.ErrCodeQueueTimeout
+		msg = fmt.Sprintf("timed out waiting for backend %q", backendName)
+	default:
+		return nil, err
+	}
+
+	if metricsEnabled {
+		metrics.RecordConcurrencyRejection(backendName, string(code))
+	}
+	return nil, apperrors.Wrap(code, msg, err).
+		WithContext("backend", backendName).
+		WithContext("retry_after_secs", s.retryAfterSecs())
+}
+
+// IsConcurrencyRejection reports whether err is a request turned away by the
+// server's concurrency limits.
+func IsConcurrencyRejection(err error) bool {
+	code := apperrors.GetCode(err)
+	return code == apperrors.ErrCodeQueueFull || code == apperrors.ErrCodeQueueTimeout
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+)
+
+func TestAcquireProcessSlot(t *testing.T) {
+	initBreakerConfig(t, 1)
+
+	release, err := AcquireProcessSlot(context.Background(), "claude")
+	if err != nil {
+		t.Fatalf("expected no limit by default, got %v", err)
+	}
+	release()
+
+	server := &config.Get().Server
+	server.BackendConcurrency = map[string]int{"claude": 1}
+	server.QueueSize = 0
+	server.QueueTimeoutSecs = 30
+
+	release, err = AcquireProcessSlot(context.Background(), "claude")
+	if err != nil {
+		t.Fatalf("AcquireProcessSlot() error: %v", err)
+	}
+
+	_, err = AcquireProcessSlot(context.Background(), "claude")
+	if !apperrors.IsCode(err, apperrors.ErrCodeQueueFull) || !IsConcurrencyRejection(err) {
+		t.Fatalf("expected queue_full, got %v", err)
+	}
+	if got := apperrors.GetContext(err)["retry_after_secs"]; got != 30 {
+		t.Errorf("retry_after_secs = %v, want 30", got)
+	}
+
+	otherRelease, err := AcquireProcessSlot(context.Background(), "codex")
+	if err != nil {
+		t.Fatalf("expected other backends to be unlimited, got %v", err)
+	}
+	otherRelease()
+
+	release()
+	release, err = AcquireProcessSlot(context.Background(), "claude")
+	if err != nil {
+		t.Fatalf("expected a slot after release, got %v", err)
+	}
+	release()
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"log/slog"
+	"maps"
 	"strings"
 	"sync"
 
@@ -41,6 +42,25 @@ func ApplyUnifiedDefaults(opts *backend.UnifiedOptions, cfg *config.Config, effe
 	if !opts.DryRun && effectiveDryRun {
 		opts.DryRun = true
 	}
+
+	// Configured MCP servers join the requested ones; a requested server
+	// replaces a configured one of the same name.
+	if len(cfg.MCPServers) > 0 {
+		servers := make(map[string]backend.MCPServer, len(cfg.MCPServers)+len(opts.MCPServers))
+		for name, sc := range cfg.MCPServers {
+			servers[name] = backend.MCPServer{
+				Command:        sc.Command,
+				Args:           sc.Args,
+				Env:            sc.Env,
+				EnvVars:        sc.EnvVars,
+				URL:            sc.URL,
+				Headers:        sc.Headers,
+				BearerTokenEnv: sc.BearerTokenEnv,
+			}
+		}
+		maps.Copy(servers, opts.MCPServers)
+		opts.MCPServers = servers
+	}
 }
 
 // ApplyOutputFormatDefault applies output format defaults with the following priority:
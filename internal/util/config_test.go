@@ -126,6 +126,33 @@ func TestApplyUnifiedDefaults(t *testing.T) {
 			t.Error("expected DryRun to be true from effectiveDryRun")
 		}
 	})
+
+	t.Run("merges MCP servers from config", func(t *testing.T) {
+		requested := map[string]backend.MCPServer{
+			"docs": {URL: "https://docs.example.com/mcp"},
+		}
+		opts := &backend.UnifiedOptions{MCPServers: requested}
+		cfg := &config.Config{
+			MCPServers: map[string]config.MCPServerConfig{
+				"docs":   {URL: "https://old.example.com/mcp"},
+				"github": {Command: "github-mcp", EnvVars: []string{"GITHUB_TOKEN"}},
+			},
+		}
+		ApplyUnifiedDefaults(opts, cfg, false)
+
+		if len(opts.MCPServers) != 2 {
+			t.Fatalf("MCPServers = %v, want 2 servers", opts.MCPServers)
+		}
+		if got := opts.MCPServers["docs"].URL; got != "https://docs.example.com/mcp" {
+			t.Errorf("docs URL = %q, want the requested one", got)
+		}
+		if got := opts.MCPServers["github"]; got.Command != "github-mcp" || len(got.EnvVars) != 1 {
+			t.Errorf("github = %+v, want the configured server", got)
+		}
+		if len(requested) != 1 {
+			t.Error("expected the requested servers to be left unchanged")
+		}
+	})
 }
 
 func TestApplyOutputFormatDefault(t *testing.T) {
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+)
+
+// FallbackAttempt records one backend run made while serving a request, one
+// per retry and fallback.
+type FallbackAttempt struct {
+	Backend    string `json:"backend"`
+	ExitCode   int    `json:"exit_code"`
+	ErrorCode  string `json:"error_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// FallbackChain returns the backends to try in order: primary first, then the
+// given fallback list, or the configured one when the list is empty.
+// Duplicates and empty names are skipped.
+func FallbackChain(primary string, fallback []string, cfg *config.Config) []string {
+	if len(fallback) == 0 && cfg != nil {
+		fallback = cfg.Fallback
+	}
+
+	chain := []string{primary}
+	seen := map[string]bool{primary: true}
+	for _, name := range fallback {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		chain = append(chain, name)
+	}
+	return chain
+}
+
+// FallbackModel returns the model to request from a fallback backend.
+// Aliases such as "fast" carry over; concrete model names belong to the
+// original backend and are dropped so the fallback backend's default applies.
+func FallbackModel(backendName, model string) string {
+	if model != "" && backend.ResolveModelAlias(backendName, model) != model {
+		return model
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/signalridge/clinvoker/internal/config"
+)
+
+func TestFallbackChain(t *testing.T) {
+	cfg := &config.Config{Fallback: []string{"claude", "gemini", "codex"}}
+
+	tests := []struct {
+		name     string
+		primary  string
+		fallback []string
+		cfg      *config.Config
+		want     []string
+	}{
+		{"config chain", "claude", nil, cfg, []string{"claude", "gemini", "codex"}},
+		{"primary moved to front", "codex", nil, cfg, []string{"codex", "claude", "gemini"}},
+		{"request overrides config", "claude", []string{"codex"}, cfg, []string{"claude", "codex"}},
+		{"no fallback", "claude", nil, &config.Config{}, []string{"claude"}},
+		{"nil config", "gemini", nil, nil, []string{"gemini"}},
+		{"skips empty and duplicates", "claude", []string{"", "gemini", "gemini"}, nil, []string{"claude", "gemini"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FallbackChain(tt.primary, tt.fallback, tt.cfg)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FallbackChain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbackModel(t *testing.T) {
+	if got := FallbackModel("gemini", "fast"); got != "fast" {
+		t.Errorf("expected alias to carry over, got %q", got)
+	}
+	if got := FallbackModel("gemini", "claude-opus-4-5"); got != "" {
+		t.Errorf("expected concrete model to be dropped, got %q", got)
+	}
+	if got := FallbackModel("gemini", ""); got != "" {
+		t.Errorf("expected empty model, got %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/signalridge/clinvoker/internal/backend"
+	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+	"github.com/signalridge/clinvoker/internal/pool"
+)
+
+var (
+	poolsMu  sync.Mutex
+	pools    map[string]*pool.Pool
+	poolsCfg map[string]config.PoolConfig
+)
+
+// backendPools returns the pools declared in the current config.
+// The pools are rebuilt, dropping their in-flight counts, when the
+// declarations change.
+func backendPools() map[string]*pool.Pool {
+	cfg := config.Get().Pools
+
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	if pools != nil && reflect.DeepEqual(poolsCfg, cfg) {
+		return pools
+	}
+
+	pools = make(map[string]*pool.Pool, len(cfg))
+	for name, pc := range cfg {
+		members := make([]pool.Member, len(pc.Members))
+		for i, m := range pc.Members {
+			members[i] = pool.Member{
+				Backend:        m.Backend,
+				Weight:         m.Weight,
+				MaxConcurrency: m.MaxConcurrency,
+			}
+		}
+		pools[name] = pool.New(name, pool.Strategy(pc.Strategy), members)
+	}
+	poolsCfg = cfg
+	return pools
+}
+
+// IsPool reports whether name is a backend pool.
+func IsPool(name string) bool {
+	_, ok := backendPools()[name]
+	return ok
+}
+
+// PoolNames returns the names of all backend pools, sorted.
+func PoolNames() []string {
+	all := backendPools()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AcquirePoolMember leases a member of the named pool for one request,
+// waiting while every available member is at its concurrency limit. Members
+// whose backend is not available are skipped; when none is left it returns an
+// ErrCodeBackendUnavailable error. For names that are not pools
+// it returns a nil lease, whose Release is a no-op.
+func AcquirePoolMember(ctx context.Context, name string) (*pool.Lease, error) {
+	p, ok := backendPools()[name]
+	if !ok {
+		return nil, nil
+	}
+	lease, err := p.Acquire(ctx, backend.IsAvailableCached)
+	if errors.Is(err, pool.ErrNoMember) {
+		return nil, apperrors.Wrap(apperrors.ErrCodeBackendUnavailable,
+			fmt.Sprintf("no member of pool %q is available", name), err).WithContext("backend", name)
+	}
+	return lease, err
+}
+
+// BackendPoolStats returns the state of every pool, ordered by name.
+func BackendPoolStats() []pool.Stats {
+	all := backendPools()
+	stats := make([]pool.Stats, 0, len(all))
+	for _, p := range all {
+		stats = append(stats, p.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	apperrors "github.com/signalridge/clinvoker/internal/errors"
+)
+
+func TestAcquirePoolMember(t *testing.T) {
+	initBreakerConfig(t, 1)
+	config.Get().Pools = map[string]config.PoolConfig{
+		"pool-missing": {Members: []config.PoolMemberConfig{{Backend: "no-such-backend"}}},
+	}
+
+	if !IsPool("pool-missing") || IsPool("claude") {
+		t.Error("IsPool() does not match the configured pools")
+	}
+	if names := PoolNames(); len(names) != 1 || names[0] != "pool-missing" {
+		t.Errorf("PoolNames() = %v", names)
+	}
+
+	lease, err := AcquirePoolMember(context.Background(), "claude")
+	if lease != nil || err != nil {
+		t.Errorf("expected no lease for a plain backend, got %v, %v", lease, err)
+	}
+	lease.Release()
+
+	if _, err := AcquirePoolMember(context.Background(), "pool-missing"); !apperrors.IsCode(err, apperrors.ErrCodeBackendUnavailable) {
+		t.Errorf("expected backend_unavailable without available members, got %v", err)
+	}
+
+	config.Get().Pools = nil
+	if IsPool("pool-missing") {
+		t.Error("expected pools to follow config changes")
+	}
+}
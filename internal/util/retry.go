@@ -0,0 +1,51 @@
+package util
+
+import (
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/resilience"
+)
+
+// RetryOptions overrides the configured retry policy for one request.
+// Zero fields inherit the configured values.
+type RetryOptions struct {
+	MaxAttempts      int     `json:"max_attempts,omitempty" doc:"Runs per backend including the first (1 disables retries)" minimum:"0"`
+	InitialBackoffMS int     `json:"initial_backoff_ms,omitempty" doc:"Wait before the first retry in milliseconds" minimum:"0"`
+	MaxBackoffMS     int     `json:"max_backoff_ms,omitempty" doc:"Maximum wait between retries in milliseconds" minimum:"0"`
+	Multiplier       float64 `json:"multiplier,omitempty" doc:"Growth factor of the wait after each retry" minimum:"0"`
+	Jitter           float64 `json:"jitter,omitempty" doc:"Random fraction added to or removed from each wait" minimum:"0" maximum:"1"`
+}
+
+// ResolveRetryPolicy returns the retry policy for a request: the configured
+// policy with any fields set in opts overriding it.
+func ResolveRetryPolicy(opts *RetryOptions, cfg *config.Config) resilience.RetryPolicy {
+	policy := resilience.DefaultRetryPolicy()
+	if cfg != nil {
+		mergeRetryPolicy(&policy, cfg.Retry.MaxAttempts, cfg.Retry.InitialBackoffMS, cfg.Retry.MaxBackoffMS,
+			cfg.Retry.Multiplier, cfg.Retry.Jitter)
+	}
+	if opts != nil {
+		mergeRetryPolicy(&policy, opts.MaxAttempts, opts.InitialBackoffMS, opts.MaxBackoffMS,
+			opts.Multiplier, opts.Jitter)
+	}
+	return policy
+}
+
+func mergeRetryPolicy(policy *resilience.RetryPolicy, maxAttempts, initialMS, maxMS int, multiplier, jitter float64) {
+	if maxAttempts > 0 {
+		policy.MaxAttempts = maxAttempts
+	}
+	if initialMS > 0 {
+		policy.InitialBackoff = time.Duration(initialMS) * time.Millisecond
+	}
+	if maxMS > 0 {
+		policy.MaxBackoff = time.Duration(maxMS) * time.Millisecond
+	}
+	if multiplier >= 1 {
+		policy.Multiplier = multiplier
+	}
+	if jitter > 0 && jitter <= 1 {
+		policy.Jitter = jitter
+	}
+}
@@ -0,0 +1,39 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/config"
+)
+
+func TestResolveRetryPolicy(t *testing.T) {
+	cfg := &config.Config{Retry: config.RetryConfig{
+		MaxAttempts:      3,
+		InitialBackoffMS: 500,
+		MaxBackoffMS:     10000,
+		Multiplier:       2,
+		Jitter:           0.1,
+	}}
+
+	t.Run("config", func(t *testing.T) {
+		policy := ResolveRetryPolicy(nil, cfg)
+		if policy.MaxAttempts != 3 || policy.InitialBackoff != 500*time.Millisecond || policy.MaxBackoff != 10*time.Second || policy.Jitter != 0.1 {
+			t.Errorf("unexpected policy: %+v", policy)
+		}
+	})
+
+	t.Run("request overrides", func(t *testing.T) {
+		policy := ResolveRetryPolicy(&RetryOptions{MaxAttempts: 5, InitialBackoffMS: 50}, cfg)
+		if policy.MaxAttempts != 5 || policy.InitialBackoff != 50*time.Millisecond || policy.MaxBackoff != 10*time.Second {
+			t.Errorf("unexpected policy: %+v", policy)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		policy := ResolveRetryPolicy(&RetryOptions{MaxAttempts: -1}, &config.Config{})
+		if policy.Enabled() || policy.InitialBackoff != time.Second || policy.Multiplier != 2 {
+			t.Errorf("unexpected policy: %+v", policy)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package util
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/output"
+	"github.com/signalridge/clinvoker/internal/session"
+)
+
+// maxToolOutput caps the tool output kept in a transcript turn.
+const maxToolOutput = 8 * 1024
+
+// TurnRecorder collects the response text and tool calls of a streamed run
+// for its transcript turn.
+type TurnRecorder struct {
+	response strings.Builder
+	partial  strings.Builder
+	tools    []session.ToolCall
+	toolByID map[string]int
+}
+
+// Add records event. Streamed text deltas are kept until the complete
+// message that some backends send after them, which replaces them.
+func (r *TurnRecorder) Add(event *output.UnifiedEvent) {
+	if event == nil {
+		return
+	}
+
+	switch event.Type {
+	case output.EventMessage:
+		content, err := event.GetMessageContent()
+		if err != nil {
+			return
+		}
+		if content.IsPartial {
+			r.partial.WriteString(content.Text)
+			return
+		}
+		r.partial.Reset()
+		r.response.WriteString(content.Text)
+
+	case output.EventToolUse:
+		content, err := event.GetToolUseContent()
+		if err != nil {
+			return
+		}
+		r.flushPartial()
+		if content.ToolID != "" {
+			if r.toolByID == nil {
+				r.toolByID = make(map[string]int)
+			}
+			r.toolByID[content.ToolID] = len(r.tools)
+		}
+		r.tools = append(r.tools, session.ToolCall{
+			ID:    content.ToolID,
+			Name:  content.ToolName,
+			Input: content.Input,
+		})
+
+	case output.EventToolResult:
+		content, err := event.GetToolResultContent()
+		if err != nil {
+			return
+		}
+		result := content.Output
+		if result == "" {
+			result = content.ErrorMsg
+		}
+		result = truncateToolOutput(result)
+
+		if i, ok := r.toolByID[content.ToolID]; ok && content.ToolID != "" {
+			r.tools[i].Output = result
+			r.tools[i].IsError = content.IsError
+			return
+		}
+		// A result without a matching call is kept on its own
+		r.tools = append(r.tools, session.ToolCall{
+			ID:      content.ToolID,
+			Name:    content.ToolName,
+			Output:  result,
+			IsError: content.IsError,
+		})
+	}
+}
+
+// Response returns the text the backend replied with.
+func (r *TurnRecorder) Response() string {
+	return r.response.String() + r.partial.String()
+}
+
+// ToolCalls returns the tools the backend used, in the order it used them.
+func (r *TurnRecorder) ToolCalls() []session.ToolCall {
+	return r.tools
+}
+
+// flushPartial keeps streamed text that no complete message followed.
+func (r *TurnRecorder) flushPartial() {
+	r.response.WriteString(r.partial.String())
+	r.partial.Reset()
+}
+
+// truncateToolOutput cuts s to maxToolOutput bytes, on a rune boundary.
+func truncateToolOutput(s string) string {
+	if len(s) <= maxToolOutput {
+		return s
+	}
+	cut := maxToolOutput
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "\n[truncated]"
+}
+
+// RecordTurn appends turn to the transcript of sess, numbered after the
+// session's turn count. Call it once the session has been updated and
+// saved. It does nothing without a store or session, or when transcripts
+// are disabled.
+func RecordTurn(store *session.Store, sess *session.Session, turn *session.Turn) error {
+	if store == nil || sess == nil {
+		return nil
+	}
+
+	cfg := config.Get().Session
+	if !cfg.Transcripts {
+		return nil
+	}
+
+	limits := session.TranscriptLimits{
+		MaxBytes: cfg.TranscriptMaxBytes,
+		MaxFiles: cfg.TranscriptMaxFiles,
+	}
+	if limits.MaxBytes == 0 {
+		limits.MaxBytes = session.DefaultTranscriptMaxBytes
+	}
+	if limits.MaxFiles == 0 {
+		limits.MaxFiles = session.DefaultTranscriptMaxFiles
+	}
+
+	turn.Turn = sess.TurnCount
+	return store.AppendTurn(sess.ID, turn, limits)
+}
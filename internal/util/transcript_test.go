@@ -0,0 +1,134 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/signalridge/clinvoker/internal/config"
+	"github.com/signalridge/clinvoker/internal/output"
+	"github.com/signalridge/clinvoker/internal/session"
+)
+
+func newTestEvent(t *testing.T, eventType output.EventType, content any) *output.UnifiedEvent {
+	t.Helper()
+	event := output.NewUnifiedEvent(eventType, "claude", "")
+	if err := event.SetContent(content); err != nil {
+		t.Fatalf("SetContent failed: %v", err)
+	}
+	return event
+}
+
+func TestTurnRecorder(t *testing.T) {
+	tests := []struct {
+		name         string
+		events       []*output.UnifiedEvent
+		wantResponse string
+		wantTools    int
+	}{
+		{
+			name: "complete message replaces its deltas",
+			events: []*output.UnifiedEvent{
+				newTestEvent(t, output.EventMessage, &output.MessageContent{Text: "Hel", IsPartial: true}),
+				newTestEvent(t, output.EventMessage, &output.MessageContent{Text: "lo", IsPartial: true}),
+				newTestEvent(t, output.EventMessage, &output.MessageContent{Text: "Hello"}),
+			},
+			wantResponse: "Hello",
+		},
+		{
+			name: "deltas alone are kept",
+			events: []*output.UnifiedEvent{
+				newTestEvent(t, output.EventMessage, &output.MessageContent{Text: "Hel", IsPartial: true}),
+				newTestEvent(t, output.EventMessage, &output.MessageContent{Text: "lo", IsPartial: true}),
+			},
+			wantResponse: "Hello",
+		},
+		{
+			name: "tool calls are paired with their results",
+			events: []*output.UnifiedEvent{
+				newTestEvent(t, output.EventMessage, &output.MessageContent{Text: "Reading. ", IsPartial: true}),
+				newTestEvent(t, output.EventToolUse, &output.ToolUseContent{ToolID: "t1", ToolName: "Read"}),
+				newTestEvent(t, output.EventToolResult, &output.ToolResultContent{ToolID: "t1", Output: "package a"}),
+				newTestEvent(t, output.EventMessage, &output.MessageContent{Text: "Done.", IsPartial: true}),
+			},
+			wantResponse: "Reading. Done.",
+			wantTools:    1,
+		},
+		{
+			name: "unmatched result is kept",
+			events: []*output.UnifiedEvent{
+				newTestEvent(t, output.EventToolResult, &output.ToolResultContent{ToolName: "shell", Output: "ok"}),
+			},
+			wantTools: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r TurnRecorder
+			for _, event := range tt.events {
+				r.Add(event)
+			}
+			if got := r.Response(); got != tt.wantResponse {
+				t.Errorf("Response() = %q, want %q", got, tt.wantResponse)
+			}
+			if got := len(r.ToolCalls()); got != tt.wantTools {
+				t.Errorf("len(ToolCalls()) = %d, want %d", got, tt.wantTools)
+			}
+		})
+	}
+}
+
+func TestTurnRecorder_ToolOutput(t *testing.T) {
+	var r TurnRecorder
+	r.Add(newTestEvent(t, output.EventToolUse, &output.ToolUseContent{ToolID: "t1", ToolName: "Read"}))
+	r.Add(newTestEvent(t, output.EventToolResult, &output.ToolResultContent{ToolID: "t1", Output: strings.Repeat("x", 2*maxToolOutput), IsError: true}))
+
+	calls := r.ToolCalls()
+	if len(calls) != 1 {
+		t.Fatalf("got %d tool calls, want 1", len(calls))
+	}
+	if !calls[0].IsError || calls[0].Name != "Read" {
+		t.Errorf("call = %+v", calls[0])
+	}
+	if len(calls[0].Output) > maxToolOutput+len("\n[truncated]") || !strings.HasSuffix(calls[0].Output, "[truncated]") {
+		t.Errorf("output of %d bytes was not truncated", len(calls[0].Output))
+	}
+}
+
+func TestRecordTurn(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.Reset()
+	t.Cleanup(config.Reset)
+	if err := config.Init(""); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	store := session.NewStoreWithDir(t.TempDir())
+	sess, err := store.Create("claude", "/tmp")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	if err := RecordTurn(nil, sess, &session.Turn{}); err != nil {
+		t.Errorf("RecordTurn without store = %v, want nil", err)
+	}
+
+	UpdateSessionFromResponse(sess, 0, "", nil)
+	if err := RecordTurn(store, sess, &session.Turn{Prompt: "hello"}); err != nil {
+		t.Fatalf("RecordTurn failed: %v", err)
+	}
+
+	config.Get().Session.Transcripts = false
+	UpdateSessionFromResponse(sess, 0, "", nil)
+	if err := RecordTurn(store, sess, &session.Turn{Prompt: "ignored"}); err != nil {
+		t.Fatalf("RecordTurn failed: %v", err)
+	}
+
+	turns, err := store.Transcript(sess.ID)
+	if err != nil {
+		t.Fatalf("Transcript failed: %v", err)
+	}
+	if len(turns) != 1 || turns[0].Turn != 1 || turns[0].Prompt != "hello" {
+		t.Errorf("turns = %+v, want only turn 1", turns)
+	}
+}
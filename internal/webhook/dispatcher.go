@@ -0,0 +1,387 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/resilience"
+)
+
+// DefaultHistorySize is the number of deliveries kept for inspection when
+// Config.HistorySize is not set.
+const DefaultHistorySize = 1000
+
+// ErrClosed is returned by Send once the dispatcher has been shut down.
+var ErrClosed = errors.New("webhook dispatcher is shut down")
+
+// errShutdown is recorded on deliveries abandoned at shutdown.
+var errShutdown = errors.New("abandoned at server shutdown")
+
+// Config contains configuration for a webhook dispatcher.
+type Config struct {
+	// Secret signs deliveries. Deliveries are unsigned when it is empty.
+	Secret string
+
+	// Retry is the policy for redelivering after a network error or a
+	// 408, 429 or 5xx response.
+	Retry resilience.RetryPolicy
+
+	// Timeout bounds each attempt. Default: 10 seconds.
+	Timeout time.Duration
+
+	// HistorySize caps the deliveries kept for inspection; the oldest
+	// finished ones are forgotten first. Default: DefaultHistorySize.
+	HistorySize int
+
+	// AllowedHosts are the callback hosts that may resolve to loopback,
+	// link-local or private addresses.
+	AllowedHosts []string
+
+	// Client sends the requests. Default: a client with Timeout that does
+	// not follow redirects and refuses to connect to loopback, link-local
+	// or private addresses of hosts not in AllowedHosts.
+	Client *http.Client
+
+	// OnFinish is called with each delivery once it has been delivered or
+	// has failed.
+	OnFinish func(d Delivery)
+
+	// Logger logs failed attempts. Default: slog.Default().
+	Logger *slog.Logger
+}
+
+// Dispatcher POSTs payloads to callback URLs in the background, retrying
+// failed attempts, and keeps a bounded history of its deliveries. It is safe
+// for concurrent use.
+type Dispatcher struct {
+	cfg    Config
+	client *http.Client
+
+	// stopping is closed by Shutdown to end retries; ctx is canceled once
+	// Shutdown stops waiting, to end attempts in flight.
+	stopping chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu         sync.Mutex
+	closed     bool
+	deliveries map[string]*Delivery
+	order      []string // delivery IDs, oldest first
+}
+
+// NewDispatcher creates a webhook dispatcher.
+func NewDispatcher(cfg Config) *Dispatcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = DefaultHistorySize
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	client := cfg.Client
+	if client == nil {
+		client = newClient(cfg.Timeout, cfg.AllowedHosts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		cfg:        cfg,
+		client:     client,
+		stopping:   make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+		deliveries: make(map[string]*Delivery),
+	}
+}
+
+// newClient returns a client that keeps callbacks out of the server's own
+// network: redirects are returned rather than followed, and connections to
+// private addresses are refused unless the host is one of allowedHosts.
+func newClient(timeout time.Duration, allowedHosts []string) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	guarded := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: refusePrivate}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would connect on our behalf, past the address check
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(addr); err == nil && hostListed(host, allowedHosts) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		return guarded.DialContext(ctx, network, addr)
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// refusePrivate is a net.Dialer Control function that refuses connections
+// to private addresses. It runs after name resolution, so it also covers
+// host names that resolve to them.
+func refusePrivate(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if privateAddr(addrPort.Addr()) {
+		return fmt.Errorf("callback address %s is not a public address", addrPort.Addr())
+	}
+	return nil
+}
+
+// Send starts delivering data, reported as event, to callbackURL and
+// returns the new delivery. subject identifies the job or session the event
+// is about and may be empty.
+func (d *Dispatcher) Send(callbackURL, event, subject string, data any) (Delivery, error) {
+	id, err := generateID()
+	if err != nil {
+		return Delivery{}, fmt.Errorf("failed to generate delivery ID: %w", err)
+	}
+
+	now := time.Now()
+	body, err := json.Marshal(Payload{ID: id, Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		return Delivery{}, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	delivery := &Delivery{
+		ID:        id,
+		Event:     event,
+		URL:       redactURL(callbackURL),
+		Subject:   subject,
+		Status:    StatusPending,
+		Attempts:  []Attempt{},
+		CreatedAt: now,
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return Delivery{}, ErrClosed
+	}
+	d.deliveries[id] = delivery
+	d.order = append(d.order, id)
+	d.trimLocked()
+
+	d.wg.Add(1)
+	go d.deliver(delivery, callbackURL, body)
+	return copyDelivery(delivery), nil
+}
+
+// deliver attempts a delivery until it succeeds, fails permanently or runs
+// out of attempts.
+func (d *Dispatcher) deliver(delivery *Delivery, callbackURL string, body []byte) {
+	defer d.wg.Done()
+
+	maxAttempts := max(d.cfg.Retry.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		statusCode, err := d.post(delivery, callbackURL, body)
+		if err == nil {
+			d.finish(delivery, StatusDelivered)
+			return
+		}
+
+		d.cfg.Logger.Warn("webhook delivery attempt failed",
+			"delivery_id", delivery.ID, "event", delivery.Event, "attempt", attempt, "error", err)
+		if attempt >= maxAttempts || !retryable(statusCode) {
+			d.finish(delivery, StatusFailed)
+			return
+		}
+
+		wait := d.cfg.Retry.Backoff(attempt)
+		next := time.Now().Add(wait)
+		d.mu.Lock()
+		delivery.NextAttemptAt = &next
+		d.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-d.stopping:
+			timer.Stop()
+			d.mu.Lock()
+			delivery.Attempts = append(delivery.Attempts, Attempt{Time: time.Now(), Error: errShutdown.Error()})
+			d.mu.Unlock()
+			d.finish(delivery, StatusFailed)
+			return
+		}
+	}
+}
+
+// post makes one attempt and records it. It returns the response status,
+// or 0 when no response arrived, and an error unless the receiver accepted
+// the delivery.
+func (d *Dispatcher) post(delivery *Delivery, callbackURL string, body []byte) (int, error) {
+	start := time.Now()
+	statusCode, err := d.send(delivery, callbackURL, body, start)
+
+	attempt := Attempt{
+		Time:       start,
+		StatusCode: statusCode,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	d.mu.Lock()
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.NextAttemptAt = nil
+	d.mu.Unlock()
+	return statusCode, err
+}
+
+func (d *Dispatcher) send(delivery *Delivery, callbackURL string, body []byte, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "clinvk-webhook")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if d.cfg.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(d.cfg.Secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether an attempt that got statusCode is worth
+// repeating: no response at all, a timeout, throttling or a server error.
+func retryable(statusCode int) bool {
+	return statusCode == 0 ||
+		statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode >= 500
+}
+
+func (d *Dispatcher) finish(delivery *Delivery, status Status) {
+	now := time.Now()
+	d.mu.Lock()
+	delivery.Status = status
+	delivery.NextAttemptAt = nil
+	delivery.FinishedAt = &now
+	snapshot := copyDelivery(delivery)
+	d.mu.Unlock()
+
+	if d.cfg.OnFinish != nil {
+		d.cfg.OnFinish(snapshot)
+	}
+}
+
+// trimLocked forgets the oldest finished deliveries beyond the history
+// size. The caller must hold d.mu.
+func (d *Dispatcher) trimLocked() {
+	excess := len(d.order) - d.cfg.HistorySize
+	if excess <= 0 {
+		return
+	}
+	kept := d.order[:0]
+	for _, id := range d.order {
+		if excess > 0 && d.deliveries[id].Status != StatusPending {
+			delete(d.deliveries, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	d.order = kept
+}
+
+// Get returns a delivery by ID.
+func (d *Dispatcher) Get(id string) (Delivery, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delivery, ok := d.deliveries[id]
+	if !ok {
+		return Delivery{}, false
+	}
+	return copyDelivery(delivery), true
+}
+
+// List returns deliveries newest first, limited to status when it is not
+// empty and to limit entries when it is positive.
+func (d *Dispatcher) List(status Status, limit int) []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deliveries := []Delivery{}
+	for i := len(d.order) - 1; i >= 0; i-- {
+		delivery := d.deliveries[d.order[i]]
+		if status != "" && delivery.Status != status {
+			continue
+		}
+		deliveries = append(deliveries, copyDelivery(delivery))
+		if limit > 0 && len(deliveries) == limit {
+			break
+		}
+	}
+	return deliveries
+}
+
+// Shutdown stops accepting deliveries and waits, until ctx ends, for the
+// attempts in flight to finish. Deliveries waiting to be retried, and those
+// still in flight when ctx ends, are marked failed.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.stopping)
+	}
+	d.mu.Unlock()
+	defer d.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func copyDelivery(delivery *Delivery) Delivery {
+	c := *delivery
+	c.Attempts = append([]Attempt{}, delivery.Attempts...)
+	return c
+}
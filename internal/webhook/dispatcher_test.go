@@ -0,0 +1,233 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/signalridge/clinvoker/internal/resilience"
+)
+
+// testHosts allows deliveries to the loopback test servers.
+var testHosts = []string{"127.0.0.1"}
+
+func fastRetry(maxAttempts int) resilience.RetryPolicy {
+	return resilience.RetryPolicy{MaxAttempts: maxAttempts, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+}
+
+// waitForDelivery polls until the delivery has finished.
+func waitForDelivery(t *testing.T, d *Dispatcher, id string) Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		delivery, ok := d.Get(id)
+		if !ok {
+			t.Fatalf("delivery %s not found", id)
+		}
+		if delivery.Status != StatusPending {
+			return delivery
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("delivery %s did not finish", id)
+	return Delivery{}
+}
+
+// statusServer answers with the given statuses in turn, then with 200.
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestDispatcher_SignedDelivery(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var finished atomic.Int32
+	d := NewDispatcher(Config{Secret: "s3cret", AllowedHosts: testHosts, OnFinish: func(Delivery) { finished.Add(1) }})
+	sent, err := d.Send(srv.URL+"/cb?token=abc", "prompt.completed", "sess1", map[string]int{"exit_code": 0})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if sent.Status != StatusPending || sent.URL != srv.URL+"/cb" {
+		t.Errorf("unexpected delivery: %+v", sent)
+	}
+
+	r := <-received
+	body := <-bodies
+	timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if !Verify("s3cret", timestamp, body, r.Header.Get(HeaderSignature)) {
+		t.Error("expected a valid signature")
+	}
+	if r.Header.Get(HeaderEvent) != "prompt.completed" || r.Header.Get(HeaderDelivery) != sent.ID {
+		t.Errorf("unexpected headers: %v", r.Header)
+	}
+
+	var payload struct {
+		ID    string         `json:"id"`
+		Event string         `json:"event"`
+		Data  map[string]int `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.ID != sent.ID || payload.Event != "prompt.completed" {
+		t.Errorf("unexpected payload %s: %v", body, err)
+	}
+
+	delivery := waitForDelivery(t, d, sent.ID)
+	if delivery.Status != StatusDelivered || len(delivery.Attempts) != 1 || delivery.Attempts[0].StatusCode != http.StatusNoContent {
+		t.Errorf("unexpected delivery: %+v", delivery)
+	}
+	if finished.Load() != 1 {
+		t.Errorf("expected OnFinish once, got %d", finished.Load())
+	}
+}
+
+func TestDispatcher_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		maxAttempts  int
+		wantStatus   Status
+		wantAttempts int
+	}{
+		{"recovers after server errors", []int{503, 500}, 3, StatusDelivered, 3},
+		{"retries throttling", []int{429}, 3, StatusDelivered, 2},
+		{"runs out of attempts", []int{502, 502, 502}, 2, StatusFailed, 2},
+		{"client error is final", []int{400}, 3, StatusFailed, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := statusServer(t, tt.statuses...)
+			d := NewDispatcher(Config{Retry: fastRetry(tt.maxAttempts), AllowedHosts: testHosts})
+
+			sent, err := d.Send(srv.URL, "chain.completed", "", nil)
+			if err != nil {
+				t.Fatalf("Send() error: %v", err)
+			}
+			delivery := waitForDelivery(t, d, sent.ID)
+			if delivery.Status != tt.wantStatus || len(delivery.Attempts) != tt.wantAttempts || int(calls.Load()) != tt.wantAttempts {
+				t.Errorf("got status %s after %d attempts (%d calls), want %s after %d",
+					delivery.Status, len(delivery.Attempts), calls.Load(), tt.wantStatus, tt.wantAttempts)
+			}
+			if delivery.FinishedAt == nil {
+				t.Error("expected a finish time")
+			}
+		})
+	}
+}
+
+func TestDispatcher_History(t *testing.T) {
+	srv, _ := statusServer(t, http.StatusBadRequest)
+	d := NewDispatcher(Config{HistorySize: 2, AllowedHosts: testHosts})
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		sent, _ := d.Send(srv.URL, "prompt.completed", "", nil)
+		waitForDelivery(t, d, sent.ID)
+		ids = append(ids, sent.ID)
+	}
+
+	all := d.List("", 0)
+	if len(all) != 2 || all[0].ID != ids[2] || all[1].ID != ids[1] {
+		t.Fatalf("expected the two newest deliveries, got %+v", all)
+	}
+	if _, ok := d.Get(ids[0]); ok {
+		t.Error("expected the oldest delivery to be forgotten")
+	}
+	if delivered := d.List(StatusDelivered, 0); len(delivered) != 2 {
+		t.Errorf("expected two delivered deliveries, got %+v", delivered)
+	}
+	if failed := d.List(StatusFailed, 0); len(failed) != 0 {
+		t.Errorf("expected the failed delivery to be forgotten, got %+v", failed)
+	}
+	if limited := d.List("", 1); len(limited) != 1 || limited[0].ID != ids[2] {
+		t.Errorf("expected the newest delivery, got %+v", limited)
+	}
+}
+
+func TestDispatcher_Shutdown(t *testing.T) {
+	srv, _ := statusServer(t, 503, 503, 503)
+	d := NewDispatcher(Config{Retry: resilience.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour}, AllowedHosts: testHosts})
+
+	sent, _ := d.Send(srv.URL, "job.completed", "job1", nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if delivery, _ := d.Get(sent.ID); delivery.NextAttemptAt != nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error: %v", err)
+	}
+	delivery, _ := d.Get(sent.ID)
+	if delivery.Status != StatusFailed || delivery.Attempts[len(delivery.Attempts)-1].Error != errShutdown.Error() {
+		t.Errorf("expected the delivery to be abandoned, got %+v", delivery)
+	}
+	if _, err := d.Send(srv.URL, "job.completed", "job2", nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Send() after Shutdown error = %v, want ErrClosed", err)
+	}
+}
+
+func TestDispatcher_RefusesRedirects(t *testing.T) {
+	target, targetCalls := statusServer(t)
+	srv := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer srv.Close()
+
+	d := NewDispatcher(Config{Retry: fastRetry(3), AllowedHosts: testHosts})
+	sent, _ := d.Send(srv.URL, "prompt.completed", "", nil)
+	delivery := waitForDelivery(t, d, sent.ID)
+	if delivery.Status != StatusFailed || len(delivery.Attempts) != 1 || delivery.Attempts[0].StatusCode != http.StatusFound {
+		t.Errorf("expected the redirect to fail the delivery, got %+v", delivery)
+	}
+	if targetCalls.Load() != 0 {
+		t.Error("expected the redirect not to be followed")
+	}
+}
+
+func TestDispatcher_RefusesPrivateAddresses(t *testing.T) {
+	srv, calls := statusServer(t)
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	// Send does not validate URLs, so these are refused when dialing
+	d := NewDispatcher(Config{})
+	for _, callbackURL := range []string{srv.URL, "http://localhost:" + port} {
+		sent, _ := d.Send(callbackURL, "prompt.completed", "", nil)
+		delivery := waitForDelivery(t, d, sent.ID)
+		if delivery.Status != StatusFailed || !strings.Contains(delivery.Attempts[0].Error, "not a public address") {
+			t.Errorf("expected %s to be refused, got %+v", callbackURL, delivery)
+		}
+	}
+	if calls.Load() != 0 {
+		t.Errorf("expected no requests to reach the server, got %d", calls.Load())
+	}
+}
@@ -0,0 +1,167 @@
+// Package webhook delivers signed completion callbacks to client URLs.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	// HeaderEvent names the event being reported.
+	HeaderEvent = "X-Clinvk-Event"
+	// HeaderDelivery carries the delivery ID, which stays the same across
+	// retries so receivers can drop duplicates.
+	HeaderDelivery = "X-Clinvk-Delivery"
+	// HeaderTimestamp carries the Unix time the attempt was signed at.
+	HeaderTimestamp = "X-Clinvk-Timestamp"
+	// HeaderSignature carries the HMAC-SHA256 signature of the attempt,
+	// as "sha256=<hex>". It is omitted when no secret is configured.
+	HeaderSignature = "X-Clinvk-Signature"
+)
+
+// signaturePrefix prefixes the hex digest in HeaderSignature.
+const signaturePrefix = "sha256="
+
+// Status represents the state of a delivery.
+type Status string
+
+const (
+	// StatusPending is a delivery still being attempted.
+	StatusPending Status = "pending"
+	// StatusDelivered is a delivery the receiver accepted with a 2xx status.
+	StatusDelivered Status = "delivered"
+	// StatusFailed is a delivery that ran out of attempts or was refused.
+	StatusFailed Status = "failed"
+)
+
+// Payload is the JSON body POSTed to a callback URL.
+type Payload struct {
+	// ID is the delivery ID.
+	ID string `json:"id"`
+
+	// Event names what happened, such as "prompt.completed".
+	Event string `json:"event"`
+
+	// CreatedAt is when the event occurred.
+	CreatedAt time.Time `json:"created_at"`
+
+	// Data is the result being reported, as its endpoint returns it.
+	Data any `json:"data"`
+}
+
+// Delivery records the attempts to POST one payload to a callback URL.
+type Delivery struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+
+	// URL is the callback URL without credentials or query string.
+	URL string `json:"url"`
+
+	// Subject is the ID of the job or session the delivery reports on.
+	Subject string `json:"subject,omitempty"`
+
+	Status        Status     `json:"status"`
+	Attempts      []Attempt  `json:"attempts"`
+	CreatedAt     time.Time  `json:"created_at"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+}
+
+// Attempt is a single POST of a delivery.
+type Attempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// Sign returns the signature of a delivery attempt: the HMAC-SHA256, keyed
+// with secret, of the timestamp, a period and the body.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the valid signature of body sent at
+// timestamp, in constant time.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// ValidateURL checks that a callback URL is an absolute http(s) URL and,
+// when allowedHosts is not empty, that its host is one of them. Unless it is
+// listed in allowedHosts, the host may not be localhost or a loopback,
+// link-local or private address; the Dispatcher also refuses to connect to
+// such addresses when a host name resolves to them.
+func ValidateURL(raw string, allowedHosts []string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("callback_url must be an http or https URL")
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("callback_url must include a host")
+	}
+	listed := hostListed(u.Hostname(), allowedHosts)
+	if len(allowedHosts) > 0 && !listed {
+		return fmt.Errorf("callback_url host %q is not allowed", u.Hostname())
+	}
+	if !listed {
+		addr, err := netip.ParseAddr(u.Hostname())
+		if strings.EqualFold(u.Hostname(), "localhost") || (err == nil && privateAddr(addr)) {
+			return fmt.Errorf("callback_url host %q is not a public address", u.Hostname())
+		}
+	}
+	return nil
+}
+
+// hostListed reports whether host is one of allowedHosts.
+func hostListed(host string, allowedHosts []string) bool {
+	return slices.ContainsFunc(allowedHosts, func(allowed string) bool {
+		return strings.EqualFold(allowed, host)
+	})
+}
+
+// privateAddr reports whether addr is a loopback, link-local, private or
+// unspecified address, which callbacks may reach only through an allowed
+// host.
+func privateAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsPrivate() || addr.IsUnspecified()
+}
+
+// redactURL strips credentials, query and fragment from a URL for display.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+func generateID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}